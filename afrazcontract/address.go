@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"authz"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+// addressObjectType prefixes the composite keys of versioned address entries
+const addressObjectType = "address"
+
+var addressRepo = repository.New[AddressEntry](addressObjectType)
+
+// AddressEntry is one version of an identity's address. An empty EffectiveFrom marks an address
+// that was already in place before address history was recorded.
+type AddressEntry struct {
+	IdentityID    string `json:"identityId"`
+	Seq           int    `json:"seq"`
+	Address       string `json:"address"`
+	EffectiveFrom string `json:"effectiveFrom"`
+	RecordedAt    string `json:"recordedAt"`
+	RecordedByMSP string `json:"recordedByMSP"`
+}
+
+// GetAddressHistory returns every recorded address of an identity, oldest first
+func (s *SmartContract) GetAddressHistory(ctx contractapi.TransactionContextInterface, id string) ([]*AddressEntry, error) {
+	identity, err := s.ReadIdentity(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return addressEntries(ctx, identity.ID)
+}
+
+// GetAddressAsOf returns the address of an identity that was in effect on the given date
+func (s *SmartContract) GetAddressAsOf(ctx contractapi.TransactionContextInterface, id string, date string) (*AddressEntry, error) {
+	asOf, err := parseDate(date)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.GetAddressHistory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var effective *AddressEntry
+	for _, entry := range entries {
+		if entry.EffectiveFrom <= asOf.Format("2006-01-02") {
+			effective = entry
+		}
+	}
+	if effective == nil {
+		return nil, fmt.Errorf("no address recorded for identity %s as of %s", id, date)
+	}
+
+	return effective, nil
+}
+
+// recordAddress appends a new address version effective from the transaction date. When an
+// identity has an address but no history yet, that address is kept as the first entry.
+func recordAddress(ctx contractapi.TransactionContextInterface, identity *Identity, address string) error {
+	if address == identity.Address {
+		return nil
+	}
+
+	entries, err := addressEntries(ctx, identity.ID)
+	if err != nil {
+		return err
+	}
+	mspID, err := authz.CallerMSP(ctx)
+	if err != nil {
+		return err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	seq := len(entries)
+	if seq == 0 && identity.Address != "" {
+		seq++
+		err = putAddressEntry(ctx, &AddressEntry{
+			IdentityID:    identity.ID,
+			Seq:           seq,
+			Address:       identity.Address,
+			RecordedAt:    now.Format(time.RFC3339),
+			RecordedByMSP: mspID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	seq++
+	return putAddressEntry(ctx, &AddressEntry{
+		IdentityID:    identity.ID,
+		Seq:           seq,
+		Address:       address,
+		EffectiveFrom: now.Format("2006-01-02"),
+		RecordedAt:    now.Format(time.RFC3339),
+		RecordedByMSP: mspID,
+	})
+}
+
+// deleteAddressHistory removes every address entry of an identity
+func deleteAddressHistory(ctx contractapi.TransactionContextInterface, id string) error {
+	entries, err := addressEntries(ctx, id)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		err = addressRepo.Delete(ctx, id, addressSeq(entry.Seq))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func addressEntries(ctx contractapi.TransactionContextInterface, id string) ([]*AddressEntry, error) {
+	return addressRepo.List(ctx, id)
+}
+
+// addressSeq zero pads the sequence number so that entries iterate in order
+func addressSeq(seq int) string {
+	return fmt.Sprintf("%06d", seq)
+}
+
+func putAddressEntry(ctx contractapi.TransactionContextInterface, entry *AddressEntry) error {
+	return addressRepo.Put(ctx, entry, entry.IdentityID, addressSeq(entry.Seq))
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddressHistory(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	history := func(id string, want ...string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			entries, err := s.GetAddressHistory(ctx, id)
+			if err == nil {
+				var addresses []string
+				for _, entry := range entries {
+					addresses = append(addresses, entry.EffectiveFrom+" "+entry.Address)
+				}
+				require.Equal(t, want, addresses)
+			}
+			return err
+		}
+	}
+	asOf := func(date string, want string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			entry, err := s.GetAddressAsOf(ctx, "identity1", date)
+			if err == nil {
+				require.Equal(t, want, entry.Address)
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "no history without an address", Caller: registrar, Run: history("identity1")},
+		updateIdentity(registrar, "identity1", "03001234567", "12 Mall Road, Lahore"),
+		updateIdentity(registrar, "identity1", "03009999999", "12 Mall Road, Lahore"),
+		{Name: "unchanged addresses are not recorded", Caller: registrar, Run: history("identity1", "2024-01-01 12 Mall Road, Lahore")},
+		{
+			Name:   "addresses already in place start the history",
+			Caller: registrar,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				err := ctx.GetStub().PutState(identityNamespace.Key("identity2"), []byte(`{"id":"identity2","address":"7 Canal Bank, Lahore","ownerMSP":"Org1MSP"}`))
+				require.NoError(t, err)
+				return s.UpdateIdentity(ctx, "identity2", "03211234567", "9 Gulberg, Lahore")
+			},
+		},
+		{Name: "history of a pre-existing address", Caller: registrar, Run: history("identity2", " 7 Canal Bank, Lahore", "2024-01-01 9 Gulberg, Lahore")},
+	})
+
+	ledger.Advance(48 * time.Hour)
+	ledger.Run(t, []chaincodetest.Case{
+		updateIdentity(registrar, "identity1", "03009999999", "4 Mall Road, Murree"),
+		{Name: "address history", Caller: registrar, Run: history("identity1", "2024-01-01 12 Mall Road, Lahore", "2024-01-03 4 Mall Road, Murree")},
+		{Name: "address in effect earlier", Caller: registrar, Run: asOf("02-01-2024", "12 Mall Road, Lahore")},
+		{Name: "address in effect now", Caller: registrar, Run: asOf("2024-01-03", "4 Mall Road, Murree")},
+		{Name: "address before any was recorded", Caller: registrar, Run: asOf("2023-12-31", ""), Err: "no address recorded for identity identity1 as of 2023-12-31"},
+		{Name: "address as of an invalid date", Caller: registrar, Run: asOf("yesterday", ""), Err: `invalid date "yesterday"`},
+		{Name: "other organizations need consent", Caller: partner, Run: history("identity1"), Err: "not authorized to read identity identity1"},
+		{
+			Name:   "deleting an identity keeps its history for a restore",
+			Caller: registrar,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				err := s.DeleteIdentity(ctx, "identity1", "closed")
+				if err == nil {
+					entries, err := addressEntries(ctx, "identity1")
+					require.NoError(t, err)
+					require.Len(t, entries, 2)
+				}
+				return err
+			},
+		},
+	})
+}
@@ -168,7 +168,8 @@ app.put('/identities/:id', async (req, res) => {
 
 app.get('/identities', async (req, res) => {
     try {
-        const resultBytes = await contract.evaluateTransaction('GetAllIdentities');
+        const includeDeleted = req.query.includeDeleted === 'true';
+        const resultBytes = await contract.evaluateTransaction('GetAllIdentities', String(includeDeleted));
         const resultJson = utf8Decoder.decode(resultBytes);
         const result = JSON.parse(resultJson);
         res.json(result);
@@ -180,7 +181,11 @@ app.get('/identities', async (req, res) => {
 app.delete('/identities/:id', async (req, res) => {
     try {
         const id = req.params.id;
-        await contract.submitTransaction('DeleteIdentity', id);
+        const reason = req.query.reason;
+        if (!reason) {
+            return res.status(400).json({ error: 'Missing deletion reason' });
+        }
+        await contract.submitTransaction('DeleteIdentity', id, reason);
         res.json({ message: 'Identity deleted successfully' });
     } catch (error) {
         res.status(500).json({ error: error.message });
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	// identityPrivateCollection holds identity data that must not be shared on the channel
+	identityPrivateCollection = "identityPrivateCollection"
+
+	// biometricObjectType prefixes the private composite keys of biometric bindings
+	biometricObjectType = "biometric"
+)
+
+var biometricTypes = map[string]bool{
+	"fingerprint": true,
+	"face":        true,
+	"iris":        true,
+}
+
+// BiometricBinding anchors a biometric template to an identity as a salted hash.
+// The template hash itself is never stored.
+type BiometricBinding struct {
+	IdentityID    string `json:"identityId"`
+	BiometricType string `json:"biometricType"`
+	Salt          string `json:"salt"`
+	SaltedHash    string `json:"saltedHash"`
+	BoundAt       string `json:"boundAt"`
+	BoundByMSP    string `json:"boundByMSP"`
+}
+
+// BindBiometric stores a salted hash of the biometric template hash for the identity in the
+// private collection, replacing any earlier binding of the same type. Owner only.
+func (s *SmartContract) BindBiometric(ctx contractapi.TransactionContextInterface, id string, biometricType string, templateHash string) error {
+	if !biometricTypes[biometricType] {
+		return fmt.Errorf("unknown biometric type %s", biometricType)
+	}
+	if templateHash == "" {
+		return fmt.Errorf("template hash must not be empty")
+	}
+
+	identity, err := s.getIdentity(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = requireOwner(ctx, identity)
+	if err != nil {
+		return err
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	// the salt must be identical on every endorsing peer, so it is derived from the transaction ID
+	saltDigest := sha256.Sum256([]byte(ctx.GetStub().GetTxID() + identity.ID + biometricType))
+	salt := hex.EncodeToString(saltDigest[:])
+
+	binding := BiometricBinding{
+		IdentityID:    identity.ID,
+		BiometricType: biometricType,
+		Salt:          salt,
+		SaltedHash:    saltedHash(salt, templateHash),
+		BoundAt:       now.Format(time.RFC3339),
+		BoundByMSP:    identity.OwnerMSP,
+	}
+
+	bindingKey, err := ctx.GetStub().CreateCompositeKey(biometricObjectType, []string{identity.ID, biometricType})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	bindingJSON, err := json.Marshal(binding)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutPrivateData(identityPrivateCollection, bindingKey, bindingJSON)
+}
+
+// VerifyBiometric reports whether a live template hash matches the biometric bound to the identity.
+// Callers outside the owning organization need an active read consent from the subject.
+func (s *SmartContract) VerifyBiometric(ctx contractapi.TransactionContextInterface, id string, biometricType string, hash string) (bool, error) {
+	identity, err := s.getIdentity(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	err = s.authorizeRead(ctx, identity)
+	if err != nil {
+		return false, err
+	}
+
+	bindingKey, err := ctx.GetStub().CreateCompositeKey(biometricObjectType, []string{identity.ID, biometricType})
+	if err != nil {
+		return false, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	bindingJSON, err := ctx.GetStub().GetPrivateData(identityPrivateCollection, bindingKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to read from private data: %v", err)
+	}
+	if bindingJSON == nil {
+		return false, fmt.Errorf("no %s biometric bound to identity %s", biometricType, identity.ID)
+	}
+
+	var binding BiometricBinding
+	err = json.Unmarshal(bindingJSON, &binding)
+	if err != nil {
+		return false, err
+	}
+
+	computed := saltedHash(binding.Salt, hash)
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(binding.SaltedHash)) == 1, nil
+}
+
+// saltedHash returns the hex encoded SHA-256 digest of the salt followed by the value
+func saltedHash(salt string, value string) string {
+	digest := sha256.Sum256([]byte(salt + value))
+	return hex.EncodeToString(digest[:])
+}
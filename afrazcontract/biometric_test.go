@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBiometrics(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	bind := func(biometricType string, templateHash string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.BindBiometric(ctx, "identity1", biometricType, templateHash)
+		}
+	}
+	verify := func(biometricType string, hash string, want bool) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			matched, err := s.VerifyBiometric(ctx, "identity1", biometricType, hash)
+			require.Equal(t, want, matched)
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{
+			Name:   "bind fingerprint",
+			Caller: registrar,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				err := s.BindBiometric(ctx, "identity1", "fingerprint", "template-hash-1")
+				if err == nil {
+					key, _ := ctx.GetStub().CreateCompositeKey(biometricObjectType, []string{"identity1", "fingerprint"})
+					bindingJSON, err := ctx.GetStub().GetPrivateData(identityPrivateCollection, key)
+					require.NoError(t, err)
+					require.NotContains(t, string(bindingJSON), "template-hash-1")
+				}
+				return err
+			},
+		},
+		{Name: "matching template", Caller: registrar, Run: verify("fingerprint", "template-hash-1", true)},
+		{Name: "different template", Caller: registrar, Run: verify("fingerprint", "template-hash-2", false)},
+		{Name: "unbound biometric", Caller: registrar, Run: verify("face", "template-hash-1", false), Err: "no face biometric bound to identity identity1"},
+		{Name: "other organizations need consent", Caller: partner, Run: verify("fingerprint", "template-hash-1", false), Err: "not authorized to read identity identity1"},
+		{Name: "rebinding replaces the template", Caller: registrar, Run: bind("fingerprint", "template-hash-2")},
+		{Name: "rebound template matches", Caller: registrar, Run: verify("fingerprint", "template-hash-2", true)},
+		{Name: "bind unknown type", Caller: registrar, Run: bind("voice", "template-hash-1"), Err: "unknown biometric type voice"},
+		{Name: "bind empty template", Caller: registrar, Run: bind("iris", ""), Err: "template hash must not be empty"},
+		{Name: "only the owner binds", Caller: partner, Run: bind("iris", "template-hash-1"), Err: "identity identity1 is owned by Org1MSP"},
+	})
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+// identityAssetType is the asset type identities are tracked under in the update-time index
+const identityAssetType = "identity"
+
+// IdentityChange is an identity written or deleted since a sync. Identity is nil for a deletion,
+// including an erasure or the merge of a duplicate into its primary.
+type IdentityChange struct {
+	IdentityID string    `json:"identityId"`
+	TxID       string    `json:"txId"`
+	UpdatedAt  string    `json:"updatedAt"`
+	Deleted    bool      `json:"deleted"`
+	Identity   *Identity `json:"identity,omitempty" metadata:",optional"`
+}
+
+// IdentityChangePage is one page of identity changes with the bookmark the next page starts at,
+// which is empty after the last page
+type IdentityChangePage struct {
+	Changes  []*IdentityChange `json:"changes,omitempty" metadata:",optional"`
+	Bookmark string            `json:"bookmark"`
+}
+
+// GetChangesSince returns up to pageSize changes of assetType at or after the transaction
+// timestamp sinceTxTimestamp, oldest first, so that a client only fetches what changed since its
+// last sync. An empty sinceTxTimestamp starts at the first change. Pass the bookmark of a page to
+// read the next one. Only identities are tracked, under the asset type "identity". Identities
+// the caller may not read are left out of the page and the rest are redacted as ReadIdentity
+// redacts them. Evaluate it rather than submitting it.
+func (s *SmartContract) GetChangesSince(ctx contractapi.TransactionContextInterface, assetType string, sinceTxTimestamp string, pageSize int, bookmark string) (*IdentityChangePage, error) {
+	if assetType != identityAssetType {
+		return nil, fmt.Errorf("changes of %s are not tracked, only of %s", assetType, identityAssetType)
+	}
+	var since time.Time
+	if sinceTxTimestamp != "" {
+		var err error
+		since, err = time.Parse(time.RFC3339, sinceTxTimestamp)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sinceTxTimestamp %q: %v", sinceTxTimestamp, err)
+		}
+	}
+
+	changes, err := repository.ChangesSince(ctx, assetType, since, int32(pageSize), bookmark)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &IdentityChangePage{Bookmark: changes.Bookmark}
+	for _, change := range changes.Changes {
+		identityChange := &IdentityChange{
+			IdentityID: change.Key,
+			TxID:       change.TxID,
+			UpdatedAt:  change.UpdatedAt,
+			Deleted:    change.Deleted,
+		}
+		if !change.Deleted {
+			identity, err := s.getIdentity(ctx, change.Key)
+			if err != nil {
+				return nil, err
+			}
+			allowed, err := s.canRead(ctx, identity)
+			if err != nil {
+				return nil, err
+			}
+			if !allowed {
+				continue
+			}
+			err = identityRedaction.Apply(ctx, identity)
+			if err != nil {
+				return nil, err
+			}
+			identityChange.Identity = identity
+		}
+		page.Changes = append(page.Changes, identityChange)
+	}
+
+	return page, nil
+}
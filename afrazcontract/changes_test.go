@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+
+	"authz"
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetChangesSince(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+	registrar2 := chaincodetest.Identity{MSPID: "Org2MSP", CommonName: "registrar3", Attributes: map[string]string{registrarAttribute: KYCLevelFull}}
+	var bookmark string
+
+	changes := func(since string, pageSize int, want ...string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			page, err := s.GetChangesSince(ctx, identityAssetType, since, pageSize, bookmark)
+			if err == nil {
+				var got []string
+				for _, change := range page.Changes {
+					if change.Deleted {
+						require.Nil(t, change.Identity)
+						got = append(got, "-"+change.IdentityID)
+					} else {
+						require.Equal(t, change.IdentityID, change.Identity.ID)
+						got = append(got, change.IdentityID)
+					}
+				}
+				require.Equal(t, want, got)
+				bookmark = page.Bookmark
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		createIdentity(registrar2, "identity2", "35202-1234567-1"),
+		createIdentity(registrar, "identity3", "35202-7654321-1"),
+		updateIdentity(registrar, "identity1", "03009999999", "House 1, Street 2, Lahore"),
+		{
+			Name:   "delete identity3",
+			Caller: registrar,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.DeleteIdentity(ctx, "identity3", "closed")
+			},
+		},
+		{Name: "changes readable by the caller", Caller: registrar, Run: changes("", 10, "identity1", "-identity3")},
+		{Name: "changes of another org", Caller: registrar2, Run: changes("", 10, "identity2", "-identity3")},
+		{Name: "changes since a sync", Caller: registrar, Run: changes("2024-01-01T09:00:04Z", 10, "identity1", "-identity3")},
+		{Name: "first page", Caller: registrar2, Run: changes("", 2, "identity2")},
+		{Name: "next page", Caller: registrar2, Run: changes("", 2, "-identity3")},
+		{
+			Name:   "customers sync redacted identities",
+			Caller: customer,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				page, err := s.GetChangesSince(ctx, identityAssetType, "2024-01-01T09:00:04Z", 1, "")
+				if err == nil {
+					require.Equal(t, authz.Redacted, page.Changes[0].Identity.MobileNumber)
+				}
+				return err
+			},
+		},
+		{
+			Name:   "untracked asset type",
+			Caller: registrar,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.GetChangesSince(ctx, "consent", "", 10, "")
+				return err
+			},
+			Err: "changes of consent are not tracked, only of identity",
+		},
+		{Name: "malformed timestamp", Caller: registrar, Run: changes("yesterday", 10), Err: `invalid sinceTxTimestamp "yesterday"`},
+	})
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"authz"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+	"timelock"
+)
+
+const (
+	// consentObjectType prefixes the composite keys of consents granted on an identity
+	consentObjectType = "consent"
+
+	// ConsentScopeRead allows the grantee to read the full identity record
+	ConsentScopeRead = "read"
+)
+
+var consentRepo = repository.New[Consent](consentObjectType)
+
+// validConsentScopes lists the scopes a subject may grant
+var validConsentScopes = map[string]bool{
+	ConsentScopeRead: true,
+}
+
+// Consent records a subject's permission for another organization to access their identity
+type Consent struct {
+	IdentityID string `json:"identityId"`
+	GranteeMSP string `json:"granteeMSP"`
+	Scope      string `json:"scope"`
+	Expiry     string `json:"expiry"`
+	GrantedAt  string `json:"grantedAt"`
+	Revoked    bool   `json:"revoked"`
+	RevokedAt  string `json:"revokedAt"`
+}
+
+// GrantConsent allows the grantee organization to access an identity for the given scope until expiry.
+// Only the organization owning the identity may grant consent on the subject's behalf.
+func (s *SmartContract) GrantConsent(ctx contractapi.TransactionContextInterface, identityID string, granteeMSP string, scope string, expiry string) error {
+	identity, err := s.getIdentity(ctx, identityID)
+	if err != nil {
+		return err
+	}
+	err = requireOwner(ctx, identity)
+	if err != nil {
+		return err
+	}
+	if granteeMSP == "" {
+		return fmt.Errorf("grantee MSP must not be empty")
+	}
+	if !validConsentScopes[scope] {
+		return fmt.Errorf("unknown consent scope %s", scope)
+	}
+
+	expiresAt, err := parseDate(expiry)
+	if err != nil {
+		return err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	if !expiresAt.After(now) {
+		return fmt.Errorf("consent expiry %s must be in the future", expiry)
+	}
+
+	consent := Consent{
+		IdentityID: identityID,
+		GranteeMSP: granteeMSP,
+		Scope:      scope,
+		Expiry:     expiresAt.Format(time.RFC3339),
+		GrantedAt:  now.Format(time.RFC3339),
+	}
+	err = putConsent(ctx, &consent)
+	if err != nil {
+		return err
+	}
+
+	return emitIdentityEvent(ctx, EventConsentGranted, IdentityEvent{IdentityID: identityID, OwnerMSP: identity.OwnerMSP, GranteeMSP: granteeMSP, Scope: scope})
+}
+
+// RevokeConsent withdraws a previously granted consent. The consent record is kept for audit.
+func (s *SmartContract) RevokeConsent(ctx contractapi.TransactionContextInterface, identityID string, granteeMSP string, scope string) error {
+	identity, err := s.getIdentity(ctx, identityID)
+	if err != nil {
+		return err
+	}
+	err = requireOwner(ctx, identity)
+	if err != nil {
+		return err
+	}
+
+	consent, err := getConsent(ctx, identityID, granteeMSP, scope)
+	if err != nil {
+		return err
+	}
+	if consent == nil || consent.Revoked {
+		return fmt.Errorf("no active %s consent for %s on identity %s", scope, granteeMSP, identityID)
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	consent.Revoked = true
+	consent.RevokedAt = now.Format(time.RFC3339)
+
+	return putConsent(ctx, consent)
+}
+
+// GetConsents returns every consent granted on an identity, including revoked and expired ones.
+// Only the organization owning the identity may list them.
+func (s *SmartContract) GetConsents(ctx contractapi.TransactionContextInterface, identityID string) ([]*Consent, error) {
+	identity, err := s.getIdentity(ctx, identityID)
+	if err != nil {
+		return nil, err
+	}
+	err = requireOwner(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	return consentRepo.List(ctx, identityID)
+}
+
+// ConsentPage is one page of consents with the bookmark the next page starts at, which is empty
+// after the last page
+type ConsentPage struct {
+	Consents            []*Consent `json:"consents,omitempty" metadata:",optional"`
+	FetchedRecordsCount int32      `json:"fetchedRecordsCount"`
+	Bookmark            string     `json:"bookmark"`
+}
+
+// GetConsentsPaginated returns one page of at most repository.MaxPageSize of the consents
+// GetConsents returns. Evaluate it rather than submitting it, since Fabric refuses paginated
+// queries in transactions that write.
+func (s *SmartContract) GetConsentsPaginated(ctx contractapi.TransactionContextInterface, identityID string, pageSize int, bookmark string) (*ConsentPage, error) {
+	identity, err := s.getIdentity(ctx, identityID)
+	if err != nil {
+		return nil, err
+	}
+	err = requireOwner(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+	page, err := consentRepo.ListPaginated(ctx, int32(pageSize), bookmark, identityID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ConsentPage{Consents: page.Records, FetchedRecordsCount: page.FetchedRecordsCount, Bookmark: page.Bookmark}, nil
+}
+
+// authorizeRead returns an error unless the caller may read the full identity record
+func (s *SmartContract) authorizeRead(ctx contractapi.TransactionContextInterface, identity *Identity) error {
+	allowed, err := s.canRead(ctx, identity)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return fmt.Errorf("submitting client not authorized to read identity %s, requires the %s attribute, an active consent or a delegation", identity.ID, registrarAttribute)
+	}
+
+	return nil
+}
+
+// canRead reports whether the caller is a registrar of the owning organization, holds an active
+// read consent or is a delegate with view access. Other channel members are limited to ReadIdentitySummary.
+func (s *SmartContract) canRead(ctx contractapi.TransactionContextInterface, identity *Identity) (bool, error) {
+	mspID, err := authz.CallerMSP(ctx)
+	if err != nil {
+		return false, err
+	}
+	if mspID == identity.OwnerMSP {
+		registrar, err := isRegistrar(ctx)
+		if err != nil || registrar {
+			return registrar, err
+		}
+	} else {
+		consented, err := hasActiveConsent(ctx, identity.ID, mspID, ConsentScopeRead)
+		if err != nil || consented {
+			return consented, err
+		}
+	}
+
+	return isDelegate(ctx, identity.ID, DelegationScopeView)
+}
+
+// redactReadable reports whether the caller may read the identity and, if so, redacts it for
+// the caller as identityRedaction prescribes. Listings use it in place of canRead.
+func (s *SmartContract) redactReadable(ctx contractapi.TransactionContextInterface, identity *Identity) (bool, error) {
+	allowed, err := s.canRead(ctx, identity)
+	if err != nil || !allowed {
+		return false, err
+	}
+
+	return true, identityRedaction.Apply(ctx, identity)
+}
+
+// hasActiveConsent reports whether an unrevoked, unexpired consent exists for the grantee and scope
+func hasActiveConsent(ctx contractapi.TransactionContextInterface, identityID string, granteeMSP string, scope string) (bool, error) {
+	consent, err := getConsent(ctx, identityID, granteeMSP, scope)
+	if err != nil {
+		return false, err
+	}
+	if consent == nil || consent.Revoked {
+		return false, nil
+	}
+
+	expired, err := timelock.Passed(ctx, consent.Expiry)
+	if err != nil {
+		return false, fmt.Errorf("consent on identity %s has an invalid expiry: %v", identityID, err)
+	}
+
+	return !expired, nil
+}
+
+// requireOwner returns an error unless the caller belongs to the organization owning the identity
+func requireOwner(ctx contractapi.TransactionContextInterface, identity *Identity) error {
+	mspID, err := authz.CallerMSP(ctx)
+	if err != nil {
+		return err
+	}
+	if mspID != identity.OwnerMSP {
+		return fmt.Errorf("submitting client not authorized, identity %s is owned by %s", identity.ID, identity.OwnerMSP)
+	}
+
+	return nil
+}
+
+func getConsent(ctx contractapi.TransactionContextInterface, identityID string, granteeMSP string, scope string) (*Consent, error) {
+	return consentRepo.Get(ctx, identityID, granteeMSP, scope)
+}
+
+func putConsent(ctx contractapi.TransactionContextInterface, consent *Consent) error {
+	return consentRepo.Put(ctx, consent, consent.IdentityID, consent.GranteeMSP, consent.Scope)
+}
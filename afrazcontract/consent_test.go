@@ -0,0 +1,107 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsents(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	grant := func(granteeMSP string, scope string, expiry string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			err := s.GrantConsent(ctx, "identity1", granteeMSP, scope, expiry)
+			if err == nil {
+				name, event := lastEvent(t, ctx)
+				require.Equal(t, EventConsentGranted, name)
+				require.Equal(t, granteeMSP, event.GranteeMSP)
+				require.Equal(t, scope, event.Scope)
+			}
+			return err
+		}
+	}
+	revoke := func(granteeMSP string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.RevokeConsent(ctx, "identity1", granteeMSP, ConsentScopeRead)
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "other organizations need consent", Caller: partner, Run: readIdentity("identity1", nil), Err: "not authorized to read identity identity1"},
+		{Name: "grant consent", Caller: registrar, Run: grant("Org2MSP", ConsentScopeRead, "2025-01-01")},
+		{Name: "consent lets the grantee read", Caller: partner, Run: readIdentity("identity1", nil)},
+		{Name: "only the owner grants consent", Caller: partner, Run: grant("Org3MSP", ConsentScopeRead, "2025-01-01"), Err: "identity identity1 is owned by Org1MSP"},
+		{Name: "grant without grantee", Caller: registrar, Run: grant("", ConsentScopeRead, "2025-01-01"), Err: "grantee MSP must not be empty"},
+		{Name: "grant unknown scope", Caller: registrar, Run: grant("Org2MSP", "write", "2025-01-01"), Err: "unknown consent scope write"},
+		{Name: "grant with invalid expiry", Caller: registrar, Run: grant("Org2MSP", ConsentScopeRead, "next year"), Err: `invalid date "next year"`},
+		{Name: "grant expired consent", Caller: registrar, Run: grant("Org2MSP", ConsentScopeRead, "2023-12-31"), Err: "consent expiry 2023-12-31 must be in the future"},
+		{
+			Name:   "list consents",
+			Caller: registrar,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				consents, err := s.GetConsents(ctx, "identity1")
+				if err == nil {
+					require.Len(t, consents, 1)
+					require.Equal(t, "Org2MSP", consents[0].GranteeMSP)
+					require.Equal(t, "2025-01-01T00:00:00Z", consents[0].Expiry)
+				}
+				return err
+			},
+		},
+		{
+			Name:   "only the owner lists consents",
+			Caller: partner,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.GetConsents(ctx, "identity1")
+				return err
+			},
+			Err: "identity identity1 is owned by Org1MSP",
+		},
+		{
+			Name:   "list consents by page",
+			Caller: registrar,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				page, err := s.GetConsentsPaginated(ctx, "identity1", 10, "")
+				if err == nil {
+					require.Len(t, page.Consents, 1)
+					require.Equal(t, "Org2MSP", page.Consents[0].GranteeMSP)
+					require.Empty(t, page.Bookmark)
+				}
+				return err
+			},
+		},
+		{
+			Name:   "only the owner lists consents by page",
+			Caller: partner,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.GetConsentsPaginated(ctx, "identity1", 10, "")
+				return err
+			},
+			Err: "identity identity1 is owned by Org1MSP",
+		},
+		{
+			Name:   "list consents by empty page",
+			Caller: registrar,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.GetConsentsPaginated(ctx, "identity1", 0, "")
+				return err
+			},
+			Err: "pageSize must be between 1 and 100",
+		},
+		{Name: "revoke consent", Caller: registrar, Run: revoke("Org2MSP")},
+		{Name: "revoked consent no longer applies", Caller: partner, Run: readIdentity("identity1", nil), Err: "not authorized to read identity identity1"},
+		{Name: "revoke twice", Caller: registrar, Run: revoke("Org2MSP"), Err: "no active read consent for Org2MSP on identity identity1"},
+		{Name: "revoke missing consent", Caller: registrar, Run: revoke("Org3MSP"), Err: "no active read consent for Org3MSP"},
+		{Name: "grant short consent", Caller: registrar, Run: grant("Org2MSP", ConsentScopeRead, ledger.Now().Add(time.Hour).Format(time.RFC3339))},
+	})
+
+	ledger.Advance(2 * time.Hour)
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "expired consent no longer applies", Caller: partner, Run: readIdentity("identity1", nil), Err: "not authorized to read identity identity1"},
+	})
+}
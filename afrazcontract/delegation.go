@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"authz"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+	"timelock"
+)
+
+const (
+	// delegationObjectType prefixes the composite keys of delegations granted on an identity
+	delegationObjectType = "delegation"
+
+	// DelegationScopeView lets the delegate read the full identity record
+	DelegationScopeView = "view"
+
+	// DelegationScopeApply lets the delegate update the identity and attach documents on the subject's behalf
+	DelegationScopeApply = "apply"
+)
+
+var delegationRepo = repository.New[Delegation](delegationObjectType)
+
+// validDelegationScopes lists the scopes a subject may delegate
+var validDelegationScopes = map[string]bool{
+	DelegationScopeView:  true,
+	DelegationScopeApply: true,
+}
+
+// Delegation records a subject's permission for an individual, such as their relationship manager,
+// to act on their identity. The delegate is identified by the enrollment ID in their certificate.
+type Delegation struct {
+	IdentityID           string `json:"identityId"`
+	DelegateEnrollmentID string `json:"delegateEnrollmentId"`
+	Scope                string `json:"scope"`
+	Expiry               string `json:"expiry"`
+	GrantedAt            string `json:"grantedAt"`
+	Revoked              bool   `json:"revoked"`
+	RevokedAt            string `json:"revokedAt"`
+}
+
+// GrantDelegatedAccess allows the delegate to act on an identity for the given scope until expiry.
+// Only the organization owning the identity may grant it on the subject's behalf.
+func (s *SmartContract) GrantDelegatedAccess(ctx contractapi.TransactionContextInterface, identityID string, delegateEnrollmentID string, scope string, expiry string) error {
+	identity, err := s.getIdentity(ctx, identityID)
+	if err != nil {
+		return err
+	}
+	err = requireOwner(ctx, identity)
+	if err != nil {
+		return err
+	}
+	if delegateEnrollmentID == "" {
+		return fmt.Errorf("delegate enrollment ID must not be empty")
+	}
+	if !validDelegationScopes[scope] {
+		return fmt.Errorf("unknown delegation scope %s", scope)
+	}
+
+	expiresAt, err := parseDate(expiry)
+	if err != nil {
+		return err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	if !expiresAt.After(now) {
+		return fmt.Errorf("delegation expiry %s must be in the future", expiry)
+	}
+
+	delegation := Delegation{
+		IdentityID:           identity.ID,
+		DelegateEnrollmentID: delegateEnrollmentID,
+		Scope:                scope,
+		Expiry:               expiresAt.Format(time.RFC3339),
+		GrantedAt:            now.Format(time.RFC3339),
+	}
+
+	return putDelegation(ctx, &delegation)
+}
+
+// RevokeDelegatedAccess withdraws a delegation with immediate effect. The record is kept for audit.
+func (s *SmartContract) RevokeDelegatedAccess(ctx contractapi.TransactionContextInterface, identityID string, delegateEnrollmentID string, scope string) error {
+	identity, err := s.getIdentity(ctx, identityID)
+	if err != nil {
+		return err
+	}
+	err = requireOwner(ctx, identity)
+	if err != nil {
+		return err
+	}
+
+	delegation, err := getDelegation(ctx, identity.ID, delegateEnrollmentID, scope)
+	if err != nil {
+		return err
+	}
+	if delegation == nil || delegation.Revoked {
+		return fmt.Errorf("no active %s delegation for %s on identity %s", scope, delegateEnrollmentID, identityID)
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	delegation.Revoked = true
+	delegation.RevokedAt = now.Format(time.RFC3339)
+
+	return putDelegation(ctx, delegation)
+}
+
+// GetDelegations returns every delegation granted on an identity, including revoked and expired ones.
+// Only the organization owning the identity may list them.
+func (s *SmartContract) GetDelegations(ctx contractapi.TransactionContextInterface, identityID string) ([]*Delegation, error) {
+	identity, err := s.getIdentity(ctx, identityID)
+	if err != nil {
+		return nil, err
+	}
+	err = requireOwner(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	return delegationRepo.List(ctx, identity.ID)
+}
+
+// authorizeWrite returns an error unless the caller belongs to the owning organization or holds an
+// active apply delegation. It reports whether the write is made by a delegate.
+func authorizeWrite(ctx contractapi.TransactionContextInterface, identity *Identity) (bool, error) {
+	if requireOwner(ctx, identity) == nil {
+		return false, nil
+	}
+
+	delegated, err := isDelegate(ctx, identity.ID, DelegationScopeApply)
+	if err != nil {
+		return false, err
+	}
+	if !delegated {
+		return false, fmt.Errorf("submitting client not authorized, identity %s is owned by %s and no delegation is active", identity.ID, identity.OwnerMSP)
+	}
+
+	return true, nil
+}
+
+// isDelegate reports whether the caller's enrollment ID holds an active delegation for the scope
+func isDelegate(ctx contractapi.TransactionContextInterface, identityID string, scope string) (bool, error) {
+	enrollmentID, found, err := authz.Attribute(ctx, "hf.EnrollmentID")
+	if err != nil {
+		return false, fmt.Errorf("failed to read client enrollment ID: %v", err)
+	}
+	if !found || enrollmentID == "" {
+		return false, nil
+	}
+
+	delegation, err := getDelegation(ctx, identityID, enrollmentID, scope)
+	if err != nil {
+		return false, err
+	}
+	if delegation == nil || delegation.Revoked {
+		return false, nil
+	}
+
+	expired, err := timelock.Passed(ctx, delegation.Expiry)
+	if err != nil {
+		return false, fmt.Errorf("delegation on identity %s has an invalid expiry: %v", identityID, err)
+	}
+
+	return !expired, nil
+}
+
+func getDelegation(ctx contractapi.TransactionContextInterface, identityID string, delegateEnrollmentID string, scope string) (*Delegation, error) {
+	return delegationRepo.Get(ctx, identityID, delegateEnrollmentID, scope)
+}
+
+func putDelegation(ctx contractapi.TransactionContextInterface, delegation *Delegation) error {
+	return delegationRepo.Put(ctx, delegation, delegation.IdentityID, delegation.DelegateEnrollmentID, delegation.Scope)
+}
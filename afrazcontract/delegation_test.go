@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDelegations(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	grant := func(enrollmentID string, scope string, expiry string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.GrantDelegatedAccess(ctx, "identity1", enrollmentID, scope, expiry)
+		}
+	}
+	revoke := func(enrollmentID string, scope string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.RevokeDelegatedAccess(ctx, "identity1", enrollmentID, scope)
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "others cannot update", Caller: manager, Run: updateIdentity(manager, "identity1", "03001111111", "").Run, Err: "no delegation is active"},
+		{Name: "delegate apply access", Caller: registrar, Run: grant("manager1", DelegationScopeApply, "2025-01-01")},
+		updateIdentity(manager, "identity1", "03001111111", ""),
+		{
+			Name:   "delegated updates are marked",
+			Caller: registrar,
+			Run: readIdentity("identity1", func(t *testing.T, identity *Identity) {
+				require.Equal(t, "03001111111", identity.MobileNumber)
+				require.Equal(t, "manager1", identity.UpdatedBy)
+				require.Equal(t, "Org2MSP", identity.UpdatedByMSP)
+				require.True(t, identity.UpdatedByDelegate)
+			}),
+		},
+		{Name: "apply access does not allow reads", Caller: manager, Run: readIdentity("identity1", nil), Err: "not authorized to read identity identity1"},
+		{Name: "delegate view access", Caller: registrar, Run: grant("manager1", DelegationScopeView, "2025-01-01")},
+		{Name: "view access allows reads", Caller: manager, Run: readIdentity("identity1", nil)},
+		{Name: "only the owner delegates", Caller: manager, Run: grant("partner1", DelegationScopeView, "2025-01-01"), Err: "identity identity1 is owned by Org1MSP"},
+		{Name: "delegate without delegate", Caller: registrar, Run: grant("", DelegationScopeView, "2025-01-01"), Err: "delegate enrollment ID must not be empty"},
+		{Name: "delegate unknown scope", Caller: registrar, Run: grant("manager1", "close", "2025-01-01"), Err: "unknown delegation scope close"},
+		{Name: "delegate with invalid expiry", Caller: registrar, Run: grant("manager1", DelegationScopeView, "soon"), Err: `invalid date "soon"`},
+		{Name: "delegate expired access", Caller: registrar, Run: grant("manager1", DelegationScopeView, "01-12-2023"), Err: "delegation expiry 01-12-2023 must be in the future"},
+		{
+			Name:   "list delegations",
+			Caller: registrar,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				delegations, err := s.GetDelegations(ctx, "identity1")
+				if err == nil {
+					require.Len(t, delegations, 2)
+					require.Equal(t, DelegationScopeApply, delegations[0].Scope)
+					require.Equal(t, DelegationScopeView, delegations[1].Scope)
+				}
+				return err
+			},
+		},
+		{
+			Name:   "only the owner lists delegations",
+			Caller: manager,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.GetDelegations(ctx, "identity1")
+				return err
+			},
+			Err: "identity identity1 is owned by Org1MSP",
+		},
+		{Name: "revoke apply access", Caller: registrar, Run: revoke("manager1", DelegationScopeApply)},
+		{Name: "revoked delegates cannot update", Caller: manager, Run: updateIdentity(manager, "identity1", "03002222222", "").Run, Err: "no delegation is active"},
+		{Name: "revoke twice", Caller: registrar, Run: revoke("manager1", DelegationScopeApply), Err: "no active apply delegation for manager1 on identity identity1"},
+		{Name: "revoke missing delegation", Caller: registrar, Run: revoke("partner1", DelegationScopeView), Err: "no active view delegation for partner1"},
+	})
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"authz"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+// documentObjectType prefixes the composite keys of documents anchored to an identity
+const documentObjectType = "document"
+
+var documentRepo = repository.New[Document](documentObjectType)
+
+// Document anchors the hash of an off-chain identity document, such as a passport or CNIC scan
+type Document struct {
+	IdentityID    string `json:"identityId"`
+	DocType       string `json:"docType"`
+	SHA256        string `json:"sha256"`
+	URI           string `json:"uri"`
+	AttachedAt    string `json:"attachedAt"`
+	AttachedByMSP string `json:"attachedByMSP"`
+	ByDelegate    bool   `json:"byDelegate"`
+}
+
+// AttachDocument records the SHA-256 hash and storage location of a document belonging to an identity.
+// Callers outside the owning organization need an active apply delegation.
+func (s *SmartContract) AttachDocument(ctx contractapi.TransactionContextInterface, id string, docType string, sha256Hash string, uri string) error {
+	identity, err := s.getIdentity(ctx, id)
+	if err != nil {
+		return err
+	}
+	delegated, err := authorizeWrite(ctx, identity)
+	if err != nil {
+		return err
+	}
+	id = identity.ID
+	if docType == "" {
+		return fmt.Errorf("document type must not be empty")
+	}
+	if uri == "" {
+		return fmt.Errorf("document uri must not be empty")
+	}
+
+	sha256Hash = strings.ToLower(sha256Hash)
+	decoded, err := hex.DecodeString(sha256Hash)
+	if err != nil || len(decoded) != 32 {
+		return fmt.Errorf("document hash must be a hex encoded SHA-256 digest")
+	}
+
+	exists, err := documentRepo.Exists(ctx, id, docType, sha256Hash)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("the %s document %s is already attached to identity %s", docType, sha256Hash, id)
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	mspID, err := authz.CallerMSP(ctx)
+	if err != nil {
+		return err
+	}
+
+	document := Document{
+		IdentityID:    id,
+		DocType:       docType,
+		SHA256:        sha256Hash,
+		URI:           uri,
+		AttachedAt:    now.Format(time.RFC3339),
+		AttachedByMSP: mspID,
+		ByDelegate:    delegated,
+	}
+
+	return documentRepo.Put(ctx, &document, id, docType, sha256Hash)
+}
+
+// GetDocuments returns all documents anchored to an identity
+func (s *SmartContract) GetDocuments(ctx contractapi.TransactionContextInterface, id string) ([]*Document, error) {
+	return documentRepo.List(ctx, id)
+}
+
+// deleteDocuments removes every document anchored to an identity
+func deleteDocuments(ctx contractapi.TransactionContextInterface, id string) error {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(documentObjectType, []string{id})
+	if err != nil {
+		return err
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return err
+		}
+		err = ctx.GetStub().DelState(queryResponse.Key)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
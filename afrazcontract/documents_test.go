@@ -0,0 +1,55 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocuments(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	const passportHash = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	attach := func(docType string, hash string, uri string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.AttachDocument(ctx, "identity1", docType, hash, uri)
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "attach passport scan", Caller: registrar, Run: attach("passport", passportHash, "s3://kyc/identity1/passport.pdf")},
+		{Name: "attach twice", Caller: registrar, Run: attach("passport", strings.ToUpper(passportHash), "s3://kyc/identity1/copy.pdf"), Err: "is already attached to identity identity1"},
+		{Name: "attach without type", Caller: registrar, Run: attach("", passportHash, "s3://kyc/identity1/scan.pdf"), Err: "document type must not be empty"},
+		{Name: "attach without uri", Caller: registrar, Run: attach("cnic", passportHash, ""), Err: "document uri must not be empty"},
+		{Name: "attach with a short hash", Caller: registrar, Run: attach("cnic", "9f86d081", "s3://kyc/identity1/cnic.pdf"), Err: "document hash must be a hex encoded SHA-256 digest"},
+		{Name: "other organizations need a delegation", Caller: manager, Run: attach("cnic", passportHash, "s3://kyc/identity1/cnic.pdf"), Err: "no delegation is active"},
+		{
+			Name:   "delegate apply access",
+			Caller: registrar,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.GrantDelegatedAccess(ctx, "identity1", "manager1", DelegationScopeApply, "2025-01-01")
+			},
+		},
+		{Name: "delegates attach documents", Caller: manager, Run: attach("cnic", passportHash, "s3://kyc/identity1/cnic.pdf")},
+		{
+			Name:   "list documents",
+			Caller: partner,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				documents, err := s.GetDocuments(ctx, "identity1")
+				if err == nil {
+					require.Len(t, documents, 2)
+					require.Equal(t, "cnic", documents[0].DocType)
+					require.Equal(t, "Org2MSP", documents[0].AttachedByMSP)
+					require.True(t, documents[0].ByDelegate)
+					require.Equal(t, "passport", documents[1].DocType)
+					require.False(t, documents[1].ByDelegate)
+				}
+				return err
+			},
+		},
+	})
+}
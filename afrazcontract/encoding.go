@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	// responseEncodingKey is the transient key with which a client asks for an encoded response
+	responseEncodingKey = "response_encoding"
+
+	// gzipEncoding is the gzip compressed, base64 encoded JSON response encoding
+	gzipEncoding = "gzip"
+)
+
+// responseEncoding returns the response encoding the client requested in the transient map, or an
+// empty string for plain JSON
+func responseEncoding(ctx contractapi.TransactionContextInterface) (string, error) {
+	transientMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return "", fmt.Errorf("error getting transient: %v", err)
+	}
+
+	encoding := string(transientMap[responseEncodingKey])
+	switch encoding {
+	case "", gzipEncoding:
+		return encoding, nil
+	}
+
+	return "", fmt.Errorf("unsupported response encoding %q, expected %s", encoding, gzipEncoding)
+}
+
+// encodeRecords marshals records to JSON, gzip compresses them and base64 encodes the result
+func encodeRecords(records interface{}) (string, error) {
+	recordsJSON, err := json.Marshal(records)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	_, err = writer.Write(recordsJSON)
+	if err != nil {
+		return "", fmt.Errorf("failed to compress response: %v", err)
+	}
+	err = writer.Close()
+	if err != nil {
+		return "", fmt.Errorf("failed to compress response: %v", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"authz"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+// erasureObjectType prefixes the composite keys of erasure tombstones
+const erasureObjectType = "erasure"
+
+var erasureRepo = repository.New[ErasureTombstone](erasureObjectType)
+
+// ErasureTombstone records that the personal data of an identity was erased. It replaces the
+// identity in the world state and holds no personal details itself.
+type ErasureTombstone struct {
+	IdentityID  string `json:"identityId"`
+	OwnerMSP    string `json:"ownerMSP"`
+	Reason      string `json:"reason"`
+	ErasedAt    string `json:"erasedAt"`
+	ErasedByMSP string `json:"erasedByMSP"`
+}
+
+// EraseIdentityPII honours a right-to-erasure request. The personal data of the identity, every
+// version of it, its NIC index entries and its biometric bindings live in the private collection
+// and are purged, so that no peer keeps them in its private data history. The public record,
+// documents, address history and relatives are deleted from the world state; earlier versions of
+// those remain in the blocks, as does the personal data of identities written before it moved to
+// the private collection. A tombstone with the reason is kept in its place, and ReadIdentity,
+// GetIdentityHistory and GetFieldHistory refuse the identity from then on. Restricted to
+// registrars of the owning organization.
+func (s *SmartContract) EraseIdentityPII(ctx contractapi.TransactionContextInterface, id string, reason string) error {
+	if reason == "" {
+		return fmt.Errorf("erasure reason must not be empty")
+	}
+
+	// a deleted identity is erased together with its deletion tombstone
+	identity, err := deletedIdentityRepo.Get(ctx, identityAssetType, id)
+	if err != nil {
+		return err
+	}
+	if identity != nil {
+		err = deletedIdentityRepo.Delete(ctx, identityAssetType, id)
+		if err == nil {
+			err = loadPersonalData(ctx, identity)
+		}
+	} else {
+		identity, err = s.getIdentity(ctx, id)
+	}
+	if err != nil {
+		return err
+	}
+	err = requireOwner(ctx, identity)
+	if err != nil {
+		return err
+	}
+	_, err = registrarClearance(ctx)
+	if err != nil {
+		return err
+	}
+
+	for biometricType := range biometricTypes {
+		bindingKey, err := ctx.GetStub().CreateCompositeKey(biometricObjectType, []string{identity.ID, biometricType})
+		if err != nil {
+			return fmt.Errorf("failed to create composite key: %v", err)
+		}
+		err = ctx.GetStub().PurgePrivateData(identityPrivateCollection, bindingKey)
+		if err != nil {
+			return fmt.Errorf("failed to purge private data: %v", err)
+		}
+	}
+
+	err = deleteDocuments(ctx, identity.ID)
+	if err != nil {
+		return err
+	}
+	err = s.removeIdentity(ctx, identity)
+	if err != nil {
+		return err
+	}
+
+	// purged after removeIdentity deleted them, a later delete of a key would drop its purge
+	err = purgePersonalData(ctx, identity.ID)
+	if err != nil {
+		return err
+	}
+	nicKeys, err := nicIndexKeys(ctx, identity)
+	if err != nil {
+		return err
+	}
+	for _, key := range nicKeys {
+		err = ctx.GetStub().PurgePrivateData(identityPrivateCollection, key)
+		if err != nil {
+			return fmt.Errorf("failed to purge private data: %v", err)
+		}
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	mspID, err := authz.CallerMSP(ctx)
+	if err != nil {
+		return err
+	}
+
+	tombstone := ErasureTombstone{
+		IdentityID:  identity.ID,
+		OwnerMSP:    identity.OwnerMSP,
+		Reason:      reason,
+		ErasedAt:    now.Format(time.RFC3339),
+		ErasedByMSP: mspID,
+	}
+	tombstoneKey, err := ctx.GetStub().CreateCompositeKey(erasureObjectType, []string{identity.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	tombstoneJSON, err := json.Marshal(tombstone)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().PutState(tombstoneKey, tombstoneJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put to world state: %v", err)
+	}
+
+	return emitIdentityEvent(ctx, EventIdentityErased, IdentityEvent{IdentityID: identity.ID, OwnerMSP: identity.OwnerMSP})
+}
+
+// GetErasureRecord returns the erasure tombstone of an identity
+func (s *SmartContract) GetErasureRecord(ctx contractapi.TransactionContextInterface, id string) (*ErasureTombstone, error) {
+	tombstone, err := getErasureTombstone(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if tombstone == nil {
+		return nil, fmt.Errorf("the identity %s has not been erased", id)
+	}
+
+	return tombstone, nil
+}
+
+func getErasureTombstone(ctx contractapi.TransactionContextInterface, id string) (*ErasureTombstone, error) {
+	return erasureRepo.Get(ctx, id)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErasure(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	erase := func(id string, reason string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			err := s.EraseIdentityPII(ctx, id, reason)
+			if err == nil {
+				name, _ := lastEvent(t, ctx)
+				require.Equal(t, EventIdentityErased, name)
+			}
+			return err
+		}
+	}
+	erasureRecord := func(id string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			tombstone, err := s.GetErasureRecord(ctx, id)
+			if err == nil {
+				require.Equal(t, "subject request", tombstone.Reason)
+				require.Equal(t, "Org1MSP", tombstone.ErasedByMSP)
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		createIdentity(registrar, "identity2", "54321-0987654-3"),
+		{
+			Name:   "link, bind and attach",
+			Caller: registrar,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				require.NoError(t, s.LinkRelative(ctx, "identity2", "identity1", "child"))
+				require.NoError(t, s.BindBiometric(ctx, "identity1", "face", "template-hash-1"))
+				return s.AttachDocument(ctx, "identity1", "cnic", "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08", "s3://kyc/identity1/cnic.pdf")
+			},
+		},
+		{
+			Name:   "personal data is kept off the world state",
+			Caller: registrar,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				identityJSON, err := ctx.GetStub().GetState(identityNamespace.Key("identity1"))
+				require.NoError(t, err)
+				require.NotContains(t, string(identityJSON), "12345-6789012-3")
+				require.NotContains(t, string(identityJSON), "John")
+
+				identity, err := s.ReadIdentity(ctx, "identity1")
+				require.NoError(t, err)
+				require.Equal(t, "12345-6789012-3", identity.CNIC)
+				return nil
+			},
+		},
+		{Name: "erase without reason", Caller: registrar, Run: erase("identity1", ""), Err: "erasure reason must not be empty"},
+		{Name: "only the owner erases", Caller: partner, Run: erase("identity1", "subject request"), Err: "identity identity1 is owned by Org1MSP"},
+		{Name: "only registrars erase", Caller: clerk, Run: erase("identity1", "subject request"), Err: "submitting client not authorized, requires the kycRegistrar attribute"},
+		{Name: "not erased yet", Caller: registrar, Run: erasureRecord("identity1"), Err: "the identity identity1 has not been erased"},
+		{Name: "erase identity", Caller: registrar, Run: erase("identity1", "subject request")},
+		{Name: "erasure record", Caller: partner, Run: erasureRecord("identity1")},
+		{Name: "erased identities cannot be read", Caller: registrar, Run: readIdentity("identity1", nil), Err: "the identity identity1 was erased on 2024-01-01T09:00:"},
+		{Name: "erase twice", Caller: registrar, Run: erase("identity1", "subject request"), Err: "was erased on"},
+		{
+			Name:   "erased IDs stay reserved",
+			Caller: registrar,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.CreateIdentity(ctx, "identity1", "Mr.", "John", "Doe", "12345-6789012-3", "01-01-1980", "Male", "03001234567")
+			},
+			Err: "the identity identity1 already exists",
+		},
+		{
+			Name:   "the history is withheld",
+			Caller: auditor,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.GetIdentityHistory(ctx, "identity1")
+				return err
+			},
+			Err: "the identity identity1 was erased on",
+		},
+		{
+			Name:   "personal data is removed",
+			Caller: registrar,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				documents, err := s.GetDocuments(ctx, "identity1")
+				require.NoError(t, err)
+				require.Empty(t, documents)
+
+				key, _ := ctx.GetStub().CreateCompositeKey(biometricObjectType, []string{"identity1", "face"})
+				binding, err := ctx.GetStub().GetPrivateData(identityPrivateCollection, key)
+				require.NoError(t, err)
+				require.Nil(t, binding)
+
+				for _, objectType := range []string{personalDataObjectType, personalDataVersionObjectType} {
+					resultsIterator, err := ctx.GetStub().GetPrivateDataByPartialCompositeKey(identityPrivateCollection, objectType, []string{"identity1"})
+					require.NoError(t, err)
+					require.False(t, resultsIterator.HasNext(), objectType)
+					resultsIterator.Close()
+				}
+
+				_, err = s.GetIdentityByNIC(ctx, "12345-6789012-3")
+				require.EqualError(t, err, "no identity registered under NIC 12345-6789012-3")
+				return nil
+			},
+		},
+		{
+			Name:   "relatives lose their links",
+			Caller: registrar,
+			Run: readIdentity("identity2", func(t *testing.T, identity *Identity) {
+				require.Equal(t, "0", identity.NoOfDependents)
+			}),
+		},
+		{
+			Name:   "delete identity2",
+			Caller: registrar,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.DeleteIdentity(ctx, "identity2", "closed")
+			},
+		},
+		{Name: "erase a deleted identity", Caller: registrar, Run: erase("identity2", "subject request")},
+		{
+			Name:   "erasure removes the deletion tombstone",
+			Caller: registrar,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				identities, err := s.GetAllIdentities(ctx, true)
+				if err == nil {
+					require.Empty(t, identities)
+				}
+				return err
+			},
+		},
+	})
+}
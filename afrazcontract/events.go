@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Chaincode event names emitted for downstream systems
+const (
+	EventIdentityCreated  = "IdentityCreated"
+	EventIdentityVerified = "IdentityVerified"
+	EventIdentityUpdated  = "IdentityUpdated"
+	EventConsentGranted   = "ConsentGranted"
+	EventIdentityErased   = "IdentityErased"
+)
+
+// IdentityEvent is the payload of identity chaincode events. It carries identifiers and
+// status only, never personal details, since events are visible to every channel member.
+type IdentityEvent struct {
+	IdentityID string `json:"identityId"`
+	OwnerMSP   string `json:"ownerMSP"`
+	KYCLevel   string `json:"kycLevel,omitempty"`
+	GranteeMSP string `json:"granteeMSP,omitempty"`
+	Scope      string `json:"scope,omitempty"`
+	TxID       string `json:"txId"`
+	Timestamp  string `json:"timestamp"`
+}
+
+// emitIdentityEvent sets the transaction's chaincode event. Fabric keeps a single event per
+// transaction, so each transaction function emits at most one.
+func emitIdentityEvent(ctx contractapi.TransactionContextInterface, name string, event IdentityEvent) error {
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	event.TxID = ctx.GetStub().GetTxID()
+	event.Timestamp = now.Format(time.RFC3339)
+
+	eventJSON, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().SetEvent(name, eventJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set event %s: %v", name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	// expiryIndex orders identity documents by expiry date, stored as YYYY-MM-DD so that
+	// iterating the index returns the earliest expiries first
+	expiryIndex = "expiry~docType~identity"
+
+	docTypeCNIC     = "cnic"
+	docTypePassport = "passport"
+)
+
+// ExpiringIdentity points at an identity whose CNIC or passport expires soon
+type ExpiringIdentity struct {
+	IdentityID string `json:"identityId"`
+	DocType    string `json:"docType"`
+	ExpiryDate string `json:"expiryDate"`
+}
+
+// UpdateDocumentExpiry sets the CNIC and passport expiry dates of an identity and keeps the expiry
+// index in step. An empty date leaves the current value unchanged.
+func (s *SmartContract) UpdateDocumentExpiry(ctx contractapi.TransactionContextInterface, id string, cnicExpiryDate string, passportExpiryDate string) error {
+	identity, err := s.getIdentity(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = requireOwner(ctx, identity)
+	if err != nil {
+		return err
+	}
+
+	for _, date := range []string{cnicExpiryDate, passportExpiryDate} {
+		if date == "" {
+			continue
+		}
+		_, err = parseDate(date)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = deleteExpiryIndex(ctx, identity)
+	if err != nil {
+		return err
+	}
+	if cnicExpiryDate != "" {
+		identity.CNICExpiryDate = cnicExpiryDate
+	}
+	if passportExpiryDate != "" {
+		identity.PassportExpiryDate = passportExpiryDate
+	}
+	err = putExpiryIndex(ctx, identity)
+	if err != nil {
+		return err
+	}
+
+	err = stampModifier(ctx, identity)
+	if err != nil {
+		return err
+	}
+	err = putIdentity(ctx, identity)
+	if err != nil {
+		return err
+	}
+
+	return emitIdentityEvent(ctx, EventIdentityUpdated, IdentityEvent{IdentityID: identity.ID, OwnerMSP: identity.OwnerMSP})
+}
+
+// GetExpiringIdentities returns the identities readable by the caller whose CNIC or passport
+// expires before the given date, earliest expiry first
+func (s *SmartContract) GetExpiringIdentities(ctx contractapi.TransactionContextInterface, beforeDate string) ([]*ExpiringIdentity, error) {
+	before, err := parseDate(beforeDate)
+	if err != nil {
+		return nil, err
+	}
+	cutoff := before.Format("2006-01-02")
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(expiryIndex, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var expiring []*ExpiringIdentity
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, err
+		}
+		if keyParts[0] >= cutoff {
+			break
+		}
+
+		identity, err := s.getIdentity(ctx, keyParts[2])
+		if err != nil {
+			return nil, err
+		}
+		allowed, err := s.canRead(ctx, identity)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			continue
+		}
+
+		expiring = append(expiring, &ExpiringIdentity{
+			IdentityID: keyParts[2],
+			DocType:    keyParts[1],
+			ExpiryDate: keyParts[0],
+		})
+	}
+
+	return expiring, nil
+}
+
+// expiryIndexKeys returns the index entries for the identity's currently set expiry dates
+func expiryIndexKeys(ctx contractapi.TransactionContextInterface, identity *Identity) ([]string, error) {
+	dates := [][2]string{
+		{docTypeCNIC, identity.CNICExpiryDate},
+		{docTypePassport, identity.PassportExpiryDate},
+	}
+
+	var keys []string
+	for _, entry := range dates {
+		docType, date := entry[0], entry[1]
+		if date == "" {
+			continue
+		}
+		expiresAt, err := parseDate(date)
+		if err != nil {
+			// dates written before validation was introduced are simply not indexed
+			continue
+		}
+
+		key, err := ctx.GetStub().CreateCompositeKey(expiryIndex, []string{expiresAt.Format("2006-01-02"), docType, identity.ID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create composite key: %v", err)
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+func putExpiryIndex(ctx contractapi.TransactionContextInterface, identity *Identity) error {
+	keys, err := expiryIndexKeys(ctx, identity)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		err = ctx.GetStub().PutState(key, []byte{0x00})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func deleteExpiryIndex(ctx contractapi.TransactionContextInterface, identity *Identity) error {
+	keys, err := expiryIndexKeys(ctx, identity)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		err = ctx.GetStub().DelState(key)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocumentExpiry(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	updateExpiry := func(id string, cnicExpiry string, passportExpiry string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.UpdateDocumentExpiry(ctx, id, cnicExpiry, passportExpiry)
+		}
+	}
+	expiring := func(before string, want ...string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			identities, err := s.GetExpiringIdentities(ctx, before)
+			if err == nil {
+				var expiries []string
+				for _, identity := range identities {
+					expiries = append(expiries, identity.ExpiryDate+" "+identity.DocType+" "+identity.IdentityID)
+				}
+				require.Equal(t, want, expiries)
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		createIdentity(registrar, "identity2", "54321-0987654-3"),
+		{Name: "set CNIC expiry", Caller: registrar, Run: updateExpiry("identity1", "2024-06-30", "")},
+		{Name: "set passport expiry", Caller: registrar, Run: updateExpiry("identity2", "", "15-03-2024")},
+		{Name: "empty dates keep the current expiry", Caller: registrar, Run: updateExpiry("identity2", "2031-01-31", "")},
+		{Name: "earliest expiries first", Caller: registrar, Run: expiring("2024-12-31", "2024-03-15 passport identity2", "2024-06-30 cnic identity1")},
+		{Name: "expiries before a date", Caller: registrar, Run: expiring("2024-04-01", "2024-03-15 passport identity2")},
+		{Name: "renew CNIC", Caller: registrar, Run: updateExpiry("identity1", "2034-06-30", "")},
+		{
+			Name:   "renewals are kept",
+			Caller: registrar,
+			Run: readIdentity("identity1", func(t *testing.T, identity *Identity) {
+				require.Equal(t, "2034-06-30", identity.CNICExpiryDate)
+			}),
+		},
+		{Name: "renewals are reindexed", Caller: registrar, Run: expiring("2032-01-01", "2024-03-15 passport identity2", "2031-01-31 cnic identity2")},
+		{Name: "other organizations see none", Caller: partner, Run: expiring("2040-01-01")},
+		{Name: "expiries before an invalid date", Caller: registrar, Run: expiring("someday"), Err: `invalid date "someday"`},
+		{Name: "set an invalid expiry", Caller: registrar, Run: updateExpiry("identity1", "", "2024-02-30"), Err: `invalid date "2024-02-30"`},
+		{Name: "only the owner sets expiries", Caller: partner, Run: updateExpiry("identity1", "2030-01-01", ""), Err: "identity identity1 is owned by Org1MSP"},
+		{Name: "set expiry of a missing identity", Caller: registrar, Run: updateExpiry("identity9", "2030-01-01", ""), Err: "the identity identity9 does not exist"},
+	})
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	// relativeObjectType prefixes the composite keys of family links, one per direction
+	relativeObjectType = "relative"
+
+	// maxFamilyTreeDepth bounds the traversal done by GetFamilyTree
+	maxFamilyTreeDepth = 5
+)
+
+// inverseRelations maps each relation to the relation recorded in the opposite direction
+var inverseRelations = map[string]string{
+	"spouse":    "spouse",
+	"parent":    "child",
+	"child":     "parent",
+	"sibling":   "sibling",
+	"guardian":  "dependent",
+	"dependent": "guardian",
+}
+
+// dependentRelations are the relations counted in an identity's NoOfDependents
+var dependentRelations = map[string]bool{
+	"child":     true,
+	"dependent": true,
+}
+
+// RelativeLink states that RelativeID is the Relation of IdentityID, found Depth links away
+// from the root of a family tree query
+type RelativeLink struct {
+	IdentityID string `json:"identityId"`
+	RelativeID string `json:"relativeId"`
+	Relation   string `json:"relation"`
+	Depth      int    `json:"depth"`
+}
+
+// LinkRelative records that relativeID is the given relation of id, for example LinkRelative(a, b, "child")
+// when b is a's child, together with the inverse link. NoOfDependents of both identities is recomputed
+// from their child and dependent links. The submitting organization must own both identities.
+func (s *SmartContract) LinkRelative(ctx contractapi.TransactionContextInterface, id string, relativeID string, relation string) error {
+	inverse, ok := inverseRelations[relation]
+	if !ok {
+		return fmt.Errorf("unknown relation %s", relation)
+	}
+
+	identity, err := s.getIdentity(ctx, id)
+	if err != nil {
+		return err
+	}
+	relative, err := s.getIdentity(ctx, relativeID)
+	if err != nil {
+		return err
+	}
+	if identity.ID == relative.ID {
+		return fmt.Errorf("identity %s cannot be its own relative", identity.ID)
+	}
+	err = requireOwner(ctx, identity)
+	if err != nil {
+		return err
+	}
+	err = requireOwner(ctx, relative)
+	if err != nil {
+		return err
+	}
+
+	err = putRelativeLink(ctx, identity.ID, relative.ID, relation)
+	if err != nil {
+		return err
+	}
+	err = putRelativeLink(ctx, relative.ID, identity.ID, inverse)
+	if err != nil {
+		return err
+	}
+
+	err = saveDependents(ctx, relative, map[string]string{identity.ID: inverse})
+	if err != nil {
+		return err
+	}
+	err = saveDependents(ctx, identity, map[string]string{relative.ID: relation})
+	if err != nil {
+		return err
+	}
+
+	return emitIdentityEvent(ctx, EventIdentityUpdated, IdentityEvent{IdentityID: identity.ID, OwnerMSP: identity.OwnerMSP})
+}
+
+// GetFamilyTree returns the family links reachable from an identity within depth steps, walking
+// only through identities the caller may read
+func (s *SmartContract) GetFamilyTree(ctx contractapi.TransactionContextInterface, id string, depth int) ([]*RelativeLink, error) {
+	if depth <= 0 || depth > maxFamilyTreeDepth {
+		return nil, fmt.Errorf("depth must be between 1 and %d", maxFamilyTreeDepth)
+	}
+
+	root, err := s.ReadIdentity(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var tree []*RelativeLink
+	visited := map[string]bool{root.ID: true}
+	frontier := []string{root.ID}
+	for level := 1; level <= depth && len(frontier) > 0; level++ {
+		var next []string
+		for _, memberID := range frontier {
+			links, err := relativeLinks(ctx, memberID)
+			if err != nil {
+				return nil, err
+			}
+			for _, link := range links {
+				if visited[link.RelativeID] {
+					continue
+				}
+				relative, err := s.getIdentity(ctx, link.RelativeID)
+				if err != nil {
+					return nil, err
+				}
+				allowed, err := s.canRead(ctx, relative)
+				if err != nil {
+					return nil, err
+				}
+				if !allowed {
+					continue
+				}
+
+				visited[link.RelativeID] = true
+				link.Depth = level
+				tree = append(tree, link)
+				next = append(next, link.RelativeID)
+			}
+		}
+		frontier = next
+	}
+
+	return tree, nil
+}
+
+// saveDependents recomputes NoOfDependents of an identity from its family links and writes the
+// identity. Links written earlier in the same transaction are not visible to range queries, so
+// they are passed as overrides keyed by relative ID, where an empty relation means removed.
+func saveDependents(ctx contractapi.TransactionContextInterface, identity *Identity, overrides map[string]string) error {
+	err := setDependents(ctx, identity, overrides)
+	if err != nil {
+		return err
+	}
+
+	err = stampModifier(ctx, identity)
+	if err != nil {
+		return err
+	}
+	return putIdentity(ctx, identity)
+}
+
+// setDependents sets NoOfDependents from the identity's family links and the given overrides
+func setDependents(ctx contractapi.TransactionContextInterface, identity *Identity, overrides map[string]string) error {
+	links, err := relativeLinks(ctx, identity.ID)
+	if err != nil {
+		return err
+	}
+
+	relations := make(map[string]string)
+	for _, link := range links {
+		relations[link.RelativeID] = link.Relation
+	}
+	for relativeID, relation := range overrides {
+		relations[relativeID] = relation
+	}
+
+	dependents := 0
+	for _, relation := range relations {
+		if dependentRelations[relation] {
+			dependents++
+		}
+	}
+	identity.NoOfDependents = strconv.Itoa(dependents)
+
+	return nil
+}
+
+// unlinkRelatives removes every family link of an identity in both directions and updates the
+// dependents of the former relatives
+func (s *SmartContract) unlinkRelatives(ctx contractapi.TransactionContextInterface, id string) error {
+	links, err := relativeLinks(ctx, id)
+	if err != nil {
+		return err
+	}
+	for _, link := range links {
+		err = deleteRelativeLink(ctx, link.IdentityID, link.RelativeID)
+		if err != nil {
+			return err
+		}
+		err = deleteRelativeLink(ctx, link.RelativeID, link.IdentityID)
+		if err != nil {
+			return err
+		}
+
+		relative, err := s.getIdentity(ctx, link.RelativeID)
+		if err != nil {
+			return err
+		}
+		err = saveDependents(ctx, relative, map[string]string{id: ""})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// relinkRelatives moves the family links of a merged duplicate onto the primary identity and
+// updates the relatives. The primary's NoOfDependents is set but the primary is not written.
+func (s *SmartContract) relinkRelatives(ctx contractapi.TransactionContextInterface, duplicate *Identity, primary *Identity) error {
+	links, err := relativeLinks(ctx, duplicate.ID)
+	if err != nil {
+		return err
+	}
+	if len(links) == 0 {
+		return nil
+	}
+
+	primaryOverrides := make(map[string]string)
+	for _, link := range links {
+		err = deleteRelativeLink(ctx, duplicate.ID, link.RelativeID)
+		if err != nil {
+			return err
+		}
+		err = deleteRelativeLink(ctx, link.RelativeID, duplicate.ID)
+		if err != nil {
+			return err
+		}
+		if link.RelativeID == primary.ID {
+			primaryOverrides[duplicate.ID] = ""
+			continue
+		}
+
+		inverse := inverseRelations[link.Relation]
+		err = putRelativeLink(ctx, primary.ID, link.RelativeID, link.Relation)
+		if err != nil {
+			return err
+		}
+		err = putRelativeLink(ctx, link.RelativeID, primary.ID, inverse)
+		if err != nil {
+			return err
+		}
+		primaryOverrides[link.RelativeID] = link.Relation
+
+		relative, err := s.getIdentity(ctx, link.RelativeID)
+		if err != nil {
+			return err
+		}
+		err = saveDependents(ctx, relative, map[string]string{duplicate.ID: "", primary.ID: inverse})
+		if err != nil {
+			return err
+		}
+	}
+
+	return setDependents(ctx, primary, primaryOverrides)
+}
+
+// relativeLinks returns the outgoing family links of an identity
+func relativeLinks(ctx contractapi.TransactionContextInterface, id string) ([]*RelativeLink, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(relativeObjectType, []string{id})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var links []*RelativeLink
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		links = append(links, &RelativeLink{
+			IdentityID: keyParts[0],
+			RelativeID: keyParts[1],
+			Relation:   string(queryResponse.Value),
+		})
+	}
+
+	return links, nil
+}
+
+func putRelativeLink(ctx contractapi.TransactionContextInterface, id string, relativeID string, relation string) error {
+	linkKey, err := ctx.GetStub().CreateCompositeKey(relativeObjectType, []string{id, relativeID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	return ctx.GetStub().PutState(linkKey, []byte(relation))
+}
+
+func deleteRelativeLink(ctx contractapi.TransactionContextInterface, id string, relativeID string) error {
+	linkKey, err := ctx.GetStub().CreateCompositeKey(relativeObjectType, []string{id, relativeID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	return ctx.GetStub().DelState(linkKey)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFamilyTree(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	link := func(id string, relativeID string, relation string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.LinkRelative(ctx, id, relativeID, relation)
+		}
+	}
+	tree := func(depth int, want ...string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			links, err := s.GetFamilyTree(ctx, "identity1", depth)
+			if err == nil {
+				var relatives []string
+				for _, link := range links {
+					relatives = append(relatives, fmt.Sprintf("%d %s %s of %s", link.Depth, link.RelativeID, link.Relation, link.IdentityID))
+				}
+				require.Equal(t, want, relatives)
+			}
+			return err
+		}
+	}
+	dependents := func(id string, want string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return readIdentity(id, func(t *testing.T, identity *Identity) {
+			require.Equal(t, want, identity.NoOfDependents)
+		})
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		createIdentity(registrar, "identity2", "54321-0987654-3"),
+		createIdentity(registrar, "identity3", "11111-2222222-3"),
+		createIdentity(partner, "identity4", "99999-8888888-7"),
+		{Name: "link child", Caller: registrar, Run: link("identity1", "identity2", "child")},
+		{Name: "link grandchild", Caller: registrar, Run: link("identity2", "identity3", "child")},
+		{Name: "link spouse", Caller: registrar, Run: link("identity3", "identity1", "spouse")},
+		{Name: "children are dependents", Caller: registrar, Run: dependents("identity1", "1")},
+		{Name: "parents are not", Caller: registrar, Run: dependents("identity3", "0")},
+		{Name: "direct relatives", Caller: registrar, Run: tree(1, "1 identity2 child of identity1", "1 identity3 spouse of identity1")},
+		{Name: "relatives are visited once", Caller: registrar, Run: tree(5, "1 identity2 child of identity1", "1 identity3 spouse of identity1")},
+		{Name: "other organizations need consent", Caller: partner, Run: tree(1), Err: "not authorized to read identity identity1"},
+		{Name: "depth too small", Caller: registrar, Run: tree(0), Err: "depth must be between 1 and 5"},
+		{Name: "depth too large", Caller: registrar, Run: tree(6), Err: "depth must be between 1 and 5"},
+		{Name: "unknown relation", Caller: registrar, Run: link("identity1", "identity2", "cousin"), Err: "unknown relation cousin"},
+		{Name: "link to itself", Caller: registrar, Run: link("identity1", "identity1", "sibling"), Err: "identity identity1 cannot be its own relative"},
+		{Name: "link to a missing identity", Caller: registrar, Run: link("identity1", "identity9", "sibling"), Err: "the identity identity9 does not exist"},
+		{Name: "link to another organization", Caller: registrar, Run: link("identity1", "identity4", "sibling"), Err: "identity identity4 is owned by Org2MSP"},
+		{
+			Name:   "deleting an identity unlinks it",
+			Caller: registrar,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.DeleteIdentity(ctx, "identity2", "closed")
+			},
+		},
+		{Name: "former parents lose a dependent", Caller: registrar, Run: dependents("identity1", "0")},
+		{Name: "remaining relatives", Caller: registrar, Run: tree(2, "1 identity3 spouse of identity1")},
+	})
+}
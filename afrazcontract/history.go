@@ -0,0 +1,256 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"authz"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// FieldChange describes a single identity field that changed between two versions
+type FieldChange struct {
+	Field    string `json:"field"`
+	OldValue string `json:"oldValue"`
+	NewValue string `json:"newValue"`
+}
+
+// IdentityHistoryRecord describes one version of an identity as recorded on the ledger
+type IdentityHistoryRecord struct {
+	TxID        string        `json:"txId"`
+	Timestamp   string        `json:"timestamp"`
+	IsDelete    bool          `json:"isDelete"`
+	ModifiedBy  string        `json:"modifiedBy"`
+	ModifierMSP string        `json:"modifierMSP"`
+	ByDelegate  bool          `json:"byDelegate"`
+	Changes     []FieldChange `json:"changes,omitempty" metadata:",optional"`
+	Identity    *Identity     `json:"identity,omitempty" metadata:",optional"`
+}
+
+// auditFields are bookkeeping fields that change on every write and are left out of diffs
+var auditFields = map[string]bool{
+	"updatedBy":         true,
+	"updatedByMSP":      true,
+	"updatedByDelegate": true,
+}
+
+// stampModifier records the submitting client's MSP and certificate common name on the identity.
+// Writes made by a delegate set UpdatedByDelegate afterwards.
+func stampModifier(ctx contractapi.TransactionContextInterface, identity *Identity) error {
+	client, err := authz.ClientOf(ctx)
+	if err != nil {
+		return err
+	}
+
+	identity.UpdatedByMSP = client.MSPID
+	identity.UpdatedBy = client.CommonName
+	identity.UpdatedByDelegate = false
+	return nil
+}
+
+// GetIdentityHistory returns every recorded version of an identity, oldest first, with the
+// modifying MSP and identity and the fields that changed relative to the previous version.
+// Each version is redacted like ReadIdentity, so changes to redacted fields are left out.
+func (s *SmartContract) GetIdentityHistory(ctx contractapi.TransactionContextInterface, id string) ([]*IdentityHistoryRecord, error) {
+	// the ledger history still holds erased personal data, so it is withheld after an erasure
+	tombstone, err := getErasureTombstone(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if tombstone != nil {
+		return nil, fmt.Errorf("the identity %s was erased on %s", id, tombstone.ErasedAt)
+	}
+
+	modifications, err := identityNamespace.History(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history for %s: %v", id, err)
+	}
+
+	var records []*IdentityHistoryRecord
+	for _, response := range modifications {
+		record := &IdentityHistoryRecord{
+			TxID:      response.TxId,
+			Timestamp: time.Unix(response.Timestamp.Seconds, int64(response.Timestamp.Nanos)).UTC().Format(time.RFC3339),
+			IsDelete:  response.IsDelete,
+		}
+		if !response.IsDelete && len(response.Value) > 0 {
+			var identity Identity
+			err = json.Unmarshal(response.Value, &identity)
+			if err != nil {
+				return nil, err
+			}
+			err = loadPersonalDataVersion(ctx, &identity, response.TxId)
+			if err != nil {
+				return nil, err
+			}
+			record.Identity = &identity
+			record.ModifiedBy = identity.UpdatedBy
+			record.ModifierMSP = identity.UpdatedByMSP
+			record.ByDelegate = identity.UpdatedByDelegate
+		}
+		records = append(records, record)
+	}
+
+	// the history comes most recent version first
+	for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+		records[i], records[j] = records[j], records[i]
+	}
+
+	for i := len(records) - 1; i >= 0; i-- {
+		if records[i].Identity != nil {
+			err = s.authorizeRead(ctx, records[i].Identity)
+			if err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+	for _, record := range records {
+		if record.Identity != nil {
+			err = identityRedaction.Apply(ctx, record.Identity)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var previous *Identity
+	for _, record := range records {
+		changes, err := diffIdentities(previous, record.Identity)
+		if err != nil {
+			return nil, err
+		}
+		record.Changes = changes
+		previous = record.Identity
+	}
+
+	return records, nil
+}
+
+// diffIdentities lists the fields that differ between two identity versions.
+// A nil version is treated as an identity with every field empty.
+func diffIdentities(before *Identity, after *Identity) ([]FieldChange, error) {
+	beforeFields, err := identityFields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := identityFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(afterFields))
+	for name := range beforeFields {
+		names = append(names, name)
+	}
+	for name := range afterFields {
+		if _, ok := beforeFields[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var changes []FieldChange
+	for _, name := range names {
+		if auditFields[name] || beforeFields[name] == afterFields[name] {
+			continue
+		}
+		changes = append(changes, FieldChange{
+			Field:    name,
+			OldValue: beforeFields[name],
+			NewValue: afterFields[name],
+		})
+	}
+
+	return changes, nil
+}
+
+// identityFields flattens an identity into its JSON field names and values
+func identityFields(identity *Identity) (map[string]string, error) {
+	fields := make(map[string]string)
+	if identity == nil {
+		return fields, nil
+	}
+
+	identityJSON, err := json.Marshal(identity)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]interface{}
+	err = json.Unmarshal(identityJSON, &raw)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, value := range raw {
+		fields[name] = fmt.Sprint(value)
+	}
+
+	return fields, nil
+}
+
+// FieldHistoryEntry is the value a single field held from the transaction TxID on. The entry of a
+// deleting transaction has an empty value.
+type FieldHistoryEntry struct {
+	TxID      string `json:"txId"`
+	Timestamp string `json:"timestamp"`
+	IsDelete  bool   `json:"isDelete"`
+	Value     string `json:"value"`
+}
+
+// GetFieldHistory returns the timeline of one field of an asset, oldest first, listing only the
+// versions in which the field changed. fieldPath is the field's JSON or Go name, for example
+// mobileNumber or MobileNumber. The only asset type of this contract is "identity", and the same
+// read authorization and erasure rules apply as for GetIdentityHistory.
+func (s *SmartContract) GetFieldHistory(ctx contractapi.TransactionContextInterface, assetType string, id string, fieldPath string) ([]*FieldHistoryEntry, error) {
+	if assetType != "identity" {
+		return nil, fmt.Errorf("unsupported asset type %q, expected identity", assetType)
+	}
+
+	fields, err := identityFields(&Identity{})
+	if err != nil {
+		return nil, err
+	}
+	field := ""
+	for name := range fields {
+		if strings.EqualFold(name, fieldPath) {
+			field = name
+		}
+	}
+	if field == "" {
+		return nil, fmt.Errorf("identities have no field %s", fieldPath)
+	}
+
+	records, err := s.GetIdentityHistory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*FieldHistoryEntry
+	for _, record := range records {
+		fields, err := identityFields(record.Identity)
+		if err != nil {
+			return nil, err
+		}
+
+		value := fields[field]
+		if len(entries) > 0 {
+			last := entries[len(entries)-1]
+			if !record.IsDelete && !last.IsDelete && last.Value == value {
+				continue
+			}
+		}
+		entries = append(entries, &FieldHistoryEntry{
+			TxID:      record.TxID,
+			Timestamp: record.Timestamp,
+			IsDelete:  record.IsDelete,
+			Value:     value,
+		})
+	}
+
+	return entries, nil
+}
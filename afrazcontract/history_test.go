@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"chaincodetest"
+	"chaincodetest/chaincodefakes"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestIdentityHistory(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	history := func(id string, check func(t *testing.T, records []*IdentityHistoryRecord)) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			records, err := s.GetIdentityHistory(ctx, id)
+			if err == nil {
+				check(t, records)
+			}
+			return err
+		}
+	}
+	fieldHistory := func(assetType string, fieldPath string, want ...string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			entries, err := s.GetFieldHistory(ctx, assetType, "identity1", fieldPath)
+			if err == nil {
+				var values []string
+				for _, entry := range entries {
+					values = append(values, entry.Value)
+				}
+				require.Equal(t, want, values)
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		updateIdentity(registrar, "identity1", "03009999999", ""),
+		updateIdentity(registrar, "identity1", "03009999999", "12 Mall Road, Lahore"),
+		{
+			Name:   "identity history",
+			Caller: auditor,
+			Run: history("identity1", func(t *testing.T, records []*IdentityHistoryRecord) {
+				require.Len(t, records, 3)
+				require.Equal(t, "tx1", records[0].TxID)
+				require.Equal(t, "2024-01-01T09:00:01Z", records[0].Timestamp)
+				require.Contains(t, records[0].Changes, FieldChange{Field: "firstName", NewValue: "John"})
+				require.Equal(t, []FieldChange{{Field: "mobileNumber", OldValue: "03001234567", NewValue: "03009999999"}}, records[1].Changes)
+				require.Equal(t, "registrar1", records[1].ModifiedBy)
+				require.Equal(t, "Org1MSP", records[1].ModifierMSP)
+				require.Equal(t, "12 Mall Road, Lahore", records[2].Identity.Address)
+			}),
+		},
+		{
+			Name:   "customers see the history redacted",
+			Caller: customer,
+			Run: history("identity1", func(t *testing.T, records []*IdentityHistoryRecord) {
+				require.Len(t, records, 3)
+				require.Equal(t, "***", records[1].Identity.MobileNumber)
+				require.Empty(t, records[1].Changes)
+				require.Equal(t, "***", records[2].Identity.Address)
+				require.Equal(t, "John", records[2].Identity.FirstName)
+			}),
+		},
+		{Name: "customers see no changes of redacted fields", Caller: customer, Run: fieldHistory("identity", "mobileNumber", "***")},
+		{Name: "other organizations need consent", Caller: partner, Run: history("identity1", nil), Err: "not authorized to read identity identity1"},
+		{
+			Name:   "history with a corrupt version",
+			Caller: auditor,
+			Stub: func(stub *chaincodefakes.ChaincodeStub) {
+				stub.GetHistoryForKeyReturns(chaincodetest.HistoryIterator(&queryresult.KeyModification{TxId: "tx9", Value: []byte("{"), Timestamp: timestamppb.Now()}), nil)
+			},
+			Run: history("identity1", nil),
+			Err: "unexpected end of JSON input",
+		},
+		{
+			Name:   "history unavailable",
+			Caller: auditor,
+			Stub: func(stub *chaincodefakes.ChaincodeStub) {
+				stub.GetHistoryForKeyReturns(nil, errors.New("history database disabled"))
+			},
+			Run: history("identity1", nil),
+			Err: "failed to read history for identity1: history database disabled",
+		},
+		{Name: "field history lists only changes", Caller: auditor, Run: fieldHistory("identity", "mobileNumber", "03001234567", "03009999999")},
+		{Name: "fields match by Go name", Caller: auditor, Run: fieldHistory("identity", "Address", "", "12 Mall Road, Lahore")},
+		{Name: "unsupported asset type", Caller: auditor, Run: fieldHistory("loan", "status"), Err: `unsupported asset type "loan", expected identity`},
+		{Name: "unknown field", Caller: auditor, Run: fieldHistory("identity", "shoeSize"), Err: "identities have no field shoeSize"},
+		{
+			Name:   "delete identity",
+			Caller: registrar,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.DeleteIdentity(ctx, "identity1", "closed")
+			},
+		},
+		{
+			Name:   "deletions are part of the history",
+			Caller: auditor,
+			Run: history("identity1", func(t *testing.T, records []*IdentityHistoryRecord) {
+				require.Len(t, records, 4)
+				require.True(t, records[3].IsDelete)
+				require.Nil(t, records[3].Identity)
+			}),
+		},
+	})
+}
@@ -3,8 +3,12 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 
+	"authz"
+	"ccaas"
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
 )
 
 // SmartContract provides functions for managing identities
@@ -47,8 +51,25 @@ type Identity struct {
 	ApartmentOrHouse    string `json:"apartmentOrHouse"`
 	ResidenceNature     string `json:"residenceNature"`
 	MobileNumber        string `json:"mobileNumber"`
+	KYCLevel            string `json:"kycLevel"`
+	RiskRating          string `json:"riskRating"`
+	SchemaVersion       string `json:"schemaVersion"`
+	OwnerMSP            string `json:"ownerMSP"`
+	UpdatedByMSP        string `json:"updatedByMSP"`
+	UpdatedBy           string `json:"updatedBy"`
+	UpdatedByDelegate   bool   `json:"updatedByDelegate"`
+	Deleted             bool   `json:"deleted,omitempty" metadata:",optional"`
+	DeletionReason      string `json:"deletionReason,omitempty" metadata:",optional"`
+	DeletedBy           string `json:"deletedBy,omitempty" metadata:",optional"`
+	DeletedAt           string `json:"deletedAt,omitempty" metadata:",optional"`
 }
 
+// identityNamespace prefixes the plain keys of identities, IDNT_id
+const identityNamespace repository.Namespace = "IDNT_"
+
+// deletedIdentityRepo keeps deleted identities under tombstone~identity~id until they are restored
+var deletedIdentityRepo = repository.New[Identity](repository.TombstoneObjectType)
+
 // InitLedger adds a base set of identities to the ledger
 func (s *SmartContract) InitLedger(ctx contractapi.TransactionContextInterface) error {
 	identities := []Identity{
@@ -61,18 +82,30 @@ func (s *SmartContract) InitLedger(ctx contractapi.TransactionContextInterface)
 			DateOfBirth: "01-01-1980",
 			Gender: "Male",
 			MobileNumber: "03001234567",
+			KYCLevel: KYCLevelBasic,
 		},
 	}
 
 	for _, identity := range identities {
-		identityJSON, err := json.Marshal(identity)
+		err := stampModifier(ctx, &identity)
 		if err != nil {
 			return err
 		}
+		identity.OwnerMSP = identity.UpdatedByMSP
 
-		err = ctx.GetStub().PutState(identity.ID, identityJSON)
+		err = putIdentity(ctx, &identity)
 		if err != nil {
-			return fmt.Errorf("failed to put to world state: %v", err)
+			return err
+		}
+
+		err = putNICIndex(ctx, &identity)
+		if err != nil {
+			return err
+		}
+
+		err = putKYCIndex(ctx, &identity)
+		if err != nil {
+			return err
 		}
 	}
 
@@ -98,22 +131,86 @@ func (s *SmartContract) CreateIdentity(ctx contractapi.TransactionContextInterfa
 		DateOfBirth: dob,
 		Gender:     gender,
 		MobileNumber: mobile,
+		KYCLevel:   KYCLevelBasic,
+	}
+	err = validateAgainstSchema(ctx, &identity)
+	if err != nil {
+		return err
+	}
+	err = stampModifier(ctx, &identity)
+	if err != nil {
+		return err
 	}
-	identityJSON, err := json.Marshal(identity)
+	identity.OwnerMSP = identity.UpdatedByMSP
+
+	err = putIdentity(ctx, &identity)
+	if err != nil {
+		return err
+	}
+
+	err = putNICIndex(ctx, &identity)
+	if err != nil {
+		return err
+	}
+
+	err = putKYCIndex(ctx, &identity)
 	if err != nil {
 		return err
 	}
 
-	return ctx.GetStub().PutState(id, identityJSON)
+	return emitIdentityEvent(ctx, EventIdentityCreated, IdentityEvent{IdentityID: id, OwnerMSP: identity.OwnerMSP, KYCLevel: identity.KYCLevel})
 }
 
 // ReadIdentity returns the identity stored in the world state with given id.
+// Callers outside the owning organization need an active read consent from the subject.
+// Personal data is redacted for customers, see identityRedaction.
 func (s *SmartContract) ReadIdentity(ctx contractapi.TransactionContextInterface, id string) (*Identity, error) {
-	identityJSON, err := ctx.GetStub().GetState(id)
+	identity, err := s.getIdentity(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.authorizeRead(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+	err = identityRedaction.Apply(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	return identity, nil
+}
+
+// getIdentity loads an identity from the world state without any access checks.
+// The ID of a merged duplicate resolves to its primary identity.
+func (s *SmartContract) getIdentity(ctx contractapi.TransactionContextInterface, id string) (*Identity, error) {
+	identityJSON, err := ctx.GetStub().GetState(identityNamespace.Key(id))
 	if err != nil {
 		return nil, fmt.Errorf("failed to read from world state: %v", err)
 	}
 	if identityJSON == nil {
+		primaryID, err := resolveAlias(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if primaryID != "" {
+			return s.getIdentity(ctx, primaryID)
+		}
+		tombstone, err := getErasureTombstone(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if tombstone != nil {
+			return nil, fmt.Errorf("the identity %s was erased on %s", id, tombstone.ErasedAt)
+		}
+		deleted, err := deletedIdentityRepo.Get(ctx, identityAssetType, id)
+		if err != nil {
+			return nil, err
+		}
+		if deleted != nil {
+			return nil, fmt.Errorf("the identity %s was deleted on %s", id, deleted.DeletedAt)
+		}
 		return nil, fmt.Errorf("the identity %s does not exist", id)
 	}
 
@@ -122,10 +219,43 @@ func (s *SmartContract) ReadIdentity(ctx contractapi.TransactionContextInterface
 	if err != nil {
 		return nil, err
 	}
+	err = loadPersonalData(ctx, &identity)
+	if err != nil {
+		return nil, err
+	}
 
 	return &identity, nil
 }
 
+// putIdentity writes an identity to the world state, its personal data to the private collection,
+// and moves it in the update-time index
+func putIdentity(ctx contractapi.TransactionContextInterface, identity *Identity) error {
+	identityJSON, personalJSON, err := splitPersonalData(identity)
+	if err != nil {
+		return err
+	}
+	err = putPersonalData(ctx, identity, personalJSON)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().PutState(identityNamespace.Key(identity.ID), identityJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put to world state: %v", err)
+	}
+
+	return repository.RecordChange(ctx, identityAssetType, identity.ID, false)
+}
+
+// delIdentity deletes an identity from the world state, leaving the deletion in the update-time index
+func delIdentity(ctx contractapi.TransactionContextInterface, id string) error {
+	err := repository.RecordChange(ctx, identityAssetType, id, true)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().DelState(identityNamespace.Key(id))
+}
+
 // UpdateIdentity updates an existing identity in the world state with provided parameters.
 func (s *SmartContract) UpdateIdentity(ctx contractapi.TransactionContextInterface, id string, mobile string, address string) error {
 	exists, err := s.IdentityExists(ctx, id)
@@ -137,7 +267,17 @@ func (s *SmartContract) UpdateIdentity(ctx contractapi.TransactionContextInterfa
 	}
 
 	// Get current identity
-	identity, err := s.ReadIdentity(ctx, id)
+	identity, err := s.getIdentity(ctx, id)
+	if err != nil {
+		return err
+	}
+	delegated, err := authorizeWrite(ctx, identity)
+	if err != nil {
+		return err
+	}
+
+	// Address changes are kept as versioned entries, the identity holds the current one
+	err = recordAddress(ctx, identity, address)
 	if err != nil {
 		return err
 	}
@@ -146,40 +286,196 @@ func (s *SmartContract) UpdateIdentity(ctx contractapi.TransactionContextInterfa
 	identity.MobileNumber = mobile
 	identity.Address = address
 
-	identityJSON, err := json.Marshal(identity)
+	err = stampModifier(ctx, identity)
 	if err != nil {
 		return err
 	}
+	identity.UpdatedByDelegate = delegated
 
-	return ctx.GetStub().PutState(id, identityJSON)
+	err = putIdentity(ctx, identity)
+	if err != nil {
+		return err
+	}
+
+	return emitIdentityEvent(ctx, EventIdentityUpdated, IdentityEvent{IdentityID: identity.ID, OwnerMSP: identity.OwnerMSP})
 }
 
-// DeleteIdentity deletes an given identity from the world state.
-func (s *SmartContract) DeleteIdentity(ctx contractapi.TransactionContextInterface, id string) error {
-	exists, err := s.IdentityExists(ctx, id)
+// DeleteIdentity soft deletes an identity. It is dropped from the indexes and listings and its
+// family links are dissolved, but the record and its address history are kept, flagged with the
+// reason and the deleting client, until RestoreIdentity brings it back. Owner only.
+func (s *SmartContract) DeleteIdentity(ctx contractapi.TransactionContextInterface, id string, reason string) error {
+	if reason == "" {
+		return fmt.Errorf("deletion reason must not be empty")
+	}
+	identity, err := s.getIdentity(ctx, id)
 	if err != nil {
 		return err
 	}
-	if !exists {
-		return fmt.Errorf("the identity %s does not exist", id)
+	err = requireOwner(ctx, identity)
+	if err != nil {
+		return err
+	}
+
+	err = deleteExpiryIndex(ctx, identity)
+	if err != nil {
+		return err
+	}
+	err = deleteNICIndex(ctx, identity)
+	if err != nil {
+		return err
+	}
+	err = deleteKYCIndex(ctx, identity)
+	if err != nil {
+		return err
+	}
+	err = s.unlinkRelatives(ctx, identity.ID)
+	if err != nil {
+		return err
+	}
+	err = delIdentity(ctx, identity.ID)
+	if err != nil {
+		return err
 	}
 
-	return ctx.GetStub().DelState(id)
+	client, err := authz.ClientOf(ctx)
+	if err != nil {
+		return err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	identity.Deleted = true
+	identity.DeletionReason = reason
+	identity.DeletedBy = client.CommonName
+	identity.DeletedAt = now.Format(time.RFC3339)
+
+	// the personal data stays in the private collection until the identity is restored or erased
+	identityJSON, _, err := splitPersonalData(identity)
+	if err != nil {
+		return err
+	}
+	var tombstone Identity
+	err = json.Unmarshal(identityJSON, &tombstone)
+	if err != nil {
+		return err
+	}
+
+	return deletedIdentityRepo.Put(ctx, &tombstone, identityAssetType, identity.ID)
+}
+
+// RestoreIdentity brings back a deleted identity and re-indexes it. Family links dissolved by
+// the deletion are not restored. Owner only.
+func (s *SmartContract) RestoreIdentity(ctx contractapi.TransactionContextInterface, id string) error {
+	identity, err := deletedIdentityRepo.Get(ctx, identityAssetType, id)
+	if err != nil {
+		return err
+	}
+	if identity == nil {
+		return fmt.Errorf("the identity %s is not deleted", id)
+	}
+	err = loadPersonalData(ctx, identity)
+	if err != nil {
+		return err
+	}
+	err = requireOwner(ctx, identity)
+	if err != nil {
+		return err
+	}
+
+	identity.Deleted = false
+	identity.DeletionReason = ""
+	identity.DeletedBy = ""
+	identity.DeletedAt = ""
+	err = stampModifier(ctx, identity)
+	if err != nil {
+		return err
+	}
+	err = putIdentity(ctx, identity)
+	if err != nil {
+		return err
+	}
+	err = putNICIndex(ctx, identity)
+	if err != nil {
+		return err
+	}
+	err = putKYCIndex(ctx, identity)
+	if err != nil {
+		return err
+	}
+	err = putExpiryIndex(ctx, identity)
+	if err != nil {
+		return err
+	}
+
+	return deletedIdentityRepo.Delete(ctx, identityAssetType, identity.ID)
+}
+
+// removeIdentity deletes the identity record together with its indexes, relatives and address history
+func (s *SmartContract) removeIdentity(ctx contractapi.TransactionContextInterface, identity *Identity) error {
+	err := deleteExpiryIndex(ctx, identity)
+	if err != nil {
+		return err
+	}
+
+	err = deleteNICIndex(ctx, identity)
+	if err != nil {
+		return err
+	}
+
+	err = deleteKYCIndex(ctx, identity)
+	if err != nil {
+		return err
+	}
+
+	err = s.unlinkRelatives(ctx, identity.ID)
+	if err != nil {
+		return err
+	}
+
+	err = deleteAddressHistory(ctx, identity.ID)
+	if err != nil {
+		return err
+	}
+
+	return delIdentity(ctx, identity.ID)
 }
 
-// IdentityExists returns true when identity with given ID exists in world state
+// IdentityExists returns true when identity with given ID exists in world state,
+// either as a record or as the alias of a merged duplicate
 func (s *SmartContract) IdentityExists(ctx contractapi.TransactionContextInterface, id string) (bool, error) {
-	identityJSON, err := ctx.GetStub().GetState(id)
+	identityJSON, err := ctx.GetStub().GetState(identityNamespace.Key(id))
 	if err != nil {
 		return false, fmt.Errorf("failed to read from world state: %v", err)
 	}
+	if identityJSON != nil {
+		return true, nil
+	}
 
-	return identityJSON != nil, nil
+	primaryID, err := resolveAlias(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	if primaryID != "" {
+		return true, nil
+	}
+
+	// erased and deleted IDs stay reserved so that a new identity cannot take over their history
+	tombstone, err := getErasureTombstone(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	if tombstone != nil {
+		return true, nil
+	}
+
+	return deletedIdentityRepo.Exists(ctx, identityAssetType, id)
 }
 
-// GetAllIdentities returns all identities found in world state
-func (s *SmartContract) GetAllIdentities(ctx contractapi.TransactionContextInterface) ([]*Identity, error) {
-	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
+// GetAllIdentities returns all identities found in world state that the caller may read, followed
+// by the deleted ones when includeDeleted is set
+func (s *SmartContract) GetAllIdentities(ctx contractapi.TransactionContextInterface, includeDeleted bool) ([]*Identity, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByRange(identityNamespace.Range(""))
 	if err != nil {
 		return nil, err
 	}
@@ -197,20 +493,91 @@ func (s *SmartContract) GetAllIdentities(ctx contractapi.TransactionContextInter
 		if err != nil {
 			return nil, err
 		}
-		identities = append(identities, &identity)
+		err = loadPersonalData(ctx, &identity)
+		if err != nil {
+			return nil, err
+		}
+
+		allowed, err := s.redactReadable(ctx, &identity)
+		if err != nil {
+			return nil, err
+		}
+		if allowed {
+			identities = append(identities, &identity)
+		}
+	}
+
+	if includeDeleted {
+		deleted, err := deletedIdentityRepo.List(ctx, identityAssetType)
+		if err != nil {
+			return nil, err
+		}
+		for _, identity := range deleted {
+			err = loadPersonalData(ctx, identity)
+			if err != nil {
+				return nil, err
+			}
+			allowed, err := s.redactReadable(ctx, identity)
+			if err != nil {
+				return nil, err
+			}
+			if allowed {
+				identities = append(identities, identity)
+			}
+		}
 	}
 
 	return identities, nil
 }
 
+// MigrateIdentityKeys moves up to limit identities from the plain IDs they were stored under
+// before identityNamespace to their IDNT_ keys and returns how many it moved. Submit it until it
+// moves none; identities not yet moved are invisible to the contract. Admin only.
+func (s *SmartContract) MigrateIdentityKeys(ctx contractapi.TransactionContextInterface, limit int) (int, error) {
+	err := requireAdmin(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return identityNamespace.Migrate(ctx, limit)
+}
+
+// GetAuditTrail returns up to pageSize entries of the audit trail, from the transaction startTx up
+// to but excluding endTx in transaction ID order. Evaluate it rather than submitting it.
+func (s *SmartContract) GetAuditTrail(ctx contractapi.TransactionContextInterface, startTx string, endTx string, pageSize int) (*authz.AuditPage, error) {
+	return authz.AuditTrail(ctx, startTx, endTx, pageSize)
+}
+
+// SetLogLevel changes the chaincode's log level until it restarts, after which
+// CORE_CHAINCODE_LOGGING_LEVEL applies again. Submit it so that every endorsing peer's chaincode
+// runs it. Admin only.
+func (s *SmartContract) SetLogLevel(ctx contractapi.TransactionContextInterface, level string) error {
+	err := requireAdmin(ctx)
+	if err != nil {
+		return err
+	}
+
+	return authz.SetLogLevel(level)
+}
+
+// audit is the BeforeTransaction handler of the contracts of the chaincode. Fabric refuses
+// paginated queries in a transaction that has written, so the functions running one are not audited.
+var audit = authz.Audit("GetIdentitiesPaginated", "GetConsentsPaginated", "GetAuditTrail", "GetChangesSince", "ExportState")
+
 func main() {
-	chaincode, err := contractapi.NewChaincode(&SmartContract{})
+	identityContract, schemaRegistry, referenceData := &SmartContract{}, &SchemaRegistry{}, &ReferenceData{}
+	for _, contract := range []*contractapi.Contract{&identityContract.Contract, &schemaRegistry.Contract, &referenceData.Contract} {
+		contract.TransactionContextHandler = new(authz.TransactionContext)
+		contract.BeforeTransaction = audit
+	}
+
+	chaincode, err := contractapi.NewChaincode(identityContract, schemaRegistry, referenceData)
 	if err != nil {
-		fmt.Printf("Error creating identity chaincode: %v", err)
+		authz.Logger.Error("failed to create identity chaincode", "error", err)
 		return
 	}
 
-	if err := chaincode.Start(); err != nil {
-		fmt.Printf("Error starting identity chaincode: %v", err)
+	if err := ccaas.Start(chaincode); err != nil {
+		authz.Logger.Error("failed to start identity chaincode", "error", err)
 	}
 }
\ No newline at end of file
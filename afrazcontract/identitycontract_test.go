@@ -0,0 +1,403 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"authz"
+	"chaincodetest"
+	"chaincodetest/chaincodefakes"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	registrar = chaincodetest.Identity{MSPID: "Org1MSP", CommonName: "registrar1", Attributes: map[string]string{registrarAttribute: KYCLevelFull}}
+	junior    = chaincodetest.Identity{MSPID: "Org1MSP", CommonName: "registrar2", Attributes: map[string]string{registrarAttribute: KYCLevelBasic}}
+	clerk     = chaincodetest.Identity{MSPID: "Org1MSP", CommonName: "clerk1"}
+	admin     = chaincodetest.Identity{MSPID: "Org1MSP", CommonName: "admin1", Attributes: map[string]string{"role": "admin"}}
+	customer  = chaincodetest.Identity{MSPID: "Org1MSP", CommonName: "customer1", Attributes: map[string]string{"role": "customer", registrarAttribute: KYCLevelBasic}}
+	auditor   = chaincodetest.Identity{MSPID: "Org1MSP", CommonName: "auditor1", Attributes: map[string]string{"role": "auditor", registrarAttribute: KYCLevelBasic}}
+	partner   = chaincodetest.Identity{MSPID: "Org2MSP", CommonName: "partner1"}
+	manager   = chaincodetest.Identity{MSPID: "Org2MSP", CommonName: "manager1"}
+)
+
+// newLedger returns a ledger holding the sample identity of InitLedger, owned by Org1MSP
+func newLedger(t *testing.T) *chaincodetest.Ledger {
+	ledger := chaincodetest.NewLedger()
+	ctx, _ := ledger.Tx(registrar)
+	require.NoError(t, new(SmartContract).InitLedger(ctx))
+
+	return ledger
+}
+
+// createIdentity returns a case in which the caller creates an identity with the given CNIC
+func createIdentity(caller chaincodetest.Identity, id string, cnic string) chaincodetest.Case {
+	return chaincodetest.Case{
+		Name:   "create " + id,
+		Caller: caller,
+		Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return new(SmartContract).CreateIdentity(ctx, id, "Ms.", "Jane", "Roe", cnic, "1990-05-17", "Female", "03211234567")
+		},
+	}
+}
+
+// updateIdentity returns a case in which the caller updates the mobile number and address of an identity
+func updateIdentity(caller chaincodetest.Identity, id string, mobile string, address string) chaincodetest.Case {
+	return chaincodetest.Case{
+		Name:   caller.CommonName + " updates " + id,
+		Caller: caller,
+		Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return new(SmartContract).UpdateIdentity(ctx, id, mobile, address)
+		},
+	}
+}
+
+// readIdentity returns a check reading an identity and passing it to check on success
+func readIdentity(id string, check func(t *testing.T, identity *Identity)) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+	return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		identity, err := new(SmartContract).ReadIdentity(ctx, id)
+		if err == nil && check != nil {
+			check(t, identity)
+		}
+		return err
+	}
+}
+
+// lastEvent returns the name and payload of the chaincode event the transaction set last
+func lastEvent(t *testing.T, ctx contractapi.TransactionContextInterface) (string, IdentityEvent) {
+	stub := ctx.GetStub().(*chaincodefakes.ChaincodeStub)
+	require.NotZero(t, stub.SetEventCallCount())
+	name, payload := stub.SetEventArgsForCall(stub.SetEventCallCount() - 1)
+
+	var event IdentityEvent
+	require.NoError(t, json.Unmarshal(payload, &event))
+	return name, event
+}
+
+func TestIdentities(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	listIdentities := func(want ...string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			identities, err := s.GetAllIdentities(ctx, false)
+			if err == nil {
+				var ids []string
+				for _, identity := range identities {
+					ids = append(ids, identity.ID)
+				}
+				require.Equal(t, want, ids)
+			}
+			return err
+		}
+	}
+	exists := func(id string, want bool) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			exists, err := s.IdentityExists(ctx, id)
+			require.Equal(t, want, exists)
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{
+			Name:   "read sample identity",
+			Caller: registrar,
+			Run: readIdentity("identity1", func(t *testing.T, identity *Identity) {
+				require.Equal(t, "John", identity.FirstName)
+				require.Equal(t, "12345-6789012-3", identity.CNIC)
+				require.Equal(t, KYCLevelBasic, identity.KYCLevel)
+				require.Equal(t, "Org1MSP", identity.OwnerMSP)
+				require.Equal(t, "registrar1", identity.UpdatedBy)
+			}),
+		},
+		{
+			Name:   "customers read identities without personal data",
+			Caller: customer,
+			Run: readIdentity("identity1", func(t *testing.T, identity *Identity) {
+				require.Equal(t, "John", identity.FirstName)
+				require.Equal(t, authz.Redacted, identity.CNIC)
+				require.Equal(t, authz.Redacted, identity.MobileNumber)
+			}),
+		},
+		{
+			Name:   "auditors read identities in full",
+			Caller: auditor,
+			Run: readIdentity("identity1", func(t *testing.T, identity *Identity) {
+				require.Equal(t, "12345-6789012-3", identity.CNIC)
+			}),
+		},
+		{Name: "owners need the registrar attribute", Caller: clerk, Run: readIdentity("identity1", nil), Err: "requires the kycRegistrar attribute, an active consent or a delegation"},
+		{Name: "other organizations need consent", Caller: partner, Run: readIdentity("identity1", nil), Err: "not authorized to read identity identity1"},
+		{Name: "read missing identity", Caller: registrar, Run: readIdentity("identity9", nil), Err: "the identity identity9 does not exist"},
+		createIdentity(registrar, "identity2", "54321-0987654-3"),
+		{
+			Name:   "creation is announced",
+			Caller: registrar,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				err := s.CreateIdentity(ctx, "identity3", "Mr.", "Ali", "Khan", "11111-2222222-3", "1985-02-01", "Male", "03331234567")
+				if err == nil {
+					name, event := lastEvent(t, ctx)
+					require.Equal(t, EventIdentityCreated, name)
+					require.Equal(t, "identity3", event.IdentityID)
+					require.Equal(t, KYCLevelBasic, event.KYCLevel)
+					require.Equal(t, ctx.GetStub().GetTxID(), event.TxID)
+				}
+				return err
+			},
+		},
+		{
+			Name:   "create duplicate",
+			Caller: registrar,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.CreateIdentity(ctx, "identity2", "Ms.", "Jane", "Roe", "54321-0987654-3", "1990-05-17", "Female", "03211234567")
+			},
+			Err: "the identity identity2 already exists",
+		},
+		{Name: "identity exists", Caller: partner, Run: exists("identity2", true)},
+		{Name: "identity does not exist", Caller: partner, Run: exists("identity9", false)},
+		{Name: "registrars list every identity", Caller: registrar, Run: listIdentities("identity1", "identity2", "identity3")},
+		{Name: "other organizations list none", Caller: partner, Run: listIdentities()},
+		{
+			Name:   "list identities with a corrupt record",
+			Caller: registrar,
+			Stub: func(stub *chaincodefakes.ChaincodeStub) {
+				stub.GetStateByRangeReturns(chaincodetest.StateIterator(&queryresult.KV{Key: "identity9", Value: []byte("{")}), nil)
+			},
+			Run: listIdentities(),
+			Err: "unexpected end of JSON input",
+		},
+		updateIdentity(registrar, "identity2", "03219999999", "12 Mall Road, Lahore"),
+		{
+			Name:   "updates are kept",
+			Caller: registrar,
+			Run: readIdentity("identity2", func(t *testing.T, identity *Identity) {
+				require.Equal(t, "03219999999", identity.MobileNumber)
+				require.Equal(t, "12 Mall Road, Lahore", identity.Address)
+				require.False(t, identity.UpdatedByDelegate)
+			}),
+		},
+		{Name: "other organizations cannot update", Caller: partner, Run: updateIdentity(partner, "identity2", "03210000000", "").Run, Err: "identity identity2 is owned by Org1MSP and no delegation is active"},
+		{Name: "update missing identity", Caller: registrar, Run: updateIdentity(registrar, "identity9", "03210000000", "").Run, Err: "the identity identity9 does not exist"},
+		{
+			Name:   "delete identity",
+			Caller: registrar,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				err := s.DeleteIdentity(ctx, "identity2", "closed")
+				if err == nil {
+					_, err := s.GetIdentityByNIC(ctx, "54321-0987654-3")
+					require.EqualError(t, err, "no identity registered under NIC 54321-0987654-3")
+				}
+				return err
+			},
+		},
+		{Name: "deleted identities are not listed", Caller: registrar, Run: listIdentities("identity1", "identity3")},
+		{Name: "deleted identity IDs stay reserved", Caller: registrar, Run: exists("identity2", true)},
+		{
+			Name:   "delete a deleted identity",
+			Caller: registrar,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.DeleteIdentity(ctx, "identity2", "closed")
+			},
+			Err: "the identity identity2 was deleted on",
+		},
+		{
+			Name:   "delete missing identity",
+			Caller: registrar,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.DeleteIdentity(ctx, "identity9", "closed")
+			},
+			Err: "the identity identity9 does not exist",
+		},
+	})
+}
+
+func TestRestoreIdentity(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	remove := func(id string, reason string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.DeleteIdentity(ctx, id, reason)
+		}
+	}
+	restore := func(id string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.RestoreIdentity(ctx, id)
+		}
+	}
+	list := func(includeDeleted bool, want ...string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			identities, err := s.GetAllIdentities(ctx, includeDeleted)
+			if err == nil {
+				var ids []string
+				for _, identity := range identities {
+					ids = append(ids, identity.ID)
+					if identity.Deleted {
+						require.Equal(t, "duplicate record", identity.DeletionReason)
+						require.Equal(t, "registrar1", identity.DeletedBy)
+					}
+				}
+				require.Equal(t, want, ids)
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		createIdentity(registrar, "identity2", "54321-0987654-3"),
+		{Name: "delete without a reason", Caller: registrar, Run: remove("identity2", ""), Err: "deletion reason must not be empty"},
+		{Name: "other organizations cannot delete", Caller: partner, Run: remove("identity2", "duplicate record"), Err: "identity identity2 is owned by Org1MSP"},
+		{Name: "delete identity2", Caller: registrar, Run: remove("identity2", "duplicate record")},
+		{Name: "list deleted identities", Caller: registrar, Run: list(true, "identity1", "identity2")},
+		{Name: "restore a live identity", Caller: registrar, Run: restore("identity1"), Err: "the identity identity1 is not deleted"},
+		{Name: "other organizations cannot restore", Caller: partner, Run: restore("identity2"), Err: "identity identity2 is owned by Org1MSP"},
+		{Name: "restore identity2", Caller: registrar, Run: restore("identity2")},
+		{Name: "a restored identity is listed again", Caller: registrar, Run: list(false, "identity1", "identity2")},
+		{
+			Name:   "a restored identity is indexed again",
+			Caller: registrar,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				identity, err := s.GetIdentityByNIC(ctx, "54321-0987654-3")
+				if err == nil {
+					require.Equal(t, "identity2", identity.ID)
+					require.False(t, identity.Deleted)
+				}
+				return err
+			},
+		},
+	})
+}
+
+func TestMigrateIdentityKeys(t *testing.T) {
+	s := new(SmartContract)
+	ledger := chaincodetest.NewLedger()
+
+	migrate := func(moved int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			count, err := s.MigrateIdentityKeys(ctx, 10)
+			if err == nil {
+				require.Equal(t, moved, count)
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{
+			Name:   "identity under its plain ID",
+			Caller: registrar,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return ctx.GetStub().PutState("identity1", []byte(`{"id":"identity1","firstName":"Afraz","ownerMSP":"Org1MSP"}`))
+			},
+		},
+		{Name: "only admins migrate", Caller: registrar, Run: migrate(0), Err: "requires role admin"},
+		{Name: "migrate", Caller: admin, Run: migrate(1)},
+		{Name: "nothing left to migrate", Caller: admin, Run: migrate(0)},
+		{Name: "the migrated identity", Caller: registrar, Run: readIdentity("identity1", func(t *testing.T, identity *Identity) {
+			require.Equal(t, "Afraz", identity.FirstName)
+		})},
+		{
+			Name:   "the migrated identity keeps its history",
+			Caller: registrar,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				history, err := s.GetIdentityHistory(ctx, "identity1")
+				if err == nil {
+					require.Len(t, history, 2)
+					require.Equal(t, "tx1", history[0].TxID)
+					require.Empty(t, history[1].Changes)
+				}
+				return err
+			},
+		},
+	})
+}
+
+func TestGetAuditTrail(t *testing.T) {
+	s := new(SmartContract)
+	ledger := chaincodetest.NewLedger()
+
+	var cases []chaincodetest.Case
+	for _, function := range []string{"CreateIdentity", "GetIdentitiesPaginated", "SetKYCLevel"} {
+		function := function
+		cases = append(cases, chaincodetest.Case{
+			Name:   "audit " + function,
+			Caller: registrar,
+			Stub: func(stub *chaincodefakes.ChaincodeStub) {
+				stub.GetFunctionAndParametersReturns(function, nil)
+			},
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return audit(ctx)
+			},
+		})
+	}
+	cases = append(cases, chaincodetest.Case{
+		Name:   "read the trail",
+		Caller: auditor,
+		Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			page, err := s.GetAuditTrail(ctx, "", "", 10)
+			if err == nil {
+				require.Len(t, page.Entries, 2)
+				require.Equal(t, "CreateIdentity", page.Entries[0].Function)
+				require.Equal(t, "tx3", page.Entries[1].TxID)
+				require.Equal(t, "SetKYCLevel", page.Entries[1].Function)
+			}
+			return err
+		},
+	})
+
+	ledger.Run(t, cases)
+}
+
+func TestAuditSkipsPaginatedQueries(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	// paginated runs the audit handler as Fabric does before the function, then the function's
+	// paginated query, which fails if the handler wrote
+	paginated := func(function string, query func(ctx contractapi.TransactionContextInterface) error) chaincodetest.Case {
+		return chaincodetest.Case{
+			Name:   function,
+			Caller: admin,
+			Stub: func(stub *chaincodefakes.ChaincodeStub) {
+				stub.GetFunctionAndParametersReturns(function, nil)
+			},
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				err := audit(ctx)
+				if err != nil {
+					return err
+				}
+				return query(ctx)
+			},
+		}
+	}
+	listIdentities := func(ctx contractapi.TransactionContextInterface) error {
+		_, err := s.GetIdentitiesPaginated(ctx, 10, "", nil)
+		return err
+	}
+
+	audited := paginated("SetKYCLevel", listIdentities)
+	audited.Err = "has written and cannot run a paginated query"
+	ledger.Run(t, []chaincodetest.Case{
+		paginated("GetIdentitiesPaginated", listIdentities),
+		paginated("GetConsentsPaginated", func(ctx contractapi.TransactionContextInterface) error {
+			_, err := s.GetConsentsPaginated(ctx, "identity1", 10, "")
+			return err
+		}),
+		paginated("GetAuditTrail", func(ctx contractapi.TransactionContextInterface) error {
+			_, err := s.GetAuditTrail(ctx, "", "", 10)
+			return err
+		}),
+		paginated("GetChangesSince", func(ctx contractapi.TransactionContextInterface) error {
+			_, err := s.GetChangesSince(ctx, identityAssetType, "", 10, "")
+			return err
+		}),
+		paginated("ExportState", func(ctx contractapi.TransactionContextInterface) error {
+			_, err := s.ExportState(ctx, "", 10, "")
+			return err
+		}),
+		audited,
+	})
+}
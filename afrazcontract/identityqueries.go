@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"authz"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// IdentityPage is one page of projected identity records with the bookmark for the next page.
+// When the client requested an encoded response, Records is empty and EncodedRecords holds the
+// records in the named Encoding instead.
+type IdentityPage struct {
+	Records             []map[string]string `json:"records,omitempty" metadata:",optional"`
+	Encoding            string              `json:"encoding"`
+	EncodedRecords      string              `json:"encodedRecords"`
+	FetchedRecordsCount int32               `json:"fetchedRecordsCount"`
+	Bookmark            string              `json:"bookmark"`
+}
+
+// IdentitySummary is the non-sensitive view of an identity available to every channel member
+type IdentitySummary struct {
+	ID        string `json:"id"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	Gender    string `json:"gender"`
+	KYCLevel  string `json:"kycLevel"`
+}
+
+// identityRedaction hides personal data from everyone but auditors and registrars, whatever gave
+// them read access to the identity. Clients matching no other rule, customers and partner
+// organizations reading by consent or delegation among them, get the personal data redacted.
+var identityRedaction = authz.Policy{
+	{Match: "role=auditor"},
+	{Match: "role=customer", Redact: identityPersonalFields},
+	{Match: registrarAttribute + "=" + KYCLevelBasic},
+	{Match: registrarAttribute + "=" + KYCLevelEnhanced},
+	{Match: registrarAttribute + "=" + KYCLevelFull},
+	{Match: "*", Redact: identityPersonalFields},
+}
+
+// identityPersonalFields are the JSON names of the personal data identityRedaction hides
+var identityPersonalFields = []string{
+	"cnic", "cnicIssueDate", "cnicExpiryDate", "oldNIC",
+	"passportNumber", "passportIssueDate", "passportExpiryDate",
+	"dateOfBirth", "placeOfBirth", "fatherOrHusbandName", "motherMaidenName",
+	"address", "landline", "postalCode", "mobileNumber", "ntn",
+}
+
+// identityFieldNames holds the JSON names of every Identity field that may be projected
+var identityFieldNames = func() map[string]bool {
+	names := make(map[string]bool)
+	identityType := reflect.TypeOf(Identity{})
+	for i := 0; i < identityType.NumField(); i++ {
+		name := strings.Split(identityType.Field(i).Tag.Get("json"), ",")[0]
+		names[name] = true
+	}
+	return names
+}()
+
+// ReadIdentitySummary returns the name, gender and verification status of an identity. Unlike
+// ReadIdentity it needs neither the registrar attribute nor consent.
+func (s *SmartContract) ReadIdentitySummary(ctx contractapi.TransactionContextInterface, id string) (*IdentitySummary, error) {
+	identity, err := s.getIdentity(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &IdentitySummary{
+		ID:        identity.ID,
+		FirstName: identity.FirstName,
+		LastName:  identity.LastName,
+		Gender:    identity.Gender,
+		KYCLevel:  identity.KYCLevel,
+	}, nil
+}
+
+// GetIdentitiesPaginated returns one page of the identities readable by the caller, each redacted
+// like ReadIdentity and reduced to the requested fields. The id field is always included. Large pages can exceed the peer's
+// gRPC message limit; setting the response_encoding transient key to gzip returns the records
+// gzip compressed and base64 encoded in EncodedRecords.
+func (s *SmartContract) GetIdentitiesPaginated(ctx contractapi.TransactionContextInterface, pageSize int, bookmark string, fields []string) (*IdentityPage, error) {
+	if pageSize <= 0 {
+		return nil, fmt.Errorf("pageSize must be positive")
+	}
+	encoding, err := responseEncoding(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range fields {
+		if !identityFieldNames[field] {
+			return nil, fmt.Errorf("unknown identity field %s", field)
+		}
+	}
+
+	// bookmarks are identity IDs rather than keys
+	start, end := identityNamespace.Range("")
+	if bookmark != "" {
+		bookmark = identityNamespace.Key(bookmark)
+	}
+	resultsIterator, metadata, err := ctx.GetStub().GetStateByRangeWithPagination(start, end, int32(pageSize), bookmark)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	page := &IdentityPage{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var identity Identity
+		err = json.Unmarshal(queryResponse.Value, &identity)
+		if err != nil {
+			return nil, err
+		}
+		err = loadPersonalData(ctx, &identity)
+		if err != nil {
+			return nil, err
+		}
+		allowed, err := s.redactReadable(ctx, &identity)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			continue
+		}
+
+		values, err := identityFields(&identity)
+		if err != nil {
+			return nil, err
+		}
+		record := map[string]string{"id": identity.ID}
+		for _, field := range fields {
+			record[field] = values[field]
+		}
+		page.Records = append(page.Records, record)
+	}
+
+	if encoding != "" {
+		page.Encoding = encoding
+		page.EncodedRecords, err = encodeRecords(page.Records)
+		if err != nil {
+			return nil, err
+		}
+		page.Records = nil
+	}
+
+	page.FetchedRecordsCount = metadata.FetchedRecordsCount
+	page.Bookmark, _ = identityNamespace.ID(metadata.Bookmark)
+	return page, nil
+}
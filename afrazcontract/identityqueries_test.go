@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"chaincodetest"
+	"chaincodetest/chaincodefakes"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentityQueries(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	summary := func(id string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			summary, err := s.ReadIdentitySummary(ctx, id)
+			if err == nil {
+				require.Equal(t, &IdentitySummary{ID: "identity1", FirstName: "John", LastName: "Doe", Gender: "Male", KYCLevel: KYCLevelBasic}, summary)
+			}
+			return err
+		}
+	}
+	paginated := func(pageSize int, bookmark string, fields []string, check func(t *testing.T, page *IdentityPage)) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			page, err := s.GetIdentitiesPaginated(ctx, pageSize, bookmark, fields)
+			if err == nil {
+				check(t, page)
+			}
+			return err
+		}
+	}
+	encoding := func(value string) func(stub *chaincodefakes.ChaincodeStub) {
+		return func(stub *chaincodefakes.ChaincodeStub) {
+			stub.GetTransientReturns(map[string][]byte{responseEncodingKey: []byte(value)}, nil)
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		createIdentity(registrar, "identity2", "54321-0987654-3"),
+		createIdentity(partner, "identity3", "11111-2222222-3"),
+		{Name: "summaries need no consent", Caller: partner, Run: summary("identity1")},
+		{Name: "summary of a missing identity", Caller: partner, Run: summary("identity9"), Err: "the identity identity9 does not exist"},
+		{
+			Name:   "first page",
+			Caller: registrar,
+			Run: paginated(1, "", []string{"firstName"}, func(t *testing.T, page *IdentityPage) {
+				require.Equal(t, []map[string]string{{"id": "identity1", "firstName": "John"}}, page.Records)
+				require.Equal(t, int32(1), page.FetchedRecordsCount)
+				require.Equal(t, "identity2", page.Bookmark)
+			}),
+		},
+		{
+			Name:   "next page",
+			Caller: registrar,
+			Run: paginated(1, "identity2", []string{"cnic", "kycLevel"}, func(t *testing.T, page *IdentityPage) {
+				require.Equal(t, []map[string]string{{"id": "identity2", "cnic": "54321-0987654-3", "kycLevel": KYCLevelBasic}}, page.Records)
+				require.Equal(t, "identity3", page.Bookmark)
+			}),
+		},
+		{
+			Name:   "unreadable identities are skipped",
+			Caller: registrar,
+			Run: paginated(1, "identity3", nil, func(t *testing.T, page *IdentityPage) {
+				require.Empty(t, page.Records)
+				require.Equal(t, int32(1), page.FetchedRecordsCount)
+				require.Empty(t, page.Bookmark)
+			}),
+		},
+		{
+			Name:   "gzip encoded page",
+			Caller: registrar,
+			Stub:   encoding(gzipEncoding),
+			Run: paginated(10, "", []string{"lastName"}, func(t *testing.T, page *IdentityPage) {
+				require.Nil(t, page.Records)
+				require.Equal(t, gzipEncoding, page.Encoding)
+
+				compressed, err := base64.StdEncoding.DecodeString(page.EncodedRecords)
+				require.NoError(t, err)
+				reader, err := gzip.NewReader(bytes.NewReader(compressed))
+				require.NoError(t, err)
+				recordsJSON, err := io.ReadAll(reader)
+				require.NoError(t, err)
+				var records []map[string]string
+				require.NoError(t, json.Unmarshal(recordsJSON, &records))
+				require.Equal(t, []map[string]string{{"id": "identity1", "lastName": "Doe"}, {"id": "identity2", "lastName": "Roe"}}, records)
+			}),
+		},
+		{Name: "unsupported encoding", Caller: registrar, Stub: encoding("br"), Run: paginated(10, "", nil, nil), Err: `unsupported response encoding "br", expected gzip`},
+		{Name: "page size must be positive", Caller: registrar, Run: paginated(0, "", nil, nil), Err: "pageSize must be positive"},
+		{Name: "unknown field", Caller: registrar, Run: paginated(10, "", []string{"shoeSize"}, nil), Err: "unknown identity field shoeSize"},
+		{
+			Name:   "customers get personal data redacted",
+			Caller: customer,
+			Run: paginated(10, "", []string{"cnic", "lastName"}, func(t *testing.T, page *IdentityPage) {
+				require.Equal(t, []map[string]string{{"id": "identity1", "cnic": "***", "lastName": "Doe"}, {"id": "identity2", "cnic": "***", "lastName": "Roe"}}, page.Records)
+			}),
+		},
+		{
+			Name:   "grant consent",
+			Caller: registrar,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.GrantConsent(ctx, "identity1", "Org2MSP", ConsentScopeRead, "2025-01-01")
+			},
+		},
+		{
+			Name:   "consent does not reveal personal data",
+			Caller: partner,
+			Run: paginated(10, "", []string{"cnic", "mobileNumber", "lastName"}, func(t *testing.T, page *IdentityPage) {
+				require.Equal(t, []map[string]string{{"id": "identity1", "cnic": "***", "mobileNumber": "***", "lastName": "Doe"}}, page.Records)
+			}),
+		},
+	})
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"fmt"
+
+	"authz"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// KYC levels in increasing order of verification
+const (
+	KYCLevelBasic    = "Basic"
+	KYCLevelEnhanced = "Enhanced"
+	KYCLevelFull     = "Full"
+)
+
+// Risk ratings assigned by registrars
+const (
+	RiskRatingLow    = "Low"
+	RiskRatingMedium = "Medium"
+	RiskRatingHigh   = "High"
+)
+
+const (
+	// kycIndex groups identities by KYC level
+	kycIndex = "kyc~identity"
+
+	// registrarAttribute is the client attribute holding the highest KYC level a registrar may assign
+	registrarAttribute = "kycRegistrar"
+)
+
+var kycRank = map[string]int{
+	KYCLevelBasic:    1,
+	KYCLevelEnhanced: 2,
+	KYCLevelFull:     3,
+}
+
+var riskRatings = map[string]bool{
+	RiskRatingLow:    true,
+	RiskRatingMedium: true,
+	RiskRatingHigh:   true,
+}
+
+// SetKYCLevel moves an identity to a new KYC level. The submitting registrar's kycRegistrar
+// attribute must be at least both the current and the new level, so only registrars cleared
+// for a level can grant it or take it away.
+func (s *SmartContract) SetKYCLevel(ctx contractapi.TransactionContextInterface, id string, level string) error {
+	if _, ok := kycRank[level]; !ok {
+		return fmt.Errorf("unknown KYC level %s", level)
+	}
+
+	identity, err := s.getIdentity(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = requireOwner(ctx, identity)
+	if err != nil {
+		return err
+	}
+
+	clearance, err := registrarClearance(ctx)
+	if err != nil {
+		return err
+	}
+	if kycRank[level] > clearance || kycRank[identity.KYCLevel] > clearance {
+		return fmt.Errorf("submitting client not authorized to move identity %s from KYC level %q to %s", id, identity.KYCLevel, level)
+	}
+
+	err = deleteKYCIndex(ctx, identity)
+	if err != nil {
+		return err
+	}
+	identity.KYCLevel = level
+	err = putKYCIndex(ctx, identity)
+	if err != nil {
+		return err
+	}
+
+	err = stampModifier(ctx, identity)
+	if err != nil {
+		return err
+	}
+	err = putIdentity(ctx, identity)
+	if err != nil {
+		return err
+	}
+
+	return emitIdentityEvent(ctx, EventIdentityVerified, IdentityEvent{IdentityID: identity.ID, OwnerMSP: identity.OwnerMSP, KYCLevel: identity.KYCLevel})
+}
+
+// SetRiskRating assigns a risk rating to an identity. Requires the kycRegistrar attribute.
+func (s *SmartContract) SetRiskRating(ctx contractapi.TransactionContextInterface, id string, rating string) error {
+	if !riskRatings[rating] {
+		return fmt.Errorf("unknown risk rating %s", rating)
+	}
+
+	identity, err := s.getIdentity(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = requireOwner(ctx, identity)
+	if err != nil {
+		return err
+	}
+	_, err = registrarClearance(ctx)
+	if err != nil {
+		return err
+	}
+
+	identity.RiskRating = rating
+
+	err = stampModifier(ctx, identity)
+	if err != nil {
+		return err
+	}
+	err = putIdentity(ctx, identity)
+	if err != nil {
+		return err
+	}
+
+	return emitIdentityEvent(ctx, EventIdentityUpdated, IdentityEvent{IdentityID: identity.ID, OwnerMSP: identity.OwnerMSP})
+}
+
+// GetIdentitiesByKYCLevel returns the identities at the given KYC level that the caller may read
+func (s *SmartContract) GetIdentitiesByKYCLevel(ctx contractapi.TransactionContextInterface, level string) ([]*Identity, error) {
+	if _, ok := kycRank[level]; !ok {
+		return nil, fmt.Errorf("unknown KYC level %s", level)
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(kycIndex, []string{level})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var identities []*Identity
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		identity, err := s.getIdentity(ctx, keyParts[1])
+		if err != nil {
+			return nil, err
+		}
+		allowed, err := s.redactReadable(ctx, identity)
+		if err != nil {
+			return nil, err
+		}
+		if allowed {
+			identities = append(identities, identity)
+		}
+	}
+
+	return identities, nil
+}
+
+// GetKYCLevel returns only the KYC level of an identity. It needs no read consent so that other
+// chaincodes, such as the bank loan contract, can check the level of an applicant.
+func (s *SmartContract) GetKYCLevel(ctx contractapi.TransactionContextInterface, id string) (string, error) {
+	identity, err := s.getIdentity(ctx, id)
+	if err != nil {
+		return "", err
+	}
+
+	return identity.KYCLevel, nil
+}
+
+// registrarClearance returns the rank of the highest KYC level the submitting client may assign
+func registrarClearance(ctx contractapi.TransactionContextInterface) (int, error) {
+	level, found, err := authz.Attribute(ctx, registrarAttribute)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read client %s attribute: %v", registrarAttribute, err)
+	}
+	if !found || kycRank[level] == 0 {
+		return 0, fmt.Errorf("submitting client not authorized, requires the %s attribute", registrarAttribute)
+	}
+
+	return kycRank[level], nil
+}
+
+// isRegistrar reports whether the submitting client carries a valid kycRegistrar attribute
+func isRegistrar(ctx contractapi.TransactionContextInterface) (bool, error) {
+	level, found, err := authz.Attribute(ctx, registrarAttribute)
+	if err != nil {
+		return false, fmt.Errorf("failed to read client %s attribute: %v", registrarAttribute, err)
+	}
+
+	return found && kycRank[level] > 0, nil
+}
+
+func kycIndexKey(ctx contractapi.TransactionContextInterface, identity *Identity) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(kycIndex, []string{identity.KYCLevel, identity.ID})
+	if err != nil {
+		return "", fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	return key, nil
+}
+
+func putKYCIndex(ctx contractapi.TransactionContextInterface, identity *Identity) error {
+	if identity.KYCLevel == "" {
+		return nil
+	}
+	key, err := kycIndexKey(ctx, identity)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(key, []byte{0x00})
+}
+
+func deleteKYCIndex(ctx contractapi.TransactionContextInterface, identity *Identity) error {
+	if identity.KYCLevel == "" {
+		return nil
+	}
+	key, err := kycIndexKey(ctx, identity)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().DelState(key)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKYC(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	setLevel := func(level string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			err := s.SetKYCLevel(ctx, "identity1", level)
+			if err == nil {
+				name, event := lastEvent(t, ctx)
+				require.Equal(t, EventIdentityVerified, name)
+				require.Equal(t, level, event.KYCLevel)
+			}
+			return err
+		}
+	}
+	setRating := func(rating string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.SetRiskRating(ctx, "identity1", rating)
+		}
+	}
+	byLevel := func(level string, want ...string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			identities, err := s.GetIdentitiesByKYCLevel(ctx, level)
+			if err == nil {
+				var ids []string
+				for _, identity := range identities {
+					ids = append(ids, identity.ID)
+				}
+				require.Equal(t, want, ids)
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		createIdentity(registrar, "identity2", "54321-0987654-3"),
+		{Name: "raise KYC level", Caller: registrar, Run: setLevel(KYCLevelEnhanced)},
+		{Name: "identities by KYC level", Caller: registrar, Run: byLevel(KYCLevelEnhanced, "identity1")},
+		{Name: "identities leave their former level", Caller: registrar, Run: byLevel(KYCLevelBasic, "identity2")},
+		{Name: "other organizations see none", Caller: partner, Run: byLevel(KYCLevelBasic)},
+		{Name: "identities by unknown level", Caller: registrar, Run: byLevel("Gold"), Err: "unknown KYC level Gold"},
+		{
+			Name:   "KYC levels need no consent",
+			Caller: partner,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				level, err := s.GetKYCLevel(ctx, "identity1")
+				require.Equal(t, KYCLevelEnhanced, level)
+				return err
+			},
+		},
+		{Name: "registrars cannot lower levels above their clearance", Caller: junior, Run: setLevel(KYCLevelBasic), Err: `not authorized to move identity identity1 from KYC level "Enhanced" to Basic`},
+		{Name: "registrars cannot raise levels above their clearance", Caller: junior, Run: setLevel(KYCLevelFull), Err: "not authorized to move identity identity1"},
+		{Name: "set level without the registrar attribute", Caller: clerk, Run: setLevel(KYCLevelFull), Err: "requires the kycRegistrar attribute"},
+		{Name: "set level from another organization", Caller: partner, Run: setLevel(KYCLevelFull), Err: "identity identity1 is owned by Org1MSP"},
+		{Name: "set unknown level", Caller: registrar, Run: setLevel("Gold"), Err: "unknown KYC level Gold"},
+		{Name: "set risk rating", Caller: junior, Run: setRating(RiskRatingHigh)},
+		{
+			Name:   "risk ratings are kept",
+			Caller: registrar,
+			Run: readIdentity("identity1", func(t *testing.T, identity *Identity) {
+				require.Equal(t, RiskRatingHigh, identity.RiskRating)
+				require.Equal(t, "registrar2", identity.UpdatedBy)
+			}),
+		},
+		{Name: "set unknown risk rating", Caller: registrar, Run: setRating("Extreme"), Err: "unknown risk rating Extreme"},
+		{Name: "set risk rating without the registrar attribute", Caller: clerk, Run: setRating(RiskRatingLow), Err: "requires the kycRegistrar attribute"},
+		{Name: "set risk rating from another organization", Caller: partner, Run: setRating(RiskRatingLow), Err: "identity identity1 is owned by Org1MSP"},
+	})
+}
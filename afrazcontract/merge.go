@@ -0,0 +1,241 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	// cnicIndex and oldNICIndex map national identity card numbers to identity IDs
+	cnicIndex   = "cnic~identity"
+	oldNICIndex = "oldnic~identity"
+
+	// aliasObjectType prefixes the keys left behind by merged duplicates, holding the primary ID
+	aliasObjectType = "alias"
+)
+
+// mergeSkipFields are never copied from a duplicate onto the primary identity
+var mergeSkipFields = map[string]bool{
+	"ID":           true,
+	"CNIC":         true,
+	"OldNIC":       true,
+	"KYCLevel":     true,
+	"RiskRating":   true,
+	"OwnerMSP":     true,
+	"UpdatedByMSP": true,
+	"UpdatedBy":    true,
+}
+
+// MergeIdentities consolidates a duplicate identity into the primary one, for example a record
+// created under an old NIC and another under the new CNIC. Fields empty on the primary are filled
+// from the duplicate, a differing duplicate CNIC becomes the primary's OldNIC, and the duplicate is
+// removed leaving an alias key so that reads of the duplicate ID resolve to the primary.
+// The primary keeps its own KYC level and risk rating.
+func (s *SmartContract) MergeIdentities(ctx contractapi.TransactionContextInterface, primaryID string, duplicateID string) error {
+	if primaryID == duplicateID {
+		return fmt.Errorf("cannot merge identity %s into itself", primaryID)
+	}
+
+	primary, err := s.getIdentity(ctx, primaryID)
+	if err != nil {
+		return err
+	}
+	if primary.ID != primaryID {
+		return fmt.Errorf("identity %s was already merged into %s", primaryID, primary.ID)
+	}
+	duplicate, err := s.getIdentity(ctx, duplicateID)
+	if err != nil {
+		return err
+	}
+	if duplicate.ID != duplicateID {
+		return fmt.Errorf("identity %s was already merged into %s", duplicateID, duplicate.ID)
+	}
+
+	err = requireOwner(ctx, primary)
+	if err != nil {
+		return err
+	}
+	err = requireOwner(ctx, duplicate)
+	if err != nil {
+		return err
+	}
+
+	for _, identity := range []*Identity{primary, duplicate} {
+		err = deleteNICIndex(ctx, identity)
+		if err != nil {
+			return err
+		}
+		err = deleteExpiryIndex(ctx, identity)
+		if err != nil {
+			return err
+		}
+	}
+	err = deleteKYCIndex(ctx, duplicate)
+	if err != nil {
+		return err
+	}
+
+	primaryValue := reflect.ValueOf(primary).Elem()
+	duplicateValue := reflect.ValueOf(duplicate).Elem()
+	for i := 0; i < primaryValue.NumField(); i++ {
+		if mergeSkipFields[primaryValue.Type().Field(i).Name] {
+			continue
+		}
+		if primaryValue.Field(i).String() == "" {
+			primaryValue.Field(i).SetString(duplicateValue.Field(i).String())
+		}
+	}
+	if primary.CNIC == "" {
+		primary.CNIC = duplicate.CNIC
+	} else if primary.OldNIC == "" && duplicate.CNIC != primary.CNIC {
+		primary.OldNIC = duplicate.CNIC
+	}
+	if primary.OldNIC == "" {
+		primary.OldNIC = duplicate.OldNIC
+	}
+	err = s.relinkRelatives(ctx, duplicate, primary)
+	if err != nil {
+		return err
+	}
+
+	err = stampModifier(ctx, primary)
+	if err != nil {
+		return err
+	}
+	err = putIdentity(ctx, primary)
+	if err != nil {
+		return err
+	}
+	err = putNICIndex(ctx, primary)
+	if err != nil {
+		return err
+	}
+	err = putExpiryIndex(ctx, primary)
+	if err != nil {
+		return err
+	}
+
+	err = delIdentity(ctx, duplicate.ID)
+	if err != nil {
+		return fmt.Errorf("failed to delete duplicate %s: %v", duplicate.ID, err)
+	}
+	aliasKey, err := ctx.GetStub().CreateCompositeKey(aliasObjectType, []string{duplicate.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(aliasKey, []byte(primary.ID))
+	if err != nil {
+		return err
+	}
+
+	return emitIdentityEvent(ctx, EventIdentityUpdated, IdentityEvent{IdentityID: primary.ID, OwnerMSP: primary.OwnerMSP})
+}
+
+// GetIdentityByNIC returns the identity registered under the given CNIC, or under it as an old NIC
+func (s *SmartContract) GetIdentityByNIC(ctx contractapi.TransactionContextInterface, nic string) (*Identity, error) {
+	for _, index := range []string{cnicIndex, oldNICIndex} {
+		id, err := identityIDByNIC(ctx, index, nic)
+		if err != nil {
+			return nil, err
+		}
+		if id != "" {
+			return s.ReadIdentity(ctx, id)
+		}
+	}
+
+	return nil, fmt.Errorf("no identity registered under NIC %s", nic)
+}
+
+func identityIDByNIC(ctx contractapi.TransactionContextInterface, index string, nic string) (string, error) {
+	resultsIterator, err := ctx.GetStub().GetPrivateDataByPartialCompositeKey(identityPrivateCollection, index, []string{nic})
+	if err != nil {
+		return "", err
+	}
+	defer resultsIterator.Close()
+
+	if !resultsIterator.HasNext() {
+		return "", nil
+	}
+	queryResponse, err := resultsIterator.Next()
+	if err != nil {
+		return "", err
+	}
+	_, keyParts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+	if err != nil {
+		return "", err
+	}
+
+	return keyParts[1], nil
+}
+
+// resolveAlias returns the primary identity ID a merged duplicate points to, or an empty string
+func resolveAlias(ctx contractapi.TransactionContextInterface, id string) (string, error) {
+	aliasKey, err := ctx.GetStub().CreateCompositeKey(aliasObjectType, []string{id})
+	if err != nil {
+		return "", fmt.Errorf("failed to create composite key: %v", err)
+	}
+	primaryID, err := ctx.GetStub().GetState(aliasKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to read from world state: %v", err)
+	}
+
+	return string(primaryID), nil
+}
+
+// nicIndexKeys returns the CNIC and old NIC index entries for the identity. The entries hold the
+// numbers themselves, so they are kept in the private collection with the rest of the personal data.
+func nicIndexKeys(ctx contractapi.TransactionContextInterface, identity *Identity) ([]string, error) {
+	numbers := [][2]string{
+		{cnicIndex, identity.CNIC},
+		{oldNICIndex, identity.OldNIC},
+	}
+
+	var keys []string
+	for _, entry := range numbers {
+		index, number := entry[0], entry[1]
+		if number == "" {
+			continue
+		}
+
+		key, err := ctx.GetStub().CreateCompositeKey(index, []string{number, identity.ID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create composite key: %v", err)
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+func putNICIndex(ctx contractapi.TransactionContextInterface, identity *Identity) error {
+	keys, err := nicIndexKeys(ctx, identity)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		err = ctx.GetStub().PutPrivateData(identityPrivateCollection, key, []byte{0x00})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func deleteNICIndex(ctx contractapi.TransactionContextInterface, identity *Identity) error {
+	keys, err := nicIndexKeys(ctx, identity)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		err = ctx.GetStub().DelPrivateData(identityPrivateCollection, key)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeIdentities(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	merge := func(primaryID string, duplicateID string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.MergeIdentities(ctx, primaryID, duplicateID)
+		}
+	}
+	byNIC := func(nic string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			identity, err := s.GetIdentityByNIC(ctx, nic)
+			if err == nil {
+				require.Equal(t, "identity1", identity.ID)
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		createIdentity(registrar, "identity2", "54321-0987654-3"),
+		createIdentity(registrar, "identity3", "11111-2222222-3"),
+		createIdentity(partner, "identity4", "99999-8888888-7"),
+		updateIdentity(registrar, "identity2", "03211234567", "12 Mall Road, Lahore"),
+		{
+			Name:   "link the duplicate",
+			Caller: registrar,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.LinkRelative(ctx, "identity2", "identity3", "child")
+			},
+		},
+		{Name: "merge duplicate", Caller: registrar, Run: merge("identity1", "identity2")},
+		{
+			Name:   "the primary absorbs the duplicate",
+			Caller: registrar,
+			Run: readIdentity("identity1", func(t *testing.T, identity *Identity) {
+				require.Equal(t, "12345-6789012-3", identity.CNIC)
+				require.Equal(t, "54321-0987654-3", identity.OldNIC)
+				require.Equal(t, "John", identity.FirstName)
+				require.Equal(t, "12 Mall Road, Lahore", identity.Address)
+				require.Equal(t, "1", identity.NoOfDependents)
+			}),
+		},
+		{
+			Name:   "duplicate IDs resolve to the primary",
+			Caller: registrar,
+			Run: readIdentity("identity2", func(t *testing.T, identity *Identity) {
+				require.Equal(t, "identity1", identity.ID)
+			}),
+		},
+		{
+			Name:   "relatives move to the primary",
+			Caller: registrar,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				links, err := s.GetFamilyTree(ctx, "identity3", 1)
+				if err == nil {
+					require.Len(t, links, 1)
+					require.Equal(t, "identity1", links[0].RelativeID)
+					require.Equal(t, "parent", links[0].Relation)
+				}
+				return err
+			},
+		},
+		{Name: "identity by CNIC", Caller: registrar, Run: byNIC("12345-6789012-3")},
+		{Name: "identity by old NIC", Caller: registrar, Run: byNIC("54321-0987654-3")},
+		{Name: "identity by unknown NIC", Caller: registrar, Run: byNIC("00000-0000000-0"), Err: "no identity registered under NIC 00000-0000000-0"},
+		{Name: "identity by NIC needs consent", Caller: partner, Run: byNIC("12345-6789012-3"), Err: "not authorized to read identity identity1"},
+		{Name: "merge into itself", Caller: registrar, Run: merge("identity1", "identity1"), Err: "cannot merge identity identity1 into itself"},
+		{Name: "merge twice", Caller: registrar, Run: merge("identity1", "identity2"), Err: "identity identity2 was already merged into identity1"},
+		{Name: "merge into a merged duplicate", Caller: registrar, Run: merge("identity2", "identity3"), Err: "identity identity2 was already merged into identity1"},
+		{Name: "merge another organization's identity", Caller: registrar, Run: merge("identity1", "identity4"), Err: "identity identity4 is owned by Org2MSP"},
+		{Name: "merge a missing identity", Caller: registrar, Run: merge("identity1", "identity9"), Err: "the identity identity9 does not exist"},
+	})
+}
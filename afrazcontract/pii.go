@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	// personalDataObjectType prefixes the private composite keys of the current personal data of
+	// identities
+	personalDataObjectType = "personaldata"
+
+	// personalDataVersionObjectType prefixes the private composite keys of the personal data an
+	// identity held after each transaction that wrote it, personaldataversion~id~txID
+	personalDataVersionObjectType = "personaldataversion"
+)
+
+// identityPublicFields are the JSON names of the Identity fields kept in the world state. Every
+// other field is personal data and lives in identityPrivateCollection, so that it stays out of
+// the blocks and can be purged.
+var identityPublicFields = map[string]bool{
+	"id":                true,
+	"kycLevel":          true,
+	"riskRating":        true,
+	"schemaVersion":     true,
+	"ownerMSP":          true,
+	"updatedByMSP":      true,
+	"updatedBy":         true,
+	"updatedByDelegate": true,
+	"deleted":           true,
+	"deletionReason":    true,
+	"deletedBy":         true,
+	"deletedAt":         true,
+}
+
+// splitPersonalData returns the JSON of the identity with its personal data blanked, for the world
+// state, and the JSON of the personal data alone, for identityPrivateCollection
+func splitPersonalData(identity *Identity) ([]byte, []byte, error) {
+	identityJSON, err := json.Marshal(identity)
+	if err != nil {
+		return nil, nil, err
+	}
+	var fields map[string]interface{}
+	err = json.Unmarshal(identityJSON, &fields)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	personal := make(map[string]interface{})
+	for name, value := range fields {
+		if !identityPublicFields[name] {
+			personal[name] = value
+			fields[name] = ""
+		}
+	}
+
+	publicJSON, err := json.Marshal(fields)
+	if err != nil {
+		return nil, nil, err
+	}
+	personalJSON, err := json.Marshal(personal)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return publicJSON, personalJSON, nil
+}
+
+// putPersonalData stores the personal data of the identity as its current one and as the version
+// written by this transaction
+func putPersonalData(ctx contractapi.TransactionContextInterface, identity *Identity, personalJSON []byte) error {
+	keys, err := personalDataKeys(ctx, identity.ID, ctx.GetStub().GetTxID())
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		err = ctx.GetStub().PutPrivateData(identityPrivateCollection, key, personalJSON)
+		if err != nil {
+			return fmt.Errorf("failed to put to private data: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// loadPersonalData fills in the current personal data of the identity. Identities written before
+// their personal data moved to the private collection still carry it in the world state and are
+// left as they are.
+func loadPersonalData(ctx contractapi.TransactionContextInterface, identity *Identity) error {
+	key, err := ctx.GetStub().CreateCompositeKey(personalDataObjectType, []string{identity.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	return mergePersonalData(ctx, identity, key)
+}
+
+// loadPersonalDataVersion fills in the personal data the identity held after the transaction txID
+func loadPersonalDataVersion(ctx contractapi.TransactionContextInterface, identity *Identity, txID string) error {
+	key, err := ctx.GetStub().CreateCompositeKey(personalDataVersionObjectType, []string{identity.ID, txID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	return mergePersonalData(ctx, identity, key)
+}
+
+func mergePersonalData(ctx contractapi.TransactionContextInterface, identity *Identity, key string) error {
+	personalJSON, err := ctx.GetStub().GetPrivateData(identityPrivateCollection, key)
+	if err != nil {
+		return fmt.Errorf("failed to read from private data: %v", err)
+	}
+	if personalJSON == nil {
+		return nil
+	}
+
+	return json.Unmarshal(personalJSON, identity)
+}
+
+// purgePersonalData purges the current personal data of an identity and every earlier version of
+// it from the private collection, so that no peer keeps it in its private data history
+func purgePersonalData(ctx contractapi.TransactionContextInterface, id string) error {
+	resultsIterator, err := ctx.GetStub().GetPrivateDataByPartialCompositeKey(identityPrivateCollection, personalDataVersionObjectType, []string{id})
+	if err != nil {
+		return err
+	}
+	defer resultsIterator.Close()
+
+	var keys []string
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return err
+		}
+		keys = append(keys, queryResponse.Key)
+	}
+	currentKey, err := ctx.GetStub().CreateCompositeKey(personalDataObjectType, []string{id})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	keys = append(keys, currentKey)
+
+	for _, key := range keys {
+		err = ctx.GetStub().PurgePrivateData(identityPrivateCollection, key)
+		if err != nil {
+			return fmt.Errorf("failed to purge private data: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// personalDataKeys returns the keys of the current personal data of an identity and of its
+// version written by the transaction txID
+func personalDataKeys(ctx contractapi.TransactionContextInterface, id string, txID string) ([]string, error) {
+	currentKey, err := ctx.GetStub().CreateCompositeKey(personalDataObjectType, []string{id})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	versionKey, err := ctx.GetStub().CreateCompositeKey(personalDataVersionObjectType, []string{id, txID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	return []string{currentKey, versionKey}, nil
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// maxPolicySample bounds how many recent transactions a policy simulation replays
+const maxPolicySample = 500
+
+// PolicySimulation reports how a proposed endorsement policy would have treated recent identity
+// transactions
+type PolicySimulation struct {
+	Policy       string                  `json:"policy"`
+	Sampled      int                     `json:"sampled"`
+	Failed       int                     `json:"failed"`
+	Transactions []*SimulatedTransaction `json:"transactions,omitempty" metadata:",optional"`
+}
+
+// SimulatedTransaction is one replayed transaction and whether it would have been endorsed
+type SimulatedTransaction struct {
+	TxID         string `json:"txId"`
+	IdentityID   string `json:"identityId"`
+	Timestamp    string `json:"timestamp"`
+	SubmitterMSP string `json:"submitterMSP"`
+	Endorsed     bool   `json:"endorsed"`
+}
+
+// SimulateEndorsementPolicy replays the sampleSize most recent identity writes recorded in the
+// identity history against a proposed signature policy, such as
+// AND('Org1MSP.member', OR('Org2MSP.peer', 'Org3MSP.peer')) or OutOf(2, 'Org1MSP.member', ...),
+// and reports those that would have failed endorsement. Chaincode cannot see past endorsements, so
+// each transaction is assumed to have been endorsed by peers of its submitting organization only.
+// Meant to be evaluated, not submitted. Requires the role=admin attribute.
+func (s *SmartContract) SimulateEndorsementPolicy(ctx contractapi.TransactionContextInterface, policy string, sampleSize int) (*PolicySimulation, error) {
+	err := requireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if sampleSize <= 0 || sampleSize > maxPolicySample {
+		return nil, fmt.Errorf("sample size must be between 1 and %d", maxPolicySample)
+	}
+
+	parsed, err := parsePolicy(policy)
+	if err != nil {
+		return nil, err
+	}
+
+	transactions, err := recentIdentityTransactions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(transactions) > sampleSize {
+		transactions = transactions[:sampleSize]
+	}
+
+	simulation := &PolicySimulation{Policy: policy, Sampled: len(transactions)}
+	for _, transaction := range transactions {
+		transaction.Endorsed = parsed.satisfiedBy(map[string]bool{transaction.SubmitterMSP: true})
+		if !transaction.Endorsed {
+			simulation.Failed++
+		}
+		simulation.Transactions = append(simulation.Transactions, transaction)
+	}
+
+	return simulation, nil
+}
+
+// recentIdentityTransactions returns every recorded write of the current identities, most recent first
+func recentIdentityTransactions(ctx contractapi.TransactionContextInterface) ([]*SimulatedTransaction, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByRange(identityNamespace.Range(""))
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	type timedTransaction struct {
+		at          time.Time
+		transaction *SimulatedTransaction
+	}
+	var timed []timedTransaction
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		identityID, _ := identityNamespace.ID(queryResponse.Key)
+		modifications, err := identityNamespace.History(ctx, identityID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read history for %s: %v", identityID, err)
+		}
+		for _, response := range modifications {
+			if response.IsDelete || len(response.Value) == 0 {
+				continue
+			}
+
+			var identity Identity
+			err = json.Unmarshal(response.Value, &identity)
+			if err != nil {
+				return nil, err
+			}
+			at := time.Unix(response.Timestamp.Seconds, int64(response.Timestamp.Nanos)).UTC()
+			timed = append(timed, timedTransaction{at: at, transaction: &SimulatedTransaction{
+				TxID:         response.TxId,
+				IdentityID:   identityID,
+				Timestamp:    at.Format(time.RFC3339),
+				SubmitterMSP: identity.UpdatedByMSP,
+			}})
+		}
+	}
+
+	sort.SliceStable(timed, func(i, j int) bool {
+		return timed[i].at.After(timed[j].at)
+	})
+
+	transactions := make([]*SimulatedTransaction, len(timed))
+	for i := range timed {
+		transactions[i] = timed[i].transaction
+	}
+
+	return transactions, nil
+}
+
+// policyNode is a parsed signature policy. Leaves carry a principal, inner nodes require at least
+// threshold of their children.
+type policyNode struct {
+	mspID     string
+	role      string
+	threshold int
+	children  []*policyNode
+}
+
+// satisfiedBy reports whether peer endorsements from the given organizations satisfy the policy.
+// Peers match member and peer principals only.
+func (n *policyNode) satisfiedBy(endorsers map[string]bool) bool {
+	if n.children == nil {
+		return endorsers[n.mspID] && (n.role == "member" || n.role == "peer")
+	}
+
+	satisfied := 0
+	for _, child := range n.children {
+		if child.satisfiedBy(endorsers) {
+			satisfied++
+		}
+	}
+
+	return satisfied >= n.threshold
+}
+
+// parsePolicy parses the AND, OR and OutOf signature policy syntax used by the peer CLI
+func parsePolicy(policy string) (*policyNode, error) {
+	parser := &policyParser{input: policy}
+	node, err := parser.parseNode()
+	if err != nil {
+		return nil, err
+	}
+	parser.skipSpace()
+	if parser.pos != len(parser.input) {
+		return nil, fmt.Errorf("invalid policy: unexpected %q at offset %d", parser.input[parser.pos:], parser.pos)
+	}
+
+	return node, nil
+}
+
+type policyParser struct {
+	input string
+	pos   int
+}
+
+func (p *policyParser) skipSpace() {
+	for p.pos < len(p.input) && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *policyParser) expect(token byte) error {
+	p.skipSpace()
+	if p.pos >= len(p.input) || p.input[p.pos] != token {
+		return fmt.Errorf("invalid policy: expected %q at offset %d", token, p.pos)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *policyParser) parseNode() (*policyNode, error) {
+	p.skipSpace()
+	if p.pos >= len(p.input) {
+		return nil, fmt.Errorf("invalid policy: unexpected end")
+	}
+	if quote := p.input[p.pos]; quote == '\'' || quote == '"' {
+		return p.parsePrincipal(quote)
+	}
+
+	start := p.pos
+	for p.pos < len(p.input) && unicode.IsLetter(rune(p.input[p.pos])) {
+		p.pos++
+	}
+	operator := p.input[start:p.pos]
+	err := p.expect('(')
+	if err != nil {
+		return nil, err
+	}
+
+	node := &policyNode{}
+	switch operator {
+	case "AND", "OR":
+	case "OutOf":
+		p.skipSpace()
+		start = p.pos
+		for p.pos < len(p.input) && unicode.IsDigit(rune(p.input[p.pos])) {
+			p.pos++
+		}
+		node.threshold, err = strconv.Atoi(p.input[start:p.pos])
+		if err != nil {
+			return nil, fmt.Errorf("invalid policy: OutOf needs a count at offset %d", start)
+		}
+		err = p.expect(',')
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("invalid policy: unknown operator %q", operator)
+	}
+
+	for {
+		child, err := p.parseNode()
+		if err != nil {
+			return nil, err
+		}
+		node.children = append(node.children, child)
+
+		p.skipSpace()
+		if p.pos < len(p.input) && p.input[p.pos] == ',' {
+			p.pos++
+			continue
+		}
+		err = p.expect(')')
+		if err != nil {
+			return nil, err
+		}
+		break
+	}
+
+	switch operator {
+	case "AND":
+		node.threshold = len(node.children)
+	case "OR":
+		node.threshold = 1
+	}
+	if node.threshold < 1 || node.threshold > len(node.children) {
+		return nil, fmt.Errorf("invalid policy: OutOf count %d does not fit %d principals", node.threshold, len(node.children))
+	}
+
+	return node, nil
+}
+
+func (p *policyParser) parsePrincipal(quote byte) (*policyNode, error) {
+	p.pos++
+	end := strings.IndexByte(p.input[p.pos:], quote)
+	if end < 0 {
+		return nil, fmt.Errorf("invalid policy: unterminated principal at offset %d", p.pos-1)
+	}
+	principal := p.input[p.pos : p.pos+end]
+	p.pos += end + 1
+
+	dot := strings.LastIndexByte(principal, '.')
+	if dot <= 0 {
+		return nil, fmt.Errorf("invalid policy: principal %q must be MSPID.role", principal)
+	}
+	role := principal[dot+1:]
+	switch role {
+	case "member", "peer", "admin", "client", "orderer":
+	default:
+		return nil, fmt.Errorf("invalid policy: unknown role %q in %q", role, principal)
+	}
+
+	return &policyNode{mspID: principal[:dot], role: role}, nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimulateEndorsementPolicy(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	simulate := func(policy string, sampleSize int, sampled int, failed int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			simulation, err := s.SimulateEndorsementPolicy(ctx, policy, sampleSize)
+			if err == nil {
+				require.Equal(t, sampled, simulation.Sampled)
+				require.Equal(t, failed, simulation.Failed)
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		createIdentity(partner, "identity2", "54321-0987654-3"),
+		updateIdentity(registrar, "identity1", "03009999999", ""),
+		{Name: "single organization", Caller: admin, Run: simulate("AND('Org1MSP.member')", 10, 3, 1)},
+		{Name: "either organization", Caller: admin, Run: simulate(`OR('Org1MSP.peer', "Org2MSP.peer")`, 10, 3, 0)},
+		{Name: "both organizations", Caller: admin, Run: simulate("OutOf(2, 'Org1MSP.member', 'Org2MSP.member')", 10, 3, 3)},
+		{Name: "admins do not endorse", Caller: admin, Run: simulate("OR('Org1MSP.admin', 'Org2MSP.admin')", 10, 3, 3)},
+		{
+			Name:   "most recent transactions first",
+			Caller: admin,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				simulation, err := s.SimulateEndorsementPolicy(ctx, "AND('Org2MSP.member')", 2)
+				if err == nil {
+					require.Len(t, simulation.Transactions, 2)
+					require.Equal(t, "identity1", simulation.Transactions[0].IdentityID)
+					require.False(t, simulation.Transactions[0].Endorsed)
+					require.Equal(t, "identity2", simulation.Transactions[1].IdentityID)
+					require.Equal(t, "Org2MSP", simulation.Transactions[1].SubmitterMSP)
+					require.True(t, simulation.Transactions[1].Endorsed)
+				}
+				return err
+			},
+		},
+		{Name: "requires admin", Caller: registrar, Run: simulate("AND('Org1MSP.member')", 10, 0, 0), Err: "requires role admin"},
+		{Name: "sample too small", Caller: admin, Run: simulate("AND('Org1MSP.member')", 0, 0, 0), Err: "sample size must be between 1 and 500"},
+		{Name: "sample too large", Caller: admin, Run: simulate("AND('Org1MSP.member')", 501, 0, 0), Err: "sample size must be between 1 and 500"},
+		{Name: "unknown operator", Caller: admin, Run: simulate("XOR('Org1MSP.member')", 10, 0, 0), Err: `unknown operator "XOR"`},
+		{Name: "principal without role", Caller: admin, Run: simulate("AND('Org1MSP')", 10, 0, 0), Err: `principal "Org1MSP" must be MSPID.role`},
+		{Name: "unknown role", Caller: admin, Run: simulate("AND('Org1MSP.owner')", 10, 0, 0), Err: `unknown role "owner"`},
+		{Name: "unterminated principal", Caller: admin, Run: simulate("AND('Org1MSP.member)", 10, 0, 0), Err: "unterminated principal"},
+		{Name: "unbalanced parentheses", Caller: admin, Run: simulate("AND('Org1MSP.member'", 10, 0, 0), Err: `expected ')'`},
+		{Name: "trailing input", Caller: admin, Run: simulate("AND('Org1MSP.member') x", 10, 0, 0), Err: `unexpected "x"`},
+		{Name: "OutOf without count", Caller: admin, Run: simulate("OutOf('Org1MSP.member')", 10, 0, 0), Err: "OutOf needs a count"},
+		{Name: "OutOf count too large", Caller: admin, Run: simulate("OutOf(2, 'Org1MSP.member')", 10, 0, 0), Err: "OutOf count 2 does not fit 1 principals"},
+		{Name: "empty policy", Caller: admin, Run: simulate("", 10, 0, 0), Err: "unexpected end"},
+	})
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+const (
+	countryObjectType  = "country"
+	alpha3Index        = "alpha3~country"
+	currencyObjectType = "currency"
+)
+
+var (
+	countryRepo  = repository.New[Country](countryObjectType)
+	currencyRepo = repository.New[Currency](currencyObjectType)
+)
+
+var (
+	alpha2Pattern       = regexp.MustCompile(`^[A-Z]{2}$`)
+	alpha3Pattern       = regexp.MustCompile(`^[A-Z]{3}$`)
+	currencyCodePattern = regexp.MustCompile(`^[A-Z]{3}$`)
+)
+
+// ReferenceData is the contract managing the ISO 3166 country and ISO 4217 currency codes that
+// identity nationalities and loan currencies are validated against. Its functions are invoked
+// with the ReferenceData: prefix, for example ReferenceData:GetCountry.
+type ReferenceData struct {
+	contractapi.Contract
+}
+
+// Country is an ISO 3166-1 country with its alpha-2 and alpha-3 codes
+type Country struct {
+	Alpha2 string `json:"alpha2"`
+	Alpha3 string `json:"alpha3"`
+	Name   string `json:"name"`
+}
+
+// Currency is an ISO 4217 currency with the number of digits after the decimal separator
+type Currency struct {
+	Code       string `json:"code"`
+	Name       string `json:"name"`
+	MinorUnits int    `json:"minorUnits"`
+}
+
+// referenceCountries is the country bundle written by LoadReferenceBundle
+var referenceCountries = []Country{
+	{"AE", "ARE", "United Arab Emirates"},
+	{"AF", "AFG", "Afghanistan"},
+	{"AU", "AUS", "Australia"},
+	{"BD", "BGD", "Bangladesh"},
+	{"BH", "BHR", "Bahrain"},
+	{"CA", "CAN", "Canada"},
+	{"CH", "CHE", "Switzerland"},
+	{"CN", "CHN", "China"},
+	{"DE", "DEU", "Germany"},
+	{"EG", "EGY", "Egypt"},
+	{"ES", "ESP", "Spain"},
+	{"FR", "FRA", "France"},
+	{"GB", "GBR", "United Kingdom of Great Britain and Northern Ireland"},
+	{"ID", "IDN", "Indonesia"},
+	{"IN", "IND", "India"},
+	{"IQ", "IRQ", "Iraq"},
+	{"IR", "IRN", "Iran (Islamic Republic of)"},
+	{"IT", "ITA", "Italy"},
+	{"JP", "JPN", "Japan"},
+	{"KW", "KWT", "Kuwait"},
+	{"LK", "LKA", "Sri Lanka"},
+	{"MY", "MYS", "Malaysia"},
+	{"NL", "NLD", "Netherlands"},
+	{"NP", "NPL", "Nepal"},
+	{"OM", "OMN", "Oman"},
+	{"PK", "PAK", "Pakistan"},
+	{"QA", "QAT", "Qatar"},
+	{"SA", "SAU", "Saudi Arabia"},
+	{"SG", "SGP", "Singapore"},
+	{"TR", "TUR", "Türkiye"},
+	{"US", "USA", "United States of America"},
+}
+
+// referenceCurrencies is the currency bundle written by LoadReferenceBundle
+var referenceCurrencies = []Currency{
+	{"AED", "UAE Dirham", 2},
+	{"AUD", "Australian Dollar", 2},
+	{"BDT", "Taka", 2},
+	{"BHD", "Bahraini Dinar", 3},
+	{"CAD", "Canadian Dollar", 2},
+	{"CHF", "Swiss Franc", 2},
+	{"CNY", "Yuan Renminbi", 2},
+	{"EUR", "Euro", 2},
+	{"GBP", "Pound Sterling", 2},
+	{"INR", "Indian Rupee", 2},
+	{"JPY", "Yen", 0},
+	{"KWD", "Kuwaiti Dinar", 3},
+	{"LKR", "Sri Lanka Rupee", 2},
+	{"MYR", "Malaysian Ringgit", 2},
+	{"OMR", "Rial Omani", 3},
+	{"PKR", "Pakistan Rupee", 2},
+	{"QAR", "Qatari Rial", 2},
+	{"SAR", "Saudi Riyal", 2},
+	{"SGD", "Singapore Dollar", 2},
+	{"TRY", "Turkish Lira", 2},
+	{"USD", "US Dollar", 2},
+}
+
+// LoadReferenceBundle writes the built-in country and currency bundle, overwriting entries with
+// the same codes. Requires the role=admin attribute.
+func (r *ReferenceData) LoadReferenceBundle(ctx contractapi.TransactionContextInterface) error {
+	err := requireAdmin(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i := range referenceCountries {
+		err = putCountry(ctx, &referenceCountries[i])
+		if err != nil {
+			return err
+		}
+	}
+	for i := range referenceCurrencies {
+		err = putCurrency(ctx, &referenceCurrencies[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RegisterCountry adds or corrects a single country. Requires the role=admin attribute.
+func (r *ReferenceData) RegisterCountry(ctx contractapi.TransactionContextInterface, alpha2 string, alpha3 string, name string) error {
+	err := requireAdmin(ctx)
+	if err != nil {
+		return err
+	}
+
+	country := Country{Alpha2: strings.ToUpper(alpha2), Alpha3: strings.ToUpper(alpha3), Name: name}
+	if !alpha2Pattern.MatchString(country.Alpha2) || !alpha3Pattern.MatchString(country.Alpha3) {
+		return fmt.Errorf("country codes must be ISO 3166-1 alpha-2 and alpha-3 codes")
+	}
+	if name == "" {
+		return fmt.Errorf("country name must not be empty")
+	}
+
+	existing, err := getCountry(ctx, country.Alpha2)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.Alpha3 != country.Alpha3 {
+		alpha3Key, err := ctx.GetStub().CreateCompositeKey(alpha3Index, []string{existing.Alpha3})
+		if err != nil {
+			return fmt.Errorf("failed to create composite key: %v", err)
+		}
+		err = ctx.GetStub().DelState(alpha3Key)
+		if err != nil {
+			return err
+		}
+	}
+
+	return putCountry(ctx, &country)
+}
+
+// RegisterCurrency adds or corrects a single currency. Requires the role=admin attribute.
+func (r *ReferenceData) RegisterCurrency(ctx contractapi.TransactionContextInterface, code string, name string, minorUnits int) error {
+	err := requireAdmin(ctx)
+	if err != nil {
+		return err
+	}
+
+	currency := Currency{Code: strings.ToUpper(code), Name: name, MinorUnits: minorUnits}
+	if !currencyCodePattern.MatchString(currency.Code) {
+		return fmt.Errorf("currency code must be an ISO 4217 alphabetic code")
+	}
+	if name == "" {
+		return fmt.Errorf("currency name must not be empty")
+	}
+	if minorUnits < 0 || minorUnits > 4 {
+		return fmt.Errorf("minor units must be between 0 and 4")
+	}
+
+	return putCurrency(ctx, &currency)
+}
+
+// GetCountry returns the country with the given alpha-2 or alpha-3 code, in any letter case
+func (r *ReferenceData) GetCountry(ctx contractapi.TransactionContextInterface, code string) (*Country, error) {
+	country, err := lookupCountry(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if country == nil {
+		return nil, fmt.Errorf("unknown country code %q", code)
+	}
+
+	return country, nil
+}
+
+// GetCurrency returns the currency with the given ISO 4217 code, in any letter case
+func (r *ReferenceData) GetCurrency(ctx contractapi.TransactionContextInterface, code string) (*Currency, error) {
+	currency, err := getCurrency(ctx, strings.ToUpper(code))
+	if err != nil {
+		return nil, err
+	}
+	if currency == nil {
+		return nil, fmt.Errorf("unknown currency code %q", code)
+	}
+
+	return currency, nil
+}
+
+// GetAllCountries returns every registered country ordered by alpha-2 code
+func (r *ReferenceData) GetAllCountries(ctx contractapi.TransactionContextInterface) ([]*Country, error) {
+	return countryRepo.List(ctx)
+}
+
+// GetAllCurrencies returns every registered currency ordered by code
+func (r *ReferenceData) GetAllCurrencies(ctx contractapi.TransactionContextInterface) ([]*Currency, error) {
+	return currencyRepo.List(ctx)
+}
+
+// SetNationality records the nationality of an identity as the ISO 3166-1 alpha-2 code of a
+// registered country, given as either its alpha-2 or alpha-3 code. Callers outside the owning
+// organization need an active apply delegation.
+func (s *SmartContract) SetNationality(ctx contractapi.TransactionContextInterface, id string, country string) error {
+	identity, err := s.getIdentity(ctx, id)
+	if err != nil {
+		return err
+	}
+	delegated, err := authorizeWrite(ctx, identity)
+	if err != nil {
+		return err
+	}
+
+	identity.Nationality, err = canonicalCountry(ctx, country)
+	if err != nil {
+		return err
+	}
+
+	err = stampModifier(ctx, identity)
+	if err != nil {
+		return err
+	}
+	identity.UpdatedByDelegate = delegated
+
+	err = putIdentity(ctx, identity)
+	if err != nil {
+		return err
+	}
+
+	return emitIdentityEvent(ctx, EventIdentityUpdated, IdentityEvent{IdentityID: identity.ID, OwnerMSP: identity.OwnerMSP})
+}
+
+// canonicalCountry validates a country given as an alpha-2 or alpha-3 code and returns its
+// alpha-2 code, the form stored on identities
+func canonicalCountry(ctx contractapi.TransactionContextInterface, code string) (string, error) {
+	country, err := lookupCountry(ctx, code)
+	if err != nil {
+		return "", err
+	}
+	if country == nil {
+		return "", fmt.Errorf("unknown country code %q, expected an ISO 3166-1 alpha-2 or alpha-3 code", code)
+	}
+
+	return country.Alpha2, nil
+}
+
+// lookupCountry returns the country with the given alpha-2 or alpha-3 code, or nil if there is none
+func lookupCountry(ctx contractapi.TransactionContextInterface, code string) (*Country, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if alpha2Pattern.MatchString(code) {
+		return getCountry(ctx, code)
+	}
+	if !alpha3Pattern.MatchString(code) {
+		return nil, nil
+	}
+
+	alpha3Key, err := ctx.GetStub().CreateCompositeKey(alpha3Index, []string{code})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	alpha2, err := ctx.GetStub().GetState(alpha3Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if alpha2 == nil {
+		return nil, nil
+	}
+
+	return getCountry(ctx, string(alpha2))
+}
+
+func getCountry(ctx contractapi.TransactionContextInterface, alpha2 string) (*Country, error) {
+	return countryRepo.Get(ctx, alpha2)
+}
+
+// putCountry writes the country together with its alpha-3 index entry, which holds the alpha-2 code
+func putCountry(ctx contractapi.TransactionContextInterface, country *Country) error {
+	countryKey, err := ctx.GetStub().CreateCompositeKey(countryObjectType, []string{country.Alpha2})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	countryJSON, err := json.Marshal(country)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().PutState(countryKey, countryJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put to world state: %v", err)
+	}
+
+	alpha3Key, err := ctx.GetStub().CreateCompositeKey(alpha3Index, []string{country.Alpha3})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	return ctx.GetStub().PutState(alpha3Key, []byte(country.Alpha2))
+}
+
+func getCurrency(ctx contractapi.TransactionContextInterface, code string) (*Currency, error) {
+	return currencyRepo.Get(ctx, code)
+}
+
+func putCurrency(ctx contractapi.TransactionContextInterface, currency *Currency) error {
+	return currencyRepo.Put(ctx, currency, currency.Code)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReferenceData(t *testing.T) {
+	r := new(ReferenceData)
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	country := func(code string, want string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			country, err := r.GetCountry(ctx, code)
+			if err == nil {
+				require.Equal(t, want, country.Alpha2)
+			}
+			return err
+		}
+	}
+	registerCountry := func(alpha2 string, alpha3 string, name string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return r.RegisterCountry(ctx, alpha2, alpha3, name)
+		}
+	}
+	registerCurrency := func(code string, name string, minorUnits int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return r.RegisterCurrency(ctx, code, name, minorUnits)
+		}
+	}
+	nationality := func(code string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.SetNationality(ctx, "identity1", code)
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "unknown countries before loading", Caller: registrar, Run: country("PK", ""), Err: `unknown country code "PK"`},
+		{
+			Name:   "load bundle requires admin",
+			Caller: registrar,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return r.LoadReferenceBundle(ctx)
+			},
+			Err: "requires role admin",
+		},
+		{
+			Name:   "load bundle",
+			Caller: admin,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return r.LoadReferenceBundle(ctx)
+			},
+		},
+		{Name: "country by alpha-2 code", Caller: partner, Run: country("PK", "PK")},
+		{Name: "country by alpha-3 code in lower case", Caller: partner, Run: country("pak", "PK")},
+		{Name: "unknown alpha-3 code", Caller: partner, Run: country("XKX", ""), Err: `unknown country code "XKX"`},
+		{Name: "malformed code", Caller: partner, Run: country("Pakistan", ""), Err: `unknown country code "Pakistan"`},
+		{
+			Name:   "currency in lower case",
+			Caller: partner,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				currency, err := r.GetCurrency(ctx, "kwd")
+				if err == nil {
+					require.Equal(t, &Currency{Code: "KWD", Name: "Kuwaiti Dinar", MinorUnits: 3}, currency)
+				}
+				return err
+			},
+		},
+		{
+			Name:   "unknown currency",
+			Caller: partner,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := r.GetCurrency(ctx, "XYZ")
+				return err
+			},
+			Err: `unknown currency code "XYZ"`,
+		},
+		{
+			Name:   "all countries and currencies",
+			Caller: partner,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				countries, err := r.GetAllCountries(ctx)
+				require.NoError(t, err)
+				require.Len(t, countries, len(referenceCountries))
+				require.Equal(t, "AE", countries[0].Alpha2)
+
+				currencies, err := r.GetAllCurrencies(ctx)
+				if err == nil {
+					require.Len(t, currencies, len(referenceCurrencies))
+				}
+				return err
+			},
+		},
+		{Name: "register country", Caller: admin, Run: registerCountry("xk", "xkx", "Kosovo")},
+		{Name: "registered country by alpha-3 code", Caller: partner, Run: country("XKX", "XK")},
+		{Name: "correct alpha-3 code", Caller: admin, Run: registerCountry("XK", "XKK", "Kosovo")},
+		{Name: "former alpha-3 code is dropped", Caller: partner, Run: country("XKX", ""), Err: `unknown country code "XKX"`},
+		{Name: "corrected alpha-3 code", Caller: partner, Run: country("XKK", "XK")},
+		{Name: "register country requires admin", Caller: registrar, Run: registerCountry("XK", "XKX", "Kosovo"), Err: "requires role admin"},
+		{Name: "register malformed country", Caller: admin, Run: registerCountry("XKX", "XK", "Kosovo"), Err: "country codes must be ISO 3166-1 alpha-2 and alpha-3 codes"},
+		{Name: "register unnamed country", Caller: admin, Run: registerCountry("XK", "XKX", ""), Err: "country name must not be empty"},
+		{Name: "register currency", Caller: admin, Run: registerCurrency("xau", "Gold", 4)},
+		{Name: "register currency requires admin", Caller: registrar, Run: registerCurrency("XAU", "Gold", 4), Err: "requires role admin"},
+		{Name: "register malformed currency", Caller: admin, Run: registerCurrency("GOLD", "Gold", 4), Err: "currency code must be an ISO 4217 alphabetic code"},
+		{Name: "register unnamed currency", Caller: admin, Run: registerCurrency("XAU", "", 4), Err: "currency name must not be empty"},
+		{Name: "register currency with too many minor units", Caller: admin, Run: registerCurrency("XAU", "Gold", 5), Err: "minor units must be between 0 and 4"},
+		{Name: "set nationality", Caller: registrar, Run: nationality("pak")},
+		{
+			Name:   "nationalities are kept as alpha-2 codes",
+			Caller: registrar,
+			Run: readIdentity("identity1", func(t *testing.T, identity *Identity) {
+				require.Equal(t, "PK", identity.Nationality)
+			}),
+		},
+		{Name: "set unknown nationality", Caller: registrar, Run: nationality("ZZZ"), Err: `unknown country code "ZZZ", expected an ISO 3166-1 alpha-2 or alpha-3 code`},
+		{Name: "set nationality from another organization", Caller: partner, Run: nationality("GB"), Err: "no delegation is active"},
+	})
+}
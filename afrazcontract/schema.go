@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"authz"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+const (
+	schemaObjectType       = "schema"
+	activeSchemaObjectType = "activeschema"
+)
+
+var schemaRepo = repository.New[IdentitySchema](schemaObjectType)
+
+// SchemaRegistry is the contract managing identity attribute schemas. Its functions are invoked
+// with the SchemaRegistry: prefix, for example SchemaRegistry:RegisterSchema.
+type SchemaRegistry struct {
+	contractapi.Contract
+}
+
+// IdentitySchema lists the identity fields required on creation and the regular expressions their
+// values must match, keyed by JSON field name
+type IdentitySchema struct {
+	Version          string            `json:"version"`
+	RequiredFields   []string          `json:"requiredFields,omitempty" metadata:",optional"`
+	RegexConstraints map[string]string `json:"regexConstraints,omitempty" metadata:",optional"`
+	RegisteredAt     string            `json:"registeredAt"`
+	RegisteredByMSP  string            `json:"registeredByMSP"`
+}
+
+// RegisterSchema stores a new schema version and makes it the active one used by CreateIdentity.
+// Versions are immutable. Requires the role=admin attribute.
+func (r *SchemaRegistry) RegisterSchema(ctx contractapi.TransactionContextInterface, version string, requiredFields []string, regexConstraints map[string]string) error {
+	err := requireAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	if version == "" {
+		return fmt.Errorf("schema version must not be empty")
+	}
+
+	existing, err := getSchema(ctx, version)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("schema version %s already exists", version)
+	}
+
+	for _, field := range requiredFields {
+		if !identityFieldNames[field] {
+			return fmt.Errorf("unknown identity field %s", field)
+		}
+	}
+	for field, expr := range regexConstraints {
+		if !identityFieldNames[field] {
+			return fmt.Errorf("unknown identity field %s", field)
+		}
+		_, err = regexp.Compile(expr)
+		if err != nil {
+			return fmt.Errorf("invalid constraint for %s: %v", field, err)
+		}
+	}
+
+	mspID, err := authz.CallerMSP(ctx)
+	if err != nil {
+		return err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	schema := IdentitySchema{
+		Version:          version,
+		RequiredFields:   requiredFields,
+		RegexConstraints: regexConstraints,
+		RegisteredAt:     now.Format(time.RFC3339),
+		RegisteredByMSP:  mspID,
+	}
+	schemaKey, err := ctx.GetStub().CreateCompositeKey(schemaObjectType, []string{version})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	schemaJSON, err := json.Marshal(schema)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().PutState(schemaKey, schemaJSON)
+	if err != nil {
+		return err
+	}
+
+	activeKey, err := ctx.GetStub().CreateCompositeKey(activeSchemaObjectType, []string{})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	return ctx.GetStub().PutState(activeKey, []byte(version))
+}
+
+// GetSchema returns the schema with the given version
+func (r *SchemaRegistry) GetSchema(ctx contractapi.TransactionContextInterface, version string) (*IdentitySchema, error) {
+	schema, err := getSchema(ctx, version)
+	if err != nil {
+		return nil, err
+	}
+	if schema == nil {
+		return nil, fmt.Errorf("schema version %s does not exist", version)
+	}
+
+	return schema, nil
+}
+
+// GetActiveSchema returns the schema currently applied to new identities
+func (r *SchemaRegistry) GetActiveSchema(ctx contractapi.TransactionContextInterface) (*IdentitySchema, error) {
+	schema, err := activeSchema(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if schema == nil {
+		return nil, fmt.Errorf("no schema has been registered")
+	}
+
+	return schema, nil
+}
+
+// validateAgainstSchema checks a new identity against the active schema and stamps it with the
+// schema version. Without a registered schema identities are accepted as they are.
+func validateAgainstSchema(ctx contractapi.TransactionContextInterface, identity *Identity) error {
+	schema, err := activeSchema(ctx)
+	if err != nil {
+		return err
+	}
+	if schema == nil {
+		return nil
+	}
+
+	values, err := identityFields(identity)
+	if err != nil {
+		return err
+	}
+	for _, field := range schema.RequiredFields {
+		if values[field] == "" {
+			return fmt.Errorf("field %s is required by schema version %s", field, schema.Version)
+		}
+	}
+
+	fields := make([]string, 0, len(schema.RegexConstraints))
+	for field := range schema.RegexConstraints {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		if values[field] == "" {
+			continue
+		}
+		matched, err := regexp.MatchString(schema.RegexConstraints[field], values[field])
+		if err != nil {
+			return err
+		}
+		if !matched {
+			return fmt.Errorf("field %s does not match the format required by schema version %s", field, schema.Version)
+		}
+	}
+
+	identity.SchemaVersion = schema.Version
+	return nil
+}
+
+func activeSchema(ctx contractapi.TransactionContextInterface) (*IdentitySchema, error) {
+	activeKey, err := ctx.GetStub().CreateCompositeKey(activeSchemaObjectType, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	version, err := ctx.GetStub().GetState(activeKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if version == nil {
+		return nil, nil
+	}
+
+	return getSchema(ctx, string(version))
+}
+
+func getSchema(ctx contractapi.TransactionContextInterface, version string) (*IdentitySchema, error) {
+	return schemaRepo.Get(ctx, version)
+}
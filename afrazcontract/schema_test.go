@@ -0,0 +1,83 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaRegistry(t *testing.T) {
+	r := new(SchemaRegistry)
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	register := func(version string, requiredFields []string, regexConstraints map[string]string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return r.RegisterSchema(ctx, version, requiredFields, regexConstraints)
+		}
+	}
+	active := func(want string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			schema, err := r.GetActiveSchema(ctx)
+			if err == nil {
+				require.Equal(t, want, schema.Version)
+			}
+			return err
+		}
+	}
+	create := func(id string, cnic string, mobile string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.CreateIdentity(ctx, id, "Ms.", "Jane", "Roe", cnic, "1990-05-17", "Female", mobile)
+		}
+	}
+	cnicFormat := map[string]string{"cnic": `^\d{5}-\d{7}-\d$`}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "no active schema", Caller: registrar, Run: active(""), Err: "no schema has been registered"},
+		{Name: "identities are accepted without a schema", Caller: registrar, Run: create("identity2", "not a cnic", "")},
+		{Name: "register schema", Caller: admin, Run: register("v1", []string{"mobileNumber"}, cnicFormat)},
+		{Name: "registered schema is active", Caller: registrar, Run: active("v1")},
+		{Name: "register newer schema", Caller: admin, Run: register("v2", []string{"mobileNumber", "gender"}, cnicFormat)},
+		{Name: "newer schema is active", Caller: registrar, Run: active("v2")},
+		{
+			Name:   "earlier versions are kept",
+			Caller: registrar,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				schema, err := r.GetSchema(ctx, "v1")
+				if err == nil {
+					require.Equal(t, []string{"mobileNumber"}, schema.RequiredFields)
+					require.Equal(t, cnicFormat, schema.RegexConstraints)
+					require.Equal(t, "Org1MSP", schema.RegisteredByMSP)
+				}
+				return err
+			},
+		},
+		{
+			Name:   "missing version",
+			Caller: registrar,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := r.GetSchema(ctx, "v9")
+				return err
+			},
+			Err: "schema version v9 does not exist",
+		},
+		{Name: "versions are immutable", Caller: admin, Run: register("v1", nil, nil), Err: "schema version v1 already exists"},
+		{Name: "register requires admin", Caller: registrar, Run: register("v3", nil, nil), Err: "requires role admin"},
+		{Name: "register without version", Caller: admin, Run: register("", nil, nil), Err: "schema version must not be empty"},
+		{Name: "require unknown field", Caller: admin, Run: register("v3", []string{"shoeSize"}, nil), Err: "unknown identity field shoeSize"},
+		{Name: "constrain unknown field", Caller: admin, Run: register("v3", nil, map[string]string{"shoeSize": `^\d+$`}), Err: "unknown identity field shoeSize"},
+		{Name: "invalid constraint", Caller: admin, Run: register("v3", nil, map[string]string{"cnic": `^(\d{5}`}), Err: "invalid constraint for cnic"},
+		{Name: "create without a required field", Caller: registrar, Run: create("identity3", "54321-0987654-3", ""), Err: "field mobileNumber is required by schema version v2"},
+		{Name: "create with a malformed field", Caller: registrar, Run: create("identity3", "5432109876543", "03211234567"), Err: "field cnic does not match the format required by schema version v2"},
+		{Name: "create conforming identity", Caller: registrar, Run: create("identity3", "54321-0987654-3", "03211234567")},
+		{
+			Name:   "identities record their schema version",
+			Caller: registrar,
+			Run: readIdentity("identity3", func(t *testing.T, identity *Identity) {
+				require.Equal(t, "v2", identity.SchemaVersion)
+			}),
+		},
+	})
+}
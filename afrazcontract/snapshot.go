@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"authz"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+// ExportState returns one page of the world state as canonical JSON, for migrating the identities
+// to another network or chaincode name. A prefix exports the composite keys of that object type;
+// the empty prefix exports the identities and other plain keys. Pass the bookmark of a page to
+// read the next one. The hash of every page belongs in the manifest of the export, against which
+// ImportState checks the page. Private data, the personal data of identities and their biometric
+// bindings, is not exported. Restricted to admins; evaluate it rather than submitting it.
+func (s *SmartContract) ExportState(ctx contractapi.TransactionContextInterface, prefix string, pageSize int, bookmark string) (string, error) {
+	err := requireAdmin(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	page, err := repository.ExportState(ctx, prefix, int32(pageSize), bookmark)
+	if err != nil {
+		return "", err
+	}
+	pageJSON, err := json.Marshal(page)
+	if err != nil {
+		return "", err
+	}
+
+	return string(pageJSON), nil
+}
+
+// ImportState writes a page returned by ExportState, overwriting the keys it holds, once its
+// entries match its hash. Restricted to admins.
+func (s *SmartContract) ImportState(ctx contractapi.TransactionContextInterface, pageJSON string) (*repository.ImportReceipt, error) {
+	err := requireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var page repository.StatePage
+	err = json.Unmarshal([]byte(pageJSON), &page)
+	if err != nil {
+		return nil, fmt.Errorf("invalid state page: %v", err)
+	}
+	receipt, err := repository.ImportState(ctx, &page)
+	if err != nil {
+		return nil, err
+	}
+	authz.TxLogger(ctx).Info("state page imported", "prefix", receipt.Prefix, "hash", receipt.Hash, "keys", receipt.Keys)
+
+	return receipt, nil
+}
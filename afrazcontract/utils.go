@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"authz"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// txTime returns the transaction timestamp, which is identical on every endorsing peer
+func txTime(ctx contractapi.TransactionContextInterface) (time.Time, error) {
+	ts, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	return time.Unix(ts.Seconds, int64(ts.Nanos)).UTC(), nil
+}
+
+// parseDate accepts RFC3339 timestamps as well as plain dates in YYYY-MM-DD or DD-MM-YYYY form
+func parseDate(value string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02", "02-01-2006"} {
+		parsed, err := time.Parse(layout, value)
+		if err == nil {
+			return parsed.UTC(), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD, DD-MM-YYYY or RFC3339", value)
+}
+
+// requireAdmin returns an error unless the submitting client carries the role=admin attribute
+func requireAdmin(ctx contractapi.TransactionContextInterface) error {
+	role, _, err := authz.Attribute(ctx, "role")
+	if err != nil {
+		return err
+	}
+	if role != "admin" {
+		return fmt.Errorf("submitting client not authorized, requires role admin")
+	}
+
+	return nil
+}
@@ -1,34 +1,261 @@
 package main
 
 import (
-    "fmt"
-    "encoding/json"
-    "github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"authz"
+	"ccaas"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
 )
 
-// SmartContract provides functions for managing applications
+// SmartContract provides functions for managing loan catalog assets under attribute-based
+// access control
 type SmartContract struct {
-    contractapi.Contract
+	contractapi.Contract
 }
 
-// InitLedger adds initial data to the ledger
-func (s *SmartContract) InitLedger(ctx contractapi.TransactionContextInterface) error {
-    return nil
+// Asset describes basic details of what makes up a simple asset
+type Asset struct {
+	ID             string `json:"ID"`
+	Color          string `json:"color"`
+	Size           int    `json:"size"`
+	Owner          string `json:"owner"`
+	AppraisedValue int    `json:"appraisedValue"`
 }
 
-// Example function
-func (s *SmartContract) Ping(ctx contractapi.TransactionContextInterface) (string, error) {
-    return "Pong", nil
+// CreateAsset issues a new asset to the world state with given details.
+func (s *SmartContract) CreateAsset(ctx contractapi.TransactionContextInterface, id string, color string, size int, appraisedValue int) error {
+
+	// Demonstrate the use of Attribute-Based Access Control (ABAC) by checking
+	// to see if the caller has the "abac.creator" attribute with a value of true;
+	// if not, return an error.
+
+	client, err := authz.ClientOf(ctx)
+	if err != nil {
+		return err
+	}
+	if !client.HasAttribute("abac.creator", "true") {
+		return fmt.Errorf("submitting client not authorized to create asset, does not have abac.creator role")
+	}
+
+	exists, err := s.AssetExists(ctx, id)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("the asset %s already exists", id)
+	}
+
+	// Get ID of submitting client identity
+	clientID, err := s.GetSubmittingClientIdentity(ctx)
+	if err != nil {
+		return err
+	}
+
+	asset := Asset{
+		ID:             id,
+		Color:          color,
+		Size:           size,
+		Owner:          clientID,
+		AppraisedValue: appraisedValue,
+	}
+	assetJSON, err := json.Marshal(asset)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(id, assetJSON)
 }
 
+// UpdateAsset updates an existing asset in the world state with provided parameters.
+func (s *SmartContract) UpdateAsset(ctx contractapi.TransactionContextInterface, id string, newColor string, newSize int, newValue int) error {
+
+	asset, err := s.ReadAsset(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	clientID, err := s.GetSubmittingClientIdentity(ctx)
+	if err != nil {
+		return err
+	}
+
+	if clientID != asset.Owner {
+		return fmt.Errorf("submitting client not authorized to update asset, does not own asset")
+	}
+
+	asset.Color = newColor
+	asset.Size = newSize
+	asset.AppraisedValue = newValue
+
+	assetJSON, err := json.Marshal(asset)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(id, assetJSON)
+}
+
+// DeleteAsset deletes a given asset from the world state.
+func (s *SmartContract) DeleteAsset(ctx contractapi.TransactionContextInterface, id string) error {
+
+	asset, err := s.ReadAsset(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	clientID, err := s.GetSubmittingClientIdentity(ctx)
+	if err != nil {
+		return err
+	}
+
+	if clientID != asset.Owner {
+		return fmt.Errorf("submitting client not authorized to update asset, does not own asset")
+	}
+
+	return ctx.GetStub().DelState(id)
+}
+
+// TransferAsset updates the owner field of asset with given id in world state.
+func (s *SmartContract) TransferAsset(ctx contractapi.TransactionContextInterface, id string, newOwner string) error {
+
+	asset, err := s.ReadAsset(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	clientID, err := s.GetSubmittingClientIdentity(ctx)
+	if err != nil {
+		return err
+	}
+
+	if clientID != asset.Owner {
+		return fmt.Errorf("submitting client not authorized to update asset, does not own asset")
+	}
+
+	asset.Owner = newOwner
+	assetJSON, err := json.Marshal(asset)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(id, assetJSON)
+}
+
+// ReadAsset returns the asset stored in the world state with given id.
+func (s *SmartContract) ReadAsset(ctx contractapi.TransactionContextInterface, id string) (*Asset, error) {
+
+	assetJSON, err := ctx.GetStub().GetState(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if assetJSON == nil {
+		return nil, fmt.Errorf("the asset %s does not exist", id)
+	}
+
+	var asset Asset
+	err = json.Unmarshal(assetJSON, &asset)
+	if err != nil {
+		return nil, err
+	}
+
+	return &asset, nil
+}
+
+// GetAllAssets returns all assets found in world state
+func (s *SmartContract) GetAllAssets(ctx contractapi.TransactionContextInterface) ([]*Asset, error) {
+	// range query with empty string for startKey and endKey does an
+	// open-ended query of all assets in the chaincode namespace.
+	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var assets []*Asset
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var asset Asset
+		err = json.Unmarshal(queryResponse.Value, &asset)
+		if err != nil {
+			return nil, err
+		}
+		assets = append(assets, &asset)
+	}
+
+	return assets, nil
+}
+
+// AssetExists returns true when asset with given ID exists in world state
+func (s *SmartContract) AssetExists(ctx contractapi.TransactionContextInterface, id string) (bool, error) {
+
+	assetJSON, err := ctx.GetStub().GetState(id)
+	if err != nil {
+		return false, fmt.Errorf("failed to read from world state: %v", err)
+	}
+
+	return assetJSON != nil, nil
+}
+
+// GetSubmittingClientIdentity returns the name and issuer of the identity that
+// invokes the smart contract. This function base64 decodes the identity string
+// before returning the value to the client or smart contract.
+func (s *SmartContract) GetSubmittingClientIdentity(ctx contractapi.TransactionContextInterface) (string, error) {
+
+	client, err := authz.ClientOf(ctx)
+	if err != nil {
+		return "", fmt.Errorf("Failed to read clientID: %v", err)
+	}
+	decodeID, err := base64.StdEncoding.DecodeString(client.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to base64 decode clientID: %v", err)
+	}
+	return string(decodeID), nil
+}
+
+// GetAuditTrail returns up to pageSize entries of the audit trail, from the transaction startTx up
+// to but excluding endTx in transaction ID order. Evaluate it rather than submitting it.
+func (s *SmartContract) GetAuditTrail(ctx contractapi.TransactionContextInterface, startTx string, endTx string, pageSize int) (*authz.AuditPage, error) {
+	return authz.AuditTrail(ctx, startTx, endTx, pageSize)
+}
+
+// SetLogLevel changes the chaincode's log level until it restarts, after which
+// CORE_CHAINCODE_LOGGING_LEVEL applies again. Submit it so that every endorsing peer's chaincode
+// runs it. Restricted to the admin role.
+func (s *SmartContract) SetLogLevel(ctx contractapi.TransactionContextInterface, level string) error {
+	client, err := authz.ClientOf(ctx)
+	if err != nil {
+		return err
+	}
+	if !client.HasAttribute("role", "admin") {
+		return fmt.Errorf("submitting client not authorized to set the log level, does not have admin role")
+	}
+
+	return authz.SetLogLevel(level)
+}
+
+// audit is the BeforeTransaction handler of the loan catalog contract. Fabric refuses paginated
+// queries in a transaction that has written, so GetAuditTrail is not audited.
+var audit = authz.Audit("GetAuditTrail")
+
 func main() {
-    chaincode, err := contractapi.NewChaincode(new(SmartContract))
-    if err != nil {
-        fmt.Printf("Error create chaincode: %s", err.Error())
-        return
-    }
-
-    if err := chaincode.Start(); err != nil {
-        fmt.Printf("Error starting chaincode: %s", err.Error())
-    }
+	catalogContract := new(SmartContract)
+	catalogContract.TransactionContextHandler = new(authz.TransactionContext)
+	catalogContract.BeforeTransaction = audit
+
+	chaincode, err := contractapi.NewChaincode(catalogContract)
+	if err != nil {
+		authz.Logger.Error("failed to create loan catalog chaincode", "error", err)
+		return
+	}
+
+	if err := ccaas.Start(chaincode); err != nil {
+		authz.Logger.Error("failed to start loan catalog chaincode", "error", err)
+	}
 }
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"chaincodetest/chaincodefakes"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	creator  = chaincodetest.Identity{MSPID: "Org1MSP", CommonName: "creator1", Attributes: map[string]string{"abac.creator": "true"}}
+	creator2 = chaincodetest.Identity{MSPID: "Org2MSP", CommonName: "creator2", Attributes: map[string]string{"abac.creator": "true"}}
+	reader   = chaincodetest.Identity{MSPID: "Org1MSP", CommonName: "reader1"}
+)
+
+func TestAssets(t *testing.T) {
+	s := new(SmartContract)
+	ledger := chaincodetest.NewLedger()
+	owner := "x509::CN=creator1,OU=client::CN=ca.Org1MSP"
+
+	create := func(id string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.CreateAsset(ctx, id, "blue", 5, 300)
+		}
+	}
+	update := func(id string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.UpdateAsset(ctx, id, "green", 10, 600)
+		}
+	}
+	transfer := func(id string, newOwner string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.TransferAsset(ctx, id, newOwner)
+		}
+	}
+	remove := func(id string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.DeleteAsset(ctx, id)
+		}
+	}
+	readAsset := func(id string, check func(t *testing.T, asset *Asset)) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			asset, err := s.ReadAsset(ctx, id)
+			if err == nil && check != nil {
+				check(t, asset)
+			}
+			return err
+		}
+	}
+	exists := func(id string, want bool) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			exists, err := s.AssetExists(ctx, id)
+			require.Equal(t, want, exists)
+			return err
+		}
+	}
+	listAssets := func(want ...string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			assets, err := s.GetAllAssets(ctx)
+			if err == nil {
+				var ids []string
+				for _, asset := range assets {
+					ids = append(ids, asset.ID)
+				}
+				require.Equal(t, want, ids)
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "create requires the creator attribute", Caller: reader, Run: create("asset1"), Err: "does not have abac.creator role"},
+		{Name: "create asset", Caller: creator, Run: create("asset1")},
+		{Name: "create another asset", Caller: creator, Run: create("asset2")},
+		{Name: "create duplicate", Caller: creator2, Run: create("asset1"), Err: "the asset asset1 already exists"},
+		{
+			Name:   "assets are owned by their creator",
+			Caller: reader,
+			Run: readAsset("asset1", func(t *testing.T, asset *Asset) {
+				require.Equal(t, &Asset{ID: "asset1", Color: "blue", Size: 5, Owner: owner, AppraisedValue: 300}, asset)
+			}),
+		},
+		{Name: "read missing asset", Caller: reader, Run: readAsset("asset9", nil), Err: "the asset asset9 does not exist"},
+		{Name: "asset exists", Caller: reader, Run: exists("asset1", true)},
+		{Name: "asset does not exist", Caller: reader, Run: exists("asset9", false)},
+		{Name: "list assets", Caller: reader, Run: listAssets("asset1", "asset2")},
+		{
+			Name:   "list assets with a corrupt record",
+			Caller: reader,
+			Stub: func(stub *chaincodefakes.ChaincodeStub) {
+				stub.GetStateByRangeReturns(chaincodetest.StateIterator(&queryresult.KV{Key: "asset9", Value: []byte("{")}), nil)
+			},
+			Run: listAssets(),
+			Err: "unexpected end of JSON input",
+		},
+		{
+			Name:   "submitting client identity",
+			Caller: creator,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				id, err := s.GetSubmittingClientIdentity(ctx)
+				if err == nil {
+					require.Equal(t, owner, id)
+				}
+				return err
+			},
+		},
+		{Name: "update as another client", Caller: creator2, Run: update("asset1"), Err: "does not own asset"},
+		{Name: "update missing asset", Caller: creator, Run: update("asset9"), Err: "the asset asset9 does not exist"},
+		{Name: "update", Caller: creator, Run: update("asset1")},
+		{
+			Name:   "updates are kept",
+			Caller: reader,
+			Run: readAsset("asset1", func(t *testing.T, asset *Asset) {
+				require.Equal(t, "green", asset.Color)
+				require.Equal(t, 600, asset.AppraisedValue)
+			}),
+		},
+		{Name: "transfer as another client", Caller: creator2, Run: transfer("asset1", "x509::CN=creator2,OU=client::CN=ca.Org2MSP"), Err: "does not own asset"},
+		{Name: "transfer", Caller: creator, Run: transfer("asset1", "x509::CN=creator2,OU=client::CN=ca.Org2MSP")},
+		{Name: "former owners cannot delete", Caller: creator, Run: remove("asset1"), Err: "does not own asset"},
+		{Name: "new owners delete", Caller: creator2, Run: remove("asset1")},
+		{Name: "deleted assets are gone", Caller: reader, Run: exists("asset1", false)},
+		{Name: "delete missing asset", Caller: creator2, Run: remove("asset1"), Err: "the asset asset1 does not exist"},
+	})
+}
+
+func TestGetAuditTrail(t *testing.T) {
+	s := new(SmartContract)
+	ledger := chaincodetest.NewLedger()
+
+	var cases []chaincodetest.Case
+	for _, function := range []string{"CreateAsset", "GetAuditTrail", "TransferAsset"} {
+		function := function
+		cases = append(cases, chaincodetest.Case{
+			Name:   "audit " + function,
+			Caller: creator,
+			Stub: func(stub *chaincodefakes.ChaincodeStub) {
+				stub.GetFunctionAndParametersReturns(function, nil)
+			},
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return audit(ctx)
+			},
+		})
+	}
+	cases = append(cases,
+		chaincodetest.Case{
+			Name:   "read the trail",
+			Caller: reader,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				page, err := s.GetAuditTrail(ctx, "", "", 10)
+				if err == nil {
+					require.Len(t, page.Entries, 2)
+					require.Equal(t, "CreateAsset", page.Entries[0].Function)
+					require.Equal(t, "tx3", page.Entries[1].TxID)
+				}
+				return err
+			},
+		},
+		chaincodetest.Case{
+			Name:   "read an empty page",
+			Caller: reader,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.GetAuditTrail(ctx, "", "", 0)
+				return err
+			},
+			Err: "pageSize must be positive",
+		},
+	)
+
+	ledger.Run(t, cases)
+}
+
+func TestAuditSkipsGetAuditTrail(t *testing.T) {
+	s := new(SmartContract)
+	ledger := chaincodetest.NewLedger()
+
+	// readTrail runs the audit handler as Fabric does before the function, then reads the trail,
+	// which fails if the handler wrote
+	readTrail := func(function string) chaincodetest.Case {
+		return chaincodetest.Case{
+			Name:   function,
+			Caller: creator,
+			Stub: func(stub *chaincodefakes.ChaincodeStub) {
+				stub.GetFunctionAndParametersReturns(function, nil)
+			},
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				err := audit(ctx)
+				if err != nil {
+					return err
+				}
+				_, err = s.GetAuditTrail(ctx, "", "", 10)
+				return err
+			},
+		}
+	}
+
+	audited := readTrail("CreateAsset")
+	audited.Err = "has written and cannot run a paginated query"
+	ledger.Run(t, []chaincodetest.Case{readTrail("GetAuditTrail"), audited})
+}
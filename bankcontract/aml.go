@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"authz"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+const (
+	// complianceCollection holds AML flags, shared only with the compliance org
+	complianceCollection = "complianceCollection"
+
+	// amlFlagObjectType prefixes the private composite keys of AML flags, amlflag~targetID~flagID
+	amlFlagObjectType = "amlflag"
+	// amlHoldObjectType prefixes the public composite keys marking open HIGH flags,
+	// amlhold~targetID~flagID, so that every peer can block disbursements without reading the
+	// collection
+	amlHoldObjectType = "amlhold"
+
+	amlSeverityLow    = "LOW"
+	amlSeverityMedium = "MEDIUM"
+	amlSeverityHigh   = "HIGH"
+)
+
+var (
+	amlFlagRepo = repository.NewPrivate[AMLFlag](complianceCollection, amlFlagObjectType)
+	amlHoldRepo = repository.New[AMLHold](amlHoldObjectType)
+)
+
+// AMLFlag is an anti-money laundering concern raised by compliance on a loan application or an
+// identity
+type AMLFlag struct {
+	ID         string `json:"id"` // the transaction that raised it
+	TargetID   string `json:"targetId"`
+	Reason     string `json:"reason"`
+	Severity   string `json:"severity"`
+	FlaggedBy  string `json:"flaggedBy"`
+	FlaggedAt  string `json:"flaggedAt"`
+	Resolved   bool   `json:"resolved"`
+	Resolution string `json:"resolution,omitempty" metadata:",optional"`
+	ResolvedBy string `json:"resolvedBy,omitempty" metadata:",optional"`
+	ResolvedAt string `json:"resolvedAt,omitempty" metadata:",optional"`
+}
+
+// AMLHold is the public marker of an open HIGH flag. It names neither the reason nor who raised it.
+type AMLHold struct {
+	TargetID string `json:"targetId"`
+	FlagID   string `json:"flagId"`
+}
+
+// FlagForAML raises an AML flag on a loan application or identity, keeping it in the compliance
+// collection. While a HIGH flag is open on a loan or its applicant, the loan cannot be disbursed.
+// Restricted to the compliance role.
+func (s *SmartContract) FlagForAML(ctx contractapi.TransactionContextInterface, targetID string, reason string, severity string) (*AMLFlag, error) {
+	err := requireRole(ctx, "compliance")
+	if err != nil {
+		return nil, err
+	}
+	if targetID == "" {
+		return nil, fmt.Errorf("target ID must not be empty")
+	}
+	if reason == "" {
+		return nil, fmt.Errorf("flag reason must not be empty")
+	}
+	severity = strings.ToUpper(severity)
+	switch severity {
+	case amlSeverityLow, amlSeverityMedium, amlSeverityHigh:
+	default:
+		return nil, fmt.Errorf("unknown severity %q, expected LOW, MEDIUM or HIGH", severity)
+	}
+
+	compliance, err := enrollmentID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	flag := &AMLFlag{
+		ID:        ctx.GetStub().GetTxID(),
+		TargetID:  targetID,
+		Reason:    reason,
+		Severity:  severity,
+		FlaggedBy: compliance,
+		FlaggedAt: now.Format(time.RFC3339),
+	}
+	err = amlFlagRepo.Put(ctx, flag, targetID, flag.ID)
+	if err != nil {
+		return nil, err
+	}
+	if severity == amlSeverityHigh {
+		err = amlHoldRepo.Put(ctx, &AMLHold{TargetID: targetID, FlagID: flag.ID}, targetID, flag.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	authz.TxLogger(ctx).Info("AML flag raised", "flagId", flag.ID, "severity", severity)
+
+	return flag, nil
+}
+
+// ResolveAMLFlag closes an AML flag with the outcome of its review, lifting the block a HIGH flag
+// puts on disbursements. Restricted to the compliance role.
+func (s *SmartContract) ResolveAMLFlag(ctx contractapi.TransactionContextInterface, targetID string, flagID string, resolution string) (*AMLFlag, error) {
+	err := requireRole(ctx, "compliance")
+	if err != nil {
+		return nil, err
+	}
+	if resolution == "" {
+		return nil, fmt.Errorf("resolution must not be empty")
+	}
+	flag, err := amlFlagRepo.Get(ctx, targetID, flagID)
+	if err != nil {
+		return nil, err
+	}
+	if flag == nil {
+		return nil, fmt.Errorf("the AML flag %s on %s does not exist", flagID, targetID)
+	}
+	if flag.Resolved {
+		return nil, fmt.Errorf("the AML flag %s was resolved on %s", flagID, flag.ResolvedAt)
+	}
+
+	compliance, err := enrollmentID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	flag.Resolved = true
+	flag.Resolution = resolution
+	flag.ResolvedBy = compliance
+	flag.ResolvedAt = now.Format(time.RFC3339)
+	err = amlFlagRepo.Put(ctx, flag, targetID, flagID)
+	if err != nil {
+		return nil, err
+	}
+	if flag.Severity == amlSeverityHigh {
+		err = amlHoldRepo.Delete(ctx, targetID, flagID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return flag, nil
+}
+
+// GetOpenFlags returns the unresolved AML flags on a loan application or identity. Restricted to
+// the compliance role.
+func (s *SmartContract) GetOpenFlags(ctx contractapi.TransactionContextInterface, targetID string) ([]*AMLFlag, error) {
+	err := requireRole(ctx, "compliance")
+	if err != nil {
+		return nil, err
+	}
+	flags, err := amlFlagRepo.List(ctx, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	open := []*AMLFlag{}
+	for _, flag := range flags {
+		if !flag.Resolved {
+			open = append(open, flag)
+		}
+	}
+
+	return open, nil
+}
+
+// checkAMLHold refuses the disbursement of a loan while a HIGH AML flag is open on it or on its
+// applicant
+func checkAMLHold(ctx contractapi.TransactionContextInterface, loan *LoanApplication) error {
+	for _, targetID := range []string{loan.Applicant, loan.ID} {
+		holds, err := amlHoldRepo.List(ctx, targetID)
+		if err != nil {
+			return err
+		}
+		if len(holds) > 0 {
+			return fmt.Errorf("%s has an open HIGH AML flag, the loan application %s cannot be disbursed", targetID, loan.ID)
+		}
+	}
+
+	return nil
+}
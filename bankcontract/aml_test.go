@@ -0,0 +1,98 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAMLFlags(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	compliance := chaincodetest.Identity{MSPID: "Org2MSP", CommonName: "compliance1", Attributes: map[string]string{"role": "compliance"}}
+	flag := func(targetID string, severity string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.FlagForAML(ctx, targetID, "structured cash deposits", severity)
+			return err
+		}
+	}
+	var highFlagID string
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "only compliance flags", Caller: officer, Run: flag("Alam", "HIGH"), Err: "requires role compliance"},
+		{Name: "unknown severity", Caller: compliance, Run: flag("Alam", "SEVERE"), Err: `unknown severity "SEVERE"`},
+		{Name: "low flags do not block", Caller: compliance, Run: flag("Alam", "low")},
+		{
+			Name:   "flag the applicant HIGH",
+			Caller: compliance,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				flag, err := s.FlagForAML(ctx, "Alam", "sanctions list match", "HIGH")
+				if err == nil {
+					highFlagID = flag.ID
+					require.Equal(t, "compliance1", flag.FlaggedBy)
+				}
+				return err
+			},
+		},
+		{Name: "disbursement is blocked", Caller: bank, Run: setStatus("loan2", "Disbursed").Run, Err: "Alam has an open HIGH AML flag, the loan application loan2 cannot be disbursed"},
+		{
+			Name:   "list open flags",
+			Caller: compliance,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				flags, err := s.GetOpenFlags(ctx, "Alam")
+				if err == nil {
+					require.Len(t, flags, 2)
+				}
+				return err
+			},
+		},
+		{
+			Name:   "resolve the HIGH flag",
+			Caller: compliance,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				flag, err := s.ResolveAMLFlag(ctx, "Alam", highFlagID, "false positive, different date of birth")
+				if err == nil {
+					require.True(t, flag.Resolved)
+				}
+				return err
+			},
+		},
+		{
+			Name:   "resolve twice",
+			Caller: compliance,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.ResolveAMLFlag(ctx, "Alam", highFlagID, "again")
+				return err
+			},
+			Err: "was resolved on",
+		},
+		{
+			Name:   "resolved flags are not open",
+			Caller: compliance,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				flags, err := s.GetOpenFlags(ctx, "Alam")
+				if err == nil {
+					require.Len(t, flags, 1)
+					require.Equal(t, "LOW", flags[0].Severity)
+				}
+				return err
+			},
+		},
+		setStatus("loan2", "Disbursed"),
+		{Name: "flag a loan HIGH", Caller: compliance, Run: flag("loan1", "HIGH")},
+		setStatus("loan1", "Approved"),
+		{Name: "flagged loans are not disbursed", Caller: bank, Run: setStatus("loan1", "Disbursed").Run, Err: "loan1 has an open HIGH AML flag"},
+		{
+			Name:   "officers cannot read flags",
+			Caller: officer,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.GetOpenFlags(ctx, "loan1")
+				return err
+			},
+			Err: "requires role compliance",
+		},
+	})
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+const (
+	// branchObjectType prefixes the composite keys of branches
+	branchObjectType = "branch"
+
+	// originationObjectType prefixes the private composite keys of origination records
+	originationObjectType = "origination"
+
+	// geohashAlphabet is the base32 alphabet used by geohashes
+	geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+)
+
+var branchRepo = repository.New[Branch](branchObjectType)
+
+// Branch is a bank branch whose service area is a list of geohash prefixes
+type Branch struct {
+	ID          string   `json:"id"`
+	ServiceArea []string `json:"serviceArea"`
+}
+
+// OriginationRecord is the private record of where a loan application was originated
+type OriginationRecord struct {
+	LoanID        string `json:"loanId"`
+	BranchID      string `json:"branchId"`
+	GeoHash       string `json:"geoHash"`
+	InServiceArea bool   `json:"inServiceArea"`
+	RecordedAt    string `json:"recordedAt"`
+}
+
+// RegisterBranch creates or replaces a branch and its service area. Restricted to the ops role.
+func (s *SmartContract) RegisterBranch(ctx contractapi.TransactionContextInterface, id string, serviceArea []string) error {
+	err := requireRole(ctx, "ops")
+	if err != nil {
+		return err
+	}
+	if len(serviceArea) == 0 {
+		return fmt.Errorf("service area of branch %s must not be empty", id)
+	}
+	for i, prefix := range serviceArea {
+		serviceArea[i] = strings.ToLower(prefix)
+		if !validGeohash(serviceArea[i]) {
+			return fmt.Errorf("invalid geohash %s in service area", prefix)
+		}
+	}
+
+	branch := Branch{
+		ID:          id,
+		ServiceArea: serviceArea,
+	}
+	return branchRepo.Put(ctx, &branch, id)
+}
+
+// ReadBranch returns the branch with the given ID
+func (s *SmartContract) ReadBranch(ctx contractapi.TransactionContextInterface, id string) (*Branch, error) {
+	branch, err := getBranch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if branch == nil {
+		return nil, fmt.Errorf("the branch %s does not exist", id)
+	}
+
+	return branch, nil
+}
+
+// GetOrigination returns the private origination record of a loan. Restricted to officer and fraud roles.
+func (s *SmartContract) GetOrigination(ctx contractapi.TransactionContextInterface, loanID string) (*OriginationRecord, error) {
+	err := requireRole(ctx, "officer", "fraud")
+	if err != nil {
+		return nil, err
+	}
+
+	recordKey, err := ctx.GetStub().CreateCompositeKey(originationObjectType, []string{loanID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	recordJSON, err := ctx.GetStub().GetPrivateData(loanPrivateCollection, recordKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from private data: %v", err)
+	}
+	if recordJSON == nil {
+		return nil, fmt.Errorf("no origination recorded for loan %s", loanID)
+	}
+
+	var record OriginationRecord
+	err = json.Unmarshal(recordJSON, &record)
+	if err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+// recordOrigination reads the claimed branch and origination geohash from the transient map
+// under the branch_id and origination_geohash keys. The branch is recorded on the loan, the
+// geohash only in the private collection, and applications originated outside the branch's
+// service area are flagged for review.
+func recordOrigination(ctx contractapi.TransactionContextInterface, loan *LoanApplication) error {
+	transientMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return fmt.Errorf("error getting transient: %v", err)
+	}
+	branchID := string(transientMap["branch_id"])
+	geoHash := strings.ToLower(string(transientMap["origination_geohash"]))
+	if branchID == "" && geoHash == "" {
+		return nil
+	}
+	if branchID == "" || geoHash == "" {
+		return fmt.Errorf("branch_id and origination_geohash must be provided together")
+	}
+	if !validGeohash(geoHash) {
+		return fmt.Errorf("invalid origination geohash %s", geoHash)
+	}
+
+	branch, err := getBranch(ctx, branchID)
+	if err != nil {
+		return err
+	}
+	if branch == nil {
+		return fmt.Errorf("the branch %s does not exist", branchID)
+	}
+
+	record := OriginationRecord{
+		LoanID:   loan.ID,
+		BranchID: branch.ID,
+		GeoHash:  geoHash,
+	}
+	for _, prefix := range branch.ServiceArea {
+		if strings.HasPrefix(geoHash, prefix) {
+			record.InServiceArea = true
+			break
+		}
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	record.RecordedAt = now.Format(time.RFC3339)
+
+	loan.BranchID = branch.ID
+	if !record.InServiceArea {
+		loan.ReviewFlags = append(loan.ReviewFlags, fmt.Sprintf("originated outside the service area of branch %s", branch.ID))
+	}
+
+	recordKey, err := ctx.GetStub().CreateCompositeKey(originationObjectType, []string{loan.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	recordJSON, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutPrivateData(loanPrivateCollection, recordKey, recordJSON)
+}
+
+func getBranch(ctx contractapi.TransactionContextInterface, id string) (*Branch, error) {
+	return branchRepo.Get(ctx, id)
+}
+
+// validGeohash reports whether value is a non-empty geohash of at most 12 characters
+func validGeohash(value string) bool {
+	if value == "" || len(value) > 12 {
+		return false
+	}
+	for _, c := range value {
+		if !strings.ContainsRune(geohashAlphabet, c) {
+			return false
+		}
+	}
+
+	return true
+}
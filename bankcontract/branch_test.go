@@ -0,0 +1,139 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"chaincodetest/chaincodefakes"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+// originatedAt returns a stub override passing a branch and origination geohash in the
+// transient map
+func originatedAt(branchID string, geoHash string) func(stub *chaincodefakes.ChaincodeStub) {
+	return func(stub *chaincodefakes.ChaincodeStub) {
+		stub.GetTransientReturns(map[string][]byte{"branch_id": []byte(branchID), "origination_geohash": []byte(geoHash)}, nil)
+	}
+}
+
+func TestBranches(t *testing.T) {
+	s := new(SmartContract)
+	fraud := chaincodetest.Identity{MSPID: "Org1MSP", CommonName: "fraud1", Attributes: map[string]string{"role": "fraud"}}
+	ledger := chaincodetest.NewLedger()
+
+	createAt := func(id string, branchID string, geoHash string, err string) chaincodetest.Case {
+		return chaincodetest.Case{
+			Name:   "create " + id + " at " + geoHash,
+			Caller: bank,
+			Stub:   originatedAt(branchID, geoHash),
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.CreateLoanApplication(ctx, id, "Bob", 1000, 12, 5)
+			},
+			Err: err,
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{
+			Name:   "register branch requires ops",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.RegisterBranch(ctx, "LHR1", []string{"ttsg"})
+			},
+			Err: "requires role ops",
+		},
+		{
+			Name:   "register branch with empty service area",
+			Caller: ops,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.RegisterBranch(ctx, "LHR1", nil)
+			},
+			Err: "service area of branch LHR1 must not be empty",
+		},
+		{
+			Name:   "register branch with invalid geohash",
+			Caller: ops,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.RegisterBranch(ctx, "LHR1", []string{"ttaa"})
+			},
+			Err: "invalid geohash ttaa in service area",
+		},
+		{
+			Name:   "register branch",
+			Caller: ops,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.RegisterBranch(ctx, "LHR1", []string{"ttsg", "TTSU"})
+			},
+		},
+		{
+			Name:   "read branch",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				branch, err := s.ReadBranch(ctx, "LHR1")
+				if err == nil {
+					require.Equal(t, []string{"ttsg", "ttsu"}, branch.ServiceArea)
+				}
+				return err
+			},
+		},
+		{
+			Name:   "read missing branch",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.ReadBranch(ctx, "KHI1")
+				return err
+			},
+			Err: "the branch KHI1 does not exist",
+		},
+		createAt("a", "LHR1", "ttsgq2", ""),
+		createAt("b", "LHR1", "tw2z", ""),
+		createAt("c", "LHR1", "", "branch_id and origination_geohash must be provided together"),
+		createAt("c", "LHR1", "ttsa", "invalid origination geohash ttsa"),
+		createAt("c", "KHI1", "tw2z", "the branch KHI1 does not exist"),
+		{
+			Name:   "loans originated outside the service area are flagged",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				inside, err := s.ReadLoanApplication(ctx, "a")
+				require.NoError(t, err)
+				require.Equal(t, "LHR1", inside.BranchID)
+				require.Empty(t, inside.ReviewFlags)
+				outside, err := s.ReadLoanApplication(ctx, "b")
+				require.NoError(t, err)
+				require.Len(t, outside.ReviewFlags, 1)
+				return nil
+			},
+		},
+		{
+			Name:   "origination requires officer or fraud",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.GetOrigination(ctx, "b")
+				return err
+			},
+			Err: "requires role officer or fraud",
+		},
+		{
+			Name:   "read origination",
+			Caller: fraud,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				record, err := s.GetOrigination(ctx, "b")
+				if err == nil {
+					require.Equal(t, "tw2z", record.GeoHash)
+					require.False(t, record.InServiceArea)
+				}
+				return err
+			},
+		},
+		{
+			Name:   "read missing origination",
+			Caller: officer,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.GetOrigination(ctx, "z")
+				return err
+			},
+			Err: "no origination recorded for loan z",
+		},
+	})
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+// loanAssetType is the asset type loan applications are tracked under in the update-time index
+const loanAssetType = "loan"
+
+// LoanChange is a loan application written or deleted since a sync. Loan is nil for a deletion.
+type LoanChange struct {
+	LoanID    string           `json:"loanId"`
+	TxID      string           `json:"txId"`
+	UpdatedAt string           `json:"updatedAt"`
+	Deleted   bool             `json:"deleted"`
+	Loan      *LoanApplication `json:"loan,omitempty" metadata:",optional"`
+}
+
+// LoanChangePage is one page of loan changes with the bookmark the next page starts at, which is
+// empty after the last page
+type LoanChangePage struct {
+	Changes  []*LoanChange `json:"changes,omitempty" metadata:",optional"`
+	Bookmark string        `json:"bookmark"`
+}
+
+// GetChangesSince returns up to pageSize changes of assetType at or after the transaction
+// timestamp sinceTxTimestamp, oldest first, so that a client only fetches what changed since its
+// last sync. An empty sinceTxTimestamp starts at the first change. Pass the bookmark of a page to
+// read the next one. Only loan applications are tracked, under the asset type "loan", and they
+// are redacted as ReadLoanApplication redacts them. Loans last written before changes were
+// tracked show up once they next change. Evaluate it rather than submitting it.
+func (s *SmartContract) GetChangesSince(ctx contractapi.TransactionContextInterface, assetType string, sinceTxTimestamp string, pageSize int, bookmark string) (*LoanChangePage, error) {
+	if assetType != loanAssetType {
+		return nil, fmt.Errorf("changes of %s are not tracked, only of %s", assetType, loanAssetType)
+	}
+	var since time.Time
+	if sinceTxTimestamp != "" {
+		var err error
+		since, err = time.Parse(time.RFC3339, sinceTxTimestamp)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sinceTxTimestamp %q: %v", sinceTxTimestamp, err)
+		}
+	}
+
+	changes, err := repository.ChangesSince(ctx, assetType, since, int32(pageSize), bookmark)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &LoanChangePage{Bookmark: changes.Bookmark}
+	for _, change := range changes.Changes {
+		loanChange := &LoanChange{
+			LoanID:    change.Key,
+			TxID:      change.TxID,
+			UpdatedAt: change.UpdatedAt,
+			Deleted:   change.Deleted,
+		}
+		if !change.Deleted {
+			loanChange.Loan, err = s.ReadLoanApplication(ctx, change.Key)
+			if err != nil {
+				return nil, err
+			}
+		}
+		page.Changes = append(page.Changes, loanChange)
+	}
+
+	return page, nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"testing"
+
+	"authz"
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetChangesSince(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+	var bookmark string
+
+	changes := func(since string, pageSize int, want ...string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			page, err := s.GetChangesSince(ctx, loanAssetType, since, pageSize, bookmark)
+			if err == nil {
+				var got []string
+				for _, change := range page.Changes {
+					if change.Deleted {
+						require.Nil(t, change.Loan)
+						got = append(got, "-"+change.LoanID)
+					} else {
+						require.Equal(t, change.LoanID, change.Loan.ID)
+						got = append(got, change.LoanID)
+					}
+				}
+				require.Equal(t, want, got)
+				bookmark = page.Bookmark
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		createLoan("loan3", "Bob", 2000),
+		setStatus("loan1", "Rejected"),
+		{
+			Name:   "delete loan2",
+			Caller: officer,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.DeleteLoanApplication(ctx, "loan2", "withdrawn")
+			},
+		},
+		{Name: "every change, latest per loan", Caller: bank, Run: changes("", 10, "loan3", "loan1", "-loan2")},
+		{Name: "changes since a sync", Caller: bank, Run: changes("2024-01-01T09:00:03Z", 10, "loan1", "-loan2")},
+		{Name: "first page", Caller: bank, Run: changes("", 2, "loan3", "loan1")},
+		{Name: "next page", Caller: bank, Run: changes("", 2, "-loan2")},
+		{
+			Name:   "the last page has no bookmark",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				require.Empty(t, bookmark)
+				return nil
+			},
+		},
+		{
+			Name:   "customers sync redacted loans",
+			Caller: customer,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				page, err := s.GetChangesSince(ctx, loanAssetType, "", 1, "")
+				if err == nil {
+					require.Equal(t, authz.Redacted, page.Changes[0].Loan.Applicant)
+				}
+				return err
+			},
+		},
+		{Name: "nothing changed since", Caller: bank, Run: changes("2024-01-02T00:00:00Z", 10)},
+		{
+			Name:   "untracked asset type",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.GetChangesSince(ctx, "invoice", "", 10, "")
+				return err
+			},
+			Err: "changes of invoice are not tracked, only of loan",
+		},
+		{Name: "malformed timestamp", Caller: bank, Run: changes("yesterday", 10), Err: `invalid sinceTxTimestamp "yesterday"`},
+		{Name: "empty page", Caller: bank, Run: changes("", 0), Err: "pageSize must be positive"},
+	})
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// CoApplicant is a further borrower on a loan application, jointly liable for the loan and
+// holding a share of it in percent
+type CoApplicant struct {
+	Applicant    string  `json:"applicant"`
+	SharePercent float64 `json:"sharePercent"`
+}
+
+// AddCoApplicant adds a co-applicant holding sharePercent of a pending loan application. The
+// applicant keeps the share the co-applicants do not hold, which must stay above zero. The loan is
+// then also listed under the co-applicant by GetLoansByApplicant. Restricted to the officer role.
+func (s *SmartContract) AddCoApplicant(ctx contractapi.TransactionContextInterface, loanID string, applicant string, sharePercent float64) error {
+	err := requireRole(ctx, "officer")
+	if err != nil {
+		return err
+	}
+	if applicant == "" {
+		return fmt.Errorf("co-applicant must not be empty")
+	}
+	if sharePercent <= 0 || sharePercent >= 100 {
+		return fmt.Errorf("share must be between 0 and 100 percent")
+	}
+
+	loan, err := readLoan(ctx, loanID)
+	if err != nil {
+		return err
+	}
+	if loan.Status != "Pending" {
+		return fmt.Errorf("the loan application %s is %s, co-applicants can only be added while Pending", loanID, loan.Status)
+	}
+	for _, existing := range loanApplicants(loan) {
+		if existing == applicant {
+			return fmt.Errorf("%s is already an applicant of loan application %s", applicant, loanID)
+		}
+	}
+	remaining := applicantShare(loan)
+	if sharePercent >= remaining {
+		return fmt.Errorf("the applicant %s holds %g percent of loan application %s, a share of %g percent would leave them none", loan.Applicant, remaining, loanID, sharePercent)
+	}
+
+	loan.CoApplicants = append(loan.CoApplicants, &CoApplicant{Applicant: applicant, SharePercent: sharePercent})
+	err = putLoan(ctx, loan)
+	if err != nil {
+		return err
+	}
+
+	return putApplicantIndex(ctx, loan)
+}
+
+// RemoveCoApplicant takes a co-applicant off a pending loan application, returning their share to
+// the applicant. Restricted to the officer role.
+func (s *SmartContract) RemoveCoApplicant(ctx contractapi.TransactionContextInterface, loanID string, applicant string) error {
+	err := requireRole(ctx, "officer")
+	if err != nil {
+		return err
+	}
+	loan, err := readLoan(ctx, loanID)
+	if err != nil {
+		return err
+	}
+	if loan.Status != "Pending" {
+		return fmt.Errorf("the loan application %s is %s, co-applicants can only be removed while Pending", loanID, loan.Status)
+	}
+
+	coApplicants := make([]*CoApplicant, 0, len(loan.CoApplicants))
+	for _, coApplicant := range loan.CoApplicants {
+		if coApplicant.Applicant != applicant {
+			coApplicants = append(coApplicants, coApplicant)
+		}
+	}
+	if len(coApplicants) == len(loan.CoApplicants) {
+		return fmt.Errorf("%s is not a co-applicant of loan application %s", applicant, loanID)
+	}
+	loan.CoApplicants = coApplicants
+	err = putLoan(ctx, loan)
+	if err != nil {
+		return err
+	}
+
+	indexKey, err := ctx.GetStub().CreateCompositeKey(applicantIndex, []string{applicant, loanID})
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().DelState(indexKey)
+}
+
+// loanApplicants lists the applicant of a loan followed by its co-applicants
+func loanApplicants(loan *LoanApplication) []string {
+	applicants := []string{loan.Applicant}
+	for _, coApplicant := range loan.CoApplicants {
+		applicants = append(applicants, coApplicant.Applicant)
+	}
+
+	return applicants
+}
+
+// applicantShare is the share of a loan in percent held by its applicant, what the co-applicants
+// do not hold
+func applicantShare(loan *LoanApplication) float64 {
+	share := 100.0
+	for _, coApplicant := range loan.CoApplicants {
+		share -= coApplicant.SharePercent
+	}
+
+	return share
+}
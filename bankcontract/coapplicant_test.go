@@ -0,0 +1,103 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoApplicants(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	add := func(loanID string, applicant string, share float64) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.AddCoApplicant(ctx, loanID, applicant, share)
+		}
+	}
+	remove := func(loanID string, applicant string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.RemoveCoApplicant(ctx, loanID, applicant)
+		}
+	}
+	loansOf := func(applicant string, loanIDs ...string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			loans, err := s.GetLoansByApplicant(ctx, applicant)
+			if err == nil {
+				ids := []string{}
+				for _, loan := range loans {
+					ids = append(ids, loan.ID)
+				}
+				require.ElementsMatch(t, loanIDs, ids)
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "only officers add co-applicants", Caller: bank, Run: add("loan1", "Sara", 40), Err: "requires role officer"},
+		{Name: "add an empty co-applicant", Caller: officer, Run: add("loan1", "", 40), Err: "co-applicant must not be empty"},
+		{Name: "add a whole share", Caller: officer, Run: add("loan1", "Sara", 100), Err: "share must be between 0 and 100 percent"},
+		{Name: "add to an approved loan", Caller: officer, Run: add("loan2", "Sara", 40), Err: "co-applicants can only be added while Pending"},
+		{Name: "add the applicant", Caller: officer, Run: add("loan1", "Afraz", 40), Err: "Afraz is already an applicant of loan application loan1"},
+		{Name: "add a co-applicant", Caller: officer, Run: add("loan1", "Sara", 40)},
+		{Name: "add a co-applicant twice", Caller: officer, Run: add("loan1", "Sara", 10), Err: "Sara is already an applicant of loan application loan1"},
+		{Name: "leave the applicant no share", Caller: officer, Run: add("loan1", "Omar", 60), Err: "the applicant Afraz holds 60 percent of loan application loan1, a share of 60 percent would leave them none"},
+		{Name: "add a second co-applicant", Caller: officer, Run: add("loan1", "Omar", 35)},
+		{
+			Name:   "shares",
+			Caller: officer,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				loan, err := s.ReadLoanApplication(ctx, "loan1")
+				if err == nil {
+					require.Equal(t, []*CoApplicant{{Applicant: "Sara", SharePercent: 40}, {Applicant: "Omar", SharePercent: 35}}, loan.CoApplicants)
+					require.Equal(t, 25.0, applicantShare(loan))
+				}
+				return err
+			},
+		},
+		{
+			Name:   "customers do not see co-applicants",
+			Caller: customer,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				loan, err := s.ReadLoanApplication(ctx, "loan1")
+				if err == nil {
+					require.Empty(t, loan.CoApplicants)
+				}
+				return err
+			},
+		},
+		{Name: "loans of a co-applicant", Caller: bank, Run: loansOf("Sara", "loan1")},
+		{Name: "loans of the applicant", Caller: bank, Run: loansOf("Afraz", "loan1")},
+		{Name: "only officers remove co-applicants", Caller: bank, Run: remove("loan1", "Omar"), Err: "requires role officer"},
+		{Name: "remove someone who is not a co-applicant", Caller: officer, Run: remove("loan1", "Afraz"), Err: "Afraz is not a co-applicant of loan application loan1"},
+		{Name: "remove a co-applicant", Caller: officer, Run: remove("loan1", "Omar")},
+		{Name: "a removed co-applicant has no loans", Caller: bank, Run: loansOf("Omar")},
+		setStatus("loan1", "Approved"),
+		{Name: "remove from an approved loan", Caller: officer, Run: remove("loan1", "Sara"), Err: "co-applicants can only be removed while Pending"},
+		{
+			Name:   "deleting the loan drops it from the co-applicant's loans",
+			Caller: officer,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				err := s.DeleteLoanApplication(ctx, "loan1", "withdrawn")
+				if err == nil {
+					err = loansOf("Sara")(t, ctx)
+				}
+				return err
+			},
+		},
+		{
+			Name:   "restoring the loan lists it for the co-applicant again",
+			Caller: officer,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				err := s.RestoreLoan(ctx, "loan1")
+				if err == nil {
+					err = loansOf("Sara", "loan1")(t, ctx)
+				}
+				return err
+			},
+		},
+	})
+}
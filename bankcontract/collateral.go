@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// deedChaincode is the NFT chaincode holding the property deeds pledged as collateral
+const deedChaincode = "deedcontract"
+
+// AttachCollateral pledges a property deed as collateral for a loan application by locking it on
+// the deed chaincode, so that it cannot change hands while the loan is outstanding. The deed owner
+// must have approved the submitting client on the deed chaincode. Collateral can only be attached
+// before disbursement. Restricted to the officer role.
+func (s *SmartContract) AttachCollateral(ctx contractapi.TransactionContextInterface, loanID string, deedID string) error {
+	err := requireRole(ctx, "officer")
+	if err != nil {
+		return err
+	}
+	if deedID == "" {
+		return fmt.Errorf("deed ID must not be empty")
+	}
+
+	loan, err := readLoan(ctx, loanID)
+	if err != nil {
+		return err
+	}
+	if loan.Status != "Pending" && loan.Status != "Approved" {
+		return fmt.Errorf("the loan application %s is %s, collateral can only be attached before disbursement", loanID, loan.Status)
+	}
+	if loan.CollateralDeedID != "" {
+		return fmt.Errorf("the loan application %s already holds deed %s as collateral", loanID, loan.CollateralDeedID)
+	}
+
+	err = invokeDeedChaincode(ctx, "LockDeed", deedID, loanID)
+	if err != nil {
+		return err
+	}
+
+	loan.CollateralDeedID = deedID
+	return putLoan(ctx, loan)
+}
+
+// ReleaseCollateral unlocks the deed pledged for a loan application on the deed chaincode. The
+// deed is held while the loan is disbursed or defaulted, and released once it is closed or if it
+// never reached disbursement. Restricted to the officer role.
+func (s *SmartContract) ReleaseCollateral(ctx contractapi.TransactionContextInterface, loanID string) error {
+	err := requireRole(ctx, "officer")
+	if err != nil {
+		return err
+	}
+
+	loan, err := readLoan(ctx, loanID)
+	if err != nil {
+		return err
+	}
+	if loan.CollateralDeedID == "" {
+		return fmt.Errorf("the loan application %s holds no collateral", loanID)
+	}
+	if loan.Status == "Disbursed" || loan.Status == "Overdue" || loan.Status == "Defaulted" {
+		return fmt.Errorf("the loan application %s is %s, collateral is held until it is closed", loanID, loan.Status)
+	}
+
+	err = invokeDeedChaincode(ctx, "UnlockDeed", loan.CollateralDeedID, loanID)
+	if err != nil {
+		return err
+	}
+
+	loan.CollateralDeedID = ""
+	return putLoan(ctx, loan)
+}
+
+// invokeDeedChaincode locks or unlocks a deed for a loan on the deed chaincode on the same channel
+func invokeDeedChaincode(ctx contractapi.TransactionContextInterface, function string, deedID string, loanID string) error {
+	args := [][]byte{[]byte(function), []byte(deedID), []byte(loanID)}
+	response := ctx.GetStub().InvokeChaincode(deedChaincode, args, "")
+	if response.Status != shim.OK {
+		return fmt.Errorf("failed to %s deed %s on %s: %s", function, deedID, deedChaincode, response.Message)
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/stretchr/testify/require"
+)
+
+// deedChaincodeOf returns a fake deed chaincode keeping the loan each deed is locked for in liens
+func deedChaincodeOf(liens map[string]string) func(function string, args []string) peer.Response {
+	return func(function string, args []string) peer.Response {
+		deedID, loanID := args[0], args[1]
+		switch function {
+		case "LockDeed":
+			if liens[deedID] != "" {
+				return shim.Error("the deed " + deedID + " is pledged as collateral for loan " + liens[deedID])
+			}
+			liens[deedID] = loanID
+			return shim.Success(nil)
+		case "UnlockDeed":
+			if liens[deedID] != loanID {
+				return shim.Error("the deed " + deedID + " is not pledged as collateral for loan " + loanID)
+			}
+			delete(liens, deedID)
+			return shim.Success(nil)
+		}
+		return shim.Error("unexpected function " + function)
+	}
+}
+
+func TestCollateral(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+	liens := map[string]string{"deed2": "loan9"}
+	ledger.Install(deedChaincode, deedChaincodeOf(liens))
+
+	attach := func(loanID string, deedID string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.AttachCollateral(ctx, loanID, deedID)
+		}
+	}
+	release := func(loanID string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.ReleaseCollateral(ctx, loanID)
+		}
+	}
+	collateralOf := func(loanID string, deedID string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			loan, err := s.ReadLoanApplication(ctx, loanID)
+			if err == nil {
+				require.Equal(t, deedID, loan.CollateralDeedID)
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "attach requires officer", Caller: bank, Run: attach("loan1", "deed1"), Err: "requires role officer"},
+		{Name: "attach without a deed", Caller: officer, Run: attach("loan1", ""), Err: "deed ID must not be empty"},
+		{Name: "attach a deed pledged elsewhere", Caller: officer, Run: attach("loan1", "deed2"), Err: "failed to LockDeed deed deed2 on deedcontract: the deed deed2 is pledged as collateral for loan loan9"},
+		{Name: "a failed lock attaches nothing", Caller: officer, Run: collateralOf("loan1", "")},
+		{Name: "attach", Caller: officer, Run: attach("loan1", "deed1")},
+		{
+			Name:   "the deed is locked for the loan",
+			Caller: officer,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				require.Equal(t, "loan1", liens["deed1"])
+				return collateralOf("loan1", "deed1")(t, ctx)
+			},
+		},
+		{Name: "attach a second deed", Caller: officer, Run: attach("loan1", "deed3"), Err: "the loan application loan1 already holds deed deed1 as collateral"},
+		{
+			Name:   "delete a loan holding collateral",
+			Caller: officer,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.DeleteLoanApplication(ctx, "loan1", "withdrawn")
+			},
+			Err: "the loan application loan1 holds deed deed1 as collateral, release it first",
+		},
+		setStatus("loan1", "Approved"),
+		setStatus("loan1", "Disbursed"),
+		{Name: "attach after disbursement", Caller: officer, Run: attach("loan1", "deed3"), Err: "is Disbursed, collateral can only be attached before disbursement"},
+		{Name: "release while disbursed", Caller: officer, Run: release("loan1"), Err: "the loan application loan1 is Disbursed, collateral is held until it is closed"},
+		setStatus("loan1", "Closed"),
+		{Name: "release requires officer", Caller: bank, Run: release("loan1"), Err: "requires role officer"},
+		{Name: "release", Caller: officer, Run: release("loan1")},
+		{
+			Name:   "the deed is unlocked",
+			Caller: officer,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				require.NotContains(t, liens, "deed1")
+				return collateralOf("loan1", "")(t, ctx)
+			},
+		},
+		{Name: "release twice", Caller: officer, Run: release("loan1"), Err: "the loan application loan1 holds no collateral"},
+	})
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// savingsChaincode is the token chaincode holding borrowers' committed savings balances
+const savingsChaincode = "token_erc20"
+
+// CommitmentSweep reports the outcome of one batch of VerifyCommitments
+type CommitmentSweep struct {
+	Flagged  []string `json:"flagged,omitempty" metadata:",optional"`
+	Cleared  []string `json:"cleared,omitempty" metadata:",optional"`
+	Checked  int      `json:"checked"`
+	Bookmark string   `json:"bookmark"`
+}
+
+// SetSavingsCommitment requires the borrower to keep at least requiredBalance in the given savings
+// account for the life of the loan. It can only be set before the loan is approved. Restricted to
+// the applicant and the officer role.
+func (s *SmartContract) SetSavingsCommitment(ctx contractapi.TransactionContextInterface, loanID string, accountRef string, requiredBalance int) error {
+	loan, err := readLoan(ctx, loanID)
+	if err != nil {
+		return err
+	}
+	err = requireApplicantOrOfficer(ctx, loan)
+	if err != nil {
+		return err
+	}
+	if loan.Status != "Pending" {
+		return fmt.Errorf("the loan application %s is %s, commitments can only be set while Pending", loanID, loan.Status)
+	}
+	if accountRef == "" {
+		return fmt.Errorf("savings account must not be empty")
+	}
+	if requiredBalance <= 0 {
+		return fmt.Errorf("required balance must be positive")
+	}
+
+	loan.CommitmentAccount = accountRef
+	loan.CommitmentAmount = requiredBalance
+	return putLoan(ctx, loan)
+}
+
+// VerifyCommitments checks the savings balance of up to pageSize loans in repayment, starting at
+// the bookmark, and flags those that have fallen below their commitment. Loans whose balance has
+// recovered are cleared. Pass the returned bookmark to continue the sweep; an empty bookmark means
+// the whole book has been checked. Restricted to officer and ops roles.
+func (s *SmartContract) VerifyCommitments(ctx contractapi.TransactionContextInterface, pageSize int, bookmark string) (*CommitmentSweep, error) {
+	err := requireRole(ctx, "officer", "ops")
+	if err != nil {
+		return nil, err
+	}
+	if pageSize <= 0 {
+		return nil, fmt.Errorf("pageSize must be positive")
+	}
+
+	// paginated queries are not allowed in update transactions, so the batch is bounded by hand
+	resultsIterator, err := ctx.GetStub().GetStateByRange(loanNamespace.Range(bookmark))
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	sweep := &CommitmentSweep{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		if sweep.Checked == pageSize {
+			sweep.Bookmark, _ = loanNamespace.ID(queryResponse.Key)
+			break
+		}
+		sweep.Checked++
+
+		var loan LoanApplication
+		err = json.Unmarshal(queryResponse.Value, &loan)
+		if err != nil {
+			return nil, err
+		}
+		if loan.CommitmentAmount == 0 || loan.Status != "Disbursed" {
+			continue
+		}
+
+		balance, err := savingsBalance(ctx, loan.CommitmentAccount)
+		if err != nil {
+			return nil, err
+		}
+		shortfall := balance < loan.CommitmentAmount
+		if shortfall == loan.CommitmentShortfall {
+			continue
+		}
+
+		loan.CommitmentShortfall = shortfall
+		err = putLoan(ctx, &loan)
+		if err != nil {
+			return nil, err
+		}
+		if shortfall {
+			sweep.Flagged = append(sweep.Flagged, loan.ID)
+		} else {
+			sweep.Cleared = append(sweep.Cleared, loan.ID)
+		}
+	}
+
+	return sweep, nil
+}
+
+// checkCommitment rejects approval of a loan whose committed savings balance is not in place
+func checkCommitment(ctx contractapi.TransactionContextInterface, loan *LoanApplication) error {
+	if loan.CommitmentAmount == 0 {
+		return nil
+	}
+
+	balance, err := savingsBalance(ctx, loan.CommitmentAccount)
+	if err != nil {
+		return err
+	}
+	if balance < loan.CommitmentAmount {
+		return fmt.Errorf("savings account %s holds %d, loan application %s requires a committed balance of %d", loan.CommitmentAccount, balance, loan.ID, loan.CommitmentAmount)
+	}
+
+	return nil
+}
+
+// savingsBalance reads an account balance from the token chaincode on the same channel
+func savingsBalance(ctx contractapi.TransactionContextInterface, account string) (int, error) {
+	response := ctx.GetStub().InvokeChaincode(savingsChaincode, [][]byte{[]byte("BalanceOf"), []byte(account)}, "")
+	if response.Status != shim.OK {
+		return 0, fmt.Errorf("failed to query balance of %s from %s: %s", account, savingsChaincode, response.Message)
+	}
+
+	balance, err := strconv.Atoi(string(response.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("unexpected balance %q returned by %s", response.Payload, savingsChaincode)
+	}
+
+	return balance, nil
+}
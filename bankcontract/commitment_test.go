@@ -0,0 +1,140 @@
+package main
+
+import (
+	"strconv"
+	"testing"
+
+	"chaincodetest"
+	"chaincodetest/chaincodefakes"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/stretchr/testify/require"
+)
+
+// savingsChaincodeOf returns a stand-in for the token chaincode answering balances from the map
+// and moving allowances between its accounts
+func savingsChaincodeOf(balances map[string]int) func(function string, args []string) peer.Response {
+	return func(function string, args []string) peer.Response {
+		switch function {
+		case "BalanceOf":
+			balance, ok := balances[args[0]]
+			if !ok {
+				return shim.Error("account " + args[0] + " has no balance")
+			}
+			return shim.Success([]byte(strconv.Itoa(balance)))
+		case "TransferFrom":
+			amount, _ := strconv.Atoi(args[2])
+			if balances[args[0]] < amount {
+				return shim.Error("client account " + args[0] + " has insufficient funds")
+			}
+			balances[args[0]] -= amount
+			balances[args[1]] += amount
+			return shim.Success(nil)
+		}
+		return shim.Error("unexpected function " + function)
+	}
+}
+
+func TestSavingsCommitments(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+	balances := map[string]int{"acc1": 500, "acc2": 2000}
+	ledger.Install(savingsChaincode, savingsChaincodeOf(balances))
+
+	commit := func(id string, account string, amount int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.SetSavingsCommitment(ctx, id, account, amount)
+		}
+	}
+	sweep := func(pageSize int, bookmark string, check func(t *testing.T, sweep *CommitmentSweep)) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			result, err := s.VerifyCommitments(ctx, pageSize, bookmark)
+			if err == nil {
+				check(t, result)
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		createLoan("loan3", "Bob", 1000),
+		createLoan("loan4", "Carol", 1000),
+		{Name: "commit savings", Caller: officer, Run: commit("loan3", "acc1", 1000)},
+		{Name: "commit savings of someone else's loan", Caller: customer, Run: commit("loan4", "acc1", 1000), Err: "requires the applicant of loan application loan4 or role officer"},
+		{Name: "commit savings of another loan", Caller: officer, Run: commit("loan4", "acc2", 1000)},
+		{Name: "commit without an account", Caller: officer, Run: commit("loan3", "", 1000), Err: "savings account must not be empty"},
+		{Name: "commit a non-positive balance", Caller: officer, Run: commit("loan3", "acc1", 0), Err: "required balance must be positive"},
+		{Name: "commit on an approved loan", Caller: officer, Run: commit("loan2", "acc1", 1000), Err: "commitments can only be set while Pending"},
+		{Name: "commit on a missing loan", Caller: officer, Run: commit("loan9", "acc1", 1000), Err: "does not exist"},
+		{
+			Name:   "approve without the committed balance",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.UpdateLoanStatus(ctx, "loan3", "Approved")
+			},
+			Err: "savings account acc1 holds 500, loan application loan3 requires a committed balance of 1000",
+		},
+		setStatus("loan4", "Approved"),
+		setStatus("loan4", "Disbursed"),
+		{
+			Name:   "sweep requires officer or ops",
+			Caller: bank,
+			Run:    sweep(10, "", nil),
+			Err:    "requires role officer or ops",
+		},
+		{
+			Name:   "sweep with a non-positive page size",
+			Caller: ops,
+			Run:    sweep(0, "", nil),
+			Err:    "pageSize must be positive",
+		},
+		{
+			Name:   "sweep a book in good standing",
+			Caller: ops,
+			Run: sweep(10, "", func(t *testing.T, sweep *CommitmentSweep) {
+				require.Equal(t, 4, sweep.Checked)
+				require.Empty(t, sweep.Flagged)
+				require.Empty(t, sweep.Bookmark)
+			}),
+		},
+		{
+			Name:   "sweep flags a shortfall",
+			Caller: ops,
+			Stub: func(*chaincodefakes.ChaincodeStub) {
+				balances["acc2"] = 900
+			},
+			Run: sweep(10, "", func(t *testing.T, sweep *CommitmentSweep) {
+				require.Equal(t, []string{"loan4"}, sweep.Flagged)
+			}),
+		},
+		{
+			Name:   "sweep a page at a time",
+			Caller: officer,
+			Run: sweep(2, "", func(t *testing.T, sweep *CommitmentSweep) {
+				require.Equal(t, 2, sweep.Checked)
+				require.Equal(t, "loan3", sweep.Bookmark)
+			}),
+		},
+		{
+			Name:   "sweep clears a recovered balance",
+			Caller: officer,
+			Stub: func(*chaincodefakes.ChaincodeStub) {
+				balances["acc2"] = 1000
+			},
+			Run: sweep(2, "loan3", func(t *testing.T, sweep *CommitmentSweep) {
+				require.Equal(t, []string{"loan4"}, sweep.Cleared)
+				require.Empty(t, sweep.Bookmark)
+			}),
+		},
+		{
+			Name:   "sweep fails when the token chaincode does",
+			Caller: ops,
+			Stub: func(*chaincodefakes.ChaincodeStub) {
+				delete(balances, "acc2")
+			},
+			Run: sweep(10, "", nil),
+			Err: "failed to query balance of acc2 from token_erc20",
+		},
+	})
+}
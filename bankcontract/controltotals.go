@@ -0,0 +1,393 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+const (
+	controlTotalObjectType   = "controltotal"
+	reconciliationObjectType = "reconciliation"
+
+	// controlTotalShards spreads the control totals of a currency over several keys, so that
+	// concurrent loan updates rarely collide on the same key
+	controlTotalShards = 8
+
+	controlReconcileJob = "control-reconcile"
+
+	reconciliationBalanced = "Balanced"
+	reconciliationDrifted  = "Drifted"
+)
+
+var reconciliationRepo = repository.New[Reconciliation](reconciliationObjectType)
+
+// ControlTotals are the running totals of the disbursed loan book in one currency. They are
+// updated incrementally on every loan write, in shards picked by loan ID.
+type ControlTotals struct {
+	Currency    string `json:"currency"`
+	Disbursed   int    `json:"disbursed"`   // principal of every loan ever disbursed
+	Outstanding int    `json:"outstanding"` // principal still to be repaid
+	Fees        int    `json:"fees"`        // processing fees of disbursed loans
+}
+
+// ControlDrift is a difference between a recorded control total and its recomputation
+type ControlDrift struct {
+	Currency   string `json:"currency"`
+	Total      string `json:"total"` // disbursed, outstanding or fees
+	Recorded   int    `json:"recorded"`
+	Recomputed int    `json:"recomputed"`
+}
+
+// Reconciliation is the outcome of a ReconcileControlTotals job. Recomputed accumulates the totals
+// of the loans scanned so far; Recorded and Drift are filled in when the last page is processed.
+type Reconciliation struct {
+	JobID       string           `json:"jobId"`
+	Status      string           `json:"status"` // Running, Balanced or Drifted
+	Recomputed  []*ControlTotals `json:"recomputed,omitempty" metadata:",optional"`
+	Recorded    []*ControlTotals `json:"recorded,omitempty" metadata:",optional"`
+	Drift       []*ControlDrift  `json:"drift,omitempty" metadata:",optional"`
+	CompletedAt string           `json:"completedAt"`
+}
+
+// GetControlTotals returns the recorded control totals of every currency
+func (s *SmartContract) GetControlTotals(ctx contractapi.TransactionContextInterface) ([]*ControlTotals, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(controlTotalObjectType, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	totals := make(map[string]*ControlTotals)
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var shard ControlTotals
+		err = json.Unmarshal(queryResponse.Value, &shard)
+		if err != nil {
+			return nil, err
+		}
+		addControlTotals(totals, &shard, 1)
+	}
+
+	return sortedControlTotals(totals), nil
+}
+
+// StartReconciliation starts a job recomputing the control totals from every loan, which is then
+// driven page by page with ReconcileControlTotals. Restricted to the ops role.
+func (s *SmartContract) StartReconciliation(ctx contractapi.TransactionContextInterface, jobID string) error {
+	err := requireRole(ctx, "ops")
+	if err != nil {
+		return err
+	}
+	err = startJob(ctx, jobID, controlReconcileJob)
+	if err != nil {
+		return err
+	}
+
+	return putReconciliation(ctx, &Reconciliation{JobID: jobID, Status: jobRunning})
+}
+
+// ReconcileControlTotals processes the next page of up to pageSize loans of a reconciliation job.
+// After the last page the recomputed totals are compared with the recorded ones, and any
+// difference is reported as drift. Loans written while the job runs, or before control totals
+// were kept, also show up as drift, so reconciliations are best run in a quiet period.
+// Restricted to the ops role.
+func (s *SmartContract) ReconcileControlTotals(ctx contractapi.TransactionContextInterface, jobID string, pageSize int) (*Reconciliation, error) {
+	err := requireRole(ctx, "ops")
+	if err != nil {
+		return nil, err
+	}
+	if pageSize <= 0 {
+		return nil, fmt.Errorf("pageSize must be positive")
+	}
+
+	job, err := s.ReadJob(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != jobRunning {
+		return nil, fmt.Errorf("the job %s is %s", jobID, job.Status)
+	}
+	if job.Kind != controlReconcileJob {
+		return nil, fmt.Errorf("the job %s is a %s job, not a %s job", jobID, job.Kind, controlReconcileJob)
+	}
+	reconciliation, err := s.ReadReconciliation(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	recomputed := make(map[string]*ControlTotals)
+	for _, totals := range reconciliation.Recomputed {
+		addControlTotals(recomputed, totals, 1)
+	}
+
+	// paginated queries are not allowed in update transactions, so the page is bounded by hand
+	resultsIterator, err := ctx.GetStub().GetStateByRange(loanNamespace.Range(job.Cursor))
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	scanned := 0
+	cursor := ""
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		if scanned == pageSize {
+			cursor, _ = loanNamespace.ID(queryResponse.Key)
+			break
+		}
+		scanned++
+
+		figures, err := controlFigures(queryResponse.Value)
+		if err != nil {
+			return nil, err
+		}
+		addControlTotals(recomputed, figures, 1)
+	}
+	reconciliation.Recomputed = sortedControlTotals(recomputed)
+
+	job.Cursor = cursor
+	job.Pages++
+	job.Processed += scanned
+	if cursor == "" {
+		job.Status = jobCompleted
+		reconciliation.Recorded, err = s.GetControlTotals(ctx)
+		if err != nil {
+			return nil, err
+		}
+		reconciliation.Drift = controlDrift(reconciliation.Recorded, reconciliation.Recomputed)
+		reconciliation.Status = reconciliationBalanced
+		if len(reconciliation.Drift) > 0 {
+			reconciliation.Status = reconciliationDrifted
+		}
+		now, err := txTime(ctx)
+		if err != nil {
+			return nil, err
+		}
+		reconciliation.CompletedAt = now.Format(time.RFC3339)
+	}
+	err = putReconciliation(ctx, reconciliation)
+	if err != nil {
+		return nil, err
+	}
+	err = finishJobStep(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+
+	return reconciliation, nil
+}
+
+// ReadReconciliation returns the progress or outcome of a reconciliation job
+func (s *SmartContract) ReadReconciliation(ctx contractapi.TransactionContextInterface, jobID string) (*Reconciliation, error) {
+	reconciliationKey, err := ctx.GetStub().CreateCompositeKey(reconciliationObjectType, []string{jobID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	reconciliationJSON, err := ctx.GetStub().GetState(reconciliationKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if reconciliationJSON == nil {
+		return nil, fmt.Errorf("the reconciliation %s does not exist", jobID)
+	}
+
+	var reconciliation Reconciliation
+	err = json.Unmarshal(reconciliationJSON, &reconciliation)
+	if err != nil {
+		return nil, err
+	}
+
+	return &reconciliation, nil
+}
+
+// updateControlTotals moves the control totals by the difference between the previous and
+// current versions of a loan. Either version may be nil. Writes are not visible within a
+// transaction, so a transaction must not update two loans sharing a control total shard.
+func updateControlTotals(ctx contractapi.TransactionContextInterface, loanID string, previous []byte, current []byte) error {
+	before, err := controlFigures(previous)
+	if err != nil {
+		return err
+	}
+	after, err := controlFigures(current)
+	if err != nil {
+		return err
+	}
+
+	deltas := make(map[string]*ControlTotals)
+	addControlTotals(deltas, before, -1)
+	addControlTotals(deltas, after, 1)
+	for _, delta := range deltas {
+		if delta.Disbursed == 0 && delta.Outstanding == 0 && delta.Fees == 0 {
+			continue
+		}
+
+		shardKey, err := controlShardKey(ctx, delta.Currency, loanID)
+		if err != nil {
+			return err
+		}
+		shardJSON, err := ctx.GetStub().GetState(shardKey)
+		if err != nil {
+			return fmt.Errorf("failed to read from world state: %v", err)
+		}
+		shard := ControlTotals{Currency: delta.Currency}
+		if shardJSON != nil {
+			err = json.Unmarshal(shardJSON, &shard)
+			if err != nil {
+				return err
+			}
+		}
+		shard.Disbursed += delta.Disbursed
+		shard.Outstanding += delta.Outstanding
+		shard.Fees += delta.Fees
+
+		shardJSON, err = json.Marshal(shard)
+		if err != nil {
+			return err
+		}
+		err = ctx.GetStub().PutState(shardKey, shardJSON)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// controlFigures returns what a stored loan contributes to the control totals. Only disbursed
+// loans contribute; a nil loan contributes nothing.
+func controlFigures(loanJSON []byte) (*ControlTotals, error) {
+	if loanJSON == nil {
+		return nil, nil
+	}
+	var loan LoanApplication
+	err := json.Unmarshal(loanJSON, &loan)
+	if err != nil {
+		return nil, err
+	}
+
+	return loanFigures(&loan)
+}
+
+// loanFigures returns what a loan contributes to the control totals, nil unless it was disbursed
+func loanFigures(loan *LoanApplication) (*ControlTotals, error) {
+	if loan.DisbursedAt == "" {
+		return nil, nil
+	}
+
+	outstanding, err := outstandingPrincipal(loan)
+	if err != nil {
+		return nil, err
+	}
+	figures := ControlTotals{
+		Currency:    loan.Currency,
+		Disbursed:   loan.Amount,
+		Outstanding: outstanding,
+		Fees:        loan.ProcessingFee,
+	}
+	if figures.Currency == "" {
+		figures.Currency = defaultCurrency
+	}
+
+	return &figures, nil
+}
+
+// controlShardKey returns the key of the control total shard a loan is counted in
+func controlShardKey(ctx contractapi.TransactionContextInterface, currency string, loanID string) (string, error) {
+	h := fnv.New32a()
+	h.Write([]byte(loanID))
+	shard := strconv.Itoa(int(h.Sum32() % controlTotalShards))
+	if currency == "" {
+		currency = defaultCurrency
+	}
+
+	shardKey, err := ctx.GetStub().CreateCompositeKey(controlTotalObjectType, []string{currency, shard})
+	if err != nil {
+		return "", fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	return shardKey, nil
+}
+
+// addControlTotals adds sign times the figures to the totals of their currency
+func addControlTotals(totals map[string]*ControlTotals, figures *ControlTotals, sign int) {
+	if figures == nil {
+		return
+	}
+	total, ok := totals[figures.Currency]
+	if !ok {
+		total = &ControlTotals{Currency: figures.Currency}
+		totals[figures.Currency] = total
+	}
+	total.Disbursed += sign * figures.Disbursed
+	total.Outstanding += sign * figures.Outstanding
+	total.Fees += sign * figures.Fees
+}
+
+func sortedControlTotals(totals map[string]*ControlTotals) []*ControlTotals {
+	var sorted []*ControlTotals
+	for _, total := range totals {
+		sorted = append(sorted, total)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Currency < sorted[j].Currency
+	})
+
+	return sorted
+}
+
+// controlDrift lists every total whose recorded value differs from its recomputation
+func controlDrift(recorded []*ControlTotals, recomputed []*ControlTotals) []*ControlDrift {
+	recordedTotals := make(map[string]*ControlTotals)
+	for _, total := range recorded {
+		addControlTotals(recordedTotals, total, 1)
+	}
+	recomputedTotals := make(map[string]*ControlTotals)
+	for _, total := range recomputed {
+		addControlTotals(recomputedTotals, total, 1)
+		addControlTotals(recordedTotals, &ControlTotals{Currency: total.Currency}, 1)
+	}
+
+	var drift []*ControlDrift
+	for _, want := range sortedControlTotals(recordedTotals) {
+		got := recomputedTotals[want.Currency]
+		if got == nil {
+			got = &ControlTotals{Currency: want.Currency}
+		}
+		for _, total := range []struct {
+			name               string
+			recorded, computed int
+		}{
+			{"disbursed", want.Disbursed, got.Disbursed},
+			{"outstanding", want.Outstanding, got.Outstanding},
+			{"fees", want.Fees, got.Fees},
+		} {
+			if total.recorded != total.computed {
+				drift = append(drift, &ControlDrift{
+					Currency:   want.Currency,
+					Total:      total.name,
+					Recorded:   total.recorded,
+					Recomputed: total.computed,
+				})
+			}
+		}
+	}
+
+	return drift
+}
+
+func putReconciliation(ctx contractapi.TransactionContextInterface, reconciliation *Reconciliation) error {
+	return reconciliationRepo.Put(ctx, reconciliation, reconciliation.JobID)
+}
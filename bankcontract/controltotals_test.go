@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestControlTotals(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	reconcile := func(jobID string, pageSize int, status string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			reconciliation, err := s.ReconcileControlTotals(ctx, jobID, pageSize)
+			if err == nil {
+				require.Equal(t, status, reconciliation.Status)
+			}
+			return err
+		}
+	}
+	start := func(jobID string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.StartReconciliation(ctx, jobID)
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		setStatus("loan2", "Disbursed"),
+		{
+			Name:   "disbursed loans are counted",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				totals, err := s.GetControlTotals(ctx)
+				if err == nil {
+					require.Len(t, totals, 1)
+					require.Equal(t, ControlTotals{Currency: "PKR", Disbursed: 5000, Outstanding: 5000}, *totals[0])
+				}
+				return err
+			},
+		},
+		{Name: "start reconciliation requires ops", Caller: bank, Run: start("rec1"), Err: "requires role ops"},
+		{Name: "start reconciliation", Caller: ops, Run: start("rec1")},
+		{Name: "start a second reconciliation", Caller: ops, Run: start("rec2"), Err: "a control-reconcile job is already running as rec1"},
+		{Name: "reconcile with a non-positive page size", Caller: ops, Run: reconcile("rec1", 0, ""), Err: "pageSize must be positive"},
+		{Name: "reconcile a missing job", Caller: ops, Run: reconcile("rec9", 1, ""), Err: "the job rec9 does not exist"},
+		{Name: "reconcile the first page", Caller: ops, Run: reconcile("rec1", 1, jobRunning)},
+		{Name: "reconcile the last page", Caller: ops, Run: reconcile("rec1", 1, reconciliationBalanced)},
+		{Name: "reconcile a completed job", Caller: ops, Run: reconcile("rec1", 1, ""), Err: "the job rec1 is Completed"},
+		{
+			Name:   "write a loan around the control totals",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				loanJSON, err := json.Marshal(LoanApplication{ID: "loan7", Applicant: "Eve", Amount: 700, Term: 12, Status: "Disbursed", DisbursedAt: "2024-01-01T09:00:00Z"})
+				require.NoError(t, err)
+				return ctx.GetStub().PutState(loanNamespace.Key("loan7"), loanJSON)
+			},
+		},
+		{Name: "start another reconciliation", Caller: ops, Run: start("rec2")},
+		{Name: "reconciliation reports the drift", Caller: ops, Run: reconcile("rec2", 10, reconciliationDrifted)},
+		{
+			Name:   "read reconciliation",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				reconciliation, err := s.ReadReconciliation(ctx, "rec2")
+				if err == nil {
+					require.Contains(t, reconciliation.Drift, &ControlDrift{Currency: "PKR", Total: "disbursed", Recorded: 5000, Recomputed: 5700})
+					require.NotEmpty(t, reconciliation.CompletedAt)
+				}
+				return err
+			},
+		},
+		{
+			Name:   "read missing reconciliation",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.ReadReconciliation(ctx, "rec9")
+				return err
+			},
+			Err: "the reconciliation rec9 does not exist",
+		},
+	})
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// defaultCurrency is the currency of loan applications that do not set one
+const defaultCurrency = "PKR"
+
+// SetLoanCurrency sets the currency of a pending loan application. The code is validated against
+// the ISO 4217 reference data held by the identity chaincode and must be one loans are
+// denominated in: PKR, USD or EUR. Restricted to the applicant and the officer role.
+func (s *SmartContract) SetLoanCurrency(ctx contractapi.TransactionContextInterface, loanID string, currency string) error {
+	loan, err := readLoan(ctx, loanID)
+	if err != nil {
+		return err
+	}
+	err = requireApplicantOrOfficer(ctx, loan)
+	if err != nil {
+		return err
+	}
+	if loan.Status != "Pending" {
+		return fmt.Errorf("the loan application %s is %s, the currency can only be changed while Pending", loanID, loan.Status)
+	}
+
+	loan.Currency, err = canonicalCurrency(ctx, currency)
+	if err != nil {
+		return err
+	}
+	if _, ok := loanCurrencies[loan.Currency]; !ok {
+		return unsupportedCurrency(loan.Currency)
+	}
+
+	return putLoan(ctx, loan)
+}
+
+// canonicalCurrency looks a currency code up in the reference data and returns it in its
+// registered upper case form
+func canonicalCurrency(ctx contractapi.TransactionContextInterface, code string) (string, error) {
+	response := ctx.GetStub().InvokeChaincode(identityChaincode, [][]byte{[]byte("ReferenceData:GetCurrency"), []byte(code)}, "")
+	if response.Status != shim.OK {
+		return "", fmt.Errorf("invalid currency %q: %s", code, response.Message)
+	}
+
+	var currency struct {
+		Code string `json:"code"`
+	}
+	err := json.Unmarshal(response.Payload, &currency)
+	if err != nil {
+		return "", fmt.Errorf("unexpected currency returned by %s: %v", identityChaincode, err)
+	}
+
+	return currency.Code, nil
+}
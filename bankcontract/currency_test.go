@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetLoanCurrency(t *testing.T) {
+	s := new(SmartContract)
+	afraz := chaincodetest.Identity{MSPID: "Org1MSP", CommonName: "Afraz", Attributes: map[string]string{"role": "customer"}}
+	ledger := newLedger(t)
+	ledger.Install(identityChaincode, identityChaincodeOf(nil))
+
+	setCurrency := func(id string, currency string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.SetLoanCurrency(ctx, id, currency)
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "strangers cannot set the currency", Caller: customer, Run: setCurrency("loan1", "USD"), Err: "requires the applicant of loan application loan1 or role officer"},
+		{Name: "the applicant sets the currency", Caller: afraz, Run: setCurrency("loan1", "USD")},
+		{Name: "set currency", Caller: officer, Run: setCurrency("loan1", "usd")},
+		{
+			Name:   "currencies are stored in their registered form",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				loan, err := s.ReadLoanApplication(ctx, "loan1")
+				if err == nil {
+					require.Equal(t, "USD", loan.Currency)
+				}
+				return err
+			},
+		},
+		{Name: "set unknown currency", Caller: officer, Run: setCurrency("loan1", "XYZ"), Err: `invalid currency "XYZ"`},
+		{Name: "set currency of an approved loan", Caller: officer, Run: setCurrency("loan2", "USD"), Err: "the currency can only be changed while Pending"},
+		{Name: "set currency of a missing loan", Caller: officer, Run: setCurrency("loan9", "USD"), Err: "does not exist"},
+	})
+}
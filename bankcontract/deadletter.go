@@ -0,0 +1,238 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+const (
+	deadLetterObjectType = "deadletter"
+
+	deadLetterPending         = "Pending"
+	deadLetterReplayRequested = "ReplayRequested"
+	deadLetterResolved        = "Resolved"
+
+	// maxDeadLetterReference bounds the reference stored in a dead letter, which must identify
+	// the business event rather than carry its payload
+	maxDeadLetterReference = 128
+	maxDeadLetterError     = 256
+
+	// EventDeadLetterReplay is the chaincode event emitted with the DeadLetter whose replay an
+	// operator requested. Bridge services listen for it and deliver the event again.
+	EventDeadLetterReplay = "DeadLetterReplay"
+)
+
+var deadLetterRepo = repository.New[DeadLetter](deadLetterObjectType)
+
+// DeadLetter records a business event an off-chain bridge service, such as the webhook or core
+// banking bridge, permanently failed to deliver. It holds a compact reference to the event, not
+// its payload, which the bridge rebuilds from the source transaction on replay.
+type DeadLetter struct {
+	ID                string `json:"id"`
+	Service           string `json:"service"`
+	EventName         string `json:"eventName"`
+	SourceTxID        string `json:"sourceTxId"` // transaction that emitted the event
+	Reference         string `json:"reference"`  // e.g. the loan ID the event is about
+	Attempts          int    `json:"attempts"`
+	LastError         string `json:"lastError"`
+	Status            string `json:"status"`
+	Replays           int    `json:"replays"`
+	RecordedAt        string `json:"recordedAt"`
+	ReplayRequestedBy string `json:"replayRequestedBy"`
+	ReplayRequestedAt string `json:"replayRequestedAt"`
+	ResolvedAt        string `json:"resolvedAt"`
+}
+
+// RecordDeadLetter records that a bridge service gave up delivering an event, and returns the
+// dead letter. Recording the same event again, for example after a failed replay, adds the
+// attempts and returns the dead letter to Pending. Restricted to the bridge role.
+func (s *SmartContract) RecordDeadLetter(ctx contractapi.TransactionContextInterface, service string, eventName string, sourceTxID string, reference string, attempts int, lastError string) (*DeadLetter, error) {
+	err := requireRole(ctx, "bridge")
+	if err != nil {
+		return nil, err
+	}
+	if service == "" || eventName == "" || sourceTxID == "" {
+		return nil, fmt.Errorf("service, event name and source transaction ID must not be empty")
+	}
+	if len(reference) > maxDeadLetterReference {
+		return nil, fmt.Errorf("the reference must be at most %d bytes, record the event's identifier rather than its payload", maxDeadLetterReference)
+	}
+	if attempts <= 0 {
+		return nil, fmt.Errorf("attempts must be positive")
+	}
+	if len(lastError) > maxDeadLetterError {
+		lastError = lastError[:maxDeadLetterError]
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256([]byte(service + "\x00" + eventName + "\x00" + sourceTxID))
+	id := hex.EncodeToString(digest[:8])
+	letter, err := getDeadLetter(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if letter == nil {
+		letter = &DeadLetter{
+			ID:         id,
+			Service:    service,
+			EventName:  eventName,
+			SourceTxID: sourceTxID,
+			RecordedAt: now.Format(time.RFC3339),
+		}
+	}
+	if letter.Status == deadLetterResolved {
+		return nil, fmt.Errorf("the dead letter %s was resolved on %s", id, letter.ResolvedAt)
+	}
+	letter.Reference = reference
+	letter.Attempts += attempts
+	letter.LastError = lastError
+	letter.Status = deadLetterPending
+
+	err = putDeadLetter(ctx, letter)
+	if err != nil {
+		return nil, err
+	}
+
+	return letter, nil
+}
+
+// ReplayDeadLetter asks the bridge services to deliver a pending dead letter again, by emitting a
+// DeadLetterReplay event. Restricted to the ops role.
+func (s *SmartContract) ReplayDeadLetter(ctx contractapi.TransactionContextInterface, id string) (*DeadLetter, error) {
+	err := requireRole(ctx, "ops")
+	if err != nil {
+		return nil, err
+	}
+
+	letter, err := s.ReadDeadLetter(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if letter.Status != deadLetterPending {
+		return nil, fmt.Errorf("the dead letter %s is %s, not %s", id, letter.Status, deadLetterPending)
+	}
+
+	operator, _, err := operatorName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	letter.Status = deadLetterReplayRequested
+	letter.Replays++
+	letter.ReplayRequestedBy = operator
+	letter.ReplayRequestedAt = now.Format(time.RFC3339)
+	err = putDeadLetter(ctx, letter)
+	if err != nil {
+		return nil, err
+	}
+
+	letterJSON, err := json.Marshal(letter)
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.GetStub().SetEvent(EventDeadLetterReplay, letterJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set event: %v", err)
+	}
+
+	return letter, nil
+}
+
+// ResolveDeadLetter records that a replayed dead letter was delivered. Restricted to the bridge
+// role.
+func (s *SmartContract) ResolveDeadLetter(ctx contractapi.TransactionContextInterface, id string) (*DeadLetter, error) {
+	err := requireRole(ctx, "bridge")
+	if err != nil {
+		return nil, err
+	}
+
+	letter, err := s.ReadDeadLetter(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if letter.Status != deadLetterReplayRequested {
+		return nil, fmt.Errorf("the dead letter %s is %s, no replay was requested", id, letter.Status)
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	letter.Status = deadLetterResolved
+	letter.ResolvedAt = now.Format(time.RFC3339)
+	err = putDeadLetter(ctx, letter)
+	if err != nil {
+		return nil, err
+	}
+
+	return letter, nil
+}
+
+// ReadDeadLetter returns a dead letter
+func (s *SmartContract) ReadDeadLetter(ctx contractapi.TransactionContextInterface, id string) (*DeadLetter, error) {
+	letter, err := getDeadLetter(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if letter == nil {
+		return nil, fmt.Errorf("the dead letter %s does not exist", id)
+	}
+
+	return letter, nil
+}
+
+// GetDeadLetters returns the dead letters of a service with the given status. An empty service
+// or status matches every one, so GetDeadLetters("", "Pending") lists the unprocessed business
+// events of all bridges.
+func (s *SmartContract) GetDeadLetters(ctx contractapi.TransactionContextInterface, service string, status string) ([]*DeadLetter, error) {
+	switch status {
+	case "", deadLetterPending, deadLetterReplayRequested, deadLetterResolved:
+	default:
+		return nil, fmt.Errorf("unknown dead letter status %q", status)
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(deadLetterObjectType, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var letters []*DeadLetter
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var letter DeadLetter
+		err = json.Unmarshal(queryResponse.Value, &letter)
+		if err != nil {
+			return nil, err
+		}
+		if (service == "" || letter.Service == service) && (status == "" || letter.Status == status) {
+			letters = append(letters, &letter)
+		}
+	}
+
+	return letters, nil
+}
+
+func getDeadLetter(ctx contractapi.TransactionContextInterface, id string) (*DeadLetter, error) {
+	return deadLetterRepo.Get(ctx, id)
+}
+
+func putDeadLetter(ctx contractapi.TransactionContextInterface, letter *DeadLetter) error {
+	return deadLetterRepo.Put(ctx, letter, letter.ID)
+}
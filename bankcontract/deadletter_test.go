@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"chaincodetest"
+	"chaincodetest/chaincodefakes"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeadLetters(t *testing.T) {
+	s := new(SmartContract)
+	bridge := chaincodetest.Identity{MSPID: "Org1MSP", CommonName: "webhooks", Attributes: map[string]string{"role": "bridge"}}
+	ledger := chaincodetest.NewLedger()
+
+	var id string
+	record := func(service string, sourceTxID string, reference string, attempts int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			letter, err := s.RecordDeadLetter(ctx, service, "LoanApproved", sourceTxID, reference, attempts, strings.Repeat("timeout ", 40))
+			if err == nil {
+				id = letter.ID
+				require.Len(t, letter.LastError, maxDeadLetterError)
+			}
+			return err
+		}
+	}
+	list := func(service string, status string, count int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			letters, err := s.GetDeadLetters(ctx, service, status)
+			require.Len(t, letters, count)
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "record requires bridge", Caller: ops, Run: record("webhook", "tx0", "loan1", 3), Err: "requires role bridge"},
+		{Name: "record without a source", Caller: bridge, Run: record("webhook", "", "loan1", 3), Err: "must not be empty"},
+		{Name: "record a payload", Caller: bridge, Run: record("webhook", "tx0", strings.Repeat("x", maxDeadLetterReference+1), 3), Err: "record the event's identifier rather than its payload"},
+		{Name: "record without attempts", Caller: bridge, Run: record("webhook", "tx0", "loan1", 0), Err: "attempts must be positive"},
+		{Name: "record", Caller: bridge, Run: record("webhook", "tx0", "loan1", 3)},
+		{Name: "record for another service", Caller: bridge, Run: record("corebanking", "tx0", "loan1", 1)},
+		{
+			Name:   "recording again adds the attempts",
+			Caller: bridge,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				letter, err := s.RecordDeadLetter(ctx, "webhook", "LoanApproved", "tx0", "loan1", 2, "")
+				if err == nil {
+					require.Equal(t, 5, letter.Attempts)
+					id = letter.ID
+				}
+				return err
+			},
+		},
+		{Name: "list pending", Caller: ops, Run: list("", deadLetterPending, 2)},
+		{Name: "list by service", Caller: ops, Run: list("webhook", "", 1)},
+		{Name: "list by unknown status", Caller: ops, Run: list("", "Lost", 0), Err: `unknown dead letter status "Lost"`},
+		{
+			Name:   "replay requires ops",
+			Caller: bridge,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.ReplayDeadLetter(ctx, id)
+				return err
+			},
+			Err: "requires role ops",
+		},
+		{
+			Name:   "replay emits an event",
+			Caller: ops,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				letter, err := s.ReplayDeadLetter(ctx, id)
+				if err == nil {
+					require.Equal(t, "ops1", letter.ReplayRequestedBy)
+					stub := ctx.GetStub().(*chaincodefakes.ChaincodeStub)
+					require.Equal(t, 1, stub.SetEventCallCount())
+					name, payload := stub.SetEventArgsForCall(0)
+					require.Equal(t, EventDeadLetterReplay, name)
+					var event DeadLetter
+					require.NoError(t, json.Unmarshal(payload, &event))
+					require.Equal(t, deadLetterReplayRequested, event.Status)
+				}
+				return err
+			},
+		},
+		{
+			Name:   "replay twice",
+			Caller: ops,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.ReplayDeadLetter(ctx, id)
+				return err
+			},
+			Err: "is ReplayRequested, not Pending",
+		},
+		{
+			Name:   "resolve",
+			Caller: bridge,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				letter, err := s.ResolveDeadLetter(ctx, id)
+				if err == nil {
+					require.Equal(t, deadLetterResolved, letter.Status)
+				}
+				return err
+			},
+		},
+		{
+			Name:   "resolve twice",
+			Caller: bridge,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.ResolveDeadLetter(ctx, id)
+				return err
+			},
+			Err: "no replay was requested",
+		},
+		{Name: "record a resolved event", Caller: bridge, Run: record("webhook", "tx0", "loan1", 1), Err: "was resolved on"},
+		{
+			Name:   "read missing dead letter",
+			Caller: ops,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.ReadDeadLetter(ctx, "0000")
+				return err
+			},
+			Err: "the dead letter 0000 does not exist",
+		},
+	})
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"authz"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+const (
+	// delinquencyRulesObjectType prefixes the composite key of the delinquency rules
+	delinquencyRulesObjectType = "delinquencyrules"
+
+	// defaultOverdueAfterDays and defaultDefaultAfterDays are the grace periods in force while ops
+	// have not configured them
+	defaultOverdueAfterDays = 5
+	defaultDefaultAfterDays = 90
+
+	// EventLoanDelinquency is the chaincode event emitted with the DelinquencySweep of a
+	// MarkDelinquentLoans run that changed the status of at least one loan. Fabric keeps a single
+	// event per transaction, so every transition of the run is listed in the one payload.
+	EventLoanDelinquency = "LoanDelinquency"
+)
+
+var delinquencyRulesRepo = repository.New[DelinquencyRules](delinquencyRulesObjectType)
+
+// DelinquencyRules are the grace periods, in days past the due date of the oldest unpaid
+// installment, after which a loan in repayment is marked Overdue and then Defaulted
+type DelinquencyRules struct {
+	OverdueAfterDays int    `json:"overdueAfterDays"`
+	DefaultAfterDays int    `json:"defaultAfterDays"`
+	SetBy            string `json:"setBy"`
+	SetAt            string `json:"setAt"`
+}
+
+// DelinquencyTransition records one status change made by MarkDelinquentLoans
+type DelinquencyTransition struct {
+	LoanID      string `json:"loanId"`
+	From        string `json:"from"`
+	To          string `json:"to"`
+	Installment int    `json:"installment"` // the oldest unpaid installment
+	DueDate     string `json:"dueDate"`
+	DaysPastDue int    `json:"daysPastDue"`
+}
+
+// DelinquencySweep reports the outcome of MarkDelinquentLoans
+type DelinquencySweep struct {
+	AsOfDate    string                   `json:"asOfDate"`
+	TxID        string                   `json:"txId"`
+	Transitions []*DelinquencyTransition `json:"transitions,omitempty" metadata:",optional"`
+	Scanned     int                      `json:"scanned"`
+}
+
+// SetDelinquencyRules sets the grace periods MarkDelinquentLoans applies from now on. Restricted to
+// the ops role.
+func (s *SmartContract) SetDelinquencyRules(ctx contractapi.TransactionContextInterface, overdueAfterDays int, defaultAfterDays int) error {
+	err := requireRole(ctx, "ops")
+	if err != nil {
+		return err
+	}
+	if overdueAfterDays < 0 {
+		return fmt.Errorf("overdue grace period must not be negative")
+	}
+	if defaultAfterDays <= overdueAfterDays {
+		return fmt.Errorf("default grace period must be longer than the overdue grace period")
+	}
+
+	operator, _, err := operatorName(ctx)
+	if err != nil {
+		return err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	return delinquencyRulesRepo.Put(ctx, &DelinquencyRules{
+		OverdueAfterDays: overdueAfterDays,
+		DefaultAfterDays: defaultAfterDays,
+		SetBy:            operator,
+		SetAt:            now.Format(time.RFC3339),
+	}, "current")
+}
+
+// GetDelinquencyRules returns the grace periods in force
+func (s *SmartContract) GetDelinquencyRules(ctx contractapi.TransactionContextInterface) (*DelinquencyRules, error) {
+	return delinquencyRules(ctx)
+}
+
+// MarkDelinquentLoans moves every loan in repayment whose oldest unpaid installment is further
+// past due on asOfDate than the configured grace periods to Overdue or Defaulted, and an Overdue
+// loan the borrower has caught up on back to Disbursed. Running it again for the same date
+// changes nothing. It is meant to be submitted daily by the off-chain scheduler and is restricted
+// to the scheduler role.
+func (s *SmartContract) MarkDelinquentLoans(ctx contractapi.TransactionContextInterface, asOfDate string) (*DelinquencySweep, error) {
+	err := requireRole(ctx, "scheduler")
+	if err != nil {
+		return nil, err
+	}
+
+	asOf, err := time.Parse("2006-01-02", asOfDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", asOfDate)
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if asOfDate > now.Format("2006-01-02") {
+		return nil, fmt.Errorf("delinquency cannot be marked ahead of %s", now.Format("2006-01-02"))
+	}
+	rules, err := delinquencyRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByRange(loanNamespace.Range(""))
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	sweep := &DelinquencySweep{AsOfDate: asOfDate, TxID: ctx.GetStub().GetTxID()}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var loan LoanApplication
+		err = json.Unmarshal(queryResponse.Value, &loan)
+		if err != nil {
+			return nil, err
+		}
+		if loan.Status != "Disbursed" && loan.Status != "Overdue" {
+			continue
+		}
+		sweep.Scanned++
+
+		schedule, err := repaymentSchedule(&loan)
+		if err != nil {
+			return nil, err
+		}
+		if loan.PaidInstallments >= len(schedule) {
+			continue
+		}
+		installment := schedule[loan.PaidInstallments]
+		dueDate, err := time.Parse("2006-01-02", installment.DueDate)
+		if err != nil {
+			return nil, err
+		}
+		daysPastDue := int(asOf.Sub(dueDate).Hours() / 24)
+
+		status := "Disbursed"
+		switch {
+		case daysPastDue > rules.DefaultAfterDays:
+			status = "Defaulted"
+		case daysPastDue > rules.OverdueAfterDays:
+			status = "Overdue"
+		}
+		if status == loan.Status {
+			continue
+		}
+
+		sweep.Transitions = append(sweep.Transitions, &DelinquencyTransition{
+			LoanID:      loan.ID,
+			From:        loan.Status,
+			To:          status,
+			Installment: installment.Number,
+			DueDate:     installment.DueDate,
+			DaysPastDue: daysPastDue,
+		})
+		authz.TxLogger(ctx).Info("loan delinquency status changed", "loanId", loan.ID, "from", loan.Status, "to", status, "daysPastDue", daysPastDue)
+		loan.Status = status
+		if status == "Defaulted" {
+			err = flagGroupOf(ctx, &loan)
+			if err != nil {
+				return nil, err
+			}
+		}
+		err = putLoan(ctx, &loan)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(sweep.Transitions) == 0 {
+		return sweep, nil
+	}
+	sweepJSON, err := json.Marshal(sweep)
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.GetStub().SetEvent(EventLoanDelinquency, sweepJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set event: %v", err)
+	}
+
+	return sweep, nil
+}
+
+// delinquencyRules returns the configured delinquency rules, or the defaults while ops have not
+// configured them
+func delinquencyRules(ctx contractapi.TransactionContextInterface) (*DelinquencyRules, error) {
+	rules, err := delinquencyRulesRepo.Get(ctx, "current")
+	if err != nil {
+		return nil, err
+	}
+	if rules == nil {
+		rules = &DelinquencyRules{
+			OverdueAfterDays: defaultOverdueAfterDays,
+			DefaultAfterDays: defaultDefaultAfterDays,
+		}
+	}
+
+	return rules, nil
+}
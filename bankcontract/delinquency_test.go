@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"chaincodetest"
+	"chaincodetest/chaincodefakes"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarkDelinquentLoans(t *testing.T) {
+	s := new(SmartContract)
+	scheduler := chaincodetest.Identity{MSPID: "Org1MSP", CommonName: "scheduler1", Attributes: map[string]string{"role": "scheduler"}}
+	ledger := newLedger(t)
+
+	mark := func(asOfDate string, check func(t *testing.T, sweep *DelinquencySweep, stub *chaincodefakes.ChaincodeStub)) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			sweep, err := s.MarkDelinquentLoans(ctx, asOfDate)
+			if err == nil {
+				check(t, sweep, ctx.GetStub().(*chaincodefakes.ChaincodeStub))
+			}
+			return err
+		}
+	}
+	unchanged := func(t *testing.T, sweep *DelinquencySweep, stub *chaincodefakes.ChaincodeStub) {
+		require.Empty(t, sweep.Transitions)
+		require.Zero(t, stub.SetEventCallCount())
+	}
+	transitioned := func(from string, to string, daysPastDue int) func(t *testing.T, sweep *DelinquencySweep, stub *chaincodefakes.ChaincodeStub) {
+		return func(t *testing.T, sweep *DelinquencySweep, stub *chaincodefakes.ChaincodeStub) {
+			require.Len(t, sweep.Transitions, 1)
+			transition := sweep.Transitions[0]
+			require.Equal(t, "loan1", transition.LoanID)
+			require.Equal(t, from, transition.From)
+			require.Equal(t, to, transition.To)
+			require.Equal(t, daysPastDue, transition.DaysPastDue)
+
+			require.Equal(t, 1, stub.SetEventCallCount())
+			name, payload := stub.SetEventArgsForCall(0)
+			require.Equal(t, EventLoanDelinquency, name)
+			var event DelinquencySweep
+			require.NoError(t, json.Unmarshal(payload, &event))
+			require.Equal(t, *sweep, event)
+		}
+	}
+	status := func(want string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			loan, err := readLoan(ctx, "loan1")
+			if err == nil {
+				require.Equal(t, want, loan.Status)
+			}
+			return err
+		}
+	}
+	setRules := func(overdueAfterDays int, defaultAfterDays int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.SetDelinquencyRules(ctx, overdueAfterDays, defaultAfterDays)
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		setStatus("loan1", "Approved"),
+		setStatus("loan1", "Disbursed"),
+		{Name: "mark requires scheduler", Caller: ops, Run: mark("2024-01-01", nil), Err: "requires role scheduler"},
+		{Name: "mark an invalid date", Caller: scheduler, Run: mark("01/01/2024", nil), Err: `invalid date "01/01/2024"`},
+		{Name: "mark ahead of time", Caller: scheduler, Run: mark("2024-02-10", nil), Err: "delinquency cannot be marked ahead of 2024-01-01"},
+		{Name: "set rules requires ops", Caller: scheduler, Run: setRules(5, 30), Err: "requires role ops"},
+		{Name: "set rules defaulting before overdue", Caller: ops, Run: setRules(30, 30), Err: "default grace period must be longer"},
+		{
+			Name:   "default rules",
+			Caller: scheduler,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				rules, err := s.GetDelinquencyRules(ctx)
+				if err == nil {
+					require.Equal(t, DelinquencyRules{OverdueAfterDays: defaultOverdueAfterDays, DefaultAfterDays: defaultDefaultAfterDays}, *rules)
+				}
+				return err
+			},
+		},
+		{
+			Name:   "nothing is due yet",
+			Caller: scheduler,
+			Run: mark("2024-01-01", func(t *testing.T, sweep *DelinquencySweep, stub *chaincodefakes.ChaincodeStub) {
+				require.Equal(t, 1, sweep.Scanned)
+				unchanged(t, sweep, stub)
+			}),
+		},
+	})
+
+	ledger.Advance(40 * 24 * time.Hour)
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "within the grace period", Caller: scheduler, Run: mark("2024-02-06", unchanged)},
+		{Name: "past the grace period", Caller: scheduler, Run: mark("2024-02-10", transitioned("Disbursed", "Overdue", 9))},
+		{Name: "overdue", Caller: bank, Run: status("Overdue")},
+		{Name: "mark the same date again", Caller: scheduler, Run: mark("2024-02-10", unchanged)},
+		{
+			Name:   "repay while overdue",
+			Caller: officer,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.RecordPayment(ctx, "loan1")
+				return err
+			},
+		},
+		{Name: "caught up", Caller: scheduler, Run: mark("2024-02-10", transitioned("Overdue", "Disbursed", -20))},
+		{Name: "current", Caller: bank, Run: status("Disbursed")},
+		{Name: "set rules", Caller: ops, Run: setRules(5, 30)},
+	})
+
+	ledger.Advance(60 * 24 * time.Hour)
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "past the default grace period", Caller: scheduler, Run: mark("2024-04-10", transitioned("Disbursed", "Defaulted", 40))},
+		{Name: "defaulted", Caller: bank, Run: status("Defaulted")},
+		{
+			Name:   "defaulted loans are not scanned",
+			Caller: scheduler,
+			Run: mark("2024-04-10", func(t *testing.T, sweep *DelinquencySweep, stub *chaincodefakes.ChaincodeStub) {
+				require.Zero(t, sweep.Scanned)
+				unchanged(t, sweep, stub)
+			}),
+		},
+	})
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	// loanPrivateCollection holds application details that must not be shared on the channel
+	loanPrivateCollection = "loanPrivateCollection"
+
+	// deviceObjectType prefixes the private composite keys of device binding records
+	deviceObjectType = "device"
+
+	// maxIdentitiesPerDevice is how many different applicants one device may apply for before
+	// the application is flagged for review
+	maxIdentitiesPerDevice = 3
+)
+
+// DeviceRecord binds a hashed device fingerprint and session to a loan application
+type DeviceRecord struct {
+	DeviceHash  string `json:"deviceHash"`
+	SessionHash string `json:"sessionHash"`
+	LoanID      string `json:"loanId"`
+	Applicant   string `json:"applicant"`
+	RecordedAt  string `json:"recordedAt"`
+}
+
+// GetDeviceHistory returns every application submitted from a device, identified by the hex
+// SHA-256 hash of its fingerprint. Restricted to officer and fraud roles.
+func (s *SmartContract) GetDeviceHistory(ctx contractapi.TransactionContextInterface, deviceHash string) ([]*DeviceRecord, error) {
+	err := requireRole(ctx, "officer", "fraud")
+	if err != nil {
+		return nil, err
+	}
+
+	return deviceRecords(ctx, deviceHash)
+}
+
+// recordDevice stores the device and session presented in the transient map under the
+// device_fingerprint and session_id keys, hashing both so raw values never reach the ledger.
+// Applications from a device already used by too many other applicants are flagged for review.
+func recordDevice(ctx contractapi.TransactionContextInterface, loan *LoanApplication) error {
+	transientMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return fmt.Errorf("error getting transient: %v", err)
+	}
+	fingerprint, ok := transientMap["device_fingerprint"]
+	if !ok || len(fingerprint) == 0 {
+		return nil
+	}
+
+	record := DeviceRecord{
+		DeviceHash: hashHex(fingerprint),
+		LoanID:     loan.ID,
+		Applicant:  loan.Applicant,
+	}
+	if session, ok := transientMap["session_id"]; ok && len(session) > 0 {
+		record.SessionHash = hashHex(session)
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	record.RecordedAt = now.Format(time.RFC3339)
+
+	previous, err := deviceRecords(ctx, record.DeviceHash)
+	if err != nil {
+		return err
+	}
+	applicants := map[string]bool{loan.Applicant: true}
+	for _, other := range previous {
+		applicants[other.Applicant] = true
+	}
+	if len(applicants) > maxIdentitiesPerDevice {
+		loan.ReviewFlags = append(loan.ReviewFlags, fmt.Sprintf("device used by %d different applicants", len(applicants)))
+	}
+
+	recordKey, err := ctx.GetStub().CreateCompositeKey(deviceObjectType, []string{record.DeviceHash, loan.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	recordJSON, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutPrivateData(loanPrivateCollection, recordKey, recordJSON)
+}
+
+func deviceRecords(ctx contractapi.TransactionContextInterface, deviceHash string) ([]*DeviceRecord, error) {
+	resultsIterator, err := ctx.GetStub().GetPrivateDataByPartialCompositeKey(loanPrivateCollection, deviceObjectType, []string{deviceHash})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var records []*DeviceRecord
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var record DeviceRecord
+		err = json.Unmarshal(queryResponse.Value, &record)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, &record)
+	}
+
+	return records, nil
+}
+
+// hashHex returns the hex encoded SHA-256 digest of value
+func hashHex(value []byte) string {
+	digest := sha256.Sum256(value)
+	return hex.EncodeToString(digest[:])
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"chaincodetest/chaincodefakes"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeviceHistory(t *testing.T) {
+	s := new(SmartContract)
+	fraud := chaincodetest.Identity{MSPID: "Org1MSP", CommonName: "fraud1", Attributes: map[string]string{"role": "fraud"}}
+	ledger := chaincodetest.NewLedger()
+	deviceHash := hashHex([]byte("device-1"))
+
+	var cases []chaincodetest.Case
+	for _, applicant := range []string{"Ann", "Ben", "Cat", "Dan"} {
+		applicant := applicant
+		cases = append(cases, chaincodetest.Case{
+			Name:   "apply from the device as " + applicant,
+			Caller: bank,
+			Stub: func(stub *chaincodefakes.ChaincodeStub) {
+				stub.GetTransientReturns(map[string][]byte{"device_fingerprint": []byte("device-1"), "session_id": []byte("session-" + applicant)}, nil)
+			},
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.CreateLoanApplication(ctx, "loan-"+applicant, applicant, 1000, 12, 5)
+			},
+		})
+	}
+	cases = append(cases,
+		chaincodetest.Case{
+			Name:   "applications beyond the device limit are flagged",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				third, err := s.ReadLoanApplication(ctx, "loan-Cat")
+				require.NoError(t, err)
+				require.Empty(t, third.ReviewFlags)
+				fourth, err := s.ReadLoanApplication(ctx, "loan-Dan")
+				require.NoError(t, err)
+				require.Equal(t, []string{"device used by 4 different applicants"}, fourth.ReviewFlags)
+				return nil
+			},
+		},
+		chaincodetest.Case{
+			Name:   "device history requires officer or fraud",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.GetDeviceHistory(ctx, deviceHash)
+				return err
+			},
+			Err: "requires role officer or fraud",
+		},
+		chaincodetest.Case{
+			Name:   "read device history",
+			Caller: fraud,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				records, err := s.GetDeviceHistory(ctx, deviceHash)
+				if err == nil {
+					require.Len(t, records, 4)
+					require.Equal(t, hashHex([]byte("session-Ann")), records[0].SessionHash)
+				}
+				return err
+			},
+		},
+		chaincodetest.Case{
+			Name:   "read history of an unknown device",
+			Caller: officer,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				records, err := s.GetDeviceHistory(ctx, hashHex([]byte("device-2")))
+				require.Empty(t, records)
+				return err
+			},
+		},
+	)
+
+	ledger.Run(t, cases)
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+	"timelock"
+)
+
+const (
+	// disputeObjectType prefixes the composite keys of disputes, one per loan application
+	disputeObjectType = "dispute"
+
+	// disputeWindowDays is how long after a rejection the applicant can dispute it
+	disputeWindowDays = 14
+
+	disputeOpen      = "Open"
+	disputeUpheld    = "Upheld"
+	disputeDismissed = "Dismissed"
+)
+
+var disputeRepo = repository.New[Dispute](disputeObjectType)
+
+// Dispute is an applicant's challenge of the rejection of their loan application
+type Dispute struct {
+	LoanID     string `json:"loanId"`
+	Reason     string `json:"reason"`
+	Status     string `json:"status"`
+	FiledBy    string `json:"filedBy"`
+	FiledAt    string `json:"filedAt"`
+	ResolvedBy string `json:"resolvedBy"`
+	ResolvedAt string `json:"resolvedAt"`
+}
+
+// FileDispute challenges the rejection of a loan application. Only the applicant can file it, and
+// only until the dispute window opened by the rejection closes.
+func (s *SmartContract) FileDispute(ctx contractapi.TransactionContextInterface, loanID string, reason string) (*Dispute, error) {
+	if reason == "" {
+		return nil, fmt.Errorf("dispute reason must not be empty")
+	}
+	loan, err := readLoan(ctx, loanID)
+	if err != nil {
+		return nil, err
+	}
+	if loan.Status != "Rejected" || loan.DisputeDeadline == "" {
+		return nil, fmt.Errorf("the loan application %s is %s, only rejections can be disputed", loanID, loan.Status)
+	}
+	applicant, err := enrollmentID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if applicant != loan.Applicant {
+		return nil, fmt.Errorf("only the applicant can dispute the rejection of loan application %s", loanID)
+	}
+	err = timelock.RequireOpen(ctx, loan.DisputeDeadline, "dispute window")
+	if err != nil {
+		return nil, err
+	}
+	existing, err := disputeRepo.Get(ctx, loanID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil && existing.Status == disputeOpen {
+		return nil, fmt.Errorf("the rejection of loan application %s is already disputed", loanID)
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	dispute := Dispute{
+		LoanID:  loanID,
+		Reason:  reason,
+		Status:  disputeOpen,
+		FiledBy: applicant,
+		FiledAt: now.Format(time.RFC3339),
+	}
+	err = disputeRepo.Put(ctx, &dispute, loanID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dispute, nil
+}
+
+// ResolveDispute settles an open dispute. An upheld dispute returns the loan application to
+// Pending for review again; a dismissed one leaves it rejected. Restricted to the officer role.
+func (s *SmartContract) ResolveDispute(ctx contractapi.TransactionContextInterface, loanID string, upheld bool) (*Dispute, error) {
+	err := requireRole(ctx, "officer")
+	if err != nil {
+		return nil, err
+	}
+	dispute, err := disputeRepo.Get(ctx, loanID)
+	if err != nil {
+		return nil, err
+	}
+	if dispute == nil || dispute.Status != disputeOpen {
+		return nil, fmt.Errorf("the rejection of loan application %s is not under dispute", loanID)
+	}
+
+	operator, _, err := operatorName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	dispute.Status = disputeDismissed
+	if upheld {
+		dispute.Status = disputeUpheld
+		loan, err := readLoan(ctx, loanID)
+		if err != nil {
+			return nil, err
+		}
+		loan.Status = "Pending"
+		loan.DisputeDeadline = ""
+		err = putLoan(ctx, loan)
+		if err != nil {
+			return nil, err
+		}
+	}
+	dispute.ResolvedBy = operator
+	dispute.ResolvedAt = now.Format(time.RFC3339)
+	err = disputeRepo.Put(ctx, dispute, loanID)
+	if err != nil {
+		return nil, err
+	}
+
+	return dispute, nil
+}
+
+// GetDispute returns the dispute of a loan application's rejection, or nil if there is none
+func (s *SmartContract) GetDispute(ctx contractapi.TransactionContextInterface, loanID string) (*Dispute, error) {
+	return disputeRepo.Get(ctx, loanID)
+}
+
+// openDisputeWindow starts the window in which the applicant can dispute a rejection
+func openDisputeWindow(ctx contractapi.TransactionContextInterface, loan *LoanApplication) error {
+	deadline, err := timelock.Deadline(ctx, disputeWindowDays*24*time.Hour)
+	if err != nil {
+		return err
+	}
+
+	loan.DisputeDeadline = deadline
+	return nil
+}
+
+// checkRejectionFinal returns an error while the rejection of a loan application can still be
+// disputed or is under dispute, so that it is not acted on as final
+func checkRejectionFinal(ctx contractapi.TransactionContextInterface, loan *LoanApplication) error {
+	if loan.Status != "Rejected" || loan.DisputeDeadline == "" {
+		return nil
+	}
+	err := timelock.RequireElapsed(ctx, loan.DisputeDeadline, "dispute window of loan application "+loan.ID)
+	if err != nil {
+		return err
+	}
+	dispute, err := disputeRepo.Get(ctx, loan.ID)
+	if err != nil {
+		return err
+	}
+	if dispute != nil && dispute.Status == disputeOpen {
+		return fmt.Errorf("the rejection of loan application %s is under dispute", loan.ID)
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDisputes(t *testing.T) {
+	s := new(SmartContract)
+	bob := chaincodetest.Identity{MSPID: "Org1MSP", CommonName: "Bob", Attributes: map[string]string{"role": "customer"}}
+	carol := chaincodetest.Identity{MSPID: "Org1MSP", CommonName: "Carol", Attributes: map[string]string{"role": "customer"}}
+	ledger := newLedger(t)
+
+	file := func(loanID string, reason string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			dispute, err := s.FileDispute(ctx, loanID, reason)
+			if err == nil {
+				require.Equal(t, disputeOpen, dispute.Status)
+			}
+			return err
+		}
+	}
+	resolve := func(loanID string, upheld bool, status string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			dispute, err := s.ResolveDispute(ctx, loanID, upheld)
+			if err == nil {
+				require.Equal(t, status, dispute.Status)
+				require.Equal(t, "officer1", dispute.ResolvedBy)
+			}
+			return err
+		}
+	}
+	remove := func(loanID string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.DeleteLoanApplication(ctx, loanID, "withdrawn")
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		createLoan("loan3", "Bob", 2000),
+		createLoan("loan4", "Carol", 2000),
+		{Name: "dispute a pending loan", Caller: bob, Run: file("loan3", "income was misread"), Err: "the loan application loan3 is Pending, only rejections can be disputed"},
+		setStatus("loan3", "Rejected"),
+		setStatus("loan4", "Rejected"),
+		{Name: "dispute without a reason", Caller: bob, Run: file("loan3", ""), Err: "dispute reason must not be empty"},
+		{Name: "dispute another applicant's loan", Caller: carol, Run: file("loan3", "income was misread"), Err: "only the applicant can dispute"},
+		{Name: "dispute", Caller: bob, Run: file("loan3", "income was misread")},
+		{Name: "dispute twice", Caller: bob, Run: file("loan3", "income was misread"), Err: "is already disputed"},
+		{Name: "delete within the dispute window", Caller: officer, Run: remove("loan4"), Err: "the dispute window of loan application loan4 is open until 2024-01-15T09:00:"},
+	})
+
+	ledger.Advance(disputeWindowDays * 24 * time.Hour)
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "dispute after the window", Caller: carol, Run: file("loan4", "income was misread"), Err: "the dispute window closed at 2024-01-15T09:00:"},
+		{Name: "delete after the window", Caller: officer, Run: remove("loan4")},
+		{Name: "delete while under dispute", Caller: officer, Run: remove("loan3"), Err: "the rejection of loan application loan3 is under dispute"},
+		{Name: "resolve requires officer", Caller: bank, Run: resolve("loan3", false, ""), Err: "requires role officer"},
+		{Name: "dismiss", Caller: officer, Run: resolve("loan3", false, disputeDismissed)},
+		{Name: "resolve twice", Caller: officer, Run: resolve("loan3", true, ""), Err: "the rejection of loan application loan3 is not under dispute"},
+		{Name: "delete after dismissal", Caller: officer, Run: remove("loan3")},
+	})
+
+	ledger.Run(t, []chaincodetest.Case{
+		createLoan("loan5", "Bob", 2000),
+		setStatus("loan5", "Rejected"),
+		{Name: "dispute loan5", Caller: bob, Run: file("loan5", "wrong branch")},
+		{Name: "uphold", Caller: officer, Run: resolve("loan5", true, disputeUpheld)},
+		{
+			Name:   "an upheld dispute reopens the application",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				loan, err := readLoan(ctx, "loan5")
+				if err == nil {
+					require.Equal(t, "Pending", loan.Status)
+					require.Empty(t, loan.DisputeDeadline)
+				}
+				return err
+			},
+		},
+	})
+}
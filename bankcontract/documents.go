@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"authz"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+// loanDocumentObjectType prefixes the composite keys of documents anchored to a loan application,
+// loandocument~loanID~docType~sha256
+const loanDocumentObjectType = "loandocument"
+
+var loanDocumentRepo = repository.New[LoanDocument](loanDocumentObjectType)
+
+// loanDocumentRoles lists, by document type, the roles allowed to attach it. Customers may only
+// attach documents to their own applications.
+var loanDocumentRoles = map[string][]string{
+	"salarySlip":      {"customer", "officer"},
+	"bankStatement":   {"customer", "officer"},
+	"identityProof":   {"customer", "officer"},
+	"creditReport":    {"officer"},
+	"valuationReport": {"officer"},
+	"sanctionLetter":  {"officer"},
+}
+
+// LoanDocument anchors the hash of an off-chain document supporting a loan application, such as a
+// salary slip or bank statement kept in S3 or IPFS
+type LoanDocument struct {
+	LoanID     string `json:"loanId"`
+	DocType    string `json:"docType"`
+	SHA256     string `json:"sha256"`
+	StorageURI string `json:"storageUri"`
+	AttachedAt string `json:"attachedAt"`
+	AttachedBy string `json:"attachedBy"`
+	Role       string `json:"role"`
+}
+
+// AttachLoanDocument records the SHA-256 hash and storage location of a document supporting a loan
+// application, so that the stored file can later be checked against the ledger. Which roles may
+// attach a document depends on its type; a customer's enrollment must be bound by its identityId
+// attribute to the applicant.
+func (s *SmartContract) AttachLoanDocument(ctx contractapi.TransactionContextInterface, loanID string, docType string, sha256Hash string, storageURI string) error {
+	roles, known := loanDocumentRoles[docType]
+	if !known {
+		return fmt.Errorf("unknown document type %q, expected one of %s", docType, strings.Join(loanDocumentTypes(), ", "))
+	}
+	err := requireRole(ctx, roles...)
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(storageURI, "s3://") && !strings.HasPrefix(storageURI, "ipfs://") {
+		return fmt.Errorf("storage URI must be an s3:// or ipfs:// location")
+	}
+
+	sha256Hash = strings.ToLower(sha256Hash)
+	decoded, err := hex.DecodeString(sha256Hash)
+	if err != nil || len(decoded) != 32 {
+		return fmt.Errorf("document hash must be a hex encoded SHA-256 digest")
+	}
+
+	loan, err := readLoan(ctx, loanID)
+	if err != nil {
+		return err
+	}
+	role, _, err := authz.Attribute(ctx, "role")
+	if err != nil {
+		return err
+	}
+	if role == "customer" {
+		bound, _, err := authz.Attribute(ctx, "identityId")
+		if err != nil {
+			return fmt.Errorf("failed to read client identityId attribute: %v", err)
+		}
+		if bound == "" || bound != loan.Applicant {
+			return fmt.Errorf("customers may only attach documents to their own loan applications")
+		}
+	}
+
+	exists, err := loanDocumentRepo.Exists(ctx, loanID, docType, sha256Hash)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("the %s document %s is already attached to loan application %s", docType, sha256Hash, loanID)
+	}
+
+	attacher, err := enrollmentID(ctx)
+	if err != nil {
+		return err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	return loanDocumentRepo.Put(ctx, &LoanDocument{
+		LoanID:     loanID,
+		DocType:    docType,
+		SHA256:     sha256Hash,
+		StorageURI: storageURI,
+		AttachedAt: now.Format(time.RFC3339),
+		AttachedBy: attacher,
+		Role:       role,
+	}, loanID, docType, sha256Hash)
+}
+
+// ListLoanDocuments returns the documents anchored to a loan application, by type
+func (s *SmartContract) ListLoanDocuments(ctx contractapi.TransactionContextInterface, loanID string) ([]*LoanDocument, error) {
+	_, err := readLoan(ctx, loanID)
+	if err != nil {
+		return nil, err
+	}
+
+	return loanDocumentRepo.List(ctx, loanID)
+}
+
+// loanDocumentTypes returns the known document types in order
+func loanDocumentTypes() []string {
+	docTypes := make([]string, 0, len(loanDocumentRoles))
+	for docType := range loanDocumentRoles {
+		docTypes = append(docTypes, docType)
+	}
+	sort.Strings(docTypes)
+
+	return docTypes
+}
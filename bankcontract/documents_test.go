@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoanDocuments(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	const slipHash = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	afraz := chaincodetest.Identity{MSPID: "Org1MSP", CommonName: "afraz", Attributes: map[string]string{"role": "customer", "identityId": "Afraz"}}
+	attach := func(loanID string, docType string, hash string, uri string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.AttachLoanDocument(ctx, loanID, docType, hash, uri)
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "applicant attaches a salary slip", Caller: afraz, Run: attach("loan1", "salarySlip", slipHash, "s3://loans/loan1/slip.pdf")},
+		{Name: "attach twice", Caller: officer, Run: attach("loan1", "salarySlip", strings.ToUpper(slipHash), "ipfs://bafy/slip.pdf"), Err: "is already attached to loan application loan1"},
+		{Name: "customers attach to their own applications only", Caller: afraz, Run: attach("loan2", "bankStatement", slipHash, "s3://loans/loan2/statement.pdf"), Err: "customers may only attach documents to their own loan applications"},
+		{Name: "unbound customers", Caller: customer, Run: attach("loan1", "bankStatement", slipHash, "s3://loans/loan1/statement.pdf"), Err: "customers may only attach documents"},
+		{Name: "customers cannot attach credit reports", Caller: afraz, Run: attach("loan1", "creditReport", slipHash, "s3://loans/loan1/credit.pdf"), Err: "requires role officer"},
+		{Name: "unknown document type", Caller: officer, Run: attach("loan1", "selfie", slipHash, "s3://loans/loan1/selfie.jpg"), Err: `unknown document type "selfie"`},
+		{Name: "unsupported storage", Caller: officer, Run: attach("loan1", "creditReport", slipHash, "https://example.com/credit.pdf"), Err: "storage URI must be an s3:// or ipfs:// location"},
+		{Name: "short hash", Caller: officer, Run: attach("loan1", "creditReport", "9f86d081", "s3://loans/loan1/credit.pdf"), Err: "document hash must be a hex encoded SHA-256 digest"},
+		{Name: "unknown loan", Caller: officer, Run: attach("loan9", "creditReport", slipHash, "s3://loans/loan9/credit.pdf"), Err: "does not exist"},
+		{Name: "officer attaches a credit report", Caller: officer, Run: attach("loan1", "creditReport", slipHash, "ipfs://bafy/credit.pdf")},
+		{
+			Name:   "list documents",
+			Caller: auditor,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				documents, err := s.ListLoanDocuments(ctx, "loan1")
+				if err == nil {
+					require.Len(t, documents, 2)
+					require.Equal(t, "creditReport", documents[0].DocType)
+					require.Equal(t, "officer1", documents[0].AttachedBy)
+					require.Equal(t, "salarySlip", documents[1].DocType)
+					require.Equal(t, "customer", documents[1].Role)
+					require.Equal(t, "2024-01-01T09:00:02Z", documents[1].AttachedAt)
+				}
+				return err
+			},
+		},
+	})
+}
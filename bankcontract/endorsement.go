@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/statebased"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	// highValueLoanThreshold is the loan amount above which changes to a loan need the endorsement
+	// of the risk org as well as of the org holding it
+	highValueLoanThreshold = 100000
+
+	// riskMSP is the org of the risk function co-endorsing changes to high-value loans
+	riskMSP = "Org2MSP"
+)
+
+// KeyEndorsementPolicy lists the orgs whose peers must all endorse changes to a loan. Orgs is empty
+// while the chaincode endorsement policy applies.
+type KeyEndorsementPolicy struct {
+	LoanID string   `json:"loanId"`
+	Orgs   []string `json:"orgs,omitempty" metadata:",optional"`
+}
+
+// GetKeyEndorsementPolicy returns the key-level endorsement policy set on a loan
+func (s *SmartContract) GetKeyEndorsementPolicy(ctx contractapi.TransactionContextInterface, loanID string) (*KeyEndorsementPolicy, error) {
+	_, err := readLoan(ctx, loanID)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := ctx.GetStub().GetStateValidationParameter(loanNamespace.Key(loanID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the endorsement policy of loan %s: %v", loanID, err)
+	}
+
+	result := &KeyEndorsementPolicy{LoanID: loanID}
+	if len(policy) == 0 {
+		return result, nil
+	}
+	endorsementPolicy, err := statebased.NewStateEP(policy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the endorsement policy of loan %s: %v", loanID, err)
+	}
+	result.Orgs = endorsementPolicy.ListOrgs()
+	sort.Strings(result.Orgs)
+
+	return result, nil
+}
+
+// loanEndorsers returns the orgs that must endorse changes to a loan held by another org than the
+// one that created it: the holder, and the risk org too while a high-value loan is open
+func loanEndorsers(loan *LoanApplication) []string {
+	if loan.Amount > highValueLoanThreshold && loan.Status != "Closed" {
+		return []string{loan.OwnerMSP, riskMSP}
+	}
+
+	return []string{loan.OwnerMSP}
+}
+
+// updateHighValueEndorsement requires the risk org to co-endorse changes to a high-value loan from
+// the moment it is created, and releases the loan to the chaincode endorsement policy once it is
+// closed. previous is the loan as stored before the write, nil for a new loan.
+func updateHighValueEndorsement(ctx contractapi.TransactionContextInterface, loan *LoanApplication, previous []byte) error {
+	if loan.Amount <= highValueLoanThreshold {
+		return nil
+	}
+	if previous == nil {
+		return setLoanEndorsers(ctx, loan.ID, loan.OwnerMSP, riskMSP)
+	}
+	if loan.Status != "Closed" {
+		return nil
+	}
+
+	var before LoanApplication
+	err := json.Unmarshal(previous, &before)
+	if err != nil {
+		return err
+	}
+	if before.Status == "Closed" {
+		return nil
+	}
+
+	return setLoanEndorsers(ctx, loan.ID)
+}
+
+// setLoanEndorsers sets a key-level endorsement policy on a loan requiring a peer of each of the
+// given orgs, which takes precedence over the chaincode endorsement policy for changes to that loan.
+// Without orgs the key-level policy is removed.
+func setLoanEndorsers(ctx contractapi.TransactionContextInterface, loanID string, mspIDs ...string) error {
+	if len(mspIDs) == 0 {
+		return ctx.GetStub().SetStateValidationParameter(loanNamespace.Key(loanID), nil)
+	}
+
+	endorsementPolicy, err := statebased.NewStateEP(nil)
+	if err != nil {
+		return err
+	}
+	err = endorsementPolicy.AddOrgs(statebased.RoleTypePeer, mspIDs...)
+	if err != nil {
+		return fmt.Errorf("failed to add orgs %v to the endorsement policy of loan %s: %v", mspIDs, loanID, err)
+	}
+	policy, err := endorsementPolicy.Policy()
+	if err != nil {
+		return fmt.Errorf("failed to create the endorsement policy of loan %s: %v", loanID, err)
+	}
+
+	return ctx.GetStub().SetStateValidationParameter(loanNamespace.Key(loanID), policy)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHighValueEndorsement(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	endorsedBy := func(loanID string, want ...string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			policy, err := s.GetKeyEndorsementPolicy(ctx, loanID)
+			if err == nil {
+				require.Equal(t, loanID, policy.LoanID)
+				require.Equal(t, want, policy.Orgs)
+			}
+			return err
+		}
+	}
+	create := func(id string, amount int, term int) chaincodetest.Case {
+		return chaincodetest.Case{
+			Name:   "create " + id,
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.CreateLoanApplication(ctx, id, "Bob", amount, term, 5)
+			},
+		}
+	}
+	pay := func(loanID string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.RecordPayment(ctx, loanID)
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		create("small", highValueLoanThreshold, 12),
+		{Name: "loans up to the threshold keep the chaincode policy", Caller: bank, Run: endorsedBy("small")},
+		create("large", highValueLoanThreshold+1, 1),
+		{Name: "high-value loans need the risk org", Caller: bank, Run: endorsedBy("large", "Org1MSP", riskMSP)},
+		{Name: "the policy of a missing loan", Caller: bank, Run: endorsedBy("loan9"), Err: "the loan application loan9 does not exist"},
+		setStatus("large", "Disbursed"),
+		{Name: "the policy holds while the loan is open", Caller: bank, Run: endorsedBy("large", "Org1MSP", riskMSP)},
+		{Name: "repay the only installment", Caller: officer, Run: pay("large")},
+		{Name: "closing the loan releases the policy", Caller: bank, Run: endorsedBy("large")},
+	})
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"authz"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// dataExportVersion is the version of the DataExport layout, raised whenever a field changes
+const dataExportVersion = 1
+
+// DataExport bundles everything the ledger holds about one data subject. The identity record and
+// consents are read from the identity chaincode as it returns them.
+type DataExport struct {
+	Version    int                       `json:"version"`
+	IdentityID string                    `json:"identityId"`
+	ExportedAt string                    `json:"exportedAt"`
+	ExportedBy string                    `json:"exportedBy"`
+	Identity   json.RawMessage           `json:"identity"`
+	Consents   json.RawMessage           `json:"consents"`
+	Loans      []*LoanApplication        `json:"loans"`
+	Terms      map[string]*TermsRecord   `json:"terms"`     // the terms disclosed at approval, by loan ID
+	Schedules  map[string][]*Installment `json:"schedules"` // by loan ID, for disbursed loans
+}
+
+// ExportMyData answers a data subject access request with a single JSON DataExport of the
+// identity record, consents, loans, approved terms and repayment schedules of an identity.
+// A customer's enrollment is bound to its identity by the identityId attribute and exports only
+// that identity; an officer exports any identity on a customer's behalf.
+func (s *SmartContract) ExportMyData(ctx contractapi.TransactionContextInterface, identityID string) (string, error) {
+	bound, found, err := authz.Attribute(ctx, "identityId")
+	if err != nil {
+		return "", fmt.Errorf("failed to read client identityId attribute: %v", err)
+	}
+	switch {
+	case found && bound != "" && (identityID == "" || identityID == bound):
+		identityID = bound
+	case identityID == "":
+		return "", fmt.Errorf("identityID must not be empty unless the enrollment is bound to an identity")
+	default:
+		err = requireRole(ctx, "officer")
+		if err != nil {
+			return "", err
+		}
+	}
+
+	exporter, err := enrollmentID(ctx)
+	if err != nil {
+		return "", err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return "", err
+	}
+	export := DataExport{
+		Version:    dataExportVersion,
+		IdentityID: identityID,
+		ExportedAt: now.Format(time.RFC3339),
+		ExportedBy: exporter,
+		Terms:      make(map[string]*TermsRecord),
+		Schedules:  make(map[string][]*Installment),
+	}
+
+	export.Identity, err = queryIdentityChaincode(ctx, "ReadIdentity", identityID)
+	if err != nil {
+		return "", err
+	}
+	export.Consents, err = queryIdentityChaincode(ctx, "GetConsents", identityID)
+	if err != nil {
+		return "", err
+	}
+
+	export.Loans, err = loansByApplicant(ctx, identityID)
+	if err != nil {
+		return "", err
+	}
+	for _, loan := range export.Loans {
+		if loan.TermsHash != "" {
+			export.Terms[loan.ID], err = s.GetTermsRecord(ctx, loan.ID)
+			if err != nil {
+				return "", err
+			}
+		}
+		if loan.DisbursedAt != "" {
+			export.Schedules[loan.ID], err = repaymentSchedule(loan)
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+
+	exportJSON, err := json.Marshal(export)
+	if err != nil {
+		return "", err
+	}
+
+	return string(exportJSON), nil
+}
+
+// queryIdentityChaincode calls a function of the identity chaincode taking an identity ID and
+// returns its JSON result. The identity chaincode checks the submitting client's access itself.
+func queryIdentityChaincode(ctx contractapi.TransactionContextInterface, function string, identityID string) (json.RawMessage, error) {
+	response := ctx.GetStub().InvokeChaincode(identityChaincode, [][]byte{[]byte(function), []byte(identityID)}, "")
+	if response.Status != shim.OK {
+		return nil, fmt.Errorf("failed to query %s of %s from %s: %s", function, identityID, identityChaincode, response.Message)
+	}
+	if len(response.Payload) == 0 {
+		return json.RawMessage("null"), nil
+	}
+
+	return json.RawMessage(response.Payload), nil
+}
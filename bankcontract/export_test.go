@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportMyData(t *testing.T) {
+	s := new(SmartContract)
+	afraz := chaincodetest.Identity{MSPID: "Org1MSP", CommonName: "afraz", Attributes: map[string]string{"role": "customer", "identityId": "Afraz"}}
+	ledger := newLedger(t)
+	ledger.Install(identityChaincode, identityChaincodeOf(map[string]string{"Afraz": "Full", "Alam": "Basic"}))
+
+	export := func(identityID string, check func(t *testing.T, export *DataExport)) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			exportJSON, err := s.ExportMyData(ctx, identityID)
+			if err == nil {
+				var export DataExport
+				require.NoError(t, json.Unmarshal([]byte(exportJSON), &export))
+				check(t, &export)
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		setStatus("loan1", "Approved"),
+		setStatus("loan1", "Disbursed"),
+		{
+			Name:   "export own data",
+			Caller: afraz,
+			Run: export("", func(t *testing.T, export *DataExport) {
+				require.Equal(t, dataExportVersion, export.Version)
+				require.Equal(t, "Afraz", export.IdentityID)
+				require.Equal(t, "afraz", export.ExportedBy)
+				require.JSONEq(t, `{"id":"Afraz"}`, string(export.Identity))
+				require.Len(t, export.Loans, 1)
+				require.Contains(t, export.Terms, "loan1")
+				require.Len(t, export.Schedules["loan1"], 12)
+			}),
+		},
+		{
+			Name:   "customers cannot export another identity",
+			Caller: afraz,
+			Run:    export("Alam", nil),
+			Err:    "requires role officer",
+		},
+		{
+			Name:   "officers export on a customer's behalf",
+			Caller: officer,
+			Run: export("Alam", func(t *testing.T, export *DataExport) {
+				require.Equal(t, "Alam", export.IdentityID)
+				require.Len(t, export.Loans, 1)
+				require.Empty(t, export.Schedules)
+			}),
+		},
+		{
+			Name:   "export without an identity",
+			Caller: officer,
+			Run:    export("", nil),
+			Err:    "identityID must not be empty",
+		},
+		{
+			Name:   "export an unknown identity",
+			Caller: officer,
+			Run:    export("Zed", nil),
+			Err:    "failed to query ReadIdentity of Zed from identitycontract",
+		},
+	})
+}
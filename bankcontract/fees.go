@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+// feeScheduleObjectType prefixes the composite key of the processing fee schedule. Only the
+// current schedule is kept in the world state; earlier versions remain in its key history.
+const feeScheduleObjectType = "feeschedule"
+
+var feeScheduleRepo = repository.New[FeeSchedule](feeScheduleObjectType)
+
+// FeeBand is the processing fee charged on loan amounts up to UpTo, or on any larger amount when
+// UpTo is 0. The fee is Flat plus RateBps basis points of the amount, and at least Minimum.
+type FeeBand struct {
+	UpTo    int `json:"upTo"`
+	RateBps int `json:"rateBps"`
+	Flat    int `json:"flat"`
+	Minimum int `json:"minimum"`
+}
+
+// FeeSchedule is the banding table processing fees are calculated from
+type FeeSchedule struct {
+	Version int        `json:"version"`
+	Bands   []*FeeBand `json:"bands"`
+	SetBy   string     `json:"setBy"`
+	SetAt   string     `json:"setAt"`
+}
+
+// FeeQuote is the processing fee of a loan amount under a version of the fee schedule
+type FeeQuote struct {
+	Amount             int `json:"amount"`
+	Fee                int `json:"fee"`
+	BandUpTo           int `json:"bandUpTo"`
+	FeeScheduleVersion int `json:"feeScheduleVersion"`
+}
+
+// SetFeeSchedule replaces the processing fee schedule with the bands in bandsJSON, ordered by
+// ascending UpTo, with only the last band left open ended. Each replacement gets the next version
+// number, which is recorded on the loans charged under it. Restricted to the ops role.
+func (s *SmartContract) SetFeeSchedule(ctx contractapi.TransactionContextInterface, bandsJSON string) (*FeeSchedule, error) {
+	err := requireRole(ctx, "ops")
+	if err != nil {
+		return nil, err
+	}
+
+	var bands []*FeeBand
+	err = json.Unmarshal([]byte(bandsJSON), &bands)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fee bands: %v", err)
+	}
+	if len(bands) == 0 {
+		return nil, fmt.Errorf("the fee schedule must have at least one band")
+	}
+	for i, band := range bands {
+		if band.RateBps < 0 || band.Flat < 0 || band.Minimum < 0 {
+			return nil, fmt.Errorf("fee band %d must not have negative fees", i+1)
+		}
+		if band.UpTo == 0 && i != len(bands)-1 {
+			return nil, fmt.Errorf("only the last fee band may be open ended")
+		}
+		if band.UpTo < 0 || (i > 0 && band.UpTo != 0 && band.UpTo <= bands[i-1].UpTo) {
+			return nil, fmt.Errorf("fee band %d must cover larger amounts than the band before it", i+1)
+		}
+	}
+
+	current, err := getFeeSchedule(ctx)
+	if err != nil {
+		return nil, err
+	}
+	operator, _, err := operatorName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	schedule := FeeSchedule{
+		Version: 1,
+		Bands:   bands,
+		SetBy:   operator,
+		SetAt:   now.Format(time.RFC3339),
+	}
+	if current != nil {
+		schedule.Version = current.Version + 1
+	}
+
+	err = putFeeSchedule(ctx, &schedule)
+	if err != nil {
+		return nil, err
+	}
+
+	return &schedule, nil
+}
+
+// GetFeeSchedule returns the current processing fee schedule
+func (s *SmartContract) GetFeeSchedule(ctx contractapi.TransactionContextInterface) (*FeeSchedule, error) {
+	schedule, err := getFeeSchedule(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if schedule == nil {
+		return nil, fmt.Errorf("no fee schedule has been set")
+	}
+
+	return schedule, nil
+}
+
+// PreviewFees returns the processing fee CreateLoanApplication would charge on a loan amount
+// under the current fee schedule. Meant to be evaluated, not submitted.
+func (s *SmartContract) PreviewFees(ctx contractapi.TransactionContextInterface, amount int) (*FeeQuote, error) {
+	if amount <= 0 {
+		return nil, fmt.Errorf("loan amount must be positive")
+	}
+
+	return quoteFees(ctx, amount)
+}
+
+// quoteFees calculates the processing fee of a loan amount under the current fee schedule. No fee
+// is charged while no schedule has been set, and the quote then carries version 0.
+func quoteFees(ctx contractapi.TransactionContextInterface, amount int) (*FeeQuote, error) {
+	quote := FeeQuote{Amount: amount}
+	schedule, err := getFeeSchedule(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if schedule == nil {
+		return &quote, nil
+	}
+	quote.FeeScheduleVersion = schedule.Version
+
+	for _, band := range schedule.Bands {
+		if band.UpTo != 0 && amount > band.UpTo {
+			continue
+		}
+		quote.BandUpTo = band.UpTo
+		quote.Fee = band.Flat + amount*band.RateBps/10000
+		if quote.Fee < band.Minimum {
+			quote.Fee = band.Minimum
+		}
+		return &quote, nil
+	}
+
+	return nil, fmt.Errorf("no band of fee schedule version %d covers the amount %d", schedule.Version, amount)
+}
+
+func getFeeSchedule(ctx contractapi.TransactionContextInterface) (*FeeSchedule, error) {
+	return feeScheduleRepo.Get(ctx, "current")
+}
+
+func putFeeSchedule(ctx contractapi.TransactionContextInterface, schedule *FeeSchedule) error {
+	return feeScheduleRepo.Put(ctx, schedule, "current")
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeeSchedule(t *testing.T) {
+	s := new(SmartContract)
+	ledger := chaincodetest.NewLedger()
+
+	setSchedule := func(bandsJSON string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.SetFeeSchedule(ctx, bandsJSON)
+			return err
+		}
+	}
+	preview := func(amount int, fee int, version int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			quote, err := s.PreviewFees(ctx, amount)
+			if err == nil {
+				require.Equal(t, fee, quote.Fee)
+				require.Equal(t, version, quote.FeeScheduleVersion)
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{
+			Name:   "read before any schedule is set",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.GetFeeSchedule(ctx)
+				return err
+			},
+			Err: "no fee schedule has been set",
+		},
+		{Name: "no fee is charged without a schedule", Caller: bank, Run: preview(1000, 0, 0)},
+		{Name: "set schedule requires ops", Caller: bank, Run: setSchedule(`[{"upTo":0,"flat":10}]`), Err: "requires role ops"},
+		{Name: "set invalid bands", Caller: ops, Run: setSchedule(`{`), Err: "invalid fee bands"},
+		{Name: "set no bands", Caller: ops, Run: setSchedule(`[]`), Err: "at least one band"},
+		{Name: "set negative fees", Caller: ops, Run: setSchedule(`[{"upTo":0,"flat":-1}]`), Err: "fee band 1 must not have negative fees"},
+		{Name: "set an open band first", Caller: ops, Run: setSchedule(`[{"upTo":0},{"upTo":1000}]`), Err: "only the last fee band may be open ended"},
+		{Name: "set unordered bands", Caller: ops, Run: setSchedule(`[{"upTo":1000},{"upTo":500},{"upTo":0}]`), Err: "fee band 2 must cover larger amounts"},
+		{Name: "set a closed schedule", Caller: ops, Run: setSchedule(`[{"upTo":1000,"flat":10}]`)},
+		{Name: "preview beyond the last band", Caller: bank, Run: preview(2000, 0, 0), Err: "no band of fee schedule version 1 covers the amount 2000"},
+		{Name: "set schedule", Caller: ops, Run: setSchedule(`[{"upTo":1000,"flat":10},{"upTo":0,"rateBps":100,"minimum":25}]`)},
+		{
+			Name:   "schedules are versioned",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				schedule, err := s.GetFeeSchedule(ctx)
+				if err == nil {
+					require.Equal(t, 2, schedule.Version)
+					require.Equal(t, "ops1", schedule.SetBy)
+					require.Len(t, schedule.Bands, 2)
+				}
+				return err
+			},
+		},
+		{Name: "preview the flat band", Caller: bank, Run: preview(1000, 10, 2)},
+		{Name: "preview the minimum", Caller: bank, Run: preview(2000, 25, 2)},
+		{Name: "preview the rate", Caller: bank, Run: preview(5000, 50, 2)},
+		{Name: "preview a non-positive amount", Caller: bank, Run: preview(0, 0, 0), Err: "loan amount must be positive"},
+		createLoan("loan3", "Bob", 5000),
+		{
+			Name:   "applications are charged the processing fee",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				loan, err := s.ReadLoanApplication(ctx, "loan3")
+				if err == nil {
+					require.Equal(t, 50, loan.ProcessingFee)
+					require.Equal(t, 2, loan.FeeScheduleVersion)
+				}
+				return err
+			},
+		},
+	})
+}
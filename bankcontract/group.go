@@ -0,0 +1,250 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+const (
+	// groupObjectType prefixes the composite keys of borrower groups
+	groupObjectType = "group"
+
+	// groupMemberIndex maps each member identity to the single group it belongs to
+	groupMemberIndex = "member~group"
+)
+
+var groupRepo = repository.New[BorrowerGroup](groupObjectType)
+
+// BorrowerGroup is a set of borrowers who are jointly liable for each other's loans, as used by
+// microfinance products. A default by any member flags the whole group and blocks new applications.
+type BorrowerGroup struct {
+	ID            string   `json:"id"`
+	Members       []string `json:"members"`
+	ExposureLimit int      `json:"exposureLimit"`
+	Flagged       bool     `json:"flagged"`
+	FlaggedReason string   `json:"flaggedReason"`
+	FlaggedAt     string   `json:"flaggedAt"`
+}
+
+// CreateBorrowerGroup registers a joint-liability group. A member may belong to only one group.
+// Restricted to the officer role.
+func (s *SmartContract) CreateBorrowerGroup(ctx contractapi.TransactionContextInterface, id string, members []string, exposureLimit int) error {
+	err := requireRole(ctx, "officer")
+	if err != nil {
+		return err
+	}
+
+	existing, err := getBorrowerGroup(ctx, id)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("the borrower group %s already exists", id)
+	}
+	if len(members) < 2 {
+		return fmt.Errorf("a borrower group needs at least two members")
+	}
+	if exposureLimit <= 0 {
+		return fmt.Errorf("exposure limit must be positive")
+	}
+
+	seen := make(map[string]bool)
+	for _, member := range members {
+		if member == "" {
+			return fmt.Errorf("member identity must not be empty")
+		}
+		if seen[member] {
+			return fmt.Errorf("member %s is listed twice", member)
+		}
+		seen[member] = true
+
+		groupID, err := groupOfMember(ctx, member)
+		if err != nil {
+			return err
+		}
+		if groupID != "" {
+			return fmt.Errorf("member %s already belongs to borrower group %s", member, groupID)
+		}
+
+		memberKey, err := ctx.GetStub().CreateCompositeKey(groupMemberIndex, []string{member})
+		if err != nil {
+			return fmt.Errorf("failed to create composite key: %v", err)
+		}
+		err = ctx.GetStub().PutState(memberKey, []byte(id))
+		if err != nil {
+			return err
+		}
+	}
+
+	group := BorrowerGroup{
+		ID:            id,
+		Members:       members,
+		ExposureLimit: exposureLimit,
+	}
+
+	return putBorrowerGroup(ctx, &group)
+}
+
+// ReadBorrowerGroup returns the borrower group with the given ID
+func (s *SmartContract) ReadBorrowerGroup(ctx contractapi.TransactionContextInterface, id string) (*BorrowerGroup, error) {
+	group, err := getBorrowerGroup(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if group == nil {
+		return nil, fmt.Errorf("the borrower group %s does not exist", id)
+	}
+
+	return group, nil
+}
+
+// GetGroupExposure returns the outstanding principal across all loans of the group's members
+func (s *SmartContract) GetGroupExposure(ctx contractapi.TransactionContextInterface, id string) (int, error) {
+	group, err := s.ReadBorrowerGroup(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+
+	return groupExposure(ctx, group)
+}
+
+// ClearGroupFlag lifts the joint-liability flag once the defaulted loan has been resolved.
+// Restricted to officers.
+func (s *SmartContract) ClearGroupFlag(ctx contractapi.TransactionContextInterface, id string) error {
+	err := requireRole(ctx, "officer")
+	if err != nil {
+		return err
+	}
+
+	group, err := s.ReadBorrowerGroup(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	group.Flagged = false
+	group.FlaggedReason = ""
+	group.FlaggedAt = ""
+	return putBorrowerGroup(ctx, group)
+}
+
+// checkGroupExposure rejects a new application when the applicant's group is flagged or the
+// requested amount would push the group over its exposure limit
+func checkGroupExposure(ctx contractapi.TransactionContextInterface, applicant string, amount int) error {
+	groupID, err := groupOfMember(ctx, applicant)
+	if err != nil || groupID == "" {
+		return err
+	}
+	group, err := getBorrowerGroup(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	if group.Flagged {
+		return fmt.Errorf("borrower group %s is flagged: %s", group.ID, group.FlaggedReason)
+	}
+
+	exposure, err := groupExposure(ctx, group)
+	if err != nil {
+		return err
+	}
+	if exposure+amount > group.ExposureLimit {
+		return fmt.Errorf("borrower group %s exposure of %d plus %d exceeds its limit of %d", group.ID, exposure, amount, group.ExposureLimit)
+	}
+
+	return nil
+}
+
+// flagGroupOf flags the borrower group of a defaulted loan's applicant, if any
+func flagGroupOf(ctx contractapi.TransactionContextInterface, loan *LoanApplication) error {
+	groupID, err := groupOfMember(ctx, loan.Applicant)
+	if err != nil || groupID == "" {
+		return err
+	}
+	group, err := getBorrowerGroup(ctx, groupID)
+	if err != nil {
+		return err
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	group.Flagged = true
+	group.FlaggedReason = fmt.Sprintf("member %s defaulted on loan %s", loan.Applicant, loan.ID)
+	group.FlaggedAt = now.Format(time.RFC3339)
+
+	return putBorrowerGroup(ctx, group)
+}
+
+// groupExposure sums the outstanding principal of every member's open loans, counting a loan
+// that members share as co-applicants once
+func groupExposure(ctx contractapi.TransactionContextInterface, group *BorrowerGroup) (int, error) {
+	exposure := 0
+	counted := make(map[string]bool)
+	for _, member := range group.Members {
+		loans, err := loansByApplicant(ctx, member)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, loan := range loans {
+			if counted[loan.ID] {
+				continue
+			}
+			counted[loan.ID] = true
+			outstanding, err := outstandingPrincipal(loan)
+			if err != nil {
+				return 0, err
+			}
+			exposure += outstanding
+		}
+	}
+
+	return exposure, nil
+}
+
+// outstandingPrincipal is the principal still owed on a loan; applications that are not yet
+// disbursed count at their full amount, closed and rejected ones count as zero
+func outstandingPrincipal(loan *LoanApplication) (int, error) {
+	switch loan.Status {
+	case "Closed", "Rejected":
+		return 0, nil
+	}
+	if loan.DisbursedAt == "" {
+		return loan.Amount, nil
+	}
+
+	schedule, err := repaymentSchedule(loan)
+	if err != nil {
+		return 0, err
+	}
+	outstanding := 0
+	for _, installment := range schedule[loan.PaidInstallments:] {
+		outstanding += installment.Principal
+	}
+
+	return outstanding, nil
+}
+
+func groupOfMember(ctx contractapi.TransactionContextInterface, member string) (string, error) {
+	memberKey, err := ctx.GetStub().CreateCompositeKey(groupMemberIndex, []string{member})
+	if err != nil {
+		return "", fmt.Errorf("failed to create composite key: %v", err)
+	}
+	groupID, err := ctx.GetStub().GetState(memberKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to read from world state: %v", err)
+	}
+
+	return string(groupID), nil
+}
+
+func getBorrowerGroup(ctx contractapi.TransactionContextInterface, id string) (*BorrowerGroup, error) {
+	return groupRepo.Get(ctx, id)
+}
+
+func putBorrowerGroup(ctx contractapi.TransactionContextInterface, group *BorrowerGroup) error {
+	return groupRepo.Put(ctx, group, group.ID)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBorrowerGroups(t *testing.T) {
+	s := new(SmartContract)
+	ledger := chaincodetest.NewLedger()
+
+	createGroup := func(id string, members []string, limit int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.CreateBorrowerGroup(ctx, id, members, limit)
+		}
+	}
+	exposure := func(id string, want int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			got, err := s.GetGroupExposure(ctx, id)
+			require.Equal(t, want, got)
+			return err
+		}
+	}
+	apply := func(id string, applicant string, amount int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.CreateLoanApplication(ctx, id, applicant, amount, 12, 5)
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "create group requires officer", Caller: customer, Run: createGroup("g1", []string{"Ann", "Ben"}, 3000), Err: "submitting client not authorized, requires role officer"},
+		{Name: "create group", Caller: officer, Run: createGroup("g1", []string{"Ann", "Ben"}, 3000)},
+		{Name: "create duplicate group", Caller: officer, Run: createGroup("g1", []string{"Cat", "Dan"}, 3000), Err: "the borrower group g1 already exists"},
+		{Name: "create group of one", Caller: officer, Run: createGroup("g2", []string{"Cat"}, 3000), Err: "at least two members"},
+		{Name: "create group without a limit", Caller: officer, Run: createGroup("g2", []string{"Cat", "Dan"}, 0), Err: "exposure limit must be positive"},
+		{Name: "create group with an empty member", Caller: officer, Run: createGroup("g2", []string{"Cat", ""}, 3000), Err: "member identity must not be empty"},
+		{Name: "create group with a member twice", Caller: officer, Run: createGroup("g2", []string{"Cat", "Cat"}, 3000), Err: "member Cat is listed twice"},
+		{Name: "create group with a member of another", Caller: officer, Run: createGroup("g2", []string{"Cat", "Ann"}, 3000), Err: "member Ann already belongs to borrower group g1"},
+		{
+			Name:   "read group",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				group, err := s.ReadBorrowerGroup(ctx, "g1")
+				if err == nil {
+					require.Equal(t, []string{"Ann", "Ben"}, group.Members)
+				}
+				return err
+			},
+		},
+		{
+			Name:   "read missing group",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.ReadBorrowerGroup(ctx, "g9")
+				return err
+			},
+			Err: "the borrower group g9 does not exist",
+		},
+		{Name: "apply as a member", Caller: bank, Run: apply("loan1", "Ann", 2000)},
+		{Name: "exposure counts pending loans", Caller: bank, Run: exposure("g1", 2000)},
+		{Name: "apply beyond the group limit", Caller: bank, Run: apply("loan2", "Ben", 1001), Err: "borrower group g1 exposure of 2000 plus 1001 exceeds its limit of 3000"},
+		{Name: "apply within the group limit", Caller: bank, Run: apply("loan2", "Ben", 1000)},
+		{Name: "exposure of missing group", Caller: bank, Run: exposure("g9", 0), Err: "does not exist"},
+		setStatus("loan2", "Rejected"),
+		{Name: "rejected loans do not count", Caller: bank, Run: exposure("g1", 2000)},
+		setStatus("loan1", "Defaulted"),
+		{Name: "apply while the group is flagged", Caller: bank, Run: apply("loan3", "Ben", 100), Err: "borrower group g1 is flagged: member Ann defaulted on loan loan1"},
+		{
+			Name:   "clear flag requires officer",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.ClearGroupFlag(ctx, "g1")
+			},
+			Err: "requires role officer",
+		},
+		{
+			Name:   "clear flag",
+			Caller: officer,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.ClearGroupFlag(ctx, "g1")
+			},
+		},
+		setStatus("loan1", "Closed"),
+		{Name: "apply once the flag is cleared", Caller: bank, Run: apply("loan3", "Ben", 3000)},
+	})
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"authz"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+const (
+	// guaranteeObjectType prefixes the composite keys of guarantees, keyed by loan and guarantor
+	guaranteeObjectType = "guarantee"
+
+	guaranteePending   = "Pending"
+	guaranteeConfirmed = "Confirmed"
+)
+
+var guaranteeRepo = repository.New[Guarantee](guaranteeObjectType)
+
+// Guarantee is a guarantor's undertaking to cover part of a loan. It only takes effect once the
+// guarantor has confirmed it from their own enrollment; the confirming certificate is recorded as
+// the guarantor's acknowledgment.
+type Guarantee struct {
+	LoanID         string `json:"loanId"`
+	Guarantor      string `json:"guarantor"` // enrollment ID of the guarantor
+	Amount         int    `json:"amount"`
+	Status         string `json:"status"`
+	AddedBy        string `json:"addedBy"`
+	AddedAt        string `json:"addedAt"`
+	ConfirmedBy    string `json:"confirmedBy"` // client identity of the confirming certificate
+	ConfirmedByMSP string `json:"confirmedByMSP"`
+	ConfirmedAt    string `json:"confirmedAt"`
+	ConfirmTxID    string `json:"confirmTxId"`
+}
+
+// AddGuarantor records a pending guarantee of amount by the guarantor, identified by their
+// enrollment ID, on a pending loan application. The loan cannot be approved until the guarantor
+// confirms with ConfirmGuarantee. Restricted to the officer role.
+func (s *SmartContract) AddGuarantor(ctx contractapi.TransactionContextInterface, loanID string, guarantor string, amount int) error {
+	err := requireRole(ctx, "officer")
+	if err != nil {
+		return err
+	}
+	if guarantor == "" {
+		return fmt.Errorf("guarantor must not be empty")
+	}
+	if amount <= 0 {
+		return fmt.Errorf("guaranteed amount must be positive")
+	}
+
+	loan, err := readLoan(ctx, loanID)
+	if err != nil {
+		return err
+	}
+	if loan.Status != "Pending" {
+		return fmt.Errorf("the loan application %s is %s, guarantors can only be added while Pending", loanID, loan.Status)
+	}
+	if guarantor == loan.Applicant {
+		return fmt.Errorf("the applicant cannot guarantee their own loan")
+	}
+	existing, err := getGuarantee(ctx, loanID, guarantor)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("%s already guarantees loan application %s", guarantor, loanID)
+	}
+
+	officer, _, err := operatorName(ctx)
+	if err != nil {
+		return err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	guarantee := Guarantee{
+		LoanID:    loanID,
+		Guarantor: guarantor,
+		Amount:    amount,
+		Status:    guaranteePending,
+		AddedBy:   officer,
+		AddedAt:   now.Format(time.RFC3339),
+	}
+
+	return putGuarantee(ctx, &guarantee)
+}
+
+// ConfirmGuarantee records the submitting client's acknowledgment of the guarantee they were added
+// to on a loan. The client's enrollment ID must be the guarantor's.
+func (s *SmartContract) ConfirmGuarantee(ctx contractapi.TransactionContextInterface, loanID string) (*Guarantee, error) {
+	guarantor, err := enrollmentID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	guarantee, err := getGuarantee(ctx, loanID, guarantor)
+	if err != nil {
+		return nil, err
+	}
+	if guarantee == nil {
+		return nil, fmt.Errorf("%s is not a guarantor of loan application %s", guarantor, loanID)
+	}
+	if guarantee.Status != guaranteePending {
+		return nil, fmt.Errorf("the guarantee of %s on loan application %s is already %s", guarantor, loanID, guarantee.Status)
+	}
+
+	client, err := authz.ClientOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	guarantee.Status = guaranteeConfirmed
+	guarantee.ConfirmedBy = client.ID
+	guarantee.ConfirmedByMSP = client.MSPID
+	guarantee.ConfirmedAt = now.Format(time.RFC3339)
+	guarantee.ConfirmTxID = ctx.GetStub().GetTxID()
+
+	err = putGuarantee(ctx, guarantee)
+	if err != nil {
+		return nil, err
+	}
+
+	return guarantee, nil
+}
+
+// GetGuarantees returns the guarantees recorded on a loan application
+func (s *SmartContract) GetGuarantees(ctx contractapi.TransactionContextInterface, loanID string) ([]*Guarantee, error) {
+	return guaranteesOf(ctx, loanID)
+}
+
+func guaranteesOf(ctx contractapi.TransactionContextInterface, loanID string) ([]*Guarantee, error) {
+	return guaranteeRepo.List(ctx, loanID)
+}
+
+// confirmedGuarantees rejects approval while a guarantee is still awaiting its guarantor's
+// confirmation and returns the total amount guaranteed
+func confirmedGuarantees(ctx contractapi.TransactionContextInterface, loan *LoanApplication) (int, error) {
+	guarantees, err := guaranteesOf(ctx, loan.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, guarantee := range guarantees {
+		if guarantee.Status != guaranteeConfirmed {
+			return 0, fmt.Errorf("the guarantee of %s on loan application %s has not been confirmed by the guarantor", guarantee.Guarantor, loan.ID)
+		}
+		total += guarantee.Amount
+	}
+
+	return total, nil
+}
+
+// enrollmentID returns the enrollment ID of the submitting client, falling back to its certificate
+// common name for certificates issued without the hf.EnrollmentID attribute
+func enrollmentID(ctx contractapi.TransactionContextInterface) (string, error) {
+	client, err := authz.ClientOf(ctx)
+	if err != nil {
+		return "", err
+	}
+	value, found := client.Attribute("hf.EnrollmentID")
+	if found && value != "" {
+		return value, nil
+	}
+
+	return client.CommonName, nil
+}
+
+func getGuarantee(ctx contractapi.TransactionContextInterface, loanID string, guarantor string) (*Guarantee, error) {
+	return guaranteeRepo.Get(ctx, loanID, guarantor)
+}
+
+func putGuarantee(ctx contractapi.TransactionContextInterface, guarantee *Guarantee) error {
+	return guaranteeRepo.Put(ctx, guarantee, guarantee.LoanID, guarantee.Guarantor)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGuarantees(t *testing.T) {
+	s := new(SmartContract)
+	carol := chaincodetest.Identity{MSPID: "Org2MSP", CommonName: "carol"}
+	ledger := newLedger(t)
+
+	addGuarantor := func(loanID string, guarantor string, amount int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.AddGuarantor(ctx, loanID, guarantor, amount)
+		}
+	}
+	confirm := func(loanID string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.ConfirmGuarantee(ctx, loanID)
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "add guarantor requires officer", Caller: bank, Run: addGuarantor("loan1", "carol", 5000), Err: "requires role officer"},
+		{Name: "add an empty guarantor", Caller: officer, Run: addGuarantor("loan1", "", 5000), Err: "guarantor must not be empty"},
+		{Name: "add a non-positive guarantee", Caller: officer, Run: addGuarantor("loan1", "carol", 0), Err: "guaranteed amount must be positive"},
+		{Name: "add guarantor to an approved loan", Caller: officer, Run: addGuarantor("loan2", "carol", 5000), Err: "guarantors can only be added while Pending"},
+		{Name: "add the applicant as guarantor", Caller: officer, Run: addGuarantor("loan1", "Afraz", 5000), Err: "the applicant cannot guarantee their own loan"},
+		{Name: "add guarantor", Caller: officer, Run: addGuarantor("loan1", "carol", 5000)},
+		{Name: "add guarantor twice", Caller: officer, Run: addGuarantor("loan1", "carol", 5000), Err: "carol already guarantees loan application loan1"},
+		{
+			Name:   "approve before the guarantor confirms",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.UpdateLoanStatus(ctx, "loan1", "Approved")
+			},
+			Err: "the guarantee of carol on loan application loan1 has not been confirmed by the guarantor",
+		},
+		{Name: "confirm as someone else", Caller: bank, Run: confirm("loan1"), Err: "bank1 is not a guarantor of loan application loan1"},
+		{
+			Name:   "confirm",
+			Caller: carol,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				guarantee, err := s.ConfirmGuarantee(ctx, "loan1")
+				if err == nil {
+					require.Equal(t, guaranteeConfirmed, guarantee.Status)
+					require.Equal(t, "Org2MSP", guarantee.ConfirmedByMSP)
+					require.Equal(t, ctx.GetStub().GetTxID(), guarantee.ConfirmTxID)
+				}
+				return err
+			},
+		},
+		{Name: "confirm twice", Caller: carol, Run: confirm("loan1"), Err: "is already Confirmed"},
+		{
+			Name:   "list guarantees",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				guarantees, err := s.GetGuarantees(ctx, "loan1")
+				if err == nil {
+					require.Len(t, guarantees, 1)
+					require.Equal(t, "officer1", guarantees[0].AddedBy)
+				}
+				return err
+			},
+		},
+		setStatus("loan1", "Approved"),
+	})
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"repository"
+)
+
+// historyAssetTypes maps the asset types GetFieldHistory accepts to the object type prefixing their
+// composite keys. Loan applications are stored under plain keys in loanNamespace.
+var historyAssetTypes = map[string]string{
+	"loan":                "",
+	"terms":               termsObjectType,
+	"job":                 jobObjectType,
+	"standinginstruction": standingInstructionObjectType,
+	"invoice":             invoiceObjectType,
+	"product":             productObjectType,
+}
+
+// FieldHistoryEntry is one recorded value of a single field
+type FieldHistoryEntry struct {
+	TxID      string `json:"txId"`
+	Timestamp string `json:"timestamp"`
+	IsDelete  bool   `json:"isDelete"`
+	Value     string `json:"value"`
+}
+
+// GetFieldHistory returns the timeline of one field of an asset, oldest first, listing only the
+// versions in which the field changed. assetType is loan, terms, job, standinginstruction, invoice
+// or product.
+// fieldPath names the field by its JSON or Go name, with dots separating nested fields, for
+// example InterestRate. String values are returned as is, other values JSON encoded, and an absent
+// field as an empty string.
+func (s *SmartContract) GetFieldHistory(ctx contractapi.TransactionContextInterface, assetType string, id string, fieldPath string) ([]*FieldHistoryEntry, error) {
+	objectType, ok := historyAssetTypes[assetType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported asset type %q", assetType)
+	}
+	if fieldPath == "" {
+		return nil, fmt.Errorf("field path must not be empty")
+	}
+
+	var modifications []*queryresult.KeyModification
+	var err error
+	if objectType == "" {
+		modifications, err = loanNamespace.History(ctx, id)
+	} else {
+		var key string
+		key, err = ctx.GetStub().CreateCompositeKey(objectType, []string{id})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create composite key: %v", err)
+		}
+		modifications, err = repository.KeyHistory(ctx, key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history for %s: %v", id, err)
+	}
+
+	var versions []*FieldHistoryEntry
+	for _, response := range modifications {
+
+		entry := &FieldHistoryEntry{
+			TxID:      response.TxId,
+			Timestamp: time.Unix(response.Timestamp.Seconds, int64(response.Timestamp.Nanos)).UTC().Format(time.RFC3339),
+			IsDelete:  response.IsDelete,
+		}
+		if !response.IsDelete && len(response.Value) > 0 {
+			entry.Value, err = fieldValue(response.Value, fieldPath)
+			if err != nil {
+				return nil, err
+			}
+		}
+		versions = append(versions, entry)
+	}
+
+	// the history comes most recent version first
+	var entries []*FieldHistoryEntry
+	for i := len(versions) - 1; i >= 0; i-- {
+		if len(entries) > 0 {
+			last := entries[len(entries)-1]
+			if !versions[i].IsDelete && !last.IsDelete && last.Value == versions[i].Value {
+				continue
+			}
+		}
+		entries = append(entries, versions[i])
+	}
+
+	return entries, nil
+}
+
+// fieldValue extracts the field at a dotted path from a JSON document, matching field names
+// case-insensitively
+func fieldValue(document []byte, fieldPath string) (string, error) {
+	var value interface{}
+	err := json.Unmarshal(document, &value)
+	if err != nil {
+		return "", err
+	}
+
+	for _, name := range strings.Split(fieldPath, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return "", nil
+		}
+		value = nil
+		for field, v := range object {
+			if strings.EqualFold(field, name) {
+				value = v
+				break
+			}
+		}
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	}
+	valueJSON, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	return string(valueJSON), nil
+}
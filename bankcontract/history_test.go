@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"chaincodetest"
+	"chaincodetest/chaincodefakes"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestGetFieldHistory(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	history := func(assetType string, id string, fieldPath string, want ...string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			entries, err := s.GetFieldHistory(ctx, assetType, id, fieldPath)
+			if err == nil {
+				var values []string
+				for _, entry := range entries {
+					if entry.IsDelete {
+						values = append(values, "<deleted>")
+						continue
+					}
+					values = append(values, entry.Value)
+				}
+				require.Equal(t, want, values)
+			}
+			return err
+		}
+	}
+	modification := func(txID string, value string) *queryresult.KeyModification {
+		return &queryresult.KeyModification{TxId: txID, Value: []byte(value), Timestamp: timestamppb.Now(), IsDelete: value == ""}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		setStatus("loan1", "Approved"),
+		setStatus("loan1", "Approved"),
+		setStatus("loan1", "Disbursed"),
+		{Name: "status history lists only changes", Caller: bank, Run: history("loan", "loan1", "status", "Pending", "Approved", "Disbursed")},
+		{Name: "fields match by Go name", Caller: bank, Run: history("loan", "loan1", "InterestRate", "5.5")},
+		{Name: "absent fields are empty", Caller: bank, Run: history("loan", "loan1", "terms.rate", "")},
+		{Name: "terms history", Caller: bank, Run: history("terms", "loan1", "loanId", "loan1")},
+		{
+			Name:   "deletions break runs of equal values",
+			Caller: bank,
+			Stub: func(stub *chaincodefakes.ChaincodeStub) {
+				stub.GetHistoryForKeyReturns(chaincodetest.HistoryIterator(
+					modification("tx3", `{"status":"Pending"}`),
+					modification("tx2", ""),
+					modification("tx1", `{"status":"Pending"}`),
+				), nil)
+			},
+			Run: history("loan", "loan9", "status", "Pending", "<deleted>", "Pending"),
+		},
+		{
+			Name:   "corrupt versions",
+			Caller: bank,
+			Stub: func(stub *chaincodefakes.ChaincodeStub) {
+				stub.GetHistoryForKeyReturns(chaincodetest.HistoryIterator(modification("tx1", "{")), nil)
+			},
+			Run: history("loan", "loan9", "status"),
+			Err: "unexpected end of JSON input",
+		},
+		{
+			Name:   "history unavailable",
+			Caller: bank,
+			Stub: func(stub *chaincodefakes.ChaincodeStub) {
+				stub.GetHistoryForKeyStub = nil
+				stub.GetHistoryForKeyReturns(nil, errors.New("history database disabled"))
+			},
+			Run: history("loan", "loan1", "status"),
+			Err: "failed to read history for loan1: history database disabled",
+		},
+		{Name: "unsupported asset type", Caller: bank, Run: history("pokemon", "loan1", "status"), Err: `unsupported asset type "pokemon"`},
+		{Name: "empty field path", Caller: bank, Run: history("loan", "loan1", ""), Err: "field path must not be empty"},
+	})
+}
@@ -0,0 +1,392 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+const (
+	// indexObjectType prefixes the composite keys of the loan index definitions
+	indexObjectType = "loanindex"
+
+	// indexBuilding indexes are maintained on every loan write while a backfill job fills them
+	indexBuilding = "Building"
+	indexActive   = "Active"
+	// indexRetiring indexes only have entries removed while a teardown job clears them
+	indexRetiring = "Retiring"
+	indexRetired  = "Retired"
+
+	indexBackfillJob = "index-backfill"
+	indexTeardownJob = "index-teardown"
+)
+
+var indexRepo = repository.New[LoanIndex](indexObjectType)
+
+// LoanIndex is a secondary index over loan applications. Each loan has one entry keyed by the
+// values of Fields followed by the loan ID, under the composite key object type Name.
+type LoanIndex struct {
+	Name         string   `json:"name"`
+	Fields       []string `json:"fields"`
+	Status       string   `json:"status"`
+	JobID        string   `json:"jobId"` // the backfill or teardown job of the current status
+	RegisteredBy string   `json:"registeredBy"`
+	RegisteredAt string   `json:"registeredAt"`
+	RetiredAt    string   `json:"retiredAt"`
+}
+
+// IndexJobResult reports one page of a backfill or teardown job
+type IndexJobResult struct {
+	Job     *Job `json:"job"`
+	Scanned int  `json:"scanned"`
+	Written int  `json:"written"`
+	Deleted int  `json:"deleted"`
+}
+
+// RegisterIndex defines a composite key index over loan applications and starts the job that
+// backfills it from the existing loans. name must end in ~loan, for example currency~loan, and
+// fields are the JSON paths of the indexed loan fields. From registration on, every loan write
+// keeps the index up to date; it can be queried once RunIndexJob has completed the backfill.
+// Restricted to the ops role.
+func (s *SmartContract) RegisterIndex(ctx contractapi.TransactionContextInterface, name string, fields []string, jobID string) (*LoanIndex, error) {
+	err := requireRole(ctx, "ops")
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(name, "~loan") || name == applicantIndex {
+		return nil, fmt.Errorf("index name %q must end in ~loan and not be a built-in index", name)
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("an index must have at least one field")
+	}
+	for _, field := range fields {
+		if field == "" {
+			return nil, fmt.Errorf("index fields must not be empty")
+		}
+	}
+
+	index, err := getLoanIndex(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if index != nil && index.Status != indexRetired {
+		return nil, fmt.Errorf("the index %s is %s", name, index.Status)
+	}
+
+	err = startJob(ctx, jobID, indexBackfillJob+":"+name)
+	if err != nil {
+		return nil, err
+	}
+	operator, _, err := operatorName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	index = &LoanIndex{
+		Name:         name,
+		Fields:       fields,
+		Status:       indexBuilding,
+		JobID:        jobID,
+		RegisteredBy: operator,
+		RegisteredAt: now.Format(time.RFC3339),
+	}
+	err = putLoanIndex(ctx, index)
+	if err != nil {
+		return nil, err
+	}
+
+	return index, nil
+}
+
+// RetireIndex stops maintaining an index and starts the job that deletes its entries. A backfill
+// still running is aborted. Restricted to the ops role.
+func (s *SmartContract) RetireIndex(ctx contractapi.TransactionContextInterface, name string, jobID string) (*LoanIndex, error) {
+	err := requireRole(ctx, "ops")
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := s.ReadIndex(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if index.Status != indexBuilding && index.Status != indexActive {
+		return nil, fmt.Errorf("the index %s is %s", name, index.Status)
+	}
+	if index.Status == indexBuilding {
+		backfill, err := s.ReadJob(ctx, index.JobID)
+		if err != nil {
+			return nil, err
+		}
+		if backfill.Status == jobRunning {
+			backfill.Status = jobAborted
+			backfill.Reason = "index retired"
+			err = finishJobStep(ctx, backfill)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	err = startJob(ctx, jobID, indexTeardownJob+":"+name)
+	if err != nil {
+		return nil, err
+	}
+	index.Status = indexRetiring
+	index.JobID = jobID
+	err = putLoanIndex(ctx, index)
+	if err != nil {
+		return nil, err
+	}
+
+	return index, nil
+}
+
+// RunIndexJob processes the next page of up to pageSize loans of an index backfill or teardown
+// job, writing or deleting their index entries, and advances the job. Once the last page is
+// processed the job completes and the index becomes Active or Retired. Restricted to the ops role.
+func (s *SmartContract) RunIndexJob(ctx contractapi.TransactionContextInterface, jobID string, pageSize int) (*IndexJobResult, error) {
+	err := requireRole(ctx, "ops")
+	if err != nil {
+		return nil, err
+	}
+	if pageSize <= 0 {
+		return nil, fmt.Errorf("pageSize must be positive")
+	}
+
+	job, err := s.ReadJob(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != jobRunning {
+		return nil, fmt.Errorf("the job %s is %s", jobID, job.Status)
+	}
+	action, name, found := strings.Cut(job.Kind, ":")
+	if !found || (action != indexBackfillJob && action != indexTeardownJob) {
+		return nil, fmt.Errorf("the job %s is a %s job, not an index job", jobID, job.Kind)
+	}
+	index, err := s.ReadIndex(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if index.JobID != jobID {
+		return nil, fmt.Errorf("the job %s is not the current job of index %s", jobID, name)
+	}
+
+	// paginated queries are not allowed in update transactions, so the page is bounded by hand
+	resultsIterator, err := ctx.GetStub().GetStateByRange(loanNamespace.Range(job.Cursor))
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	result := &IndexJobResult{Job: job}
+	cursor := ""
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		if result.Scanned == pageSize {
+			cursor, _ = loanNamespace.ID(queryResponse.Key)
+			break
+		}
+		result.Scanned++
+
+		loanID, _ := loanNamespace.ID(queryResponse.Key)
+		indexKey, err := loanIndexKey(ctx, index, loanID, queryResponse.Value)
+		if err != nil {
+			return nil, err
+		}
+		if action == indexBackfillJob {
+			err = ctx.GetStub().PutState(indexKey, []byte{0x00})
+			result.Written++
+		} else {
+			err = ctx.GetStub().DelState(indexKey)
+			result.Deleted++
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	job.Cursor = cursor
+	job.Pages++
+	job.Processed += result.Scanned
+	if cursor == "" {
+		job.Status = jobCompleted
+		now, err := txTime(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if action == indexBackfillJob {
+			index.Status = indexActive
+		} else {
+			index.Status = indexRetired
+			index.RetiredAt = now.Format(time.RFC3339)
+		}
+		err = putLoanIndex(ctx, index)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = finishJobStep(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// ReadIndex returns the definition of a loan index
+func (s *SmartContract) ReadIndex(ctx contractapi.TransactionContextInterface, name string) (*LoanIndex, error) {
+	index, err := getLoanIndex(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if index == nil {
+		return nil, fmt.Errorf("the index %s does not exist", name)
+	}
+
+	return index, nil
+}
+
+// GetIndexes returns the definitions of every registered loan index
+func (s *SmartContract) GetIndexes(ctx contractapi.TransactionContextInterface) ([]*LoanIndex, error) {
+	return loanIndexes(ctx)
+}
+
+// GetLoansByIndex returns the loans whose leading indexed fields equal values, using an active
+// index. String fields match as stored; other fields match their JSON encoding.
+func (s *SmartContract) GetLoansByIndex(ctx contractapi.TransactionContextInterface, name string, values []string) ([]*LoanApplication, error) {
+	index, err := s.ReadIndex(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if index.Status != indexActive {
+		return nil, fmt.Errorf("the index %s is %s, not Active", name, index.Status)
+	}
+	if len(values) > len(index.Fields) {
+		return nil, fmt.Errorf("the index %s has only %d fields", name, len(index.Fields))
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(name, values)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var loans []*LoanApplication
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, err
+		}
+		loanJSON, err := ctx.GetStub().GetState(loanNamespace.Key(keyParts[len(keyParts)-1]))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read from world state: %v", err)
+		}
+		if loanJSON == nil {
+			continue
+		}
+
+		var loan LoanApplication
+		err = json.Unmarshal(loanJSON, &loan)
+		if err != nil {
+			return nil, err
+		}
+		loans = append(loans, &loan)
+	}
+
+	return loans, redactLoans(ctx, loans)
+}
+
+// updateLoanIndexes moves the entries of a loan in every maintained index from its previous
+// version to its new one. Either version is nil when the loan is created or deleted. Retiring
+// indexes only lose entries.
+func updateLoanIndexes(ctx contractapi.TransactionContextInterface, loanID string, previous []byte, current []byte) error {
+	indexes, err := loanIndexes(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, index := range indexes {
+		if index.Status == indexRetired {
+			continue
+		}
+		var previousKey, currentKey string
+		if previous != nil {
+			previousKey, err = loanIndexKey(ctx, index, loanID, previous)
+			if err != nil {
+				return err
+			}
+		}
+		if current != nil && index.Status != indexRetiring {
+			currentKey, err = loanIndexKey(ctx, index, loanID, current)
+			if err != nil {
+				return err
+			}
+		}
+		if previousKey == currentKey {
+			continue
+		}
+		if previousKey != "" {
+			err = ctx.GetStub().DelState(previousKey)
+			if err != nil {
+				return err
+			}
+		}
+		if currentKey != "" {
+			err = ctx.GetStub().PutState(currentKey, []byte{0x00})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// loanIndexKey returns the index entry key of a stored loan
+func loanIndexKey(ctx contractapi.TransactionContextInterface, index *LoanIndex, loanID string, loanJSON []byte) (string, error) {
+	attributes := make([]string, 0, len(index.Fields)+1)
+	for _, field := range index.Fields {
+		value, err := fieldValue(loanJSON, field)
+		if err != nil {
+			return "", err
+		}
+		attributes = append(attributes, value)
+	}
+	attributes = append(attributes, loanID)
+
+	indexKey, err := ctx.GetStub().CreateCompositeKey(index.Name, attributes)
+	if err != nil {
+		return "", fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	return indexKey, nil
+}
+
+func loanIndexes(ctx contractapi.TransactionContextInterface) ([]*LoanIndex, error) {
+	return indexRepo.List(ctx)
+}
+
+func getLoanIndex(ctx contractapi.TransactionContextInterface, name string) (*LoanIndex, error) {
+	return indexRepo.Get(ctx, name)
+}
+
+func putLoanIndex(ctx contractapi.TransactionContextInterface, index *LoanIndex) error {
+	return indexRepo.Put(ctx, index, index.Name)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoanIndexes(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	register := func(name string, fields []string, jobID string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.RegisterIndex(ctx, name, fields, jobID)
+			return err
+		}
+	}
+	retire := func(name string, jobID string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.RetireIndex(ctx, name, jobID)
+			return err
+		}
+	}
+	runJob := func(jobID string, pageSize int, status string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			result, err := s.RunIndexJob(ctx, jobID, pageSize)
+			if err == nil {
+				require.Equal(t, status, result.Job.Status)
+			}
+			return err
+		}
+	}
+	byIndex := func(name string, values []string, ids ...string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			loans, err := s.GetLoansByIndex(ctx, name, values)
+			if err == nil {
+				var got []string
+				for _, loan := range loans {
+					got = append(got, loan.ID)
+				}
+				require.Equal(t, ids, got)
+			}
+			return err
+		}
+	}
+	indexStatus := func(name string, status string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			index, err := s.ReadIndex(ctx, name)
+			if err == nil {
+				require.Equal(t, status, index.Status)
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "register requires ops", Caller: bank, Run: register("status~loan", []string{"status"}, "j1"), Err: "requires role ops"},
+		{Name: "register without the loan suffix", Caller: ops, Run: register("status", []string{"status"}, "j1"), Err: `index name "status" must end in ~loan`},
+		{Name: "register a built-in index", Caller: ops, Run: register(applicantIndex, []string{"applicant"}, "j1"), Err: "not be a built-in index"},
+		{Name: "register without fields", Caller: ops, Run: register("status~loan", nil, "j1"), Err: "at least one field"},
+		{Name: "register an empty field", Caller: ops, Run: register("status~loan", []string{""}, "j1"), Err: "index fields must not be empty"},
+		{Name: "register", Caller: ops, Run: register("status~loan", []string{"status", "currency"}, "j1")},
+		{Name: "register twice", Caller: ops, Run: register("status~loan", []string{"status"}, "j2"), Err: "the index status~loan is Building"},
+		{Name: "query a building index", Caller: bank, Run: byIndex("status~loan", nil), Err: "the index status~loan is Building, not Active"},
+		createLoan("loan3", "Bob", 1000),
+		{Name: "run requires ops", Caller: bank, Run: runJob("j1", 1, ""), Err: "requires role ops"},
+		{Name: "run with a non-positive page size", Caller: ops, Run: runJob("j1", 0, ""), Err: "pageSize must be positive"},
+		{Name: "run a backfill page", Caller: ops, Run: runJob("j1", 2, jobRunning)},
+		{Name: "run the last backfill page", Caller: ops, Run: runJob("j1", 2, jobCompleted)},
+		{Name: "run a completed job", Caller: ops, Run: runJob("j1", 2, ""), Err: "the job j1 is Completed"},
+		{Name: "backfilled index is active", Caller: bank, Run: indexStatus("status~loan", indexActive)},
+		{Name: "query by leading field", Caller: bank, Run: byIndex("status~loan", []string{"Pending"}, "loan1", "loan3")},
+		{Name: "query by every field", Caller: bank, Run: byIndex("status~loan", []string{"Approved", "PKR"}, "loan2")},
+		{Name: "query by too many fields", Caller: bank, Run: byIndex("status~loan", []string{"Approved", "PKR", "x"}), Err: "has only 2 fields"},
+		setStatus("loan3", "Approved"),
+		{Name: "writes maintain the index", Caller: bank, Run: byIndex("status~loan", []string{"Approved"}, "loan2", "loan3")},
+		{
+			Name:   "list indexes",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				indexes, err := s.GetIndexes(ctx)
+				if err == nil {
+					require.Len(t, indexes, 1)
+					require.Equal(t, "ops1", indexes[0].RegisteredBy)
+				}
+				return err
+			},
+		},
+		{Name: "retire requires ops", Caller: bank, Run: retire("status~loan", "j2"), Err: "requires role ops"},
+		{Name: "retire a missing index", Caller: ops, Run: retire("term~loan", "j2"), Err: "the index term~loan does not exist"},
+		{Name: "retire", Caller: ops, Run: retire("status~loan", "j2")},
+		{Name: "retire twice", Caller: ops, Run: retire("status~loan", "j3"), Err: "the index status~loan is Retiring"},
+		{Name: "run the superseded job", Caller: ops, Run: runJob("j1", 2, ""), Err: "the job j1 is Completed"},
+		{Name: "run the teardown", Caller: ops, Run: runJob("j2", 10, jobCompleted)},
+		{Name: "torn down index is retired", Caller: bank, Run: indexStatus("status~loan", indexRetired)},
+		{Name: "query a retired index", Caller: bank, Run: byIndex("status~loan", nil), Err: "is Retired, not Active"},
+		{Name: "register a retired index again", Caller: ops, Run: register("status~loan", []string{"status"}, "j3")},
+		{Name: "retire while the backfill runs", Caller: ops, Run: retire("status~loan", "j4")},
+		{
+			Name:   "retiring aborts the backfill",
+			Caller: ops,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				job, err := s.ReadJob(ctx, "j3")
+				if err == nil {
+					require.Equal(t, jobAborted, job.Status)
+					require.Equal(t, "index retired", job.Reason)
+				}
+				return err
+			},
+		},
+		{Name: "run an aborted backfill", Caller: ops, Run: runJob("j3", 10, ""), Err: "the job j3 is Aborted"},
+	})
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"authz"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+const (
+	// invoiceObjectType prefixes the composite keys of invoices
+	invoiceObjectType = "invoice"
+
+	// obligorAttribute is the client attribute naming the obligor a client confirms invoices for
+	obligorAttribute = "obligor"
+
+	// maxAdvanceRate is the largest share of an invoice, in percent, that may be advanced
+	maxAdvanceRate = 90
+
+	// payerObligor marks repayment legs paid by an invoice's obligor
+	payerObligor = "obligor"
+
+	invoiceRegistered = "Registered"
+	invoiceConfirmed  = "Confirmed"
+	invoiceDisputed   = "Disputed"
+	invoiceDiscounted = "Discounted"
+	invoiceSettled    = "Settled"
+)
+
+var invoiceRepo = repository.New[Invoice](invoiceObjectType)
+
+// Invoice is a receivable owed by an obligor to a business, which the business can discount
+// against a short-term loan once the obligor has confirmed it
+type Invoice struct {
+	ID           string `json:"id"`
+	Issuer       string `json:"issuer"` // identity ID of the issuing business
+	Obligor      string `json:"obligor"`
+	Amount       int    `json:"amount"`
+	Currency     string `json:"currency"`
+	DueDate      string `json:"dueDate"`
+	DocumentHash string `json:"documentHash"` // SHA-256 of the invoice document
+	Status       string `json:"status"`
+	RegisteredAt string `json:"registeredAt"`
+	ConfirmedAt  string `json:"confirmedAt"`
+	LoanID       string `json:"loanId"`
+	SettledAt    string `json:"settledAt"`
+	Rebate       int    `json:"rebate"` // paid on to the issuer after the loan was repaid
+}
+
+// RegisterInvoice records an invoice issued by a business registered in the identity chaincode.
+// The invoice can be discounted once its obligor confirms it. Restricted to the officer role.
+func (s *SmartContract) RegisterInvoice(ctx contractapi.TransactionContextInterface, id string, issuer string, obligor string, amount int, dueDate string, documentHash string) error {
+	err := requireRole(ctx, "officer")
+	if err != nil {
+		return err
+	}
+	if id == "" || issuer == "" || obligor == "" {
+		return fmt.Errorf("invoice ID, issuer and obligor must not be empty")
+	}
+	if issuer == obligor {
+		return fmt.Errorf("the issuer of an invoice cannot be its obligor")
+	}
+	if amount <= 0 {
+		return fmt.Errorf("invoice amount must be positive")
+	}
+	due, err := time.Parse("2006-01-02", dueDate)
+	if err != nil {
+		return fmt.Errorf("invalid due date %q, expected YYYY-MM-DD", dueDate)
+	}
+	documentHash = strings.ToLower(documentHash)
+	decoded, err := hex.DecodeString(documentHash)
+	if err != nil || len(decoded) != 32 {
+		return fmt.Errorf("document hash must be a hex encoded SHA-256 digest")
+	}
+
+	existing, err := getInvoice(ctx, id)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("the invoice %s already exists", id)
+	}
+
+	response := ctx.GetStub().InvokeChaincode(identityChaincode, [][]byte{[]byte("IdentityExists"), []byte(issuer)}, "")
+	if response.Status != shim.OK {
+		return fmt.Errorf("failed to look up issuer %s in %s: %s", issuer, identityChaincode, response.Message)
+	}
+	if string(response.Payload) != "true" {
+		return fmt.Errorf("the issuer %s is not registered in %s", issuer, identityChaincode)
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	invoice := Invoice{
+		ID:           id,
+		Issuer:       issuer,
+		Obligor:      obligor,
+		Amount:       amount,
+		Currency:     defaultCurrency,
+		DueDate:      due.Format("2006-01-02"),
+		DocumentHash: documentHash,
+		Status:       invoiceRegistered,
+		RegisteredAt: now.Format(time.RFC3339),
+	}
+
+	return putInvoice(ctx, &invoice)
+}
+
+// ConfirmInvoice records the obligor's acknowledgement that it owes the invoice. The submitting
+// client must carry an obligor attribute naming the invoice's obligor.
+func (s *SmartContract) ConfirmInvoice(ctx contractapi.TransactionContextInterface, id string) error {
+	invoice, err := s.ReadInvoice(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = requireObligor(ctx, invoice)
+	if err != nil {
+		return err
+	}
+	if invoice.Status != invoiceRegistered && invoice.Status != invoiceDisputed {
+		return fmt.Errorf("the invoice %s is %s", id, invoice.Status)
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	invoice.Status = invoiceConfirmed
+	invoice.ConfirmedAt = now.Format(time.RFC3339)
+
+	return putInvoice(ctx, invoice)
+}
+
+// DisputeInvoice records that the obligor does not acknowledge the invoice, which then cannot be
+// discounted unless it is confirmed later. The submitting client must carry an obligor attribute
+// naming the invoice's obligor.
+func (s *SmartContract) DisputeInvoice(ctx contractapi.TransactionContextInterface, id string) error {
+	invoice, err := s.ReadInvoice(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = requireObligor(ctx, invoice)
+	if err != nil {
+		return err
+	}
+	if invoice.Status != invoiceRegistered && invoice.Status != invoiceConfirmed {
+		return fmt.Errorf("the invoice %s is %s", id, invoice.Status)
+	}
+
+	invoice.Status = invoiceDisputed
+	invoice.ConfirmedAt = ""
+
+	return putInvoice(ctx, invoice)
+}
+
+// DiscountInvoice opens a pending loan application for the issuer of a confirmed invoice, secured
+// by the invoice. The loan advances advanceRate percent of the invoice amount, at most
+// maxAdvanceRate, and runs in monthly installments until the invoice falls due. It is approved
+// and disbursed like any other application. An invoice whose discounting loan was rejected can be
+// discounted again. Restricted to the officer role.
+func (s *SmartContract) DiscountInvoice(ctx contractapi.TransactionContextInterface, invoiceID string, loanID string, advanceRate float64, interestRate float64) (*LoanApplication, error) {
+	err := requireRole(ctx, "officer")
+	if err != nil {
+		return nil, err
+	}
+	if advanceRate <= 0 || advanceRate > maxAdvanceRate {
+		return nil, fmt.Errorf("advance rate must be above 0 and at most %d percent", maxAdvanceRate)
+	}
+	if interestRate < 0 {
+		return nil, fmt.Errorf("interest rate must not be negative")
+	}
+
+	invoice, err := s.ReadInvoice(ctx, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	if invoice.Status == invoiceDiscounted {
+		previous, err := readLoan(ctx, invoice.LoanID)
+		if err != nil {
+			return nil, err
+		}
+		if previous.Status != "Rejected" {
+			return nil, fmt.Errorf("the invoice %s is already discounted by loan %s", invoiceID, invoice.LoanID)
+		}
+	} else if invoice.Status != invoiceConfirmed {
+		return nil, fmt.Errorf("the invoice %s is %s, only confirmed invoices can be discounted", invoiceID, invoice.Status)
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	due, err := time.Parse("2006-01-02", invoice.DueDate)
+	if err != nil {
+		return nil, fmt.Errorf("the invoice %s has an invalid due date: %v", invoiceID, err)
+	}
+	if !due.After(now) {
+		return nil, fmt.Errorf("the invoice %s fell due on %s", invoiceID, invoice.DueDate)
+	}
+	term := 1
+	for now.AddDate(0, term, 0).Before(due) {
+		term++
+	}
+
+	loan := LoanApplication{
+		ID:           loanID,
+		Applicant:    invoice.Issuer,
+		Amount:       int(float64(invoice.Amount) * advanceRate / 100),
+		Currency:     invoice.Currency,
+		Term:         term,
+		InterestRate: interestRate,
+		Status:       "Pending",
+		InvoiceID:    invoice.ID,
+	}
+	err = s.createLoan(ctx, &loan)
+	if err != nil {
+		return nil, err
+	}
+
+	invoice.Status = invoiceDiscounted
+	invoice.LoanID = loan.ID
+	err = putInvoice(ctx, invoice)
+	if err != nil {
+		return nil, err
+	}
+
+	return &loan, nil
+}
+
+// SettleInvoice records the obligor paying an invoice in full. For a discounted invoice the
+// payment first repays every outstanding installment of the discounting loan, closing it, and the
+// rest is passed on to the issuer as a rebate. The loan must be disbursed and the invoice amount
+// must cover its payoff. Restricted to the ops role.
+func (s *SmartContract) SettleInvoice(ctx contractapi.TransactionContextInterface, id string) (*Invoice, error) {
+	err := requireRole(ctx, "ops")
+	if err != nil {
+		return nil, err
+	}
+
+	invoice, err := s.ReadInvoice(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	switch invoice.Status {
+	case invoiceConfirmed:
+		invoice.Rebate = invoice.Amount
+	case invoiceDiscounted:
+		loan, err := readLoan(ctx, invoice.LoanID)
+		if err != nil {
+			return nil, err
+		}
+		if loan.Status != "Disbursed" {
+			return nil, fmt.Errorf("the discounting loan %s is %s, not Disbursed", loan.ID, loan.Status)
+		}
+		schedule, err := repaymentSchedule(loan)
+		if err != nil {
+			return nil, err
+		}
+		remaining := schedule[loan.PaidInstallments:]
+		payoff := 0
+		for _, installment := range remaining {
+			payoff += installment.Amount
+		}
+		if payoff > invoice.Amount {
+			return nil, fmt.Errorf("the invoice amount %d does not cover the payoff of %d on loan %s", invoice.Amount, payoff, loan.ID)
+		}
+
+		for _, installment := range remaining {
+			err = putRepayment(ctx, installment, payerObligor, installment.Amount)
+			if err != nil {
+				return nil, err
+			}
+		}
+		loan.PaidInstallments = loan.Term
+		loan.Status = "Closed"
+		err = putLoan(ctx, loan)
+		if err != nil {
+			return nil, err
+		}
+		invoice.Rebate = invoice.Amount - payoff
+	default:
+		return nil, fmt.Errorf("the invoice %s is %s", id, invoice.Status)
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	invoice.Status = invoiceSettled
+	invoice.SettledAt = now.Format(time.RFC3339)
+	err = putInvoice(ctx, invoice)
+	if err != nil {
+		return nil, err
+	}
+
+	return invoice, nil
+}
+
+// ReadInvoice returns the invoice with the given ID
+func (s *SmartContract) ReadInvoice(ctx contractapi.TransactionContextInterface, id string) (*Invoice, error) {
+	invoice, err := getInvoice(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if invoice == nil {
+		return nil, fmt.Errorf("the invoice %s does not exist", id)
+	}
+
+	return invoice, nil
+}
+
+// requireObligor returns an error unless the submitting client's obligor attribute names the
+// invoice's obligor
+func requireObligor(ctx contractapi.TransactionContextInterface, invoice *Invoice) error {
+	client, err := authz.ClientOf(ctx)
+	if err != nil {
+		return err
+	}
+	if !client.HasAttribute(obligorAttribute, invoice.Obligor) {
+		return fmt.Errorf("submitting client not authorized, requires %s=%s", obligorAttribute, invoice.Obligor)
+	}
+
+	return nil
+}
+
+func getInvoice(ctx contractapi.TransactionContextInterface, id string) (*Invoice, error) {
+	return invoiceRepo.Get(ctx, id)
+}
+
+func putInvoice(ctx contractapi.TransactionContextInterface, invoice *Invoice) error {
+	return invoiceRepo.Put(ctx, invoice, invoice.ID)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvoiceDiscounting(t *testing.T) {
+	s := new(SmartContract)
+	buyer := chaincodetest.Identity{MSPID: "Org2MSP", CommonName: "buyer1", Attributes: map[string]string{obligorAttribute: "Buyer"}}
+	ledger := chaincodetest.NewLedger()
+	ledger.Install(identityChaincode, identityChaincodeOf(map[string]string{"Acme": "Full"}))
+	documentHash := strings.Repeat("ab", 32)
+
+	register := func(id string, issuer string, amount int, dueDate string, documentHash string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.RegisterInvoice(ctx, id, issuer, "Buyer", amount, dueDate, documentHash)
+		}
+	}
+	confirm := func(id string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.ConfirmInvoice(ctx, id)
+		}
+	}
+	dispute := func(id string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.DisputeInvoice(ctx, id)
+		}
+	}
+	discount := func(invoiceID string, loanID string, advanceRate float64) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.DiscountInvoice(ctx, invoiceID, loanID, advanceRate, 12)
+			return err
+		}
+	}
+	settle := func(id string, rebate int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			invoice, err := s.SettleInvoice(ctx, id)
+			if err == nil {
+				require.Equal(t, invoiceSettled, invoice.Status)
+				require.Equal(t, rebate, invoice.Rebate)
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "register requires officer", Caller: bank, Run: register("inv1", "Acme", 100000, "2024-04-15", documentHash), Err: "requires role officer"},
+		{Name: "register without an issuer", Caller: officer, Run: register("inv1", "", 100000, "2024-04-15", documentHash), Err: "must not be empty"},
+		{Name: "register to the issuer itself", Caller: officer, Run: register("inv1", "Buyer", 100000, "2024-04-15", documentHash), Err: "cannot be its obligor"},
+		{Name: "register a non-positive amount", Caller: officer, Run: register("inv1", "Acme", 0, "2024-04-15", documentHash), Err: "invoice amount must be positive"},
+		{Name: "register an invalid due date", Caller: officer, Run: register("inv1", "Acme", 100000, "15/04/2024", documentHash), Err: `invalid due date "15/04/2024"`},
+		{Name: "register an invalid document hash", Caller: officer, Run: register("inv1", "Acme", 100000, "2024-04-15", "abcd"), Err: "document hash must be a hex encoded SHA-256 digest"},
+		{Name: "register for an unknown issuer", Caller: officer, Run: register("inv1", "Zed", 100000, "2024-04-15", documentHash), Err: "the issuer Zed is not registered in identitycontract"},
+		{Name: "register", Caller: officer, Run: register("inv1", "Acme", 100000, "2024-04-15", strings.ToUpper(documentHash))},
+		{Name: "register twice", Caller: officer, Run: register("inv1", "Acme", 100000, "2024-04-15", documentHash), Err: "the invoice inv1 already exists"},
+		{Name: "register another", Caller: officer, Run: register("inv2", "Acme", 5000, "2024-01-01", documentHash)},
+		{Name: "discount before confirmation", Caller: officer, Run: discount("inv1", "loan1", 80), Err: "only confirmed invoices can be discounted"},
+		{Name: "confirm as someone else", Caller: officer, Run: confirm("inv1"), Err: "requires obligor=Buyer"},
+		{Name: "dispute", Caller: buyer, Run: dispute("inv1")},
+		{Name: "dispute twice", Caller: buyer, Run: dispute("inv1"), Err: "the invoice inv1 is Disputed"},
+		{Name: "confirm a disputed invoice", Caller: buyer, Run: confirm("inv1")},
+		{Name: "confirm twice", Caller: buyer, Run: confirm("inv1"), Err: "the invoice inv1 is Confirmed"},
+		{Name: "confirm missing invoice", Caller: buyer, Run: confirm("inv9"), Err: "the invoice inv9 does not exist"},
+		{Name: "confirm an overdue invoice", Caller: buyer, Run: confirm("inv2")},
+		{Name: "discount requires officer", Caller: bank, Run: discount("inv1", "loan1", 80), Err: "requires role officer"},
+		{Name: "discount beyond the advance limit", Caller: officer, Run: discount("inv1", "loan1", 95), Err: "advance rate must be above 0 and at most 90 percent"},
+		{Name: "discount an overdue invoice", Caller: officer, Run: discount("inv2", "loan2", 80), Err: "the invoice inv2 fell due on 2024-01-01"},
+		{
+			Name:   "discount",
+			Caller: officer,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				loan, err := s.DiscountInvoice(ctx, "inv1", "loan1", 80, 12)
+				if err == nil {
+					require.Equal(t, "Acme", loan.Applicant)
+					require.Equal(t, 80000, loan.Amount)
+					require.Equal(t, 4, loan.Term)
+					require.Equal(t, "inv1", loan.InvoiceID)
+				}
+				return err
+			},
+		},
+		{Name: "discount twice", Caller: officer, Run: discount("inv1", "loan3", 80), Err: "the invoice inv1 is already discounted by loan loan1"},
+		{Name: "dispute a discounted invoice", Caller: buyer, Run: dispute("inv1"), Err: "the invoice inv1 is Discounted"},
+		{Name: "settle requires ops", Caller: officer, Run: settle("inv1", 0), Err: "requires role ops"},
+		{Name: "settle before disbursement", Caller: ops, Run: settle("inv1", 0), Err: "the discounting loan loan1 is Pending, not Disbursed"},
+		setStatus("loan1", "Approved"),
+		setStatus("loan1", "Disbursed"),
+		{
+			Name:   "settle repays the discounting loan",
+			Caller: ops,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				invoice, err := s.SettleInvoice(ctx, "inv1")
+				if err != nil {
+					return err
+				}
+				loan, err := s.ReadLoanApplication(ctx, "loan1")
+				require.NoError(t, err)
+				require.Equal(t, "Closed", loan.Status)
+				require.Greater(t, invoice.Rebate, 0)
+				require.Less(t, invoice.Rebate, 20000)
+				return nil
+			},
+		},
+		{Name: "settle twice", Caller: ops, Run: settle("inv1", 0), Err: "the invoice inv1 is Settled"},
+		{Name: "settle an undiscounted invoice", Caller: ops, Run: settle("inv2", 5000)},
+		{
+			Name:   "read invoice",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				invoice, err := s.ReadInvoice(ctx, "inv1")
+				if err == nil {
+					require.Equal(t, documentHash, invoice.DocumentHash)
+					require.Equal(t, "loan1", invoice.LoanID)
+				}
+				return err
+			},
+		},
+	})
+}
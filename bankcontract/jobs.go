@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"authz"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+const (
+	jobObjectType       = "job"
+	activeJobObjectType = "activejob"
+
+	jobRunning   = "Running"
+	jobCompleted = "Completed"
+	jobAborted   = "Aborted"
+)
+
+var jobRepo = repository.New[Job](jobObjectType)
+
+// Job coordinates a multi-page export or migration driven by operators. The cursor is the
+// bookmark of the next page to process, so an interrupted job can be resumed by any operator
+// from exactly where the last recorded page ended.
+type Job struct {
+	ID           string `json:"id"`
+	Kind         string `json:"kind"`
+	Status       string `json:"status"`
+	Cursor       string `json:"cursor"`
+	Pages        int    `json:"pages"`
+	Processed    int    `json:"processed"`
+	StartedBy    string `json:"startedBy"`
+	StartedByMSP string `json:"startedByMSP"`
+	StartedAt    string `json:"startedAt"`
+	LastOperator string `json:"lastOperator"`
+	UpdatedAt    string `json:"updatedAt"`
+	Reason       string `json:"reason"`
+}
+
+// StartJob records a new job of the given kind, for example "loan-export". Only one job of a kind
+// may run at a time. Restricted to officer and ops roles.
+func (s *SmartContract) StartJob(ctx contractapi.TransactionContextInterface, jobID string, kind string) error {
+	err := requireRole(ctx, "officer", "ops")
+	if err != nil {
+		return err
+	}
+
+	return startJob(ctx, jobID, kind)
+}
+
+// startJob records a new running job of the given kind and marks its kind active
+func startJob(ctx contractapi.TransactionContextInterface, jobID string, kind string) error {
+	if jobID == "" || kind == "" {
+		return fmt.Errorf("job ID and kind must not be empty")
+	}
+
+	existing, err := getJob(ctx, jobID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("the job %s already exists", jobID)
+	}
+
+	activeKey, err := ctx.GetStub().CreateCompositeKey(activeJobObjectType, []string{kind})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	activeID, err := ctx.GetStub().GetState(activeKey)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if activeID != nil {
+		return fmt.Errorf("a %s job is already running as %s, resume it instead", kind, activeID)
+	}
+
+	operator, mspID, err := operatorName(ctx)
+	if err != nil {
+		return err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	job := Job{
+		ID:           jobID,
+		Kind:         kind,
+		Status:       jobRunning,
+		StartedBy:    operator,
+		StartedByMSP: mspID,
+		StartedAt:    now.Format(time.RFC3339),
+		LastOperator: operator,
+		UpdatedAt:    now.Format(time.RFC3339),
+	}
+	err = putJob(ctx, &job)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(activeKey, []byte(jobID))
+}
+
+// AdvanceJob records that the page starting at fromCursor was processed and that the job
+// continues at toCursor. fromCursor must match the job's cursor, so a duplicate run working from
+// a stale cursor is rejected rather than processing the same page twice. An empty toCursor
+// completes the job. Restricted to officer and ops roles.
+func (s *SmartContract) AdvanceJob(ctx contractapi.TransactionContextInterface, jobID string, fromCursor string, toCursor string, processed int) (*Job, error) {
+	err := requireRole(ctx, "officer", "ops")
+	if err != nil {
+		return nil, err
+	}
+	if processed < 0 {
+		return nil, fmt.Errorf("processed must not be negative")
+	}
+
+	job, err := s.ReadJob(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != jobRunning {
+		return nil, fmt.Errorf("the job %s is %s", jobID, job.Status)
+	}
+	if fromCursor != job.Cursor {
+		return nil, fmt.Errorf("the job %s is at cursor %q, not %q", jobID, job.Cursor, fromCursor)
+	}
+	if toCursor != "" && toCursor == fromCursor {
+		return nil, fmt.Errorf("the job %s must move past cursor %q", jobID, fromCursor)
+	}
+
+	job.Cursor = toCursor
+	job.Pages++
+	job.Processed += processed
+	if toCursor == "" {
+		job.Status = jobCompleted
+	}
+
+	err = finishJobStep(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// AbortJob stops a running job so that a fresh job of its kind can be started. Restricted to
+// officer and ops roles.
+func (s *SmartContract) AbortJob(ctx contractapi.TransactionContextInterface, jobID string, reason string) error {
+	err := requireRole(ctx, "officer", "ops")
+	if err != nil {
+		return err
+	}
+
+	job, err := s.ReadJob(ctx, jobID)
+	if err != nil {
+		return err
+	}
+	if job.Status != jobRunning {
+		return fmt.Errorf("the job %s is %s", jobID, job.Status)
+	}
+
+	job.Status = jobAborted
+	job.Reason = reason
+	return finishJobStep(ctx, job)
+}
+
+// ReadJob returns the job with the given ID
+func (s *SmartContract) ReadJob(ctx contractapi.TransactionContextInterface, jobID string) (*Job, error) {
+	job, err := getJob(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, fmt.Errorf("the job %s does not exist", jobID)
+	}
+
+	return job, nil
+}
+
+// finishJobStep stamps the operator on the job, saves it and releases its kind once it has stopped running
+func finishJobStep(ctx contractapi.TransactionContextInterface, job *Job) error {
+	operator, _, err := operatorName(ctx)
+	if err != nil {
+		return err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	job.LastOperator = operator
+	job.UpdatedAt = now.Format(time.RFC3339)
+
+	err = putJob(ctx, job)
+	if err != nil {
+		return err
+	}
+	if job.Status == jobRunning {
+		return nil
+	}
+
+	activeKey, err := ctx.GetStub().CreateCompositeKey(activeJobObjectType, []string{job.Kind})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	return ctx.GetStub().DelState(activeKey)
+}
+
+// operatorName returns the certificate common name and MSP ID of the submitting client
+func operatorName(ctx contractapi.TransactionContextInterface) (string, string, error) {
+	client, err := authz.ClientOf(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	return client.CommonName, client.MSPID, nil
+}
+
+func getJob(ctx contractapi.TransactionContextInterface, jobID string) (*Job, error) {
+	return jobRepo.Get(ctx, jobID)
+}
+
+func putJob(ctx contractapi.TransactionContextInterface, job *Job) error {
+	return jobRepo.Put(ctx, job, job.ID)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobs(t *testing.T) {
+	s := new(SmartContract)
+	ledger := chaincodetest.NewLedger()
+
+	start := func(jobID string, kind string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.StartJob(ctx, jobID, kind)
+		}
+	}
+	advance := func(jobID string, from string, to string, processed int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.AdvanceJob(ctx, jobID, from, to, processed)
+			return err
+		}
+	}
+	abort := func(jobID string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.AbortJob(ctx, jobID, "operator request")
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "start requires officer or ops", Caller: bank, Run: start("e1", "loan-export"), Err: "requires role officer or ops"},
+		{Name: "start without a kind", Caller: ops, Run: start("e1", ""), Err: "job ID and kind must not be empty"},
+		{Name: "start", Caller: ops, Run: start("e1", "loan-export")},
+		{Name: "start an existing job", Caller: ops, Run: start("e1", "other"), Err: "the job e1 already exists"},
+		{Name: "start a second job of the kind", Caller: ops, Run: start("e2", "loan-export"), Err: "a loan-export job is already running as e1, resume it instead"},
+		{Name: "advance requires officer or ops", Caller: bank, Run: advance("e1", "", "loan2", 1), Err: "requires role officer or ops"},
+		{Name: "advance by a negative count", Caller: ops, Run: advance("e1", "", "loan2", -1), Err: "processed must not be negative"},
+		{Name: "advance", Caller: officer, Run: advance("e1", "", "loan2", 1)},
+		{Name: "advance from a stale cursor", Caller: ops, Run: advance("e1", "", "loan2", 1), Err: `the job e1 is at cursor "loan2", not ""`},
+		{Name: "advance in place", Caller: ops, Run: advance("e1", "loan2", "loan2", 1), Err: `must move past cursor "loan2"`},
+		{Name: "advance to the end", Caller: ops, Run: advance("e1", "loan2", "", 1)},
+		{
+			Name:   "read job",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				job, err := s.ReadJob(ctx, "e1")
+				if err == nil {
+					require.Equal(t, jobCompleted, job.Status)
+					require.Equal(t, 2, job.Pages)
+					require.Equal(t, 2, job.Processed)
+					require.Equal(t, "ops1", job.StartedBy)
+					require.Equal(t, "ops1", job.LastOperator)
+				}
+				return err
+			},
+		},
+		{Name: "advance a completed job", Caller: ops, Run: advance("e1", "", "", 0), Err: "the job e1 is Completed"},
+		{Name: "start once the kind is released", Caller: ops, Run: start("e2", "loan-export")},
+		{Name: "abort requires officer or ops", Caller: bank, Run: abort("e2"), Err: "requires role officer or ops"},
+		{Name: "abort", Caller: officer, Run: abort("e2")},
+		{Name: "abort twice", Caller: officer, Run: abort("e2"), Err: "the job e2 is Aborted"},
+		{Name: "abort missing job", Caller: officer, Run: abort("e9"), Err: "the job e9 does not exist"},
+		{Name: "start after an abort", Caller: ops, Run: start("e3", "loan-export")},
+	})
+}
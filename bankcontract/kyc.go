@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	// identityChaincode is the identity chaincode holding applicants' KYC levels and the ISO
+	// country and currency reference data
+	identityChaincode = "identitycontract"
+
+	// enhancedKYCThreshold is the loan amount above which the applicant needs Enhanced KYC or better
+	enhancedKYCThreshold = 50000
+)
+
+// checkKYC rejects approval of a loan above enhancedKYCThreshold unless the applicant's identity
+// has reached Enhanced or Full KYC
+func checkKYC(ctx contractapi.TransactionContextInterface, loan *LoanApplication) error {
+	if loan.Amount <= enhancedKYCThreshold {
+		return nil
+	}
+
+	response := ctx.GetStub().InvokeChaincode(identityChaincode, [][]byte{[]byte("GetKYCLevel"), []byte(loan.Applicant)}, "")
+	if response.Status != shim.OK {
+		return fmt.Errorf("failed to query KYC level of %s from %s: %s", loan.Applicant, identityChaincode, response.Message)
+	}
+
+	level := string(response.Payload)
+	if level != "Enhanced" && level != "Full" {
+		return fmt.Errorf("loans above %d require Enhanced KYC, applicant %s has KYC level %q", enhancedKYCThreshold, loan.Applicant, level)
+	}
+
+	return nil
+}
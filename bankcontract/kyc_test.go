@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/peer"
+)
+
+// identityChaincodeOf returns a stand-in for the identity chaincode holding the identities of
+// the given map with their KYC levels, each registered under its ID prefixed with 35202- as CNIC,
+// and knowing the PKR and USD currencies
+func identityChaincodeOf(kycLevels map[string]string) func(function string, args []string) peer.Response {
+	return func(function string, args []string) peer.Response {
+		switch function {
+		case "GetKYCLevel":
+			level, ok := kycLevels[args[0]]
+			if !ok {
+				return shim.Error("the identity " + args[0] + " does not exist")
+			}
+			return shim.Success([]byte(level))
+		case "IdentityExists":
+			_, ok := kycLevels[args[0]]
+			return shim.Success([]byte(strconv.FormatBool(ok)))
+		case "GetIdentityByNIC":
+			id := strings.TrimPrefix(args[0], "35202-")
+			if _, ok := kycLevels[id]; !ok || id == args[0] {
+				return shim.Error("no identity registered under NIC " + args[0])
+			}
+			return shim.Success([]byte(`{"id":"` + id + `","cnic":"` + args[0] + `","kycLevel":"` + kycLevels[id] + `"}`))
+		case "ReadIdentity", "GetConsents":
+			if _, ok := kycLevels[args[0]]; !ok {
+				return shim.Error("the identity " + args[0] + " does not exist")
+			}
+			if function == "GetConsents" {
+				return shim.Success([]byte(`[{"purpose":"marketing"}]`))
+			}
+			return shim.Success([]byte(`{"id":"` + args[0] + `"}`))
+		case "ReferenceData:GetCurrency":
+			code := strings.ToUpper(args[0])
+			if code != "PKR" && code != "USD" {
+				return shim.Error("the currency " + args[0] + " does not exist")
+			}
+			currencyJSON, _ := json.Marshal(map[string]string{"code": code})
+			return shim.Success(currencyJSON)
+		}
+		return shim.Error("unexpected function " + function)
+	}
+}
+
+func TestKYC(t *testing.T) {
+	ledger := newLedger(t)
+	ledger.Install(identityChaincode, identityChaincodeOf(map[string]string{"Bob": "Basic", "Carol": "Enhanced"}))
+
+	approve := func(id string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return new(SmartContract).UpdateLoanStatus(ctx, id, "Approved")
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		createLoan("small", "Bob", enhancedKYCThreshold),
+		createLoan("large", "Bob", enhancedKYCThreshold+1),
+		createLoan("enhanced", "Carol", enhancedKYCThreshold+1),
+		createLoan("unknown", "Dave", enhancedKYCThreshold+1),
+		{Name: "approve at the threshold", Caller: bank, Run: approve("small")},
+		{Name: "approve above the threshold with basic KYC", Caller: bank, Run: approve("large"), Err: `applicant Bob has KYC level "Basic"`},
+		{Name: "approve above the threshold with enhanced KYC", Caller: bank, Run: approve("enhanced")},
+		{Name: "approve for an unknown applicant", Caller: bank, Run: approve("unknown"), Err: "failed to query KYC level of Dave from identitycontract"},
+	})
+}
@@ -3,10 +3,26 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 
+	"authz"
+	"ccaas"
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
 )
 
+// applicantIndex indexes loan applications by applicant
+const applicantIndex = "applicant~loan"
+
+// loanNamespace prefixes the plain keys of loan applications, LOAN_id
+const loanNamespace repository.Namespace = "LOAN_"
+
+// loanRepo stores loan applications under their plain keys in loanNamespace
+var loanRepo = repository.NewInNamespace[LoanApplication](loanNamespace)
+
+// deletedLoanRepo keeps deleted loan applications under tombstone~loan~id until they are restored
+var deletedLoanRepo = repository.New[LoanApplication](repository.TombstoneObjectType)
+
 type SmartContract struct {
 	contractapi.Contract
 }
@@ -15,111 +31,395 @@ type LoanApplication struct {
 	ID           string  `json:"id"`
 	Applicant    string  `json:"applicant"`
 	Amount       int     `json:"amount"`
-	Term         int     `json:"term"` // in months
+	Currency     string  `json:"currency"` // ISO 4217 code
+	Term         int     `json:"term"`     // in months
 	InterestRate float64 `json:"interestRate"`
 	Status       string  `json:"status"`
+	DisbursedAt  string  `json:"disbursedAt"`
+	TermsHash    string  `json:"termsHash"` // SHA-256 of the terms snapshotted at approval
+
+	DisputeDeadline string `json:"disputeDeadline"` // until when the applicant can dispute a rejection
+
+	SubsidyProgramID string  `json:"subsidyProgramId"`
+	SubsidyRate      float64 `json:"subsidyRate"` // percentage points paid by the program
+	PaidInstallments int     `json:"paidInstallments"`
+	RepaymentAccount string  `json:"repaymentAccount"` // the token account installments are collected from
+
+	CommitmentAccount   string `json:"commitmentAccount"`
+	CommitmentAmount    int    `json:"commitmentAmount"`
+	CommitmentShortfall bool   `json:"commitmentShortfall"`
+	FailedDebits        int    `json:"failedDebits"` // consecutive failed standing instruction debits
+
+	ReferralCode string `json:"referralCode"`
+	ReferredBy   string `json:"referredBy"`
+
+	BranchID    string   `json:"branchId"`
+	ReviewFlags []string `json:"reviewFlags,omitempty" metadata:",optional"`
+
+	InvoiceID string `json:"invoiceId"` // the invoice securing an invoice discounting loan
+
+	CollateralDeedID string `json:"collateralDeedId"` // the deed pledged as collateral, locked on the deed chaincode
+
+	ProductID string `json:"productId"` // the catalog product the loan was taken out under
+
+	CoApplicants []*CoApplicant `json:"coApplicants,omitempty" metadata:",optional"` // the applicant holds the remaining share
+
+	BenchmarkTenor string        `json:"benchmarkTenor,omitempty" metadata:",optional"` // set on floating-rate loans
+	Spread         float64       `json:"spread,omitempty" metadata:",optional"`         // over the benchmark, in percentage points
+	RateFixings    []*RateFixing `json:"rateFixings,omitempty" metadata:",optional"`
+
+	ProductType  string  `json:"productType,omitempty" metadata:",optional"`  // Murabaha, or empty for conventional loans
+	ProfitMarkup float64 `json:"profitMarkup,omitempty" metadata:",optional"` // of a Murabaha sale, in percent of the cost
+
+	ProcessingFee      int `json:"processingFee"`
+	FeeScheduleVersion int `json:"feeScheduleVersion"` // the fee schedule the processing fee was calculated from
+
+	OwnerMSP string `json:"ownerMsp"` // the org holding the loan, until it is sold in a portfolio transfer
+
+	WrittenOffAt     string `json:"writtenOffAt,omitempty" metadata:",optional"`
+	WrittenOffAmount int    `json:"writtenOffAmount,omitempty" metadata:",optional"` // the principal outstanding when written off
+
+	Deleted        bool   `json:"deleted,omitempty" metadata:",optional"`
+	DeletionReason string `json:"deletionReason,omitempty" metadata:",optional"`
+	DeletedBy      string `json:"deletedBy,omitempty" metadata:",optional"`
+	DeletedAt      string `json:"deletedAt,omitempty" metadata:",optional"`
+}
+
+// loanRedaction hides who is behind a loan from everyone but bank staff and auditors. Clients
+// matching no other rule, customers and clients without a role among them, get it redacted.
+var loanRedaction = authz.Policy{
+	{Match: "role=auditor"},
+	{Match: "role=officer"},
+	{Match: "role=ops"},
+	{Match: "role=compliance"},
+	{Match: "role=fraud"},
+	{Match: "*", Redact: []string{"applicant", "coApplicants", "commitmentAccount", "repaymentAccount", "referredBy"}},
 }
 
-// InitLedger initializes the ledger with some sample loan applications
+// InitLedger initializes the ledger with some sample loan applications held by the org of the
+// submitting client
 func (s *SmartContract) InitLedger(ctx contractapi.TransactionContextInterface) error {
+	mspID, err := authz.CallerMSP(ctx)
+	if err != nil {
+		return err
+	}
 	loans := []LoanApplication{
-		{ID: "loan1", Applicant: "Afraz", Amount: 10000, Term: 12, InterestRate: 5.5, Status: "Pending"},
-		{ID: "loan2", Applicant: "Alam", Amount: 5000, Term: 6, InterestRate: 4.2, Status: "Approved"},
+		{ID: "loan1", Applicant: "Afraz", Amount: 10000, Currency: defaultCurrency, Term: 12, InterestRate: 5.5, Status: "Pending", OwnerMSP: mspID},
+		{ID: "loan2", Applicant: "Alam", Amount: 5000, Currency: defaultCurrency, Term: 6, InterestRate: 4.2, Status: "Approved", OwnerMSP: mspID},
 	}
 
 	for _, loan := range loans {
-		loanJSON, err := json.Marshal(loan)
+		err := putLoan(ctx, &loan)
 		if err != nil {
-			return err
+			return fmt.Errorf("failed to put to world state: %v", err)
 		}
-		err = ctx.GetStub().PutState(loan.ID, loanJSON)
+		err = putApplicantIndex(ctx, &loan)
 		if err != nil {
-			return fmt.Errorf("failed to put to world state: %v", err)
+			return err
 		}
 	}
 
 	return nil
 }
 
-// CreateLoanApplication adds a new loan application to the ledger
+// CreateLoanApplication adds a new loan application to the ledger, charging the processing fee
+// of its amount band in the current fee schedule. A retry under the idempotency key of an
+// application already created succeeds without creating it again.
 func (s *SmartContract) CreateLoanApplication(ctx contractapi.TransactionContextInterface, id, applicant string, amount, term int, interestRate float64) error {
-	exists, err := s.LoanExists(ctx, id)
-	if err != nil {
-		return err
-	}
-	if exists {
-		return fmt.Errorf("the loan application %s already exists", id)
-	}
-
 	loan := LoanApplication{
 		ID:           id,
 		Applicant:    applicant,
 		Amount:       amount,
+		Currency:     defaultCurrency,
 		Term:         term,
 		InterestRate: interestRate,
 		Status:       "Pending",
 	}
 
-	loanJSON, err := json.Marshal(loan)
+	return repository.IdempotentOnce(ctx, func() error {
+		return s.createLoan(ctx, &loan)
+	})
+}
+
+// createLoan checks and records a new pending loan application together with its processing
+// fee, its device, origination and applicant index records and its reviewer assignment. The
+// loan is held by the org of the submitting client.
+func (s *SmartContract) createLoan(ctx contractapi.TransactionContextInterface, loan *LoanApplication) error {
+	exists, err := s.LoanExists(ctx, loan.ID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("the loan application %s already exists", loan.ID)
+	}
+	deleted, err := deletedLoanRepo.Get(ctx, loanAssetType, loan.ID)
+	if err != nil {
+		return err
+	}
+	if deleted != nil {
+		return fmt.Errorf("the loan application %s was deleted, restore it instead", loan.ID)
+	}
+	loan.OwnerMSP, err = authz.CallerMSP(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = checkGroupExposure(ctx, loan.Applicant, loan.Amount)
+	if err != nil {
+		return err
+	}
+
+	quote, err := quoteFees(ctx, loan.Amount)
 	if err != nil {
 		return err
 	}
+	loan.ProcessingFee = quote.Fee
+	loan.FeeScheduleVersion = quote.FeeScheduleVersion
 
-	return ctx.GetStub().PutState(id, loanJSON)
+	err = recordDevice(ctx, loan)
+	if err != nil {
+		return err
+	}
+	err = recordOrigination(ctx, loan)
+	if err != nil {
+		return err
+	}
+
+	err = putLoan(ctx, loan)
+	if err != nil {
+		return err
+	}
+	err = assignReviewer(ctx, loan.ID)
+	if err != nil {
+		return err
+	}
+
+	return putApplicantIndex(ctx, loan)
 }
 
-// ReadLoanApplication returns the loan application by ID
+// ReadLoanApplication returns the loan application by ID, redacted for the submitting client as
+// loanRedaction prescribes
 func (s *SmartContract) ReadLoanApplication(ctx contractapi.TransactionContextInterface, id string) (*LoanApplication, error) {
-	loanJSON, err := ctx.GetStub().GetState(id)
+	loan, err := readLoan(ctx, id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read from world state: %v", err)
+		return nil, err
 	}
-	if loanJSON == nil {
-		return nil, fmt.Errorf("the loan application %s does not exist", id)
+	err = loanRedaction.Apply(ctx, loan)
+	if err != nil {
+		return nil, err
+	}
+
+	return loan, nil
+}
+
+// redactLoans redacts each of the loans for the submitting client as loanRedaction prescribes
+func redactLoans(ctx contractapi.TransactionContextInterface, loans []*LoanApplication) error {
+	for _, loan := range loans {
+		err := loanRedaction.Apply(ctx, loan)
+		if err != nil {
+			return err
+		}
 	}
 
-	var loan LoanApplication
-	err = json.Unmarshal(loanJSON, &loan)
+	return nil
+}
+
+// readLoan returns the unredacted loan application by ID
+func readLoan(ctx contractapi.TransactionContextInterface, id string) (*LoanApplication, error) {
+	loan, err := loanRepo.Get(ctx, id)
 	if err != nil {
 		return nil, err
 	}
+	if loan == nil {
+		deleted, err := deletedLoanRepo.Get(ctx, loanAssetType, id)
+		if err != nil {
+			return nil, err
+		}
+		if deleted != nil {
+			return nil, fmt.Errorf("the loan application %s was deleted on %s", id, deleted.DeletedAt)
+		}
+		return nil, fmt.Errorf("the loan application %s does not exist", id)
+	}
 
-	return &loan, nil
+	return loan, nil
 }
 
-// UpdateLoanStatus changes the status of an existing loan application
+// UpdateLoanStatus changes the status of an existing loan application. It is refused while another
+// officer holds a review lock on the application.
 func (s *SmartContract) UpdateLoanStatus(ctx contractapi.TransactionContextInterface, id, newStatus string) error {
-	loan, err := s.ReadLoanApplication(ctx, id)
+	loan, err := readLoan(ctx, id)
 	if err != nil {
 		return err
 	}
+	err = checkReviewLock(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if newStatus == "Approved" {
+		err = checkKYC(ctx, loan)
+		if err != nil {
+			return err
+		}
+		err = checkCommitment(ctx, loan)
+		if err != nil {
+			return err
+		}
+		guaranteed, err := confirmedGuarantees(ctx, loan)
+		if err != nil {
+			return err
+		}
+		err = snapshotTerms(ctx, loan, guaranteed)
+		if err != nil {
+			return err
+		}
+	}
 
 	loan.Status = newStatus
+	if newStatus == "Disbursed" {
+		disbursedAt, err := txTime(ctx)
+		if err != nil {
+			return err
+		}
+		if loan.DisbursedAt == "" {
+			err = checkAMLHold(ctx, loan)
+			if err != nil {
+				return err
+			}
+			err = accrueReferralReward(ctx, loan)
+			if err != nil {
+				return err
+			}
+			err = fixFloatingRate(ctx, loan, 1, disbursedAt.Format("2006-01-02"))
+			if err != nil {
+				return err
+			}
+		}
+		loan.DisbursedAt = disbursedAt.Format(time.RFC3339)
+	}
+	if newStatus == "Defaulted" {
+		err = flagGroupOf(ctx, loan)
+		if err != nil {
+			return err
+		}
+	}
+	if newStatus == "Rejected" {
+		err = openDisputeWindow(ctx, loan)
+		if err != nil {
+			return err
+		}
+	}
 
-	loanJSON, err := json.Marshal(loan)
+	return putLoan(ctx, loan)
+}
+
+// DeleteLoanApplication soft deletes a loan application. It leaves the indexes, control totals
+// and listings like a removed loan, but is kept as a tombstone with the reason and the deleting
+// client until RestoreLoan brings it back. A loan holding collateral must release it first, or the
+// deed would stay locked, and a rejected one is kept until its dispute window has elapsed and no
+// dispute is open. Restricted to the officer role.
+func (s *SmartContract) DeleteLoanApplication(ctx contractapi.TransactionContextInterface, id string, reason string) error {
+	err := requireRole(ctx, "officer")
+	if err != nil {
+		return err
+	}
+	if reason == "" {
+		return fmt.Errorf("deletion reason must not be empty")
+	}
+	loan, err := readLoan(ctx, id)
+	if err != nil {
+		return err
+	}
+	if loan.CollateralDeedID != "" {
+		return fmt.Errorf("the loan application %s holds deed %s as collateral, release it first", id, loan.CollateralDeedID)
+	}
+	err = checkRejectionFinal(ctx, loan)
 	if err != nil {
 		return err
 	}
 
-	return ctx.GetStub().PutState(id, loanJSON)
+	for _, applicant := range loanApplicants(loan) {
+		indexKey, err := ctx.GetStub().CreateCompositeKey(applicantIndex, []string{applicant, loan.ID})
+		if err != nil {
+			return err
+		}
+		err = ctx.GetStub().DelState(indexKey)
+		if err != nil {
+			return err
+		}
+	}
+	previous, err := ctx.GetStub().GetState(loanNamespace.Key(id))
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	err = updateLoanIndexes(ctx, id, previous, nil)
+	if err != nil {
+		return err
+	}
+	err = updateControlTotals(ctx, id, previous, nil)
+	if err != nil {
+		return err
+	}
+	err = repository.RecordChange(ctx, loanAssetType, id, true)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().DelState(loanNamespace.Key(id))
+	if err != nil {
+		return err
+	}
+
+	operator, _, err := operatorName(ctx)
+	if err != nil {
+		return err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	loan.Deleted = true
+	loan.DeletionReason = reason
+	loan.DeletedBy = operator
+	loan.DeletedAt = now.Format(time.RFC3339)
+
+	return deletedLoanRepo.Put(ctx, loan, loanAssetType, id)
 }
 
-// DeleteLoanApplication removes a loan application from the ledger
-func (s *SmartContract) DeleteLoanApplication(ctx contractapi.TransactionContextInterface, id string) error {
-	exists, err := s.LoanExists(ctx, id)
+// RestoreLoan brings back a deleted loan application as it was when deleted. Restricted to the
+// officer role.
+func (s *SmartContract) RestoreLoan(ctx contractapi.TransactionContextInterface, id string) error {
+	err := requireRole(ctx, "officer")
 	if err != nil {
 		return err
 	}
-	if !exists {
-		return fmt.Errorf("the loan application %s does not exist", id)
+	loan, err := deletedLoanRepo.Get(ctx, loanAssetType, id)
+	if err != nil {
+		return err
+	}
+	if loan == nil {
+		return fmt.Errorf("the loan application %s is not deleted", id)
 	}
 
-	return ctx.GetStub().DelState(id)
+	loan.Deleted = false
+	loan.DeletionReason = ""
+	loan.DeletedBy = ""
+	loan.DeletedAt = ""
+	err = putLoan(ctx, loan)
+	if err != nil {
+		return err
+	}
+	err = putApplicantIndex(ctx, loan)
+	if err != nil {
+		return err
+	}
+
+	return deletedLoanRepo.Delete(ctx, loanAssetType, id)
 }
 
-// GetAllLoanApplications lists all loan applications in the ledger
-func (s *SmartContract) GetAllLoanApplications(ctx contractapi.TransactionContextInterface) ([]*LoanApplication, error) {
-	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
+// GetAllLoanApplications lists all loan applications in the ledger, followed by the deleted ones
+// when includeDeleted is set
+func (s *SmartContract) GetAllLoanApplications(ctx contractapi.TransactionContextInterface, includeDeleted bool) ([]*LoanApplication, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByRange(loanNamespace.Range(""))
 	if err != nil {
 		return nil, err
 	}
@@ -140,26 +440,232 @@ func (s *SmartContract) GetAllLoanApplications(ctx contractapi.TransactionContex
 		loans = append(loans, &loan)
 	}
 
+	if includeDeleted {
+		deleted, err := deletedLoanRepo.List(ctx, loanAssetType)
+		if err != nil {
+			return nil, err
+		}
+		loans = append(loans, deleted...)
+	}
+
+	return loans, redactLoans(ctx, loans)
+}
+
+// GetLoansByApplicant lists the loan applications of a single applicant using the applicant index,
+// including those they are a co-applicant of
+func (s *SmartContract) GetLoansByApplicant(ctx contractapi.TransactionContextInterface, applicant string) ([]*LoanApplication, error) {
+	loans, err := loansByApplicant(ctx, applicant)
+	if err != nil {
+		return nil, err
+	}
+
+	return loans, redactLoans(ctx, loans)
+}
+
+func loansByApplicant(ctx contractapi.TransactionContextInterface, applicant string) ([]*LoanApplication, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(applicantIndex, []string{applicant})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var loans []*LoanApplication
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, err
+		}
+		loanJSON, err := ctx.GetStub().GetState(loanNamespace.Key(keyParts[1]))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read from world state: %v", err)
+		}
+		if loanJSON == nil {
+			continue
+		}
+
+		var loan LoanApplication
+		err = json.Unmarshal(loanJSON, &loan)
+		if err != nil {
+			return nil, err
+		}
+		loans = append(loans, &loan)
+	}
+
 	return loans, nil
 }
 
+// LoanPage is one page of loan applications with the bookmark the next page starts at, which is
+// empty after the last page
+type LoanPage struct {
+	Loans               []*LoanApplication `json:"loans,omitempty" metadata:",optional"`
+	FetchedRecordsCount int32              `json:"fetchedRecordsCount"`
+	Bookmark            string             `json:"bookmark"`
+}
+
+// GetLoansByApplicantPaginated returns one page of at most repository.MaxPageSize of the loan
+// applications GetLoansByApplicant returns. Evaluate it rather than submitting it, since Fabric
+// refuses paginated queries in transactions that write.
+func (s *SmartContract) GetLoansByApplicantPaginated(ctx contractapi.TransactionContextInterface, applicant string, pageSize int, bookmark string) (*LoanPage, error) {
+	page := &LoanPage{}
+	var err error
+	page.FetchedRecordsCount, page.Bookmark, err = repository.KeyPage(ctx, applicantIndex, []string{applicant}, int32(pageSize), bookmark, func(keyParts []string, _ []byte) error {
+		loan, err := loanRepo.Get(ctx, keyParts[1])
+		if err != nil {
+			return err
+		}
+		if loan != nil {
+			page.Loans = append(page.Loans, loan)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return page, redactLoans(ctx, page.Loans)
+}
+
+// putApplicantIndex records the applicant~loan index entries of a loan application, one for the
+// applicant and one for each co-applicant. Only the key is needed, so a single null byte is stored
+// as the value.
+func putApplicantIndex(ctx contractapi.TransactionContextInterface, loan *LoanApplication) error {
+	for _, applicant := range loanApplicants(loan) {
+		indexKey, err := ctx.GetStub().CreateCompositeKey(applicantIndex, []string{applicant, loan.ID})
+		if err != nil {
+			return err
+		}
+		err = ctx.GetStub().PutState(indexKey, []byte{0x00})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// putLoan writes a loan application to the world state and moves its entries in the registered
+// loan indexes and the update-time index, keeping the key-level endorsement policy of a high-value
+// loan in step with its status. The previous version is read through the transaction context,
+// which returns a version written earlier in the same transaction, so the indexes stay right
+// when a transaction writes a loan more than once.
+func putLoan(ctx contractapi.TransactionContextInterface, loan *LoanApplication) error {
+	loanJSON, err := json.Marshal(loan)
+	if err != nil {
+		return err
+	}
+	previous, err := ctx.GetStub().GetState(loanNamespace.Key(loan.ID))
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	err = updateLoanIndexes(ctx, loan.ID, previous, loanJSON)
+	if err != nil {
+		return err
+	}
+	err = updateControlTotals(ctx, loan.ID, previous, loanJSON)
+	if err != nil {
+		return err
+	}
+	err = repository.RecordChange(ctx, loanAssetType, loan.ID, false)
+	if err != nil {
+		return err
+	}
+	err = updateHighValueEndorsement(ctx, loan, previous)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(loanNamespace.Key(loan.ID), loanJSON)
+}
+
 // LoanExists checks if a loan with the given ID exists
 func (s *SmartContract) LoanExists(ctx contractapi.TransactionContextInterface, id string) (bool, error) {
-	loanJSON, err := ctx.GetStub().GetState(id)
+	loanJSON, err := ctx.GetStub().GetState(loanNamespace.Key(id))
 	if err != nil {
 		return false, err
 	}
 	return loanJSON != nil, nil
 }
 
+// MigrateLoanKeys moves up to limit loan applications from the plain IDs they were stored under
+// before loanNamespace to their LOAN_ keys, keeping their key-level endorsement policies, and
+// returns how many it moved. Submit it until it moves none; loans not yet moved are invisible to
+// the contract. Restricted to the ops role.
+func (s *SmartContract) MigrateLoanKeys(ctx contractapi.TransactionContextInterface, limit int) (int, error) {
+	err := requireRole(ctx, "ops")
+	if err != nil {
+		return 0, err
+	}
+	moved, err := loanNamespace.Migrate(ctx, limit)
+	if err != nil {
+		return 0, err
+	}
+	authz.TxLogger(ctx).Info("loan keys migrated", "moved", moved)
+
+	return moved, nil
+}
+
+// GetAuditTrail returns up to pageSize entries of the audit trail, from the transaction startTx up
+// to but excluding endTx in transaction ID order. Evaluate it rather than submitting it.
+func (s *SmartContract) GetAuditTrail(ctx contractapi.TransactionContextInterface, startTx string, endTx string, pageSize int) (*authz.AuditPage, error) {
+	return authz.AuditTrail(ctx, startTx, endTx, pageSize)
+}
+
+// SetLogLevel changes the chaincode's log level until it restarts, after which
+// CORE_CHAINCODE_LOGGING_LEVEL applies again. Submit it so that every endorsing peer's chaincode
+// runs it. Restricted to the ops role.
+func (s *SmartContract) SetLogLevel(ctx contractapi.TransactionContextInterface, level string) error {
+	err := requireRole(ctx, "ops")
+	if err != nil {
+		return err
+	}
+
+	return authz.SetLogLevel(level)
+}
+
+// PruneIdempotencyKeys forgets up to limit idempotency keys whose retention has run out and returns
+// how many it forgot. Submit it periodically from a single scheduler. Restricted to the scheduler role.
+func (s *SmartContract) PruneIdempotencyKeys(ctx contractapi.TransactionContextInterface, limit int) (int, error) {
+	err := requireRole(ctx, "scheduler")
+	if err != nil {
+		return 0, err
+	}
+
+	return repository.PruneIdempotencyRecords(ctx, limit)
+}
+
+// audit audits the invocations of the contracts of the chaincode. Fabric refuses paginated queries
+// in a transaction that has written, so the functions running one are not audited.
+var audit = authz.Audit("GetUpcomingInstallments", "GetLoansByApplicantPaginated", "GetAuditTrail", "GetChangesSince", "ExportState")
+
+// beforeTransaction is the BeforeTransaction handler of the contracts of the chaincode
+func beforeTransaction(ctx contractapi.TransactionContextInterface) error {
+	err := throttle(ctx)
+	if err != nil {
+		return err
+	}
+
+	return audit(ctx)
+}
+
 func main() {
-	chaincode, err := contractapi.NewChaincode(&SmartContract{})
+	loanContract, rateOracle := &SmartContract{}, &RateOracle{}
+	for _, contract := range []*contractapi.Contract{&loanContract.Contract, &rateOracle.Contract} {
+		contract.TransactionContextHandler = new(authz.TransactionContext)
+		contract.BeforeTransaction = beforeTransaction
+	}
+
+	chaincode, err := contractapi.NewChaincode(loanContract, rateOracle)
 	if err != nil {
-		fmt.Printf("Error creating loan application chaincode: %v\n", err)
+		authz.Logger.Error("failed to create loan application chaincode", "error", err)
 		return
 	}
 
-	if err := chaincode.Start(); err != nil {
-		fmt.Printf("Error starting loan application chaincode: %v\n", err)
+	if err := ccaas.Start(chaincode); err != nil {
+		authz.Logger.Error("failed to start loan application chaincode", "error", err)
 	}
 }
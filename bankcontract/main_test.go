@@ -0,0 +1,603 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"authz"
+	"chaincodetest"
+	"chaincodetest/chaincodefakes"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	bank     = chaincodetest.Identity{MSPID: "Org1MSP", CommonName: "bank1"}
+	officer  = chaincodetest.Identity{MSPID: "Org1MSP", CommonName: "officer1", Attributes: map[string]string{"role": "officer"}}
+	officer2 = chaincodetest.Identity{MSPID: "Org1MSP", CommonName: "officer2", Attributes: map[string]string{"role": "officer"}}
+	ops      = chaincodetest.Identity{MSPID: "Org1MSP", CommonName: "ops1", Attributes: map[string]string{"role": "ops"}}
+	customer = chaincodetest.Identity{MSPID: "Org1MSP", CommonName: "customer1", Attributes: map[string]string{"role": "customer"}}
+	auditor  = chaincodetest.Identity{MSPID: "Org1MSP", CommonName: "auditor1", Attributes: map[string]string{"role": "auditor"}}
+)
+
+// newLedger returns a ledger holding the sample loan applications of InitLedger
+func newLedger(t *testing.T) *chaincodetest.Ledger {
+	ledger := chaincodetest.NewLedger()
+	ctx, _ := ledger.Tx(bank)
+	require.NoError(t, new(SmartContract).InitLedger(ctx))
+
+	return ledger
+}
+
+// createLoan returns a case creating a loan application
+func createLoan(id string, applicant string, amount int) chaincodetest.Case {
+	return chaincodetest.Case{
+		Name:   "create " + id,
+		Caller: bank,
+		Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return new(SmartContract).CreateLoanApplication(ctx, id, applicant, amount, 12, 5)
+		},
+	}
+}
+
+// setStatus returns a case moving a loan application to a status
+func setStatus(id string, status string) chaincodetest.Case {
+	return chaincodetest.Case{
+		Name:   id + " " + status,
+		Caller: bank,
+		Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return new(SmartContract).UpdateLoanStatus(ctx, id, status)
+		},
+	}
+}
+
+func TestLoanApplications(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	ledger.Run(t, []chaincodetest.Case{
+		{
+			Name:   "read sample loan",
+			Caller: officer,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				loan, err := s.ReadLoanApplication(ctx, "loan1")
+				if err == nil {
+					require.Equal(t, "Afraz", loan.Applicant)
+					require.Equal(t, 10000, loan.Amount)
+					require.Equal(t, defaultCurrency, loan.Currency)
+				}
+				return err
+			},
+		},
+		{
+			Name:   "customers read loans without the applicant",
+			Caller: customer,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				loan, err := s.ReadLoanApplication(ctx, "loan1")
+				if err == nil {
+					require.Equal(t, authz.Redacted, loan.Applicant)
+					require.Equal(t, 10000, loan.Amount)
+				}
+				return err
+			},
+		},
+		{
+			Name:   "auditors read loans in full",
+			Caller: auditor,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				loan, err := s.ReadLoanApplication(ctx, "loan1")
+				if err == nil {
+					require.Equal(t, "Afraz", loan.Applicant)
+				}
+				return err
+			},
+		},
+		{
+			Name:   "clients without a role read loans without the applicant",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				loan, err := s.ReadLoanApplication(ctx, "loan1")
+				if err == nil {
+					require.Equal(t, authz.Redacted, loan.Applicant)
+				}
+				return err
+			},
+		},
+		{
+			Name:   "listings are redacted like reads",
+			Caller: customer,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				all, err := s.GetAllLoanApplications(ctx, false)
+				require.NoError(t, err)
+				byApplicant, err := s.GetLoansByApplicant(ctx, "Afraz")
+				require.NoError(t, err)
+				page, err := s.GetLoansByApplicantPaginated(ctx, "Afraz", 10, "")
+				require.NoError(t, err)
+
+				loans := append(append(all, byApplicant...), page.Loans...)
+				require.Len(t, loans, 4)
+				for _, loan := range loans {
+					require.Equal(t, authz.Redacted, loan.Applicant)
+				}
+				return nil
+			},
+		},
+		{
+			Name:   "read missing loan",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.ReadLoanApplication(ctx, "loan9")
+				return err
+			},
+			Err: "the loan application loan9 does not exist",
+		},
+		{
+			Name:   "read fails with the world state",
+			Caller: bank,
+			Stub: func(stub *chaincodefakes.ChaincodeStub) {
+				stub.GetStateStub = nil
+				stub.GetStateReturns(nil, errors.New("peer unavailable"))
+			},
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.ReadLoanApplication(ctx, "loan1")
+				return err
+			},
+			Err: "failed to read from world state: peer unavailable",
+		},
+		createLoan("loan3", "Bob", 2000),
+		{
+			Name:   "create duplicate",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.CreateLoanApplication(ctx, "loan3", "Bob", 2000, 12, 5)
+			},
+			Err: "the loan application loan3 already exists",
+		},
+		{
+			Name:   "loan exists",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				exists, err := s.LoanExists(ctx, "loan3")
+				require.True(t, exists)
+				return err
+			},
+		},
+		{
+			Name:   "loan does not exist",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				exists, err := s.LoanExists(ctx, "loan9")
+				require.False(t, exists)
+				return err
+			},
+		},
+		{
+			Name:   "list loans",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				loans, err := s.GetAllLoanApplications(ctx, false)
+				if err == nil {
+					require.Len(t, loans, 3)
+					require.Equal(t, "loan3", loans[2].ID)
+				}
+				return err
+			},
+		},
+		{
+			Name:   "list loans with a corrupt record",
+			Caller: bank,
+			Stub: func(stub *chaincodefakes.ChaincodeStub) {
+				stub.GetStateByRangeStub = nil
+				stub.GetStateByRangeReturns(chaincodetest.StateIterator(&queryresult.KV{Key: "loan9", Value: []byte("{")}), nil)
+			},
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.GetAllLoanApplications(ctx, false)
+				return err
+			},
+			Err: "unexpected end of JSON input",
+		},
+		{
+			Name:   "list loans by applicant",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				loans, err := s.GetLoansByApplicant(ctx, "Bob")
+				if err == nil {
+					require.Len(t, loans, 1)
+					require.Equal(t, "loan3", loans[0].ID)
+				}
+				return err
+			},
+		},
+		setStatus("loan3", "Rejected"),
+		{
+			Name:   "status changes are kept",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				loan, err := s.ReadLoanApplication(ctx, "loan3")
+				if err == nil {
+					require.Equal(t, "Rejected", loan.Status)
+				}
+				return err
+			},
+		},
+		{
+			Name:   "update missing loan",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.UpdateLoanStatus(ctx, "loan9", "Approved")
+			},
+			Err: "does not exist",
+		},
+		{
+			Name:   "delete within the dispute window",
+			Caller: officer,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.DeleteLoanApplication(ctx, "loan3", "withdrawn")
+			},
+			Err: "the dispute window of loan application loan3 is open until",
+		},
+	})
+
+	ledger.Advance(disputeWindowDays * 24 * time.Hour)
+	ledger.Run(t, []chaincodetest.Case{
+		{
+			Name:   "delete loan",
+			Caller: officer,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				err := s.DeleteLoanApplication(ctx, "loan3", "withdrawn")
+				if err == nil {
+					loans, err := s.GetLoansByApplicant(ctx, "Bob")
+					require.NoError(t, err)
+					require.Empty(t, loans)
+				}
+				return err
+			},
+		},
+		{
+			Name:   "delete a deleted loan",
+			Caller: officer,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.DeleteLoanApplication(ctx, "loan3", "withdrawn")
+			},
+			Err: "the loan application loan3 was deleted on 2024-01-15T09:00:",
+		},
+		{
+			Name:   "delete missing loan",
+			Caller: officer,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.DeleteLoanApplication(ctx, "loan9", "withdrawn")
+			},
+			Err: "the loan application loan9 does not exist",
+		},
+	})
+}
+
+func TestGetLoansByApplicantPaginated(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	var bookmark string
+	page := func(pageSize int, ids ...string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			page, err := s.GetLoansByApplicantPaginated(ctx, "Bob", pageSize, bookmark)
+			if err == nil {
+				var loanIDs []string
+				for _, loan := range page.Loans {
+					loanIDs = append(loanIDs, loan.ID)
+				}
+				require.Equal(t, ids, loanIDs)
+				require.EqualValues(t, len(ids), page.FetchedRecordsCount)
+				bookmark = page.Bookmark
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		createLoan("loan3", "Bob", 1000),
+		createLoan("loan4", "Bob", 2000),
+		createLoan("loan5", "Bob", 3000),
+		{Name: "empty page", Caller: bank, Run: page(0), Err: "pageSize must be between 1 and 100"},
+		{Name: "page above the maximum", Caller: bank, Run: page(101), Err: "pageSize must be between 1 and 100"},
+		{Name: "first page", Caller: bank, Run: page(2, "loan3", "loan4")},
+		{Name: "last page", Caller: bank, Run: page(2, "loan5")},
+		{
+			Name:   "no bookmark after the last page",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				require.Empty(t, bookmark)
+				return nil
+			},
+		},
+	})
+}
+
+func TestRestoreLoan(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	remove := func(id string, reason string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.DeleteLoanApplication(ctx, id, reason)
+		}
+	}
+	restore := func(id string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.RestoreLoan(ctx, id)
+		}
+	}
+	status := func(id string, want string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			loan, err := s.ReadLoanApplication(ctx, id)
+			if err == nil {
+				require.Equal(t, want, loan.Status)
+			}
+			return err
+		}
+	}
+	list := func(includeDeleted bool, want ...string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			loans, err := s.GetAllLoanApplications(ctx, includeDeleted)
+			if err == nil {
+				var got []string
+				for _, loan := range loans {
+					got = append(got, loan.ID)
+					require.Equal(t, loan.ID == "loan2" && includeDeleted, loan.Deleted)
+				}
+				require.Equal(t, want, got)
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "delete without a reason", Caller: officer, Run: remove("loan2", ""), Err: "deletion reason must not be empty"},
+		{Name: "only officers delete", Caller: customer, Run: remove("loan2", "duplicate application"), Err: "requires role officer"},
+		{Name: "delete loan2", Caller: officer, Run: remove("loan2", "duplicate application")},
+		{Name: "deleted loans are not listed", Caller: bank, Run: list(false, "loan1")},
+		{Name: "list deleted loans", Caller: bank, Run: list(true, "loan1", "loan2")},
+		{
+			Name:   "the tombstone records the deletion",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				loan, err := deletedLoanRepo.Get(ctx, loanAssetType, "loan2")
+				if err == nil {
+					require.Equal(t, "duplicate application", loan.DeletionReason)
+					require.Equal(t, "officer1", loan.DeletedBy)
+					require.NotEmpty(t, loan.DeletedAt)
+				}
+				return err
+			},
+		},
+		{Name: "read a deleted loan", Caller: bank, Run: status("loan2", ""), Err: "the loan application loan2 was deleted on"},
+		{
+			Name:   "reuse the ID of a deleted loan",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.CreateLoanApplication(ctx, "loan2", "Bob", 1000, 12, 5)
+			},
+			Err: "the loan application loan2 was deleted, restore it instead",
+		},
+		{Name: "restore requires officer", Caller: bank, Run: restore("loan2"), Err: "requires role officer"},
+		{Name: "restore a live loan", Caller: officer, Run: restore("loan1"), Err: "the loan application loan1 is not deleted"},
+		{Name: "restore loan2", Caller: officer, Run: restore("loan2")},
+		{Name: "a restored loan is listed again", Caller: bank, Run: list(false, "loan1", "loan2")},
+		{Name: "a restored loan keeps its status", Caller: bank, Run: status("loan2", "Approved")},
+		{
+			Name:   "a restored loan is indexed again",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				loans, err := s.GetLoansByApplicant(ctx, "Alam")
+				if err == nil {
+					require.Len(t, loans, 1)
+					require.False(t, loans[0].Deleted)
+				}
+				return err
+			},
+		},
+	})
+}
+
+func TestMigrateLoanKeys(t *testing.T) {
+	s := new(SmartContract)
+	ledger := chaincodetest.NewLedger()
+
+	migrate := func(limit int, moved int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			count, err := s.MigrateLoanKeys(ctx, limit)
+			if err == nil {
+				require.Equal(t, moved, count)
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{
+			Name:   "loans under plain IDs",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				for _, id := range []string{"loan1", "loan2"} {
+					loanJSON, err := json.Marshal(LoanApplication{ID: id, Applicant: "Afraz", Amount: 1000, Status: "Pending"})
+					require.NoError(t, err)
+					require.NoError(t, ctx.GetStub().PutState(id, loanJSON))
+				}
+				return ctx.GetStub().SetStateValidationParameter("loan2", []byte("policy"))
+			},
+		},
+		{Name: "only ops migrate", Caller: officer, Run: migrate(1, 0), Err: "requires role ops"},
+		{Name: "limit must be positive", Caller: ops, Run: migrate(0, 0), Err: "limit must be positive"},
+		{Name: "migrate a page", Caller: ops, Run: migrate(1, 1)},
+		{
+			Name:   "loans not yet migrated are not listed",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				loans, err := s.GetAllLoanApplications(ctx, false)
+				if err == nil {
+					require.Len(t, loans, 1)
+					require.Equal(t, "loan1", loans[0].ID)
+				}
+				return err
+			},
+		},
+		{Name: "migrate the rest", Caller: ops, Run: migrate(10, 1)},
+		{Name: "nothing left to migrate", Caller: ops, Run: migrate(10, 0)},
+		{
+			Name:   "migrated loans keep their endorsement policy and history",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				require.Nil(t, ledger.State("loan2"))
+				policy, err := ctx.GetStub().GetStateValidationParameter(loanNamespace.Key("loan2"))
+				require.NoError(t, err)
+				require.Equal(t, []byte("policy"), policy)
+
+				history, err := s.GetFieldHistory(ctx, "loan", "loan2", "status")
+				if err == nil {
+					require.Len(t, history, 1)
+					require.Equal(t, "tx1", history[0].TxID)
+				}
+				return err
+			},
+		},
+	})
+}
+
+func TestGetAuditTrail(t *testing.T) {
+	s := new(SmartContract)
+	ledger := chaincodetest.NewLedger()
+
+	var cases []chaincodetest.Case
+	for _, function := range []string{"CreateLoanApplication", "UpdateLoanStatus", "GetAuditTrail"} {
+		function := function
+		cases = append(cases, chaincodetest.Case{
+			Name:   "audit " + function,
+			Caller: officer,
+			Stub: func(stub *chaincodefakes.ChaincodeStub) {
+				stub.GetFunctionAndParametersReturns(function, nil)
+			},
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return audit(ctx)
+			},
+		})
+	}
+	cases = append(cases,
+		chaincodetest.Case{
+			Name:   "read the trail",
+			Caller: auditor,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				page, err := s.GetAuditTrail(ctx, "", "", 10)
+				if err == nil {
+					require.Len(t, page.Entries, 2)
+					require.Equal(t, "tx1", page.Entries[0].TxID)
+					require.Equal(t, "CreateLoanApplication", page.Entries[0].Function)
+					require.Equal(t, "Org1MSP", page.Entries[0].MSPID)
+					require.Empty(t, page.NextTxID)
+				}
+				return err
+			},
+		},
+		chaincodetest.Case{
+			Name:   "read the trail a page at a time",
+			Caller: auditor,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				page, err := s.GetAuditTrail(ctx, "", "", 1)
+				if err == nil {
+					require.Len(t, page.Entries, 1)
+					require.Equal(t, "tx2", page.NextTxID)
+				}
+				return err
+			},
+		},
+	)
+
+	ledger.Run(t, cases)
+}
+
+func TestSetLogLevel(t *testing.T) {
+	s := new(SmartContract)
+	ledger := chaincodetest.NewLedger()
+	defer authz.SetLogLevel("INFO")
+
+	setLevel := func(level string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.SetLogLevel(ctx, level)
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "set requires ops", Caller: officer, Run: setLevel("DEBUG"), Err: "requires role ops"},
+		{Name: "set an unknown level", Caller: ops, Run: setLevel("VERBOSE"), Err: `unknown log level "VERBOSE"`},
+		{
+			Name:   "set",
+			Caller: ops,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				err := s.SetLogLevel(ctx, "warning")
+				if err == nil {
+					require.Equal(t, "WARN", authz.LogLevel())
+				}
+				return err
+			},
+		},
+	})
+}
+
+func TestBeforeTransactionSkipsPaginatedQueries(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	// paginated runs the BeforeTransaction handler as Fabric does before the function, then the
+	// function's paginated query, which fails if the handler wrote
+	paginated := func(caller chaincodetest.Identity, function string, query func(ctx contractapi.TransactionContextInterface) error) chaincodetest.Case {
+		return chaincodetest.Case{
+			Name:   function,
+			Caller: caller,
+			Stub: func(stub *chaincodefakes.ChaincodeStub) {
+				stub.GetFunctionAndParametersReturns(function, nil)
+			},
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				err := beforeTransaction(ctx)
+				if err != nil {
+					return err
+				}
+				return query(ctx)
+			},
+		}
+	}
+	listLoans := func(ctx contractapi.TransactionContextInterface) error {
+		_, err := s.GetLoansByApplicantPaginated(ctx, "Afraz", 10, "")
+		return err
+	}
+
+	audited := paginated(officer, "UpdateLoanStatus", listLoans)
+	audited.Err = "has written and cannot run a paginated query"
+	ledger.Run(t, []chaincodetest.Case{
+		{
+			Name:   "throttle officers",
+			Caller: ops,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.SetBankConfig(ctx, 2, 60)
+			},
+		},
+		paginated(officer, "GetUpcomingInstallments", func(ctx contractapi.TransactionContextInterface) error {
+			_, err := s.GetUpcomingInstallments(ctx, 30, 10, "")
+			return err
+		}),
+		paginated(officer, "GetLoansByApplicantPaginated", listLoans),
+		paginated(officer, "GetAuditTrail", func(ctx contractapi.TransactionContextInterface) error {
+			_, err := s.GetAuditTrail(ctx, "", "", 10)
+			return err
+		}),
+		paginated(officer, "GetChangesSince", func(ctx contractapi.TransactionContextInterface) error {
+			_, err := s.GetChangesSince(ctx, loanAssetType, "", 10, "")
+			return err
+		}),
+		paginated(ops, "ExportState", func(ctx contractapi.TransactionContextInterface) error {
+			_, err := s.ExportState(ctx, "", 10, "")
+			return err
+		}),
+		audited,
+	})
+}
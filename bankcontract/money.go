@@ -0,0 +1,230 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+// exchangeRateObjectType prefixes the composite keys of the FX table, exchangerate~from~to
+const exchangeRateObjectType = "exchangerate"
+
+var exchangeRateRepo = repository.New[ExchangeRate](exchangeRateObjectType)
+
+// loanCurrencies are the currencies loans can be denominated in, with the number of digits of
+// their minor unit
+var loanCurrencies = map[string]int{
+	"EUR": 2,
+	"PKR": 2,
+	"USD": 2,
+}
+
+// Money is an amount in the minor unit of its currency, for example paisa for PKR or cents for USD
+type Money struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"` // ISO 4217 code
+}
+
+// ExchangeRate is the number of units of To that one unit of From buys
+type ExchangeRate struct {
+	From  string  `json:"from"`
+	To    string  `json:"to"`
+	Rate  float64 `json:"rate"`
+	SetBy string  `json:"setBy"`
+	SetAt string  `json:"setAt"`
+}
+
+// PortfolioSummary is the disbursed loan book converted into a base currency, from the control
+// totals of each currency it is held in and the exchange rates they were converted at
+type PortfolioSummary struct {
+	BaseCurrency string           `json:"baseCurrency"`
+	Disbursed    Money            `json:"disbursed"`
+	Outstanding  Money            `json:"outstanding"`
+	Fees         Money            `json:"fees"`
+	ByCurrency   []*ControlTotals `json:"byCurrency,omitempty" metadata:",optional"`
+	Rates        []*ExchangeRate  `json:"rates,omitempty" metadata:",optional"`
+}
+
+// SetExchangeRate sets the rate at which amounts in one loan currency are converted into another.
+// The inverse conversion uses the reciprocal unless a rate is set for it too. Restricted to the
+// ops role.
+func (s *SmartContract) SetExchangeRate(ctx contractapi.TransactionContextInterface, from string, to string, rate float64) error {
+	err := requireRole(ctx, "ops")
+	if err != nil {
+		return err
+	}
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	for _, currency := range []string{from, to} {
+		if _, ok := loanCurrencies[currency]; !ok {
+			return unsupportedCurrency(currency)
+		}
+	}
+	if from == to {
+		return fmt.Errorf("an exchange rate needs two different currencies")
+	}
+	if rate <= 0 {
+		return fmt.Errorf("exchange rate must be positive")
+	}
+
+	operator, _, err := operatorName(ctx)
+	if err != nil {
+		return err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	return exchangeRateRepo.Put(ctx, &ExchangeRate{
+		From:  from,
+		To:    to,
+		Rate:  rate,
+		SetBy: operator,
+		SetAt: now.Format(time.RFC3339),
+	}, from, to)
+}
+
+// ConvertAmount converts an amount in the minor unit of one loan currency into another at the
+// exchange rate in the FX table
+func (s *SmartContract) ConvertAmount(ctx contractapi.TransactionContextInterface, amount int64, from string, to string) (*Money, error) {
+	converted, _, err := convertAmount(ctx, Money{Amount: amount, Currency: strings.ToUpper(from)}, strings.ToUpper(to))
+	if err != nil {
+		return nil, err
+	}
+
+	return &converted, nil
+}
+
+// GetPortfolioSummary returns the control totals of the disbursed loan book converted into a
+// base currency. Restricted to officer, ops and auditor roles.
+func (s *SmartContract) GetPortfolioSummary(ctx contractapi.TransactionContextInterface, baseCurrency string) (*PortfolioSummary, error) {
+	err := requireRole(ctx, "officer", "ops", "auditor")
+	if err != nil {
+		return nil, err
+	}
+	baseCurrency = strings.ToUpper(baseCurrency)
+	if _, ok := loanCurrencies[baseCurrency]; !ok {
+		return nil, unsupportedCurrency(baseCurrency)
+	}
+
+	totals, err := s.GetControlTotals(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &PortfolioSummary{
+		BaseCurrency: baseCurrency,
+		Disbursed:    Money{Currency: baseCurrency},
+		Outstanding:  Money{Currency: baseCurrency},
+		Fees:         Money{Currency: baseCurrency},
+		ByCurrency:   totals,
+	}
+	for _, total := range totals {
+		rate, err := addConverted(ctx, &summary.Disbursed, total.Disbursed, total.Currency)
+		if err != nil {
+			return nil, err
+		}
+		_, err = addConverted(ctx, &summary.Outstanding, total.Outstanding, total.Currency)
+		if err != nil {
+			return nil, err
+		}
+		_, err = addConverted(ctx, &summary.Fees, total.Fees, total.Currency)
+		if err != nil {
+			return nil, err
+		}
+		if rate != nil {
+			summary.Rates = append(summary.Rates, rate)
+		}
+	}
+
+	return summary, nil
+}
+
+// addConverted adds an amount in whole units of a loan currency to a sum in another and returns
+// the exchange rate used
+func addConverted(ctx contractapi.TransactionContextInterface, sum *Money, amount int, currency string) (*ExchangeRate, error) {
+	money, err := toMoney(amount, currency)
+	if err != nil {
+		return nil, err
+	}
+	converted, rate, err := convertAmount(ctx, money, sum.Currency)
+	if err != nil {
+		return nil, err
+	}
+	sum.Amount += converted.Amount
+
+	return rate, nil
+}
+
+// toMoney returns an amount in whole units of a loan currency, as loan amounts are held, in its
+// minor unit
+func toMoney(amount int, currency string) (Money, error) {
+	digits, ok := loanCurrencies[currency]
+	if !ok {
+		return Money{}, unsupportedCurrency(currency)
+	}
+
+	return Money{Amount: int64(amount) * int64(math.Pow10(digits)), Currency: currency}, nil
+}
+
+// convertAmount converts money into another loan currency, rounding to the nearest minor unit, and
+// returns the exchange rate used, which is nil when the currencies are the same
+func convertAmount(ctx contractapi.TransactionContextInterface, amount Money, to string) (Money, *ExchangeRate, error) {
+	fromDigits, ok := loanCurrencies[amount.Currency]
+	if !ok {
+		return Money{}, nil, unsupportedCurrency(amount.Currency)
+	}
+	toDigits, ok := loanCurrencies[to]
+	if !ok {
+		return Money{}, nil, unsupportedCurrency(to)
+	}
+	if amount.Currency == to {
+		return amount, nil, nil
+	}
+
+	rate, err := exchangeRate(ctx, amount.Currency, to)
+	if err != nil {
+		return Money{}, nil, err
+	}
+	converted := float64(amount.Amount) / math.Pow10(fromDigits) * rate.Rate * math.Pow10(toDigits)
+
+	return Money{Amount: int64(math.Round(converted)), Currency: to}, rate, nil
+}
+
+// exchangeRate returns the rate converting one currency into another, derived from the inverse
+// rate when only that one is set
+func exchangeRate(ctx contractapi.TransactionContextInterface, from string, to string) (*ExchangeRate, error) {
+	rate, err := exchangeRateRepo.Get(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if rate != nil {
+		return rate, nil
+	}
+
+	inverse, err := exchangeRateRepo.Get(ctx, to, from)
+	if err != nil {
+		return nil, err
+	}
+	if inverse == nil {
+		return nil, fmt.Errorf("no exchange rate from %s to %s is set", from, to)
+	}
+
+	return &ExchangeRate{From: from, To: to, Rate: 1 / inverse.Rate, SetBy: inverse.SetBy, SetAt: inverse.SetAt}, nil
+}
+
+// unsupportedCurrency is the error for currencies loans cannot be denominated in
+func unsupportedCurrency(currency string) error {
+	currencies := make([]string, 0, len(loanCurrencies))
+	for code := range loanCurrencies {
+		currencies = append(currencies, code)
+	}
+	sort.Strings(currencies)
+
+	return fmt.Errorf("unsupported currency %q, loans are denominated in %s", currency, strings.Join(currencies, ", "))
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPortfolioSummary(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+	ledger.Install(identityChaincode, identityChaincodeOf(nil))
+
+	setRate := func(from string, to string, rate float64) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.SetExchangeRate(ctx, from, to, rate)
+		}
+	}
+	summary := func(baseCurrency string, disbursed int64) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			summary, err := s.GetPortfolioSummary(ctx, baseCurrency)
+			if err == nil {
+				require.Equal(t, Money{Amount: disbursed, Currency: baseCurrency}, summary.Disbursed)
+				require.Equal(t, summary.Disbursed, summary.Outstanding)
+				require.Len(t, summary.ByCurrency, 2)
+				require.Len(t, summary.Rates, 1)
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		createLoan("loan3", "Bob", 1000),
+		{
+			Name:   "denominate a loan in USD",
+			Caller: officer,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.SetLoanCurrency(ctx, "loan3", "USD")
+			},
+		},
+		setStatus("loan2", "Disbursed"),
+		setStatus("loan3", "Disbursed"),
+		{Name: "only ops set exchange rates", Caller: officer, Run: setRate("USD", "PKR", 280), Err: "requires role ops"},
+		{Name: "unsupported currency", Caller: ops, Run: setRate("GBP", "PKR", 350), Err: `unsupported currency "GBP", loans are denominated in EUR, PKR, USD`},
+		{Name: "same currency", Caller: ops, Run: setRate("PKR", "pkr", 1), Err: "an exchange rate needs two different currencies"},
+		{Name: "summary without a rate", Caller: ops, Run: summary("PKR", 0), Err: "no exchange rate from USD to PKR is set"},
+		{Name: "set the USD rate", Caller: ops, Run: setRate("usd", "pkr", 280)},
+		{Name: "summary in PKR", Caller: auditor, Run: summary("PKR", 5000*100+1000*100*280)},
+		{Name: "summary in USD uses the inverse rate", Caller: officer, Run: summary("USD", 1786+1000*100)},
+		{Name: "customers get no summary", Caller: customer, Run: summary("PKR", 0), Err: "requires role officer or ops or auditor"},
+		{
+			Name:   "convert an amount",
+			Caller: customer,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				converted, err := s.ConvertAmount(ctx, 250, "USD", "PKR")
+				if err == nil {
+					require.Equal(t, Money{Amount: 70000, Currency: "PKR"}, *converted)
+				}
+				return err
+			},
+		},
+		{
+			Name:   "convert without a rate",
+			Caller: customer,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.ConvertAmount(ctx, 250, "EUR", "PKR")
+				return err
+			},
+			Err: "no exchange rate from EUR to PKR is set",
+		},
+	})
+}
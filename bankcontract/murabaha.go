@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	// productTypeMurabaha marks products and loans financed as a Murabaha sale: the bank buys the
+	// asset and sells it to the customer at its cost plus a profit markup agreed up front, paid in
+	// installments. No interest accrues, so the price never changes with time or rates.
+	productTypeMurabaha = "Murabaha"
+
+	// murabahaScheduleMethod names how murabahaSchedule derives installments from the terms
+	murabahaScheduleMethod = "murabaha-cost-plus-profit"
+)
+
+// CreateMurabahaProduct adds an Islamic finance product to the catalog, selling at cost plus a
+// fixed profit markup in percent of the cost instead of charging interest. Restricted to the ops
+// role.
+func (s *SmartContract) CreateMurabahaProduct(ctx contractapi.TransactionContextInterface, id string, name string, profitMarkup float64, maxAmount int, maxTerm int) error {
+	if profitMarkup <= 0 {
+		return fmt.Errorf("profit markup must be positive")
+	}
+
+	return createProduct(ctx, &LoanProduct{
+		ID:           id,
+		Name:         name,
+		MaxAmount:    maxAmount,
+		MaxTerm:      maxTerm,
+		ProductType:  productTypeMurabaha,
+		ProfitMarkup: profitMarkup,
+	})
+}
+
+// murabahaSchedule derives the installments of a Murabaha sale: the cost and the profit markup on
+// it are each spread evenly over the term, the last installment taking the remainders
+func murabahaSchedule(loan *LoanApplication, disbursedAt time.Time) []*Installment {
+	profit := int(math.Round(float64(loan.Amount) * loan.ProfitMarkup / 100))
+	installments := make([]*Installment, 0, loan.Term)
+	for n := 1; n <= loan.Term; n++ {
+		principal := loan.Amount / loan.Term
+		installmentProfit := profit / loan.Term
+		if n == loan.Term {
+			principal = loan.Amount - principal*(loan.Term-1)
+			installmentProfit = profit - installmentProfit*(loan.Term-1)
+		}
+
+		installments = append(installments, &Installment{
+			LoanID:         loan.ID,
+			Number:         n,
+			DueDate:        disbursedAt.AddDate(0, n, 0).Format("2006-01-02"),
+			Amount:         principal + installmentProfit,
+			Principal:      principal,
+			Profit:         installmentProfit,
+			BorrowerAmount: principal + installmentProfit,
+		})
+	}
+
+	return installments
+}
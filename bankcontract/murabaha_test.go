@@ -0,0 +1,99 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMurabaha(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	ledger.Run(t, []chaincodetest.Case{
+		{
+			Name:   "markup must be positive",
+			Caller: ops,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.CreateMurabahaProduct(ctx, "car", "Car Murabaha", 0, 50000, 36)
+			},
+			Err: "profit markup must be positive",
+		},
+		{
+			Name:   "create a Murabaha product",
+			Caller: ops,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.CreateMurabahaProduct(ctx, "car", "Car Murabaha", 10, 50000, 36)
+			},
+		},
+		{
+			Name:   "apply for a Murabaha sale",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				err := s.ApplyForProduct(ctx, "loan3", "Bob", "car", 1000, 3)
+				if err == nil {
+					loan, err := s.ReadLoanApplication(ctx, "loan3")
+					require.NoError(t, err)
+					require.Equal(t, productTypeMurabaha, loan.ProductType)
+					require.Equal(t, 10.0, loan.ProfitMarkup)
+					require.Zero(t, loan.InterestRate)
+				}
+				return err
+			},
+		},
+		{
+			Name:   "Murabaha sales are not subsidized",
+			Caller: officer,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.LinkSubsidy(ctx, "loan3", "program1")
+			},
+			Err: "the loan application loan3 is a Murabaha sale and bears no interest to subsidize",
+		},
+		setStatus("loan3", "Approved"),
+		{
+			Name:   "the terms disclose the markup",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				terms, err := s.GetTermsRecord(ctx, "loan3")
+				if err == nil {
+					require.Equal(t, 10.0, terms.ProfitMarkup)
+					require.Equal(t, murabahaScheduleMethod, terms.ScheduleMethod)
+				}
+				return err
+			},
+		},
+		setStatus("loan3", "Disbursed"),
+		{
+			Name:   "installments spread cost and profit evenly",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				schedule, err := s.GetRepaymentSchedule(ctx, "loan3")
+				if err == nil {
+					require.Len(t, schedule, 3)
+					require.Equal(t, []int{333, 333, 334}, []int{schedule[0].Principal, schedule[1].Principal, schedule[2].Principal})
+					require.Equal(t, []int{33, 33, 34}, []int{schedule[0].Profit, schedule[1].Profit, schedule[2].Profit})
+					require.Zero(t, schedule[0].Interest)
+					require.Equal(t, 366, schedule[0].BorrowerAmount)
+				}
+				return err
+			},
+		},
+		{
+			Name:   "pay the sale off",
+			Caller: officer,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				for range 3 {
+					_, err := s.RecordPayment(ctx, "loan3")
+					require.NoError(t, err)
+				}
+				loan, err := s.ReadLoanApplication(ctx, "loan3")
+				if err == nil {
+					require.Equal(t, "Closed", loan.Status)
+				}
+				return err
+			},
+		},
+	})
+}
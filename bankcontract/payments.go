@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"authz"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+const (
+	// repaymentObjectType prefixes the composite keys of recorded repayment legs
+	repaymentObjectType = "repayment"
+
+	// payerBorrower marks the leg of an installment paid by the borrower
+	payerBorrower = "borrower"
+)
+
+var repaymentRepo = repository.New[Repayment](repaymentObjectType)
+
+// Repayment is one leg of a settled installment, paid either by the borrower or by a subsidy program
+type Repayment struct {
+	LoanID      string `json:"loanId"`
+	Installment int    `json:"installment"`
+	Payer       string `json:"payer"`
+	Amount      int    `json:"amount"`
+	PaidAt      string `json:"paidAt"`
+	TxID        string `json:"txId"`
+}
+
+// RecordPayment settles the next unpaid installment of a disbursed loan. For subsidized loans the
+// program's share of the interest is drawn from its budget and recorded as a separate leg; whatever
+// the remaining budget cannot cover falls back to the borrower. When the loan has a repayment
+// account, the borrower's share is moved from it to the submitting client's own token account in
+// the same transaction, so the installment stays unpaid if the transfer fails. A retry under the
+// idempotency key of a recorded payment returns the installment it settled instead of settling
+// the next one. Restricted to officer and scheduler roles.
+func (s *SmartContract) RecordPayment(ctx contractapi.TransactionContextInterface, loanID string) (*Installment, error) {
+	err := requireRole(ctx, "officer", "scheduler")
+	if err != nil {
+		return nil, err
+	}
+
+	return repository.Idempotent(ctx, func() (*Installment, error) {
+		return s.recordPayment(ctx, loanID)
+	})
+}
+
+// recordPayment settles the next unpaid installment of a loan
+func (s *SmartContract) recordPayment(ctx contractapi.TransactionContextInterface, loanID string) (*Installment, error) {
+	loan, err := readLoan(ctx, loanID)
+	if err != nil {
+		return nil, err
+	}
+
+	installment, err := nextInstallment(ctx, loan)
+	if err != nil {
+		return nil, err
+	}
+
+	if loan.RepaymentAccount != "" && installment.BorrowerAmount > 0 {
+		client, err := authz.ClientOf(ctx)
+		if err != nil {
+			return nil, err
+		}
+		err = debitAccount(ctx, loan.RepaymentAccount, client.ID, installment.BorrowerAmount)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = settleInstallment(ctx, loan, installment)
+	if err != nil {
+		return nil, err
+	}
+
+	return installment, nil
+}
+
+// SetRepaymentAccount has the borrower's share of every installment recorded with RecordPayment
+// collected from the given token account. The account holder must grant the clients recording
+// payments an allowance on the token chaincode. Restricted to the applicant and the officer role.
+func (s *SmartContract) SetRepaymentAccount(ctx contractapi.TransactionContextInterface, loanID string, accountRef string) error {
+	loan, err := readLoan(ctx, loanID)
+	if err != nil {
+		return err
+	}
+	err = requireApplicantOrOfficer(ctx, loan)
+	if err != nil {
+		return err
+	}
+	if loan.Status == "Closed" || loan.Status == "Defaulted" {
+		return fmt.Errorf("the loan application %s is %s", loanID, loan.Status)
+	}
+	if accountRef == "" {
+		return fmt.Errorf("repayment account must not be empty")
+	}
+
+	loan.RepaymentAccount = accountRef
+	return putLoan(ctx, loan)
+}
+
+// nextInstallment returns the next unpaid installment of a disbursed or overdue loan with the
+// subsidy share capped at what the program budget can still cover. Nothing is written.
+func nextInstallment(ctx contractapi.TransactionContextInterface, loan *LoanApplication) (*Installment, error) {
+	if loan.Status != "Disbursed" && loan.Status != "Overdue" {
+		return nil, fmt.Errorf("the loan application %s is not in repayment, status is %s", loan.ID, loan.Status)
+	}
+
+	schedule, err := repaymentSchedule(loan)
+	if err != nil {
+		return nil, err
+	}
+	if loan.PaidInstallments >= len(schedule) {
+		return nil, fmt.Errorf("the loan application %s has no installments left to pay", loan.ID)
+	}
+	installment := schedule[loan.PaidInstallments]
+
+	if installment.SubsidyAmount > 0 {
+		program, err := getSubsidyProgram(ctx, loan.SubsidyProgramID)
+		if err != nil {
+			return nil, err
+		}
+		if program == nil {
+			return nil, fmt.Errorf("the subsidy program %s does not exist", loan.SubsidyProgramID)
+		}
+		available := program.Budget - program.Drawn
+		if available < 0 {
+			available = 0
+		}
+		if installment.SubsidyAmount > available {
+			installment.BorrowerAmount += installment.SubsidyAmount - available
+			installment.SubsidyAmount = available
+		}
+	}
+
+	return installment, nil
+}
+
+// settleInstallment draws the subsidy share of an installment returned by nextInstallment, records
+// the repayment legs, credits syndicate participants with their part and advances the loan,
+// closing it after the final installment or fixing the rate of the next one if it floats
+func settleInstallment(ctx contractapi.TransactionContextInterface, loan *LoanApplication, installment *Installment) error {
+	if installment.SubsidyAmount > 0 {
+		_, err := drawSubsidy(ctx, loan.SubsidyProgramID, installment.SubsidyAmount)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := putRepayment(ctx, installment, payerBorrower, installment.BorrowerAmount)
+	if err != nil {
+		return err
+	}
+	if installment.SubsidyAmount > 0 {
+		err = putRepayment(ctx, installment, loan.SubsidyProgramID, installment.SubsidyAmount)
+		if err != nil {
+			return err
+		}
+	}
+	err = allocateToSyndicate(ctx, loan, installment)
+	if err != nil {
+		return err
+	}
+
+	loan.PaidInstallments++
+	if loan.PaidInstallments == loan.Term {
+		loan.Status = "Closed"
+	} else {
+		// the next installment accrues from the due date of this one
+		err = fixFloatingRate(ctx, loan, loan.PaidInstallments+1, installment.DueDate)
+		if err != nil {
+			return err
+		}
+	}
+
+	return putLoan(ctx, loan)
+}
+
+// GetRepayments returns every repayment leg recorded against a loan
+func (s *SmartContract) GetRepayments(ctx contractapi.TransactionContextInterface, loanID string) ([]*Repayment, error) {
+	return repaymentRepo.List(ctx, loanID)
+}
+
+func putRepayment(ctx contractapi.TransactionContextInterface, installment *Installment, payer string, amount int) error {
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	repayment := Repayment{
+		LoanID:      installment.LoanID,
+		Installment: installment.Number,
+		Payer:       payer,
+		Amount:      amount,
+		PaidAt:      now.Format(time.RFC3339),
+		TxID:        ctx.GetStub().GetTxID(),
+	}
+	return repaymentRepo.Put(ctx, &repayment, installment.LoanID, fmt.Sprintf("%04d", installment.Number), payer)
+}
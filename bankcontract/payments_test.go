@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"chaincodetest"
+	"chaincodetest/chaincodefakes"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+	"repository"
+)
+
+// retried returns a stub override submitting a function under an idempotency key
+func retried(key string, function string, args ...string) func(stub *chaincodefakes.ChaincodeStub) {
+	return func(stub *chaincodefakes.ChaincodeStub) {
+		stub.GetTransientReturns(map[string][]byte{repository.IdempotencyKeyTransient: []byte(key)}, nil)
+		stub.GetFunctionAndParametersReturns(function, args)
+		invocation := [][]byte{[]byte(function)}
+		for _, arg := range args {
+			invocation = append(invocation, []byte(arg))
+		}
+		stub.GetArgsReturns(invocation)
+	}
+}
+
+func TestRecordPayment(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	pay := func(loanID string, number int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			installment, err := s.RecordPayment(ctx, loanID)
+			if err == nil {
+				require.Equal(t, number, installment.Number)
+				require.Equal(t, installment.Amount, installment.BorrowerAmount)
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{
+			Name:   "create a two month loan",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.CreateLoanApplication(ctx, "loan3", "Bob", 1000, 2, 12)
+			},
+		},
+		{Name: "pay before disbursement", Caller: officer, Run: pay("loan3", 1), Err: "the loan application loan3 is not in repayment, status is Pending"},
+		setStatus("loan3", "Approved"),
+		setStatus("loan3", "Disbursed"),
+		{Name: "only officers and the scheduler record payments", Caller: customer, Run: pay("loan3", 1), Err: "requires role officer or scheduler"},
+		{Name: "pay the first installment", Caller: officer, Run: pay("loan3", 1)},
+		{Name: "pay the last installment", Caller: officer, Run: pay("loan3", 2)},
+		{
+			Name:   "the last installment closes the loan",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				loan, err := s.ReadLoanApplication(ctx, "loan3")
+				if err == nil {
+					require.Equal(t, "Closed", loan.Status)
+					require.Equal(t, 2, loan.PaidInstallments)
+				}
+				return err
+			},
+		},
+		{Name: "pay a closed loan", Caller: officer, Run: pay("loan3", 3), Err: "is not in repayment, status is Closed"},
+		{Name: "pay a missing loan", Caller: officer, Run: pay("loan9", 1), Err: "the loan application loan9 does not exist"},
+		{
+			Name:   "list repayments",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				repayments, err := s.GetRepayments(ctx, "loan3")
+				if err == nil {
+					require.Len(t, repayments, 2)
+					require.Equal(t, payerBorrower, repayments[0].Payer)
+					require.Equal(t, 1, repayments[0].Installment)
+					require.NotEmpty(t, repayments[1].TxID)
+				}
+				return err
+			},
+		},
+	})
+}
+
+func TestRecordPaymentRetry(t *testing.T) {
+	s := new(SmartContract)
+	scheduler := chaincodetest.Identity{MSPID: "Org1MSP", CommonName: "scheduler1", Attributes: map[string]string{"role": "scheduler"}}
+	ledger := newLedger(t)
+
+	pay := func(key string, loanID string, number int, err string) chaincodetest.Case {
+		return chaincodetest.Case{
+			Name:   "pay " + loanID + " under " + key,
+			Caller: officer,
+			Stub:   retried(key, "RecordPayment", loanID),
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				installment, err := s.RecordPayment(ctx, loanID)
+				if err == nil {
+					require.Equal(t, number, installment.Number)
+				}
+				return err
+			},
+			Err: err,
+		}
+	}
+	paid := func(installments int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			loan, err := readLoan(ctx, "loan3")
+			if err == nil {
+				require.Equal(t, installments, loan.PaidInstallments)
+			}
+			return err
+		}
+	}
+	prune := func(pruned int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			n, err := s.PruneIdempotencyKeys(ctx, 10)
+			if err == nil {
+				require.Equal(t, pruned, n)
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		createLoan("loan3", "Bob", 1200),
+		setStatus("loan3", "Approved"),
+		setStatus("loan3", "Disbursed"),
+		pay("k1", "loan3", 1, ""),
+		pay("k1", "loan3", 1, ""),
+		{Name: "a retry settles nothing", Caller: bank, Run: paid(1)},
+		pay("k1", "loan2", 0, "the idempotency key k1 was used for a different request in transaction"),
+		pay("k2", "loan3", 2, ""),
+		{Name: "prune requires scheduler", Caller: ops, Run: prune(0), Err: "requires role scheduler"},
+		{Name: "prune before the keys expire", Caller: scheduler, Run: prune(0)},
+	})
+
+	ledger.Advance(repository.IdempotencyTTL)
+	ledger.Run(t, []chaincodetest.Case{
+		pay("k1", "loan3", 3, ""),
+		{Name: "an expired key starts a new payment", Caller: bank, Run: paid(3)},
+		{Name: "prune the expired keys", Caller: scheduler, Run: prune(1)},
+	})
+}
+
+func TestRecordPaymentFromRepaymentAccount(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+	balances := map[string]int{"acc1": 1100, "acc2": 0}
+	ledger.Install(savingsChaincode, savingsChaincodeOf(balances))
+	bob := chaincodetest.Identity{MSPID: "Org1MSP", CommonName: "Bob", Attributes: map[string]string{"role": "customer"}}
+	lender := base64.StdEncoding.EncodeToString([]byte("x509::CN=officer1,OU=client::CN=ca.Org1MSP"))
+
+	account := func(loanID string, accountRef string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.SetRepaymentAccount(ctx, loanID, accountRef)
+		}
+	}
+	pay := func(loanID string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			collected := balances[lender]
+			installment, err := s.RecordPayment(ctx, loanID)
+			if err == nil {
+				require.Equal(t, installment.BorrowerAmount, balances[lender]-collected)
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{
+			Name:   "create a two month loan",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.CreateLoanApplication(ctx, "loan3", "Bob", 1000, 2, 12)
+			},
+		},
+		{Name: "set an empty account", Caller: officer, Run: account("loan3", ""), Err: "repayment account must not be empty"},
+		{Name: "set the account of a missing loan", Caller: officer, Run: account("loan9", "acc1"), Err: "does not exist"},
+		{Name: "only the applicant or officers set the account", Caller: customer, Run: account("loan3", "acc1"), Err: "requires the applicant of loan application loan3 or role officer"},
+		{Name: "the applicant sets the account", Caller: bob, Run: account("loan3", "acc1")},
+		setStatus("loan3", "Approved"),
+		setStatus("loan3", "Disbursed"),
+		{Name: "pay the first installment from the account", Caller: officer, Run: pay("loan3")},
+		{
+			Name:   "the installment moved to the lender",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				require.Positive(t, balances[lender])
+				require.Equal(t, 1100, balances["acc1"]+balances[lender])
+				return nil
+			},
+		},
+		{Name: "switch to an empty account", Caller: officer, Run: account("loan3", "acc2")},
+		{Name: "pay from an empty account", Caller: officer, Run: pay("loan3"), Err: "client account acc2 has insufficient funds"},
+		{
+			Name:   "a failed transfer leaves the installment unpaid",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				loan, err := s.ReadLoanApplication(ctx, "loan3")
+				if err == nil {
+					require.Equal(t, 1, loan.PaidInstallments)
+					require.Equal(t, "Disbursed", loan.Status)
+				}
+				return err
+			},
+		},
+		{Name: "switch back", Caller: officer, Run: account("loan3", "acc1")},
+		{Name: "pay the last installment from the account", Caller: officer, Run: pay("loan3")},
+		{Name: "set the account of a closed loan", Caller: officer, Run: account("loan3", "acc1"), Err: "the loan application loan3 is Closed"},
+	})
+}
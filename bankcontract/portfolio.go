@@ -0,0 +1,407 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+const (
+	// portfolioTransferObjectType prefixes the composite keys of portfolio transfers
+	portfolioTransferObjectType = "portfoliotransfer"
+
+	portfolioTransferJob = "portfolio-transfer"
+)
+
+var portfolioTransferRepo = repository.New[PortfolioTransfer](portfolioTransferObjectType)
+
+// PortfolioTransfer is the sale of a book of loans to another organization. The loans owned by the
+// selling org that match the filter change hands in batches: the seller stages each batch, and it
+// only commits once an ops client of the acquiring org accepts it in a transaction of its own.
+// The transfer is driven by a job of the same ID, whose cursor is where the next batch starts.
+type PortfolioTransfer struct {
+	ID          string          `json:"id"`
+	Filter      string          `json:"filter"`
+	SellerMSP   string          `json:"sellerMsp"`
+	BuyerMSP    string          `json:"buyerMsp"`
+	Status      string          `json:"status"`                               // Running, Completed or Aborted, as its job
+	Batch       *PortfolioBatch `json:"batch,omitempty" metadata:",optional"` // the staged batch awaiting acceptance
+	Batches     int             `json:"batches"`                              // batches committed so far
+	Transferred int             `json:"transferred"`                          // loans committed so far
+	ProposedBy  string          `json:"proposedBy"`
+	ProposedAt  string          `json:"proposedAt"`
+}
+
+// PortfolioBatch is a page of loans staged for transfer. The digest covers the loans as they were
+// staged, so the acquiring org accepts exactly the loans it reviewed.
+type PortfolioBatch struct {
+	Number   int      `json:"number"`
+	LoanIDs  []string `json:"loanIds"`
+	Scanned  int      `json:"scanned"` // loans looked at to fill the batch
+	Cursor   string   `json:"cursor"`  // where the job continues once the batch commits
+	Digest   string   `json:"digest"`
+	StagedBy string   `json:"stagedBy"`
+	StagedAt string   `json:"stagedAt"`
+}
+
+// loanFilter selects loans by the values of their JSON fields, for example
+// {"status":"Disbursed","currency":"EUR"}. The empty filter selects every loan.
+type loanFilter map[string]interface{}
+
+// TransferPortfolio starts the sale of the loans of the submitting client's org matching filter
+// to the org targetOrgMSP. Batches are then staged with StagePortfolioBatch and accepted by the
+// acquiring org with AcceptPortfolioBatch. Only one portfolio transfer may run at a time.
+// Restricted to the ops role.
+func (s *SmartContract) TransferPortfolio(ctx contractapi.TransactionContextInterface, transferID string, filter string, targetOrgMSP string) (*PortfolioTransfer, error) {
+	err := requireRole(ctx, "ops")
+	if err != nil {
+		return nil, err
+	}
+	operator, mspID, err := operatorName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if targetOrgMSP == "" || targetOrgMSP == mspID {
+		return nil, fmt.Errorf("the portfolio must be transferred to another org")
+	}
+	_, err = parseLoanFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	err = startJob(ctx, transferID, portfolioTransferJob)
+	if err != nil {
+		return nil, err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	transfer := PortfolioTransfer{
+		ID:         transferID,
+		Filter:     filter,
+		SellerMSP:  mspID,
+		BuyerMSP:   targetOrgMSP,
+		Status:     jobRunning,
+		ProposedBy: operator,
+		ProposedAt: now.Format(time.RFC3339),
+	}
+	err = portfolioTransferRepo.Put(ctx, &transfer, transferID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &transfer, nil
+}
+
+// StagePortfolioBatch looks at the next pageSize loans of a portfolio transfer and stages those
+// the transfer covers as a batch for the acquiring org to accept, replacing any batch still
+// awaiting acceptance. A page without such loans is skipped at once. Restricted to the ops role
+// of the selling org.
+func (s *SmartContract) StagePortfolioBatch(ctx contractapi.TransactionContextInterface, transferID string, pageSize int) (*PortfolioTransfer, error) {
+	err := requireRole(ctx, "ops")
+	if err != nil {
+		return nil, err
+	}
+	if pageSize <= 0 {
+		return nil, fmt.Errorf("pageSize must be positive")
+	}
+	transfer, job, err := runningPortfolioTransfer(ctx, transferID)
+	if err != nil {
+		return nil, err
+	}
+	operator, mspID, err := operatorName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if mspID != transfer.SellerMSP {
+		return nil, fmt.Errorf("only the selling org %s stages batches of portfolio transfer %s", transfer.SellerMSP, transferID)
+	}
+	filter, err := parseLoanFilter(transfer.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// paginated queries are not allowed in update transactions, so the page is bounded by hand
+	resultsIterator, err := ctx.GetStub().GetStateByRange(loanNamespace.Range(job.Cursor))
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	batch := &PortfolioBatch{Number: transfer.Batches + 1, StagedBy: operator}
+	var loans []*LoanApplication
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		if batch.Scanned == pageSize {
+			batch.Cursor, _ = loanNamespace.ID(queryResponse.Key)
+			break
+		}
+		batch.Scanned++
+
+		var loan LoanApplication
+		err = json.Unmarshal(queryResponse.Value, &loan)
+		if err != nil {
+			return nil, err
+		}
+		matches, err := filter.matches(&loan)
+		if err != nil {
+			return nil, err
+		}
+		if loan.OwnerMSP == transfer.SellerMSP && matches {
+			loans = append(loans, &loan)
+			batch.LoanIDs = append(batch.LoanIDs, loan.ID)
+		}
+	}
+
+	if len(loans) == 0 {
+		transfer.Batch = nil
+		return transfer, commitPortfolioPage(ctx, transfer, job, batch)
+	}
+
+	batch.Digest, err = portfolioDigest(loans)
+	if err != nil {
+		return nil, err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	batch.StagedAt = now.Format(time.RFC3339)
+	transfer.Batch = batch
+	err = portfolioTransferRepo.Put(ctx, transfer, transferID)
+	if err != nil {
+		return nil, err
+	}
+
+	return transfer, nil
+}
+
+// AcceptPortfolioBatch commits the staged batch of a portfolio transfer on behalf of the acquiring
+// org. digest must be the digest of the staged batch, and the batch is refused if any of its loans
+// changed since it was staged. Each loan is reassigned to the acquiring org and gets a key-level
+// endorsement policy requiring its peers, so later changes need the new owner's endorsement.
+// Loans the seller acquired in an earlier sale carry its own key-level policy, so accepting them
+// needs endorsements from peers of both orgs. Restricted to the ops role of the acquiring org.
+func (s *SmartContract) AcceptPortfolioBatch(ctx contractapi.TransactionContextInterface, transferID string, digest string) (*PortfolioTransfer, error) {
+	err := requireRole(ctx, "ops")
+	if err != nil {
+		return nil, err
+	}
+	transfer, job, err := runningPortfolioTransfer(ctx, transferID)
+	if err != nil {
+		return nil, err
+	}
+	_, mspID, err := operatorName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if mspID != transfer.BuyerMSP {
+		return nil, fmt.Errorf("only the acquiring org %s accepts batches of portfolio transfer %s", transfer.BuyerMSP, transferID)
+	}
+	batch := transfer.Batch
+	if batch == nil {
+		return nil, fmt.Errorf("the portfolio transfer %s has no batch awaiting acceptance", transferID)
+	}
+	if digest != batch.Digest {
+		return nil, fmt.Errorf("batch %d of portfolio transfer %s has digest %s, not %s", batch.Number, transferID, batch.Digest, digest)
+	}
+
+	loans := make([]*LoanApplication, 0, len(batch.LoanIDs))
+	for _, loanID := range batch.LoanIDs {
+		loan, err := readLoan(ctx, loanID)
+		if err != nil {
+			return nil, err
+		}
+		loans = append(loans, loan)
+	}
+	current, err := portfolioDigest(loans)
+	if err != nil {
+		return nil, err
+	}
+	if current != batch.Digest {
+		return nil, fmt.Errorf("loans of batch %d of portfolio transfer %s changed since it was staged, it must be staged again", batch.Number, transferID)
+	}
+
+	for _, loan := range loans {
+		loan.OwnerMSP = transfer.BuyerMSP
+		err = putLoan(ctx, loan)
+		if err != nil {
+			return nil, err
+		}
+		err = setLoanEndorsers(ctx, loan.ID, loanEndorsers(loan)...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	transfer.Batch = nil
+	transfer.Batches++
+	transfer.Transferred += len(loans)
+	err = commitPortfolioPage(ctx, transfer, job, batch)
+	if err != nil {
+		return nil, err
+	}
+
+	return transfer, nil
+}
+
+// CancelPortfolioTransfer aborts a running portfolio transfer, dropping any batch awaiting
+// acceptance. Batches already accepted stay with the acquiring org. Restricted to the ops role of
+// either org.
+func (s *SmartContract) CancelPortfolioTransfer(ctx contractapi.TransactionContextInterface, transferID string, reason string) error {
+	err := requireRole(ctx, "ops")
+	if err != nil {
+		return err
+	}
+	transfer, job, err := runningPortfolioTransfer(ctx, transferID)
+	if err != nil {
+		return err
+	}
+	_, mspID, err := operatorName(ctx)
+	if err != nil {
+		return err
+	}
+	if mspID != transfer.SellerMSP && mspID != transfer.BuyerMSP {
+		return fmt.Errorf("only the orgs party to portfolio transfer %s may cancel it", transferID)
+	}
+
+	job.Status = jobAborted
+	job.Reason = reason
+	transfer.Status = jobAborted
+	transfer.Batch = nil
+	err = portfolioTransferRepo.Put(ctx, transfer, transferID)
+	if err != nil {
+		return err
+	}
+
+	return finishJobStep(ctx, job)
+}
+
+// ReadPortfolioTransfer returns a portfolio transfer with its batch awaiting acceptance, if any
+func (s *SmartContract) ReadPortfolioTransfer(ctx contractapi.TransactionContextInterface, transferID string) (*PortfolioTransfer, error) {
+	transfer, err := portfolioTransferRepo.Get(ctx, transferID)
+	if err != nil {
+		return nil, err
+	}
+	if transfer == nil {
+		return nil, fmt.Errorf("the portfolio transfer %s does not exist", transferID)
+	}
+
+	return transfer, nil
+}
+
+// runningPortfolioTransfer returns a portfolio transfer together with its job, which must be running
+func runningPortfolioTransfer(ctx contractapi.TransactionContextInterface, transferID string) (*PortfolioTransfer, *Job, error) {
+	transfer, err := portfolioTransferRepo.Get(ctx, transferID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if transfer == nil {
+		return nil, nil, fmt.Errorf("the portfolio transfer %s does not exist", transferID)
+	}
+	job, err := getJob(ctx, transferID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if job == nil || job.Kind != portfolioTransferJob {
+		return nil, nil, fmt.Errorf("the portfolio transfer %s has no job", transferID)
+	}
+	if job.Status != jobRunning {
+		return nil, nil, fmt.Errorf("the portfolio transfer %s is %s", transferID, job.Status)
+	}
+
+	return transfer, job, nil
+}
+
+// commitPortfolioPage moves the job of a portfolio transfer past the page of a batch and saves
+// both, completing the transfer after its last page
+func commitPortfolioPage(ctx contractapi.TransactionContextInterface, transfer *PortfolioTransfer, job *Job, batch *PortfolioBatch) error {
+	job.Cursor = batch.Cursor
+	job.Pages++
+	job.Processed += batch.Scanned
+	if batch.Cursor == "" {
+		job.Status = jobCompleted
+	}
+	transfer.Status = job.Status
+
+	err := portfolioTransferRepo.Put(ctx, transfer, transfer.ID)
+	if err != nil {
+		return err
+	}
+
+	return finishJobStep(ctx, job)
+}
+
+// portfolioDigest returns the hex SHA-256 digest of the JSON of the loans in order
+func portfolioDigest(loans []*LoanApplication) (string, error) {
+	h := sha256.New()
+	for _, loan := range loans {
+		loanJSON, err := json.Marshal(loan)
+		if err != nil {
+			return "", err
+		}
+		h.Write(loanJSON)
+		h.Write([]byte{'\n'})
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// parseLoanFilter parses a JSON loan filter, refusing fields a loan does not have
+func parseLoanFilter(filter string) (loanFilter, error) {
+	parsed := loanFilter{}
+	if filter == "" {
+		return parsed, nil
+	}
+	err := json.Unmarshal([]byte(filter), &parsed)
+	if err != nil {
+		return nil, fmt.Errorf("invalid loan filter: %v", err)
+	}
+
+	fields := make(map[string]bool)
+	loanType := reflect.TypeOf(LoanApplication{})
+	for i := 0; i < loanType.NumField(); i++ {
+		name, _, _ := strings.Cut(loanType.Field(i).Tag.Get("json"), ",")
+		fields[name] = true
+	}
+	for field := range parsed {
+		if !fields[field] {
+			return nil, fmt.Errorf("loans have no field %s to filter by", field)
+		}
+	}
+
+	return parsed, nil
+}
+
+// matches reports whether a loan holds the filter's value in every field the filter names
+func (f loanFilter) matches(loan *LoanApplication) (bool, error) {
+	loanJSON, err := json.Marshal(loan)
+	if err != nil {
+		return false, err
+	}
+	var fields map[string]interface{}
+	err = json.Unmarshal(loanJSON, &fields)
+	if err != nil {
+		return false, err
+	}
+
+	for field, value := range f {
+		if !reflect.DeepEqual(fields[field], value) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-chaincode-go/pkg/statebased"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPortfolioTransfers(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+	buyer := chaincodetest.Identity{MSPID: "Org2MSP", CommonName: "ops2", Attributes: map[string]string{"role": "ops"}}
+	outsider := chaincodetest.Identity{MSPID: "Org3MSP", CommonName: "ops3", Attributes: map[string]string{"role": "ops"}}
+
+	propose := func(transferID string, filter string, targetOrgMSP string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.TransferPortfolio(ctx, transferID, filter, targetOrgMSP)
+			return err
+		}
+	}
+	stage := func(transferID string, want ...string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			transfer, err := s.StagePortfolioBatch(ctx, transferID, 2)
+			if err == nil && want != nil {
+				require.Equal(t, want, transfer.Batch.LoanIDs)
+			}
+			if err == nil && want == nil {
+				require.Nil(t, transfer.Batch)
+			}
+			return err
+		}
+	}
+	accept := func(transferID string, digest string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.AcceptPortfolioBatch(ctx, transferID, digest)
+			return err
+		}
+	}
+	acceptStaged := func(transferID string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			transfer, err := s.ReadPortfolioTransfer(ctx, transferID)
+			require.NoError(t, err)
+			require.NotNil(t, transfer.Batch)
+			_, err = s.AcceptPortfolioBatch(ctx, transferID, transfer.Batch.Digest)
+			return err
+		}
+	}
+	cancel := func(transferID string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.CancelPortfolioTransfer(ctx, transferID, "deal fell through")
+		}
+	}
+	ownedBy := func(loanID string, mspID string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			loan, err := s.ReadLoanApplication(ctx, loanID)
+			if err == nil {
+				require.Equal(t, mspID, loan.OwnerMSP)
+			}
+			return err
+		}
+	}
+	endorsedBy := func(loanID string, want ...string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			policy, err := ctx.GetStub().GetStateValidationParameter(loanNamespace.Key(loanID))
+			require.NoError(t, err)
+			if want == nil {
+				require.Nil(t, policy)
+				return nil
+			}
+			endorsementPolicy, err := statebased.NewStateEP(policy)
+			require.NoError(t, err)
+			require.Equal(t, want, endorsementPolicy.ListOrgs())
+			return nil
+		}
+	}
+	const pending = `{"status":"Pending"}`
+
+	ledger.Run(t, []chaincodetest.Case{
+		createLoan("loan3", "Bob", 2000),
+		{Name: "loans are held by the org creating them", Caller: bank, Run: ownedBy("loan3", "Org1MSP")},
+		{Name: "transfer requires ops", Caller: officer, Run: propose("t1", pending, "Org2MSP"), Err: "requires role ops"},
+		{Name: "transfer to one's own org", Caller: ops, Run: propose("t1", pending, "Org1MSP"), Err: "the portfolio must be transferred to another org"},
+		{Name: "transfer with a malformed filter", Caller: ops, Run: propose("t1", "{", "Org2MSP"), Err: "invalid loan filter"},
+		{Name: "transfer by an unknown field", Caller: ops, Run: propose("t1", `{"colour":"red"}`, "Org2MSP"), Err: "loans have no field colour to filter by"},
+		{Name: "transfer", Caller: ops, Run: propose("t1", pending, "Org2MSP")},
+		{Name: "one transfer at a time", Caller: ops, Run: propose("t2", "", "Org3MSP"), Err: "a portfolio-transfer job is already running as t1, resume it instead"},
+		{Name: "accept before a batch is staged", Caller: buyer, Run: accept("t1", ""), Err: "the portfolio transfer t1 has no batch awaiting acceptance"},
+		{Name: "the buyer cannot stage", Caller: buyer, Run: stage("t1"), Err: "only the selling org Org1MSP stages batches of portfolio transfer t1"},
+		{Name: "stage the first page", Caller: ops, Run: stage("t1", "loan1")},
+		{Name: "staged loans stay with the seller", Caller: bank, Run: ownedBy("loan1", "Org1MSP")},
+		{Name: "the seller cannot accept", Caller: ops, Run: acceptStaged("t1"), Err: "only the acquiring org Org2MSP accepts batches of portfolio transfer t1"},
+		{Name: "accept another digest", Caller: buyer, Run: accept("t1", "abc"), Err: "batch 1 of portfolio transfer t1 has digest"},
+		{Name: "accept the first batch", Caller: buyer, Run: acceptStaged("t1")},
+		{Name: "accepted loans change hands", Caller: bank, Run: ownedBy("loan1", "Org2MSP")},
+		{Name: "accepted loans are endorsed by the buyer", Caller: bank, Run: endorsedBy("loan1", "Org2MSP")},
+		{Name: "loans left out keep their owner", Caller: bank, Run: ownedBy("loan2", "Org1MSP")},
+		{Name: "loans left out keep the chaincode policy", Caller: bank, Run: endorsedBy("loan2")},
+		{Name: "stage the last page", Caller: ops, Run: stage("t1", "loan3")},
+		{
+			Name:   "change a staged loan",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				loan, err := readLoan(ctx, "loan3")
+				require.NoError(t, err)
+				loan.InterestRate = 7
+				return putLoan(ctx, loan)
+			},
+		},
+		{Name: "accept a changed batch", Caller: buyer, Run: acceptStaged("t1"), Err: "loans of batch 2 of portfolio transfer t1 changed since it was staged, it must be staged again"},
+		{Name: "stage the last page again", Caller: ops, Run: stage("t1", "loan3")},
+		{Name: "accept the last batch", Caller: buyer, Run: acceptStaged("t1")},
+		{
+			Name:   "the transfer completes with its last batch",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				transfer, err := s.ReadPortfolioTransfer(ctx, "t1")
+				if err == nil {
+					require.Equal(t, jobCompleted, transfer.Status)
+					require.Equal(t, 2, transfer.Batches)
+					require.Equal(t, 2, transfer.Transferred)
+					require.Nil(t, transfer.Batch)
+				}
+				return err
+			},
+		},
+		{Name: "stage a completed transfer", Caller: ops, Run: stage("t1"), Err: "the portfolio transfer t1 is Completed"},
+		{Name: "transfer loans the seller does not hold", Caller: ops, Run: propose("t2", pending, "Org3MSP")},
+		{Name: "pages without loans to transfer are skipped", Caller: ops, Run: stage("t2")},
+		{Name: "the last page completes the transfer", Caller: ops, Run: stage("t2")},
+		{Name: "transfer onwards", Caller: buyer, Run: propose("t3", "", "Org1MSP")},
+		{Name: "outsiders cannot cancel", Caller: outsider, Run: cancel("t3"), Err: "only the orgs party to portfolio transfer t3 may cancel it"},
+		{Name: "cancel", Caller: ops, Run: cancel("t3")},
+		{Name: "stage a cancelled transfer", Caller: buyer, Run: stage("t3"), Err: "the portfolio transfer t3 is Aborted"},
+		{
+			Name:   "read missing transfer",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.ReadPortfolioTransfer(ctx, "t9")
+				return err
+			},
+			Err: "the portfolio transfer t9 does not exist",
+		},
+	})
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+const (
+	// productObjectType prefixes the composite keys of loan products
+	productObjectType = "product"
+
+	productActive = "Active"
+	// productSunsetting products still accept applications until their sunset date
+	productSunsetting = "Sunsetting"
+	productRetired    = "Retired"
+)
+
+var productRepo = repository.New[LoanProduct](productObjectType)
+
+// LoanProduct is a loan product of the catalog. Loans taken out under a product copy its terms,
+// so they keep them after the product changes or is retired. Status is derived from the sunset
+// date when the product is read. Floating-rate products have a benchmark tenor and a spread
+// instead of an interest rate, and Murabaha products a profit markup.
+type LoanProduct struct {
+	ID           string  `json:"id"`
+	Name         string  `json:"name"`
+	InterestRate float64 `json:"interestRate"`
+	MaxAmount    int     `json:"maxAmount"`
+	MaxTerm      int     `json:"maxTerm"` // in months
+	Status       string  `json:"status"`
+	SunsetDate   string  `json:"sunsetDate"` // first date on which applications are refused
+	RetiredBy    string  `json:"retiredBy"`
+	CreatedBy    string  `json:"createdBy"`
+	CreatedAt    string  `json:"createdAt"`
+
+	BenchmarkTenor string  `json:"benchmarkTenor,omitempty" metadata:",optional"` // set on floating-rate products
+	Spread         float64 `json:"spread,omitempty" metadata:",optional"`         // over the benchmark, in percentage points
+
+	ProductType  string  `json:"productType,omitempty" metadata:",optional"`  // Murabaha, or empty for conventional products
+	ProfitMarkup float64 `json:"profitMarkup,omitempty" metadata:",optional"` // of Murabaha products, in percent of the cost
+}
+
+// CreateProduct adds a fixed-rate loan product to the catalog. Restricted to the ops role.
+func (s *SmartContract) CreateProduct(ctx contractapi.TransactionContextInterface, id string, name string, interestRate float64, maxAmount int, maxTerm int) error {
+	if interestRate <= 0 {
+		return fmt.Errorf("interest rate must be positive")
+	}
+
+	return createProduct(ctx, &LoanProduct{
+		ID:           id,
+		Name:         name,
+		InterestRate: interestRate,
+		MaxAmount:    maxAmount,
+		MaxTerm:      maxTerm,
+	})
+}
+
+// CreateFloatingRateProduct adds a loan product to the catalog whose loans accrue interest at the
+// benchmark rate of a tenor published by the RateOracle plus a spread. The rate is fixed when a loan
+// is disbursed and again as each installment starts accruing. Restricted to the ops role.
+func (s *SmartContract) CreateFloatingRateProduct(ctx contractapi.TransactionContextInterface, id string, name string, benchmarkTenor string, spread float64, maxAmount int, maxTerm int) error {
+	if benchmarkTenor == "" {
+		return fmt.Errorf("benchmark tenor must not be empty")
+	}
+	if spread < 0 {
+		return fmt.Errorf("spread must not be negative")
+	}
+
+	return createProduct(ctx, &LoanProduct{
+		ID:             id,
+		Name:           name,
+		MaxAmount:      maxAmount,
+		MaxTerm:        maxTerm,
+		BenchmarkTenor: benchmarkTenor,
+		Spread:         spread,
+	})
+}
+
+// createProduct adds a product to the catalog on behalf of ops
+func createProduct(ctx contractapi.TransactionContextInterface, product *LoanProduct) error {
+	err := requireRole(ctx, "ops")
+	if err != nil {
+		return err
+	}
+	id := product.ID
+	if id == "" || product.Name == "" {
+		return fmt.Errorf("product ID and name must not be empty")
+	}
+	if id == standardProduct {
+		return fmt.Errorf("%s is reserved for loans outside the catalog", standardProduct)
+	}
+	if product.MaxAmount <= 0 || product.MaxTerm <= 0 {
+		return fmt.Errorf("max amount and max term must be positive")
+	}
+	existing, err := getProduct(ctx, id)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("the product %s already exists", id)
+	}
+
+	operator, _, err := operatorName(ctx)
+	if err != nil {
+		return err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	product.CreatedBy = operator
+	product.CreatedAt = now.Format(time.RFC3339)
+
+	return putProduct(ctx, product)
+}
+
+// RetireProduct sets the date from which a product no longer accepts applications. Loans already
+// taken out under the product are unaffected. The sunset date may be brought forward or pushed
+// back until it has passed, but not before today. Restricted to the ops role.
+func (s *SmartContract) RetireProduct(ctx contractapi.TransactionContextInterface, productID string, sunsetDate string) (*LoanProduct, error) {
+	err := requireRole(ctx, "ops")
+	if err != nil {
+		return nil, err
+	}
+	sunset, err := time.Parse("2006-01-02", sunsetDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sunset date %q, expected YYYY-MM-DD", sunsetDate)
+	}
+
+	product, err := s.ReadProduct(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	today := now.Format("2006-01-02")
+	if product.Status == productRetired {
+		return nil, fmt.Errorf("the product %s was retired on %s", productID, product.SunsetDate)
+	}
+	if sunset.Format("2006-01-02") < today {
+		return nil, fmt.Errorf("the sunset date %s has already passed", sunsetDate)
+	}
+
+	operator, _, err := operatorName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	product.SunsetDate = sunset.Format("2006-01-02")
+	product.RetiredBy = operator
+	err = putProduct(ctx, product)
+	if err != nil {
+		return nil, err
+	}
+	product.Status = productStatus(product, today)
+
+	return product, nil
+}
+
+// ReadProduct returns a loan product
+func (s *SmartContract) ReadProduct(ctx contractapi.TransactionContextInterface, id string) (*LoanProduct, error) {
+	product, err := getProduct(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if product == nil {
+		return nil, fmt.Errorf("the product %s does not exist", id)
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	product.Status = productStatus(product, now.Format("2006-01-02"))
+
+	return product, nil
+}
+
+// GetProducts returns the catalog's products with the given status: Active, Sunsetting or
+// Retired. Active products include sunsetting ones, which still accept applications; an empty
+// status returns every product.
+func (s *SmartContract) GetProducts(ctx contractapi.TransactionContextInterface, status string) ([]*LoanProduct, error) {
+	switch status {
+	case "", productActive, productSunsetting, productRetired:
+	default:
+		return nil, fmt.Errorf("unknown product status %q", status)
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	today := now.Format("2006-01-02")
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(productObjectType, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var products []*LoanProduct
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var product LoanProduct
+		err = json.Unmarshal(queryResponse.Value, &product)
+		if err != nil {
+			return nil, err
+		}
+		product.Status = productStatus(&product, today)
+		if status == "" || product.Status == status || (status == productActive && product.Status == productSunsetting) {
+			products = append(products, &product)
+		}
+	}
+
+	return products, nil
+}
+
+// ApplyForProduct adds a new loan application under a catalog product, at the product's interest
+// rate. Products accept applications until their sunset date. A floating-rate loan is quoted at the
+// benchmark rate in force today plus the spread, until its rate is fixed on disbursement.
+func (s *SmartContract) ApplyForProduct(ctx contractapi.TransactionContextInterface, id string, applicant string, productID string, amount int, term int) error {
+	product, err := s.ReadProduct(ctx, productID)
+	if err != nil {
+		return err
+	}
+	if product.Status == productRetired {
+		return fmt.Errorf("the product %s was retired on %s and accepts no new applications", productID, product.SunsetDate)
+	}
+	if amount <= 0 || amount > product.MaxAmount {
+		return fmt.Errorf("the amount must be between 1 and %d for product %s", product.MaxAmount, productID)
+	}
+	if term <= 0 || term > product.MaxTerm {
+		return fmt.Errorf("the term must be between 1 and %d months for product %s", product.MaxTerm, productID)
+	}
+
+	loan := LoanApplication{
+		ID:           id,
+		Applicant:    applicant,
+		Amount:       amount,
+		Currency:     defaultCurrency,
+		Term:         term,
+		InterestRate: product.InterestRate,
+		Status:       "Pending",
+		ProductID:    productID,
+	}
+	if product.ProductType == productTypeMurabaha {
+		loan.ProductType = productTypeMurabaha
+		loan.ProfitMarkup = product.ProfitMarkup
+	}
+	if product.BenchmarkTenor != "" {
+		now, err := txTime(ctx)
+		if err != nil {
+			return err
+		}
+		benchmark, err := benchmarkRateOn(ctx, product.BenchmarkTenor, now.Format("2006-01-02"))
+		if err != nil {
+			return err
+		}
+		loan.InterestRate = benchmark.Rate + product.Spread
+		loan.BenchmarkTenor = product.BenchmarkTenor
+		loan.Spread = product.Spread
+	}
+
+	return s.createLoan(ctx, &loan)
+}
+
+// productStatus derives a product's status on the given date
+func productStatus(product *LoanProduct, today string) string {
+	switch {
+	case product.SunsetDate == "":
+		return productActive
+	case today < product.SunsetDate:
+		return productSunsetting
+	}
+	return productRetired
+}
+
+func getProduct(ctx contractapi.TransactionContextInterface, id string) (*LoanProduct, error) {
+	return productRepo.Get(ctx, id)
+}
+
+func putProduct(ctx contractapi.TransactionContextInterface, product *LoanProduct) error {
+	productKey, err := ctx.GetStub().CreateCompositeKey(productObjectType, []string{product.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	stored := *product
+	stored.Status = ""
+	productJSON, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(productKey, productJSON)
+}
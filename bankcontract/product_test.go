@@ -0,0 +1,100 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoanProducts(t *testing.T) {
+	s := new(SmartContract)
+	ledger := chaincodetest.NewLedger()
+
+	create := func(id string, interestRate float64, maxAmount int, maxTerm int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.CreateProduct(ctx, id, "Product "+id, interestRate, maxAmount, maxTerm)
+		}
+	}
+	retire := func(id string, sunsetDate string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.RetireProduct(ctx, id, sunsetDate)
+			return err
+		}
+	}
+	apply := func(id string, productID string, amount int, term int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.ApplyForProduct(ctx, id, "Bob", productID, amount, term)
+		}
+	}
+	products := func(status string, ids ...string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			products, err := s.GetProducts(ctx, status)
+			if err == nil {
+				var got []string
+				for _, product := range products {
+					got = append(got, product.ID)
+				}
+				require.Equal(t, ids, got)
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "create requires ops", Caller: bank, Run: create("auto", 9, 50000, 36), Err: "requires role ops"},
+		{Name: "create without a name", Caller: ops, Run: create("", 9, 50000, 36), Err: "product ID and name must not be empty"},
+		{Name: "create the standard product", Caller: ops, Run: create(standardProduct, 9, 50000, 36), Err: "is reserved for loans outside the catalog"},
+		{Name: "create without interest", Caller: ops, Run: create("auto", 0, 50000, 36), Err: "interest rate must be positive"},
+		{Name: "create without a limit", Caller: ops, Run: create("auto", 9, 50000, 0), Err: "max amount and max term must be positive"},
+		{Name: "create", Caller: ops, Run: create("auto", 9, 50000, 36)},
+		{Name: "create another", Caller: ops, Run: create("home", 7, 500000, 240)},
+		{Name: "create twice", Caller: ops, Run: create("auto", 9, 50000, 36), Err: "the product auto already exists"},
+		{Name: "apply above the maximum amount", Caller: bank, Run: apply("loan1", "auto", 60000, 12), Err: "the amount must be between 1 and 50000 for product auto"},
+		{Name: "apply beyond the maximum term", Caller: bank, Run: apply("loan1", "auto", 20000, 48), Err: "the term must be between 1 and 36 months for product auto"},
+		{Name: "apply for a missing product", Caller: bank, Run: apply("loan1", "boat", 20000, 12), Err: "the product boat does not exist"},
+		{Name: "apply", Caller: bank, Run: apply("loan1", "auto", 20000, 12)},
+		{
+			Name:   "loans copy the product's terms",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				loan, err := s.ReadLoanApplication(ctx, "loan1")
+				if err == nil {
+					require.Equal(t, "auto", loan.ProductID)
+					require.Equal(t, 9.0, loan.InterestRate)
+				}
+				return err
+			},
+		},
+		{Name: "retire requires ops", Caller: bank, Run: retire("auto", "2024-02-01"), Err: "requires role ops"},
+		{Name: "retire with an invalid date", Caller: ops, Run: retire("auto", "02/2024"), Err: `invalid sunset date "02/2024"`},
+		{Name: "retire in the past", Caller: ops, Run: retire("auto", "2023-12-31"), Err: "the sunset date 2023-12-31 has already passed"},
+		{Name: "retire", Caller: ops, Run: retire("auto", "2024-02-01")},
+		{Name: "sunsetting products count as active", Caller: bank, Run: products(productActive, "auto", "home")},
+		{Name: "list sunsetting products", Caller: bank, Run: products(productSunsetting, "auto")},
+		{Name: "list by unknown status", Caller: bank, Run: products("Draft"), Err: `unknown product status "Draft"`},
+		{Name: "apply while sunsetting", Caller: bank, Run: apply("loan2", "auto", 20000, 12)},
+	})
+
+	ledger.Advance(31 * 24 * time.Hour)
+	ledger.Run(t, []chaincodetest.Case{
+		{
+			Name:   "products retire on their sunset date",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				product, err := s.ReadProduct(ctx, "auto")
+				if err == nil {
+					require.Equal(t, productRetired, product.Status)
+					require.Equal(t, "ops1", product.RetiredBy)
+				}
+				return err
+			},
+		},
+		{Name: "list retired products", Caller: bank, Run: products(productRetired, "auto")},
+		{Name: "list every product", Caller: bank, Run: products("", "auto", "home")},
+		{Name: "apply for a retired product", Caller: bank, Run: apply("loan3", "auto", 20000, 12), Err: "the product auto was retired on 2024-02-01 and accepts no new applications"},
+		{Name: "retire a retired product", Caller: ops, Run: retire("auto", "2024-03-01"), Err: "the product auto was retired on 2024-02-01"},
+	})
+}
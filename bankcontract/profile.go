@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// CustomerProfile is the single customer view: the identity registered under a CNIC as the
+// identity chaincode returns it to the caller, with the customer's loans and their repayments
+type CustomerProfile struct {
+	CNIC        string                  `json:"cnic"`
+	IdentityID  string                  `json:"identityId"`
+	KYCLevel    string                  `json:"kycLevel"`
+	Identity    json.RawMessage         `json:"identity"`
+	Loans       []*LoanApplication      `json:"loans"`
+	Repayments  map[string][]*Repayment `json:"repayments"` // by loan ID
+	Balances    []*ControlTotals        `json:"balances"`   // disbursed and outstanding principal per currency
+	GeneratedAt string                  `json:"generatedAt"`
+}
+
+// GetCustomerProfile returns the CustomerProfile of the identity registered under a CNIC as a
+// single JSON document. The identity is looked up on the identity chaincode as the submitting
+// client, so its consent checks decide whether the identity, and with it the profile, may be read.
+// Restricted to officer and auditor roles.
+func (s *SmartContract) GetCustomerProfile(ctx contractapi.TransactionContextInterface, cnic string) (string, error) {
+	err := requireRole(ctx, "officer", "auditor")
+	if err != nil {
+		return "", err
+	}
+	if cnic == "" {
+		return "", fmt.Errorf("CNIC must not be empty")
+	}
+
+	identityJSON, err := queryIdentityChaincode(ctx, "GetIdentityByNIC", cnic)
+	if err != nil {
+		return "", err
+	}
+	var identity struct {
+		ID       string `json:"id"`
+		KYCLevel string `json:"kycLevel"`
+	}
+	err = json.Unmarshal(identityJSON, &identity)
+	if err != nil || identity.ID == "" {
+		return "", fmt.Errorf("unexpected identity returned by %s for CNIC %s", identityChaincode, cnic)
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return "", err
+	}
+	profile := CustomerProfile{
+		CNIC:        cnic,
+		IdentityID:  identity.ID,
+		KYCLevel:    identity.KYCLevel,
+		Identity:    identityJSON,
+		Repayments:  make(map[string][]*Repayment),
+		GeneratedAt: now.Format(time.RFC3339),
+	}
+
+	profile.Loans, err = loansByApplicant(ctx, identity.ID)
+	if err != nil {
+		return "", err
+	}
+	balances := make(map[string]*ControlTotals)
+	for _, loan := range profile.Loans {
+		repayments, err := repaymentRepo.List(ctx, loan.ID)
+		if err != nil {
+			return "", err
+		}
+		if len(repayments) > 0 {
+			profile.Repayments[loan.ID] = repayments
+		}
+
+		figures, err := loanFigures(loan)
+		if err != nil {
+			return "", err
+		}
+		addControlTotals(balances, figures, 1)
+	}
+	profile.Balances = sortedControlTotals(balances)
+
+	profileJSON, err := json.Marshal(profile)
+	if err != nil {
+		return "", err
+	}
+
+	return string(profileJSON), nil
+}
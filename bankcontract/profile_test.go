@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCustomerProfile(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+	ledger.Install(identityChaincode, identityChaincodeOf(map[string]string{"Afraz": "Enhanced", "Bob": "Basic"}))
+
+	ledger.Run(t, []chaincodetest.Case{
+		createLoan("loan3", "Afraz", 1200),
+		setStatus("loan3", "Disbursed"),
+		{
+			Name:   "pay an installment",
+			Caller: officer,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.RecordPayment(ctx, "loan3")
+				return err
+			},
+		},
+		{
+			Name:   "profile of a borrower",
+			Caller: officer,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				profileJSON, err := s.GetCustomerProfile(ctx, "35202-Afraz")
+				if err != nil {
+					return err
+				}
+				var profile CustomerProfile
+				require.NoError(t, json.Unmarshal([]byte(profileJSON), &profile))
+				require.Equal(t, "Afraz", profile.IdentityID)
+				require.Equal(t, "Enhanced", profile.KYCLevel)
+				require.JSONEq(t, `{"id":"Afraz","cnic":"35202-Afraz","kycLevel":"Enhanced"}`, string(profile.Identity))
+				require.Len(t, profile.Loans, 2)
+				require.Len(t, profile.Repayments["loan3"], 1)
+				require.NotContains(t, profile.Repayments, "loan1")
+				require.Len(t, profile.Balances, 1)
+				require.Equal(t, 1200, profile.Balances[0].Disbursed)
+				require.Less(t, profile.Balances[0].Outstanding, 1200)
+				return nil
+			},
+		},
+		{
+			Name:   "profile of a customer without loans",
+			Caller: auditor,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				profileJSON, err := s.GetCustomerProfile(ctx, "35202-Bob")
+				if err == nil {
+					var profile CustomerProfile
+					require.NoError(t, json.Unmarshal([]byte(profileJSON), &profile))
+					require.Empty(t, profile.Loans)
+					require.Empty(t, profile.Balances)
+				}
+				return err
+			},
+		},
+		{
+			Name:   "unknown CNIC",
+			Caller: officer,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.GetCustomerProfile(ctx, "35202-Carol")
+				return err
+			},
+			Err: "no identity registered under NIC 35202-Carol",
+		},
+		{
+			Name:   "customers cannot read profiles",
+			Caller: customer,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.GetCustomerProfile(ctx, "35202-Afraz")
+				return err
+			},
+			Err: "requires role officer or auditor",
+		},
+	})
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"authz"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+// benchmarkRateObjectType prefixes the composite keys of published benchmark rates,
+// benchmarkrate~tenor~effectiveDate
+const benchmarkRateObjectType = "benchmarkrate"
+
+var benchmarkRateRepo = repository.New[BenchmarkRate](benchmarkRateObjectType)
+
+// RateOracle is the contract through which the rate oracle publishes interest rate benchmarks,
+// such as KIBOR, that floating-rate loans are priced from. Its functions are invoked with the
+// RateOracle: prefix, for example RateOracle:PublishBenchmarkRate.
+type RateOracle struct {
+	contractapi.Contract
+}
+
+// BenchmarkRate is a benchmark rate for one tenor, in percent per year, in force from its
+// effective date until the next rate of the tenor takes effect
+type BenchmarkRate struct {
+	Tenor         string  `json:"tenor"`
+	Rate          float64 `json:"rate"`
+	EffectiveDate string  `json:"effectiveDate"`
+	PublishedBy   string  `json:"publishedBy"`
+	PublishedAt   string  `json:"publishedAt"`
+	TxID          string  `json:"txId"`
+}
+
+// RateFixing records the benchmark rate a floating-rate loan accrues interest at from one
+// installment on, until the next fixing
+type RateFixing struct {
+	FromInstallment int     `json:"fromInstallment"`
+	Tenor           string  `json:"tenor"`
+	BenchmarkRate   float64 `json:"benchmarkRate"`
+	Spread          float64 `json:"spread"`
+	InterestRate    float64 `json:"interestRate"` // the benchmark rate plus the spread
+	EffectiveDate   string  `json:"effectiveDate"`
+	BenchmarkTxID   string  `json:"benchmarkTxId"` // the transaction that published the benchmark rate
+	FixedAt         string  `json:"fixedAt"`
+}
+
+// PublishBenchmarkRate publishes the benchmark rate of a tenor taking effect on a date. Published
+// rates are immutable; a correction is published with a later effective date. Restricted to the
+// oracle role.
+func (o *RateOracle) PublishBenchmarkRate(ctx contractapi.TransactionContextInterface, tenor string, rate float64, effectiveDate string) error {
+	err := requireRole(ctx, "oracle")
+	if err != nil {
+		return err
+	}
+	if tenor == "" {
+		return fmt.Errorf("tenor must not be empty")
+	}
+	if rate < 0 {
+		return fmt.Errorf("benchmark rate must not be negative")
+	}
+	_, err = time.Parse("2006-01-02", effectiveDate)
+	if err != nil {
+		return fmt.Errorf("invalid effective date %q, expected YYYY-MM-DD", effectiveDate)
+	}
+
+	exists, err := benchmarkRateRepo.Exists(ctx, tenor, effectiveDate)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("the %s benchmark rate effective on %s is already published", tenor, effectiveDate)
+	}
+
+	oracle, err := enrollmentID(ctx)
+	if err != nil {
+		return err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	authz.TxLogger(ctx).Info("benchmark rate published", "tenor", tenor, "rate", rate, "effectiveDate", effectiveDate)
+
+	return benchmarkRateRepo.Put(ctx, &BenchmarkRate{
+		Tenor:         tenor,
+		Rate:          rate,
+		EffectiveDate: effectiveDate,
+		PublishedBy:   oracle,
+		PublishedAt:   now.Format(time.RFC3339),
+		TxID:          ctx.GetStub().GetTxID(),
+	}, tenor, effectiveDate)
+}
+
+// GetLatestRate returns the benchmark rate of a tenor in force today. Rates published ahead of
+// their effective date are not returned until it arrives.
+func (o *RateOracle) GetLatestRate(ctx contractapi.TransactionContextInterface, tenor string) (*BenchmarkRate, error) {
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return benchmarkRateOn(ctx, tenor, now.Format("2006-01-02"))
+}
+
+// benchmarkRateOn returns the benchmark rate of a tenor in force on a date
+func benchmarkRateOn(ctx contractapi.TransactionContextInterface, tenor string, date string) (*BenchmarkRate, error) {
+	rates, err := benchmarkRateRepo.List(ctx, tenor)
+	if err != nil {
+		return nil, err
+	}
+
+	var latest *BenchmarkRate
+	for _, rate := range rates {
+		if rate.EffectiveDate > date {
+			break
+		}
+		latest = rate
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("no %s benchmark rate is in force on %s", tenor, date)
+	}
+
+	return latest, nil
+}
+
+// fixFloatingRate fixes the interest rate a floating-rate loan accrues at from an installment on
+// to the benchmark in force on the date its accrual period starts, plus the loan's spread, and
+// records the benchmark rate used. Fixed-rate loans are left alone. The loan is not written.
+func fixFloatingRate(ctx contractapi.TransactionContextInterface, loan *LoanApplication, fromInstallment int, periodStart string) error {
+	if loan.BenchmarkTenor == "" {
+		return nil
+	}
+	benchmark, err := benchmarkRateOn(ctx, loan.BenchmarkTenor, periodStart)
+	if err != nil {
+		return err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	loan.InterestRate = benchmark.Rate + loan.Spread
+	loan.RateFixings = append(loan.RateFixings, &RateFixing{
+		FromInstallment: fromInstallment,
+		Tenor:           benchmark.Tenor,
+		BenchmarkRate:   benchmark.Rate,
+		Spread:          loan.Spread,
+		InterestRate:    loan.InterestRate,
+		EffectiveDate:   benchmark.EffectiveDate,
+		BenchmarkTxID:   benchmark.TxID,
+		FixedAt:         now.Format(time.RFC3339),
+	})
+
+	return nil
+}
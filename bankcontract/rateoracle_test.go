@@ -0,0 +1,130 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFloatingRateLoans(t *testing.T) {
+	s, o := new(SmartContract), new(RateOracle)
+	ledger := newLedger(t)
+
+	oracle := chaincodetest.Identity{MSPID: "Org1MSP", CommonName: "oracle1", Attributes: map[string]string{"role": "oracle"}}
+	publish := func(tenor string, rate float64, effectiveDate string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return o.PublishBenchmarkRate(ctx, tenor, rate, effectiveDate)
+		}
+	}
+	var februaryTxID string
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "only the oracle publishes rates", Caller: ops, Run: publish("3M", 9.5, "2024-01-01"), Err: "requires role oracle"},
+		{
+			Name:   "no rate published yet",
+			Caller: ops,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := o.GetLatestRate(ctx, "3M")
+				return err
+			},
+			Err: "no 3M benchmark rate is in force on 2024-01-01",
+		},
+		{Name: "publish the January rate", Caller: oracle, Run: publish("3M", 9.5, "2024-01-01")},
+		{Name: "publish a rate twice", Caller: oracle, Run: publish("3M", 9.75, "2024-01-01"), Err: "the 3M benchmark rate effective on 2024-01-01 is already published"},
+		{Name: "invalid effective date", Caller: oracle, Run: publish("3M", 9.5, "01/02/2024"), Err: `invalid effective date "01/02/2024"`},
+		{
+			Name:   "publish the February rate in advance",
+			Caller: oracle,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				februaryTxID = ctx.GetStub().GetTxID()
+				return o.PublishBenchmarkRate(ctx, "3M", 21.5, "2024-02-01")
+			},
+		},
+		{
+			Name:   "rates apply from their effective date",
+			Caller: customer,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				rate, err := o.GetLatestRate(ctx, "3M")
+				if err == nil {
+					require.Equal(t, 9.5, rate.Rate)
+					require.Equal(t, "oracle1", rate.PublishedBy)
+				}
+				return err
+			},
+		},
+		{
+			Name:   "create a floating-rate product",
+			Caller: ops,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.CreateFloatingRateProduct(ctx, "kibor3m", "KIBOR 3M + 2.5", "3M", 2.5, 50000, 12)
+			},
+		},
+		{
+			Name:   "products on unpublished tenors take no applications",
+			Caller: ops,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				err := s.CreateFloatingRateProduct(ctx, "kibor6m", "KIBOR 6M + 2", "6M", 2, 50000, 12)
+				require.NoError(t, err)
+				return s.ApplyForProduct(ctx, "loan4", "Alam", "kibor6m", 12000, 3)
+			},
+			Err: "no 6M benchmark rate is in force on 2024-01-01",
+		},
+		{
+			Name:   "apply at the benchmark plus the spread",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				err := s.ApplyForProduct(ctx, "loan3", "Bob", "kibor3m", 12000, 3)
+				if err == nil {
+					loan, err := s.ReadLoanApplication(ctx, "loan3")
+					require.NoError(t, err)
+					require.Equal(t, 12.0, loan.InterestRate)
+					require.Empty(t, loan.RateFixings)
+				}
+				return err
+			},
+		},
+		setStatus("loan3", "Approved"),
+		setStatus("loan3", "Disbursed"),
+		{
+			Name:   "the rate is fixed on disbursement",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				schedule, err := s.GetRepaymentSchedule(ctx, "loan3")
+				if err == nil {
+					require.Len(t, schedule, 3)
+					require.Equal(t, 120, schedule[0].Interest)
+					require.Equal(t, 4080, schedule[0].Amount)
+				}
+				return err
+			},
+		},
+		{
+			Name:   "the next installment is fixed at the rate in force when it starts accruing",
+			Caller: officer,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.RecordPayment(ctx, "loan3")
+				if err != nil {
+					return err
+				}
+				loan, err := s.ReadLoanApplication(ctx, "loan3")
+				require.NoError(t, err)
+				require.Equal(t, 24.0, loan.InterestRate)
+				require.Len(t, loan.RateFixings, 2)
+				require.Equal(t, 1, loan.RateFixings[0].FromInstallment)
+				require.Equal(t, "2024-01-01", loan.RateFixings[0].EffectiveDate)
+				require.Equal(t, 2, loan.RateFixings[1].FromInstallment)
+				require.Equal(t, 21.5, loan.RateFixings[1].BenchmarkRate)
+				require.Equal(t, februaryTxID, loan.RateFixings[1].BenchmarkTxID)
+
+				schedule, err := s.GetRepaymentSchedule(ctx, "loan3")
+				require.NoError(t, err)
+				require.Equal(t, 120, schedule[0].Interest)
+				require.Equal(t, 161, schedule[1].Interest)
+				require.Equal(t, 12000, schedule[0].Principal+schedule[1].Principal+schedule[2].Principal)
+				return nil
+			},
+		},
+	})
+}
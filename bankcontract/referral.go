@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+const (
+	// referralObjectType and referralStatsObjectType prefix the composite keys of referral
+	// codes and of per-referrer statistics
+	referralObjectType      = "referral"
+	referralStatsObjectType = "referralstats"
+
+	// referralRewardPercent is the share of the disbursed amount accrued to the referrer
+	referralRewardPercent = 1
+)
+
+var (
+	referralRepo      = repository.New[ReferralCode](referralObjectType)
+	referralStatsRepo = repository.New[ReferralStats](referralStatsObjectType)
+)
+
+// ReferralCode binds a shareable code to the identity of the referrer
+type ReferralCode struct {
+	Code     string `json:"code"`
+	Referrer string `json:"referrer"`
+}
+
+// ReferralStats summarizes the referrals made by one identity
+type ReferralStats struct {
+	Referrer      string `json:"referrer"`
+	Referred      int    `json:"referred"`
+	Disbursed     int    `json:"disbursed"`
+	RewardAccrued int    `json:"rewardAccrued"`
+}
+
+// CreateReferralCode issues a referral code for the given identity. Restricted to the officer role.
+func (s *SmartContract) CreateReferralCode(ctx contractapi.TransactionContextInterface, code string, referrer string) error {
+	err := requireRole(ctx, "officer")
+	if err != nil {
+		return err
+	}
+	if code == "" || referrer == "" {
+		return fmt.Errorf("code and referrer must not be empty")
+	}
+
+	existing, err := getReferralCode(ctx, code)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("the referral code %s already exists", code)
+	}
+
+	referral := ReferralCode{
+		Code:     code,
+		Referrer: referrer,
+	}
+	return referralRepo.Put(ctx, &referral, code)
+}
+
+// ApplyReferralCode records that a pending loan application was referred through the given code.
+// Applicants cannot refer themselves, and cannot be referred by someone they referred earlier.
+// Restricted to the applicant and the officer role.
+func (s *SmartContract) ApplyReferralCode(ctx contractapi.TransactionContextInterface, loanID string, code string) error {
+	loan, err := readLoan(ctx, loanID)
+	if err != nil {
+		return err
+	}
+	err = requireApplicantOrOfficer(ctx, loan)
+	if err != nil {
+		return err
+	}
+	if loan.Status != "Pending" {
+		return fmt.Errorf("the loan application %s is %s, referral codes can only be applied while Pending", loanID, loan.Status)
+	}
+	if loan.ReferralCode != "" {
+		return fmt.Errorf("the loan application %s already uses referral code %s", loanID, loan.ReferralCode)
+	}
+
+	referral, err := getReferralCode(ctx, code)
+	if err != nil {
+		return err
+	}
+	if referral == nil {
+		return fmt.Errorf("the referral code %s does not exist", code)
+	}
+	if referral.Referrer == loan.Applicant {
+		return fmt.Errorf("applicant %s cannot use their own referral code", loan.Applicant)
+	}
+
+	referrerLoans, err := loansByApplicant(ctx, referral.Referrer)
+	if err != nil {
+		return err
+	}
+	for _, referrerLoan := range referrerLoans {
+		if referrerLoan.ReferredBy == loan.Applicant {
+			return fmt.Errorf("circular referral, %s was referred by %s", referral.Referrer, loan.Applicant)
+		}
+	}
+
+	loan.ReferralCode = referral.Code
+	loan.ReferredBy = referral.Referrer
+	err = putLoan(ctx, loan)
+	if err != nil {
+		return err
+	}
+
+	stats, err := getReferralStats(ctx, referral.Referrer)
+	if err != nil {
+		return err
+	}
+	stats.Referred++
+
+	return putReferralStats(ctx, stats)
+}
+
+// GetReferralStats returns the referral statistics and accrued reward of an identity
+func (s *SmartContract) GetReferralStats(ctx contractapi.TransactionContextInterface, referrer string) (*ReferralStats, error) {
+	return getReferralStats(ctx, referrer)
+}
+
+// accrueReferralReward credits the referrer of a loan with referralRewardPercent of its amount
+func accrueReferralReward(ctx contractapi.TransactionContextInterface, loan *LoanApplication) error {
+	if loan.ReferredBy == "" {
+		return nil
+	}
+
+	stats, err := getReferralStats(ctx, loan.ReferredBy)
+	if err != nil {
+		return err
+	}
+	stats.Disbursed++
+	stats.RewardAccrued += loan.Amount * referralRewardPercent / 100
+
+	return putReferralStats(ctx, stats)
+}
+
+func getReferralCode(ctx contractapi.TransactionContextInterface, code string) (*ReferralCode, error) {
+	return referralRepo.Get(ctx, code)
+}
+
+// getReferralStats returns the statistics of a referrer, zero valued if none were recorded yet
+func getReferralStats(ctx contractapi.TransactionContextInterface, referrer string) (*ReferralStats, error) {
+	statsKey, err := ctx.GetStub().CreateCompositeKey(referralStatsObjectType, []string{referrer})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	statsJSON, err := ctx.GetStub().GetState(statsKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+
+	stats := ReferralStats{Referrer: referrer}
+	if statsJSON != nil {
+		err = json.Unmarshal(statsJSON, &stats)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &stats, nil
+}
+
+func putReferralStats(ctx contractapi.TransactionContextInterface, stats *ReferralStats) error {
+	return referralStatsRepo.Put(ctx, stats, stats.Referrer)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReferrals(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	createCode := func(code string, referrer string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.CreateReferralCode(ctx, code, referrer)
+		}
+	}
+	applyCode := func(loanID string, code string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.ApplyReferralCode(ctx, loanID, code)
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "create code requires officer", Caller: bank, Run: createCode("AFRAZ10", "Afraz"), Err: "requires role officer"},
+		{Name: "create code without a referrer", Caller: officer, Run: createCode("AFRAZ10", ""), Err: "code and referrer must not be empty"},
+		{Name: "create code", Caller: officer, Run: createCode("AFRAZ10", "Afraz")},
+		{Name: "create code twice", Caller: officer, Run: createCode("AFRAZ10", "Bob"), Err: "the referral code AFRAZ10 already exists"},
+		{Name: "create code for Bob", Caller: officer, Run: createCode("BOB10", "Bob")},
+		createLoan("loan3", "Bob", 20000),
+		{Name: "apply own code", Caller: officer, Run: applyCode("loan1", "AFRAZ10"), Err: "applicant Afraz cannot use their own referral code"},
+		{Name: "strangers cannot apply their code to others' loans", Caller: customer, Run: applyCode("loan3", "AFRAZ10"), Err: "requires the applicant of loan application loan3 or role officer"},
+		{Name: "apply unknown code", Caller: officer, Run: applyCode("loan3", "ZED10"), Err: "the referral code ZED10 does not exist"},
+		{Name: "apply code to an approved loan", Caller: officer, Run: applyCode("loan2", "AFRAZ10"), Err: "referral codes can only be applied while Pending"},
+		{Name: "apply code", Caller: officer, Run: applyCode("loan3", "AFRAZ10")},
+		{Name: "apply a second code", Caller: officer, Run: applyCode("loan3", "BOB10"), Err: "the loan application loan3 already uses referral code AFRAZ10"},
+		{Name: "apply a circular referral", Caller: officer, Run: applyCode("loan1", "BOB10"), Err: "circular referral, Bob was referred by Afraz"},
+		setStatus("loan3", "Approved"),
+		setStatus("loan3", "Disbursed"),
+		{
+			Name:   "disbursement accrues the reward",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				stats, err := s.GetReferralStats(ctx, "Afraz")
+				if err == nil {
+					require.Equal(t, ReferralStats{Referrer: "Afraz", Referred: 1, Disbursed: 1, RewardAccrued: 200}, *stats)
+				}
+				return err
+			},
+		},
+		{
+			Name:   "stats of a referrer without referrals",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				stats, err := s.GetReferralStats(ctx, "Zed")
+				if err == nil {
+					require.Zero(t, stats.Referred)
+				}
+				return err
+			},
+		},
+	})
+}
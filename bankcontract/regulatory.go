@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"authz"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+// regulatoryReportObjectType prefixes the composite keys of generated regulatory reports,
+// regulatoryreport~periodStart~periodEnd
+const regulatoryReportObjectType = "regulatoryreport"
+
+var regulatoryReportRepo = repository.New[RegulatoryReport](regulatoryReportObjectType)
+
+// RegulatoryReport is the regulatory return of a reporting period as generated on the ledger.
+// Figures is the JSON report and CSV the same report as a file; JSONHash and CSVHash are their
+// SHA-256, against which the file submitted to the regulator can be checked.
+type RegulatoryReport struct {
+	PeriodStart string             `json:"periodStart"`
+	PeriodEnd   string             `json:"periodEnd"`
+	Figures     *RegulatoryFigures `json:"figures"`
+	CSV         string             `json:"csv"`
+	JSONHash    string             `json:"jsonHash"` // of the JSON encoding of Figures
+	CSVHash     string             `json:"csvHash"`
+	GeneratedBy string             `json:"generatedBy"`
+	GeneratedAt string             `json:"generatedAt"`
+	TxID        string             `json:"txId"`
+}
+
+// RegulatoryFigures are the contents of a regulatory report. Disbursements and write-offs are
+// those dated within the period; the non-performing loans are the book as it stood when the
+// report was generated.
+type RegulatoryFigures struct {
+	Totals        []*RegulatoryTotals `json:"totals"` // by currency
+	Disbursements []*ReportedLoan     `json:"disbursements"`
+	WriteOffs     []*ReportedLoan     `json:"writeOffs"`
+}
+
+// RegulatoryTotals are the figures of a regulatory report in one currency. Gross loans are the
+// outstanding principal of the loans in repayment that have not been written off, and the
+// non-performing ones those among them that have defaulted.
+type RegulatoryTotals struct {
+	Currency      string  `json:"currency"`
+	Disbursed     int     `json:"disbursed"`
+	WrittenOff    int     `json:"writtenOff"`
+	GrossLoans    int     `json:"grossLoans"`
+	NonPerforming int     `json:"nonPerforming"`
+	NPLRatio      float64 `json:"nplRatio"` // non-performing over gross loans, to four decimals
+}
+
+// ReportedLoan is a disbursement or write-off listed in a regulatory report
+type ReportedLoan struct {
+	LoanID   string `json:"loanId"`
+	Currency string `json:"currency"`
+	Date     string `json:"date"`
+	Amount   int    `json:"amount"`
+}
+
+// WriteOffLoan writes off the outstanding principal of a defaulted loan, which then no longer
+// counts towards the gross loans of regulatory reports. The loan stays Defaulted, so that any
+// recoveries can still be recorded. Restricted to the ops role.
+func (s *SmartContract) WriteOffLoan(ctx contractapi.TransactionContextInterface, loanID string) (*LoanApplication, error) {
+	err := requireRole(ctx, "ops")
+	if err != nil {
+		return nil, err
+	}
+	loan, err := readLoan(ctx, loanID)
+	if err != nil {
+		return nil, err
+	}
+	if loan.Status != "Defaulted" {
+		return nil, fmt.Errorf("the loan application %s is %s, only defaulted loans can be written off", loanID, loan.Status)
+	}
+	if loan.WrittenOffAt != "" {
+		return nil, fmt.Errorf("the loan application %s was written off on %s", loanID, loan.WrittenOffAt)
+	}
+
+	outstanding, err := outstandingPrincipal(loan)
+	if err != nil {
+		return nil, err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	loan.WrittenOffAt = now.Format(time.RFC3339)
+	loan.WrittenOffAmount = outstanding
+	authz.TxLogger(ctx).Info("loan written off", "loanId", loanID, "amount", outstanding)
+
+	return loan, putLoan(ctx, loan)
+}
+
+// GenerateRegulatoryReport computes the regulatory return of a period that has ended, from
+// periodStart to periodEnd inclusive, and stores it with its hashes. The report depends only on
+// the ledger, so every endorsing peer computes the same bytes. A period is reported once.
+// Restricted to the ops role.
+func (s *SmartContract) GenerateRegulatoryReport(ctx contractapi.TransactionContextInterface, periodStart string, periodEnd string) (*RegulatoryReport, error) {
+	err := requireRole(ctx, "ops")
+	if err != nil {
+		return nil, err
+	}
+	for _, date := range []string{periodStart, periodEnd} {
+		_, err = time.Parse("2006-01-02", date)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", date)
+		}
+	}
+	if periodEnd < periodStart {
+		return nil, fmt.Errorf("the period must not end before it starts")
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if periodEnd >= now.Format("2006-01-02") {
+		return nil, fmt.Errorf("the period must have ended before it is reported")
+	}
+	existing, err := regulatoryReportRepo.Get(ctx, periodStart, periodEnd)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("the period %s to %s was reported in transaction %s", periodStart, periodEnd, existing.TxID)
+	}
+
+	figures, err := regulatoryFigures(ctx, periodStart, periodEnd)
+	if err != nil {
+		return nil, err
+	}
+	figuresJSON, err := json.Marshal(figures)
+	if err != nil {
+		return nil, err
+	}
+	reportCSV, err := regulatoryCSV(figures)
+	if err != nil {
+		return nil, err
+	}
+	operator, _, err := operatorName(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	jsonSum := sha256.Sum256(figuresJSON)
+	csvSum := sha256.Sum256([]byte(reportCSV))
+	report := &RegulatoryReport{
+		PeriodStart: periodStart,
+		PeriodEnd:   periodEnd,
+		Figures:     figures,
+		CSV:         reportCSV,
+		JSONHash:    hex.EncodeToString(jsonSum[:]),
+		CSVHash:     hex.EncodeToString(csvSum[:]),
+		GeneratedBy: operator,
+		GeneratedAt: now.Format(time.RFC3339),
+		TxID:        ctx.GetStub().GetTxID(),
+	}
+	err = regulatoryReportRepo.Put(ctx, report, periodStart, periodEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// GetRegulatoryReport returns the stored report of a period. Restricted to ops, auditor and
+// regulator roles.
+func (s *SmartContract) GetRegulatoryReport(ctx contractapi.TransactionContextInterface, periodStart string, periodEnd string) (*RegulatoryReport, error) {
+	err := requireRole(ctx, "ops", "auditor", "regulator")
+	if err != nil {
+		return nil, err
+	}
+	report, err := regulatoryReportRepo.Get(ctx, periodStart, periodEnd)
+	if err != nil {
+		return nil, err
+	}
+	if report == nil {
+		return nil, fmt.Errorf("the period %s to %s has not been reported", periodStart, periodEnd)
+	}
+
+	return report, nil
+}
+
+// regulatoryFigures scans the loan book for the figures of a period, in loan ID order
+func regulatoryFigures(ctx contractapi.TransactionContextInterface, periodStart string, periodEnd string) (*RegulatoryFigures, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByRange(loanNamespace.Range(""))
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	figures := &RegulatoryFigures{Totals: []*RegulatoryTotals{}, Disbursements: []*ReportedLoan{}, WriteOffs: []*ReportedLoan{}}
+	totals := make(map[string]*RegulatoryTotals)
+	inPeriod := func(timestamp string) bool {
+		return len(timestamp) >= 10 && timestamp[:10] >= periodStart && timestamp[:10] <= periodEnd
+	}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var loan LoanApplication
+		err = json.Unmarshal(queryResponse.Value, &loan)
+		if err != nil {
+			return nil, err
+		}
+		if loan.DisbursedAt == "" {
+			continue
+		}
+		currency := loan.Currency
+		if currency == "" {
+			currency = defaultCurrency
+		}
+		total, ok := totals[currency]
+		if !ok {
+			total = &RegulatoryTotals{Currency: currency}
+			totals[currency] = total
+		}
+
+		if inPeriod(loan.DisbursedAt) {
+			total.Disbursed += loan.Amount
+			figures.Disbursements = append(figures.Disbursements, &ReportedLoan{LoanID: loan.ID, Currency: currency, Date: loan.DisbursedAt[:10], Amount: loan.Amount})
+		}
+		if inPeriod(loan.WrittenOffAt) {
+			total.WrittenOff += loan.WrittenOffAmount
+			figures.WriteOffs = append(figures.WriteOffs, &ReportedLoan{LoanID: loan.ID, Currency: currency, Date: loan.WrittenOffAt[:10], Amount: loan.WrittenOffAmount})
+		}
+		if loan.WrittenOffAt != "" {
+			continue
+		}
+		outstanding, err := outstandingPrincipal(&loan)
+		if err != nil {
+			return nil, err
+		}
+		total.GrossLoans += outstanding
+		if loan.Status == "Defaulted" {
+			total.NonPerforming += outstanding
+		}
+	}
+
+	for _, total := range totals {
+		if total.GrossLoans > 0 {
+			total.NPLRatio = math.Round(float64(total.NonPerforming)/float64(total.GrossLoans)*10000) / 10000
+		}
+		figures.Totals = append(figures.Totals, total)
+	}
+	sort.Slice(figures.Totals, func(i, j int) bool {
+		return figures.Totals[i].Currency < figures.Totals[j].Currency
+	})
+
+	return figures, nil
+}
+
+// regulatoryCSV renders the figures of a report as CSV: one row per disbursement and write-off,
+// followed by the totals of each currency
+func regulatoryCSV(figures *RegulatoryFigures) (string, error) {
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	rows := [][]string{{"record", "currency", "loan_id", "date", "disbursed", "written_off", "gross_loans", "non_performing", "npl_ratio"}}
+	for _, loan := range figures.Disbursements {
+		rows = append(rows, []string{"disbursement", loan.Currency, loan.LoanID, loan.Date, strconv.Itoa(loan.Amount), "", "", "", ""})
+	}
+	for _, loan := range figures.WriteOffs {
+		rows = append(rows, []string{"writeoff", loan.Currency, loan.LoanID, loan.Date, "", strconv.Itoa(loan.Amount), "", "", ""})
+	}
+	for _, total := range figures.Totals {
+		rows = append(rows, []string{
+			"total", total.Currency, "", "",
+			strconv.Itoa(total.Disbursed),
+			strconv.Itoa(total.WrittenOff),
+			strconv.Itoa(total.GrossLoans),
+			strconv.Itoa(total.NonPerforming),
+			strconv.FormatFloat(total.NPLRatio, 'f', 4, 64),
+		})
+	}
+	err := writer.WriteAll(rows)
+	if err != nil {
+		return "", err
+	}
+
+	return buffer.String(), nil
+}
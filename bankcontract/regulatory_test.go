@@ -0,0 +1,110 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegulatoryReport(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	regulator := chaincodetest.Identity{MSPID: "Org2MSP", CommonName: "regulator1", Attributes: map[string]string{"role": "regulator"}}
+	generate := func(periodStart string, periodEnd string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.GenerateRegulatoryReport(ctx, periodStart, periodEnd)
+			return err
+		}
+	}
+	writeOff := func(loanID string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.WriteOffLoan(ctx, loanID)
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		createLoan("loan3", "Bob", 1200),
+		createLoan("loan4", "Carol", 2400),
+		setStatus("loan2", "Disbursed"),
+		setStatus("loan3", "Disbursed"),
+		setStatus("loan4", "Disbursed"),
+		setStatus("loan4", "Defaulted"),
+		{Name: "only defaulted loans are written off", Caller: ops, Run: writeOff("loan3"), Err: "the loan application loan3 is Disbursed, only defaulted loans can be written off"},
+		{Name: "only ops write off", Caller: officer, Run: writeOff("loan4"), Err: "requires role ops"},
+		{Name: "period not over", Caller: ops, Run: generate("2024-01-01", "2024-01-01"), Err: "the period must have ended before it is reported"},
+	})
+	ledger.Advance(24 * time.Hour)
+	ledger.Run(t, []chaincodetest.Case{
+		setStatus("loan3", "Defaulted"),
+		{Name: "write off a defaulted loan", Caller: ops, Run: writeOff("loan3")},
+		{Name: "write off twice", Caller: ops, Run: writeOff("loan3"), Err: "was written off on 2024-01-02"},
+	})
+	ledger.Advance(24 * time.Hour)
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "invalid date", Caller: ops, Run: generate("2024-01", "2024-01-02"), Err: `invalid date "2024-01"`},
+		{Name: "inverted period", Caller: ops, Run: generate("2024-01-02", "2024-01-01"), Err: "the period must not end before it starts"},
+		{
+			Name:   "generate the report",
+			Caller: ops,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				report, err := s.GenerateRegulatoryReport(ctx, "2024-01-01", "2024-01-02")
+				if err != nil {
+					return err
+				}
+				require.Equal(t, []*ReportedLoan{
+					{LoanID: "loan2", Currency: "PKR", Date: "2024-01-01", Amount: 5000},
+					{LoanID: "loan3", Currency: "PKR", Date: "2024-01-01", Amount: 1200},
+					{LoanID: "loan4", Currency: "PKR", Date: "2024-01-01", Amount: 2400},
+				}, report.Figures.Disbursements)
+				require.Equal(t, []*ReportedLoan{{LoanID: "loan3", Currency: "PKR", Date: "2024-01-02", Amount: 1200}}, report.Figures.WriteOffs)
+				require.Equal(t, []*RegulatoryTotals{{
+					Currency:      "PKR",
+					Disbursed:     8600,
+					WrittenOff:    1200,
+					GrossLoans:    7400,
+					NonPerforming: 2400,
+					NPLRatio:      0.3243,
+				}}, report.Figures.Totals)
+				require.Equal(t, "record,currency,loan_id,date,disbursed,written_off,gross_loans,non_performing,npl_ratio\n"+
+					"disbursement,PKR,loan2,2024-01-01,5000,,,,\n"+
+					"disbursement,PKR,loan3,2024-01-01,1200,,,,\n"+
+					"disbursement,PKR,loan4,2024-01-01,2400,,,,\n"+
+					"writeoff,PKR,loan3,2024-01-02,,1200,,,\n"+
+					"total,PKR,,,8600,1200,7400,2400,0.3243\n", report.CSV)
+				sum := sha256.Sum256([]byte(report.CSV))
+				require.Equal(t, hex.EncodeToString(sum[:]), report.CSVHash)
+				return nil
+			},
+		},
+		{Name: "a period is reported once", Caller: ops, Run: generate("2024-01-01", "2024-01-02"), Err: "the period 2024-01-01 to 2024-01-02 was reported in transaction"},
+		{
+			Name:   "regulators read the stored report",
+			Caller: regulator,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				report, err := s.GetRegulatoryReport(ctx, "2024-01-01", "2024-01-02")
+				if err == nil {
+					require.Equal(t, "ops1", report.GeneratedBy)
+					require.Len(t, report.JSONHash, 64)
+				}
+				return err
+			},
+		},
+		{
+			Name:   "unreported period",
+			Caller: regulator,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.GetRegulatoryReport(ctx, "2024-01-01", "2024-01-01")
+				return err
+			},
+			Err: "the period 2024-01-01 to 2024-01-01 has not been reported",
+		},
+	})
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+const (
+	// reviewerListObjectType prefixes the composite keys of the reviewer list versions. Every
+	// version is kept under its number so that old assignments can be recomputed, and the latest
+	// under "current".
+	reviewerListObjectType       = "reviewerlist"
+	reviewerAssignmentObjectType = "reviewerassignment"
+)
+
+var (
+	reviewerListRepo       = repository.New[ReviewerList](reviewerListObjectType)
+	reviewerAssignmentRepo = repository.New[ReviewerAssignment](reviewerAssignmentObjectType)
+)
+
+// ReviewerList is a version of the list of officers loan applications are assigned to for review
+type ReviewerList struct {
+	Version   int      `json:"version"`
+	Reviewers []string `json:"reviewers"`
+	Hash      string   `json:"hash"` // SHA-256 of the JSON encoded reviewers, in order
+	SetBy     string   `json:"setBy"`
+	SetAt     string   `json:"setAt"`
+}
+
+// ReviewerAssignment records the reviewer a loan application was assigned to together with every
+// input of the draw, so that any organization can recompute it
+type ReviewerAssignment struct {
+	LoanID      string `json:"loanId"`
+	Reviewer    string `json:"reviewer"`
+	TxID        string `json:"txId"`
+	ListVersion int    `json:"listVersion"`
+	ListHash    string `json:"listHash"`
+	Digest      string `json:"digest"`
+	AssignedAt  string `json:"assignedAt"`
+}
+
+// ReviewerVerification is the outcome of recomputing a reviewer assignment
+type ReviewerVerification struct {
+	Assignment *ReviewerAssignment `json:"assignment"`
+	Recomputed string              `json:"recomputed"` // the reviewer the recorded inputs draw
+	Valid      bool                `json:"valid"`
+	Problem    string              `json:"problem"`
+}
+
+// SetLoanReviewers registers the officers new loan applications are assigned to for review as the
+// next version of the reviewer list. Restricted to the ops role.
+func (s *SmartContract) SetLoanReviewers(ctx contractapi.TransactionContextInterface, reviewers []string) (*ReviewerList, error) {
+	err := requireRole(ctx, "ops")
+	if err != nil {
+		return nil, err
+	}
+	if len(reviewers) == 0 {
+		return nil, fmt.Errorf("the reviewer list must not be empty")
+	}
+	seen := make(map[string]bool)
+	for _, reviewer := range reviewers {
+		if reviewer == "" {
+			return nil, fmt.Errorf("reviewer names must not be empty")
+		}
+		if seen[reviewer] {
+			return nil, fmt.Errorf("reviewer %s is listed twice", reviewer)
+		}
+		seen[reviewer] = true
+	}
+
+	current, err := getReviewerList(ctx, "current")
+	if err != nil {
+		return nil, err
+	}
+	operator, _, err := operatorName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := reviewerListHash(reviewers)
+	if err != nil {
+		return nil, err
+	}
+	list := ReviewerList{
+		Version:   1,
+		Reviewers: reviewers,
+		Hash:      hash,
+		SetBy:     operator,
+		SetAt:     now.Format(time.RFC3339),
+	}
+	if current != nil {
+		list.Version = current.Version + 1
+	}
+
+	for _, slot := range []string{strconv.Itoa(list.Version), "current"} {
+		err = reviewerListRepo.Put(ctx, &list, slot)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &list, nil
+}
+
+// GetLoanReviewers returns a version of the reviewer list, or the current one for version 0
+func (s *SmartContract) GetLoanReviewers(ctx contractapi.TransactionContextInterface, version int) (*ReviewerList, error) {
+	slot := "current"
+	if version > 0 {
+		slot = strconv.Itoa(version)
+	}
+	list, err := getReviewerList(ctx, slot)
+	if err != nil {
+		return nil, err
+	}
+	if list == nil {
+		return nil, fmt.Errorf("reviewer list %s has not been set", slot)
+	}
+
+	return list, nil
+}
+
+// GetReviewerAssignment returns the reviewer assignment of a loan application
+func (s *SmartContract) GetReviewerAssignment(ctx contractapi.TransactionContextInterface, loanID string) (*ReviewerAssignment, error) {
+	assignment, err := getReviewerAssignment(ctx, loanID)
+	if err != nil {
+		return nil, err
+	}
+	if assignment == nil {
+		return nil, fmt.Errorf("the loan application %s has no reviewer assignment", loanID)
+	}
+
+	return assignment, nil
+}
+
+// VerifyReviewerAssignment recomputes the reviewer assignment of a loan application from its
+// recorded inputs. It checks that the reviewer list version still hashes to the recorded hash,
+// that the assignment was written by the transaction whose ID it was drawn from, and that the
+// draw yields the recorded reviewer.
+func (s *SmartContract) VerifyReviewerAssignment(ctx contractapi.TransactionContextInterface, loanID string) (*ReviewerVerification, error) {
+	assignment, err := s.GetReviewerAssignment(ctx, loanID)
+	if err != nil {
+		return nil, err
+	}
+	verification := &ReviewerVerification{Assignment: assignment}
+
+	list, err := s.GetLoanReviewers(ctx, assignment.ListVersion)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := reviewerListHash(list.Reviewers)
+	if err != nil {
+		return nil, err
+	}
+	if hash != assignment.ListHash {
+		verification.Problem = fmt.Sprintf("reviewer list version %d does not match the recorded hash", assignment.ListVersion)
+		return verification, nil
+	}
+
+	writer, err := assignmentWriter(ctx, loanID)
+	if err != nil {
+		return nil, err
+	}
+	if writer != assignment.TxID {
+		verification.Problem = fmt.Sprintf("the assignment was written by transaction %s, not the recorded %s", writer, assignment.TxID)
+		return verification, nil
+	}
+
+	reviewer, digest := drawReviewer(list, loanID, assignment.TxID)
+	verification.Recomputed = reviewer
+	if reviewer != assignment.Reviewer || digest != assignment.Digest {
+		verification.Problem = fmt.Sprintf("the recorded inputs draw %s, not %s", reviewer, assignment.Reviewer)
+		return verification, nil
+	}
+	verification.Valid = true
+
+	return verification, nil
+}
+
+// assignReviewer draws the reviewer of a new loan application from the current reviewer list.
+// The draw hashes the transaction ID, derived from the client's nonce and certificate, with the
+// loan ID and the list version and hash. Nothing is assigned while no reviewer list has been set.
+func assignReviewer(ctx contractapi.TransactionContextInterface, loanID string) error {
+	list, err := getReviewerList(ctx, "current")
+	if err != nil || list == nil {
+		return err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	txID := ctx.GetStub().GetTxID()
+	reviewer, digest := drawReviewer(list, loanID, txID)
+	assignment := ReviewerAssignment{
+		LoanID:      loanID,
+		Reviewer:    reviewer,
+		TxID:        txID,
+		ListVersion: list.Version,
+		ListHash:    list.Hash,
+		Digest:      digest,
+		AssignedAt:  now.Format(time.RFC3339),
+	}
+
+	return reviewerAssignmentRepo.Put(ctx, &assignment, loanID)
+}
+
+// drawReviewer picks a reviewer from the list by the digest of the assignment inputs and returns
+// it with the hex encoded digest
+func drawReviewer(list *ReviewerList, loanID string, txID string) (string, string) {
+	digest := sha256.Sum256([]byte(txID + "\x00" + loanID + "\x00" + strconv.Itoa(list.Version) + "\x00" + list.Hash))
+	index := binary.BigEndian.Uint64(digest[:8]) % uint64(len(list.Reviewers))
+
+	return list.Reviewers[index], hex.EncodeToString(digest[:])
+}
+
+func reviewerListHash(reviewers []string) (string, error) {
+	reviewersJSON, err := json.Marshal(reviewers)
+	if err != nil {
+		return "", err
+	}
+
+	return hashHex(reviewersJSON), nil
+}
+
+// assignmentWriter returns the ID of the transaction that first wrote the reviewer assignment of
+// a loan application
+func assignmentWriter(ctx contractapi.TransactionContextInterface, loanID string) (string, error) {
+	assignmentKey, err := ctx.GetStub().CreateCompositeKey(reviewerAssignmentObjectType, []string{loanID})
+	if err != nil {
+		return "", fmt.Errorf("failed to create composite key: %v", err)
+	}
+	resultsIterator, err := ctx.GetStub().GetHistoryForKey(assignmentKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to read history of the reviewer assignment of %s: %v", loanID, err)
+	}
+	defer resultsIterator.Close()
+
+	writer := ""
+	for resultsIterator.HasNext() {
+		modification, err := resultsIterator.Next()
+		if err != nil {
+			return "", err
+		}
+		// history is returned newest first
+		writer = modification.TxId
+	}
+
+	return writer, nil
+}
+
+func getReviewerList(ctx contractapi.TransactionContextInterface, slot string) (*ReviewerList, error) {
+	return reviewerListRepo.Get(ctx, slot)
+}
+
+func getReviewerAssignment(ctx contractapi.TransactionContextInterface, loanID string) (*ReviewerAssignment, error) {
+	return reviewerAssignmentRepo.Get(ctx, loanID)
+}
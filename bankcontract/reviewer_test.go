@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"chaincodetest"
+	"chaincodetest/chaincodefakes"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReviewerAssignment(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	setReviewers := func(reviewers ...string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.SetLoanReviewers(ctx, reviewers)
+			return err
+		}
+	}
+	verify := func(loanID string, problem string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			verification, err := s.VerifyReviewerAssignment(ctx, loanID)
+			if err == nil {
+				require.Equal(t, problem == "", verification.Valid)
+				require.Contains(t, verification.Problem, problem)
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		createLoan("loan3", "Bob", 1000),
+		{
+			Name:   "nothing is assigned without a reviewer list",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.GetReviewerAssignment(ctx, "loan3")
+				return err
+			},
+			Err: "the loan application loan3 has no reviewer assignment",
+		},
+		{
+			Name:   "read reviewers before any are set",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.GetLoanReviewers(ctx, 0)
+				return err
+			},
+			Err: "reviewer list current has not been set",
+		},
+		{Name: "set reviewers requires ops", Caller: bank, Run: setReviewers("officer1"), Err: "requires role ops"},
+		{Name: "set no reviewers", Caller: ops, Run: setReviewers(), Err: "the reviewer list must not be empty"},
+		{Name: "set an empty reviewer", Caller: ops, Run: setReviewers("officer1", ""), Err: "reviewer names must not be empty"},
+		{Name: "set a reviewer twice", Caller: ops, Run: setReviewers("officer1", "officer1"), Err: "reviewer officer1 is listed twice"},
+		{Name: "set reviewers", Caller: ops, Run: setReviewers("officer1", "officer2")},
+		{Name: "set the next reviewers", Caller: ops, Run: setReviewers("officer1", "officer2", "officer3")},
+		{
+			Name:   "reviewer lists are versioned",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				current, err := s.GetLoanReviewers(ctx, 0)
+				require.NoError(t, err)
+				require.Equal(t, 2, current.Version)
+				first, err := s.GetLoanReviewers(ctx, 1)
+				require.NoError(t, err)
+				require.Len(t, first.Reviewers, 2)
+				_, err = s.GetLoanReviewers(ctx, 3)
+				return err
+			},
+			Err: "reviewer list 3 has not been set",
+		},
+		createLoan("loan4", "Bob", 1000),
+		{
+			Name:   "new applications are assigned a reviewer",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				assignment, err := s.GetReviewerAssignment(ctx, "loan4")
+				if err == nil {
+					require.Equal(t, 2, assignment.ListVersion)
+					require.Contains(t, []string{"officer1", "officer2", "officer3"}, assignment.Reviewer)
+				}
+				return err
+			},
+		},
+		{Name: "verify the assignment", Caller: auditor, Run: verify("loan4", "")},
+		{
+			Name:   "verify an assignment rewritten by another transaction",
+			Caller: auditor,
+			Stub: func(stub *chaincodefakes.ChaincodeStub) {
+				stub.GetHistoryForKeyReturns(chaincodetest.HistoryIterator(&queryresult.KeyModification{TxId: "tx99"}), nil)
+			},
+			Run: verify("loan4", "the assignment was written by transaction tx99"),
+		},
+		{
+			Name:   "forge the assignment",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				assignment, err := s.GetReviewerAssignment(ctx, "loan4")
+				require.NoError(t, err)
+				assignment.Reviewer = "officer9"
+				return reviewerAssignmentRepo.Put(ctx, assignment, "loan4")
+			},
+		},
+		{Name: "verify a forged reviewer", Caller: auditor, Run: verify("loan4", "not officer9")},
+		{
+			Name:   "forge the reviewer list",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				key, err := ctx.GetStub().CreateCompositeKey(reviewerListObjectType, []string{"2"})
+				require.NoError(t, err)
+				listJSON, err := json.Marshal(ReviewerList{Version: 2, Reviewers: []string{"officer9"}})
+				require.NoError(t, err)
+				return ctx.GetStub().PutState(key, listJSON)
+			},
+		},
+		{Name: "verify against a forged list", Caller: auditor, Run: verify("loan4", "reviewer list version 2 does not match the recorded hash")},
+		{Name: "verify a missing assignment", Caller: auditor, Run: verify("loan9", ""), Err: "the loan application loan9 has no reviewer assignment"},
+	})
+}
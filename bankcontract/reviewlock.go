@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+const (
+	// reviewLockObjectType prefixes the composite keys of review locks, one per loan application
+	reviewLockObjectType = "reviewlock"
+	// reviewLockConfigObjectType prefixes the composite key of the review lock settings
+	reviewLockConfigObjectType = "reviewlockconfig"
+
+	// defaultReviewLockMinutes is how long a review lock lasts while ops have not configured it
+	defaultReviewLockMinutes = 30
+)
+
+var (
+	reviewLockRepo       = repository.New[ReviewLock](reviewLockObjectType)
+	reviewLockConfigRepo = repository.New[ReviewLockConfig](reviewLockConfigObjectType)
+)
+
+// ReviewLock marks a loan application as under review by one officer until it expires. While the
+// lock is live, status changes by anyone else are refused with a LOCKED error.
+type ReviewLock struct {
+	LoanID     string `json:"loanId"`
+	Holder     string `json:"holder"`
+	HolderMSP  string `json:"holderMsp"`
+	AcquiredAt string `json:"acquiredAt"`
+	ExpiresAt  string `json:"expiresAt"`
+}
+
+// ReviewLockConfig is how long review locks last
+type ReviewLockConfig struct {
+	Minutes int    `json:"minutes"`
+	SetBy   string `json:"setBy"`
+	SetAt   string `json:"setAt"`
+}
+
+// SetReviewLockDuration sets how many minutes review locks acquired from now on last. Restricted
+// to the ops role.
+func (s *SmartContract) SetReviewLockDuration(ctx contractapi.TransactionContextInterface, minutes int) error {
+	err := requireRole(ctx, "ops")
+	if err != nil {
+		return err
+	}
+	if minutes <= 0 {
+		return fmt.Errorf("review lock duration must be positive")
+	}
+
+	operator, _, err := operatorName(ctx)
+	if err != nil {
+		return err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	return reviewLockConfigRepo.Put(ctx, &ReviewLockConfig{
+		Minutes: minutes,
+		SetBy:   operator,
+		SetAt:   now.Format(time.RFC3339),
+	}, "current")
+}
+
+// AcquireReviewLock locks a loan application for review by the calling officer. An officer
+// acquiring a lock they already hold extends it. Restricted to the officer role.
+func (s *SmartContract) AcquireReviewLock(ctx contractapi.TransactionContextInterface, loanID string) (*ReviewLock, error) {
+	err := requireRole(ctx, "officer")
+	if err != nil {
+		return nil, err
+	}
+	_, err = readLoan(ctx, loanID)
+	if err != nil {
+		return nil, err
+	}
+	err = checkReviewLock(ctx, loanID)
+	if err != nil {
+		return nil, err
+	}
+
+	holder, mspID, err := operatorName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	minutes := defaultReviewLockMinutes
+	config, err := reviewLockConfigRepo.Get(ctx, "current")
+	if err != nil {
+		return nil, err
+	}
+	if config != nil {
+		minutes = config.Minutes
+	}
+
+	lock := ReviewLock{
+		LoanID:     loanID,
+		Holder:     holder,
+		HolderMSP:  mspID,
+		AcquiredAt: now.Format(time.RFC3339),
+		ExpiresAt:  now.Add(time.Duration(minutes) * time.Minute).Format(time.RFC3339),
+	}
+	err = reviewLockRepo.Put(ctx, &lock, loanID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &lock, nil
+}
+
+// ReleaseReviewLock releases the review lock on a loan application. Only its holder may release a
+// live lock, though the ops role may break one left behind.
+func (s *SmartContract) ReleaseReviewLock(ctx contractapi.TransactionContextInterface, loanID string) error {
+	lock, err := reviewLockRepo.Get(ctx, loanID)
+	if err != nil {
+		return err
+	}
+	if lock == nil {
+		return fmt.Errorf("the loan application %s is not locked for review", loanID)
+	}
+	if requireRole(ctx, "ops") != nil {
+		err = checkReviewLock(ctx, loanID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return reviewLockRepo.Delete(ctx, loanID)
+}
+
+// GetReviewLock returns the live review lock on a loan application, or nil if it is not under review
+func (s *SmartContract) GetReviewLock(ctx contractapi.TransactionContextInterface, loanID string) (*ReviewLock, error) {
+	lock, err := reviewLockRepo.Get(ctx, loanID)
+	if err != nil || lock == nil {
+		return nil, err
+	}
+	live, err := lockLive(ctx, lock)
+	if err != nil || !live {
+		return nil, err
+	}
+
+	return lock, nil
+}
+
+// checkReviewLock returns a LOCKED error if another officer holds a live review lock on the loan
+// application. Reading the lock puts it in the read set, so a status change endorsed before a lock
+// was acquired fails validation instead of overwriting the review.
+func checkReviewLock(ctx contractapi.TransactionContextInterface, loanID string) error {
+	lock, err := reviewLockRepo.Get(ctx, loanID)
+	if err != nil || lock == nil {
+		return err
+	}
+	live, err := lockLive(ctx, lock)
+	if err != nil || !live {
+		return err
+	}
+	holder, mspID, err := operatorName(ctx)
+	if err != nil {
+		return err
+	}
+	if holder == lock.Holder && mspID == lock.HolderMSP {
+		return nil
+	}
+
+	return fmt.Errorf("LOCKED: the loan application %s is under review by %s of %s until %s", loanID, lock.Holder, lock.HolderMSP, lock.ExpiresAt)
+}
+
+// lockLive reports whether a review lock has not expired by the transaction timestamp
+func lockLive(ctx contractapi.TransactionContextInterface, lock *ReviewLock) (bool, error) {
+	now, err := txTime(ctx)
+	if err != nil {
+		return false, err
+	}
+	expiresAt, err := time.Parse(time.RFC3339, lock.ExpiresAt)
+	if err != nil {
+		return false, fmt.Errorf("invalid review lock expiry %q: %v", lock.ExpiresAt, err)
+	}
+
+	return now.Before(expiresAt), nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReviewLocks(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	acquire := func(loanID string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.AcquireReviewLock(ctx, loanID)
+			return err
+		}
+	}
+	release := func(loanID string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.ReleaseReviewLock(ctx, loanID)
+		}
+	}
+	holder := func(loanID string, want string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			lock, err := s.GetReviewLock(ctx, loanID)
+			if err == nil {
+				if want == "" {
+					require.Nil(t, lock)
+				} else {
+					require.Equal(t, want, lock.Holder)
+				}
+			}
+			return err
+		}
+	}
+	setDuration := func(minutes int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.SetReviewLockDuration(ctx, minutes)
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "set duration requires ops", Caller: officer, Run: setDuration(5), Err: "requires role ops"},
+		{Name: "set a non-positive duration", Caller: ops, Run: setDuration(0), Err: "review lock duration must be positive"},
+		{Name: "set duration", Caller: ops, Run: setDuration(5)},
+		{Name: "acquire requires officer", Caller: bank, Run: acquire("loan1"), Err: "requires role officer"},
+		{Name: "acquire on a missing loan", Caller: officer, Run: acquire("loan9"), Err: "the loan application loan9 does not exist"},
+		{
+			Name:   "acquire",
+			Caller: officer,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				lock, err := s.AcquireReviewLock(ctx, "loan1")
+				if err == nil {
+					acquiredAt, _ := time.Parse(time.RFC3339, lock.AcquiredAt)
+					expiresAt, _ := time.Parse(time.RFC3339, lock.ExpiresAt)
+					require.Equal(t, 5*time.Minute, expiresAt.Sub(acquiredAt))
+				}
+				return err
+			},
+		},
+		{Name: "the holder extends the lock", Caller: officer, Run: acquire("loan1")},
+		{Name: "read lock", Caller: bank, Run: holder("loan1", "officer1")},
+		{Name: "acquire a lock held by another officer", Caller: officer2, Run: acquire("loan1"), Err: "LOCKED: the loan application loan1 is under review by officer1 of Org1MSP"},
+		{
+			Name:   "update status under another officer's lock",
+			Caller: officer2,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.UpdateLoanStatus(ctx, "loan1", "Rejected")
+			},
+			Err: "LOCKED",
+		},
+		{
+			Name:   "the holder updates the status",
+			Caller: officer,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.UpdateLoanStatus(ctx, "loan1", "Approved")
+			},
+		},
+		{Name: "release another officer's lock", Caller: officer2, Run: release("loan1"), Err: "LOCKED"},
+		{Name: "ops break a lock", Caller: ops, Run: release("loan1")},
+		{Name: "release an unlocked loan", Caller: officer, Run: release("loan1"), Err: "the loan application loan1 is not locked for review"},
+		{Name: "read an unlocked loan", Caller: bank, Run: holder("loan1", "")},
+		{Name: "acquire again", Caller: officer, Run: acquire("loan1")},
+	})
+
+	ledger.Advance(5 * time.Minute)
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "expired locks are not returned", Caller: bank, Run: holder("loan1", "")},
+		{Name: "acquire an expired lock", Caller: officer2, Run: acquire("loan1")},
+		{Name: "the holder releases the lock", Caller: officer2, Run: release("loan1")},
+	})
+}
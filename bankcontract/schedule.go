@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+// contactChannel is the identity record field the reminder pipeline uses to reach an applicant
+const contactChannel = "mobileNumber"
+
+// Installment describes a single scheduled repayment of a disbursed loan. When the loan is
+// linked to a subsidy program, part of the interest is paid by the program instead of the borrower.
+type Installment struct {
+	LoanID         string `json:"loanId"`
+	Number         int    `json:"number"`
+	DueDate        string `json:"dueDate"`
+	Amount         int    `json:"amount"`
+	Principal      int    `json:"principal"`
+	Interest       int    `json:"interest"`
+	Profit         int    `json:"profit,omitempty" metadata:",optional"` // the share of the markup of a Murabaha sale
+	SubsidyAmount  int    `json:"subsidyAmount"`
+	BorrowerAmount int    `json:"borrowerAmount"`
+}
+
+// UpcomingInstallment is an installment due soon together with where to remind the applicant
+type UpcomingInstallment struct {
+	Installment
+	Applicant      string `json:"applicant"`
+	ContactRef     string `json:"contactRef"`
+	ContactChannel string `json:"contactChannel"`
+}
+
+// InstallmentPage is one page of upcoming installments with the bookmark for the next page
+type InstallmentPage struct {
+	Installments        []*UpcomingInstallment `json:"installments,omitempty" metadata:",optional"`
+	FetchedRecordsCount int32                  `json:"fetchedRecordsCount"`
+	Bookmark            string                 `json:"bookmark"`
+}
+
+// repaymentSchedule derives the equal monthly installments of a disbursed loan, splitting each
+// into principal and interest on the declining balance. The last installment clears the balance.
+// A floating-rate loan is re-amortized over its remaining term from each rate fixing on, and the
+// installments not fixed yet are projected at the latest fixing.
+func repaymentSchedule(loan *LoanApplication) ([]*Installment, error) {
+	if loan.DisbursedAt == "" || loan.Term <= 0 {
+		return nil, nil
+	}
+
+	disbursedAt, err := time.Parse(time.RFC3339, loan.DisbursedAt)
+	if err != nil {
+		return nil, fmt.Errorf("loan %s has an invalid disbursement date: %v", loan.ID, err)
+	}
+	if loan.ProductType == productTypeMurabaha {
+		return murabahaSchedule(loan, disbursedAt), nil
+	}
+
+	monthlyRate := loan.InterestRate / 12 / 100
+	amount := annuityPayment(loan.Amount, loan.Term, monthlyRate)
+	subsidyRate := loan.SubsidyRate / 12 / 100
+	fixings := make(map[int]*RateFixing, len(loan.RateFixings))
+	for _, fixing := range loan.RateFixings {
+		fixings[fixing.FromInstallment] = fixing
+	}
+
+	balance := loan.Amount
+	installments := make([]*Installment, 0, loan.Term)
+	for n := 1; n <= loan.Term; n++ {
+		if fixing, fixed := fixings[n]; fixed {
+			monthlyRate = fixing.InterestRate / 12 / 100
+			amount = annuityPayment(balance, loan.Term-n+1, monthlyRate)
+		}
+		interest := int(math.Round(float64(balance) * monthlyRate))
+		principal := amount - interest
+		if n == loan.Term || principal > balance {
+			principal = balance
+		}
+		balance -= principal
+
+		subsidy := int(math.Round(float64(principal+balance) * subsidyRate))
+		if subsidy > interest {
+			subsidy = interest
+		}
+
+		installments = append(installments, &Installment{
+			LoanID:         loan.ID,
+			Number:         n,
+			DueDate:        disbursedAt.AddDate(0, n, 0).Format("2006-01-02"),
+			Amount:         principal + interest,
+			Principal:      principal,
+			Interest:       interest,
+			SubsidyAmount:  subsidy,
+			BorrowerAmount: principal + interest - subsidy,
+		})
+	}
+
+	return installments, nil
+}
+
+// annuityPayment returns the equal monthly payment repaying a balance over a number of months
+func annuityPayment(balance int, months int, monthlyRate float64) int {
+	payment := float64(balance) / float64(months)
+	if monthlyRate > 0 {
+		payment = float64(balance) * monthlyRate / (1 - math.Pow(1+monthlyRate, -float64(months)))
+	}
+
+	return int(math.Round(payment))
+}
+
+// GetRepaymentSchedule returns the installment schedule of a disbursed loan
+func (s *SmartContract) GetRepaymentSchedule(ctx contractapi.TransactionContextInterface, id string) ([]*Installment, error) {
+	loan, err := readLoan(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if loan.DisbursedAt == "" {
+		return nil, fmt.Errorf("the loan application %s has not been disbursed", id)
+	}
+
+	return repaymentSchedule(loan)
+}
+
+// GetUpcomingInstallments returns the installments of the active loans, those disbursed and not
+// closed or defaulted, falling due within the given number of days, one page of loans at a time.
+// Restricted to officer and ops roles.
+func (s *SmartContract) GetUpcomingInstallments(ctx contractapi.TransactionContextInterface, withinDays int, pageSize int, bookmark string) (*InstallmentPage, error) {
+	err := requireRole(ctx, "officer", "ops")
+	if err != nil {
+		return nil, err
+	}
+	if withinDays < 0 {
+		return nil, fmt.Errorf("withinDays must not be negative")
+	}
+	if pageSize <= 0 || pageSize > repository.MaxPageSize {
+		return nil, fmt.Errorf("pageSize must be between 1 and %d", repository.MaxPageSize)
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	from := now.Format("2006-01-02")
+	until := now.AddDate(0, 0, withinDays).Format("2006-01-02")
+
+	// bookmarks are loan IDs, like the cursors of the jobs over loans
+	start, end := loanNamespace.Range("")
+	if bookmark != "" {
+		bookmark = loanNamespace.Key(bookmark)
+	}
+	resultsIterator, metadata, err := ctx.GetStub().GetStateByRangeWithPagination(start, end, int32(pageSize), bookmark)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	page := &InstallmentPage{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var loan LoanApplication
+		err = json.Unmarshal(queryResponse.Value, &loan)
+		if err != nil {
+			return nil, err
+		}
+		if loan.Status != "Disbursed" && loan.Status != "Overdue" {
+			continue
+		}
+
+		schedule, err := repaymentSchedule(&loan)
+		if err != nil {
+			return nil, err
+		}
+		for _, installment := range schedule {
+			if installment.Number <= loan.PaidInstallments {
+				continue
+			}
+			if installment.DueDate < from || installment.DueDate > until {
+				continue
+			}
+			page.Installments = append(page.Installments, &UpcomingInstallment{
+				Installment:    *installment,
+				Applicant:      loan.Applicant,
+				ContactRef:     loan.Applicant,
+				ContactChannel: contactChannel,
+			})
+		}
+	}
+
+	page.FetchedRecordsCount = metadata.FetchedRecordsCount
+	page.Bookmark, _ = loanNamespace.ID(metadata.Bookmark)
+	return page, nil
+}
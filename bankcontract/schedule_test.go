@@ -0,0 +1,125 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepaymentSchedules(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	upcoming := func(withinDays int, pageSize int, bookmark string, check func(t *testing.T, page *InstallmentPage)) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			page, err := s.GetUpcomingInstallments(ctx, withinDays, pageSize, bookmark)
+			if err == nil {
+				check(t, page)
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{
+			Name:   "schedule of an undisbursed loan",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.GetRepaymentSchedule(ctx, "loan2")
+				return err
+			},
+			Err: "the loan application loan2 has not been disbursed",
+		},
+		setStatus("loan2", "Disbursed"),
+		{
+			Name:   "installments clear the loan",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				schedule, err := s.GetRepaymentSchedule(ctx, "loan2")
+				if err == nil {
+					require.Len(t, schedule, 6)
+					require.Equal(t, "2024-02-01", schedule[0].DueDate)
+					principal := 0
+					for _, installment := range schedule {
+						principal += installment.Principal
+						require.Equal(t, installment.Amount, installment.Principal+installment.Interest)
+					}
+					require.Equal(t, 5000, principal)
+					require.Greater(t, schedule[0].Interest, schedule[5].Interest)
+				}
+				return err
+			},
+		},
+		{
+			Name:   "create an interest free loan",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.CreateLoanApplication(ctx, "loan3", "Bob", 1200, 12, 0)
+			},
+		},
+		setStatus("loan3", "Approved"),
+		setStatus("loan3", "Disbursed"),
+		{
+			Name:   "interest free installments are equal",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				schedule, err := s.GetRepaymentSchedule(ctx, "loan3")
+				if err == nil {
+					for _, installment := range schedule {
+						require.Equal(t, 100, installment.Amount)
+					}
+				}
+				return err
+			},
+		},
+		{Name: "upcoming requires officer or ops", Caller: bank, Run: upcoming(31, 10, "", nil), Err: "requires role officer or ops"},
+		{Name: "upcoming with negative days", Caller: officer, Run: upcoming(-1, 10, "", nil), Err: "withinDays must not be negative"},
+		{Name: "upcoming with a non-positive page size", Caller: officer, Run: upcoming(31, 0, "", nil), Err: "pageSize must be between 1 and 100"},
+		{Name: "upcoming with a page size over the maximum", Caller: officer, Run: upcoming(31, 101, "", nil), Err: "pageSize must be between 1 and 100"},
+		{
+			Name:   "upcoming installments",
+			Caller: officer,
+			Run: upcoming(31, 10, "", func(t *testing.T, page *InstallmentPage) {
+				require.Len(t, page.Installments, 2)
+				require.Equal(t, "Alam", page.Installments[0].Applicant)
+				require.Equal(t, contactChannel, page.Installments[0].ContactChannel)
+				require.Equal(t, 1, page.Installments[1].Number)
+			}),
+		},
+		{
+			Name:   "upcoming installments a page at a time",
+			Caller: ops,
+			Run: upcoming(31, 2, "", func(t *testing.T, page *InstallmentPage) {
+				require.Len(t, page.Installments, 1)
+				require.EqualValues(t, 2, page.FetchedRecordsCount)
+				require.Equal(t, "loan3", page.Bookmark)
+			}),
+		},
+		{
+			Name:   "upcoming installments from a bookmark",
+			Caller: ops,
+			Run: upcoming(31, 2, "loan3", func(t *testing.T, page *InstallmentPage) {
+				require.Len(t, page.Installments, 1)
+				require.Equal(t, "loan3", page.Installments[0].LoanID)
+			}),
+		},
+		{
+			Name:   "no installments fall due today",
+			Caller: ops,
+			Run: upcoming(0, 10, "", func(t *testing.T, page *InstallmentPage) {
+				require.Empty(t, page.Installments)
+			}),
+		},
+		setStatus("loan3", "Defaulted"),
+		{
+			Name:   "defaulted loans have no upcoming installments",
+			Caller: ops,
+			Run: upcoming(31, 10, "", func(t *testing.T, page *InstallmentPage) {
+				require.Len(t, page.Installments, 1)
+				require.Equal(t, "loan2", page.Installments[0].LoanID)
+			}),
+		},
+	})
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+	"repository"
+)
+
+func TestExportImportState(t *testing.T) {
+	s := new(SmartContract)
+	source := newLedger(t)
+	target := chaincodetest.NewLedger()
+	var pages []string
+
+	export := func(prefix string, pageSize int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			bookmark := ""
+			for {
+				pageJSON, err := s.ExportState(ctx, prefix, pageSize, bookmark)
+				if err != nil {
+					return err
+				}
+				var page repository.StatePage
+				require.NoError(t, json.Unmarshal([]byte(pageJSON), &page))
+				pages = append(pages, pageJSON)
+				if page.Bookmark == "" {
+					return nil
+				}
+				bookmark = page.Bookmark
+			}
+		}
+	}
+	importPage := func(pageJSON func() string, keys int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			receipt, err := s.ImportState(ctx, pageJSON())
+			if err == nil {
+				require.Equal(t, keys, receipt.Keys)
+			}
+			return err
+		}
+	}
+	page := func(i int) func() string {
+		return func() string { return pages[i] }
+	}
+
+	source.Run(t, []chaincodetest.Case{
+		createLoan("loan3", "Bob", 2000),
+		{Name: "export requires ops", Caller: bank, Run: export("", 2), Err: "requires role ops"},
+		{Name: "export the loans", Caller: ops, Run: export("", 2)},
+	})
+	require.Len(t, pages, 2)
+
+	tampered := func() string {
+		var p repository.StatePage
+		require.NoError(t, json.Unmarshal([]byte(pages[0]), &p))
+		p.Entries[0].Value = []byte(`{"ID":"loan1","Amount":1}`)
+		tamperedJSON, err := json.Marshal(p)
+		require.NoError(t, err)
+		return string(tamperedJSON)
+	}
+	target.Run(t, []chaincodetest.Case{
+		{Name: "import requires ops", Caller: bank, Run: importPage(page(0), 0), Err: "requires role ops"},
+		{Name: "import a tampered page", Caller: ops, Run: importPage(tampered, 0), Err: "the entries of the page hash to"},
+		{Name: "import an invalid page", Caller: ops, Run: importPage(func() string { return "{" }, 0), Err: "invalid state page"},
+		{Name: "import the first page", Caller: ops, Run: importPage(page(0), 2)},
+		{Name: "import the last page", Caller: ops, Run: importPage(page(1), 1)},
+		{
+			Name:   "the imported loans match the export",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				loan, err := readLoan(ctx, "loan3")
+				if err == nil {
+					require.Equal(t, "Bob", loan.Applicant)
+					require.Equal(t, 2000, loan.Amount)
+				}
+				return err
+			},
+		},
+	})
+}
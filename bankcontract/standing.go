@@ -0,0 +1,252 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"authz"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+const (
+	// standingInstructionObjectType prefixes the composite keys of standing instructions
+	standingInstructionObjectType = "standinginstruction"
+
+	// debitAttemptObjectType prefixes the composite keys of recorded debit attempts
+	debitAttemptObjectType = "debitattempt"
+
+	// maxDebitDay keeps the debit day valid in every month
+	maxDebitDay = 28
+)
+
+var (
+	standingInstructionRepo = repository.New[StandingInstruction](standingInstructionObjectType)
+	debitAttemptRepo        = repository.New[DebitAttempt](debitAttemptObjectType)
+)
+
+// StandingInstruction authorizes the bank to collect a loan's installments from a token account
+// on a fixed day of the month. The account must grant the collecting client an allowance.
+type StandingInstruction struct {
+	LoanID          string `json:"loanId"`
+	AccountRef      string `json:"accountRef"`
+	DayOfMonth      int    `json:"dayOfMonth"`
+	LastAttemptDate string `json:"lastAttemptDate"`
+}
+
+// DebitAttempt records one attempt to collect an installment under a standing instruction
+type DebitAttempt struct {
+	LoanID      string `json:"loanId"`
+	Installment int    `json:"installment"`
+	AccountRef  string `json:"accountRef"`
+	Amount      int    `json:"amount"`
+	AttemptedOn string `json:"attemptedOn"`
+	Succeeded   bool   `json:"succeeded"`
+	Reason      string `json:"reason"`
+	TxID        string `json:"txId"`
+}
+
+// StandingInstructionSweep reports the outcome of ExecuteStandingInstructions
+type StandingInstructionSweep struct {
+	Debited   []string `json:"debited,omitempty" metadata:",optional"`
+	Failed    []string `json:"failed,omitempty" metadata:",optional"`
+	Deferred  []string `json:"deferred,omitempty" metadata:",optional"`
+	Skipped   []string `json:"skipped,omitempty" metadata:",optional"` // deleted loans, resumed once restored
+	Attempted int      `json:"attempted"`
+}
+
+// SetStandingInstruction sets up automatic repayment of a loan from the given token account on
+// dayOfMonth, replacing any earlier instruction for the loan. Restricted to the applicant and the
+// officer role.
+func (s *SmartContract) SetStandingInstruction(ctx contractapi.TransactionContextInterface, loanID string, accountRef string, dayOfMonth int) error {
+	loan, err := readLoan(ctx, loanID)
+	if err != nil {
+		return err
+	}
+	err = requireApplicantOrOfficer(ctx, loan)
+	if err != nil {
+		return err
+	}
+	if loan.Status == "Closed" || loan.Status == "Defaulted" {
+		return fmt.Errorf("the loan application %s is %s", loanID, loan.Status)
+	}
+	if accountRef == "" {
+		return fmt.Errorf("debit account must not be empty")
+	}
+	if dayOfMonth < 1 || dayOfMonth > maxDebitDay {
+		return fmt.Errorf("day of month must be between 1 and %d", maxDebitDay)
+	}
+
+	return putStandingInstruction(ctx, &StandingInstruction{
+		LoanID:     loanID,
+		AccountRef: accountRef,
+		DayOfMonth: dayOfMonth,
+	})
+}
+
+// ExecuteStandingInstructions collects the next unpaid installment of every loan whose debit day
+// for that installment's month falls on or before asOfDate. Each instruction is attempted at most
+// once per date. A successful debit settles the installment like RecordPayment; a failed one is
+// recorded and counted in the loan's FailedDebits for delinquency tracking. Loans sharing a debit
+// account with a loan already attempted in the sweep are deferred to the next run, since the token
+// chaincode's balances do not reflect the debits made earlier in the same transaction. The
+// instructions of deleted loans are skipped until RestoreLoan brings them back. Restricted to the
+// ops role.
+func (s *SmartContract) ExecuteStandingInstructions(ctx contractapi.TransactionContextInterface, asOfDate string) (*StandingInstructionSweep, error) {
+	err := requireRole(ctx, "ops")
+	if err != nil {
+		return nil, err
+	}
+
+	asOf, err := time.Parse("2006-01-02", asOfDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", asOfDate)
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if asOfDate > now.Format("2006-01-02") {
+		return nil, fmt.Errorf("standing instructions cannot be executed ahead of %s", now.Format("2006-01-02"))
+	}
+
+	// the repayments are collected into the submitting client's own token account
+	client, err := authz.ClientOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	collector := client.ID
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(standingInstructionObjectType, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	sweep := &StandingInstructionSweep{}
+	touched := make(map[string]bool)
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var instruction StandingInstruction
+		err = json.Unmarshal(queryResponse.Value, &instruction)
+		if err != nil {
+			return nil, err
+		}
+		if instruction.LastAttemptDate == asOfDate {
+			continue
+		}
+
+		loan, err := loanRepo.Get(ctx, instruction.LoanID)
+		if err != nil {
+			return nil, err
+		}
+		if loan == nil {
+			sweep.Skipped = append(sweep.Skipped, instruction.LoanID)
+			continue
+		}
+		if loan.Status != "Disbursed" && loan.Status != "Overdue" {
+			continue
+		}
+		installment, err := nextInstallment(ctx, loan)
+		if err != nil {
+			return nil, err
+		}
+		dueDate, err := time.Parse("2006-01-02", installment.DueDate)
+		if err != nil {
+			return nil, err
+		}
+		debitDate := time.Date(dueDate.Year(), dueDate.Month(), instruction.DayOfMonth, 0, 0, 0, 0, time.UTC)
+		if asOf.Before(debitDate) {
+			continue
+		}
+
+		if touched[instruction.AccountRef] {
+			sweep.Deferred = append(sweep.Deferred, loan.ID)
+			continue
+		}
+		touched[instruction.AccountRef] = true
+		sweep.Attempted++
+
+		debitErr := debitAccount(ctx, instruction.AccountRef, collector, installment.BorrowerAmount)
+		attempt := DebitAttempt{
+			LoanID:      loan.ID,
+			Installment: installment.Number,
+			AccountRef:  instruction.AccountRef,
+			Amount:      installment.BorrowerAmount,
+			AttemptedOn: asOfDate,
+			Succeeded:   debitErr == nil,
+			TxID:        ctx.GetStub().GetTxID(),
+		}
+		if debitErr != nil {
+			attempt.Reason = debitErr.Error()
+			authz.TxLogger(ctx).Warn("standing instruction debit failed", "loanId", loan.ID, "installment", installment.Number, "error", debitErr)
+			loan.FailedDebits++
+			err = putLoan(ctx, loan)
+			sweep.Failed = append(sweep.Failed, loan.ID)
+		} else {
+			loan.FailedDebits = 0
+			err = settleInstallment(ctx, loan, installment)
+			sweep.Debited = append(sweep.Debited, loan.ID)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		err = putDebitAttempt(ctx, &attempt)
+		if err != nil {
+			return nil, err
+		}
+		instruction.LastAttemptDate = asOfDate
+		err = putStandingInstruction(ctx, &instruction)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return sweep, nil
+}
+
+// GetStandingInstruction returns the standing instruction of a loan
+func (s *SmartContract) GetStandingInstruction(ctx contractapi.TransactionContextInterface, loanID string) (*StandingInstruction, error) {
+	instruction, err := standingInstructionRepo.Get(ctx, loanID)
+	if err != nil {
+		return nil, err
+	}
+	if instruction == nil {
+		return nil, fmt.Errorf("the loan application %s has no standing instruction", loanID)
+	}
+
+	return instruction, nil
+}
+
+// GetDebitAttempts returns every standing instruction debit attempted for a loan, oldest first
+func (s *SmartContract) GetDebitAttempts(ctx contractapi.TransactionContextInterface, loanID string) ([]*DebitAttempt, error) {
+	return debitAttemptRepo.List(ctx, loanID)
+}
+
+// debitAccount moves amount from the account to the collector on the token chaincode, drawing on
+// the allowance the account holder granted the submitting client
+func debitAccount(ctx contractapi.TransactionContextInterface, account string, collector string, amount int) error {
+	args := [][]byte{[]byte("TransferFrom"), []byte(account), []byte(collector), []byte(strconv.Itoa(amount))}
+	response := ctx.GetStub().InvokeChaincode(savingsChaincode, args, "")
+	if response.Status != shim.OK {
+		return fmt.Errorf("debit of %d from %s failed: %s", amount, account, response.Message)
+	}
+
+	return nil
+}
+
+func putStandingInstruction(ctx contractapi.TransactionContextInterface, instruction *StandingInstruction) error {
+	return standingInstructionRepo.Put(ctx, instruction, instruction.LoanID)
+}
+
+func putDebitAttempt(ctx contractapi.TransactionContextInterface, attempt *DebitAttempt) error {
+	return debitAttemptRepo.Put(ctx, attempt, attempt.LoanID, attempt.AttemptedOn)
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStandingInstructions(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+	balances := map[string]int{"acc1": 10000, "acc2": 0}
+	ledger.Install(savingsChaincode, savingsChaincodeOf(balances))
+	afraz := chaincodetest.Identity{MSPID: "Org1MSP", CommonName: "Afraz", Attributes: map[string]string{"role": "customer"}}
+
+	instruct := func(loanID string, account string, day int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.SetStandingInstruction(ctx, loanID, account, day)
+		}
+	}
+	execute := func(asOfDate string, check func(t *testing.T, sweep *StandingInstructionSweep)) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			sweep, err := s.ExecuteStandingInstructions(ctx, asOfDate)
+			if err == nil {
+				check(t, sweep)
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		createLoan("loan3", "Bob", 3000),
+		setStatus("loan1", "Approved"),
+		setStatus("loan1", "Disbursed"),
+		setStatus("loan2", "Disbursed"),
+		setStatus("loan3", "Approved"),
+		setStatus("loan3", "Disbursed"),
+		{Name: "instruct without an account", Caller: officer, Run: instruct("loan1", "", 5), Err: "debit account must not be empty"},
+		{Name: "instruct on the 31st", Caller: officer, Run: instruct("loan1", "acc1", 31), Err: "day of month must be between 1 and 28"},
+		{Name: "instruct a missing loan", Caller: officer, Run: instruct("loan9", "acc1", 5), Err: "does not exist"},
+		{Name: "only the applicant or officers instruct", Caller: customer, Run: instruct("loan1", "acc1", 5), Err: "requires the applicant of loan application loan1 or role officer"},
+		{Name: "the applicant instructs", Caller: afraz, Run: instruct("loan1", "acc1", 5)},
+		{Name: "instruct from a shared account", Caller: officer, Run: instruct("loan2", "acc1", 5)},
+		{Name: "instruct from an empty account", Caller: officer, Run: instruct("loan3", "acc2", 5)},
+		{
+			Name:   "read instruction",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				instruction, err := s.GetStandingInstruction(ctx, "loan1")
+				if err == nil {
+					require.Equal(t, StandingInstruction{LoanID: "loan1", AccountRef: "acc1", DayOfMonth: 5}, *instruction)
+				}
+				return err
+			},
+		},
+		{
+			Name:   "read missing instruction",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.GetStandingInstruction(ctx, "loan9")
+				return err
+			},
+			Err: "the loan application loan9 has no standing instruction",
+		},
+		{Name: "execute requires ops", Caller: bank, Run: execute("2024-01-01", nil), Err: "requires role ops"},
+		{Name: "execute an invalid date", Caller: ops, Run: execute("01/01/2024", nil), Err: `invalid date "01/01/2024"`},
+		{Name: "execute ahead of time", Caller: ops, Run: execute("2024-02-05", nil), Err: "standing instructions cannot be executed ahead of 2024-01-01"},
+		{
+			Name:   "nothing is due before the debit day",
+			Caller: ops,
+			Run: execute("2024-01-01", func(t *testing.T, sweep *StandingInstructionSweep) {
+				require.Zero(t, sweep.Attempted)
+			}),
+		},
+	})
+
+	ledger.Advance(35 * 24 * time.Hour)
+	ledger.Run(t, []chaincodetest.Case{
+		{
+			Name:   "execute on the debit day",
+			Caller: ops,
+			Run: execute("2024-02-05", func(t *testing.T, sweep *StandingInstructionSweep) {
+				require.Equal(t, []string{"loan1"}, sweep.Debited)
+				require.Equal(t, []string{"loan3"}, sweep.Failed)
+				require.Equal(t, []string{"loan2"}, sweep.Deferred)
+				require.Equal(t, 2, sweep.Attempted)
+			}),
+		},
+		{
+			Name:   "instructions are attempted once per date",
+			Caller: ops,
+			Run: execute("2024-02-05", func(t *testing.T, sweep *StandingInstructionSweep) {
+				require.Equal(t, []string{"loan2"}, sweep.Debited)
+				require.Empty(t, sweep.Failed)
+			}),
+		},
+		{
+			Name:   "failed debits are counted",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				loan, err := s.ReadLoanApplication(ctx, "loan3")
+				require.NoError(t, err)
+				require.Equal(t, 1, loan.FailedDebits)
+				attempts, err := s.GetDebitAttempts(ctx, "loan3")
+				if err == nil {
+					require.Len(t, attempts, 1)
+					require.False(t, attempts[0].Succeeded)
+					require.Contains(t, attempts[0].Reason, "insufficient funds")
+				}
+				return err
+			},
+		},
+		{
+			Name:   "successful debits settle the installment",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				loan, err := s.ReadLoanApplication(ctx, "loan1")
+				require.NoError(t, err)
+				require.Equal(t, 1, loan.PaidInstallments)
+				require.Less(t, balances["acc1"], 10000)
+				return nil
+			},
+		},
+		setStatus("loan3", "Defaulted"),
+		{Name: "instruct a defaulted loan", Caller: officer, Run: instruct("loan3", "acc1", 5), Err: "the loan application loan3 is Defaulted"},
+	})
+}
+
+func TestStandingInstructionsOfDeletedLoans(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+	ledger.Install(savingsChaincode, savingsChaincodeOf(map[string]int{"acc1": 10000}))
+
+	execute := func(skipped ...string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			sweep, err := s.ExecuteStandingInstructions(ctx, "2024-01-01")
+			if err == nil {
+				require.Equal(t, skipped, sweep.Skipped)
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		createLoan("loan3", "Bob", 3000),
+		{
+			Name:   "instruct",
+			Caller: officer,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.SetStandingInstruction(ctx, "loan3", "acc1", 5)
+			},
+		},
+		{
+			Name:   "delete the loan",
+			Caller: officer,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.DeleteLoanApplication(ctx, "loan3", "withdrawn")
+			},
+		},
+		{Name: "the sweep skips the deleted loan", Caller: ops, Run: execute("loan3")},
+		{
+			Name:   "restore the loan",
+			Caller: officer,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.RestoreLoan(ctx, "loan3")
+			},
+		},
+		{Name: "the restored loan's instruction resumes", Caller: ops, Run: execute()},
+	})
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	// standardProduct is the product of loans outside the catalog and not linked to a subsidy program
+	standardProduct = "standard"
+
+	// anyProduct keys the default rate applied to products the scenario does not list
+	anyProduct = "*"
+)
+
+// StressScenario is a set of hypothetical shocks applied to the loan book. Default rates and the
+// loss given default are percentages; rate shocks are in basis points.
+type StressScenario struct {
+	Name             string             `json:"name"`
+	RateShockBps     int                `json:"rateShockBps"`
+	DefaultRates     map[string]float64 `json:"defaultRates"`
+	LossGivenDefault float64            `json:"lossGivenDefault"`
+}
+
+// StressImpact is the projected effect of a scenario on one product in one currency, or on a whole
+// currency when Product is empty
+type StressImpact struct {
+	Product            string  `json:"product"`
+	Currency           string  `json:"currency"`
+	Loans              int     `json:"loans"`
+	Exposure           int     `json:"exposure"`
+	DefaultRate        float64 `json:"defaultRate"`
+	ExpectedDefaults   int     `json:"expectedDefaults"`
+	Provision          int     `json:"provision"`
+	AdditionalInterest int     `json:"additionalInterest"`
+}
+
+// StressResult reports a scenario's impact on the book by product and in total per currency
+type StressResult struct {
+	Scenario       string          `json:"scenario"`
+	RateShockBps   int             `json:"rateShockBps"`
+	LoansEvaluated int             `json:"loansEvaluated"`
+	Segments       []*StressImpact `json:"segments,omitempty" metadata:",optional"`
+	Totals         []*StressImpact `json:"totals,omitempty" metadata:",optional"`
+}
+
+// RunStressScenario applies the shocks in scenarioJSON to every approved and disbursed loan and
+// returns the projected provisioning and exposure impacts. A loan's product is its catalog
+// product, otherwise its subsidy program, or "standard" when it has neither; the default rate
+// listed under "*" applies to unlisted products. The rate shock is applied to the outstanding
+// principal over the remaining installments and reported as the additional interest it would
+// cost borrowers. The result depends only on the ledger and the scenario, so every peer evaluates
+// it identically. Meant to be evaluated, not submitted. Restricted to officer and ops roles.
+func (s *SmartContract) RunStressScenario(ctx contractapi.TransactionContextInterface, scenarioJSON string) (*StressResult, error) {
+	err := requireRole(ctx, "officer", "ops")
+	if err != nil {
+		return nil, err
+	}
+
+	var scenario StressScenario
+	err = json.Unmarshal([]byte(scenarioJSON), &scenario)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stress scenario: %v", err)
+	}
+	if scenario.LossGivenDefault == 0 {
+		scenario.LossGivenDefault = 100
+	}
+	if scenario.LossGivenDefault < 0 || scenario.LossGivenDefault > 100 {
+		return nil, fmt.Errorf("loss given default must be between 0 and 100 percent")
+	}
+	for product, rate := range scenario.DefaultRates {
+		if rate < 0 || rate > 100 {
+			return nil, fmt.Errorf("default rate of product %s must be between 0 and 100 percent", product)
+		}
+	}
+
+	loans, err := s.GetAllLoanApplications(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &StressResult{Scenario: scenario.Name, RateShockBps: scenario.RateShockBps}
+	segments := make(map[[2]string]*StressImpact)
+	totals := make(map[string]*StressImpact)
+	for _, loan := range loans {
+		if loan.Status != "Approved" && loan.Status != "Disbursed" && loan.Status != "Overdue" {
+			continue
+		}
+		exposure, err := outstandingPrincipal(loan)
+		if err != nil {
+			return nil, err
+		}
+		if exposure == 0 {
+			continue
+		}
+		result.LoansEvaluated++
+
+		product := standardProduct
+		if loan.ProductID != "" {
+			product = loan.ProductID
+		} else if loan.SubsidyProgramID != "" {
+			product = loan.SubsidyProgramID
+		}
+		defaultRate, ok := scenario.DefaultRates[product]
+		if !ok {
+			defaultRate = scenario.DefaultRates[anyProduct]
+		}
+		remaining := loan.Term - loan.PaidInstallments
+		shockedRate := loan.InterestRate + float64(scenario.RateShockBps)/100
+		additionalInterest := amortizedInterest(exposure, shockedRate, remaining) - amortizedInterest(exposure, loan.InterestRate, remaining)
+		if loan.ProductType == productTypeMurabaha {
+			// the markup of a Murabaha sale is fixed when it is agreed
+			additionalInterest = 0
+		}
+		expectedDefaults := int(math.Round(float64(exposure) * defaultRate / 100))
+		provision := int(math.Round(float64(expectedDefaults) * scenario.LossGivenDefault / 100))
+
+		segment, ok := segments[[2]string{product, loan.Currency}]
+		if !ok {
+			segment = &StressImpact{Product: product, Currency: loan.Currency, DefaultRate: defaultRate}
+			segments[[2]string{product, loan.Currency}] = segment
+			result.Segments = append(result.Segments, segment)
+		}
+		total, ok := totals[loan.Currency]
+		if !ok {
+			total = &StressImpact{Currency: loan.Currency}
+			totals[loan.Currency] = total
+			result.Totals = append(result.Totals, total)
+		}
+		for _, impact := range []*StressImpact{segment, total} {
+			impact.Loans++
+			impact.Exposure += exposure
+			impact.ExpectedDefaults += expectedDefaults
+			impact.Provision += provision
+			impact.AdditionalInterest += additionalInterest
+		}
+	}
+
+	sort.Slice(result.Segments, func(i, j int) bool {
+		if result.Segments[i].Currency != result.Segments[j].Currency {
+			return result.Segments[i].Currency < result.Segments[j].Currency
+		}
+		return result.Segments[i].Product < result.Segments[j].Product
+	})
+	sort.Slice(result.Totals, func(i, j int) bool {
+		return result.Totals[i].Currency < result.Totals[j].Currency
+	})
+	// a currency's default rate is the exposure-weighted average of its products' rates
+	for _, segment := range result.Segments {
+		total := totals[segment.Currency]
+		total.DefaultRate += segment.DefaultRate * float64(segment.Exposure) / float64(total.Exposure)
+	}
+	for _, total := range result.Totals {
+		total.DefaultRate = math.Round(total.DefaultRate*100) / 100
+	}
+
+	return result, nil
+}
+
+// amortizedInterest is the total interest paid on principal repaid in equal monthly installments
+// over the given number of months at an annual rate in percent
+func amortizedInterest(principal int, annualRate float64, months int) int {
+	if months <= 0 || principal <= 0 {
+		return 0
+	}
+	monthlyRate := annualRate / 12 / 100
+	if monthlyRate <= 0 {
+		return 0
+	}
+	payment := float64(principal) * monthlyRate / (1 - math.Pow(1+monthlyRate, -float64(months)))
+
+	return int(math.Round(payment*float64(months))) - principal
+}
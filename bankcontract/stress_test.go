@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunStressScenario(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	stress := func(scenarioJSON string, check func(t *testing.T, result *StressResult)) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			result, err := s.RunStressScenario(ctx, scenarioJSON)
+			if err == nil {
+				check(t, result)
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{
+			Name:   "create product",
+			Caller: ops,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.CreateProduct(ctx, "auto", "Auto loan", 9, 50000, 36)
+			},
+		},
+		{
+			Name:   "apply for product",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.ApplyForProduct(ctx, "loan3", "Bob", "auto", 20000, 24)
+			},
+		},
+		setStatus("loan3", "Approved"),
+		{Name: "stress requires officer or ops", Caller: bank, Run: stress(`{}`, nil), Err: "requires role officer or ops"},
+		{Name: "stress an invalid scenario", Caller: ops, Run: stress(`{`, nil), Err: "invalid stress scenario"},
+		{Name: "stress beyond total loss", Caller: ops, Run: stress(`{"lossGivenDefault":150}`, nil), Err: "loss given default must be between 0 and 100 percent"},
+		{Name: "stress a negative default rate", Caller: ops, Run: stress(`{"defaultRates":{"auto":-1}}`, nil), Err: "default rate of product auto must be between 0 and 100 percent"},
+		{
+			Name:   "stress the book",
+			Caller: officer,
+			Run: stress(`{"name":"recession","rateShockBps":200,"defaultRates":{"standard":10,"*":20},"lossGivenDefault":50}`, func(t *testing.T, result *StressResult) {
+				require.Equal(t, "recession", result.Scenario)
+				require.Equal(t, 2, result.LoansEvaluated)
+				require.Len(t, result.Segments, 2)
+				require.Equal(t, "auto", result.Segments[0].Product)
+				require.Equal(t, 20.0, result.Segments[0].DefaultRate)
+				require.Equal(t, 2000, result.Segments[0].Provision)
+				require.Equal(t, standardProduct, result.Segments[1].Product)
+				require.Equal(t, 250, result.Segments[1].Provision)
+				require.Len(t, result.Totals, 1)
+				require.Equal(t, 25000, result.Totals[0].Exposure)
+				require.Equal(t, 18.0, result.Totals[0].DefaultRate)
+				require.Greater(t, result.Totals[0].AdditionalInterest, 0)
+			}),
+		},
+		{
+			Name:   "total loss is the default",
+			Caller: ops,
+			Run: stress(`{"defaultRates":{"*":10}}`, func(t *testing.T, result *StressResult) {
+				require.Equal(t, result.Totals[0].ExpectedDefaults, result.Totals[0].Provision)
+				require.Zero(t, result.Totals[0].AdditionalInterest)
+			}),
+		},
+	})
+}
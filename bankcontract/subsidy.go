@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+
+	"authz"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+// subsidyObjectType prefixes the composite keys of subsidy programs
+const subsidyObjectType = "subsidy"
+
+var subsidyRepo = repository.New[SubsidyProgram](subsidyObjectType)
+
+// SubsidyProgram is an interest subsidy funded by a government organization. Eligible loans
+// linked to the program have RateDiscount percentage points of their interest paid from the budget.
+type SubsidyProgram struct {
+	ID            string  `json:"id"`
+	FundingMSP    string  `json:"fundingMSP"`
+	RateDiscount  float64 `json:"rateDiscount"`
+	MaxLoanAmount int     `json:"maxLoanAmount"`
+	MaxTerm       int     `json:"maxTerm"`
+	Budget        int     `json:"budget"`
+	Drawn         int     `json:"drawn"`
+	Active        bool    `json:"active"`
+}
+
+// CreateSubsidyProgram registers a subsidy program funded by the submitting client's organization.
+// Restricted to the government role.
+func (s *SmartContract) CreateSubsidyProgram(ctx contractapi.TransactionContextInterface, id string, rateDiscount float64, maxLoanAmount int, maxTerm int, budget int) error {
+	err := requireRole(ctx, "government")
+	if err != nil {
+		return err
+	}
+
+	existing, err := getSubsidyProgram(ctx, id)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("the subsidy program %s already exists", id)
+	}
+	if rateDiscount <= 0 {
+		return fmt.Errorf("rate discount must be positive")
+	}
+	if maxLoanAmount <= 0 || maxTerm <= 0 || budget <= 0 {
+		return fmt.Errorf("max loan amount, max term and budget must be positive")
+	}
+
+	mspID, err := authz.CallerMSP(ctx)
+	if err != nil {
+		return err
+	}
+
+	program := SubsidyProgram{
+		ID:            id,
+		FundingMSP:    mspID,
+		RateDiscount:  rateDiscount,
+		MaxLoanAmount: maxLoanAmount,
+		MaxTerm:       maxTerm,
+		Budget:        budget,
+		Active:        true,
+	}
+
+	return putSubsidyProgram(ctx, &program)
+}
+
+// ReadSubsidyProgram returns the subsidy program with the given ID
+func (s *SmartContract) ReadSubsidyProgram(ctx contractapi.TransactionContextInterface, id string) (*SubsidyProgram, error) {
+	program, err := getSubsidyProgram(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if program == nil {
+		return nil, fmt.Errorf("the subsidy program %s does not exist", id)
+	}
+
+	return program, nil
+}
+
+// CloseSubsidyProgram stops a program from accepting new loans. Loans already linked keep their subsidy
+// until the budget runs out. Only the funding organization may close its program.
+func (s *SmartContract) CloseSubsidyProgram(ctx contractapi.TransactionContextInterface, id string) error {
+	program, err := s.ReadSubsidyProgram(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	mspID, err := authz.CallerMSP(ctx)
+	if err != nil {
+		return err
+	}
+	if mspID != program.FundingMSP {
+		return fmt.Errorf("submitting client not authorized, subsidy program %s is funded by %s", id, program.FundingMSP)
+	}
+
+	program.Active = false
+	return putSubsidyProgram(ctx, program)
+}
+
+// LinkSubsidy attaches an eligible, not yet disbursed loan to an active subsidy program.
+// Restricted to the officer role.
+func (s *SmartContract) LinkSubsidy(ctx contractapi.TransactionContextInterface, loanID string, programID string) error {
+	err := requireRole(ctx, "officer")
+	if err != nil {
+		return err
+	}
+
+	loan, err := readLoan(ctx, loanID)
+	if err != nil {
+		return err
+	}
+	if loan.DisbursedAt != "" {
+		return fmt.Errorf("the loan application %s has already been disbursed", loanID)
+	}
+	if loan.TermsHash != "" {
+		return fmt.Errorf("the terms of loan application %s were fixed at approval", loanID)
+	}
+	if loan.SubsidyProgramID != "" {
+		return fmt.Errorf("the loan application %s is already linked to subsidy program %s", loanID, loan.SubsidyProgramID)
+	}
+	if loan.ProductType == productTypeMurabaha {
+		return fmt.Errorf("the loan application %s is a Murabaha sale and bears no interest to subsidize", loanID)
+	}
+
+	program, err := s.ReadSubsidyProgram(ctx, programID)
+	if err != nil {
+		return err
+	}
+	if !program.Active {
+		return fmt.Errorf("the subsidy program %s is closed", programID)
+	}
+	if loan.Amount > program.MaxLoanAmount {
+		return fmt.Errorf("loan amount %d exceeds the subsidy program limit of %d", loan.Amount, program.MaxLoanAmount)
+	}
+	if loan.Term > program.MaxTerm {
+		return fmt.Errorf("loan term %d exceeds the subsidy program limit of %d months", loan.Term, program.MaxTerm)
+	}
+
+	loan.SubsidyProgramID = programID
+	loan.SubsidyRate = program.RateDiscount
+	if loan.SubsidyRate > loan.InterestRate {
+		loan.SubsidyRate = loan.InterestRate
+	}
+
+	return putLoan(ctx, loan)
+}
+
+// drawSubsidy takes up to amount from the program budget and returns how much was actually drawn
+func drawSubsidy(ctx contractapi.TransactionContextInterface, programID string, amount int) (int, error) {
+	program, err := getSubsidyProgram(ctx, programID)
+	if err != nil {
+		return 0, err
+	}
+	if program == nil {
+		return 0, fmt.Errorf("the subsidy program %s does not exist", programID)
+	}
+
+	if remaining := program.Budget - program.Drawn; amount > remaining {
+		amount = remaining
+	}
+	if amount <= 0 {
+		return 0, nil
+	}
+
+	program.Drawn += amount
+	err = putSubsidyProgram(ctx, program)
+	if err != nil {
+		return 0, err
+	}
+
+	return amount, nil
+}
+
+func getSubsidyProgram(ctx contractapi.TransactionContextInterface, id string) (*SubsidyProgram, error) {
+	return subsidyRepo.Get(ctx, id)
+}
+
+func putSubsidyProgram(ctx contractapi.TransactionContextInterface, program *SubsidyProgram) error {
+	return subsidyRepo.Put(ctx, program, program.ID)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubsidyPrograms(t *testing.T) {
+	s := new(SmartContract)
+	government := chaincodetest.Identity{MSPID: "Org3MSP", CommonName: "ministry1", Attributes: map[string]string{"role": "government"}}
+	ledger := newLedger(t)
+
+	create := func(id string, rateDiscount float64, budget int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.CreateSubsidyProgram(ctx, id, rateDiscount, 20000, 24, budget)
+		}
+	}
+	link := func(loanID string, programID string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.LinkSubsidy(ctx, loanID, programID)
+		}
+	}
+	closeProgram := func(id string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.CloseSubsidyProgram(ctx, id)
+		}
+	}
+	pay := func(subsidy int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			installment, err := s.RecordPayment(ctx, "loan3")
+			if err == nil {
+				require.Equal(t, subsidy, installment.SubsidyAmount)
+				require.Equal(t, installment.Amount, installment.SubsidyAmount+installment.BorrowerAmount)
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "only the government creates programs", Caller: bank, Run: create("agri", 3, 50), Err: "submitting client not authorized, requires role government"},
+		{Name: "create without a discount", Caller: government, Run: create("agri", 0, 50), Err: "rate discount must be positive"},
+		{Name: "create without a budget", Caller: government, Run: create("agri", 3, 0), Err: "max loan amount, max term and budget must be positive"},
+		{Name: "create", Caller: government, Run: create("agri", 3, 50)},
+		{Name: "create twice", Caller: government, Run: create("agri", 3, 50), Err: "the subsidy program agri already exists"},
+		{Name: "create another", Caller: government, Run: create("housing", 2, 1000)},
+		{
+			Name:   "programs are funded by the creating organization",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				program, err := s.ReadSubsidyProgram(ctx, "agri")
+				if err == nil {
+					require.Equal(t, "Org3MSP", program.FundingMSP)
+					require.True(t, program.Active)
+				}
+				return err
+			},
+		},
+		createLoan("loan3", "Bob", 12000),
+		createLoan("loan4", "Bob", 30000),
+		{Name: "link beyond the amount limit", Caller: officer, Run: link("loan4", "agri"), Err: "loan amount 30000 exceeds the subsidy program limit of 20000"},
+		{Name: "only officers link", Caller: customer, Run: link("loan3", "agri"), Err: "submitting client not authorized, requires role officer"},
+		{Name: "link to a missing program", Caller: officer, Run: link("loan3", "fishing"), Err: "the subsidy program fishing does not exist"},
+		{Name: "link", Caller: officer, Run: link("loan3", "agri")},
+		{Name: "link twice", Caller: officer, Run: link("loan3", "housing"), Err: "the loan application loan3 is already linked to subsidy program agri"},
+		{Name: "close another organization's program", Caller: bank, Run: closeProgram("housing"), Err: "subsidy program housing is funded by Org3MSP"},
+		{Name: "close", Caller: government, Run: closeProgram("housing")},
+		{Name: "link to a closed program", Caller: officer, Run: link("loan1", "housing"), Err: "the subsidy program housing is closed"},
+		setStatus("loan3", "Approved"),
+		{Name: "link after approval", Caller: officer, Run: link("loan3", "agri"), Err: "the terms of loan application loan3 were fixed at approval"},
+		setStatus("loan3", "Disbursed"),
+		{Name: "link after disbursement", Caller: officer, Run: link("loan3", "agri"), Err: "the loan application loan3 has already been disbursed"},
+		{Name: "the program pays its share", Caller: officer, Run: pay(30)},
+		{Name: "the borrower pays what the budget cannot cover", Caller: officer, Run: pay(20)},
+		{Name: "an exhausted budget pays nothing", Caller: officer, Run: pay(0)},
+		{
+			Name:   "subsidy legs are recorded",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				repayments, err := s.GetRepayments(ctx, "loan3")
+				require.NoError(t, err)
+				require.Len(t, repayments, 5)
+				program, err := s.ReadSubsidyProgram(ctx, "agri")
+				if err == nil {
+					require.Equal(t, 50, program.Drawn)
+				}
+				return err
+			},
+		},
+	})
+}
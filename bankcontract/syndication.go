@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+const (
+	// syndicateShareObjectType prefixes the composite keys of syndicate shares, one per loan and
+	// participant
+	syndicateShareObjectType = "syndicateshare"
+
+	// catalogChaincode is the loan catalog chaincode each participant lender runs on its own
+	// channel, listing the products it lends under
+	catalogChaincode = "loancatalog"
+)
+
+var syndicateShareRepo = repository.New[SyndicateShare](syndicateShareObjectType)
+
+// SyndicateShare is the part of a loan's principal funded by a participant lender from another
+// channel. Repayments are passed on pro rata to the principal each participant funded; Received
+// is what the participant is owed from the installments settled so far.
+type SyndicateShare struct {
+	LoanID      string `json:"loanId"`
+	Participant string `json:"participant"` // MSP ID of the participant lender
+	Channel     string `json:"channel"`     // channel of the participant's loan catalog
+	ProductID   string `json:"productId"`
+	Amount      int    `json:"amount"`
+	Received    int    `json:"received"`
+	RecordedBy  string `json:"recordedBy"`
+	RecordedAt  string `json:"recordedAt"`
+}
+
+// RecordSyndicateShare records that a participant lender funds amount of a loan's principal under
+// one of its products. The product is looked up on the loan catalog chaincode of the participant's
+// channel; Fabric only allows querying a chaincode on another channel, so nothing is written
+// there. Shares can only be recorded before disbursement, one per participant, and together may
+// not exceed the loan amount. Restricted to the officer role.
+func (s *SmartContract) RecordSyndicateShare(ctx contractapi.TransactionContextInterface, loanID string, participant string, channel string, productID string, amount int) (*SyndicateShare, error) {
+	err := requireRole(ctx, "officer")
+	if err != nil {
+		return nil, err
+	}
+	if participant == "" || channel == "" || productID == "" {
+		return nil, fmt.Errorf("participant, channel and product ID must not be empty")
+	}
+	if amount <= 0 {
+		return nil, fmt.Errorf("share amount must be positive")
+	}
+
+	loan, err := readLoan(ctx, loanID)
+	if err != nil {
+		return nil, err
+	}
+	if loan.Status != "Pending" && loan.Status != "Approved" {
+		return nil, fmt.Errorf("the loan application %s is %s, syndicate shares can only be recorded before disbursement", loanID, loan.Status)
+	}
+	shares, err := syndicateShareRepo.List(ctx, loanID)
+	if err != nil {
+		return nil, err
+	}
+	syndicated := 0
+	for _, share := range shares {
+		if share.Participant == participant {
+			return nil, fmt.Errorf("%s already holds a share of the loan application %s", participant, loanID)
+		}
+		syndicated += share.Amount
+	}
+	if syndicated+amount > loan.Amount {
+		return nil, fmt.Errorf("syndicated shares of %d plus %d exceed the loan amount of %d", syndicated, amount, loan.Amount)
+	}
+
+	response := ctx.GetStub().InvokeChaincode(catalogChaincode, [][]byte{[]byte("ReadAsset"), []byte(productID)}, channel)
+	if response.Status != shim.OK {
+		return nil, fmt.Errorf("failed to read product %s from %s on channel %s: %s", productID, catalogChaincode, channel, response.Message)
+	}
+
+	operator, _, err := operatorName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	share := SyndicateShare{
+		LoanID:      loanID,
+		Participant: participant,
+		Channel:     channel,
+		ProductID:   productID,
+		Amount:      amount,
+		RecordedBy:  operator,
+		RecordedAt:  now.Format(time.RFC3339),
+	}
+	err = putSyndicateShare(ctx, &share)
+	if err != nil {
+		return nil, err
+	}
+
+	return &share, nil
+}
+
+// GetSyndicateShares returns the syndicate shares of a loan with the repayments each participant
+// has received
+func (s *SmartContract) GetSyndicateShares(ctx contractapi.TransactionContextInterface, loanID string) ([]*SyndicateShare, error) {
+	return syndicateShareRepo.List(ctx, loanID)
+}
+
+// allocateToSyndicate passes each participant its pro rata part of a settled installment. The
+// rounding remainder stays with the lead lender.
+func allocateToSyndicate(ctx contractapi.TransactionContextInterface, loan *LoanApplication, installment *Installment) error {
+	shares, err := syndicateShareRepo.List(ctx, loan.ID)
+	if err != nil {
+		return err
+	}
+	for _, share := range shares {
+		share.Received += installment.Amount * share.Amount / loan.Amount
+		err = putSyndicateShare(ctx, share)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func putSyndicateShare(ctx contractapi.TransactionContextInterface, share *SyndicateShare) error {
+	return syndicateShareRepo.Put(ctx, share, share.LoanID, share.Participant)
+}
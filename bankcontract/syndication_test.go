@@ -0,0 +1,80 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"chaincodetest/chaincodefakes"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyndication(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+	ledger.Install(catalogChaincode, func(function string, args []string) peer.Response {
+		if function != "ReadAsset" || args[0] != "prod1" {
+			return shim.Error("the asset " + args[0] + " does not exist")
+		}
+		return shim.Success([]byte(`{"ID":"prod1"}`))
+	})
+
+	record := func(participant string, productID string, amount int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.RecordSyndicateShare(ctx, "loan1", participant, "lenders", productID, amount)
+			return err
+		}
+	}
+
+	var installment *Installment
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "record requires officer", Caller: bank, Run: record("Org2MSP", "prod1", 4000), Err: "requires role officer"},
+		{Name: "record a non-positive share", Caller: officer, Run: record("Org2MSP", "prod1", 0), Err: "share amount must be positive"},
+		{Name: "record an unknown product", Caller: officer, Run: record("Org2MSP", "prod9", 4000), Err: "failed to read product prod9 from loancatalog on channel lenders"},
+		{
+			Name:   "record",
+			Caller: officer,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				share, err := s.RecordSyndicateShare(ctx, "loan1", "Org2MSP", "lenders", "prod1", 4000)
+				if err == nil {
+					require.Equal(t, "officer1", share.RecordedBy)
+					_, _, channel := ctx.GetStub().(*chaincodefakes.ChaincodeStub).InvokeChaincodeArgsForCall(0)
+					require.Equal(t, "lenders", channel)
+				}
+				return err
+			},
+		},
+		{Name: "record a participant twice", Caller: officer, Run: record("Org2MSP", "prod1", 1000), Err: "Org2MSP already holds a share of the loan application loan1"},
+		{Name: "record a second participant", Caller: officer, Run: record("Org3MSP", "prod1", 5000)},
+		{Name: "record beyond the loan amount", Caller: officer, Run: record("Org4MSP", "prod1", 2000), Err: "syndicated shares of 9000 plus 2000 exceed the loan amount of 10000"},
+		setStatus("loan1", "Approved"),
+		setStatus("loan1", "Disbursed"),
+		{Name: "record after disbursement", Caller: officer, Run: record("Org4MSP", "prod1", 1000), Err: "syndicate shares can only be recorded before disbursement"},
+		{
+			Name:   "pay an installment",
+			Caller: officer,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				var err error
+				installment, err = s.RecordPayment(ctx, "loan1")
+				return err
+			},
+		},
+		{
+			Name:   "participants receive their part",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				shares, err := s.GetSyndicateShares(ctx, "loan1")
+				if err == nil {
+					require.Len(t, shares, 2)
+					require.Equal(t, "Org2MSP", shares[0].Participant)
+					require.Equal(t, installment.Amount*4000/10000, shares[0].Received)
+					require.Equal(t, "Org3MSP", shares[1].Participant)
+					require.Equal(t, installment.Amount/2, shares[1].Received)
+				}
+				return err
+			},
+		},
+	})
+}
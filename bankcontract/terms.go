@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	// termsObjectType prefixes the composite keys of terms records
+	termsObjectType = "terms"
+
+	// scheduleMethod names how repaymentSchedule derives installments from the terms
+	scheduleMethod = "equal-installment-declining-balance"
+)
+
+// TermsRecord is the immutable snapshot of the terms a loan was approved on
+type TermsRecord struct {
+	LoanID            string  `json:"loanId"`
+	Applicant         string  `json:"applicant"`
+	Amount            int     `json:"amount"`
+	Currency          string  `json:"currency"`
+	Term              int     `json:"term"`
+	InterestRate      float64 `json:"interestRate"`
+	ProfitMarkup      float64 `json:"profitMarkup,omitempty" metadata:",optional"` // of Murabaha sales, instead of interest
+	ScheduleMethod    string  `json:"scheduleMethod"`
+	SubsidyProgramID  string  `json:"subsidyProgramId"`
+	SubsidyRate       float64 `json:"subsidyRate"`
+	CommitmentAccount string  `json:"commitmentAccount"`
+	CommitmentAmount  int     `json:"commitmentAmount"`
+	GuaranteedAmount  int     `json:"guaranteedAmount"`
+	ApprovedAt        string  `json:"approvedAt"`
+	TxID              string  `json:"txId"`
+}
+
+// GetTermsRecord returns the terms a loan was approved on, after checking them against the hash
+// stored on the loan
+func (s *SmartContract) GetTermsRecord(ctx contractapi.TransactionContextInterface, loanID string) (*TermsRecord, error) {
+	loan, err := readLoan(ctx, loanID)
+	if err != nil {
+		return nil, err
+	}
+	if loan.TermsHash == "" {
+		return nil, fmt.Errorf("the loan application %s has not been approved", loanID)
+	}
+
+	termsKey, err := ctx.GetStub().CreateCompositeKey(termsObjectType, []string{loanID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	termsJSON, err := ctx.GetStub().GetState(termsKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if termsJSON == nil {
+		return nil, fmt.Errorf("the terms record of loan application %s is missing", loanID)
+	}
+	if hashHex(termsJSON) != loan.TermsHash {
+		return nil, fmt.Errorf("the terms record of loan application %s does not match its hash", loanID)
+	}
+
+	var terms TermsRecord
+	err = json.Unmarshal(termsJSON, &terms)
+	if err != nil {
+		return nil, err
+	}
+
+	return &terms, nil
+}
+
+// snapshotTerms records the loan's effective terms, including the amount covered by confirmed
+// guarantees, the first time it is approved and stores their hash on the loan. A loan approved
+// again keeps its original terms.
+func snapshotTerms(ctx contractapi.TransactionContextInterface, loan *LoanApplication, guaranteed int) error {
+	if loan.TermsHash != "" {
+		return nil
+	}
+
+	termsKey, err := ctx.GetStub().CreateCompositeKey(termsObjectType, []string{loan.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	existing, err := ctx.GetStub().GetState(termsKey)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if existing != nil {
+		return fmt.Errorf("the terms of loan application %s have already been recorded", loan.ID)
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	terms := TermsRecord{
+		LoanID:            loan.ID,
+		Applicant:         loan.Applicant,
+		Amount:            loan.Amount,
+		Currency:          loan.Currency,
+		Term:              loan.Term,
+		InterestRate:      loan.InterestRate,
+		ProfitMarkup:      loan.ProfitMarkup,
+		ScheduleMethod:    scheduleMethod,
+		SubsidyProgramID:  loan.SubsidyProgramID,
+		SubsidyRate:       loan.SubsidyRate,
+		CommitmentAccount: loan.CommitmentAccount,
+		CommitmentAmount:  loan.CommitmentAmount,
+		GuaranteedAmount:  guaranteed,
+		ApprovedAt:        now.Format(time.RFC3339),
+		TxID:              ctx.GetStub().GetTxID(),
+	}
+	if loan.ProductType == productTypeMurabaha {
+		terms.ScheduleMethod = murabahaScheduleMethod
+	}
+	termsJSON, err := json.Marshal(terms)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().PutState(termsKey, termsJSON)
+	if err != nil {
+		return err
+	}
+
+	loan.TermsHash = hashHex(termsJSON)
+	return nil
+}
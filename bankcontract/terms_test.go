@@ -0,0 +1,84 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTermsRecords(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	readTerms := func(loanID string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.GetTermsRecord(ctx, loanID)
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "terms of a pending loan", Caller: bank, Run: readTerms("loan1"), Err: "the loan application loan1 has not been approved"},
+		setStatus("loan1", "Approved"),
+		{
+			Name:   "approval records the terms",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				terms, err := s.GetTermsRecord(ctx, "loan1")
+				if err == nil {
+					require.Equal(t, "Afraz", terms.Applicant)
+					require.Equal(t, 10000, terms.Amount)
+					require.Equal(t, scheduleMethod, terms.ScheduleMethod)
+					require.NotEmpty(t, terms.TxID)
+				}
+				return err
+			},
+		},
+		setStatus("loan1", "Pending"),
+		{
+			Name:   "lower the rate before approving again",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				loan, err := readLoan(ctx, "loan1")
+				require.NoError(t, err)
+				loan.InterestRate = 1
+				return putLoan(ctx, loan)
+			},
+		},
+		setStatus("loan1", "Approved"),
+		{
+			Name:   "loans approved again keep their original terms",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				terms, err := s.GetTermsRecord(ctx, "loan1")
+				if err == nil {
+					require.Equal(t, 5.5, terms.InterestRate)
+				}
+				return err
+			},
+		},
+		{
+			Name:   "tamper with the terms",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				key, err := ctx.GetStub().CreateCompositeKey(termsObjectType, []string{"loan1"})
+				require.NoError(t, err)
+				return ctx.GetStub().PutState(key, []byte(`{"loanId":"loan1","interestRate":0.5}`))
+			},
+		},
+		{Name: "tampered terms are refused", Caller: bank, Run: readTerms("loan1"), Err: "the terms record of loan application loan1 does not match its hash"},
+		{
+			Name:   "drop the terms",
+			Caller: bank,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				key, err := ctx.GetStub().CreateCompositeKey(termsObjectType, []string{"loan1"})
+				require.NoError(t, err)
+				return ctx.GetStub().DelState(key)
+			},
+		},
+		{Name: "missing terms are reported", Caller: bank, Run: readTerms("loan1"), Err: "the terms record of loan application loan1 is missing"},
+		{Name: "terms of a missing loan", Caller: bank, Run: readTerms("loan9"), Err: "the loan application loan9 does not exist"},
+	})
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"authz"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+const (
+	// bankConfigObjectType prefixes the composite key of the bank-wide settings
+	bankConfigObjectType = "bankconfig"
+	// throttleObjectType prefixes the composite keys of the per-client call counters,
+	// throttle~mspID~clientID
+	throttleObjectType = "throttle"
+)
+
+var (
+	bankConfigRepo = repository.New[BankConfig](bankConfigObjectType)
+	throttleRepo   = repository.New[ThrottleCounter](throttleObjectType)
+)
+
+// throttleExemptRoles are the roles whose calls are never throttled, so that operators can
+// always reconfigure the throttle and scheduled jobs always run
+var throttleExemptRoles = []string{"ops", "scheduler"}
+
+// queryPrefixes and queryFunctions name the functions that only read the ledger. The throttle lets
+// them through without counting them, as it must: the paginated queries among them fail once the
+// transaction has written.
+var (
+	queryPrefixes  = []string{"Get", "Read", "List", "Preview", "Convert", "Export"}
+	queryFunctions = map[string]bool{"LoanExists": true, "VerifyReviewerAssignment": true, "RunStressScenario": true}
+)
+
+// BankConfig is the bank-wide settings. MaxCallsPerWindow is how many mutating calls each client
+// may make per window of WindowSeconds of transaction time; zero turns the throttle off.
+type BankConfig struct {
+	MaxCallsPerWindow int    `json:"maxCallsPerWindow"`
+	WindowSeconds     int    `json:"windowSeconds"`
+	SetBy             string `json:"setBy"`
+	SetAt             string `json:"setAt"`
+}
+
+// ThrottleCounter counts the mutating calls of a client in its current window. A window starts
+// with the first call after the previous one has run out.
+type ThrottleCounter struct {
+	MSPID       string `json:"mspId"`
+	ClientID    string `json:"clientId"`
+	WindowStart string `json:"windowStart"`
+	Calls       int    `json:"calls"`
+}
+
+// SetBankConfig limits every client outside the ops and scheduler roles to maxCallsPerWindow
+// mutating calls per windowSeconds, so that a runaway client script cannot flood the ordering
+// service. A maxCallsPerWindow of zero turns the limit off. Restricted to the ops role.
+func (s *SmartContract) SetBankConfig(ctx contractapi.TransactionContextInterface, maxCallsPerWindow int, windowSeconds int) error {
+	err := requireRole(ctx, "ops")
+	if err != nil {
+		return err
+	}
+	if maxCallsPerWindow < 0 {
+		return fmt.Errorf("maximum calls per window must not be negative")
+	}
+	if windowSeconds <= 0 {
+		return fmt.Errorf("window must be positive")
+	}
+
+	operator, _, err := operatorName(ctx)
+	if err != nil {
+		return err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	return bankConfigRepo.Put(ctx, &BankConfig{
+		MaxCallsPerWindow: maxCallsPerWindow,
+		WindowSeconds:     windowSeconds,
+		SetBy:             operator,
+		SetAt:             now.Format(time.RFC3339),
+	}, "current")
+}
+
+// GetBankConfig returns the bank-wide settings, all zero while ops have not set them
+func (s *SmartContract) GetBankConfig(ctx contractapi.TransactionContextInterface) (*BankConfig, error) {
+	config, err := bankConfigRepo.Get(ctx, "current")
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		config = &BankConfig{}
+	}
+
+	return config, nil
+}
+
+// throttle is the BeforeTransaction handler counting the mutating calls of the submitting client
+// and refusing those beyond the limit of the BankConfig. The counter is only committed with a
+// submitted transaction, so evaluated calls never count. Since every counted call writes the
+// client's counter, concurrent transactions of one client also fail MVCC validation; clients
+// should submit one transaction at a time.
+func throttle(ctx contractapi.TransactionContextInterface) error {
+	function, _ := ctx.GetStub().GetFunctionAndParameters()
+	if i := strings.LastIndex(function, ":"); i >= 0 {
+		function = function[i+1:]
+	}
+	if isQuery(function) {
+		return nil
+	}
+	config, err := bankConfigRepo.Get(ctx, "current")
+	if err != nil {
+		return err
+	}
+	if config == nil || config.MaxCallsPerWindow == 0 {
+		return nil
+	}
+	if requireRole(ctx, throttleExemptRoles...) == nil {
+		return nil
+	}
+
+	client, err := authz.ClientOf(ctx)
+	if err != nil {
+		return err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	window := time.Duration(config.WindowSeconds) * time.Second
+	counter, err := throttleRepo.Get(ctx, client.MSPID, client.ID)
+	if err != nil {
+		return err
+	}
+	windowStart := now
+	if counter != nil {
+		windowStart, err = time.Parse(time.RFC3339, counter.WindowStart)
+		if err != nil {
+			return err
+		}
+	}
+	if counter == nil || !now.Before(windowStart.Add(window)) {
+		windowStart = now
+		counter = &ThrottleCounter{MSPID: client.MSPID, ClientID: client.ID, WindowStart: now.Format(time.RFC3339)}
+	}
+	if counter.Calls >= config.MaxCallsPerWindow {
+		authz.TxLogger(ctx).Warn("client throttled", "calls", counter.Calls)
+		return fmt.Errorf("THROTTLED: client %s exceeded %d calls per %d seconds, retry after %s", client.CommonName, config.MaxCallsPerWindow, config.WindowSeconds, windowStart.Add(window).Format(time.RFC3339))
+	}
+	counter.Calls++
+
+	return throttleRepo.Put(ctx, counter, client.MSPID, client.ID)
+}
+
+// isQuery reports whether a contract function only reads the ledger
+func isQuery(function string) bool {
+	if queryFunctions[function] {
+		return true
+	}
+	for _, prefix := range queryPrefixes {
+		if strings.HasPrefix(function, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"chaincodetest"
+	"chaincodetest/chaincodefakes"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThrottle(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	configure := func(maxCalls int, windowSeconds int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.SetBankConfig(ctx, maxCalls, windowSeconds)
+		}
+	}
+	call := func(name string, function string, caller chaincodetest.Identity, err string) chaincodetest.Case {
+		return chaincodetest.Case{
+			Name:   name,
+			Caller: caller,
+			Stub: func(stub *chaincodefakes.ChaincodeStub) {
+				stub.GetFunctionAndParametersReturns(function, nil)
+			},
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return throttle(ctx)
+			},
+			Err: err,
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		call("no limit until configured", "CreateLoanApplication", officer, ""),
+		call("no limit until configured again", "CreateLoanApplication", officer, ""),
+		call("nor a third time", "CreateLoanApplication", officer, ""),
+		{Name: "only ops configure the bank", Caller: officer, Run: configure(2, 60), Err: "requires role ops"},
+		{Name: "negative limit", Caller: ops, Run: configure(-1, 60), Err: "maximum calls per window must not be negative"},
+		{Name: "empty window", Caller: ops, Run: configure(2, 0), Err: "window must be positive"},
+		{Name: "configure", Caller: ops, Run: configure(2, 60)},
+		{
+			Name:   "read the configuration",
+			Caller: customer,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				config, err := s.GetBankConfig(ctx)
+				if err == nil {
+					require.Equal(t, 2, config.MaxCallsPerWindow)
+					require.Equal(t, 60, config.WindowSeconds)
+					require.Equal(t, "ops1", config.SetBy)
+				}
+				return err
+			},
+		},
+		call("first call", "CreateLoanApplication", officer, ""),
+		call("second call", "SmartContract:UpdateLoanStatus", officer, ""),
+		call("third call", "CreateLoanApplication", officer, "THROTTLED: client officer1 exceeded 2 calls per 60 seconds, retry after "),
+		call("queries are not counted", "GetLoansByApplicantPaginated", officer, ""),
+		call("other clients have their own counter", "CreateLoanApplication", officer2, ""),
+		call("ops are never throttled", "SetExchangeRate", ops, ""),
+		call("nor a second time", "SetExchangeRate", ops, ""),
+		call("nor a third time", "SetExchangeRate", ops, ""),
+	})
+
+	ledger.Advance(time.Minute)
+	ledger.Run(t, []chaincodetest.Case{
+		call("a new window", "CreateLoanApplication", officer, ""),
+		{Name: "turn the limit off", Caller: ops, Run: configure(0, 60)},
+		call("no limit", "CreateLoanApplication", officer, ""),
+		call("no limit again", "CreateLoanApplication", officer, ""),
+	})
+}
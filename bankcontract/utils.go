@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"authz"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// txTime returns the transaction timestamp, which is identical on every endorsing peer
+func txTime(ctx contractapi.TransactionContextInterface) (time.Time, error) {
+	ts, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	return time.Unix(ts.Seconds, int64(ts.Nanos)).UTC(), nil
+}
+
+// requireRole returns an error unless the submitting client carries a role attribute
+// matching one of the given roles
+func requireRole(ctx contractapi.TransactionContextInterface, roles ...string) error {
+	role, found, err := authz.Attribute(ctx, "role")
+	if err != nil {
+		return fmt.Errorf("failed to read client role attribute: %v", err)
+	}
+	if found {
+		for _, allowed := range roles {
+			if role == allowed {
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("submitting client not authorized, requires role %s", strings.Join(roles, " or "))
+}
+
+// requireApplicantOrOfficer returns an error unless the submitting client is the applicant of the
+// loan, by its enrollment ID, or carries the officer role
+func requireApplicantOrOfficer(ctx contractapi.TransactionContextInterface, loan *LoanApplication) error {
+	if requireRole(ctx, "officer") == nil {
+		return nil
+	}
+	applicant, err := enrollmentID(ctx)
+	if err != nil {
+		return err
+	}
+	if applicant != loan.Applicant {
+		return fmt.Errorf("submitting client not authorized, requires the applicant of loan application %s or role officer", loan.ID)
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+// Command demo drives a scripted scenario across the identity, loan and Pokemon chaincodes on the
+// test network, asserting the ledger state after every step. It doubles as living documentation of
+// how the contracts fit together and as a smoke test of a deployment.
+package main
+
+import (
+	"crypto/x509"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/identity"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// config holds the network settings of the demo
+type config struct {
+	channelName       string
+	identityChaincode string
+	bankChaincode     string
+	pokemonChaincode  string
+	walletPath        string
+	tlsCertPath       string
+	peerEndpoint      string
+	peerHostAlias     string
+}
+
+func main() {
+	cryptoPath := envOrDefault("CRYPTO_PATH", "../../test-network/organizations/peerOrganizations/org1.example.com")
+	cfg := config{
+		channelName:       envOrDefault("CHANNEL_NAME", "mychannel"),
+		identityChaincode: envOrDefault("IDENTITY_CHAINCODE_NAME", "identitycontract"),
+		bankChaincode:     envOrDefault("BANK_CHAINCODE_NAME", "bankcontract"),
+		pokemonChaincode:  envOrDefault("POKEMON_CHAINCODE_NAME", "pokemoncontract"),
+		walletPath:        envOrDefault("WALLET_PATH", "wallet"),
+		tlsCertPath:       envOrDefault("TLS_CERT_PATH", cryptoPath+"/peers/peer0.org1.example.com/tls/ca.crt"),
+		peerEndpoint:      envOrDefault("PEER_ENDPOINT", "dns:///localhost:7051"),
+		peerHostAlias:     envOrDefault("PEER_HOST_ALIAS", "peer0.org1.example.com"),
+	}
+
+	connection, err := newGrpcConnection(cfg)
+	if err != nil {
+		log.Fatalf("Error connecting to the gateway peer: %v", err)
+	}
+	defer connection.Close()
+
+	// a fresh run ID keeps the records of every run apart, so the demo can be run repeatedly
+	run := strconv.FormatInt(time.Now().Unix(), 36)
+	d, err := newDemo(cfg, connection, run)
+	if err != nil {
+		log.Fatalf("Error loading the wallet: %v", err)
+	}
+	defer d.close()
+
+	fmt.Printf("Running the demo scenario %s\n", run)
+	for i, step := range scenario() {
+		fmt.Printf("\n--> %d. %s\n", i+1, step.name)
+		err := step.run(d)
+		if err != nil {
+			fmt.Printf("*** FAILED: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("*** OK")
+	}
+	fmt.Println("\nThe demo scenario completed successfully")
+}
+
+func newGrpcConnection(cfg config) (*grpc.ClientConn, error) {
+	certificatePEM, err := os.ReadFile(cfg.tlsCertPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read TLS certificate file: %w", err)
+	}
+	certificate, err := identity.CertificateFromPEM(certificatePEM)
+	if err != nil {
+		return nil, err
+	}
+
+	certPool := x509.NewCertPool()
+	certPool.AddCert(certificate)
+	transportCredentials := credentials.NewClientTLSFromCert(certPool, cfg.peerHostAlias)
+
+	connection, err := grpc.NewClient(cfg.peerEndpoint, grpc.WithTransportCredentials(transportCredentials))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gRPC connection: %w", err)
+	}
+
+	return connection, nil
+}
+
+// envOrDefault returns the value of an environment variable, or the default when it is unset
+func envOrDefault(key, defaultValue string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
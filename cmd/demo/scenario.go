@@ -0,0 +1,238 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// step is one action of the scenario together with the checks of its outcome
+type step struct {
+	name string
+	run  func(d *demo) error
+}
+
+// The subset of the chaincode records the scenario checks
+type (
+	identityRecord struct {
+		ID       string `json:"id"`
+		KYCLevel string `json:"kycLevel"`
+	}
+
+	loanRecord struct {
+		ID               string `json:"id"`
+		Status           string `json:"status"`
+		Term             int    `json:"term"`
+		TermsHash        string `json:"termsHash"`
+		DisbursedAt      string `json:"disbursedAt"`
+		PaidInstallments int    `json:"paidInstallments"`
+	}
+
+	repaymentRecord struct {
+		Installment int    `json:"installment"`
+		Payer       string `json:"payer"`
+		Amount      int    `json:"amount"`
+	}
+
+	pokemonRecord struct {
+		ID      string `json:"id"`
+		Trainer string `json:"trainer"`
+	}
+
+	transferRecord struct {
+		FromTrainer string `json:"fromTrainer"`
+		NewTrainer  string `json:"newTrainer"`
+	}
+)
+
+// scenario is the lifecycle of a loan to a freshly registered customer, from KYC through
+// delinquency to write-off, followed by a Pokemon trade between two trainers. Loans above the
+// bank's Enhanced KYC threshold of 50000 are only approved once the identity chaincode reports
+// Enhanced KYC, so the loan below ties the two contracts together.
+func scenario() []step {
+	return []step{
+		{"register the applicant's identity", func(d *demo) error {
+			_, err := d.submit(registrarLabel, d.config.identityChaincode, "CreateIdentity",
+				d.applicantID(), "Mr", "Ali", "Khan", d.cnic(), "1990-01-01", "Male", "03001234567")
+			if err != nil {
+				return err
+			}
+			return d.expectKYCLevel("Basic")
+		}},
+		{"the officer cannot approve a large loan before Enhanced KYC", func(d *demo) error {
+			_, err := d.submit(officerLabel, d.config.bankChaincode, "CreateLoanApplication", d.loanID(), d.applicantID(), "60000", "12", "7.5")
+			if err != nil {
+				return err
+			}
+			err = d.expectLoan(func(loan *loanRecord) bool { return loan.Status == "Pending" }, "Pending")
+			if err != nil {
+				return err
+			}
+			_, err = d.submit(officerLabel, d.config.bankChaincode, "UpdateLoanStatus", d.loanID(), "Approved")
+			return expectFailure(err, "require Enhanced KYC")
+		}},
+		{"verify the applicant to Enhanced KYC", func(d *demo) error {
+			_, err := d.submit(registrarLabel, d.config.identityChaincode, "SetKYCLevel", d.applicantID(), "Enhanced")
+			if err != nil {
+				return err
+			}
+			return d.expectKYCLevel("Enhanced")
+		}},
+		{"approve the loan", func(d *demo) error {
+			_, err := d.submit(officerLabel, d.config.bankChaincode, "UpdateLoanStatus", d.loanID(), "Approved")
+			if err != nil {
+				return err
+			}
+			return d.expectLoan(func(loan *loanRecord) bool { return loan.Status == "Approved" && loan.TermsHash != "" }, "Approved with its terms snapshotted")
+		}},
+		{"disburse the loan", func(d *demo) error {
+			_, err := d.submit(officerLabel, d.config.bankChaincode, "UpdateLoanStatus", d.loanID(), "Disbursed")
+			if err != nil {
+				return err
+			}
+			return d.expectLoan(func(loan *loanRecord) bool { return loan.Status == "Disbursed" && loan.DisbursedAt != "" }, "Disbursed with a disbursement time")
+		}},
+		{"repay the first installment", func(d *demo) error {
+			_, err := d.submit(officerLabel, d.config.bankChaincode, "RecordPayment", d.loanID())
+			if err != nil {
+				return err
+			}
+			err = d.expectLoan(func(loan *loanRecord) bool { return loan.PaidInstallments == 1 }, "one installment paid")
+			if err != nil {
+				return err
+			}
+
+			var repayments []repaymentRecord
+			err = d.evaluate(officerLabel, d.config.bankChaincode, &repayments, "GetRepayments", d.loanID())
+			if err != nil {
+				return err
+			}
+			if len(repayments) != 1 || repayments[0].Installment != 1 || repayments[0].Amount <= 0 {
+				return fmt.Errorf("expected one repayment of installment 1, got %+v", repayments)
+			}
+			return nil
+		}},
+		{"the borrower falls delinquent and the loan defaults", func(d *demo) error {
+			_, err := d.submit(officerLabel, d.config.bankChaincode, "UpdateLoanStatus", d.loanID(), "Defaulted")
+			if err != nil {
+				return err
+			}
+			err = d.expectLoan(func(loan *loanRecord) bool { return loan.Status == "Defaulted" }, "Defaulted")
+			if err != nil {
+				return err
+			}
+			_, err = d.submit(officerLabel, d.config.bankChaincode, "RecordPayment", d.loanID())
+			return expectFailure(err, "is not in repayment")
+		}},
+		{"write off the defaulted loan", func(d *demo) error {
+			_, err := d.submit(officerLabel, d.config.bankChaincode, "UpdateLoanStatus", d.loanID(), "Closed")
+			if err != nil {
+				return err
+			}
+			return d.expectLoan(func(loan *loanRecord) bool { return loan.Status == "Closed" && loan.PaidInstallments < loan.Term }, "Closed with installments outstanding")
+		}},
+		{"trainer1 catches a Pokemon", func(d *demo) error {
+			_, err := d.submit(trainer1Label, d.config.pokemonChaincode, "CreatePokemon", d.pokemonID(), "Pikachu", "Electric", trainer1Label, "Pallet Town", "55")
+			if err != nil {
+				return err
+			}
+			return d.expectTrainer(trainer1Label)
+		}},
+		{"trainer1 offers the Pokemon to trainer2", func(d *demo) error {
+			_, err := d.submit(trainer1Label, d.config.pokemonChaincode, "TransferPokemon", d.pokemonID(), trainer2Label)
+			if err != nil {
+				return err
+			}
+
+			var transfer transferRecord
+			err = d.evaluate(trainer2Label, d.config.pokemonChaincode, &transfer, "ReadTransfer", d.pokemonID())
+			if err != nil {
+				return err
+			}
+			if transfer.FromTrainer != trainer1Label || transfer.NewTrainer != trainer2Label {
+				return fmt.Errorf("expected a transfer from %s to %s, got %+v", trainer1Label, trainer2Label, transfer)
+			}
+			// the Pokemon stays with trainer1 until trainer2 accepts
+			return d.expectTrainer(trainer1Label)
+		}},
+		{"trainer2 accepts the trade", func(d *demo) error {
+			_, err := d.submit(trainer2Label, d.config.pokemonChaincode, "AcceptTransfer", d.pokemonID())
+			if err != nil {
+				return err
+			}
+			return d.expectTrainer(trainer2Label)
+		}},
+	}
+}
+
+func (d *demo) applicantID() string {
+	return "demo-applicant-" + d.run
+}
+
+// cnic derives a CNIC from the run ID, since every identity needs its own
+func (d *demo) cnic() string {
+	var digits int
+	for _, c := range d.run {
+		digits = (digits*36 + int(c)) % 10000000
+	}
+	return fmt.Sprintf("35202-%07d-1", digits)
+}
+
+func (d *demo) loanID() string {
+	return "demo-loan-" + d.run
+}
+
+func (d *demo) pokemonID() string {
+	return "demo-pokemon-" + d.run
+}
+
+func (d *demo) expectKYCLevel(want string) error {
+	var level string
+	err := d.evaluate(registrarLabel, d.config.identityChaincode, &level, "GetKYCLevel", d.applicantID())
+	if err != nil {
+		return err
+	}
+	if level != want {
+		return fmt.Errorf("expected identity %s at KYC level %s, got %q", d.applicantID(), want, level)
+	}
+	return nil
+}
+
+func (d *demo) expectLoan(check func(loan *loanRecord) bool, want string) error {
+	var loan loanRecord
+	err := d.evaluate(officerLabel, d.config.bankChaincode, &loan, "ReadLoanApplication", d.loanID())
+	if err != nil {
+		return err
+	}
+	if !check(&loan) {
+		return fmt.Errorf("expected loan %s %s, got %+v", d.loanID(), want, loan)
+	}
+	return nil
+}
+
+func (d *demo) expectTrainer(want string) error {
+	var pokemon pokemonRecord
+	err := d.evaluate(want, d.config.pokemonChaincode, &pokemon, "ReadPokemon", d.pokemonID())
+	if err != nil {
+		return err
+	}
+	if pokemon.Trainer != want {
+		return fmt.Errorf("expected Pokemon %s to be trained by %s, got %s", d.pokemonID(), want, pokemon.Trainer)
+	}
+	return nil
+}
+
+// expectFailure checks that a transaction was rejected by the chaincode with an error containing
+// phrase
+func expectFailure(err error, phrase string) error {
+	if err == nil {
+		return fmt.Errorf("expected the transaction to fail with %q, it succeeded", phrase)
+	}
+	messages := chaincodeMessages(err)
+	for _, message := range messages {
+		if strings.Contains(message, phrase) {
+			fmt.Printf("    rejected as expected: %s\n", message)
+			return nil
+		}
+	}
+	return fmt.Errorf("expected the transaction to fail with %q, got %v %q", phrase, err, messages)
+}
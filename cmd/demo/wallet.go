@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-gateway/pkg/hash"
+	"github.com/hyperledger/fabric-gateway/pkg/identity"
+	"github.com/hyperledger/fabric-protos-go-apiv2/gateway"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// The wallet labels of the identities playing the parts of the scenario
+const (
+	registrarLabel = "registrar" // kycRegistrar=Full, Org1
+	officerLabel   = "officer"   // role=officer, Org1
+	trainer1Label  = "trainer1"  // trainer=trainer1
+	trainer2Label  = "trainer2"  // trainer=trainer2
+)
+
+// walletIdentity is a <label>.id file in the wallet format the Fabric SDKs share
+type walletIdentity struct {
+	Type        string `json:"type"`
+	MSPID       string `json:"mspId"`
+	Credentials struct {
+		Certificate string `json:"certificate"`
+		PrivateKey  string `json:"privateKey"`
+	} `json:"credentials"`
+}
+
+// demo holds a gateway for each identity of the scenario
+type demo struct {
+	config   config
+	run      string
+	gateways map[string]*client.Gateway
+}
+
+func newDemo(cfg config, connection *grpc.ClientConn, run string) (*demo, error) {
+	d := &demo{config: cfg, run: run, gateways: map[string]*client.Gateway{}}
+	for _, label := range []string{registrarLabel, officerLabel, trainer1Label, trainer2Label} {
+		gateway, err := connect(cfg.walletPath, label, connection)
+		if err != nil {
+			d.close()
+			return nil, err
+		}
+		d.gateways[label] = gateway
+	}
+
+	return d, nil
+}
+
+func (d *demo) close() {
+	for _, gateway := range d.gateways {
+		gateway.Close()
+	}
+}
+
+// submit submits a transaction to a chaincode as the identity labelled label
+func (d *demo) submit(label string, chaincode string, name string, args ...string) ([]byte, error) {
+	fmt.Printf("    %s submits %s.%s%q\n", label, chaincode, name, args)
+	return d.gateways[label].GetNetwork(d.config.channelName).GetContract(chaincode).SubmitTransaction(name, args...)
+}
+
+// evaluate evaluates a transaction of a chaincode as the identity labelled label and decodes its
+// JSON result into result
+func (d *demo) evaluate(label string, chaincode string, result any, name string, args ...string) error {
+	fmt.Printf("    %s evaluates %s.%s%q\n", label, chaincode, name, args)
+	resultJSON, err := d.gateways[label].GetNetwork(d.config.channelName).GetContract(chaincode).EvaluateTransaction(name, args...)
+	if err != nil {
+		return err
+	}
+
+	// string results are returned as they are rather than as JSON
+	if s, ok := result.(*string); ok {
+		*s = string(resultJSON)
+		return nil
+	}
+	return json.Unmarshal(resultJSON, result)
+}
+
+func connect(walletPath string, label string, connection *grpc.ClientConn) (*client.Gateway, error) {
+	content, err := os.ReadFile(filepath.Join(walletPath, label+".id"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read wallet identity %s: %w", label, err)
+	}
+	var walletID walletIdentity
+	err = json.Unmarshal(content, &walletID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse wallet identity %s: %w", label, err)
+	}
+
+	certificate, err := identity.CertificateFromPEM([]byte(walletID.Credentials.Certificate))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the certificate of wallet identity %s: %w", label, err)
+	}
+	id, err := identity.NewX509Identity(walletID.MSPID, certificate)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, err := identity.PrivateKeyFromPEM([]byte(walletID.Credentials.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the private key of wallet identity %s: %w", label, err)
+	}
+	sign, err := identity.NewPrivateKeySign(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.Connect(
+		id,
+		client.WithSign(sign),
+		client.WithHash(hash.SHA256),
+		client.WithClientConnection(connection),
+		client.WithEvaluateTimeout(5*time.Second),
+		client.WithEndorseTimeout(15*time.Second),
+		client.WithSubmitTimeout(5*time.Second),
+		client.WithCommitStatusTimeout(1*time.Minute),
+	)
+}
+
+// chaincodeMessages returns the messages a chaincode failed with on each endorsing peer
+func chaincodeMessages(err error) []string {
+	var messages []string
+	var endorseErr *client.EndorseError
+	if errors.As(err, &endorseErr) {
+		for _, detail := range status.Convert(err).Details() {
+			if errorDetail, ok := detail.(*gateway.ErrorDetail); ok {
+				messages = append(messages, errorDetail.GetMessage())
+			}
+		}
+	}
+
+	return messages
+}
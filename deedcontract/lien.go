@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+
+	"authz"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Lien is a lender's claim on a deed pledged as collateral for a loan. The deed cannot change
+// hands until the lender releases it.
+type Lien struct {
+	LoanID    string `json:"loanId"`
+	HolderMSP string `json:"holderMsp"` // the org of the lender, whose clients can release the lien
+	TxID      string `json:"txId"`      // the transaction that pledged the deed
+}
+
+// Approve allows operator, a client identity ID, to pledge a deed of the submitting client as
+// collateral. An empty operator withdraws the approval.
+func (s *SmartContract) Approve(ctx contractapi.TransactionContextInterface, id string, operator string) error {
+	deed, err := ownDeed(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	deed.Approved = operator
+	return deedRepo.Put(ctx, deed, id)
+}
+
+// LockDeed pledges a deed as collateral for a loan, on behalf of the org of the submitting client.
+// The submitting client must own the deed or have been approved by its owner; the approval is
+// used up. Lending chaincodes call it when collateral is attached to a loan.
+func (s *SmartContract) LockDeed(ctx contractapi.TransactionContextInterface, id string, loanID string) error {
+	if loanID == "" {
+		return fmt.Errorf("loan ID must not be empty")
+	}
+	deed, err := readDeed(ctx, id)
+	if err != nil {
+		return err
+	}
+	client, err := authz.ClientOf(ctx)
+	if err != nil {
+		return err
+	}
+	if client.ID != deed.Owner && client.ID != deed.Approved {
+		return fmt.Errorf("submitting client is neither the owner of the deed %s nor approved by them", id)
+	}
+	if deed.Lien != nil {
+		return fmt.Errorf("the deed %s is pledged as collateral for loan %s", id, deed.Lien.LoanID)
+	}
+
+	deed.Approved = ""
+	deed.Lien = &Lien{
+		LoanID:    loanID,
+		HolderMSP: client.MSPID,
+		TxID:      ctx.GetStub().GetTxID(),
+	}
+	return deedRepo.Put(ctx, deed, id)
+}
+
+// UnlockDeed releases the lien a loan holds on a deed. Only clients of the org holding the lien can
+// release it. Lending chaincodes call it when collateral is released from a loan.
+func (s *SmartContract) UnlockDeed(ctx contractapi.TransactionContextInterface, id string, loanID string) error {
+	deed, err := readDeed(ctx, id)
+	if err != nil {
+		return err
+	}
+	if deed.Lien == nil || deed.Lien.LoanID != loanID {
+		return fmt.Errorf("the deed %s is not pledged as collateral for loan %s", id, loanID)
+	}
+	mspID, err := authz.CallerMSP(ctx)
+	if err != nil {
+		return err
+	}
+	if mspID != deed.Lien.HolderMSP {
+		return fmt.Errorf("only %s can release the lien on the deed %s", deed.Lien.HolderMSP, id)
+	}
+
+	deed.Lien = nil
+	return deedRepo.Put(ctx, deed, id)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLiens(t *testing.T) {
+	s := new(SmartContract)
+	ledger := chaincodetest.NewLedger()
+
+	approve := func(id string, operator string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.Approve(ctx, id, operator)
+		}
+	}
+	lock := func(id string, loanID string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.LockDeed(ctx, id, loanID)
+		}
+	}
+	unlock := func(id string, loanID string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.UnlockDeed(ctx, id, loanID)
+		}
+	}
+	transfer := func(id string, to chaincodetest.Identity) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.TransferDeed(ctx, id, accountOf(to))
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		mintDeed("deed1", alice),
+		{Name: "lock without approval", Caller: lender, Run: lock("deed1", "loan1"), Err: "neither the owner of the deed deed1 nor approved by them"},
+		{Name: "approve someone else's deed", Caller: bob, Run: approve("deed1", accountOf(lender)), Err: "does not own the deed deed1"},
+		{Name: "approve the lender", Caller: alice, Run: approve("deed1", accountOf(lender))},
+		{Name: "lock without a loan", Caller: lender, Run: lock("deed1", ""), Err: "loan ID must not be empty"},
+		{Name: "lock", Caller: lender, Run: lock("deed1", "loan1")},
+		{
+			Name:   "the lien is recorded and the approval used up",
+			Caller: alice,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				deed, err := s.ReadDeed(ctx, "deed1")
+				if err == nil {
+					require.Equal(t, "loan1", deed.Lien.LoanID)
+					require.Equal(t, "Org1MSP", deed.Lien.HolderMSP)
+					require.Empty(t, deed.Approved)
+				}
+				return err
+			},
+		},
+		{Name: "lock twice", Caller: alice, Run: lock("deed1", "loan2"), Err: "the deed deed1 is pledged as collateral for loan loan1"},
+		{Name: "transfer a pledged deed", Caller: alice, Run: transfer("deed1", bob), Err: "is pledged as collateral for loan loan1"},
+		{
+			Name:   "burn a pledged deed",
+			Caller: alice,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.Burn(ctx, "deed1")
+			},
+			Err: "is pledged as collateral for loan loan1",
+		},
+		{Name: "unlock for another loan", Caller: lender, Run: unlock("deed1", "loan2"), Err: "the deed deed1 is not pledged as collateral for loan loan2"},
+		{Name: "the owner cannot unlock", Caller: alice, Run: unlock("deed1", "loan1"), Err: "only Org1MSP can release the lien on the deed deed1"},
+		{Name: "another org cannot unlock", Caller: stranger, Run: unlock("deed1", "loan1"), Err: "only Org1MSP can release the lien"},
+		{Name: "another client of the lender unlocks", Caller: lender2, Run: unlock("deed1", "loan1")},
+		{Name: "unlock twice", Caller: lender, Run: unlock("deed1", "loan1"), Err: "is not pledged as collateral for loan loan1"},
+		{Name: "the owner pledges directly", Caller: alice, Run: lock("deed1", "loan3")},
+		{Name: "release", Caller: alice, Run: unlock("deed1", "loan3")},
+		{Name: "transfer once released", Caller: alice, Run: transfer("deed1", bob)},
+	})
+}
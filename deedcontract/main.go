@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"authz"
+	"ccaas"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+// EventTransfer is emitted when a deed is minted, transferred or burned
+const EventTransfer = "Transfer"
+
+// deedNamespace prefixes the plain keys of deeds, DEED_tokenID
+const deedNamespace repository.Namespace = "DEED_"
+
+// deedRepo stores deeds under their plain keys in deedNamespace
+var deedRepo = repository.NewInNamespace[Deed](deedNamespace)
+
+// SmartContract provides functions for issuing and trading property deeds as non-fungible tokens
+type SmartContract struct {
+	contractapi.Contract
+}
+
+// Deed is a non-fungible token standing for the title to a property. The deed document itself is
+// kept off chain at TokenURI; MetadataHash pins its content.
+type Deed struct {
+	ID           string `json:"id"`
+	Owner        string `json:"owner"`        // client identity ID of the owner
+	MetadataHash string `json:"metadataHash"` // SHA-256 of the deed document, hex encoded
+	TokenURI     string `json:"tokenUri"`
+	Approved     string `json:"approved"` // client identity ID allowed to pledge the deed, if any
+	Lien         *Lien  `json:"lien,omitempty" metadata:",optional"`
+}
+
+// TransferEvent is the payload of EventTransfer. From is empty for a mint and To for a burn.
+type TransferEvent struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	TokenID string `json:"tokenId"`
+}
+
+// MintDeed issues a new deed to owner, a client identity ID. Restricted to the registrar role.
+func (s *SmartContract) MintDeed(ctx contractapi.TransactionContextInterface, id string, owner string, metadataHash string, tokenURI string) error {
+	client, err := authz.ClientOf(ctx)
+	if err != nil {
+		return err
+	}
+	if !client.HasAttribute("role", "registrar") {
+		return fmt.Errorf("submitting client not authorized to mint deeds, requires role registrar")
+	}
+
+	if id == "" {
+		return fmt.Errorf("deed ID must not be empty")
+	}
+	if owner == "" {
+		return fmt.Errorf("owner must not be empty")
+	}
+	if hash, err := hex.DecodeString(metadataHash); err != nil || len(hash) != 32 {
+		return fmt.Errorf("metadata hash must be a hex encoded SHA-256 digest")
+	}
+	exists, err := deedRepo.Exists(ctx, id)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("the deed %s already exists", id)
+	}
+
+	deed := Deed{
+		ID:           id,
+		Owner:        owner,
+		MetadataHash: metadataHash,
+		TokenURI:     tokenURI,
+	}
+	err = deedRepo.Put(ctx, &deed, id)
+	if err != nil {
+		return err
+	}
+
+	return emitTransfer(ctx, "", owner, id)
+}
+
+// TransferDeed moves a deed to a new owner. Only the owner can transfer it, and not while it is
+// pledged as collateral. Any approval is cleared.
+func (s *SmartContract) TransferDeed(ctx contractapi.TransactionContextInterface, id string, to string) error {
+	if to == "" {
+		return fmt.Errorf("recipient must not be empty")
+	}
+	deed, err := ownDeed(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	from := deed.Owner
+	deed.Owner = to
+	deed.Approved = ""
+	err = deedRepo.Put(ctx, deed, id)
+	if err != nil {
+		return err
+	}
+
+	return emitTransfer(ctx, from, to, id)
+}
+
+// Burn destroys a deed, as when the title is surrendered. Only the owner can burn it, and not while
+// it is pledged as collateral.
+func (s *SmartContract) Burn(ctx contractapi.TransactionContextInterface, id string) error {
+	deed, err := ownDeed(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	err = deedRepo.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return emitTransfer(ctx, deed.Owner, "", id)
+}
+
+// ReadDeed returns the deed by token ID
+func (s *SmartContract) ReadDeed(ctx contractapi.TransactionContextInterface, id string) (*Deed, error) {
+	return readDeed(ctx, id)
+}
+
+// OwnerOf returns the client identity ID of the owner of a deed
+func (s *SmartContract) OwnerOf(ctx contractapi.TransactionContextInterface, id string) (string, error) {
+	deed, err := readDeed(ctx, id)
+	if err != nil {
+		return "", err
+	}
+
+	return deed.Owner, nil
+}
+
+// TokenURI returns where the document of a deed is kept
+func (s *SmartContract) TokenURI(ctx contractapi.TransactionContextInterface, id string) (string, error) {
+	deed, err := readDeed(ctx, id)
+	if err != nil {
+		return "", err
+	}
+
+	return deed.TokenURI, nil
+}
+
+// ClientAccountID returns the client identity ID of the submitting client, which deeds are owned
+// and approved by
+func (s *SmartContract) ClientAccountID(ctx contractapi.TransactionContextInterface) (string, error) {
+	client, err := authz.ClientOf(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return client.ID, nil
+}
+
+// MigrateDeedKeys moves up to limit deeds from the plain token IDs they were stored under before
+// deedNamespace to their DEED_ keys and returns how many it moved. Submit it until it moves none;
+// deeds not yet moved are invisible to the contract. Restricted to the admin role.
+func (s *SmartContract) MigrateDeedKeys(ctx contractapi.TransactionContextInterface, limit int) (int, error) {
+	client, err := authz.ClientOf(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if !client.HasAttribute("role", "admin") {
+		return 0, fmt.Errorf("submitting client not authorized, requires role admin")
+	}
+
+	return deedNamespace.Migrate(ctx, limit)
+}
+
+// GetAuditTrail returns up to pageSize entries of the audit trail, from the transaction startTx up
+// to but excluding endTx in transaction ID order. Evaluate it rather than submitting it.
+func (s *SmartContract) GetAuditTrail(ctx contractapi.TransactionContextInterface, startTx string, endTx string, pageSize int) (*authz.AuditPage, error) {
+	return authz.AuditTrail(ctx, startTx, endTx, pageSize)
+}
+
+// SetLogLevel changes the chaincode's log level until it restarts, after which
+// CORE_CHAINCODE_LOGGING_LEVEL applies again. Submit it so that every endorsing peer's chaincode
+// runs it. Restricted to the admin role.
+func (s *SmartContract) SetLogLevel(ctx contractapi.TransactionContextInterface, level string) error {
+	client, err := authz.ClientOf(ctx)
+	if err != nil {
+		return err
+	}
+	if !client.HasAttribute("role", "admin") {
+		return fmt.Errorf("submitting client not authorized, requires role admin")
+	}
+
+	return authz.SetLogLevel(level)
+}
+
+func readDeed(ctx contractapi.TransactionContextInterface, id string) (*Deed, error) {
+	deed, err := deedRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if deed == nil {
+		return nil, fmt.Errorf("the deed %s does not exist", id)
+	}
+
+	return deed, nil
+}
+
+// ownDeed returns a deed the submitting client owns and is free to dispose of
+func ownDeed(ctx contractapi.TransactionContextInterface, id string) (*Deed, error) {
+	deed, err := readDeed(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	client, err := authz.ClientOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if client.ID != deed.Owner {
+		return nil, fmt.Errorf("submitting client does not own the deed %s", id)
+	}
+	if deed.Lien != nil {
+		return nil, fmt.Errorf("the deed %s is pledged as collateral for loan %s", id, deed.Lien.LoanID)
+	}
+
+	return deed, nil
+}
+
+func emitTransfer(ctx contractapi.TransactionContextInterface, from string, to string, id string) error {
+	eventJSON, err := json.Marshal(TransferEvent{From: from, To: to, TokenID: id})
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().SetEvent(EventTransfer, eventJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set event %s: %v", EventTransfer, err)
+	}
+
+	return nil
+}
+
+// audit is the BeforeTransaction handler of the deed contract. Fabric refuses paginated queries in
+// a transaction that has written, so GetAuditTrail is not audited.
+var audit = authz.Audit("GetAuditTrail")
+
+func main() {
+	deedContract := new(SmartContract)
+	deedContract.TransactionContextHandler = new(authz.TransactionContext)
+	deedContract.BeforeTransaction = audit
+
+	chaincode, err := contractapi.NewChaincode(deedContract)
+	if err != nil {
+		authz.Logger.Error("failed to create deed chaincode", "error", err)
+		return
+	}
+
+	if err := ccaas.Start(chaincode); err != nil {
+		authz.Logger.Error("failed to start deed chaincode", "error", err)
+	}
+}
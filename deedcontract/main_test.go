@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"chaincodetest"
+	"chaincodetest/chaincodefakes"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	registrar = chaincodetest.Identity{MSPID: "Org1MSP", CommonName: "registrar1", Attributes: map[string]string{"role": "registrar"}}
+	alice     = chaincodetest.Identity{MSPID: "Org2MSP", CommonName: "alice"}
+	bob       = chaincodetest.Identity{MSPID: "Org2MSP", CommonName: "bob"}
+	lender    = chaincodetest.Identity{MSPID: "Org1MSP", CommonName: "officer1"}
+	lender2   = chaincodetest.Identity{MSPID: "Org1MSP", CommonName: "officer2"}
+	stranger  = chaincodetest.Identity{MSPID: "Org3MSP", CommonName: "stranger1"}
+)
+
+// metadataHash is the SHA-256 of a sample deed document
+const metadataHash = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+// accountOf returns the client identity ID chaincodetest gives an identity
+func accountOf(identity chaincodetest.Identity) string {
+	return base64.StdEncoding.EncodeToString([]byte("x509::CN=" + identity.CommonName + ",OU=client::CN=ca." + identity.MSPID))
+}
+
+// mintDeed returns a case minting a deed to owner
+func mintDeed(id string, owner chaincodetest.Identity) chaincodetest.Case {
+	return chaincodetest.Case{
+		Name:   "mint " + id,
+		Caller: registrar,
+		Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return new(SmartContract).MintDeed(ctx, id, accountOf(owner), metadataHash, "ipfs://deeds/"+id)
+		},
+	}
+}
+
+// transferEvent returns the Transfer event set by a transaction
+func transferEvent(t *testing.T, ctx contractapi.TransactionContextInterface) TransferEvent {
+	stub := ctx.GetStub().(*chaincodefakes.ChaincodeStub)
+	require.Equal(t, 1, stub.SetEventCallCount())
+	name, payload := stub.SetEventArgsForCall(0)
+	require.Equal(t, EventTransfer, name)
+	var event TransferEvent
+	require.NoError(t, json.Unmarshal(payload, &event))
+	return event
+}
+
+func TestDeeds(t *testing.T) {
+	s := new(SmartContract)
+	ledger := chaincodetest.NewLedger()
+
+	mint := func(id string, hash string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.MintDeed(ctx, id, accountOf(alice), hash, "ipfs://deeds/"+id)
+		}
+	}
+	transfer := func(id string, to chaincodetest.Identity) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			err := s.TransferDeed(ctx, id, accountOf(to))
+			if err == nil {
+				require.Equal(t, TransferEvent{From: accountOf(alice), To: accountOf(to), TokenID: id}, transferEvent(t, ctx))
+			}
+			return err
+		}
+	}
+	burn := func(id string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.Burn(ctx, id)
+		}
+	}
+	ownerOf := func(id string, owner chaincodetest.Identity) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			actual, err := s.OwnerOf(ctx, id)
+			if err == nil {
+				require.Equal(t, accountOf(owner), actual)
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "mint requires the registrar role", Caller: alice, Run: mint("deed1", metadataHash), Err: "requires role registrar"},
+		{Name: "mint with an invalid hash", Caller: registrar, Run: mint("deed1", "abc"), Err: "metadata hash must be a hex encoded SHA-256 digest"},
+		{
+			Name:   "mint",
+			Caller: registrar,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				err := mint("deed1", metadataHash)(t, ctx)
+				if err == nil {
+					require.Equal(t, TransferEvent{To: accountOf(alice), TokenID: "deed1"}, transferEvent(t, ctx))
+				}
+				return err
+			},
+		},
+		{Name: "mint twice", Caller: registrar, Run: mint("deed1", metadataHash), Err: "the deed deed1 already exists"},
+		{Name: "owner of", Caller: bob, Run: ownerOf("deed1", alice)},
+		{Name: "owner of a missing deed", Caller: bob, Run: ownerOf("deed9", alice), Err: "the deed deed9 does not exist"},
+		{
+			Name:   "token URI",
+			Caller: bob,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				uri, err := s.TokenURI(ctx, "deed1")
+				if err == nil {
+					require.Equal(t, "ipfs://deeds/deed1", uri)
+				}
+				return err
+			},
+		},
+		{Name: "transfer someone else's deed", Caller: bob, Run: transfer("deed1", bob), Err: "submitting client does not own the deed deed1"},
+		{Name: "transfer", Caller: alice, Run: transfer("deed1", bob)},
+		{Name: "new owner", Caller: alice, Run: ownerOf("deed1", bob)},
+		{Name: "burn someone else's deed", Caller: alice, Run: burn("deed1"), Err: "submitting client does not own the deed deed1"},
+		{Name: "burn", Caller: bob, Run: burn("deed1")},
+		{Name: "burned deeds are gone", Caller: bob, Run: ownerOf("deed1", bob), Err: "the deed deed1 does not exist"},
+	})
+}
+
+func TestMigrateDeedKeys(t *testing.T) {
+	s := new(SmartContract)
+	admin := chaincodetest.Identity{MSPID: "Org1MSP", CommonName: "admin1", Attributes: map[string]string{"role": "admin"}}
+	ledger := chaincodetest.NewLedger()
+
+	migrate := func(moved int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			count, err := s.MigrateDeedKeys(ctx, 10)
+			if err == nil {
+				require.Equal(t, moved, count)
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{
+			Name:   "deed under its plain token ID",
+			Caller: registrar,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				deedJSON, err := json.Marshal(Deed{ID: "deed1", Owner: accountOf(alice), MetadataHash: metadataHash})
+				require.NoError(t, err)
+				return ctx.GetStub().PutState("deed1", deedJSON)
+			},
+		},
+		{Name: "only admins migrate", Caller: registrar, Run: migrate(0), Err: "requires role admin"},
+		{Name: "migrate", Caller: admin, Run: migrate(1)},
+		{Name: "nothing left to migrate", Caller: admin, Run: migrate(0)},
+		{
+			Name:   "the migrated deed",
+			Caller: bob,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				owner, err := s.OwnerOf(ctx, "deed1")
+				if err == nil {
+					require.Equal(t, accountOf(alice), owner)
+				}
+				return err
+			},
+		},
+	})
+}
+
+func TestAuditSkipsGetAuditTrail(t *testing.T) {
+	s := new(SmartContract)
+	ledger := chaincodetest.NewLedger()
+
+	// readTrail runs the audit handler as Fabric does before the function, then reads the trail,
+	// which fails if the handler wrote
+	readTrail := func(function string) chaincodetest.Case {
+		return chaincodetest.Case{
+			Name:   function,
+			Caller: registrar,
+			Stub: func(stub *chaincodefakes.ChaincodeStub) {
+				stub.GetFunctionAndParametersReturns(function, nil)
+			},
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				err := audit(ctx)
+				if err != nil {
+					return err
+				}
+				_, err = s.GetAuditTrail(ctx, "", "", 10)
+				return err
+			},
+		}
+	}
+
+	audited := readTrail("MintDeed")
+	audited.Err = "has written and cannot run a paginated query"
+	ledger.Run(t, []chaincodetest.Case{readTrail("GetAuditTrail"), audited})
+}
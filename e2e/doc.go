@@ -0,0 +1,5 @@
+// Package e2e holds the end-to-end tests of the sample chaincodes. The tests are built only with
+// the e2e build tag and run against a running test network:
+//
+//	go test -tags e2e ./...
+package e2e
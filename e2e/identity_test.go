@@ -0,0 +1,43 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type identityEvent struct {
+	IdentityID string `json:"identityId"`
+	KYCLevel   string `json:"kycLevel"`
+	TxID       string `json:"txId"`
+}
+
+func TestIdentityLifecycle(t *testing.T) {
+	id := "e2e-identity-" + run
+	// every identity needs its own CNIC
+	cnic := fmt.Sprintf("35202-%07d-1", time.Now().UnixNano()%10000000)
+
+	committed := submit(t, registrarLabel, identityChaincode, "CreateIdentity",
+		id, "Ms", "Sara", "Ahmed", cnic, "1992-03-14", "Female", "03001234567")
+	var created identityEvent
+	expectEvent(t, registrarLabel, identityChaincode, committed, "IdentityCreated", &created)
+	require.Equal(t, id, created.IdentityID)
+	require.Equal(t, committed.TransactionID, created.TxID)
+
+	var level string
+	evaluate(t, registrarLabel, identityChaincode, &level, "GetKYCLevel", id)
+	require.Equal(t, "Basic", level)
+
+	committed = submit(t, registrarLabel, identityChaincode, "SetKYCLevel", id, "Enhanced")
+	var verified identityEvent
+	expectEvent(t, registrarLabel, identityChaincode, committed, "IdentityVerified", &verified)
+	require.Equal(t, id, verified.IdentityID)
+	require.Equal(t, "Enhanced", verified.KYCLevel)
+
+	evaluate(t, registrarLabel, identityChaincode, &level, "GetKYCLevel", id)
+	require.Equal(t, "Enhanced", level)
+}
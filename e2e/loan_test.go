@@ -0,0 +1,57 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type loanApplication struct {
+	ID               string `json:"id"`
+	Applicant        string `json:"applicant"`
+	Amount           int    `json:"amount"`
+	Status           string `json:"status"`
+	TermsHash        string `json:"termsHash"`
+	DisbursedAt      string `json:"disbursedAt"`
+	PaidInstallments int    `json:"paidInstallments"`
+}
+
+type repayment struct {
+	LoanID      string `json:"loanId"`
+	Installment int    `json:"installment"`
+	Amount      int    `json:"amount"`
+}
+
+func TestLoanLifecycle(t *testing.T) {
+	id := "e2e-loan-" + run
+	// loans up to 50000 are approved without consulting the identity chaincode
+	submit(t, officerLabel, bankChaincode, "CreateLoanApplication", id, "e2e-applicant-"+run, "20000", "12", "7.5")
+
+	var loan loanApplication
+	evaluate(t, officerLabel, bankChaincode, &loan, "ReadLoanApplication", id)
+	require.Equal(t, "Pending", loan.Status)
+	require.Equal(t, 20000, loan.Amount)
+
+	submit(t, officerLabel, bankChaincode, "UpdateLoanStatus", id, "Approved")
+	evaluate(t, officerLabel, bankChaincode, &loan, "ReadLoanApplication", id)
+	require.Equal(t, "Approved", loan.Status)
+	require.NotEmpty(t, loan.TermsHash, "approval snapshots the loan terms")
+
+	submit(t, officerLabel, bankChaincode, "UpdateLoanStatus", id, "Disbursed")
+	evaluate(t, officerLabel, bankChaincode, &loan, "ReadLoanApplication", id)
+	require.Equal(t, "Disbursed", loan.Status)
+	require.NotEmpty(t, loan.DisbursedAt)
+
+	submit(t, officerLabel, bankChaincode, "RecordPayment", id)
+	evaluate(t, officerLabel, bankChaincode, &loan, "ReadLoanApplication", id)
+	require.Equal(t, 1, loan.PaidInstallments)
+
+	var repayments []repayment
+	evaluate(t, officerLabel, bankChaincode, &repayments, "GetRepayments", id)
+	require.Len(t, repayments, 1)
+	require.Equal(t, id, repayments[0].LoanID)
+	require.Equal(t, 1, repayments[0].Installment)
+	require.Positive(t, repayments[0].Amount)
+}
@@ -0,0 +1,222 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-gateway/pkg/hash"
+	"github.com/hyperledger/fabric-gateway/pkg/identity"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// The wallet labels of the identities the tests submit as
+const (
+	registrarLabel = "registrar" // kycRegistrar=Full, Org1
+	officerLabel   = "officer"   // role=officer, Org1
+	trainer1Label  = "trainer1"  // trainer=trainer1
+	trainer2Label  = "trainer2"  // trainer=trainer2
+)
+
+var (
+	channelName       = envOrDefault("CHANNEL_NAME", "mychannel")
+	identityChaincode = envOrDefault("IDENTITY_CHAINCODE_NAME", "identitycontract")
+	bankChaincode     = envOrDefault("BANK_CHAINCODE_NAME", "bankcontract")
+	pokemonChaincode  = envOrDefault("POKEMON_CHAINCODE_NAME", "pokemoncontract")
+
+	testNetworkPath = envOrDefault("TEST_NETWORK_PATH", "../test-network")
+	walletPath      = envOrDefault("WALLET_PATH", "wallet")
+	cryptoPath      = envOrDefault("CRYPTO_PATH", "../test-network/organizations/peerOrganizations/org1.example.com")
+	tlsCertPath     = envOrDefault("TLS_CERT_PATH", cryptoPath+"/peers/peer0.org1.example.com/tls/ca.crt")
+	peerEndpoint    = envOrDefault("PEER_ENDPOINT", "dns:///localhost:7051")
+	peerHostAlias   = envOrDefault("PEER_HOST_ALIAS", "peer0.org1.example.com")
+
+	chaincodeVersion  = envOrDefault("E2E_CC_VERSION", "1.0")
+	chaincodeSequence = envOrDefault("E2E_CC_SEQUENCE", "1")
+)
+
+// run keeps the records of every test run apart, so the suite can run repeatedly against the same
+// network
+var run = strconv.FormatInt(time.Now().UnixNano(), 36)
+
+// gateways holds a gateway for each wallet identity
+var gateways = map[string]*client.Gateway{}
+
+// deployment is a chaincode of this repository as network.sh deployCC deploys it
+type deployment struct {
+	name       string
+	path       string
+	initLedger bool
+}
+
+func TestMain(m *testing.M) {
+	if os.Getenv("E2E_SKIP_DEPLOY") == "" {
+		deployments := []deployment{
+			{name: identityChaincode, path: "../afrazcontract"},
+			{name: bankChaincode, path: "../bankcontract"},
+			// InitLedger registers the species the evolution test relies on
+			{name: pokemonChaincode, path: "../pokemoncontract", initLedger: true},
+		}
+		for _, d := range deployments {
+			if err := deploy(d); err != nil {
+				fmt.Fprintf(os.Stderr, "Error deploying %s: %v\n", d.name, err)
+				os.Exit(1)
+			}
+		}
+	}
+
+	connection, err := newGrpcConnection()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error connecting to the gateway peer: %v\n", err)
+		os.Exit(1)
+	}
+
+	code := 1
+	if err := connectAll(connection); err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading the wallet: %v\n", err)
+	} else {
+		code = m.Run()
+	}
+
+	for _, gateway := range gateways {
+		gateway.Close()
+	}
+	connection.Close()
+	os.Exit(code)
+}
+
+// deploy installs, approves and commits a chaincode with the test network's deployCC script. The
+// Gateway only endorses and submits transactions; chaincode lifecycle needs the peer admin
+// commands the script runs.
+func deploy(d deployment) error {
+	path, err := filepath.Abs(d.path)
+	if err != nil {
+		return err
+	}
+	args := []string{
+		"deployCC",
+		"-c", channelName,
+		"-ccn", d.name,
+		"-ccp", path,
+		"-ccl", "go",
+		"-ccv", chaincodeVersion,
+		"-ccs", chaincodeSequence,
+	}
+	collections := filepath.Join(path, "collections_config.json")
+	if _, err := os.Stat(collections); err == nil {
+		args = append(args, "-cccg", collections)
+	}
+	if d.initLedger {
+		args = append(args, "-cci", "InitLedger")
+	}
+
+	fmt.Printf("Deploying %s from %s\n", d.name, path)
+	cmd := exec.Command("./network.sh", args...)
+	cmd.Dir = testNetworkPath
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+func newGrpcConnection() (*grpc.ClientConn, error) {
+	certificatePEM, err := os.ReadFile(tlsCertPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read TLS certificate file: %w", err)
+	}
+	certificate, err := identity.CertificateFromPEM(certificatePEM)
+	if err != nil {
+		return nil, err
+	}
+
+	certPool := x509.NewCertPool()
+	certPool.AddCert(certificate)
+	transportCredentials := credentials.NewClientTLSFromCert(certPool, peerHostAlias)
+
+	connection, err := grpc.NewClient(peerEndpoint, grpc.WithTransportCredentials(transportCredentials))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gRPC connection: %w", err)
+	}
+
+	return connection, nil
+}
+
+// walletIdentity is a <label>.id file in the wallet format the Fabric SDKs share
+type walletIdentity struct {
+	Type        string `json:"type"`
+	MSPID       string `json:"mspId"`
+	Credentials struct {
+		Certificate string `json:"certificate"`
+		PrivateKey  string `json:"privateKey"`
+	} `json:"credentials"`
+}
+
+func connectAll(connection *grpc.ClientConn) error {
+	for _, label := range []string{registrarLabel, officerLabel, trainer1Label, trainer2Label} {
+		gateway, err := connect(label, connection)
+		if err != nil {
+			return err
+		}
+		gateways[label] = gateway
+	}
+
+	return nil
+}
+
+func connect(label string, connection *grpc.ClientConn) (*client.Gateway, error) {
+	content, err := os.ReadFile(filepath.Join(walletPath, label+".id"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read wallet identity %s: %w", label, err)
+	}
+	var walletID walletIdentity
+	err = json.Unmarshal(content, &walletID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse wallet identity %s: %w", label, err)
+	}
+
+	certificate, err := identity.CertificateFromPEM([]byte(walletID.Credentials.Certificate))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the certificate of wallet identity %s: %w", label, err)
+	}
+	id, err := identity.NewX509Identity(walletID.MSPID, certificate)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, err := identity.PrivateKeyFromPEM([]byte(walletID.Credentials.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the private key of wallet identity %s: %w", label, err)
+	}
+	sign, err := identity.NewPrivateKeySign(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.Connect(
+		id,
+		client.WithSign(sign),
+		client.WithHash(hash.SHA256),
+		client.WithClientConnection(connection),
+		client.WithEvaluateTimeout(5*time.Second),
+		client.WithEndorseTimeout(15*time.Second),
+		client.WithSubmitTimeout(5*time.Second),
+		client.WithCommitStatusTimeout(1*time.Minute),
+	)
+}
+
+// envOrDefault returns the value of an environment variable, or the default when it is unset
+func envOrDefault(key, defaultValue string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,65 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/stretchr/testify/require"
+)
+
+func contract(label string, chaincode string) *client.Contract {
+	return gateways[label].GetNetwork(channelName).GetContract(chaincode)
+}
+
+// submit submits a transaction as the identity labelled label, waits for it to commit and
+// returns its commit status
+func submit(t *testing.T, label string, chaincode string, name string, args ...string) *client.Status {
+	t.Helper()
+	_, commit, err := contract(label, chaincode).SubmitAsync(name, client.WithArguments(args...))
+	require.NoError(t, err, "%s submitting %s.%s%q", label, chaincode, name, args)
+
+	status, err := commit.Status()
+	require.NoError(t, err)
+	require.True(t, status.Successful, "transaction %s failed to commit with status %s", status.TransactionID, status.Code)
+	return status
+}
+
+// evaluate evaluates a transaction as the identity labelled label and decodes its JSON result
+// into result
+func evaluate(t *testing.T, label string, chaincode string, result any, name string, args ...string) {
+	t.Helper()
+	resultJSON, err := contract(label, chaincode).EvaluateTransaction(name, args...)
+	require.NoError(t, err, "%s evaluating %s.%s%q", label, chaincode, name, args)
+
+	// string results are returned as they are rather than as JSON
+	if s, ok := result.(*string); ok {
+		*s = string(resultJSON)
+		return
+	}
+	require.NoError(t, json.Unmarshal(resultJSON, result))
+}
+
+// expectEvent asserts that the committed transaction emitted the chaincode event name, decoding
+// its payload into payload
+func expectEvent(t *testing.T, label string, chaincode string, committed *client.Status, name string, payload any) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	events, err := gateways[label].GetNetwork(channelName).ChaincodeEvents(ctx, chaincode, client.WithStartBlock(committed.BlockNumber))
+	require.NoError(t, err)
+	for event := range events {
+		if event.TransactionID != committed.TransactionID {
+			continue
+		}
+		require.Equal(t, name, event.EventName)
+		require.NoError(t, json.Unmarshal(event.Payload, payload))
+		return
+	}
+	t.Fatalf("no %s event received from transaction %s", name, committed.TransactionID)
+}
@@ -0,0 +1,60 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type pokemon struct {
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	Trainer        string `json:"trainer"`
+	EvolutionStage int    `json:"evolutionStage"`
+	Location       string `json:"location"`
+}
+
+type pokemonMove struct {
+	PokemonID string `json:"pokemonId"`
+	Trainer   string `json:"trainer"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+}
+
+type transfer struct {
+	FromTrainer string `json:"fromTrainer"`
+	NewTrainer  string `json:"newTrainer"`
+}
+
+func TestPokemonLifecycle(t *testing.T) {
+	id := "e2e-pokemon-" + run
+	// Charmander evolves into Charmeleon from 50 power
+	submit(t, trainer1Label, pokemonChaincode, "CreatePokemon", id, "Charmander", "Fire", trainer1Label, "Pallet Town", "52")
+
+	var p pokemon
+	evaluate(t, trainer1Label, pokemonChaincode, &p, "ReadPokemon", id)
+	require.Equal(t, "Charmander", p.Name)
+	require.Equal(t, 1, p.EvolutionStage)
+
+	submit(t, trainer1Label, pokemonChaincode, "EvolvePokemon", id)
+	evaluate(t, trainer1Label, pokemonChaincode, &p, "ReadPokemon", id)
+	require.Equal(t, "Charmeleon", p.Name)
+	require.Equal(t, 2, p.EvolutionStage)
+
+	committed := submit(t, trainer1Label, pokemonChaincode, "MovePokemon", id, "Viridian City")
+	var move pokemonMove
+	expectEvent(t, trainer1Label, pokemonChaincode, committed, "PokemonMoved", &move)
+	require.Equal(t, pokemonMove{PokemonID: id, Trainer: trainer1Label, From: "Pallet Town", To: "Viridian City"}, move)
+
+	submit(t, trainer1Label, pokemonChaincode, "TransferPokemon", id, trainer2Label)
+	var offer transfer
+	evaluate(t, trainer2Label, pokemonChaincode, &offer, "ReadTransfer", id)
+	require.Equal(t, transfer{FromTrainer: trainer1Label, NewTrainer: trainer2Label}, offer)
+
+	submit(t, trainer2Label, pokemonChaincode, "AcceptTransfer", id)
+	evaluate(t, trainer2Label, pokemonChaincode, &p, "ReadPokemon", id)
+	require.Equal(t, trainer2Label, p.Trainer)
+	require.Equal(t, "Viridian City", p.Location)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"loan-rest-api-go/web"
+)
+
+func main() {
+	cryptoPath := envOrDefault("CRYPTO_PATH", "../test-network/organizations/peerOrganizations/org1.example.com")
+	config := web.Config{
+		ChannelName:   envOrDefault("CHANNEL_NAME", "mychannel"),
+		ChaincodeName: envOrDefault("CHAINCODE_NAME", "bankcontract"),
+		WalletPath:    envOrDefault("WALLET_PATH", "wallet"),
+		LabelHeader:   envOrDefault("WALLET_LABEL_HEADER", "X-Wallet-Label"),
+		DefaultLabel:  os.Getenv("DEFAULT_WALLET_LABEL"),
+		TLSCertPath:   envOrDefault("TLS_CERT_PATH", cryptoPath+"/peers/peer0.org1.example.com/tls/ca.crt"),
+		PeerEndpoint:  envOrDefault("PEER_ENDPOINT", "dns:///localhost:7051"),
+		PeerHostAlias: envOrDefault("PEER_HOST_ALIAS", "peer0.org1.example.com"),
+		ListenAddress: envOrDefault("LISTEN_ADDRESS", ":3000"),
+	}
+
+	server, err := web.NewServer(config)
+	if err != nil {
+		log.Fatalf("Error initializing the loan REST server: %v", err)
+	}
+	defer server.Close()
+
+	log.Printf("Listening on %s...", config.ListenAddress)
+	if err := server.ListenAndServe(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// envOrDefault returns the value of an environment variable, or the default when it is unset
+func envOrDefault(key, defaultValue string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
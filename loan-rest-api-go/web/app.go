@@ -0,0 +1,127 @@
+// Package web serves a REST API over the loan application chaincode, calling it through the
+// Fabric Gateway as the wallet identity each request selects.
+package web
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/hyperledger/fabric-gateway/pkg/identity"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// Config holds the network, wallet and listener settings of the server
+type Config struct {
+	ChannelName   string
+	ChaincodeName string
+
+	// WalletPath is the directory of wallet identities, one <label>.id file each
+	WalletPath string
+	// LabelHeader names the request header selecting the wallet label to call the chaincode as
+	LabelHeader string
+	// DefaultLabel is used for requests without the header. Leave it empty to require the header.
+	DefaultLabel string
+
+	TLSCertPath   string
+	PeerEndpoint  string
+	PeerHostAlias string
+	ListenAddress string
+}
+
+// Contract is the part of *client.Contract the handlers use
+type Contract interface {
+	SubmitTransaction(name string, args ...string) ([]byte, error)
+	EvaluateTransaction(name string, args ...string) ([]byte, error)
+}
+
+// Server serves the loan API. Every request is signed by the identity of the wallet label the
+// request selects, so the chaincode applies that identity's role and organization.
+type Server struct {
+	config     Config
+	connection *grpc.ClientConn
+	wallet     *Wallet
+	http       *http.Server
+}
+
+// NewServer connects to the gateway peer and returns a server ready to listen
+func NewServer(config Config) (*Server, error) {
+	connection, err := newGrpcConnection(config)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		config:     config,
+		connection: connection,
+		wallet:     NewWallet(config.WalletPath, connection),
+	}
+	s.http = &http.Server{Addr: config.ListenAddress, Handler: s.routes()}
+
+	return s, nil
+}
+
+// ListenAndServe serves requests until the server fails
+func (s *Server) ListenAndServe() error {
+	return s.http.ListenAndServe()
+}
+
+// Close closes the gateway connections of every wallet identity used so far
+func (s *Server) Close() {
+	s.wallet.Close()
+	s.connection.Close()
+}
+
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /loans", s.listLoans)
+	mux.HandleFunc("POST /loans", s.createLoan)
+	mux.HandleFunc("GET /loans/{id}", s.readLoan)
+	mux.HandleFunc("DELETE /loans/{id}", s.deleteLoan)
+	mux.HandleFunc("GET /loans/{id}/status", s.readStatus)
+	mux.HandleFunc("PUT /loans/{id}/status", s.updateStatus)
+
+	return mux
+}
+
+// contract returns the loan contract as the wallet identity the request selects
+func (s *Server) contract(r *http.Request) (Contract, error) {
+	label := r.Header.Get(s.config.LabelHeader)
+	if label == "" {
+		label = s.config.DefaultLabel
+	}
+	if label == "" {
+		return nil, &requestError{status: http.StatusUnauthorized, code: "WALLET_LABEL_REQUIRED", message: fmt.Sprintf("the %s header must name a wallet identity", s.config.LabelHeader)}
+	}
+
+	gateway, err := s.wallet.Gateway(label)
+	if err != nil {
+		return nil, err
+	}
+
+	return gateway.GetNetwork(s.config.ChannelName).GetContract(s.config.ChaincodeName), nil
+}
+
+func newGrpcConnection(config Config) (*grpc.ClientConn, error) {
+	certificatePEM, err := os.ReadFile(config.TLSCertPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read TLS certificate file: %w", err)
+	}
+	certificate, err := identity.CertificateFromPEM(certificatePEM)
+	if err != nil {
+		return nil, err
+	}
+
+	certPool := x509.NewCertPool()
+	certPool.AddCert(certificate)
+	transportCredentials := credentials.NewClientTLSFromCert(certPool, config.PeerHostAlias)
+
+	connection, err := grpc.NewClient(config.PeerEndpoint, grpc.WithTransportCredentials(transportCredentials))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gRPC connection: %w", err)
+	}
+
+	return connection, nil
+}
@@ -0,0 +1,162 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-protos-go-apiv2/gateway"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// requestError is an error the server answers with a fixed status and code
+type requestError struct {
+	status        int
+	code          string
+	message       string
+	transactionID string
+}
+
+func (e *requestError) Error() string {
+	return e.message
+}
+
+type errorBody struct {
+	Error struct {
+		Code          string `json:"code"`
+		Message       string `json:"message"`
+		TransactionID string `json:"transactionId,omitempty"`
+	} `json:"error"`
+}
+
+// chaincodeResponse is the prefix peers put in front of the message a chaincode failed with
+var chaincodeResponse = regexp.MustCompile(`^chaincode response \d+, `)
+
+// chaincodeCode matches the upper case code some chaincode errors start with, such as LOCKED:
+var chaincodeCode = regexp.MustCompile(`^([A-Z][A-Z_]+): `)
+
+// chaincodeCodes maps the codes chaincode errors start with to response statuses
+var chaincodeCodes = map[string]int{
+	"LOCKED": http.StatusLocked,
+}
+
+// chaincodePhrases maps the phrasing of chaincode errors without a code to response codes. The
+// first phrase found in the message wins.
+var chaincodePhrases = []struct {
+	phrase string
+	status int
+	code   string
+}{
+	{"does not exist", http.StatusNotFound, "NOT_FOUND"},
+	{"was deleted", http.StatusGone, "DELETED"},
+	{"already exists", http.StatusConflict, "ALREADY_EXISTS"},
+	{"not authorized", http.StatusForbidden, "FORBIDDEN"},
+	{"must be", http.StatusBadRequest, "INVALID_ARGUMENT"},
+	{"must not", http.StatusBadRequest, "INVALID_ARGUMENT"},
+	{"invalid", http.StatusBadRequest, "INVALID_ARGUMENT"},
+}
+
+// writeError answers a request with the JSON error body for err
+func writeError(w http.ResponseWriter, err error) {
+	writeRequestError(w, toRequestError(err))
+}
+
+func writeRequestError(w http.ResponseWriter, e *requestError) {
+	var body errorBody
+	body.Error.Code = e.code
+	body.Error.Message = e.message
+	body.Error.TransactionID = e.transactionID
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(e.status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// toRequestError maps err to a response. Errors raised by the chaincode are mapped by their code
+// or phrasing, errors of the gateway by their gRPC status.
+func toRequestError(err error) *requestError {
+	var reqErr *requestError
+	if errors.As(err, &reqErr) {
+		return reqErr
+	}
+
+	var commitErr *client.CommitError
+	if errors.As(err, &commitErr) {
+		return &requestError{
+			status:        http.StatusConflict,
+			code:          "COMMIT_FAILED",
+			message:       "transaction failed to commit with status " + commitErr.Code.String(),
+			transactionID: commitErr.TransactionID,
+		}
+	}
+
+	var transactionID string
+	var endorseErr *client.EndorseError
+	var submitErr *client.SubmitError
+	var commitStatusErr *client.CommitStatusError
+	switch {
+	case errors.As(err, &endorseErr):
+		transactionID = endorseErr.TransactionID
+	case errors.As(err, &submitErr):
+		transactionID = submitErr.TransactionID
+	case errors.As(err, &commitStatusErr):
+		transactionID = commitStatusErr.TransactionID
+	}
+
+	grpcStatus := status.Convert(err)
+	if message, ok := chaincodeMessage(grpcStatus); ok {
+		reqErr = chaincodeError(message)
+		reqErr.transactionID = transactionID
+		return reqErr
+	}
+
+	log.Printf("Gateway error: %v", err)
+	reqErr = &requestError{status: http.StatusBadGateway, code: "GATEWAY_ERROR", message: grpcStatus.Message(), transactionID: transactionID}
+	switch grpcStatus.Code() {
+	case codes.Unavailable:
+		reqErr.status = http.StatusServiceUnavailable
+		reqErr.code = "UNAVAILABLE"
+	case codes.DeadlineExceeded:
+		reqErr.status = http.StatusGatewayTimeout
+		reqErr.code = "TIMEOUT"
+	}
+
+	return reqErr
+}
+
+// chaincodeMessage returns the message the chaincode failed with, taken from the details the
+// gateway attaches for each peer that ran it
+func chaincodeMessage(grpcStatus *status.Status) (string, bool) {
+	for _, detail := range grpcStatus.Details() {
+		errorDetail, ok := detail.(*gateway.ErrorDetail)
+		if !ok {
+			continue
+		}
+		if chaincodeResponse.MatchString(errorDetail.GetMessage()) {
+			return chaincodeResponse.ReplaceAllString(errorDetail.GetMessage(), ""), true
+		}
+	}
+
+	return "", false
+}
+
+func chaincodeError(message string) *requestError {
+	if match := chaincodeCode.FindStringSubmatch(message); match != nil {
+		if httpStatus, ok := chaincodeCodes[match[1]]; ok {
+			return &requestError{status: httpStatus, code: match[1], message: strings.TrimPrefix(message, match[0])}
+		}
+	}
+
+	for _, mapping := range chaincodePhrases {
+		if strings.Contains(message, mapping.phrase) {
+			return &requestError{status: mapping.status, code: mapping.code, message: message}
+		}
+	}
+
+	return &requestError{status: http.StatusUnprocessableEntity, code: "CHAINCODE_ERROR", message: message}
+}
@@ -0,0 +1,261 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+)
+
+// maxBodyBytes bounds the request bodies the handlers read
+const maxBodyBytes = 1 << 16
+
+var loanID = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// loanStatuses are the statuses a loan application can be moved to
+var loanStatuses = map[string]bool{
+	"Pending":   true,
+	"Approved":  true,
+	"Rejected":  true,
+	"Disbursed": true,
+	"Defaulted": true,
+	"Closed":    true,
+}
+
+type createLoanRequest struct {
+	ID           string  `json:"id"`
+	Applicant    string  `json:"applicant"`
+	Amount       int     `json:"amount"`
+	Term         int     `json:"term"`
+	InterestRate float64 `json:"interestRate"`
+}
+
+type loanStatus struct {
+	ID     string `json:"id,omitempty"`
+	Status string `json:"status"`
+}
+
+func (s *Server) listLoans(w http.ResponseWriter, r *http.Request) {
+	contract, err := s.contract(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	includeDeleted := r.URL.Query().Get("includeDeleted") == "true"
+	result, err := contract.EvaluateTransaction("GetAllLoanApplications", strconv.FormatBool(includeDeleted))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if len(result) == 0 || string(result) == "null" {
+		result = []byte("[]")
+	}
+
+	writeResult(w, http.StatusOK, result)
+}
+
+func (s *Server) createLoan(w http.ResponseWriter, r *http.Request) {
+	var request createLoanRequest
+	if !readBody(w, r, &request) {
+		return
+	}
+	if err := request.validate(); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	contract, err := s.contract(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	_, err = contract.SubmitTransaction("CreateLoanApplication",
+		request.ID,
+		request.Applicant,
+		strconv.Itoa(request.Amount),
+		strconv.Itoa(request.Term),
+		strconv.FormatFloat(request.InterestRate, 'f', -1, 64),
+	)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	result, err := contract.EvaluateTransaction("ReadLoanApplication", request.ID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", "/loans/"+request.ID)
+	writeResult(w, http.StatusCreated, result)
+}
+
+func (s *Server) readLoan(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	contract, err := s.contract(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	result, err := contract.EvaluateTransaction("ReadLoanApplication", id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeResult(w, http.StatusOK, result)
+}
+
+func (s *Server) deleteLoan(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	reason := r.URL.Query().Get("reason")
+	if reason == "" {
+		writeError(w, invalidArgument("reason must not be empty"))
+		return
+	}
+	contract, err := s.contract(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	_, err = contract.SubmitTransaction("DeleteLoanApplication", id, reason)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) readStatus(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	contract, err := s.contract(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	result, err := contract.EvaluateTransaction("ReadLoanApplication", id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var loan loanStatus
+	err = json.Unmarshal(result, &loan)
+	if err != nil {
+		writeError(w, fmt.Errorf("failed to parse the loan application: %w", err))
+		return
+	}
+
+	writeValue(w, http.StatusOK, loan)
+}
+
+func (s *Server) updateStatus(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	var request loanStatus
+	if !readBody(w, r, &request) {
+		return
+	}
+	if request.ID != "" && request.ID != id {
+		writeError(w, invalidArgument("the id in the body must match the path"))
+		return
+	}
+	if !loanStatuses[request.Status] {
+		writeError(w, invalidArgument(fmt.Sprintf("invalid status %q", request.Status)))
+		return
+	}
+
+	contract, err := s.contract(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	_, err = contract.SubmitTransaction("UpdateLoanStatus", id, request.Status)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeValue(w, http.StatusOK, loanStatus{ID: id, Status: request.Status})
+}
+
+func (request *createLoanRequest) validate() error {
+	switch {
+	case !loanID.MatchString(request.ID):
+		return invalidArgument("id must be 1 to 64 letters, digits, underscores or hyphens")
+	case request.Applicant == "":
+		return invalidArgument("applicant must not be empty")
+	case request.Amount <= 0:
+		return invalidArgument("amount must be positive")
+	case request.Term <= 0:
+		return invalidArgument("term must be a positive number of months")
+	case request.InterestRate < 0:
+		return invalidArgument("interestRate must not be negative")
+	}
+
+	return nil
+}
+
+func invalidArgument(message string) *requestError {
+	return &requestError{status: http.StatusBadRequest, code: "INVALID_ARGUMENT", message: message}
+}
+
+// pathID returns the loan ID in the request path, answering the request itself if it is invalid
+func pathID(w http.ResponseWriter, r *http.Request) (string, bool) {
+	id := r.PathValue("id")
+	if !loanID.MatchString(id) {
+		writeError(w, invalidArgument(fmt.Sprintf("invalid loan id %q", id)))
+		return "", false
+	}
+
+	return id, true
+}
+
+// readBody decodes the JSON request body into v, answering the request itself if it is malformed
+func readBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(v)
+	if err != nil {
+		writeError(w, invalidArgument("malformed request body: "+err.Error()))
+		return false
+	}
+
+	return true
+}
+
+// writeResult answers with a JSON result returned by the chaincode
+func writeResult(w http.ResponseWriter, status int, result []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(result)
+}
+
+func writeValue(w http.ResponseWriter, status int, v any) {
+	result, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeResult(w, status, result)
+}
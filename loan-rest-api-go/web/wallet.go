@@ -0,0 +1,133 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-gateway/pkg/hash"
+	"github.com/hyperledger/fabric-gateway/pkg/identity"
+	"google.golang.org/grpc"
+)
+
+// walletLabel restricts labels to plain file names so that a header cannot reach outside the wallet
+var walletLabel = regexp.MustCompile(`^[A-Za-z0-9_@-][A-Za-z0-9._@-]*$`)
+
+// walletIdentity is a <label>.id file in the wallet format the Fabric SDKs share
+type walletIdentity struct {
+	Type        string `json:"type"`
+	MSPID       string `json:"mspId"`
+	Credentials struct {
+		Certificate string `json:"certificate"`
+		PrivateKey  string `json:"privateKey"`
+	} `json:"credentials"`
+}
+
+// Wallet connects gateways as the identities of a wallet directory. Each label is connected once,
+// on first use, and its gateway is reused by later requests over the shared gRPC connection.
+type Wallet struct {
+	path       string
+	connection *grpc.ClientConn
+
+	mu       sync.Mutex
+	gateways map[string]*client.Gateway
+}
+
+// NewWallet returns a wallet over the identity files in path
+func NewWallet(path string, connection *grpc.ClientConn) *Wallet {
+	return &Wallet{path: path, connection: connection, gateways: map[string]*client.Gateway{}}
+}
+
+// Gateway returns the gateway signing as the identity labelled label
+func (w *Wallet) Gateway(label string) (*client.Gateway, error) {
+	if !walletLabel.MatchString(label) {
+		return nil, &requestError{status: http.StatusBadRequest, code: "INVALID_WALLET_LABEL", message: fmt.Sprintf("invalid wallet label %q", label)}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if gateway, ok := w.gateways[label]; ok {
+		return gateway, nil
+	}
+
+	id, sign, err := w.load(label)
+	if err != nil {
+		return nil, err
+	}
+
+	gateway, err := client.Connect(
+		id,
+		client.WithSign(sign),
+		client.WithHash(hash.SHA256),
+		client.WithClientConnection(w.connection),
+		client.WithEvaluateTimeout(5*time.Second),
+		client.WithEndorseTimeout(15*time.Second),
+		client.WithSubmitTimeout(5*time.Second),
+		client.WithCommitStatusTimeout(1*time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect the gateway as %s: %w", label, err)
+	}
+	w.gateways[label] = gateway
+
+	return gateway, nil
+}
+
+// Close closes every gateway the wallet has connected
+func (w *Wallet) Close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for label, gateway := range w.gateways {
+		gateway.Close()
+		delete(w.gateways, label)
+	}
+}
+
+func (w *Wallet) load(label string) (*identity.X509Identity, identity.Sign, error) {
+	content, err := os.ReadFile(filepath.Join(w.path, label+".id"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, &requestError{status: http.StatusUnauthorized, code: "UNKNOWN_WALLET_LABEL", message: fmt.Sprintf("the wallet has no identity %s", label)}
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read wallet identity %s: %w", label, err)
+	}
+
+	var walletID walletIdentity
+	err = json.Unmarshal(content, &walletID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse wallet identity %s: %w", label, err)
+	}
+	if walletID.Type != "X.509" {
+		return nil, nil, fmt.Errorf("wallet identity %s is of type %s, only X.509 identities are supported", label, walletID.Type)
+	}
+
+	certificate, err := identity.CertificateFromPEM([]byte(walletID.Credentials.Certificate))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse the certificate of wallet identity %s: %w", label, err)
+	}
+	id, err := identity.NewX509Identity(walletID.MSPID, certificate)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	privateKey, err := identity.PrivateKeyFromPEM([]byte(walletID.Credentials.PrivateKey))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse the private key of wallet identity %s: %w", label, err)
+	}
+	sign, err := identity.NewPrivateKeySign(privateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return id, sign, nil
+}
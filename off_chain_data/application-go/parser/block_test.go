@@ -51,6 +51,44 @@ func Test_GetReadWriteSetsFromEndorserTransaction(t *testing.T) {
 	)
 }
 
+func Test_GetChaincodeEventsFromEndorserTransaction(t *testing.T) {
+	expectedEvent := &peer.ChaincodeEvent{
+		ChaincodeId: "basic",
+		TxId:        "tx-1",
+		EventName:   "AssetCreated",
+		Payload:     []byte(`{"ID":"id-1"}`),
+	}
+
+	transaction := &peer.Transaction{
+		Actions: []*peer.TransactionAction{
+			{
+				Payload: protoMarshalOrPanic(&peer.ChaincodeActionPayload{
+					Action: &peer.ChaincodeEndorsedAction{
+						ProposalResponsePayload: protoMarshalOrPanic(&peer.ProposalResponsePayload{
+							Extension: protoMarshalOrPanic(&peer.ChaincodeAction{
+								Events: protoMarshalOrPanic(expectedEvent),
+							}),
+						}),
+					},
+				}),
+			},
+		},
+	}
+
+	parsedEndorserTransaction := parseEndorserTransaction(transaction)
+	chaincodeEvents, err := parsedEndorserTransaction.chaincodeEvents()
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(chaincodeEvents) != 1 {
+		t.Fatal("expected 1 ChaincodeEvent, got", len(chaincodeEvents))
+	}
+	if !proto.Equal(chaincodeEvents[0], expectedEvent) {
+		t.Errorf("expected event %v, got %v", expectedEvent, chaincodeEvents[0])
+	}
+}
+
 func assertReadWriteSet(
 	parsedNsRwSet *NamespaceReadWriteSet,
 	expectedNamespace string,
@@ -9,17 +9,21 @@ import (
 )
 
 type endorserTransaction struct {
-	transaction   *peer.Transaction
-	readWriteSets func() ([]*readWriteSet, error)
+	transaction      *peer.Transaction
+	chaincodeActions func() ([]*peer.ChaincodeAction, error)
+	readWriteSets    func() ([]*readWriteSet, error)
+	chaincodeEvents  func() ([]*peer.ChaincodeEvent, error)
 }
 
 func parseEndorserTransaction(transaction *peer.Transaction) *endorserTransaction {
-	result := &endorserTransaction{transaction, nil}
+	result := &endorserTransaction{transaction, nil, nil, nil}
+	result.chaincodeActions = sync.OnceValues(result.unmarshalChaincodeActions)
 	result.readWriteSets = sync.OnceValues(result.unmarshalReadWriteSets)
+	result.chaincodeEvents = sync.OnceValues(result.unmarshalChaincodeEvents)
 	return result
 }
 
-func (p *endorserTransaction) unmarshalReadWriteSets() ([]*readWriteSet, error) {
+func (p *endorserTransaction) unmarshalChaincodeActions() ([]*peer.ChaincodeAction, error) {
 	chaincodeActionPayloads, err := p.unmarshalChaincodeActionPayloads()
 	if err != nil {
 		return nil, err
@@ -30,7 +34,11 @@ func (p *endorserTransaction) unmarshalReadWriteSets() ([]*readWriteSet, error)
 		return nil, err
 	}
 
-	chaincodeActions, err := p.unmarshalChaincodeActionsFrom(proposalResponsePayloads)
+	return p.unmarshalChaincodeActionsFrom(proposalResponsePayloads)
+}
+
+func (p *endorserTransaction) unmarshalReadWriteSets() ([]*readWriteSet, error) {
+	chaincodeActions, err := p.chaincodeActions()
 	if err != nil {
 		return nil, err
 	}
@@ -43,6 +51,26 @@ func (p *endorserTransaction) unmarshalReadWriteSets() ([]*readWriteSet, error)
 	return p.parseReadWriteSets(txReadWriteSets), nil
 }
 
+func (p *endorserTransaction) unmarshalChaincodeEvents() ([]*peer.ChaincodeEvent, error) {
+	chaincodeActions, err := p.chaincodeActions()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*peer.ChaincodeEvent
+	for _, chaincodeAction := range chaincodeActions {
+		if len(chaincodeAction.GetEvents()) == 0 {
+			continue
+		}
+		chaincodeEvent := &peer.ChaincodeEvent{}
+		if err := proto.Unmarshal(chaincodeAction.GetEvents(), chaincodeEvent); err != nil {
+			return nil, err
+		}
+		result = append(result, chaincodeEvent)
+	}
+	return result, nil
+}
+
 func (p *endorserTransaction) unmarshalChaincodeActionPayloads() ([]*peer.ChaincodeActionPayload, error) {
 	var result []*peer.ChaincodeActionPayload
 	for _, transactionAction := range p.transaction.GetActions() {
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"github.com/hyperledger/fabric-protos-go-apiv2/common"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
 )
 
 type Transaction struct {
@@ -34,6 +35,15 @@ func (t *Transaction) NamespaceReadWriteSets() ([]*NamespaceReadWriteSet, error)
 	return result, nil
 }
 
+func (t *Transaction) ChaincodeEvents() ([]*peer.ChaincodeEvent, error) {
+	endorserTransaction, err := t.payload.endorserTransaction()
+	if err != nil {
+		return nil, err
+	}
+
+	return endorserTransaction.chaincodeEvents()
+}
+
 func (t *Transaction) IsValid() bool {
 	return t.payload.isValid()
 }
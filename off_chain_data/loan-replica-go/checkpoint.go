@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// dbCheckpointer keeps the listening position of a channel in the checkpoints table. Positions are
+// saved in the same database transaction as the updates they cover, so a restart resumes exactly
+// after the last applied transaction. It implements client.Checkpoint.
+type dbCheckpointer struct {
+	channel       string
+	blockNumber   uint64
+	transactionID string
+}
+
+// loadCheckpointer returns the saved position of a channel, or block zero if there is none
+func loadCheckpointer(ctx context.Context, db *sql.DB, channel string) (*dbCheckpointer, error) {
+	checkpointer := &dbCheckpointer{channel: channel}
+	err := db.QueryRowContext(ctx,
+		"SELECT block_number, transaction_id FROM checkpoints WHERE channel = $1",
+		channel,
+	).Scan(&checkpointer.blockNumber, &checkpointer.transactionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return checkpointer, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return checkpointer, nil
+}
+
+// BlockNumber returns the next block to process
+func (c *dbCheckpointer) BlockNumber() uint64 {
+	return c.blockNumber
+}
+
+// TransactionID returns the last transaction processed in the current block, if any
+func (c *dbCheckpointer) TransactionID() string {
+	return c.transactionID
+}
+
+// commit saves a new position within tx and commits it, moving the position only once the updates
+// applied in tx are committed alongside it
+func (c *dbCheckpointer) commit(ctx context.Context, tx *sql.Tx, blockNumber uint64, transactionID string) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO checkpoints (channel, block_number, transaction_id) VALUES ($1, $2, $3)
+		ON CONFLICT (channel) DO UPDATE SET block_number = $2, transaction_id = $3`,
+		c.channel, blockNumber, transactionID,
+	)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	c.blockNumber = blockNumber
+	c.transactionID = transactionID
+	return nil
+}
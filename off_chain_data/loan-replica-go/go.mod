@@ -0,0 +1,23 @@
+module loanreplica
+
+go 1.22.0
+
+require (
+	github.com/hyperledger/fabric-gateway v1.7.0
+	github.com/lib/pq v1.12.3
+	google.golang.org/grpc v1.72.0-dev
+	offchaindata v0.0.0
+)
+
+require (
+	github.com/hyperledger/fabric-protos-go-apiv2 v0.3.4 // indirect
+	github.com/miekg/pkcs11 v1.1.1 // indirect
+	golang.org/x/crypto v0.32.0 // indirect
+	golang.org/x/net v0.34.0 // indirect
+	golang.org/x/sys v0.29.0 // indirect
+	golang.org/x/text v0.21.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250115164207-1a7da9e5054f // indirect
+	google.golang.org/protobuf v1.36.4 // indirect
+)
+
+replace offchaindata => ../application-go
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"offchaindata/parser"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+// replicator applies a write of its chaincode to the replica within a database transaction
+type replicator func(ctx context.Context, tx *sql.Tx, w write) error
+
+// A ledger write made by a valid transaction.
+type write struct {
+	BlockNumber   uint64
+	TransactionID string
+	Timestamp     time.Time
+	Key           string
+	IsDelete      bool
+	Value         []byte
+}
+
+// listen replicates the writes and chaincode events of the given chaincodes on a channel, resuming
+// after the last transaction applied to the replica
+func listen(ctx context.Context, db *sql.DB, network *client.Network, replicators map[string]replicator) error {
+	checkpointer, err := loadCheckpointer(ctx, db, network.Name())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Start event listening on channel %s from block %d\n", network.Name(), checkpointer.BlockNumber())
+	fmt.Println("Last processed transaction ID within block:", checkpointer.TransactionID())
+
+	blocks, err := network.BlockEvents(
+		ctx,
+		// Used only if there is no checkpoint block number.
+		// Order matters. WithStartBlock must be set before
+		// WithCheckpoint to work.
+		client.WithStartBlock(0),
+		client.WithCheckpoint(checkpointer),
+	)
+	if err != nil {
+		return err
+	}
+
+	for blockProto := range blocks {
+		aBlockProcessor := blockProcessor{
+			parser.ParseBlock(blockProto),
+			network.Name(),
+			checkpointer,
+			db,
+			replicators,
+		}
+
+		if err := aBlockProcessor.process(ctx); err != nil {
+			if ctx.Err() != nil && errors.Is(err, context.Canceled) {
+				// interrupted mid-block, the rolled back transaction is applied again on restart
+				return nil
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+type blockProcessor struct {
+	parsedBlock  *parser.Block
+	channel      string
+	checkpointer *dbCheckpointer
+	db           *sql.DB
+	replicators  map[string]replicator
+}
+
+func (b *blockProcessor) process(ctx context.Context) error {
+	fmt.Printf("\nReceived block %d on channel %s\n", b.parsedBlock.Number(), b.channel)
+
+	newTransactions, err := b.getNewTransactions()
+	if err != nil {
+		return err
+	}
+
+	for _, transaction := range newTransactions {
+		if !transaction.IsValid() {
+			continue
+		}
+		if err := b.apply(ctx, transaction); err != nil {
+			return err
+		}
+	}
+
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := b.checkpointer.commit(ctx, tx, b.parsedBlock.Number()+1, ""); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
+
+// apply replicates a valid transaction in a single database transaction together with the
+// checkpoint after it, so that it is applied exactly once
+func (b *blockProcessor) apply(ctx context.Context, transaction *parser.Transaction) error {
+	transactionID := transaction.ChannelHeader().GetTxId()
+
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	transactionProcessor := transactionProcessor{
+		b.parsedBlock.Number(),
+		b.channel,
+		transaction,
+		b.replicators,
+	}
+	if err := transactionProcessor.process(ctx, tx); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to replicate transaction %s: %w", transactionID, err)
+	}
+	if err := b.checkpointer.commit(ctx, tx, b.parsedBlock.Number(), transactionID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
+
+func (b *blockProcessor) getNewTransactions() ([]*parser.Transaction, error) {
+	transactions, err := b.parsedBlock.Transactions()
+	if err != nil {
+		return nil, err
+	}
+
+	lastTransactionID := b.checkpointer.TransactionID()
+	if lastTransactionID == "" {
+		// No previously processed transactions within this block so all are new
+		return transactions, nil
+	}
+
+	// Ignore transactions up to the last processed transaction ID
+	blockTransactionIDs := []string{}
+	for _, transaction := range transactions {
+		blockTransactionIDs = append(blockTransactionIDs, transaction.ChannelHeader().GetTxId())
+	}
+	for index, id := range blockTransactionIDs {
+		if id == lastTransactionID {
+			return transactions[index+1:], nil
+		}
+	}
+
+	return nil, fmt.Errorf(
+		"checkpoint transaction ID %s not found in block %d containing transactions: %s",
+		lastTransactionID,
+		b.parsedBlock.Number(),
+		strings.Join(blockTransactionIDs, ", "),
+	)
+}
+
+type transactionProcessor struct {
+	blockNumber uint64
+	channel     string
+	transaction *parser.Transaction
+	replicators map[string]replicator
+}
+
+func (t *transactionProcessor) process(ctx context.Context, tx *sql.Tx) error {
+	transactionID := t.transaction.ChannelHeader().GetTxId()
+	timestamp := t.transaction.ChannelHeader().GetTimestamp().AsTime()
+
+	nsReadWriteSets, err := t.transaction.NamespaceReadWriteSets()
+	if err != nil {
+		return err
+	}
+	for _, nsReadWriteSet := range nsReadWriteSets {
+		replicate, ok := t.replicators[nsReadWriteSet.Namespace()]
+		if !ok {
+			continue
+		}
+		kvReadWriteSet, err := nsReadWriteSet.ReadWriteSet()
+		if err != nil {
+			return err
+		}
+
+		for _, kvWrite := range kvReadWriteSet.GetWrites() {
+			err := replicate(ctx, tx, write{
+				BlockNumber:   t.blockNumber,
+				TransactionID: transactionID,
+				Timestamp:     timestamp,
+				Key:           kvWrite.GetKey(),
+				IsDelete:      kvWrite.GetIsDelete(),
+				Value:         kvWrite.GetValue(),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	chaincodeEvents, err := t.transaction.ChaincodeEvents()
+	if err != nil {
+		return err
+	}
+	for _, event := range chaincodeEvents {
+		if _, ok := t.replicators[event.GetChaincodeId()]; !ok {
+			continue
+		}
+		fmt.Printf("Chaincode event %s from %s in transaction %s\n", event.GetEventName(), event.GetChaincodeId(), transactionID)
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO chaincode_events (channel, transaction_id, chaincode, event_name, payload, block_number, recorded_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT DO NOTHING`,
+			t.channel, transactionID, event.GetChaincodeId(), event.GetEventName(), event.GetPayload(), t.blockNumber, timestamp,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
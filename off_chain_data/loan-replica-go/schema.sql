@@ -0,0 +1,62 @@
+-- The listening position of each channel. block_number is the next block to process; when
+-- transaction_id is set, the transactions of that block up to and including it are already applied.
+CREATE TABLE IF NOT EXISTS checkpoints (
+    channel        TEXT PRIMARY KEY,
+    block_number   BIGINT NOT NULL,
+    transaction_id TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS loans (
+    id             TEXT PRIMARY KEY,
+    applicant      TEXT NOT NULL,
+    amount         BIGINT NOT NULL,
+    currency       TEXT NOT NULL,
+    term           INTEGER NOT NULL,
+    interest_rate  NUMERIC NOT NULL,
+    status         TEXT NOT NULL,
+    owner_msp      TEXT NOT NULL,
+    document       JSONB NOT NULL,
+    block_number   BIGINT NOT NULL,
+    transaction_id TEXT NOT NULL,
+    updated_at     TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS loans_applicant ON loans (applicant);
+CREATE INDEX IF NOT EXISTS loans_status ON loans (status);
+
+-- Every status a loan has been in, including Deleted when the loan was removed from the ledger
+CREATE TABLE IF NOT EXISTS loan_status_history (
+    loan_id        TEXT NOT NULL,
+    status         TEXT NOT NULL,
+    block_number   BIGINT NOT NULL,
+    transaction_id TEXT NOT NULL,
+    recorded_at    TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (loan_id, transaction_id)
+);
+
+CREATE TABLE IF NOT EXISTS identities (
+    id             TEXT PRIMARY KEY,
+    first_name     TEXT NOT NULL,
+    last_name      TEXT NOT NULL,
+    cnic           TEXT NOT NULL,
+    kyc_level      TEXT NOT NULL,
+    risk_rating    TEXT NOT NULL,
+    owner_msp      TEXT NOT NULL,
+    document       JSONB NOT NULL,
+    block_number   BIGINT NOT NULL,
+    transaction_id TEXT NOT NULL,
+    updated_at     TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS identities_kyc_level ON identities (kyc_level);
+
+CREATE TABLE IF NOT EXISTS chaincode_events (
+    channel        TEXT NOT NULL,
+    transaction_id TEXT NOT NULL,
+    chaincode      TEXT NOT NULL,
+    event_name     TEXT NOT NULL,
+    payload        BYTEA,
+    block_number   BIGINT NOT NULL,
+    recorded_at    TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (channel, transaction_id, chaincode)
+);
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// compositeKeyPrefix starts the composite keys the chaincodes keep their indexes and auxiliary
+// records under. Loans and identities are stored under their plain IDs.
+const compositeKeyPrefix = "\x00"
+
+// The fields of a bankcontract loan application the replica queries by
+type loanRecord struct {
+	ID           string  `json:"id"`
+	Applicant    string  `json:"applicant"`
+	Amount       int64   `json:"amount"`
+	Currency     string  `json:"currency"`
+	Term         int     `json:"term"`
+	InterestRate float64 `json:"interestRate"`
+	Status       string  `json:"status"`
+	OwnerMSP     string  `json:"ownerMsp"`
+}
+
+// The fields of an identitycontract identity the replica queries by
+type identityRecord struct {
+	ID         string `json:"id"`
+	FirstName  string `json:"firstName"`
+	LastName   string `json:"lastName"`
+	CNIC       string `json:"cnic"`
+	KYCLevel   string `json:"kycLevel"`
+	RiskRating string `json:"riskRating"`
+	OwnerMSP   string `json:"ownerMSP"`
+}
+
+// replicateLoan applies a write of the loan chaincode, recording each status the loan moves to
+func replicateLoan(ctx context.Context, tx *sql.Tx, w write) error {
+	if strings.HasPrefix(w.Key, compositeKeyPrefix) {
+		return nil
+	}
+
+	var previousStatus string
+	err := tx.QueryRowContext(ctx, "SELECT status FROM loans WHERE id = $1", w.Key).Scan(&previousStatus)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	status := "Deleted"
+	if w.IsDelete {
+		fmt.Println("Delete loan", w.Key)
+		_, err = tx.ExecContext(ctx, "DELETE FROM loans WHERE id = $1", w.Key)
+		if err != nil {
+			return err
+		}
+	} else {
+		var l loanRecord
+		if err := json.Unmarshal(w.Value, &l); err != nil {
+			return fmt.Errorf("failed to parse loan %s: %w", w.Key, err)
+		}
+		status = l.Status
+
+		fmt.Println("Write loan", w.Key)
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO loans (id, applicant, amount, currency, term, interest_rate, status, owner_msp, document, block_number, transaction_id, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+			ON CONFLICT (id) DO UPDATE SET
+				applicant = $2, amount = $3, currency = $4, term = $5, interest_rate = $6, status = $7,
+				owner_msp = $8, document = $9, block_number = $10, transaction_id = $11, updated_at = $12`,
+			w.Key, l.Applicant, l.Amount, l.Currency, l.Term, l.InterestRate, l.Status, l.OwnerMSP,
+			string(w.Value), w.BlockNumber, w.TransactionID, w.Timestamp,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	if status == previousStatus {
+		return nil
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO loan_status_history (loan_id, status, block_number, transaction_id, recorded_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT DO NOTHING`,
+		w.Key, status, w.BlockNumber, w.TransactionID, w.Timestamp,
+	)
+	return err
+}
+
+// replicateIdentity applies a write of the identity chaincode
+func replicateIdentity(ctx context.Context, tx *sql.Tx, w write) error {
+	if strings.HasPrefix(w.Key, compositeKeyPrefix) {
+		return nil
+	}
+
+	if w.IsDelete {
+		fmt.Println("Delete identity", w.Key)
+		_, err := tx.ExecContext(ctx, "DELETE FROM identities WHERE id = $1", w.Key)
+		return err
+	}
+
+	var i identityRecord
+	if err := json.Unmarshal(w.Value, &i); err != nil {
+		return fmt.Errorf("failed to parse identity %s: %w", w.Key, err)
+	}
+
+	fmt.Println("Write identity", w.Key)
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO identities (id, first_name, last_name, cnic, kyc_level, risk_rating, owner_msp, document, block_number, transaction_id, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (id) DO UPDATE SET
+			first_name = $2, last_name = $3, cnic = $4, kyc_level = $5, risk_rating = $6,
+			owner_msp = $7, document = $8, block_number = $9, transaction_id = $10, updated_at = $11`,
+		w.Key, i.FirstName, i.LastName, i.CNIC, i.KYCLevel, i.RiskRating, i.OwnerMSP,
+		string(w.Value), w.BlockNumber, w.TransactionID, w.Timestamp,
+	)
+	return err
+}
@@ -0,0 +1,136 @@
+package authz
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const auditObjectType = "audit"
+
+// AuditEntry records one invocation of a chaincode, keyed audit~txid
+type AuditEntry struct {
+	TxID      string `json:"txId"`
+	Function  string `json:"function"` // as invoked, e.g. SchemaRegistry:RegisterSchema
+	MSPID     string `json:"mspId"`
+	ClientID  string `json:"clientId"`
+	Timestamp string `json:"timestamp"` // the transaction timestamp, RFC 3339
+}
+
+// AuditPage is one page of the audit trail with the transaction ID the next page starts at
+type AuditPage struct {
+	Entries  []*AuditEntry `json:"entries,omitempty" metadata:",optional"`
+	NextTxID string        `json:"nextTxId"`
+}
+
+// Audit returns a BeforeTransaction handler writing an AuditEntry for every invocation of a
+// contract, so each submitted transaction leaves a trace on the ledger. Evaluated transactions
+// are never committed and leave none. Every invocation is also logged at DEBUG level.
+//
+// Fabric refuses paginated queries in a transaction that has written, so functions running one,
+// GetAuditTrail among them, must be named in skip and are not audited.
+func Audit(skip ...string) func(contractapi.TransactionContextInterface) error {
+	skipped := make(map[string]bool)
+	for _, name := range skip {
+		skipped[name] = true
+	}
+
+	return func(ctx contractapi.TransactionContextInterface) error {
+		TxLogger(ctx).Debug("transaction invoked")
+
+		function, _ := ctx.GetStub().GetFunctionAndParameters()
+		name := function
+		if i := strings.LastIndex(function, ":"); i >= 0 {
+			name = function[i+1:]
+		}
+		if skipped[name] {
+			return nil
+		}
+
+		return writeAuditEntry(ctx, function)
+	}
+}
+
+// AuditTrail returns up to pageSize audit entries whose transaction IDs lie between startTx,
+// inclusive, and endTx, exclusive. Entries are ordered by transaction ID, not by time; an empty
+// startTx starts at the first entry and an empty endTx runs to the last. Pass the NextTxID of a
+// page as startTx to read the next one.
+func AuditTrail(ctx contractapi.TransactionContextInterface, startTx string, endTx string, pageSize int) (*AuditPage, error) {
+	if pageSize <= 0 {
+		return nil, fmt.Errorf("pageSize must be positive")
+	}
+	if endTx != "" && startTx >= endTx {
+		return nil, fmt.Errorf("startTx must come before endTx")
+	}
+
+	bookmark := ""
+	if startTx != "" {
+		var err error
+		bookmark, err = ctx.GetStub().CreateCompositeKey(auditObjectType, []string{startTx})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create composite key: %v", err)
+		}
+	}
+	// one entry more than the page, to tell where the next page starts
+	resultsIterator, _, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(auditObjectType, []string{}, int32(pageSize+1), bookmark)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	page := &AuditPage{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var entry AuditEntry
+		err = json.Unmarshal(queryResponse.Value, &entry)
+		if err != nil {
+			return nil, err
+		}
+		if endTx != "" && entry.TxID >= endTx {
+			break
+		}
+		if len(page.Entries) == pageSize {
+			page.NextTxID = entry.TxID
+			break
+		}
+		page.Entries = append(page.Entries, &entry)
+	}
+
+	return page, nil
+}
+
+func writeAuditEntry(ctx contractapi.TransactionContextInterface, function string) error {
+	client, err := ClientOf(ctx)
+	if err != nil {
+		return err
+	}
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	entry := AuditEntry{
+		TxID:      ctx.GetStub().GetTxID(),
+		Function:  function,
+		MSPID:     client.MSPID,
+		ClientID:  client.ID,
+		Timestamp: time.Unix(timestamp.Seconds, int64(timestamp.Nanos)).UTC().Format(time.RFC3339),
+	}
+	entryKey, err := ctx.GetStub().CreateCompositeKey(auditObjectType, []string{entry.TxID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	entryJSON, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(entryKey, entryJSON)
+}
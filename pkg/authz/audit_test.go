@@ -0,0 +1,60 @@
+package authz
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+)
+
+func TestAudit(t *testing.T) {
+	audit := Audit("GetAuditTrail", "GetIdentitiesPaginated")
+	entryKey, err := shim.CreateCompositeKey(auditObjectType, []string{"tx1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		function string
+		audited  bool
+	}{
+		{name: "unnamed functions are audited", function: "CreateIdentity", audited: true},
+		{name: "functions of a named contract are audited", function: "IdentityContract:CreateIdentity", audited: true},
+		{name: "skipped functions", function: "GetAuditTrail"},
+		{name: "skipped functions of a named contract", function: "IdentityContract:GetIdentitiesPaginated"},
+		{name: "the skip list matches whole names", function: "GetAuditTrailPage", audited: true},
+		{name: "the skip list is case sensitive", function: "getAuditTrail", audited: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newTestStub()
+			stub.function = tt.function
+			ctx := &TransactionContext{client: &Client{ID: "x509::CN=ash", MSPID: "Org1MSP"}}
+			ctx.SetStub(stub)
+
+			err := audit(ctx)
+			if err != nil {
+				t.Fatalf("Audit() error = %v", err)
+			}
+
+			entryJSON, audited := stub.writes[entryKey]
+			if audited != tt.audited || len(stub.writes) > 1 {
+				t.Fatalf("%s wrote %d keys, audited = %t, want %t", tt.function, len(stub.writes), audited, tt.audited)
+			}
+			if !audited {
+				return
+			}
+			var entry AuditEntry
+			err = json.Unmarshal(entryJSON, &entry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := AuditEntry{TxID: "tx1", Function: tt.function, MSPID: "Org1MSP", ClientID: "x509::CN=ash", Timestamp: "2024-01-01T09:00:00Z"}
+			if entry != want {
+				t.Errorf("audit entry = %+v, want %+v", entry, want)
+			}
+		})
+	}
+}
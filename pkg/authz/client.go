@@ -0,0 +1,143 @@
+// Package authz holds the helpers shared by the sample chaincodes: the submitting client's MSP
+// and attributes, read once per transaction and cached on the transaction context, reads that see
+// the transaction's own writes, declarative
+// rules redacting record fields by client attribute, an on-ledger audit trail of invocations and
+// structured logging tagged with the transaction and its caller.
+package authz
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// attributeExtension is the certificate extension the Fabric CA stores enrollment attributes in
+var attributeExtension = asn1.ObjectIdentifier{1, 2, 3, 4, 5, 6, 7, 8, 1}
+
+// Client is the identity of the submitting client of a transaction
+type Client struct {
+	ID          string // the client identity ID, as returned by the client identity's GetID
+	MSPID       string
+	CommonName  string
+	Attributes  map[string]string
+	Certificate *x509.Certificate
+}
+
+// Attribute returns the value of an enrollment attribute and whether the client carries it
+func (c *Client) Attribute(name string) (string, bool) {
+	value, ok := c.Attributes[name]
+	return value, ok
+}
+
+// HasAttribute reports whether the client carries the attribute with the given value
+func (c *Client) HasAttribute(name string, value string) bool {
+	actual, ok := c.Attributes[name]
+	return ok && actual == value
+}
+
+// TransactionContext is a transaction context caching the submitting client, so that its
+// certificate is parsed once however many checks a transaction makes, and whose stub returns the
+// values the transaction wrote from GetState and GetPrivateData. Contracts use it by setting it as
+// their TransactionContextHandler; contract functions keep taking
+// contractapi.TransactionContextInterface.
+type TransactionContext struct {
+	contractapi.TransactionContext
+
+	client *Client
+}
+
+// SetStub sets the stub of the transaction, wrapped so that its reads see the transaction's
+// earlier writes
+func (ctx *TransactionContext) SetStub(stub shim.ChaincodeStubInterface) {
+	ctx.TransactionContext.SetStub(newPendingWrites(stub))
+}
+
+// Client returns the submitting client, reading it on first use
+func (ctx *TransactionContext) Client() (*Client, error) {
+	if ctx.client == nil {
+		client, err := readClient(ctx)
+		if err != nil {
+			return nil, err
+		}
+		ctx.client = client
+	}
+
+	return ctx.client, nil
+}
+
+// ClientOf returns the submitting client of a transaction, from the cache of a TransactionContext
+// when the contract uses one
+func ClientOf(ctx contractapi.TransactionContextInterface) (*Client, error) {
+	if cached, ok := ctx.(interface{ Client() (*Client, error) }); ok {
+		return cached.Client()
+	}
+
+	return readClient(ctx)
+}
+
+// CallerMSP returns the MSP ID of the submitting client
+func CallerMSP(ctx contractapi.TransactionContextInterface) (string, error) {
+	client, err := ClientOf(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return client.MSPID, nil
+}
+
+// Attribute returns the value of an enrollment attribute of the submitting client and whether
+// the client carries it
+func Attribute(ctx contractapi.TransactionContextInterface, name string) (string, bool, error) {
+	client, err := ClientOf(ctx)
+	if err != nil {
+		return "", false, err
+	}
+	value, ok := client.Attribute(name)
+
+	return value, ok, nil
+}
+
+func readClient(ctx contractapi.TransactionContextInterface) (*Client, error) {
+	identity := ctx.GetClientIdentity()
+	id, err := identity.GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client identity: %v", err)
+	}
+	mspID, err := identity.GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client MSP ID: %v", err)
+	}
+	cert, err := identity.GetX509Certificate()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client certificate: %v", err)
+	}
+
+	client := &Client{
+		ID:          id,
+		MSPID:       mspID,
+		CommonName:  cert.Subject.CommonName,
+		Attributes:  make(map[string]string),
+		Certificate: cert,
+	}
+	for _, extension := range cert.Extensions {
+		if !extension.Id.Equal(attributeExtension) {
+			continue
+		}
+		var attributes struct {
+			Attrs map[string]string `json:"attrs"`
+		}
+		err = json.Unmarshal(extension.Value, &attributes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse client attributes: %v", err)
+		}
+		for name, value := range attributes.Attrs {
+			client.Attributes[name] = value
+		}
+	}
+
+	return client, nil
+}
@@ -0,0 +1,62 @@
+package authz
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// logLevelEnv is the variable the peer passes its chaincode logging level to chaincodes in
+const logLevelEnv = "CORE_CHAINCODE_LOGGING_LEVEL"
+
+var logLevel = new(slog.LevelVar)
+
+// Logger writes JSON log lines to stderr at the level set by CORE_CHAINCODE_LOGGING_LEVEL, INFO
+// when it is unset, or later by SetLogLevel. Use TxLogger within a transaction.
+var Logger = slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel}))
+
+func init() {
+	if value := os.Getenv(logLevelEnv); value != "" {
+		if err := SetLogLevel(value); err != nil {
+			Logger.Warn("ignoring "+logLevelEnv, "error", err)
+		}
+	}
+}
+
+// TxLogger returns Logger with the transaction ID, the function as invoked and the MSP ID of the
+// submitting client attached to every line
+func TxLogger(ctx contractapi.TransactionContextInterface) *slog.Logger {
+	function, _ := ctx.GetStub().GetFunctionAndParameters()
+	mspID, _ := CallerMSP(ctx)
+
+	return Logger.With("txId", ctx.GetStub().GetTxID(), "function", function, "mspId", mspID)
+}
+
+// SetLogLevel changes the level Logger writes at. It takes the Fabric logging level names DEBUG,
+// INFO, WARNING and ERROR, with CRITICAL, PANIC and FATAL read as ERROR. The level only holds in
+// the chaincode process that ran the call, until it restarts; contracts offering it as a
+// transaction should have it submitted, so that it runs on every endorsing peer.
+func SetLogLevel(level string) error {
+	switch strings.ToUpper(level) {
+	case "DEBUG":
+		logLevel.Set(slog.LevelDebug)
+	case "INFO":
+		logLevel.Set(slog.LevelInfo)
+	case "WARN", "WARNING":
+		logLevel.Set(slog.LevelWarn)
+	case "ERROR", "CRITICAL", "PANIC", "FATAL":
+		logLevel.Set(slog.LevelError)
+	default:
+		return fmt.Errorf("unknown log level %q, expected DEBUG, INFO, WARNING or ERROR", level)
+	}
+
+	return nil
+}
+
+// LogLevel returns the level Logger writes at
+func LogLevel() string {
+	return logLevel.Level().String()
+}
@@ -0,0 +1,96 @@
+package authz
+
+import "github.com/hyperledger/fabric-chaincode-go/shim"
+
+// pendingWrites is a chaincode stub remembering the writes of its transaction. The peer only
+// applies writes when the transaction commits, so GetState after PutState of the same key would
+// return the committed value; pendingWrites returns the value written instead, or nil once the
+// key is deleted. Range, partial composite key and rich queries still see the committed state
+// only.
+type pendingWrites struct {
+	shim.ChaincodeStubInterface
+
+	state   map[string][]byte            // by key, nil when deleted
+	private map[string]map[string][]byte // by collection and key, nil when deleted
+}
+
+func newPendingWrites(stub shim.ChaincodeStubInterface) *pendingWrites {
+	return &pendingWrites{
+		ChaincodeStubInterface: stub,
+		state:                  make(map[string][]byte),
+		private:                make(map[string]map[string][]byte),
+	}
+}
+
+// GetState returns the value of the key as last written in the transaction, or as committed
+func (s *pendingWrites) GetState(key string) ([]byte, error) {
+	if value, ok := s.state[key]; ok {
+		return value, nil
+	}
+
+	return s.ChaincodeStubInterface.GetState(key)
+}
+
+// PutState writes the value of the key and remembers it for later reads
+func (s *pendingWrites) PutState(key string, value []byte) error {
+	err := s.ChaincodeStubInterface.PutState(key, value)
+	if err != nil {
+		return err
+	}
+	s.state[key] = value
+
+	return nil
+}
+
+// DelState deletes the key and remembers it as deleted for later reads
+func (s *pendingWrites) DelState(key string) error {
+	err := s.ChaincodeStubInterface.DelState(key)
+	if err != nil {
+		return err
+	}
+	s.state[key] = nil
+
+	return nil
+}
+
+// GetPrivateData returns the value of the key in the collection as last written in the
+// transaction, or as committed
+func (s *pendingWrites) GetPrivateData(collection string, key string) ([]byte, error) {
+	if value, ok := s.private[collection][key]; ok {
+		return value, nil
+	}
+
+	return s.ChaincodeStubInterface.GetPrivateData(collection, key)
+}
+
+// PutPrivateData writes the value of the key in the collection and remembers it for later reads
+func (s *pendingWrites) PutPrivateData(collection string, key string, value []byte) error {
+	err := s.ChaincodeStubInterface.PutPrivateData(collection, key, value)
+	if err != nil {
+		return err
+	}
+	s.collection(collection)[key] = value
+
+	return nil
+}
+
+// DelPrivateData deletes the key in the collection and remembers it as deleted for later reads
+func (s *pendingWrites) DelPrivateData(collection string, key string) error {
+	err := s.ChaincodeStubInterface.DelPrivateData(collection, key)
+	if err != nil {
+		return err
+	}
+	s.collection(collection)[key] = nil
+
+	return nil
+}
+
+func (s *pendingWrites) collection(name string) map[string][]byte {
+	writes, ok := s.private[name]
+	if !ok {
+		writes = make(map[string][]byte)
+		s.private[name] = writes
+	}
+
+	return writes
+}
@@ -0,0 +1,142 @@
+package authz
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// testStub is a chaincode stub over committed state that, like a peer, does not apply the writes
+// of its transaction before the transaction commits. Calls it does not implement panic.
+type testStub struct {
+	shim.ChaincodeStubInterface
+
+	function  string
+	state     map[string][]byte            // committed, by key
+	private   map[string]map[string][]byte // committed, by collection and key
+	writes    map[string][]byte            // written by the transaction, nil when deleted
+	timestamp *timestamppb.Timestamp
+}
+
+func newTestStub() *testStub {
+	return &testStub{
+		state:     map[string][]byte{"committed": []byte("old")},
+		private:   map[string]map[string][]byte{"secrets": {"committed": []byte("old")}},
+		writes:    make(map[string][]byte),
+		timestamp: timestamppb.New(time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)),
+	}
+}
+
+func (s *testStub) GetState(key string) ([]byte, error) {
+	return s.state[key], nil
+}
+
+func (s *testStub) PutState(key string, value []byte) error {
+	s.writes[key] = value
+	return nil
+}
+
+func (s *testStub) DelState(key string) error {
+	s.writes[key] = nil
+	return nil
+}
+
+func (s *testStub) GetPrivateData(collection string, key string) ([]byte, error) {
+	return s.private[collection][key], nil
+}
+
+func (s *testStub) PutPrivateData(collection string, key string, value []byte) error {
+	s.writes[collection+"/"+key] = value
+	return nil
+}
+
+func (s *testStub) DelPrivateData(collection string, key string) error {
+	s.writes[collection+"/"+key] = nil
+	return nil
+}
+
+func (s *testStub) GetFunctionAndParameters() (string, []string) {
+	return s.function, nil
+}
+
+func (s *testStub) GetTxID() string {
+	return "tx1"
+}
+
+func (s *testStub) GetTxTimestamp() (*timestamppb.Timestamp, error) {
+	return s.timestamp, nil
+}
+
+func (s *testStub) CreateCompositeKey(objectType string, attributes []string) (string, error) {
+	return shim.CreateCompositeKey(objectType, attributes)
+}
+
+func TestPendingWrites(t *testing.T) {
+	type op func(stub *pendingWrites) error
+	put := func(key string, value string) op {
+		return func(stub *pendingWrites) error { return stub.PutState(key, []byte(value)) }
+	}
+	del := func(key string) op {
+		return func(stub *pendingWrites) error { return stub.DelState(key) }
+	}
+	putPrivate := func(key string, value string) op {
+		return func(stub *pendingWrites) error { return stub.PutPrivateData("secrets", key, []byte(value)) }
+	}
+	delPrivate := func(key string) op {
+		return func(stub *pendingWrites) error { return stub.DelPrivateData("secrets", key) }
+	}
+
+	tests := []struct {
+		name        string
+		ops         []op
+		key         string
+		want        string // the value of key read after the ops
+		wantPrivate string // the value of key in the secrets collection read after the ops
+		nilPublic   bool   // the key reads as nil
+		nilPrivate  bool   // the private key reads as nil
+	}{
+		{name: "committed values without writes", key: "committed", want: "old", wantPrivate: "old"},
+		{name: "unknown keys", key: "missing", nilPublic: true, nilPrivate: true},
+		{name: "a write is read back", ops: []op{put("committed", "new")}, key: "committed", want: "new", wantPrivate: "old"},
+		{name: "the last write wins", ops: []op{put("key", "first"), put("key", "second")}, key: "key", want: "second", nilPrivate: true},
+		{name: "a delete reads as nil", ops: []op{del("committed")}, key: "committed", nilPublic: true, wantPrivate: "old"},
+		{name: "a write after a delete", ops: []op{del("committed"), put("committed", "again")}, key: "committed", want: "again", wantPrivate: "old"},
+		{name: "a private write is read back", ops: []op{putPrivate("committed", "new")}, key: "committed", want: "old", wantPrivate: "new"},
+		{name: "a private delete reads as nil", ops: []op{delPrivate("committed")}, key: "committed", want: "old", nilPrivate: true},
+		{name: "public and private keys stay apart", ops: []op{put("key", "public"), putPrivate("key", "private")}, key: "key", want: "public", wantPrivate: "private"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := newTestStub()
+			stub := newPendingWrites(inner)
+			for _, op := range tt.ops {
+				if err := op(stub); err != nil {
+					t.Fatalf("write failed: %v", err)
+				}
+			}
+
+			got, err := stub.GetState(tt.key)
+			if err != nil {
+				t.Fatalf("GetState() error = %v", err)
+			}
+			if tt.nilPublic != (got == nil) || string(got) != tt.want {
+				t.Errorf("GetState(%s) = %q, want %q", tt.key, got, tt.want)
+			}
+			got, err = stub.GetPrivateData("secrets", tt.key)
+			if err != nil {
+				t.Fatalf("GetPrivateData() error = %v", err)
+			}
+			if tt.nilPrivate != (got == nil) || string(got) != tt.wantPrivate {
+				t.Errorf("GetPrivateData(secrets, %s) = %q, want %q", tt.key, got, tt.wantPrivate)
+			}
+
+			// the writes still reach the peer
+			if len(inner.writes) != len(stub.state)+len(stub.private["secrets"]) {
+				t.Errorf("the stub received %d writes, want %d", len(inner.writes), len(stub.state)+len(stub.private["secrets"]))
+			}
+		})
+	}
+}
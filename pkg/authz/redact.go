@@ -0,0 +1,92 @@
+package authz
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Redacted replaces the value of redacted string fields that are set
+const Redacted = "***"
+
+// Rule decides which fields of a record the clients it matches may not see. Match is either
+// attribute=value, for example "role=auditor", or "*" for every client. An empty Redact shows
+// matching clients every field.
+type Rule struct {
+	Match  string
+	Redact []string // JSON names of the hidden fields
+}
+
+// Policy is an ordered list of rules; the first rule matching the client applies, and a client no
+// rule matches sees every field. For example
+//
+//	authz.Policy{
+//		{Match: "role=auditor"},
+//		{Match: "role=customer", Redact: []string{"dateOfBirth", "address"}},
+//	}
+type Policy []Rule
+
+// Redact hides the fields of record, a pointer to a struct, that the policy denies the client.
+// Set string fields become Redacted and other fields their zero value.
+func (p Policy) Redact(client *Client, record interface{}) error {
+	rule, err := p.match(client)
+	if err != nil || rule == nil || len(rule.Redact) == 0 {
+		return err
+	}
+
+	value := reflect.ValueOf(record)
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("authz: cannot redact %T, expected a pointer to a struct", record)
+	}
+	value = value.Elem()
+	fields := make(map[string]int)
+	for i := 0; i < value.NumField(); i++ {
+		name := strings.Split(value.Type().Field(i).Tag.Get("json"), ",")[0]
+		fields[name] = i
+	}
+
+	for _, name := range rule.Redact {
+		i, ok := fields[name]
+		if !ok {
+			return fmt.Errorf("authz: %s has no field %s", value.Type(), name)
+		}
+		field := value.Field(i)
+		if field.Kind() == reflect.String && field.String() != "" {
+			field.SetString(Redacted)
+		} else {
+			field.Set(reflect.Zero(field.Type()))
+		}
+	}
+
+	return nil
+}
+
+// Apply redacts record for the submitting client of the transaction
+func (p Policy) Apply(ctx contractapi.TransactionContextInterface, record interface{}) error {
+	client, err := ClientOf(ctx)
+	if err != nil {
+		return err
+	}
+
+	return p.Redact(client, record)
+}
+
+func (p Policy) match(client *Client) (*Rule, error) {
+	for i := range p {
+		rule := &p[i]
+		if rule.Match == "*" {
+			return rule, nil
+		}
+		name, value, found := strings.Cut(rule.Match, "=")
+		if !found || name == "" {
+			return nil, fmt.Errorf("authz: invalid rule %q, expected attribute=value or *", rule.Match)
+		}
+		if client.HasAttribute(name, value) {
+			return rule, nil
+		}
+	}
+
+	return nil, nil
+}
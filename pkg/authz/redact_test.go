@@ -0,0 +1,128 @@
+package authz
+
+import (
+	"reflect"
+	"testing"
+)
+
+type testRecord struct {
+	Name    string   `json:"name"`
+	Address string   `json:"address,omitempty"`
+	Phone   string   `json:"phone"`
+	Balance int      `json:"balance"`
+	Tags    []string `json:"tags"`
+}
+
+func TestRedact(t *testing.T) {
+	policy := Policy{
+		{Match: "role=auditor"},
+		{Match: "role=customer", Redact: []string{"address", "balance"}},
+		{Match: "*", Redact: []string{"name", "address", "phone", "balance", "tags"}},
+	}
+	failOpen := Policy{
+		{Match: "role=customer", Redact: []string{"address"}},
+	}
+
+	tests := []struct {
+		name       string
+		policy     Policy
+		attributes map[string]string
+		record     interface{}
+		want       testRecord
+		err        string
+	}{
+		{
+			name:       "rules without redactions show every field",
+			policy:     policy,
+			attributes: map[string]string{"role": "auditor"},
+			want:       testRecord{Name: "Ash", Address: "Pallet Town", Balance: 100, Tags: []string{"trainer"}},
+		},
+		{
+			name:       "matching rules redact strings and zero other fields",
+			policy:     policy,
+			attributes: map[string]string{"role": "customer"},
+			want:       testRecord{Name: "Ash", Address: Redacted, Tags: []string{"trainer"}},
+		},
+		{
+			name:       "the first matching rule applies",
+			policy:     Policy{{Match: "role=customer"}, {Match: "role=customer", Redact: []string{"name"}}},
+			attributes: map[string]string{"role": "customer"},
+			want:       testRecord{Name: "Ash", Address: "Pallet Town", Balance: 100, Tags: []string{"trainer"}},
+		},
+		{
+			name:       "clients without a role fall through to the catch-all",
+			policy:     policy,
+			attributes: map[string]string{},
+			want:       testRecord{Name: Redacted, Address: Redacted},
+		},
+		{
+			name:       "other attribute values fall through to the catch-all",
+			policy:     policy,
+			attributes: map[string]string{"role": "admin", "auditor": "true"},
+			want:       testRecord{Name: Redacted, Address: Redacted},
+		},
+		{
+			name:       "without a catch-all unmatched clients see every field",
+			policy:     failOpen,
+			attributes: map[string]string{},
+			want:       testRecord{Name: "Ash", Address: "Pallet Town", Balance: 100, Tags: []string{"trainer"}},
+		},
+		{
+			name:       "invalid rule",
+			policy:     Policy{{Match: "auditor"}},
+			attributes: map[string]string{},
+			err:        `authz: invalid rule "auditor", expected attribute=value or *`,
+		},
+		{
+			name:       "unknown field",
+			policy:     Policy{{Match: "*", Redact: []string{"email"}}},
+			attributes: map[string]string{},
+			err:        "authz: authz.testRecord has no field email",
+		},
+		{
+			name:       "records must be pointers to structs",
+			policy:     policy,
+			attributes: map[string]string{},
+			record:     testRecord{},
+			err:        "authz: cannot redact authz.testRecord, expected a pointer to a struct",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := &testRecord{Name: "Ash", Address: "Pallet Town", Balance: 100, Tags: []string{"trainer"}}
+			var target interface{} = record
+			if tt.record != nil {
+				target = tt.record
+			}
+
+			err := tt.policy.Redact(&Client{Attributes: tt.attributes}, target)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("Redact() error = %v, want %s", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Redact() error = %v", err)
+			}
+			if !reflect.DeepEqual(*record, tt.want) {
+				t.Errorf("Redact() = %+v, want %+v", *record, tt.want)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	ctx := &TransactionContext{client: &Client{Attributes: map[string]string{"role": "customer"}}}
+	ctx.SetStub(newTestStub())
+	record := &testRecord{Name: "Ash", Address: "Pallet Town"}
+
+	err := Policy{{Match: "role=customer", Redact: []string{"address"}}}.Apply(ctx, record)
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if record.Address != Redacted {
+		t.Errorf("Apply() left the address %q, want it redacted for the submitting client", record.Address)
+	}
+}
@@ -0,0 +1,114 @@
+// Package ccaas starts the sample chaincodes either under the peer or as an external service
+// (Chaincode-as-a-Service), so that they can run against Fabric 2.4+ without the legacy builder.
+//
+// A chaincode runs as a service when CHAINCODE_SERVER_ADDRESS is set to the address to listen on.
+// CHAINCODE_ID must then be the package ID the chaincode was installed under. TLS between the peer
+// and the chaincode is disabled unless CHAINCODE_TLS_DISABLED=false, in which case
+// CHAINCODE_TLS_KEY and CHAINCODE_TLS_CERT name the PEM files of the server's key pair, and
+// CHAINCODE_CLIENT_CA_CERT optionally names the CA certificate peers must present a certificate of.
+//
+// A chaincode running as a service also serves Prometheus metrics on /metrics when
+// CHAINCODE_METRICS_ADDRESS is set to the address to listen on, see Instrument.
+package ccaas
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Start runs chaincode as an external service if CHAINCODE_SERVER_ADDRESS is set, and otherwise
+// connects it to the peer that launched it. It returns once the chaincode stops.
+func Start(chaincode *contractapi.ContractChaincode) error {
+	address := os.Getenv("CHAINCODE_SERVER_ADDRESS")
+	if address == "" {
+		return chaincode.Start()
+	}
+
+	ccid := os.Getenv("CHAINCODE_ID")
+	if ccid == "" {
+		return fmt.Errorf("CHAINCODE_ID must be set to the package ID when CHAINCODE_SERVER_ADDRESS is")
+	}
+	tlsProps, err := tlsProperties()
+	if err != nil {
+		return err
+	}
+
+	var cc shim.Chaincode = chaincode
+	if metricsAddress := os.Getenv("CHAINCODE_METRICS_ADDRESS"); metricsAddress != "" {
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+		cc = Instrument(chaincode, registry)
+		go serveMetrics(metricsAddress, registry)
+	}
+
+	server := &shim.ChaincodeServer{
+		CCID:     ccid,
+		Address:  address,
+		CC:       cc,
+		TLSProps: tlsProps,
+	}
+	return server.Start()
+}
+
+// serveMetrics serves the metrics of registry on /metrics. The chaincode keeps running if the
+// endpoint fails.
+func serveMetrics(address string, registry *prometheus.Registry) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	err := http.ListenAndServe(address, mux)
+	slog.Error("metrics endpoint stopped", "address", address, "error", err)
+}
+
+func tlsProperties() (shim.TLSProperties, error) {
+	disabled := true
+	if value := os.Getenv("CHAINCODE_TLS_DISABLED"); value != "" {
+		var err error
+		disabled, err = strconv.ParseBool(value)
+		if err != nil {
+			return shim.TLSProperties{}, fmt.Errorf("invalid CHAINCODE_TLS_DISABLED %q: %v", value, err)
+		}
+	}
+	if disabled {
+		return shim.TLSProperties{Disabled: true}, nil
+	}
+
+	key, err := readPEM("CHAINCODE_TLS_KEY")
+	if err != nil {
+		return shim.TLSProperties{}, err
+	}
+	cert, err := readPEM("CHAINCODE_TLS_CERT")
+	if err != nil {
+		return shim.TLSProperties{}, err
+	}
+	var clientCACerts []byte
+	if os.Getenv("CHAINCODE_CLIENT_CA_CERT") != "" {
+		clientCACerts, err = readPEM("CHAINCODE_CLIENT_CA_CERT")
+		if err != nil {
+			return shim.TLSProperties{}, err
+		}
+	}
+
+	return shim.TLSProperties{Key: key, Cert: cert, ClientCACerts: clientCACerts}, nil
+}
+
+// readPEM reads the PEM file named by an environment variable
+func readPEM(variable string) ([]byte, error) {
+	path := os.Getenv(variable)
+	if path == "" {
+		return nil, fmt.Errorf("%s must be set when chaincode TLS is enabled", variable)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", variable, err)
+	}
+	return content, nil
+}
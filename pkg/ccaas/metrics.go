@@ -0,0 +1,198 @@
+package ccaas
+
+import (
+	"strings"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// unknownFunction labels invocations of functions the chaincode does not have, so that callers
+// cannot add label values at will
+const unknownFunction = "unknown"
+
+// Instrument wraps a chaincode so that every invocation of every contract function is counted
+// in the Prometheus metrics registered with registerer:
+//
+//	chaincode_invocations_total{function}      invocations
+//	chaincode_invocation_errors_total{function} invocations answered with an error
+//	chaincode_state_reads{function}             keys read per invocation, including query results
+//	chaincode_state_writes{function}            keys written or deleted per invocation
+//
+// Functions are labelled as invoked, e.g. SchemaRegistry:RegisterSchema. State includes private
+// data. Evaluated transactions are counted as well as submitted ones.
+func Instrument(chaincode shim.Chaincode, registerer prometheus.Registerer) shim.Chaincode {
+	sizes := []float64{0, 1, 2, 5, 10, 20, 50, 100, 200, 500, 1000}
+	instrumented := &instrumentedChaincode{
+		Chaincode: chaincode,
+		invocations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "chaincode_invocations_total",
+			Help: "Invocations of chaincode functions.",
+		}, []string{"function"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "chaincode_invocation_errors_total",
+			Help: "Invocations of chaincode functions answered with an error.",
+		}, []string{"function"}),
+		reads: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "chaincode_state_reads",
+			Help:    "Keys read by one invocation, including the results of range and rich queries.",
+			Buckets: sizes,
+		}, []string{"function"}),
+		writes: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "chaincode_state_writes",
+			Help:    "Keys written or deleted by one invocation.",
+			Buckets: sizes,
+		}, []string{"function"}),
+	}
+	registerer.MustRegister(instrumented.invocations, instrumented.errors, instrumented.reads, instrumented.writes)
+
+	return instrumented
+}
+
+type instrumentedChaincode struct {
+	shim.Chaincode
+
+	invocations *prometheus.CounterVec
+	errors      *prometheus.CounterVec
+	reads       *prometheus.HistogramVec
+	writes      *prometheus.HistogramVec
+}
+
+func (c *instrumentedChaincode) Init(stub shim.ChaincodeStubInterface) peer.Response {
+	return c.observe(stub, c.Chaincode.Init)
+}
+
+func (c *instrumentedChaincode) Invoke(stub shim.ChaincodeStubInterface) peer.Response {
+	return c.observe(stub, c.Chaincode.Invoke)
+}
+
+// observe runs one invocation against a stub counting its state access and records the outcome
+func (c *instrumentedChaincode) observe(stub shim.ChaincodeStubInterface, invoke func(shim.ChaincodeStubInterface) peer.Response) peer.Response {
+	counting := &countingStub{ChaincodeStubInterface: stub}
+	response := invoke(counting)
+
+	function, _ := stub.GetFunctionAndParameters()
+	// contractapi answers functions it does not have with "Function X not found in contract Y"
+	if response.Status >= shim.ERRORTHRESHOLD && strings.Contains(response.Message, " not found in contract ") {
+		function = unknownFunction
+	}
+	c.invocations.WithLabelValues(function).Inc()
+	if response.Status >= shim.ERRORTHRESHOLD {
+		c.errors.WithLabelValues(function).Inc()
+	}
+	c.reads.WithLabelValues(function).Observe(float64(counting.reads))
+	c.writes.WithLabelValues(function).Observe(float64(counting.writes))
+
+	return response
+}
+
+// countingStub counts the keys an invocation reads and writes
+type countingStub struct {
+	shim.ChaincodeStubInterface
+
+	reads  int
+	writes int
+}
+
+func (s *countingStub) GetState(key string) ([]byte, error) {
+	s.reads++
+	return s.ChaincodeStubInterface.GetState(key)
+}
+
+func (s *countingStub) PutState(key string, value []byte) error {
+	s.writes++
+	return s.ChaincodeStubInterface.PutState(key, value)
+}
+
+func (s *countingStub) DelState(key string) error {
+	s.writes++
+	return s.ChaincodeStubInterface.DelState(key)
+}
+
+func (s *countingStub) GetStateByRange(startKey, endKey string) (shim.StateQueryIteratorInterface, error) {
+	return s.countResults(s.ChaincodeStubInterface.GetStateByRange(startKey, endKey))
+}
+
+func (s *countingStub) GetStateByRangeWithPagination(startKey, endKey string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	iterator, metadata, err := s.ChaincodeStubInterface.GetStateByRangeWithPagination(startKey, endKey, pageSize, bookmark)
+	iterator, err = s.countResults(iterator, err)
+	return iterator, metadata, err
+}
+
+func (s *countingStub) GetStateByPartialCompositeKey(objectType string, keys []string) (shim.StateQueryIteratorInterface, error) {
+	return s.countResults(s.ChaincodeStubInterface.GetStateByPartialCompositeKey(objectType, keys))
+}
+
+func (s *countingStub) GetStateByPartialCompositeKeyWithPagination(objectType string, keys []string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	iterator, metadata, err := s.ChaincodeStubInterface.GetStateByPartialCompositeKeyWithPagination(objectType, keys, pageSize, bookmark)
+	iterator, err = s.countResults(iterator, err)
+	return iterator, metadata, err
+}
+
+func (s *countingStub) GetQueryResult(query string) (shim.StateQueryIteratorInterface, error) {
+	return s.countResults(s.ChaincodeStubInterface.GetQueryResult(query))
+}
+
+func (s *countingStub) GetQueryResultWithPagination(query string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	iterator, metadata, err := s.ChaincodeStubInterface.GetQueryResultWithPagination(query, pageSize, bookmark)
+	iterator, err = s.countResults(iterator, err)
+	return iterator, metadata, err
+}
+
+func (s *countingStub) GetPrivateData(collection, key string) ([]byte, error) {
+	s.reads++
+	return s.ChaincodeStubInterface.GetPrivateData(collection, key)
+}
+
+func (s *countingStub) GetPrivateDataHash(collection, key string) ([]byte, error) {
+	s.reads++
+	return s.ChaincodeStubInterface.GetPrivateDataHash(collection, key)
+}
+
+func (s *countingStub) PutPrivateData(collection string, key string, value []byte) error {
+	s.writes++
+	return s.ChaincodeStubInterface.PutPrivateData(collection, key, value)
+}
+
+func (s *countingStub) DelPrivateData(collection, key string) error {
+	s.writes++
+	return s.ChaincodeStubInterface.DelPrivateData(collection, key)
+}
+
+func (s *countingStub) PurgePrivateData(collection, key string) error {
+	s.writes++
+	return s.ChaincodeStubInterface.PurgePrivateData(collection, key)
+}
+
+func (s *countingStub) GetPrivateDataByRange(collection, startKey, endKey string) (shim.StateQueryIteratorInterface, error) {
+	return s.countResults(s.ChaincodeStubInterface.GetPrivateDataByRange(collection, startKey, endKey))
+}
+
+func (s *countingStub) GetPrivateDataByPartialCompositeKey(collection, objectType string, keys []string) (shim.StateQueryIteratorInterface, error) {
+	return s.countResults(s.ChaincodeStubInterface.GetPrivateDataByPartialCompositeKey(collection, objectType, keys))
+}
+
+func (s *countingStub) GetPrivateDataQueryResult(collection, query string) (shim.StateQueryIteratorInterface, error) {
+	return s.countResults(s.ChaincodeStubInterface.GetPrivateDataQueryResult(collection, query))
+}
+
+// countResults counts each result of a query iterator as a read as it is consumed
+func (s *countingStub) countResults(iterator shim.StateQueryIteratorInterface, err error) (shim.StateQueryIteratorInterface, error) {
+	if err != nil {
+		return iterator, err
+	}
+	return &countingIterator{StateQueryIteratorInterface: iterator, reads: &s.reads}, nil
+}
+
+type countingIterator struct {
+	shim.StateQueryIteratorInterface
+
+	reads *int
+}
+
+func (i *countingIterator) Next() (*queryresult.KV, error) {
+	*i.reads++
+	return i.StateQueryIteratorInterface.Next()
+}
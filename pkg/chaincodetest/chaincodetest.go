@@ -0,0 +1,83 @@
+// Package chaincodetest runs contract functions of the sample chaincodes in unit tests, against
+// counterfeiter fakes of the chaincode stub and transaction context from chaincodefakes. The fakes
+// are wired to an in-memory Ledger keeping world state, private data and key history, so table
+// driven tests exercise contract functions end to end, while any stub call can be overridden per
+// case to feed a contract failures or corrupt records.
+//
+// Regenerate the fakes with go generate after upgrading fabric-chaincode-go.
+package chaincodetest
+
+import (
+	"strings"
+	"testing"
+
+	"chaincodetest/chaincodefakes"
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+//go:generate counterfeiter -o chaincodefakes/chaincodestub.go -fake-name ChaincodeStub . chaincodeStub
+type chaincodeStub interface {
+	shim.ChaincodeStubInterface
+}
+
+//go:generate counterfeiter -o chaincodefakes/transaction.go -fake-name TransactionContext . transactionContext
+type transactionContext interface {
+	contractapi.TransactionContextInterface
+}
+
+//go:generate counterfeiter -o chaincodefakes/clientidentity.go -fake-name ClientIdentity . clientIdentity
+type clientIdentity interface {
+	cid.ClientIdentity
+}
+
+//go:generate counterfeiter -o chaincodefakes/statequeryiterator.go -fake-name StateQueryIterator . stateQueryIterator
+type stateQueryIterator interface {
+	shim.StateQueryIteratorInterface
+}
+
+//go:generate counterfeiter -o chaincodefakes/historyqueryiterator.go -fake-name HistoryQueryIterator . historyQueryIterator
+type historyQueryIterator interface {
+	shim.HistoryQueryIteratorInterface
+}
+
+// Case is one transaction of a table driven test
+type Case struct {
+	Name   string
+	Caller Identity
+	// Stub, if set, overrides calls of the transaction's stub before it runs
+	Stub func(stub *chaincodefakes.ChaincodeStub)
+	// Run invokes the contract function and checks its results, returning its error
+	Run func(t *testing.T, ctx contractapi.TransactionContextInterface) error
+	// Err is part of the error the transaction must fail with, or empty if it must succeed
+	Err string
+}
+
+// Run runs the cases in order as transactions of their own against the ledger, each as a subtest.
+// The writes of a failed transaction are rolled back, as the peer would not commit them.
+func (l *Ledger) Run(t *testing.T, cases []Case) {
+	t.Helper()
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			restore := l.snapshot()
+			ctx, stub := l.Tx(c.Caller)
+			if c.Stub != nil {
+				c.Stub(stub)
+			}
+
+			err := c.Run(t, ctx)
+			if err != nil {
+				restore()
+			}
+			switch {
+			case c.Err == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case c.Err != "" && err == nil:
+				t.Fatalf("expected an error containing %q", c.Err)
+			case c.Err != "" && !strings.Contains(err.Error(), c.Err):
+				t.Fatalf("expected an error containing %q, got: %v", c.Err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package chaincodetest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+
+	"chaincodetest/chaincodefakes"
+)
+
+// attributeExtension is the certificate extension the Fabric CA stores enrollment attributes in
+var attributeExtension = asn1.ObjectIdentifier{1, 2, 3, 4, 5, 6, 7, 8, 1}
+
+// Identity is a client submitting transactions. Its enrollment attributes are carried in its
+// certificate the way the Fabric CA issues them, with hf.EnrollmentID set to the common name
+// unless given.
+type Identity struct {
+	MSPID      string
+	CommonName string
+	Attributes map[string]string
+}
+
+// clientIdentity returns a fake client identity backed by a freshly issued certificate
+func (i Identity) clientIdentity() *chaincodefakes.ClientIdentity {
+	attributes := map[string]string{"hf.EnrollmentID": i.CommonName}
+	for name, value := range i.Attributes {
+		attributes[name] = value
+	}
+	cert := i.certificate(attributes)
+	id := fmt.Sprintf("x509::CN=%s,OU=client::CN=ca.%s", i.CommonName, i.MSPID)
+
+	identity := &chaincodefakes.ClientIdentity{}
+	identity.GetIDReturns(base64.StdEncoding.EncodeToString([]byte(id)), nil)
+	identity.GetMSPIDReturns(i.MSPID, nil)
+	identity.GetX509CertificateReturns(cert, nil)
+	identity.GetAttributeValueStub = func(name string) (string, bool, error) {
+		value, ok := attributes[name]
+		return value, ok, nil
+	}
+	identity.AssertAttributeValueStub = func(name string, value string) error {
+		actual, ok := attributes[name]
+		if !ok {
+			return fmt.Errorf("attribute '%s' was not found", name)
+		}
+		if actual != value {
+			return fmt.Errorf("attribute '%s' equals '%s', not '%s'", name, actual, value)
+		}
+		return nil
+	}
+
+	return identity
+}
+
+// certificate issues a self-signed certificate carrying the attributes
+func (i Identity) certificate(attributes map[string]string) *x509.Certificate {
+	attributesJSON, err := json.Marshal(map[string]map[string]string{"attrs": attributes})
+	if err != nil {
+		panic(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:    big.NewInt(1),
+		Subject:         pkix.Name{CommonName: i.CommonName, OrganizationalUnit: []string{"client"}},
+		Issuer:          pkix.Name{CommonName: "ca." + i.MSPID},
+		NotBefore:       time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:        time.Date(2040, 1, 1, 0, 0, 0, 0, time.UTC),
+		ExtraExtensions: []pkix.Extension{{Id: attributeExtension, Value: attributesJSON}},
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		panic(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		panic(err)
+	}
+
+	return cert
+}
@@ -0,0 +1,47 @@
+package chaincodetest
+
+import (
+	"fmt"
+
+	"chaincodetest/chaincodefakes"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+)
+
+// StateIterator returns a fake state query iterator yielding the given entries in order. Tests
+// return it from stub overrides, for instance StateIterator(&queryresult.KV{Key: "k", Value:
+// []byte("not json")}) to feed a contract a corrupt record.
+func StateIterator(kvs ...*queryresult.KV) *chaincodefakes.StateQueryIterator {
+	iterator := &chaincodefakes.StateQueryIterator{}
+	next := 0
+	iterator.HasNextStub = func() bool {
+		return next < len(kvs)
+	}
+	iterator.NextStub = func() (*queryresult.KV, error) {
+		if next >= len(kvs) {
+			return nil, fmt.Errorf("the iterator is exhausted")
+		}
+		next++
+		return kvs[next-1], nil
+	}
+
+	return iterator
+}
+
+// HistoryIterator returns a fake history query iterator yielding the given key modifications in
+// order, which GetHistoryForKey returns newest first
+func HistoryIterator(modifications ...*queryresult.KeyModification) *chaincodefakes.HistoryQueryIterator {
+	iterator := &chaincodefakes.HistoryQueryIterator{}
+	next := 0
+	iterator.HasNextStub = func() bool {
+		return next < len(modifications)
+	}
+	iterator.NextStub = func() (*queryresult.KeyModification, error) {
+		if next >= len(modifications) {
+			return nil, fmt.Errorf("the iterator is exhausted")
+		}
+		next++
+		return modifications[next-1], nil
+	}
+
+	return iterator
+}
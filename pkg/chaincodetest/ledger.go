@@ -0,0 +1,352 @@
+package chaincodetest
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"chaincodetest/chaincodefakes"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// compositeKeyNamespace starts every composite key, which keeps composite keys out of range queries
+const compositeKeyNamespace = "\x00"
+
+// maxUnicodeRuneValue ends the range of keys sharing a partial composite key
+const maxUnicodeRuneValue = "\U0010FFFF"
+
+// Ledger is an in-memory world state with key-level endorsement policies, private data collections
+// and key history. Each transaction gets a fake stub wired to it, so contract functions run
+// against the ledger as they would against a peer, while tests can still override any stub call
+// to inject failures.
+type Ledger struct {
+	state      map[string][]byte
+	validation map[string][]byte // key-level endorsement policies by key
+	private    map[string]map[string][]byte
+	history    map[string][]*queryresult.KeyModification
+	now        time.Time
+	txCount    int
+
+	chaincodes map[string]Chaincode
+}
+
+// Chaincode answers the invocations of another chaincode on the channel, given the function
+// name and string arguments of each
+type Chaincode func(function string, args []string) peer.Response
+
+// NewLedger returns an empty ledger whose first transaction is timestamped 2024-01-01T09:00:00Z
+func NewLedger() *Ledger {
+	return &Ledger{
+		state:      make(map[string][]byte),
+		validation: make(map[string][]byte),
+		private:    make(map[string]map[string][]byte),
+		history:    make(map[string][]*queryresult.KeyModification),
+		now:        time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),
+
+		chaincodes: make(map[string]Chaincode),
+	}
+}
+
+// Install makes InvokeChaincode calls of the named chaincode answer with the given function.
+// Invoking a chaincode that is not installed fails.
+func (l *Ledger) Install(name string, chaincode Chaincode) {
+	l.chaincodes[name] = chaincode
+}
+
+// Advance moves the clock transactions are timestamped by forward
+func (l *Ledger) Advance(d time.Duration) {
+	l.now = l.now.Add(d)
+}
+
+// Now returns the timestamp of the last transaction
+func (l *Ledger) Now() time.Time {
+	return l.now
+}
+
+// State returns the value of a key in the world state, or nil
+func (l *Ledger) State(key string) []byte {
+	return l.state[key]
+}
+
+// Tx starts a transaction submitted by the caller and returns its transaction context together
+// with the stub behind it. Transactions are numbered tx1, tx2 and so on and are a second apart.
+// Writes apply to the ledger as they are made. As on a peer, a transaction cannot both write and
+// run a paginated query: whichever comes second fails.
+func (l *Ledger) Tx(caller Identity) (*chaincodefakes.TransactionContext, *chaincodefakes.ChaincodeStub) {
+	l.txCount++
+	l.now = l.now.Add(time.Second)
+	txID := fmt.Sprintf("tx%d", l.txCount)
+
+	stub := l.stub(txID, l.now)
+	ctx := &chaincodefakes.TransactionContext{}
+	ctx.GetStubReturns(stub)
+	ctx.GetClientIdentityReturns(caller.clientIdentity())
+
+	return ctx, stub
+}
+
+// stub returns a fake stub reading and writing the ledger on behalf of one transaction
+func (l *Ledger) stub(txID string, now time.Time) *chaincodefakes.ChaincodeStub {
+	stub := &chaincodefakes.ChaincodeStub{}
+	stub.GetTxIDReturns(txID)
+	stub.GetChannelIDReturns("mychannel")
+	stub.GetTxTimestampReturns(timestamppb.New(now), nil)
+	stub.CreateCompositeKeyStub = shim.CreateCompositeKey
+	stub.SplitCompositeKeyStub = splitCompositeKey
+
+	var wrote, paginated bool
+	write := func() error {
+		if paginated {
+			return fmt.Errorf("transaction %s ran a paginated query and cannot write", txID)
+		}
+		wrote = true
+		return nil
+	}
+	paginate := func() error {
+		if wrote {
+			return fmt.Errorf("transaction %s has written and cannot run a paginated query", txID)
+		}
+		paginated = true
+		return nil
+	}
+
+	stub.GetStateStub = func(key string) ([]byte, error) {
+		return l.state[key], nil
+	}
+	stub.PutStateStub = func(key string, value []byte) error {
+		if key == "" {
+			return fmt.Errorf("key must not be an empty string")
+		}
+		if err := write(); err != nil {
+			return err
+		}
+		l.state[key] = value
+		l.record(key, txID, now, value, false)
+		return nil
+	}
+	stub.DelStateStub = func(key string) error {
+		if err := write(); err != nil {
+			return err
+		}
+		delete(l.state, key)
+		l.record(key, txID, now, nil, true)
+		return nil
+	}
+	stub.GetStateValidationParameterStub = func(key string) ([]byte, error) {
+		return l.validation[key], nil
+	}
+	stub.SetStateValidationParameterStub = func(key string, ep []byte) error {
+		if err := write(); err != nil {
+			return err
+		}
+		l.validation[key] = ep
+		return nil
+	}
+	stub.GetHistoryForKeyStub = func(key string) (shim.HistoryQueryIteratorInterface, error) {
+		modifications := l.history[key]
+		newestFirst := make([]*queryresult.KeyModification, len(modifications))
+		for i, modification := range modifications {
+			newestFirst[len(modifications)-1-i] = modification
+		}
+		return HistoryIterator(newestFirst...), nil
+	}
+
+	stub.GetStateByRangeStub = func(startKey string, endKey string) (shim.StateQueryIteratorInterface, error) {
+		return StateIterator(rangeOf(l.state, startKey, endKey, false)...), nil
+	}
+	stub.GetStateByRangeWithPaginationStub = func(startKey string, endKey string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+		if err := paginate(); err != nil {
+			return nil, nil, err
+		}
+		iterator, metadata := page(rangeOf(l.state, startKey, endKey, false), pageSize, bookmark)
+		return iterator, metadata, nil
+	}
+	stub.GetStateByPartialCompositeKeyStub = func(objectType string, keys []string) (shim.StateQueryIteratorInterface, error) {
+		prefix, err := shim.CreateCompositeKey(objectType, keys)
+		if err != nil {
+			return nil, err
+		}
+		return StateIterator(rangeOf(l.state, prefix, prefix+maxUnicodeRuneValue, true)...), nil
+	}
+	stub.GetStateByPartialCompositeKeyWithPaginationStub = func(objectType string, keys []string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+		if err := paginate(); err != nil {
+			return nil, nil, err
+		}
+		prefix, err := shim.CreateCompositeKey(objectType, keys)
+		if err != nil {
+			return nil, nil, err
+		}
+		iterator, metadata := page(rangeOf(l.state, prefix, prefix+maxUnicodeRuneValue, true), pageSize, bookmark)
+		return iterator, metadata, nil
+	}
+	stub.GetQueryResultStub = func(query string) (shim.StateQueryIteratorInterface, error) {
+		kvs, err := queryOf(l.state, query)
+		if err != nil {
+			return nil, err
+		}
+		return StateIterator(kvs...), nil
+	}
+	stub.GetQueryResultWithPaginationStub = func(query string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+		if err := paginate(); err != nil {
+			return nil, nil, err
+		}
+		kvs, err := queryOf(l.state, query)
+		if err != nil {
+			return nil, nil, err
+		}
+		iterator, metadata := page(kvs, pageSize, bookmark)
+		return iterator, metadata, nil
+	}
+
+	stub.InvokeChaincodeStub = func(name string, args [][]byte, channel string) peer.Response {
+		chaincode, ok := l.chaincodes[name]
+		if !ok || len(args) == 0 {
+			return shim.Error(fmt.Sprintf("chaincode %s is not installed", name))
+		}
+		params := make([]string, len(args)-1)
+		for i, arg := range args[1:] {
+			params[i] = string(arg)
+		}
+		return chaincode(string(args[0]), params)
+	}
+
+	stub.GetPrivateDataStub = func(collection string, key string) ([]byte, error) {
+		return l.private[collection][key], nil
+	}
+	stub.GetPrivateDataHashStub = func(collection string, key string) ([]byte, error) {
+		value, ok := l.private[collection][key]
+		if !ok {
+			return nil, nil
+		}
+		hash := sha256.Sum256(value)
+		return hash[:], nil
+	}
+	stub.PutPrivateDataStub = func(collection string, key string, value []byte) error {
+		if err := write(); err != nil {
+			return err
+		}
+		if l.private[collection] == nil {
+			l.private[collection] = make(map[string][]byte)
+		}
+		l.private[collection][key] = value
+		return nil
+	}
+	stub.DelPrivateDataStub = func(collection string, key string) error {
+		if err := write(); err != nil {
+			return err
+		}
+		delete(l.private[collection], key)
+		return nil
+	}
+	stub.PurgePrivateDataStub = stub.DelPrivateDataStub
+	stub.GetPrivateDataByRangeStub = func(collection string, startKey string, endKey string) (shim.StateQueryIteratorInterface, error) {
+		return StateIterator(rangeOf(l.private[collection], startKey, endKey, false)...), nil
+	}
+	stub.GetPrivateDataByPartialCompositeKeyStub = func(collection string, objectType string, keys []string) (shim.StateQueryIteratorInterface, error) {
+		prefix, err := shim.CreateCompositeKey(objectType, keys)
+		if err != nil {
+			return nil, err
+		}
+		return StateIterator(rangeOf(l.private[collection], prefix, prefix+maxUnicodeRuneValue, true)...), nil
+	}
+
+	return stub
+}
+
+func (l *Ledger) record(key string, txID string, now time.Time, value []byte, isDelete bool) {
+	l.history[key] = append(l.history[key], &queryresult.KeyModification{
+		TxId:      txID,
+		Value:     value,
+		Timestamp: timestamppb.New(now),
+		IsDelete:  isDelete,
+	})
+}
+
+// snapshot returns a function restoring the ledger to its current state, for rolling back a
+// failed transaction
+func (l *Ledger) snapshot() func() {
+	state := copyValues(l.state)
+	validation := copyValues(l.validation)
+	private := make(map[string]map[string][]byte, len(l.private))
+	for collection, values := range l.private {
+		private[collection] = copyValues(values)
+	}
+	history := make(map[string][]*queryresult.KeyModification, len(l.history))
+	for key, modifications := range l.history {
+		history[key] = modifications[:len(modifications):len(modifications)]
+	}
+
+	return func() {
+		l.state, l.validation, l.private, l.history = state, validation, private, history
+	}
+}
+
+func copyValues(values map[string][]byte) map[string][]byte {
+	copied := make(map[string][]byte, len(values))
+	for key, value := range values {
+		copied[key] = value
+	}
+
+	return copied
+}
+
+// rangeOf returns the entries with keys from startKey up to but excluding endKey in key order,
+// either only composite keys or only plain ones, as Fabric keeps them apart. An empty endKey
+// leaves the range open ended.
+func rangeOf(values map[string][]byte, startKey string, endKey string, composite bool) []*queryresult.KV {
+	var keys []string
+	for key := range values {
+		if strings.HasPrefix(key, compositeKeyNamespace) != composite {
+			continue
+		}
+		if key < startKey || (endKey != "" && key >= endKey) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	kvs := make([]*queryresult.KV, len(keys))
+	for i, key := range keys {
+		kvs[i] = &queryresult.KV{Key: key, Value: values[key]}
+	}
+
+	return kvs
+}
+
+// page returns the page of pageSize entries starting at the first key not before the bookmark, with
+// the key of the entry after the page as the next bookmark
+func page(kvs []*queryresult.KV, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata) {
+	start := 0
+	if bookmark != "" {
+		start = len(kvs)
+		for i, kv := range kvs {
+			if kv.Key >= bookmark {
+				start = i
+				break
+			}
+		}
+	}
+	end := len(kvs)
+	next := ""
+	if pageSize > 0 && start+int(pageSize) < len(kvs) {
+		end = start + int(pageSize)
+		next = kvs[end].Key
+	}
+
+	return StateIterator(kvs[start:end]...), &peer.QueryResponseMetadata{FetchedRecordsCount: int32(end - start), Bookmark: next}
+}
+
+func splitCompositeKey(compositeKey string) (string, []string, error) {
+	if !strings.HasPrefix(compositeKey, compositeKeyNamespace) {
+		return "", nil, fmt.Errorf("not a composite key: %q", compositeKey)
+	}
+	parts := strings.Split(strings.TrimSuffix(compositeKey[1:], "\x00"), "\x00")
+
+	return parts[0], parts[1:], nil
+}
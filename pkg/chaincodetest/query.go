@@ -0,0 +1,140 @@
+package chaincodetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+)
+
+// richQuery is the subset of CouchDB queries the ledger answers: a selector of top level fields
+// compared for equality or with $eq, $ne, $gt, $gte, $lt, $lte, $in or $exists, a sort on top
+// level fields and a limit
+type richQuery struct {
+	Selector map[string]interface{} `json:"selector"`
+	Sort     []map[string]string    `json:"sort"`
+	Limit    int                    `json:"limit"`
+}
+
+// queryOf returns the JSON records of the world state matching a rich query
+func queryOf(values map[string][]byte, query string) ([]*queryresult.KV, error) {
+	var q richQuery
+	err := json.Unmarshal([]byte(query), &q)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %v", err)
+	}
+
+	var kvs []*queryresult.KV
+	var docs []map[string]interface{}
+	for _, kv := range rangeOf(values, "", "", false) {
+		var doc map[string]interface{}
+		if json.Unmarshal(kv.Value, &doc) != nil {
+			continue
+		}
+		matched, err := matches(doc, q.Selector)
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			kvs = append(kvs, kv)
+			docs = append(docs, doc)
+		}
+	}
+
+	order := make([]int, len(kvs))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		for _, field := range q.Sort {
+			for name, direction := range field {
+				c := compare(docs[order[i]][name], docs[order[j]][name])
+				if c == 0 {
+					continue
+				}
+				if direction == "desc" {
+					return c > 0
+				}
+				return c < 0
+			}
+		}
+		return false
+	})
+	sorted := make([]*queryresult.KV, len(kvs))
+	for i, index := range order {
+		sorted[i] = kvs[index]
+	}
+	if q.Limit > 0 && len(sorted) > q.Limit {
+		sorted = sorted[:q.Limit]
+	}
+
+	return sorted, nil
+}
+
+func matches(doc map[string]interface{}, selector map[string]interface{}) (bool, error) {
+	for name, condition := range selector {
+		value, present := doc[name]
+		operators, ok := condition.(map[string]interface{})
+		if !ok {
+			operators = map[string]interface{}{"$eq": condition}
+		}
+		for operator, operand := range operators {
+			var matched bool
+			switch operator {
+			case "$exists":
+				matched = present == (operand == true)
+			case "$eq":
+				matched = present && compare(value, operand) == 0
+			case "$ne":
+				matched = !present || compare(value, operand) != 0
+			case "$gt":
+				matched = present && compare(value, operand) > 0
+			case "$gte":
+				matched = present && compare(value, operand) >= 0
+			case "$lt":
+				matched = present && compare(value, operand) < 0
+			case "$lte":
+				matched = present && compare(value, operand) <= 0
+			case "$in":
+				candidates, _ := operand.([]interface{})
+				for _, candidate := range candidates {
+					if present && compare(value, candidate) == 0 {
+						matched = true
+					}
+				}
+			default:
+				return false, fmt.Errorf("unsupported query operator %s", operator)
+			}
+			if !matched {
+				return false, nil
+			}
+		}
+	}
+
+	return true, nil
+}
+
+// compare orders numbers numerically and everything else by its text
+func compare(a interface{}, b interface{}) int {
+	x, xNumber := a.(float64)
+	y, yNumber := b.(float64)
+	if xNumber && yNumber {
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+	s, t := fmt.Sprint(a), fmt.Sprint(b)
+	switch {
+	case s < t:
+		return -1
+	case s > t:
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,239 @@
+// Package fabricclient holds client-side helpers shared by the Go applications that submit
+// transactions to the sample chaincodes through the Fabric Gateway.
+package fabricclient
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Submitter submits a transaction and waits for it to commit. *client.Contract from
+// github.com/hyperledger/fabric-gateway/pkg/client satisfies it.
+type Submitter interface {
+	SubmitTransaction(name string, args ...string) ([]byte, error)
+}
+
+// Submission is one transaction queued on a Batcher. Key names the ledger key the transaction
+// writes: submissions with the same key run one after another in the order they were added,
+// while submissions with different keys run in parallel. An empty key never conflicts.
+type Submission struct {
+	Key  string
+	Name string
+	Args []string
+}
+
+// Result is the outcome of one submission
+type Result struct {
+	Submission Submission
+	Payload    []byte
+	Err        error
+}
+
+// Summary aggregates the results of every submission added to a Batcher, in the order they were added
+type Summary struct {
+	Results   []Result
+	Succeeded int
+	Failed    int
+}
+
+// ErrBatcherClosed is returned by Add once Close has been called
+var ErrBatcherClosed = errors.New("fabricclient: batcher is closed")
+
+// Option configures a Batcher
+type Option func(*Batcher)
+
+// WithConcurrency bounds how many transactions are in flight at once. The default is 4.
+func WithConcurrency(n int) Option {
+	return func(b *Batcher) {
+		if n > 0 {
+			b.concurrency = n
+		}
+	}
+}
+
+// WithWindow sets how long submissions are collected before a batch is sent. The default is 100ms.
+func WithWindow(d time.Duration) Option {
+	return func(b *Batcher) {
+		if d > 0 {
+			b.window = d
+		}
+	}
+}
+
+// WithMaxBatch sends a batch as soon as it holds n submissions, without waiting for the window
+// to end. The default is 100.
+func WithMaxBatch(n int) Option {
+	return func(b *Batcher) {
+		if n > 0 {
+			b.maxBatch = n
+		}
+	}
+}
+
+// Batcher coalesces independent submissions into parallel gateway submissions. Submissions are
+// collected for a time window and sent as one batch with bounded concurrency. Batches run one at
+// a time, so a key written in one batch is committed before the next batch touches it.
+type Batcher struct {
+	contract    Submitter
+	concurrency int
+	window      time.Duration
+	maxBatch    int
+
+	mu      sync.Mutex
+	pending []int
+	timer   *time.Timer
+	closed  bool
+
+	// resultsMu guards results apart from mu, since Add holds mu while it waits for the running
+	// batch, which records its results meanwhile
+	resultsMu sync.Mutex
+	results   []Result
+
+	batches chan []int
+	done    chan struct{}
+}
+
+// NewBatcher returns a Batcher submitting to the given contract
+func NewBatcher(contract Submitter, options ...Option) *Batcher {
+	b := &Batcher{
+		contract:    contract,
+		concurrency: 4,
+		window:      100 * time.Millisecond,
+		maxBatch:    100,
+		batches:     make(chan []int),
+		done:        make(chan struct{}),
+	}
+	for _, option := range options {
+		option(b)
+	}
+
+	go b.run()
+	return b
+}
+
+// Add queues a submission. It blocks while a full batch is waiting for the previous one to finish.
+func (b *Batcher) Add(submission Submission) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return ErrBatcherClosed
+	}
+
+	b.resultsMu.Lock()
+	b.results = append(b.results, Result{Submission: submission})
+	index := len(b.results) - 1
+	b.resultsMu.Unlock()
+
+	b.pending = append(b.pending, index)
+	if len(b.pending) >= b.maxBatch {
+		b.flushLocked()
+	} else if len(b.pending) == 1 {
+		b.timer = time.AfterFunc(b.window, b.flush)
+	}
+
+	return nil
+}
+
+// Close sends the remaining submissions, waits for every batch to finish and returns the results
+func (b *Batcher) Close() *Summary {
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		b.flushLocked()
+		close(b.batches)
+	}
+	b.mu.Unlock()
+
+	<-b.done
+
+	summary := &Summary{Results: b.results}
+	for _, result := range b.results {
+		if result.Err != nil {
+			summary.Failed++
+		} else {
+			summary.Succeeded++
+		}
+	}
+
+	return summary
+}
+
+func (b *Batcher) flush() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.closed {
+		b.flushLocked()
+	}
+}
+
+func (b *Batcher) flushLocked() {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	if len(b.pending) == 0 {
+		return
+	}
+
+	batch := b.pending
+	b.pending = nil
+	b.batches <- batch
+}
+
+func (b *Batcher) run() {
+	defer close(b.done)
+
+	for batch := range b.batches {
+		b.submitBatch(batch)
+	}
+}
+
+// submitBatch groups a batch by key and submits the groups in parallel, each group in order
+func (b *Batcher) submitBatch(batch []int) {
+	b.resultsMu.Lock()
+	var groups [][]int
+	byKey := make(map[string]int)
+	for _, index := range batch {
+		key := b.results[index].Submission.Key
+		if key == "" {
+			groups = append(groups, []int{index})
+			continue
+		}
+		group, ok := byKey[key]
+		if !ok {
+			group = len(groups)
+			byKey[key] = group
+			groups = append(groups, nil)
+		}
+		groups[group] = append(groups[group], index)
+	}
+	b.resultsMu.Unlock()
+
+	slots := make(chan struct{}, b.concurrency)
+	var wg sync.WaitGroup
+	for _, group := range groups {
+		wg.Add(1)
+		go func(group []int) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			for _, index := range group {
+				b.resultsMu.Lock()
+				submission := b.results[index].Submission
+				b.resultsMu.Unlock()
+
+				payload, err := b.contract.SubmitTransaction(submission.Name, submission.Args...)
+
+				b.resultsMu.Lock()
+				b.results[index].Payload = payload
+				b.results[index].Err = err
+				b.resultsMu.Unlock()
+			}
+		}(group)
+	}
+	wg.Wait()
+}
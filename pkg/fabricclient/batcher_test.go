@@ -0,0 +1,160 @@
+package fabricclient
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeContract records the transactions submitted to it and fails those named in fail
+type fakeContract struct {
+	mu        sync.Mutex
+	calls     []string
+	fail      map[string]error
+	submitted chan string // receives every call when set
+}
+
+func (c *fakeContract) SubmitTransaction(name string, args ...string) ([]byte, error) {
+	call := name + "(" + strings.Join(args, ",") + ")"
+	c.mu.Lock()
+	c.calls = append(c.calls, call)
+	c.mu.Unlock()
+	if c.submitted != nil {
+		c.submitted <- call
+	}
+
+	if err := c.fail[call]; err != nil {
+		return nil, err
+	}
+	return []byte(call), nil
+}
+
+// waitFor returns the next n calls submitted to the contract, failing the test after a second
+func waitFor(t *testing.T, contract *fakeContract, n int) []string {
+	t.Helper()
+	var calls []string
+	for len(calls) < n {
+		select {
+		case call := <-contract.submitted:
+			calls = append(calls, call)
+		case <-time.After(time.Second):
+			t.Fatalf("%d of %d submissions were sent", len(calls), n)
+		}
+	}
+	return calls
+}
+
+func TestBatcherFlushesOnSize(t *testing.T) {
+	contract := &fakeContract{submitted: make(chan string, 100)}
+	batcher := NewBatcher(contract, WithWindow(time.Hour), WithMaxBatch(3))
+
+	for i := 0; i < 2; i++ {
+		if err := batcher.Add(Submission{Name: "Mint", Args: []string{fmt.Sprint(i)}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	select {
+	case call := <-contract.submitted:
+		t.Fatalf("%s was sent before the batch was full", call)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// the third fills the batch; the rest fill more batches while the earlier ones run
+	for i := 2; i < 10; i++ {
+		if err := batcher.Add(Submission{Name: "Mint", Args: []string{fmt.Sprint(i)}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitFor(t, contract, 9)
+
+	summary := batcher.Close()
+	waitFor(t, contract, 1)
+	if summary.Succeeded != 10 || summary.Failed != 0 {
+		t.Errorf("summary %d succeeded, %d failed, want 10 and 0", summary.Succeeded, summary.Failed)
+	}
+}
+
+func TestBatcherFlushesOnWindow(t *testing.T) {
+	contract := &fakeContract{submitted: make(chan string, 10)}
+	batcher := NewBatcher(contract, WithWindow(20*time.Millisecond))
+
+	start := time.Now()
+	if err := batcher.Add(Submission{Name: "Mint", Args: []string{"1"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := batcher.Add(Submission{Name: "Mint", Args: []string{"2"}}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, contract, 2)
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("the batch was sent after %s, before the window ended", elapsed)
+	}
+
+	// a later submission opens a new window
+	if err := batcher.Add(Submission{Name: "Mint", Args: []string{"3"}}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, contract, 1)
+	batcher.Close()
+}
+
+func TestBatcherResults(t *testing.T) {
+	errEndorsement := errors.New("endorsement failure")
+	contract := &fakeContract{fail: map[string]error{"Transfer(a,bob)": errEndorsement}}
+	batcher := NewBatcher(contract, WithConcurrency(2))
+
+	submissions := []Submission{
+		{Key: "a", Name: "Transfer", Args: []string{"a", "ash"}},
+		{Key: "b", Name: "Transfer", Args: []string{"b", "misty"}},
+		{Key: "a", Name: "Transfer", Args: []string{"a", "bob"}},
+		{Name: "Mint", Args: []string{"c"}},
+		{Key: "a", Name: "Transfer", Args: []string{"a", "brock"}},
+	}
+	for _, submission := range submissions {
+		if err := batcher.Add(submission); err != nil {
+			t.Fatal(err)
+		}
+	}
+	summary := batcher.Close()
+
+	if err := batcher.Add(Submission{Name: "Mint"}); err != ErrBatcherClosed {
+		t.Errorf("Add() after Close() error = %v, want %v", err, ErrBatcherClosed)
+	}
+	if again := batcher.Close(); again.Succeeded != summary.Succeeded {
+		t.Errorf("a second Close() returned %d successes, want %d", again.Succeeded, summary.Succeeded)
+	}
+
+	if summary.Succeeded != 4 || summary.Failed != 1 {
+		t.Errorf("summary %d succeeded, %d failed, want 4 and 1", summary.Succeeded, summary.Failed)
+	}
+	for i, result := range summary.Results {
+		if !reflect.DeepEqual(result.Submission, submissions[i]) {
+			t.Errorf("result %d is of %+v, want %+v", i, result.Submission, submissions[i])
+		}
+		call := result.Submission.Name + "(" + strings.Join(result.Submission.Args, ",") + ")"
+		switch {
+		case call == "Transfer(a,bob)":
+			if result.Err != errEndorsement || result.Payload != nil {
+				t.Errorf("result of %s = %q, %v, want the submit error", call, result.Payload, result.Err)
+			}
+		case result.Err != nil || string(result.Payload) != call:
+			t.Errorf("result of %s = %q, %v, want its payload", call, result.Payload, result.Err)
+		}
+	}
+
+	// submissions sharing a key run in the order they were added, a failure does not stop the rest
+	var transfersOfA []string
+	for _, call := range contract.calls {
+		if strings.HasPrefix(call, "Transfer(a,") {
+			transfersOfA = append(transfersOfA, call)
+		}
+	}
+	want := []string{"Transfer(a,ash)", "Transfer(a,bob)", "Transfer(a,brock)"}
+	if !reflect.DeepEqual(transfersOfA, want) {
+		t.Errorf("transfers of a ran as %v, want %v", transfersOfA, want)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"go/format"
+	"strings"
+)
+
+// goHelpers are emitted once into every Go binding
+const goHelpers = `
+// Contract submits and evaluates transactions. *client.Contract from
+// github.com/hyperledger/fabric-gateway/pkg/client satisfies it.
+type Contract interface {
+	SubmitTransaction(name string, args ...string) ([]byte, error)
+	EvaluateTransaction(name string, args ...string) ([]byte, error)
+}
+
+// toArgs encodes transaction arguments the way contractapi parses them: strings as they are,
+// everything else as JSON
+func toArgs(values ...interface{}) ([]string, error) {
+	args := make([]string, len(values))
+	for i, value := range values {
+		if text, ok := value.(string); ok {
+			args[i] = text
+			continue
+		}
+		valueJSON, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode argument %d: %w", i, err)
+		}
+		args[i] = string(valueJSON)
+	}
+
+	return args, nil
+}
+`
+
+// generateGo emits Go bindings: a struct per component schema and a client type per contract
+// with a method per transaction
+func generateGo(metadata *Metadata, pkg string, source string) ([]byte, error) {
+	var out strings.Builder
+	fmt.Fprintf(&out, "// Code generated by ccbindgen from %s. DO NOT EDIT.\n\n", source)
+	fmt.Fprintf(&out, "package %s\n\n", pkg)
+	out.WriteString("import (\n\t\"encoding/json\"\n\t\"fmt\"\n)\n")
+	out.WriteString(goHelpers)
+
+	for _, name := range metadata.schemaNames() {
+		schema := metadata.Components.Schemas[name]
+		fmt.Fprintf(&out, "\ntype %s struct {\n", name)
+		for _, property := range propertyNames(schema) {
+			tag := property
+			if !isRequired(schema, property) {
+				tag += ",omitempty"
+			}
+			fmt.Fprintf(&out, "\t%s %s `json:\"%s\"`\n", exportedName(property), goType(schema.Properties[property]), tag)
+		}
+		out.WriteString("}\n")
+	}
+
+	for _, contract := range metadata.userContracts() {
+		client := contract.Name + "Client"
+		fmt.Fprintf(&out, "\n// %s invokes the transactions of the %s contract\n", client, contract.Name)
+		fmt.Fprintf(&out, "type %s struct {\n\tcontract Contract\n}\n", client)
+		fmt.Fprintf(&out, "\n// New%s returns a client of the %s contract\n", client, contract.Name)
+		fmt.Fprintf(&out, "func New%s(contract Contract) *%s {\n\treturn &%s{contract: contract}\n}\n", client, client, client)
+		for _, transaction := range contract.Transactions {
+			writeGoMethod(&out, client, contract, transaction)
+		}
+	}
+
+	return format.Source([]byte(out.String()))
+}
+
+func writeGoMethod(out *strings.Builder, client string, contract *ContractMetadata, transaction *TransactionMetadata) {
+	var params, names []string
+	for _, param := range transaction.Parameters {
+		params = append(params, param.Name+" "+goType(param.Schema))
+		names = append(names, param.Name)
+	}
+	call := "SubmitTransaction"
+	if transaction.Evaluate() {
+		call = "EvaluateTransaction"
+	}
+
+	returns, zero := "error", ""
+	if transaction.Returns != nil {
+		returnType := goType(transaction.Returns)
+		returns, zero = "("+returnType+", error)", goZero(returnType)+", "
+	}
+
+	fmt.Fprintf(out, "\nfunc (c *%s) %s(%s) %s {\n", client, transaction.Name, strings.Join(params, ", "), returns)
+	fmt.Fprintf(out, "\targs, err := toArgs(%s)\n", strings.Join(names, ", "))
+	fmt.Fprintf(out, "\tif err != nil {\n\t\treturn %serr\n\t}\n", zero)
+	if transaction.Returns == nil {
+		fmt.Fprintf(out, "\t_, err = c.contract.%s(%q, args...)\n\treturn err\n}\n", call, qualifiedName(contract, transaction))
+		return
+	}
+	fmt.Fprintf(out, "\tpayload, err := c.contract.%s(%q, args...)\n", call, qualifiedName(contract, transaction))
+	fmt.Fprintf(out, "\tif err != nil {\n\t\treturn %serr\n\t}\n", zero)
+
+	returnType := goType(transaction.Returns)
+	if returnType == "string" {
+		out.WriteString("\treturn string(payload), nil\n}\n")
+		return
+	}
+	if strings.HasPrefix(returnType, "*") {
+		fmt.Fprintf(out, "\tvar result %s\n", strings.TrimPrefix(returnType, "*"))
+		out.WriteString("\tif err := json.Unmarshal(payload, &result); err != nil {\n\t\treturn nil, err\n\t}\n\treturn &result, nil\n}\n")
+		return
+	}
+	fmt.Fprintf(out, "\tvar result %s\n", returnType)
+	fmt.Fprintf(out, "\tif err := json.Unmarshal(payload, &result); err != nil {\n\t\treturn %serr\n\t}\n\treturn result, nil\n}\n", zero)
+}
+
+// goType maps a schema to the Go type contractapi generated it from
+func goType(schema *Schema) string {
+	if schema.Ref != "" {
+		return "*" + schema.RefName()
+	}
+	switch schema.Type {
+	case "string":
+		return "string"
+	case "boolean":
+		return "bool"
+	case "number":
+		if schema.Format == "float" {
+			return "float32"
+		}
+		return "float64"
+	case "integer":
+		if schema.Format == "int32" {
+			return "int32"
+		}
+		return "int"
+	case "array":
+		if schema.Items == nil {
+			return "[]interface{}"
+		}
+		return "[]" + goType(schema.Items)
+	case "object":
+		if values := schema.MapValues(); values != nil {
+			return "map[string]" + goType(values)
+		}
+	}
+
+	return "interface{}"
+}
+
+func goZero(goType string) string {
+	switch {
+	case goType == "string":
+		return `""`
+	case goType == "bool":
+		return "false"
+	case strings.HasPrefix(goType, "int") || strings.HasPrefix(goType, "float"):
+		return "0"
+	}
+
+	return "nil"
+}
@@ -0,0 +1,82 @@
+// Command ccbindgen generates typed Go or TypeScript client bindings for a chaincode from its
+// contractapi metadata, so that applications call contract functions with checked argument and
+// result types instead of string arguments and raw JSON. Fetch the metadata from a running
+// chaincode with
+//
+//	peer chaincode query -C mychannel -n bankcontract -c '{"Args":["org.hyperledger.fabric:GetMetadata"]}' > bank.json
+//
+// and generate the bindings from pkg/fabricclient with
+//
+//	go run ./cmd/ccbindgen -metadata bank.json -lang go -package bank -o bank/client.go
+//	go run ./cmd/ccbindgen -metadata bank.json -lang ts -o bank.ts
+//
+// Regenerating after every chaincode change keeps the callers in step with the contract
+// signatures. contractapi names parameters param0, param1 and so on, and so do the bindings.
+// Transactions are submitted unless the contract tags them for evaluation.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	metadataPath := flag.String("metadata", "-", "contractapi metadata JSON file, - for standard input")
+	lang := flag.String("lang", "go", "language of the bindings, go or ts")
+	pkg := flag.String("package", "chaincode", "package name of Go bindings")
+	output := flag.String("o", "-", "output file, - for standard output")
+	flag.Parse()
+
+	if err := run(*metadataPath, *lang, *pkg, *output); err != nil {
+		fmt.Fprintf(os.Stderr, "ccbindgen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(metadataPath string, lang string, pkg string, output string) error {
+	var metadataJSON []byte
+	var err error
+	source := "standard input"
+	if metadataPath == "-" {
+		metadataJSON, err = io.ReadAll(os.Stdin)
+	} else {
+		metadataJSON, err = os.ReadFile(metadataPath)
+		source = filepath.Base(metadataPath)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read metadata: %w", err)
+	}
+
+	var metadata Metadata
+	err = json.Unmarshal(metadataJSON, &metadata)
+	if err != nil {
+		return fmt.Errorf("invalid metadata: %w", err)
+	}
+	if len(metadata.userContracts()) == 0 {
+		return fmt.Errorf("the metadata describes no contracts")
+	}
+
+	var bindings []byte
+	switch lang {
+	case "go":
+		bindings, err = generateGo(&metadata, pkg, source)
+		if err != nil {
+			return fmt.Errorf("failed to format Go bindings: %w", err)
+		}
+	case "ts":
+		bindings = generateTypeScript(&metadata, source)
+	default:
+		return fmt.Errorf("unknown language %q, expected go or ts", lang)
+	}
+
+	if output == "-" {
+		_, err = os.Stdout.Write(bindings)
+		return err
+	}
+
+	return os.WriteFile(output, bindings, 0644)
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// systemContract is the contract contractapi adds to every chaincode to serve GetMetadata
+const systemContract = "org.hyperledger.fabric"
+
+// Metadata is the part of the contractapi metadata the bindings are generated from, as returned by
+// evaluating org.hyperledger.fabric:GetMetadata
+type Metadata struct {
+	Contracts  map[string]*ContractMetadata `json:"contracts"`
+	Components struct {
+		Schemas map[string]*Schema `json:"schemas"`
+	} `json:"components"`
+}
+
+// ContractMetadata describes one contract of a chaincode
+type ContractMetadata struct {
+	Name         string                 `json:"name"`
+	Default      bool                   `json:"default"`
+	Transactions []*TransactionMetadata `json:"transactions"`
+}
+
+// TransactionMetadata describes one contract function
+type TransactionMetadata struct {
+	Name       string               `json:"name"`
+	Tag        []string             `json:"tag"`
+	Parameters []*ParameterMetadata `json:"parameters"`
+	Returns    *Schema              `json:"returns"`
+}
+
+// ParameterMetadata describes one parameter of a contract function
+type ParameterMetadata struct {
+	Name   string  `json:"name"`
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is the subset of JSON schema contractapi emits for parameters, returns and structs
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Items                *Schema            `json:"items"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties json.RawMessage    `json:"additionalProperties"`
+}
+
+// RefName returns the name of the component schema the schema refers to, or ""
+func (s *Schema) RefName() string {
+	return strings.TrimPrefix(s.Ref, "#/components/schemas/")
+}
+
+// MapValues returns the schema of the values of a map type, or nil if the schema is no map
+func (s *Schema) MapValues() *Schema {
+	raw := bytes.TrimSpace(s.AdditionalProperties)
+	if len(raw) == 0 || raw[0] != '{' {
+		return nil
+	}
+	var values Schema
+	if json.Unmarshal(raw, &values) != nil {
+		return nil
+	}
+
+	return &values
+}
+
+// Evaluate reports whether the transaction is tagged to be evaluated rather than submitted
+func (t *TransactionMetadata) Evaluate() bool {
+	for _, tag := range t.Tag {
+		if strings.EqualFold(tag, "evaluate") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// userContracts returns the contracts of the chaincode other than the system contract, by name
+func (m *Metadata) userContracts() []*ContractMetadata {
+	var contracts []*ContractMetadata
+	for name, contract := range m.Contracts {
+		if name == systemContract {
+			continue
+		}
+		contracts = append(contracts, contract)
+	}
+	sort.Slice(contracts, func(i, j int) bool { return contracts[i].Name < contracts[j].Name })
+
+	return contracts
+}
+
+// schemaNames returns the names of the component schemas in order
+func (m *Metadata) schemaNames() []string {
+	var names []string
+	for name := range m.Components.Schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// qualifiedName is the name a transaction is invoked by: bare for the default contract, otherwise
+// prefixed with the contract name
+func qualifiedName(contract *ContractMetadata, transaction *TransactionMetadata) string {
+	if contract.Default {
+		return transaction.Name
+	}
+
+	return contract.Name + ":" + transaction.Name
+}
+
+// propertyNames returns the property names of a schema in order
+func propertyNames(schema *Schema) []string {
+	var names []string
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func isRequired(schema *Schema, property string) bool {
+	for _, name := range schema.Required {
+		if name == property {
+			return true
+		}
+	}
+
+	return false
+}
+
+// initialisms are the words Go field names spell in capitals
+var initialisms = map[string]bool{"api": true, "id": true, "json": true, "msp": true, "url": true}
+
+// exportedName turns a camel case JSON property name into an exported Go identifier, so that
+// txId becomes TxID
+func exportedName(name string) string {
+	var words []string
+	var word []rune
+	var previous rune
+	for _, r := range name {
+		if !isIdentRune(r) || unicode.IsUpper(r) && !unicode.IsUpper(previous) {
+			if len(word) > 0 {
+				words = append(words, string(word))
+			}
+			word = nil
+		}
+		if isIdentRune(r) {
+			word = append(word, r)
+		}
+		previous = r
+	}
+	if len(word) > 0 {
+		words = append(words, string(word))
+	}
+
+	var exported strings.Builder
+	for _, word := range words {
+		if initialisms[strings.ToLower(word)] {
+			exported.WriteString(strings.ToUpper(word))
+			continue
+		}
+		exported.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+
+	return exported.String()
+}
+
+// lowerFirst lower cases the first letter of a function name for TypeScript
+func lowerFirst(name string) string {
+	if name == "" {
+		return name
+	}
+
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
+func isIdentRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
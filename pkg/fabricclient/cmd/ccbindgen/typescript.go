@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// tsHelpers are emitted once into every TypeScript binding
+const tsHelpers = `import { Contract } from '@hyperledger/fabric-gateway';
+
+const utf8Decoder = new TextDecoder();
+
+// toArg encodes a transaction argument the way contractapi parses it: strings as they are,
+// everything else as JSON
+function toArg(value: unknown): string {
+    return typeof value === 'string' ? value : JSON.stringify(value);
+}
+`
+
+// generateTypeScript emits TypeScript bindings for the Fabric Gateway client: an interface per
+// component schema and a class per contract with a method per transaction
+func generateTypeScript(metadata *Metadata, source string) []byte {
+	var out strings.Builder
+	fmt.Fprintf(&out, "// Code generated by ccbindgen from %s. DO NOT EDIT.\n\n", source)
+	out.WriteString(tsHelpers)
+
+	for _, name := range metadata.schemaNames() {
+		schema := metadata.Components.Schemas[name]
+		fmt.Fprintf(&out, "\nexport interface %s {\n", name)
+		for _, property := range propertyNames(schema) {
+			optional := ""
+			if !isRequired(schema, property) {
+				optional = "?"
+			}
+			fmt.Fprintf(&out, "    %s%s: %s;\n", property, optional, tsType(schema.Properties[property]))
+		}
+		out.WriteString("}\n")
+	}
+
+	for _, contract := range metadata.userContracts() {
+		fmt.Fprintf(&out, "\n/** Invokes the transactions of the %s contract */\n", contract.Name)
+		fmt.Fprintf(&out, "export class %sClient {\n", contract.Name)
+		out.WriteString("    constructor(private readonly contract: Contract) {}\n")
+		for _, transaction := range contract.Transactions {
+			writeTSMethod(&out, contract, transaction)
+		}
+		out.WriteString("}\n")
+	}
+
+	return []byte(out.String())
+}
+
+func writeTSMethod(out *strings.Builder, contract *ContractMetadata, transaction *TransactionMetadata) {
+	var params, args []string
+	for _, param := range transaction.Parameters {
+		params = append(params, param.Name+": "+tsType(param.Schema))
+		args = append(args, ", toArg("+param.Name+")")
+	}
+	call := "submitTransaction"
+	if transaction.Evaluate() {
+		call = "evaluateTransaction"
+	}
+	invoke := fmt.Sprintf("this.contract.%s('%s'%s)", call, qualifiedName(contract, transaction), strings.Join(args, ""))
+
+	returnType := "void"
+	if transaction.Returns != nil {
+		returnType = tsType(transaction.Returns)
+	}
+	fmt.Fprintf(out, "\n    async %s(%s): Promise<%s> {\n", lowerFirst(transaction.Name), strings.Join(params, ", "), returnType)
+	switch returnType {
+	case "void":
+		fmt.Fprintf(out, "        await %s;\n", invoke)
+	case "string":
+		fmt.Fprintf(out, "        return utf8Decoder.decode(await %s);\n", invoke)
+	default:
+		fmt.Fprintf(out, "        return JSON.parse(utf8Decoder.decode(await %s)) as %s;\n", invoke, returnType)
+	}
+	out.WriteString("    }\n")
+}
+
+// tsType maps a schema to the TypeScript type of its JSON
+func tsType(schema *Schema) string {
+	if schema.Ref != "" {
+		return schema.RefName()
+	}
+	switch schema.Type {
+	case "string":
+		return "string"
+	case "boolean":
+		return "boolean"
+	case "number", "integer":
+		return "number"
+	case "array":
+		if schema.Items == nil {
+			return "unknown[]"
+		}
+		return tsType(schema.Items) + "[]"
+	case "object":
+		if values := schema.MapValues(); values != nil {
+			return "Record<string, " + tsType(values) + ">"
+		}
+	}
+
+	return "unknown"
+}
@@ -0,0 +1,3 @@
+module fabricclient
+
+go 1.22.2
@@ -0,0 +1,98 @@
+package fabricclient
+
+import "sync"
+
+// Evaluator evaluates a transaction on a peer of one of the given organizations, or on a peer
+// chosen by the gateway when no organizations are given. A *client.Contract from
+// github.com/hyperledger/fabric-gateway/pkg/client is adapted with EvaluatorFunc:
+//
+//	fabricclient.EvaluatorFunc(func(name string, organizations []string, args ...string) ([]byte, error) {
+//		return contract.Evaluate(name, client.WithArguments(args...), client.WithEndorsingOrganizations(organizations...))
+//	})
+type Evaluator interface {
+	EvaluateOn(name string, organizations []string, args ...string) ([]byte, error)
+}
+
+// EvaluatorFunc adapts a function to the Evaluator interface
+type EvaluatorFunc func(name string, organizations []string, args ...string) ([]byte, error)
+
+// EvaluateOn calls f
+func (f EvaluatorFunc) EvaluateOn(name string, organizations []string, args ...string) ([]byte, error) {
+	return f(name, organizations, args...)
+}
+
+// RouterOption configures a QueryRouter
+type RouterOption func(*QueryRouter)
+
+// WithHint pins evaluate calls of the named transaction function to the peers of the given
+// organizations, typically an organization running only analytics peers.
+func WithHint(function string, organizations ...string) RouterOption {
+	return func(r *QueryRouter) {
+		if len(organizations) > 0 {
+			r.hints[function] = organizations
+		}
+	}
+}
+
+// WithFallback evaluates a pinned call again on a peer chosen by the gateway when the pinned
+// peers fail it, trading the isolation of the analytics peers for availability.
+func WithFallback() RouterOption {
+	return func(r *QueryRouter) {
+		r.fallback = true
+	}
+}
+
+// QueryRouter sends evaluate calls of heavy functions, such as exports and statistics, to
+// designated peers so that reporting load stays off the peers endorsing transactions. Functions
+// without a hint are evaluated wherever the gateway chooses.
+type QueryRouter struct {
+	evaluator Evaluator
+	hints     map[string][]string
+	fallback  bool
+
+	mu        sync.Mutex
+	fallbacks int
+}
+
+// NewQueryRouter returns a QueryRouter evaluating through the given evaluator
+func NewQueryRouter(evaluator Evaluator, options ...RouterOption) *QueryRouter {
+	r := &QueryRouter{
+		evaluator: evaluator,
+		hints:     make(map[string][]string),
+	}
+	for _, option := range options {
+		option(r)
+	}
+
+	return r
+}
+
+// EvaluateTransaction evaluates a transaction on the peers hinted for its function. It has the
+// signature of (*client.Contract).EvaluateTransaction, so callers can switch to the router
+// without other changes.
+func (r *QueryRouter) EvaluateTransaction(name string, args ...string) ([]byte, error) {
+	organizations := r.Hint(name)
+	payload, err := r.evaluator.EvaluateOn(name, organizations, args...)
+	if err == nil || organizations == nil || !r.fallback {
+		return payload, err
+	}
+
+	r.mu.Lock()
+	r.fallbacks++
+	r.mu.Unlock()
+
+	return r.evaluator.EvaluateOn(name, nil, args...)
+}
+
+// Hint returns the organizations evaluate calls of the named function are pinned to, or nil
+func (r *QueryRouter) Hint(function string) []string {
+	return r.hints[function]
+}
+
+// Fallbacks returns how many pinned calls have been evaluated again on a gateway-chosen peer
+func (r *QueryRouter) Fallbacks() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.fallbacks
+}
@@ -0,0 +1,3 @@
+module querybuilder
+
+go 1.22.2
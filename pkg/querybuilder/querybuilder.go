@@ -0,0 +1,131 @@
+// Package querybuilder builds CouchDB rich queries, as taken by GetQueryResult and
+// GetQueryResultWithPagination, so that client apps and chaincode need not write selector JSON by
+// hand. The JSON encoding sorts the fields of the selector, so a query built the same way always
+// renders the same string, as every endorsing peer must.
+//
+//	query, err := querybuilder.New().
+//		Eq("status", "Approved").
+//		Gt("amount", 1000).
+//		Sort("amount", querybuilder.Desc).
+//		UseIndex("indexAmountDoc", "indexAmount").
+//		Build()
+package querybuilder
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Direction is the order a field is sorted in
+type Direction string
+
+const (
+	Asc  Direction = "asc"
+	Desc Direction = "desc"
+)
+
+// Query is a rich query under construction. Conditions on different fields must all hold; the
+// conditions on one field are combined the same way.
+type Query struct {
+	selector map[string]map[string]interface{}
+	sort     []map[string]Direction
+	limit    int
+	useIndex []string
+}
+
+// New returns a query matching every document
+func New() *Query {
+	return &Query{selector: make(map[string]map[string]interface{})}
+}
+
+func (q *Query) where(field string, operator string, value interface{}) *Query {
+	operators, ok := q.selector[field]
+	if !ok {
+		operators = make(map[string]interface{})
+		q.selector[field] = operators
+	}
+	operators[operator] = value
+	return q
+}
+
+// Eq matches documents whose field equals value
+func (q *Query) Eq(field string, value interface{}) *Query {
+	return q.where(field, "$eq", value)
+}
+
+// Gt matches documents whose field is greater than value
+func (q *Query) Gt(field string, value interface{}) *Query {
+	return q.where(field, "$gt", value)
+}
+
+// Lt matches documents whose field is less than value
+func (q *Query) Lt(field string, value interface{}) *Query {
+	return q.where(field, "$lt", value)
+}
+
+// In matches documents whose field equals one of values
+func (q *Query) In(field string, values ...interface{}) *Query {
+	if values == nil {
+		values = []interface{}{}
+	}
+	return q.where(field, "$in", values)
+}
+
+// Exists matches documents that have the field if exists is true, and those that lack it otherwise
+func (q *Query) Exists(field string, exists bool) *Query {
+	return q.where(field, "$exists", exists)
+}
+
+// Sort orders the results by field, after the fields of earlier calls. CouchDB sorts only on
+// indexed fields, all in the same direction.
+func (q *Query) Sort(field string, direction Direction) *Query {
+	q.sort = append(q.sort, map[string]Direction{field: direction})
+	return q
+}
+
+// Limit caps the number of results. Queries with pagination take the page size instead.
+func (q *Query) Limit(limit int) *Query {
+	q.limit = limit
+	return q
+}
+
+// UseIndex directs CouchDB to an index, named by the ddoc and name of its definition under
+// META-INF/statedb/couchdb/indexes. An empty name uses any index of the design document.
+func (q *Query) UseIndex(designDoc string, name string) *Query {
+	q.useIndex = []string{"_design/" + designDoc}
+	if name != "" {
+		q.useIndex = append(q.useIndex, name)
+	}
+	return q
+}
+
+// MarshalJSON renders the query as CouchDB expects it
+func (q *Query) MarshalJSON() ([]byte, error) {
+	for _, sort := range q.sort {
+		for field, direction := range sort {
+			if direction != Asc && direction != Desc {
+				return nil, fmt.Errorf("invalid sort direction %q for %s, expected asc or desc", direction, field)
+			}
+		}
+	}
+	if q.limit < 0 {
+		return nil, fmt.Errorf("limit must not be negative")
+	}
+
+	return json.Marshal(struct {
+		Selector map[string]map[string]interface{} `json:"selector"`
+		Sort     []map[string]Direction            `json:"sort,omitempty"`
+		Limit    int                               `json:"limit,omitempty"`
+		UseIndex []string                          `json:"use_index,omitempty"`
+	}{q.selector, q.sort, q.limit, q.useIndex})
+}
+
+// Build returns the query JSON, or an error if the query is invalid or a value cannot be encoded
+func (q *Query) Build() (string, error) {
+	queryJSON, err := q.MarshalJSON()
+	if err != nil {
+		return "", err
+	}
+
+	return string(queryJSON), nil
+}
@@ -0,0 +1,86 @@
+package querybuilder
+
+import "testing"
+
+func TestBuild(t *testing.T) {
+	tests := []struct {
+		name  string
+		query *Query
+		want  string
+		err   string
+	}{
+		{
+			name:  "empty query",
+			query: New(),
+			want:  `{"selector":{}}`,
+		},
+		{
+			name:  "operators",
+			query: New().Eq("docType", "loan").Gt("amount", 1000).Lt("amount", 5000).In("status", "Approved", "Disbursed").Exists("deletedAt", false),
+			want:  `{"selector":{"amount":{"$gt":1000,"$lt":5000},"deletedAt":{"$exists":false},"docType":{"$eq":"loan"},"status":{"$in":["Approved","Disbursed"]}}}`,
+		},
+		{
+			name:  "in without values",
+			query: New().In("status"),
+			want:  `{"selector":{"status":{"$in":[]}}}`,
+		},
+		{
+			name:  "later conditions replace earlier ones on the same operator",
+			query: New().Eq("owner", "ash").Eq("owner", "misty"),
+			want:  `{"selector":{"owner":{"$eq":"misty"}}}`,
+		},
+		{
+			name:  "escaping",
+			query: New().Eq(`na"me`, "quote \" backslash \\ <tag> \n"),
+			want:  `{"selector":{"na\"me":{"$eq":"quote \" backslash \\ \u003ctag\u003e \n"}}}`,
+		},
+		{
+			name:  "sort, limit and index",
+			query: New().Eq("docType", "pokemon").Sort("level", Desc).Sort("name", Desc).Limit(10).UseIndex("indexLevelDoc", "indexLevel"),
+			want:  `{"selector":{"docType":{"$eq":"pokemon"}},"sort":[{"level":"desc"},{"name":"desc"}],"limit":10,"use_index":["_design/indexLevelDoc","indexLevel"]}`,
+		},
+		{
+			name:  "any index of a design document",
+			query: New().UseIndex("indexOwnerDoc", ""),
+			want:  `{"selector":{},"use_index":["_design/indexOwnerDoc"]}`,
+		},
+		{
+			name:  "zero limit is left out",
+			query: New().Limit(0),
+			want:  `{"selector":{}}`,
+		},
+		{
+			name:  "invalid sort direction",
+			query: New().Sort("level", "up"),
+			err:   `invalid sort direction "up" for level, expected asc or desc`,
+		},
+		{
+			name:  "negative limit",
+			query: New().Limit(-1),
+			err:   "limit must not be negative",
+		},
+		{
+			name:  "value that cannot be encoded",
+			query: New().Eq("channel", make(chan int)),
+			err:   "json: unsupported type: chan int",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.query.Build()
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("Build() error = %v, want %s", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Build() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Build() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+// Package random derives deterministic pseudo-random values for game mechanics in chaincode.
+// Chaincode cannot use math/rand or crypto/rand, since every endorsing peer must compute the same
+// write set; a Source instead draws from a SHA-256 hash chain seeded with the transaction ID and a
+// caller-supplied seed, so all endorsers of a transaction reach identical outcomes.
+//
+// The values are only as unpredictable as the transaction ID and seed: a client choosing both can
+// try proposals until it likes the outcome. Use it for game mechanics, never to guard value.
+package random
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"strings"
+)
+
+// Source is a deterministic stream of pseudo-random bytes. Each block of the stream is the
+// SHA-256 digest of the previous one; the first is the digest of the seed.
+type Source struct {
+	block [sha256.Size]byte
+	next  int // the index of the next unread byte of block
+}
+
+// New returns a source seeded with a transaction ID and the given seed parts. The parts are joined
+// with NUL bytes, so that different splits of the same text seed different streams.
+func New(txID string, seed ...string) *Source {
+	return &Source{block: sha256.Sum256([]byte(strings.Join(append([]string{txID}, seed...), "\x00")))}
+}
+
+// Read fills p with the next bytes of the stream. It never fails.
+func (s *Source) Read(p []byte) (int, error) {
+	for i := range p {
+		if s.next == len(s.block) {
+			s.block = sha256.Sum256(s.block[:])
+			s.next = 0
+		}
+		p[i] = s.block[s.next]
+		s.next++
+	}
+
+	return len(p), nil
+}
+
+// Uint64 returns the next 8 bytes of the stream as a big-endian integer
+func (s *Source) Uint64() uint64 {
+	var b [8]byte
+	s.Read(b[:])
+	return binary.BigEndian.Uint64(b[:])
+}
+
+// Intn returns a value in [0, n). The modulo bias is negligible for the small ranges of game
+// mechanics. It panics if n is not positive.
+func (s *Source) Intn(n int) int {
+	if n <= 0 {
+		panic("random: invalid argument to Intn")
+	}
+	return int(s.Uint64() % uint64(n))
+}
+
+// Between returns a value in [min, max]. It panics if max is less than min.
+func (s *Source) Between(min int, max int) int {
+	return min + s.Intn(max-min+1)
+}
+
+// Bool returns true or false with equal probability
+func (s *Source) Bool() bool {
+	return s.Intn(2) == 1
+}
@@ -0,0 +1,78 @@
+package random
+
+import "testing"
+
+func TestDeterminism(t *testing.T) {
+	draw := func(source *Source) []uint64 {
+		values := make([]uint64, 10) // more than one block of the hash chain
+		for i := range values {
+			values[i] = source.Uint64()
+		}
+		return values
+	}
+
+	tests := []struct {
+		name  string
+		other *Source
+		same  bool
+	}{
+		{name: "same transaction and seed", other: New("tx1", "pikachu", "catch"), same: true},
+		{name: "other transaction", other: New("tx2", "pikachu", "catch")},
+		{name: "other seed", other: New("tx1", "bulbasaur", "catch")},
+		{name: "other split of the seed", other: New("tx1", "pikachuc", "atch")},
+	}
+
+	want := draw(New("tx1", "pikachu", "catch"))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := draw(tt.other)
+			equal := true
+			for i := range want {
+				if got[i] != want[i] {
+					equal = false
+				}
+			}
+			if equal != tt.same {
+				t.Errorf("sequences equal = %t, want %t", equal, tt.same)
+			}
+		})
+	}
+}
+
+func TestBetween(t *testing.T) {
+	tests := []struct {
+		name     string
+		min, max int
+	}{
+		{name: "single value", min: 7, max: 7},
+		{name: "coin", min: 0, max: 1},
+		{name: "die", min: 1, max: 6},
+		{name: "negative range", min: -5, max: -2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := New("tx1", tt.name)
+			seen := make(map[int]bool)
+			for i := 0; i < 1000; i++ {
+				value := source.Between(tt.min, tt.max)
+				if value < tt.min || value > tt.max {
+					t.Fatalf("Between(%d, %d) = %d", tt.min, tt.max, value)
+				}
+				seen[value] = true
+			}
+			if len(seen) != tt.max-tt.min+1 {
+				t.Errorf("Between(%d, %d) drew %d distinct values, want every one of %d", tt.min, tt.max, len(seen), tt.max-tt.min+1)
+			}
+		})
+	}
+}
+
+func TestBetweenPanicsOnEmptyRange(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Between(2, 1) did not panic")
+		}
+	}()
+	New("tx1").Between(2, 1)
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	// changeObjectType prefixes the keys of the update-time index, change~assetType~updatedAt~key
+	changeObjectType = "change"
+	// lastChangeObjectType prefixes the keys holding when each record last changed, so that its
+	// previous index entry can be removed
+	lastChangeObjectType = "lastchange"
+
+	// changeTimeLayout is RFC 3339 with a fixed number of fractional digits, so that the keys of the
+	// update-time index sort by time
+	changeTimeLayout = "2006-01-02T15:04:05.000000000Z07:00"
+)
+
+// Change is the entry of a record in the update-time index: the last transaction to write or
+// delete it. Deleted records keep their entry, so that clients syncing later learn of the deletion.
+type Change struct {
+	AssetType string `json:"assetType"`
+	Key       string `json:"key"`
+	TxID      string `json:"txId"`
+	UpdatedAt string `json:"updatedAt"` // the transaction timestamp, RFC 3339 with nanoseconds
+	Deleted   bool   `json:"deleted"`
+}
+
+// ChangePage is one page of changes with the bookmark the next page starts at, which is empty
+// after the last page
+type ChangePage struct {
+	Changes  []*Change
+	Bookmark string
+}
+
+// RecordChange moves the record of assetType under key to the transaction timestamp in the
+// update-time index. Call it on every write and deletion of a record tracked for sync.
+func RecordChange(ctx contractapi.TransactionContextInterface, assetType string, key string, deleted bool) error {
+	now, err := txTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	change := Change{
+		AssetType: assetType,
+		Key:       key,
+		TxID:      ctx.GetStub().GetTxID(),
+		UpdatedAt: now.Format(changeTimeLayout),
+		Deleted:   deleted,
+	}
+
+	lastChangeKey, err := ctx.GetStub().CreateCompositeKey(lastChangeObjectType, []string{assetType, key})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	lastChange, err := ctx.GetStub().GetState(lastChangeKey)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if lastChange != nil {
+		previousKey, err := ctx.GetStub().CreateCompositeKey(changeObjectType, []string{assetType, string(lastChange), key})
+		if err != nil {
+			return fmt.Errorf("failed to create composite key: %v", err)
+		}
+		err = ctx.GetStub().DelState(previousKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	changeKey, err := ctx.GetStub().CreateCompositeKey(changeObjectType, []string{assetType, change.UpdatedAt, key})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	changeJSON, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().PutState(changeKey, changeJSON)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(lastChangeKey, []byte(change.UpdatedAt))
+}
+
+// ChangesSince returns up to pageSize changes of assetType at or after since, oldest first. Pass
+// the bookmark of a page to read the next one; since is then ignored. Fabric refuses paginated
+// queries in transactions that write, so evaluate the calling function.
+func ChangesSince(ctx contractapi.TransactionContextInterface, assetType string, since time.Time, pageSize int32, bookmark string) (*ChangePage, error) {
+	if pageSize <= 0 {
+		return nil, fmt.Errorf("pageSize must be positive")
+	}
+	if bookmark == "" {
+		// the index keys of a timestamp sort after the partial key of that timestamp alone
+		var err error
+		bookmark, err = ctx.GetStub().CreateCompositeKey(changeObjectType, []string{assetType, since.UTC().Format(changeTimeLayout)})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create composite key: %v", err)
+		}
+	}
+
+	resultsIterator, metadata, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(changeObjectType, []string{assetType}, pageSize, bookmark)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	changes, err := collect[Change](resultsIterator)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ChangePage{Changes: changes, Bookmark: metadata.Bookmark}, nil
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+func TestChangesSince(t *testing.T) {
+	ledger := chaincodetest.NewLedger()
+	start := ledger.Now()
+
+	record := func(key string, deleted bool) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return RecordChange(ctx, "asset", key, deleted)
+		}
+	}
+	// changes reads every page since the given number of seconds after start and checks the keys
+	// and transactions of the changes, in order
+	changes := func(seconds int, pageSize int32, want ...string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			var got []string
+			bookmark := ""
+			for {
+				page, err := ChangesSince(ctx, "asset", start.Add(time.Duration(seconds)*time.Second), pageSize, bookmark)
+				if err != nil {
+					return err
+				}
+				for _, change := range page.Changes {
+					entry := change.Key + "@" + change.TxID
+					if change.Deleted {
+						entry += " deleted"
+					}
+					got = append(got, entry)
+				}
+				if page.Bookmark == "" {
+					break
+				}
+				bookmark = page.Bookmark
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("changes %v, want %v", got, want)
+			}
+			return nil
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "create a1", Caller: ash, Run: record("a1", false)},
+		{Name: "create a2", Caller: ash, Run: record("a2", false)},
+		{Name: "create a3", Caller: ash, Run: record("a3", false)},
+		{Name: "other asset types are apart", Caller: ash, Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return RecordChange(ctx, "loan", "l1", false)
+		}},
+		{Name: "update a1", Caller: ash, Run: record("a1", false)},
+		{Name: "delete a2", Caller: ash, Run: record("a2", true)},
+		{Name: "each record keeps only its last change", Caller: ash, Run: changes(0, 10, "a3@tx3", "a1@tx5", "a2@tx6 deleted")},
+		{Name: "in pages", Caller: ash, Run: changes(0, 1, "a3@tx3", "a1@tx5", "a2@tx6 deleted")},
+		{Name: "since a time", Caller: ash, Run: changes(5, 10, "a1@tx5", "a2@tx6 deleted")},
+		{Name: "since the future", Caller: ash, Run: changes(60, 10)},
+		{Name: "page size must be positive", Caller: ash, Run: changes(0, 0), Err: "pageSize must be positive"},
+	})
+}
@@ -0,0 +1,245 @@
+package repository
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	// IdempotencyKeyTransient is the transient key under which a client supplies the idempotency key
+	// of a request
+	IdempotencyKeyTransient = "idempotency_key"
+
+	// IdempotencyTTL is how long a processed idempotency key is remembered. Gateway retries happen
+	// within minutes; a key reused after this long starts a new request.
+	IdempotencyTTL = 24 * time.Hour
+
+	// idempotencyObjectType prefixes the keys of processed requests, idempotency~client~key
+	idempotencyObjectType = "idempotency"
+	// idempotencyExpiryObjectType prefixes the keys of the expiry index of processed requests,
+	// idempotencyexpiry~expiresAt~client~key
+	idempotencyExpiryObjectType = "idempotencyexpiry"
+)
+
+// IdempotencyRecord is a request processed under an idempotency key and the result it returned
+type IdempotencyRecord struct {
+	Key        string          `json:"key"`
+	Function   string          `json:"function"`
+	ArgsHash   string          `json:"argsHash"`
+	Result     json.RawMessage `json:"result"`
+	ResultHash string          `json:"resultHash"`
+	TxID       string          `json:"txId"`
+	ExpiresAt  string          `json:"expiresAt"` // RFC 3339 with nanoseconds, like the change index
+}
+
+// Idempotent runs a mutating request once per idempotency key. A client supplies the key in the
+// transient map under IdempotencyKeyTransient; without one, run is called as usual. The first
+// request under a key runs and its result is recorded. A retry of the same function with the same
+// arguments under that key, such as a gateway re-submitting after a timeout, returns the recorded
+// result without running again, instead of failing on the writes of the first. Reusing a key for a
+// different request is an error. Keys are scoped to the submitting client and forgotten after
+// IdempotencyTTL. Failed requests are not recorded, so they may be retried under the same key.
+func Idempotent[T any](ctx contractapi.TransactionContextInterface, run func() (T, error)) (T, error) {
+	var zero T
+	transientMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return zero, fmt.Errorf("error getting transient: %v", err)
+	}
+	key := string(transientMap[IdempotencyKeyTransient])
+	if key == "" {
+		return run()
+	}
+
+	recordKey, client, err := idempotencyKey(ctx, key)
+	if err != nil {
+		return zero, err
+	}
+	function, _ := ctx.GetStub().GetFunctionAndParameters()
+	argsHash := hashArgs(ctx.GetStub().GetArgs())
+	now, err := txTimestamp(ctx)
+	if err != nil {
+		return zero, err
+	}
+
+	recordJSON, err := ctx.GetStub().GetState(recordKey)
+	if err != nil {
+		return zero, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if recordJSON != nil {
+		var record IdempotencyRecord
+		err = json.Unmarshal(recordJSON, &record)
+		if err != nil {
+			return zero, err
+		}
+		if record.ExpiresAt > now.Format(changeTimeLayout) {
+			if record.Function != function || record.ArgsHash != argsHash {
+				return zero, fmt.Errorf("the idempotency key %s was used for a different request in transaction %s", key, record.TxID)
+			}
+			var result T
+			err = json.Unmarshal(record.Result, &result)
+			if err != nil {
+				return zero, err
+			}
+			return result, nil
+		}
+		// an expired record the pruning has not reached yet
+		err = deleteIdempotencyRecord(ctx, &record, client)
+		if err != nil {
+			return zero, err
+		}
+	}
+
+	result, err := run()
+	if err != nil {
+		return zero, err
+	}
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return zero, err
+	}
+	resultSum := sha256.Sum256(resultJSON)
+	record := IdempotencyRecord{
+		Key:        key,
+		Function:   function,
+		ArgsHash:   argsHash,
+		Result:     resultJSON,
+		ResultHash: hex.EncodeToString(resultSum[:]),
+		TxID:       ctx.GetStub().GetTxID(),
+		ExpiresAt:  now.Add(IdempotencyTTL).Format(changeTimeLayout),
+	}
+	recordJSON, err = json.Marshal(record)
+	if err != nil {
+		return zero, err
+	}
+	err = ctx.GetStub().PutState(recordKey, recordJSON)
+	if err != nil {
+		return zero, err
+	}
+	expiryKey, err := ctx.GetStub().CreateCompositeKey(idempotencyExpiryObjectType, []string{record.ExpiresAt, client, key})
+	if err != nil {
+		return zero, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().PutState(expiryKey, []byte{0x00})
+	if err != nil {
+		return zero, err
+	}
+
+	return result, nil
+}
+
+// IdempotentOnce is Idempotent for requests that return nothing but an error
+func IdempotentOnce(ctx contractapi.TransactionContextInterface, run func() error) error {
+	_, err := Idempotent(ctx, func() (struct{}, error) {
+		return struct{}{}, run()
+	})
+
+	return err
+}
+
+// PruneIdempotencyRecords deletes up to limit idempotency records that have expired, oldest first,
+// and returns how many it deleted. Idempotent ignores expired records anyway; pruning keeps them
+// from piling up in the world state. It reads only the expired head of the expiry index, but two
+// prunes running at once conflict, so submit it from a single scheduler.
+func PruneIdempotencyRecords(ctx contractapi.TransactionContextInterface, limit int) (int, error) {
+	if limit <= 0 {
+		return 0, fmt.Errorf("limit must be positive")
+	}
+	now, err := txTimestamp(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(idempotencyExpiryObjectType, []string{})
+	if err != nil {
+		return 0, err
+	}
+	defer resultsIterator.Close()
+
+	pruned := 0
+	for pruned < limit && resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return pruned, err
+		}
+		_, attributes, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return pruned, err
+		}
+		if len(attributes) != 3 {
+			return pruned, fmt.Errorf("malformed idempotency expiry key %q", queryResponse.Key)
+		}
+		if attributes[0] > now.Format(changeTimeLayout) {
+			break
+		}
+		err = deleteIdempotencyRecord(ctx, &IdempotencyRecord{Key: attributes[2], ExpiresAt: attributes[0]}, attributes[1])
+		if err != nil {
+			return pruned, err
+		}
+		pruned++
+	}
+
+	return pruned, nil
+}
+
+// idempotencyKey returns the world state key of the record of a client's idempotency key and the
+// hash the client is scoped by
+func idempotencyKey(ctx contractapi.TransactionContextInterface, key string) (string, string, error) {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read client ID: %v", err)
+	}
+	clientSum := sha256.Sum256([]byte(clientID))
+	client := hex.EncodeToString(clientSum[:])
+
+	recordKey, err := ctx.GetStub().CreateCompositeKey(idempotencyObjectType, []string{client, key})
+	if err != nil {
+		return "", "", fmt.Errorf("invalid idempotency key %q: %v", key, err)
+	}
+
+	return recordKey, client, nil
+}
+
+// deleteIdempotencyRecord deletes a record and its expiry index entry
+func deleteIdempotencyRecord(ctx contractapi.TransactionContextInterface, record *IdempotencyRecord, client string) error {
+	recordKey, err := ctx.GetStub().CreateCompositeKey(idempotencyObjectType, []string{client, record.Key})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().DelState(recordKey)
+	if err != nil {
+		return err
+	}
+	expiryKey, err := ctx.GetStub().CreateCompositeKey(idempotencyExpiryObjectType, []string{record.ExpiresAt, client, record.Key})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	return ctx.GetStub().DelState(expiryKey)
+}
+
+// hashArgs returns the hex encoded SHA-256 of the function and arguments of an invocation
+func hashArgs(args [][]byte) string {
+	hash := sha256.New()
+	for _, arg := range args {
+		// length prefixed, so that moving bytes between arguments changes the hash
+		fmt.Fprintf(hash, "%d:", len(arg))
+		hash.Write(arg)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// txTimestamp returns the transaction timestamp in UTC
+func txTimestamp(ctx contractapi.TransactionContextInterface) (time.Time, error) {
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	return time.Unix(timestamp.Seconds, int64(timestamp.Nanos)).UTC(), nil
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"fmt"
+	"testing"
+
+	"chaincodetest"
+	"chaincodetest/chaincodefakes"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+var (
+	ash   = chaincodetest.Identity{MSPID: "Org1MSP", CommonName: "ash"}
+	misty = chaincodetest.Identity{MSPID: "Org2MSP", CommonName: "misty"}
+)
+
+// invocation sets the function, arguments and idempotency key a transaction is invoked with
+func invocation(key string, function string, args ...string) func(stub *chaincodefakes.ChaincodeStub) {
+	return func(stub *chaincodefakes.ChaincodeStub) {
+		transient := map[string][]byte{}
+		if key != "" {
+			transient[IdempotencyKeyTransient] = []byte(key)
+		}
+		stub.GetTransientReturns(transient, nil)
+		stub.GetFunctionAndParametersReturns(function, args)
+		rawArgs := [][]byte{[]byte(function)}
+		for _, arg := range args {
+			rawArgs = append(rawArgs, []byte(arg))
+		}
+		stub.GetArgsReturns(rawArgs)
+	}
+}
+
+func TestIdempotent(t *testing.T) {
+	ledger := chaincodetest.NewLedger()
+	runs := 0
+
+	// issue counts its runs and returns the number of the run, or fails when asked to
+	issue := func(fail bool, want int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			got, err := Idempotent(ctx, func() (int, error) {
+				if fail {
+					return 0, fmt.Errorf("token service unavailable")
+				}
+				runs++
+				return runs, nil
+			})
+			if err == nil && got != want {
+				t.Errorf("Idempotent() = %d, want %d", got, want)
+			}
+			return err
+		}
+	}
+	issueOnce := func(want int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			err := IdempotentOnce(ctx, func() error {
+				runs++
+				return nil
+			})
+			if runs != want {
+				t.Errorf("%d runs, want %d", runs, want)
+			}
+			return err
+		}
+	}
+	prune := func(limit int, want int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			pruned, err := PruneIdempotencyRecords(ctx, limit)
+			if err == nil && pruned != want {
+				t.Errorf("PruneIdempotencyRecords() = %d, want %d", pruned, want)
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "without a key every request runs", Caller: ash, Stub: invocation("", "Issue", "100"), Run: issue(false, 1)},
+		{Name: "without a key again", Caller: ash, Stub: invocation("", "Issue", "100"), Run: issue(false, 2)},
+		{Name: "the first request under a key runs", Caller: ash, Stub: invocation("k1", "Issue", "100"), Run: issue(false, 3)},
+		{Name: "a retry under the same key returns the recorded result", Caller: ash, Stub: invocation("k1", "Issue", "100"), Run: issue(false, 3)},
+		{Name: "keys are scoped to the client", Caller: misty, Stub: invocation("k1", "Issue", "100"), Run: issue(false, 4)},
+		{Name: "other arguments under the key", Caller: ash, Stub: invocation("k1", "Issue", "200"), Run: issue(false, 0), Err: "the idempotency key k1 was used for a different request in transaction tx3"},
+		{Name: "moving bytes between arguments", Caller: ash, Stub: invocation("k1", "Issue", "10", "0"), Run: issue(false, 0), Err: "was used for a different request"},
+		{Name: "another function under the key", Caller: ash, Stub: invocation("k1", "Redeem", "100"), Run: issue(false, 0), Err: "was used for a different request"},
+		{Name: "failed requests are not recorded", Caller: ash, Stub: invocation("k2", "Issue", "100"), Run: issue(true, 0), Err: "token service unavailable"},
+		{Name: "so they may be retried under the same key", Caller: ash, Stub: invocation("k2", "Issue", "100"), Run: issue(false, 5)},
+		{Name: "once", Caller: ash, Stub: invocation("k3", "Close"), Run: issueOnce(6)},
+		{Name: "once retried", Caller: ash, Stub: invocation("k3", "Close"), Run: issueOnce(6)},
+		{Name: "nothing has expired", Caller: ash, Run: prune(10, 0)},
+		{
+			Name:   "a day passes",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				ledger.Advance(IdempotencyTTL)
+				return nil
+			},
+		},
+		{Name: "expired keys start a new request", Caller: ash, Stub: invocation("k1", "Issue", "200"), Run: issue(false, 7)},
+		{Name: "prune needs a positive limit", Caller: ash, Run: prune(0, 0), Err: "limit must be positive"},
+		// k1 of ash was replaced on reuse; k1 of misty, k2 and k3 remain expired
+		{Name: "prune up to the limit", Caller: ash, Run: prune(2, 2)},
+		{Name: "prune the rest", Caller: ash, Run: prune(10, 1)},
+		{Name: "the renewed key is kept", Caller: ash, Stub: invocation("k1", "Issue", "200"), Run: issue(false, 7)},
+	})
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+)
+
+// Namespace is the prefix of the plain keys of one record type, such as LOAN_ for loan
+// applications. Range queries over a namespace read records of its type only, however many other
+// plain keys the chaincode keeps. Namespaces must be ASCII and must not be prefixes of each other.
+type Namespace string
+
+// Key returns the plain key of the record with the given ID
+func (n Namespace) Key(id string) string {
+	return string(n) + id
+}
+
+// ID returns the ID of the record under a key of the namespace, and whether the key is one
+func (n Namespace) ID(key string) (string, bool) {
+	if len(key) < len(n) || key[:len(n)] != string(n) {
+		return "", false
+	}
+
+	return key[len(n):], true
+}
+
+// Range returns the start and end keys of a GetStateByRange over the namespace, from the record
+// with the given ID on, or over all of it when fromID is empty. The empty namespace ranges over
+// every plain key.
+func (n Namespace) Range(fromID string) (string, string) {
+	if n == "" {
+		return fromID, ""
+	}
+	end := []byte(n)
+	end[len(end)-1]++
+
+	return n.Key(fromID), string(end)
+}
+
+// History returns the modifications of the record with the given ID, newest first, going on with
+// those of its plain key from before the namespace was migrated to
+func (n Namespace) History(ctx contractapi.TransactionContextInterface, id string) ([]*queryresult.KeyModification, error) {
+	modifications, err := KeyHistory(ctx, n.Key(id))
+	if err != nil || n == "" {
+		return modifications, err
+	}
+	legacy, err := KeyHistory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, modification := range legacy {
+		// skip the deletion of the plain key by the migration, which wrote the oldest version in
+		// the namespace
+		if modification.IsDelete && len(modifications) > 0 && modification.TxId == modifications[len(modifications)-1].TxId {
+			continue
+		}
+		modifications = append(modifications, modification)
+	}
+
+	return modifications, nil
+}
+
+// Migrate moves up to limit records kept under plain keys without a namespace, as chaincodes
+// kept them before namespaces, to the keys of the namespace, carrying over their key-level
+// endorsement policies, and returns how many it moved. Every plain key outside the namespace is
+// taken for a record of its type, so it must be the only namespace in its chaincode. Run it until
+// it moves no more records. A record under a key-level endorsement policy can only be moved by a
+// transaction that satisfies the policy.
+func (n Namespace) Migrate(ctx contractapi.TransactionContextInterface, limit int) (int, error) {
+	if n == "" {
+		return 0, fmt.Errorf("the empty namespace has no keys to migrate to")
+	}
+	if limit <= 0 {
+		return 0, fmt.Errorf("limit must be positive")
+	}
+
+	var legacy []*queryresult.KV
+	start, end := n.Range("")
+	for _, bounds := range [][2]string{{"", start}, {end, ""}} {
+		resultsIterator, err := ctx.GetStub().GetStateByRange(bounds[0], bounds[1])
+		if err != nil {
+			return 0, err
+		}
+		for resultsIterator.HasNext() && len(legacy) < limit {
+			queryResponse, err := resultsIterator.Next()
+			if err != nil {
+				resultsIterator.Close()
+				return 0, err
+			}
+			legacy = append(legacy, queryResponse)
+		}
+		resultsIterator.Close()
+	}
+
+	for _, entry := range legacy {
+		key := n.Key(entry.Key)
+		err := ctx.GetStub().PutState(key, entry.Value)
+		if err != nil {
+			return 0, err
+		}
+		policy, err := ctx.GetStub().GetStateValidationParameter(entry.Key)
+		if err != nil {
+			return 0, err
+		}
+		if policy != nil {
+			err = ctx.GetStub().SetStateValidationParameter(key, policy)
+			if err != nil {
+				return 0, err
+			}
+		}
+		err = ctx.GetStub().DelState(entry.Key)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(legacy), nil
+}
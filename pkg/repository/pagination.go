@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// MaxPageSize is the largest page a paginated listing returns, so that a single call stays within
+// the peer's total query limit and gRPC message size
+const MaxPageSize = 100
+
+// KeyPage reads one page of the world state entries whose composite keys of objectType start with
+// the given key attributes, calling visit with the key attributes and value of each in key order.
+// It returns how many entries it read and the bookmark the next page starts at, which is empty
+// after the last page. pageSize must be between 1 and MaxPageSize. Fabric refuses paginated
+// queries in transactions that write, so evaluate the calling function.
+func KeyPage(ctx contractapi.TransactionContextInterface, objectType string, keys []string, pageSize int32, bookmark string, visit func(attributes []string, value []byte) error) (int32, string, error) {
+	err := checkPageSize(pageSize)
+	if err != nil {
+		return 0, "", err
+	}
+	resultsIterator, metadata, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(objectType, keys, pageSize, bookmark)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return 0, "", err
+		}
+
+		_, attributes, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return 0, "", err
+		}
+		err = visit(attributes, queryResponse.Value)
+		if err != nil {
+			return 0, "", err
+		}
+	}
+
+	return metadata.FetchedRecordsCount, metadata.Bookmark, nil
+}
+
+func checkPageSize(pageSize int32) error {
+	if pageSize <= 0 || pageSize > MaxPageSize {
+		return fmt.Errorf("pageSize must be between 1 and %d", MaxPageSize)
+	}
+
+	return nil
+}
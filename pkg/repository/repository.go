@@ -0,0 +1,301 @@
+// Package repository stores JSON records of one type in the world state or a private data
+// collection, so that the sample chaincodes share a single implementation of the key, marshalling
+// and iteration code around every record they keep.
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+)
+
+// Repository stores records of type T. A repository with an object type keys its records by the
+// composite key of the object type and the key attributes passed to each call; one without keys
+// them by the single attribute passed, as a plain key within its namespace.
+type Repository[T any] struct {
+	objectType string
+	namespace  Namespace
+	collection string
+}
+
+// Page is one page of records with the bookmark the next page starts at
+type Page[T any] struct {
+	Records             []*T
+	FetchedRecordsCount int32
+	Bookmark            string
+}
+
+// Version is one recorded version of a record. Value is nil for a deletion.
+type Version[T any] struct {
+	TxID      string
+	Timestamp time.Time
+	IsDelete  bool
+	Value     *T
+}
+
+// New returns a repository of records in the world state
+func New[T any](objectType string) *Repository[T] {
+	return &Repository[T]{objectType: objectType}
+}
+
+// NewInNamespace returns a repository of records in the world state under plain keys of the
+// namespace
+func NewInNamespace[T any](namespace Namespace) *Repository[T] {
+	return &Repository[T]{namespace: namespace}
+}
+
+// NewPrivate returns a repository of records in a private data collection
+func NewPrivate[T any](collection string, objectType string) *Repository[T] {
+	return &Repository[T]{objectType: objectType, collection: collection}
+}
+
+// Key returns the ledger key of the record with the given key attributes
+func (r *Repository[T]) Key(ctx contractapi.TransactionContextInterface, keys ...string) (string, error) {
+	if r.objectType == "" {
+		if len(keys) != 1 {
+			return "", fmt.Errorf("a plain key takes exactly one attribute, got %d", len(keys))
+		}
+		return r.namespace.Key(keys[0]), nil
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(r.objectType, keys)
+	if err != nil {
+		return "", fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	return key, nil
+}
+
+// Get returns the record with the given key attributes, or nil if there is none
+func (r *Repository[T]) Get(ctx contractapi.TransactionContextInterface, keys ...string) (*T, error) {
+	key, err := r.Key(ctx, keys...)
+	if err != nil {
+		return nil, err
+	}
+	var valueJSON []byte
+	if r.collection == "" {
+		valueJSON, err = ctx.GetStub().GetState(key)
+	} else {
+		valueJSON, err = ctx.GetStub().GetPrivateData(r.collection, key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if valueJSON == nil {
+		return nil, nil
+	}
+
+	var value T
+	err = json.Unmarshal(valueJSON, &value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &value, nil
+}
+
+// Exists reports whether a record with the given key attributes exists
+func (r *Repository[T]) Exists(ctx contractapi.TransactionContextInterface, keys ...string) (bool, error) {
+	key, err := r.Key(ctx, keys...)
+	if err != nil {
+		return false, err
+	}
+	if r.collection != "" {
+		hash, err := ctx.GetStub().GetPrivateDataHash(r.collection, key)
+		if err != nil {
+			return false, fmt.Errorf("failed to read from world state: %v", err)
+		}
+		return hash != nil, nil
+	}
+	valueJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return false, fmt.Errorf("failed to read from world state: %v", err)
+	}
+
+	return valueJSON != nil, nil
+}
+
+// Put stores a record under the given key attributes
+func (r *Repository[T]) Put(ctx contractapi.TransactionContextInterface, value *T, keys ...string) error {
+	key, err := r.Key(ctx, keys...)
+	if err != nil {
+		return err
+	}
+	valueJSON, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if r.collection != "" {
+		return ctx.GetStub().PutPrivateData(r.collection, key, valueJSON)
+	}
+
+	return ctx.GetStub().PutState(key, valueJSON)
+}
+
+// Delete removes the record with the given key attributes
+func (r *Repository[T]) Delete(ctx contractapi.TransactionContextInterface, keys ...string) error {
+	key, err := r.Key(ctx, keys...)
+	if err != nil {
+		return err
+	}
+	if r.collection != "" {
+		return ctx.GetStub().DelPrivateData(r.collection, key)
+	}
+
+	return ctx.GetStub().DelState(key)
+}
+
+// List returns the records whose key attributes start with the given ones, in key order. A
+// repository of plain keys lists every plain key of its namespace; without one, every plain key
+// in the world state, so it must then be the only one in its chaincode.
+func (r *Repository[T]) List(ctx contractapi.TransactionContextInterface, keys ...string) ([]*T, error) {
+	var resultsIterator shim.StateQueryIteratorInterface
+	var err error
+	switch {
+	case r.objectType == "" && r.collection == "":
+		resultsIterator, err = ctx.GetStub().GetStateByRange(r.namespace.Range(""))
+	case r.objectType == "":
+		resultsIterator, err = ctx.GetStub().GetPrivateDataByRange(r.collection, "", "")
+	case r.collection == "":
+		resultsIterator, err = ctx.GetStub().GetStateByPartialCompositeKey(r.objectType, keys)
+	default:
+		resultsIterator, err = ctx.GetStub().GetPrivateDataByPartialCompositeKey(r.collection, r.objectType, keys)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	return collect[T](resultsIterator)
+}
+
+// ListPaginated returns one page of at most MaxPageSize of the records List returns. Private data
+// cannot be paginated. Fabric refuses paginated queries in transactions that write, so evaluate
+// the calling function.
+func (r *Repository[T]) ListPaginated(ctx contractapi.TransactionContextInterface, pageSize int32, bookmark string, keys ...string) (*Page[T], error) {
+	if r.collection != "" {
+		return nil, fmt.Errorf("private data of collection %s cannot be paginated", r.collection)
+	}
+	if r.objectType != "" {
+		page := &Page[T]{}
+		var err error
+		page.FetchedRecordsCount, page.Bookmark, err = KeyPage(ctx, r.objectType, keys, pageSize, bookmark, func(_ []string, valueJSON []byte) error {
+			var value T
+			err := json.Unmarshal(valueJSON, &value)
+			if err != nil {
+				return err
+			}
+			page.Records = append(page.Records, &value)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return page, nil
+	}
+
+	err := checkPageSize(pageSize)
+	if err != nil {
+		return nil, err
+	}
+	start, end := r.namespace.Range("")
+	resultsIterator, metadata, err := ctx.GetStub().GetStateByRangeWithPagination(start, end, pageSize, bookmark)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	records, err := collect[T](resultsIterator)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Page[T]{Records: records, FetchedRecordsCount: metadata.FetchedRecordsCount, Bookmark: metadata.Bookmark}, nil
+}
+
+// History returns every version of the record with the given key attributes, newest first.
+// The history of a record in a namespace goes on with its versions from before the namespace was
+// migrated to. Private data keeps no history.
+func (r *Repository[T]) History(ctx contractapi.TransactionContextInterface, keys ...string) ([]*Version[T], error) {
+	if r.collection != "" {
+		return nil, fmt.Errorf("private data of collection %s has no history", r.collection)
+	}
+	var modifications []*queryresult.KeyModification
+	if r.objectType == "" && len(keys) == 1 {
+		var err error
+		modifications, err = r.namespace.History(ctx, keys[0])
+		if err != nil {
+			return nil, fmt.Errorf("failed to read history of %s: %v", keys[0], err)
+		}
+	} else {
+		key, err := r.Key(ctx, keys...)
+		if err != nil {
+			return nil, err
+		}
+		modifications, err = KeyHistory(ctx, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read history of %s: %v", key, err)
+		}
+	}
+
+	versions := make([]*Version[T], 0, len(modifications))
+	for _, modification := range modifications {
+		version := &Version[T]{TxID: modification.TxId, IsDelete: modification.IsDelete}
+		if modification.Timestamp != nil {
+			version.Timestamp = time.Unix(modification.Timestamp.Seconds, int64(modification.Timestamp.Nanos)).UTC()
+		}
+		if !modification.IsDelete {
+			var value T
+			err := json.Unmarshal(modification.Value, &value)
+			if err != nil {
+				return nil, err
+			}
+			version.Value = &value
+		}
+		versions = append(versions, version)
+	}
+
+	return versions, nil
+}
+
+// KeyHistory returns the modifications of a world state key, newest first
+func KeyHistory(ctx contractapi.TransactionContextInterface, key string) ([]*queryresult.KeyModification, error) {
+	resultsIterator, err := ctx.GetStub().GetHistoryForKey(key)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var modifications []*queryresult.KeyModification
+	for resultsIterator.HasNext() {
+		modification, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		modifications = append(modifications, modification)
+	}
+
+	return modifications, nil
+}
+
+func collect[T any](resultsIterator shim.StateQueryIteratorInterface) ([]*T, error) {
+	var values []*T
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var value T
+		err = json.Unmarshal(queryResponse.Value, &value)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, &value)
+	}
+
+	return values, nil
+}
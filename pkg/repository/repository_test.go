@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+type testAsset struct {
+	ID      string `json:"id"`
+	Owner   string `json:"owner"`
+	Deleted bool   `json:"deleted,omitempty"`
+}
+
+func TestTombstones(t *testing.T) {
+	ledger := chaincodetest.NewLedger()
+	assets := NewInNamespace[testAsset]("ASSET_")
+	deleted := New[testAsset](TombstoneObjectType)
+
+	put := func(id string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return assets.Put(ctx, &testAsset{ID: id, Owner: "ash"}, id)
+		}
+	}
+	// softDelete moves an asset to its tombstone, as the chaincodes' Delete functions do
+	softDelete := func(id string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			asset, err := assets.Get(ctx, id)
+			if err != nil || asset == nil {
+				t.Fatalf("Get(%s) = %v, %v", id, asset, err)
+			}
+			err = assets.Delete(ctx, id)
+			if err != nil {
+				return err
+			}
+			asset.Deleted = true
+			return deleted.Put(ctx, asset, "asset", id)
+		}
+	}
+	// restore moves a tombstone back to its live key, as the chaincodes' Restore functions do
+	restore := func(id string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			asset, err := deleted.Get(ctx, "asset", id)
+			if err != nil || asset == nil {
+				t.Fatalf("tombstone of %s = %v, %v", id, asset, err)
+			}
+			asset.Deleted = false
+			err = assets.Put(ctx, asset, id)
+			if err != nil {
+				return err
+			}
+			return deleted.Delete(ctx, "asset", id)
+		}
+	}
+	listed := func(wantLive []string, wantDeleted []string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			live, err := assets.List(ctx)
+			if err != nil {
+				return err
+			}
+			tombstones, err := deleted.List(ctx, "asset")
+			if err != nil {
+				return err
+			}
+			if !reflect.DeepEqual(assetIDs(live), wantLive) || !reflect.DeepEqual(assetIDs(tombstones), wantDeleted) {
+				t.Errorf("live %v, deleted %v, want live %v, deleted %v", assetIDs(live), assetIDs(tombstones), wantLive, wantDeleted)
+			}
+			for _, asset := range tombstones {
+				if !asset.Deleted {
+					t.Errorf("tombstone of %s is not flagged as deleted", asset.ID)
+				}
+			}
+			return nil
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "create", Caller: ash, Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			for _, id := range []string{"a1", "a2", "a3"} {
+				if err := put(id)(t, ctx); err != nil {
+					return err
+				}
+			}
+			return nil
+		}},
+		{Name: "all live", Caller: ash, Run: listed([]string{"a1", "a2", "a3"}, nil)},
+		{Name: "soft delete", Caller: ash, Run: softDelete("a2")},
+		{Name: "tombstones leave the live range", Caller: ash, Run: listed([]string{"a1", "a3"}, []string{"a2"})},
+		{Name: "restore", Caller: ash, Run: restore("a2")},
+		{Name: "restored", Caller: ash, Run: listed([]string{"a1", "a2", "a3"}, nil)},
+		{
+			Name:   "the restored record is unflagged and keeps its history",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				versions, err := assets.History(ctx, "a2")
+				if err != nil {
+					return err
+				}
+				// newest first
+				if len(versions) != 3 || versions[0].Value.Deleted || !versions[1].IsDelete || versions[2].IsDelete {
+					t.Errorf("history of a2 has %d versions, want the restore, deletion and creation", len(versions))
+				}
+				return nil
+			},
+		},
+	})
+}
+
+func assetIDs(assets []*testAsset) []string {
+	var ids []string
+	for _, asset := range assets {
+		ids = append(ids, asset.ID)
+	}
+	return ids
+}
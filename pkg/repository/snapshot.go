@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/peer"
+)
+
+// StateEntry is one key of the world state and its raw value
+type StateEntry struct {
+	Key   string `json:"key"`
+	Value []byte `json:"value"` // base64 in JSON, since not every value is a JSON document
+}
+
+// StatePage is one page of a world state export. Hash is the SHA-256 of the canonical JSON
+// encoding of Entries, so that the page can be checked against a manifest of the export's page
+// hashes wherever it is imported.
+type StatePage struct {
+	Prefix   string        `json:"prefix"`
+	Entries  []*StateEntry `json:"entries"`
+	Hash     string        `json:"hash"`
+	Bookmark string        `json:"bookmark"` // empty after the last page
+}
+
+// ImportReceipt reports a page written by ImportState
+type ImportReceipt struct {
+	Prefix string `json:"prefix"`
+	Hash   string `json:"hash"`
+	Keys   int    `json:"keys"`
+}
+
+// ExportState returns up to pageSize world state entries in key order. A prefix names the
+// object type of the composite keys to export; the empty prefix exports the plain keys. Pass the
+// bookmark of a page to read the next one. Fabric refuses paginated queries in transactions that
+// write, so evaluate the calling function.
+func ExportState(ctx contractapi.TransactionContextInterface, prefix string, pageSize int32, bookmark string) (*StatePage, error) {
+	if pageSize <= 0 {
+		return nil, fmt.Errorf("pageSize must be positive")
+	}
+
+	var resultsIterator shim.StateQueryIteratorInterface
+	var metadata *peer.QueryResponseMetadata
+	var err error
+	if prefix == "" {
+		resultsIterator, metadata, err = ctx.GetStub().GetStateByRangeWithPagination("", "", pageSize, bookmark)
+	} else {
+		resultsIterator, metadata, err = ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(prefix, []string{}, pageSize, bookmark)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	page := &StatePage{Prefix: prefix, Entries: []*StateEntry{}, Bookmark: metadata.Bookmark}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		page.Entries = append(page.Entries, &StateEntry{Key: queryResponse.Key, Value: queryResponse.Value})
+	}
+	page.Hash, err = EntriesHash(page.Entries)
+	if err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// ImportState writes the entries of an exported page to the world state, overwriting the keys
+// that exist, after checking them against the page hash. Key-level endorsement policies and
+// private data are not part of an export.
+func ImportState(ctx contractapi.TransactionContextInterface, page *StatePage) (*ImportReceipt, error) {
+	hash, err := EntriesHash(page.Entries)
+	if err != nil {
+		return nil, err
+	}
+	if hash != page.Hash {
+		return nil, fmt.Errorf("the entries of the page hash to %s, not %s", hash, page.Hash)
+	}
+
+	for _, entry := range page.Entries {
+		if entry.Key == "" {
+			return nil, fmt.Errorf("entry key must not be empty")
+		}
+		err = ctx.GetStub().PutState(entry.Key, entry.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to put %q to world state: %v", entry.Key, err)
+		}
+	}
+
+	return &ImportReceipt{Prefix: page.Prefix, Hash: hash, Keys: len(page.Entries)}, nil
+}
+
+// EntriesHash returns the hex encoded SHA-256 of the canonical JSON encoding of entries
+func EntriesHash(entries []*StateEntry) (string, error) {
+	entriesJSON, err := json.Marshal(entries)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(entriesJSON)
+
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"bytes"
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+func TestExportImportState(t *testing.T) {
+	source := chaincodetest.NewLedger()
+	target := chaincodetest.NewLedger()
+	assets := NewInNamespace[testAsset]("ASSET_")
+	owners := New[testAsset]("owner~asset")
+
+	var pages []*StatePage
+	export := func(prefix string, pageSize int32) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			bookmark := ""
+			for {
+				page, err := ExportState(ctx, prefix, pageSize, bookmark)
+				if err != nil {
+					return err
+				}
+				if int32(len(page.Entries)) > pageSize {
+					t.Errorf("page of %d entries, want at most %d", len(page.Entries), pageSize)
+				}
+				pages = append(pages, page)
+				if page.Bookmark == "" {
+					return nil
+				}
+				bookmark = page.Bookmark
+			}
+		}
+	}
+
+	source.Run(t, []chaincodetest.Case{
+		{
+			Name:   "create",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				for _, id := range []string{"a1", "a2", "a3"} {
+					err := assets.Put(ctx, &testAsset{ID: id, Owner: "ash"}, id)
+					if err != nil {
+						return err
+					}
+					err = owners.Put(ctx, &testAsset{ID: id, Owner: "ash"}, "ash", id)
+					if err != nil {
+						return err
+					}
+				}
+				// values need not be JSON
+				return ctx.GetStub().PutState("counter", []byte{0x00, 0xff})
+			},
+		},
+		{Name: "page size must be positive", Caller: ash, Run: export("", 0), Err: "pageSize must be positive"},
+		{Name: "export plain keys", Caller: ash, Run: export("", 2)},
+		{Name: "export composite keys", Caller: ash, Run: export("owner~asset", 2)},
+	})
+
+	if len(pages) != 4 {
+		t.Fatalf("exported %d pages, want 2 of plain and 2 of composite keys", len(pages))
+	}
+
+	var cases []chaincodetest.Case
+	for _, page := range pages {
+		page := page
+		cases = append(cases, chaincodetest.Case{
+			Name:   "import " + page.Prefix + " page",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				receipt, err := ImportState(ctx, page)
+				if err == nil && (receipt.Hash != page.Hash || receipt.Keys != len(page.Entries)) {
+					t.Errorf("receipt %+v does not match the page", receipt)
+				}
+				return err
+			},
+		})
+	}
+	tampered := *pages[0]
+	tampered.Entries = []*StateEntry{{Key: pages[0].Entries[0].Key, Value: []byte(`{"id":"a1","owner":"misty"}`)}}
+	cases = append(cases,
+		chaincodetest.Case{
+			Name:   "tampered pages are refused",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := ImportState(ctx, &tampered)
+				return err
+			},
+			Err: "the entries of the page hash to",
+		},
+		chaincodetest.Case{
+			Name:   "empty keys are refused",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				entries := []*StateEntry{{Key: "", Value: []byte("x")}}
+				hash, err := EntriesHash(entries)
+				if err != nil {
+					return err
+				}
+				_, err = ImportState(ctx, &StatePage{Entries: entries, Hash: hash})
+				return err
+			},
+			Err: "entry key must not be empty",
+		},
+	)
+	target.Run(t, cases)
+
+	for _, key := range []string{"ASSET_a1", "ASSET_a2", "ASSET_a3", "counter"} {
+		if !bytes.Equal(target.State(key), source.State(key)) {
+			t.Errorf("imported %s = %q, want %q", key, target.State(key), source.State(key))
+		}
+	}
+	for _, page := range pages {
+		for _, entry := range page.Entries {
+			if !bytes.Equal(target.State(entry.Key), source.State(entry.Key)) {
+				t.Errorf("imported %q = %q, want %q", entry.Key, target.State(entry.Key), source.State(entry.Key))
+			}
+		}
+	}
+}
@@ -0,0 +1,8 @@
+package repository
+
+// TombstoneObjectType prefixes the keys under which the chaincodes keep soft deleted records,
+// tombstone~assetType~key. Moving a record there takes it out of the plain key range its live
+// counterparts are listed and swept from, while keeping it, flagged as deleted with the reason
+// and the deleting client, until it is restored. Rich queries still see tombstones, so their
+// selectors must exclude records flagged as deleted.
+const TombstoneObjectType = "tombstone"
@@ -0,0 +1,72 @@
+// Package timelock measures deadlines on transaction timestamps, which every endorsing peer
+// agrees on, so that operations can be held back until a challenge window has elapsed or refused
+// once it has closed. Deadlines are stored on records as RFC 3339 strings.
+package timelock
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Now returns the transaction timestamp
+func Now(ctx contractapi.TransactionContextInterface) (time.Time, error) {
+	ts, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	return time.Unix(ts.Seconds, int64(ts.Nanos)).UTC(), nil
+}
+
+// Deadline returns the deadline d after the transaction timestamp
+func Deadline(ctx contractapi.TransactionContextInterface, d time.Duration) (string, error) {
+	now, err := Now(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return now.Add(d).Format(time.RFC3339), nil
+}
+
+// Passed reports whether the transaction timestamp is at or after the deadline
+func Passed(ctx contractapi.TransactionContextInterface, deadline string) (bool, error) {
+	at, err := time.Parse(time.RFC3339, deadline)
+	if err != nil {
+		return false, fmt.Errorf("invalid deadline %q: %v", deadline, err)
+	}
+	now, err := Now(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	return !now.Before(at), nil
+}
+
+// RequireOpen returns an error once the window named what, closing at the deadline, has closed
+func RequireOpen(ctx contractapi.TransactionContextInterface, deadline string, what string) error {
+	passed, err := Passed(ctx, deadline)
+	if err != nil {
+		return err
+	}
+	if passed {
+		return fmt.Errorf("the %s closed at %s", what, deadline)
+	}
+
+	return nil
+}
+
+// RequireElapsed returns an error until the window named what, closing at the deadline, has
+// elapsed
+func RequireElapsed(ctx contractapi.TransactionContextInterface, deadline string, what string) error {
+	passed, err := Passed(ctx, deadline)
+	if err != nil {
+		return err
+	}
+	if !passed {
+		return fmt.Errorf("the %s is open until %s", what, deadline)
+	}
+
+	return nil
+}
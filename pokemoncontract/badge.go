@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"authz"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+const (
+	badgeObjectType        = "badge"
+	trainerBadgeObjectType = "trainerbadge"
+
+	// gymLeaderRole is the role attribute value of gym leaders, whose gym attribute names their gym
+	gymLeaderRole = "gym_leader"
+)
+
+var (
+	badgeRepo        = repository.New[Badge](badgeObjectType)
+	trainerBadgeRepo = repository.New[TrainerBadge](trainerBadgeObjectType)
+)
+
+// Badge is a gym badge. Pokemon at UnlocksLevel or above only battle for trainers holding the
+// badge; a badge with UnlocksLevel 0 unlocks nothing.
+type Badge struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Gym          string `json:"gym"`
+	UnlocksLevel int    `json:"unlocksLevel"`
+}
+
+// TrainerBadge records a badge awarded to a trainer
+type TrainerBadge struct {
+	Trainer   string `json:"trainer"`
+	BadgeID   string `json:"badgeId"`
+	Gym       string `json:"gym"`
+	AwardedBy string `json:"awardedBy"`
+	AwardedAt string `json:"awardedAt"`
+}
+
+// RegisterBadge defines the badge awarded by a gym. Admin only.
+func (s *SmartContract) RegisterBadge(ctx contractapi.TransactionContextInterface, id string, name string, gym string, unlocksLevel int) error {
+	err := requireAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	if id == "" || name == "" || gym == "" {
+		return fmt.Errorf("badge ID, name and gym must not be empty")
+	}
+	if unlocksLevel < 0 || unlocksLevel > maxLevel {
+		return fmt.Errorf("unlocked level must be between 0 and %d", maxLevel)
+	}
+	existing, err := getBadge(ctx, id)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("badge %s already exists", id)
+	}
+
+	badge := Badge{ID: id, Name: name, Gym: gym, UnlocksLevel: unlocksLevel}
+	return badgeRepo.Put(ctx, &badge, id)
+}
+
+// AwardBadge awards a gym's badge to a registered trainer. Only the leaders of the badge's gym,
+// identities carrying role=gym_leader and gym=<the badge's gym>, may award it.
+func (s *SmartContract) AwardBadge(ctx contractapi.TransactionContextInterface, trainerID string, badgeID string) error {
+	badge, err := getBadge(ctx, badgeID)
+	if err != nil {
+		return err
+	}
+	if badge == nil {
+		return fmt.Errorf("badge %s does not exist", badgeID)
+	}
+	err = requireGymLeader(ctx, badge.Gym)
+	if err != nil {
+		return err
+	}
+
+	t, err := s.ReadTrainer(ctx, trainerID)
+	if err != nil {
+		return err
+	}
+	if t.RegisteredAt == "" {
+		return fmt.Errorf("trainer %s is not registered", trainerID)
+	}
+	held, err := hasBadge(ctx, trainerID, badgeID)
+	if err != nil {
+		return err
+	}
+	if held {
+		return fmt.Errorf("trainer %s already holds the %s badge", trainerID, badge.Name)
+	}
+
+	leader, err := callerTrainer(ctx)
+	if err != nil {
+		return err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	award := TrainerBadge{
+		Trainer:   trainerID,
+		BadgeID:   badgeID,
+		Gym:       badge.Gym,
+		AwardedBy: leader,
+		AwardedAt: now.Format(time.RFC3339),
+	}
+	err = trainerBadgeRepo.Put(ctx, &award, trainerID, badgeID)
+	if err != nil {
+		return err
+	}
+
+	t.Badges = append(t.Badges, badgeID)
+	return putTrainer(ctx, t)
+}
+
+// HasBadge reports whether a trainer has been awarded a badge
+func (s *SmartContract) HasBadge(ctx contractapi.TransactionContextInterface, trainerID string, badgeID string) (bool, error) {
+	return hasBadge(ctx, trainerID, badgeID)
+}
+
+// GetTrainerBadges returns the badges awarded to a trainer
+func (s *SmartContract) GetTrainerBadges(ctx contractapi.TransactionContextInterface, trainerID string) ([]*TrainerBadge, error) {
+	return trainerBadgeRepo.List(ctx, trainerID)
+}
+
+// TrainerBadgePage is one page of awarded badges with the bookmark the next page starts at, which
+// is empty after the last page
+type TrainerBadgePage struct {
+	Badges              []*TrainerBadge `json:"badges,omitempty" metadata:",optional"`
+	FetchedRecordsCount int32           `json:"fetchedRecordsCount"`
+	Bookmark            string          `json:"bookmark"`
+}
+
+// GetTrainerBadgesPaginated returns one page of at most repository.MaxPageSize of the badges
+// awarded to a trainer. Evaluate it rather than submitting it, since Fabric refuses paginated
+// queries in transactions that write.
+func (s *SmartContract) GetTrainerBadgesPaginated(ctx contractapi.TransactionContextInterface, trainerID string, pageSize int, bookmark string) (*TrainerBadgePage, error) {
+	page, err := trainerBadgeRepo.ListPaginated(ctx, int32(pageSize), bookmark, trainerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TrainerBadgePage{Badges: page.Records, FetchedRecordsCount: page.FetchedRecordsCount, Bookmark: page.Bookmark}, nil
+}
+
+// checkBattleBadges returns an error unless the trainer battling with the Pokemon holds every
+// badge unlocking the Pokemon's level
+func checkBattleBadges(ctx contractapi.TransactionContextInterface, p *Pokemon, trainer string) error {
+	badges, err := badgeRepo.List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, badge := range badges {
+		if badge.UnlocksLevel == 0 || p.Level < badge.UnlocksLevel {
+			continue
+		}
+		held, err := hasBadge(ctx, trainer, badge.ID)
+		if err != nil {
+			return err
+		}
+		if !held {
+			return fmt.Errorf("Pokemon %s is level %d, trainer %s needs the %s badge to battle with it", p.ID, p.Level, trainer, badge.Name)
+		}
+	}
+
+	return nil
+}
+
+// requireGymLeader returns an error unless the submitting client is a leader of the given gym
+func requireGymLeader(ctx contractapi.TransactionContextInterface, gym string) error {
+	client, err := authz.ClientOf(ctx)
+	if err != nil {
+		return err
+	}
+	if !client.HasAttribute("role", gymLeaderRole) {
+		return fmt.Errorf("submitting client not authorized, requires role %s", gymLeaderRole)
+	}
+	if !client.HasAttribute("gym", gym) {
+		return fmt.Errorf("submitting client not authorized, requires gym %s", gym)
+	}
+
+	return nil
+}
+
+func hasBadge(ctx contractapi.TransactionContextInterface, trainerID string, badgeID string) (bool, error) {
+	return trainerBadgeRepo.Exists(ctx, trainerID, badgeID)
+}
+
+func getBadge(ctx contractapi.TransactionContextInterface, id string) (*Badge, error) {
+	return badgeRepo.Get(ctx, id)
+}
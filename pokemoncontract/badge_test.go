@@ -0,0 +1,110 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBadges(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+	ceruleanLeader := chaincodetest.Identity{MSPID: "Org2MSP", CommonName: "Misty", Attributes: map[string]string{"role": gymLeaderRole, "gym": "Cerulean City"}}
+
+	registerBadge := func(id string, name string, gym string, unlocksLevel int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.RegisterBadge(ctx, id, name, gym, unlocksLevel)
+		}
+	}
+	award := func(trainer string, badge string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.AwardBadge(ctx, trainer, badge)
+		}
+	}
+	hasBadge := func(trainer string, want bool) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			held, err := s.HasBadge(ctx, trainer, "boulder")
+			require.Equal(t, want, held)
+			return err
+		}
+	}
+	battle := func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		_, err := s.Battle(ctx, "poke1", "poke2", "seed")
+		return err
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "register badge", Caller: admin, Run: registerBadge("boulder", "Boulder", "Pewter City", 10)},
+		{Name: "register badge requires admin", Caller: brock, Run: registerBadge("cascade", "Cascade", "Cerulean City", 0), Err: "requires role admin"},
+		{Name: "register badge without a gym", Caller: admin, Run: registerBadge("cascade", "Cascade", "", 0), Err: "badge ID, name and gym must not be empty"},
+		{Name: "register badge above the maximum level", Caller: admin, Run: registerBadge("cascade", "Cascade", "Cerulean City", 101), Err: "unlocked level must be between 0 and 100"},
+		{Name: "register duplicate badge", Caller: admin, Run: registerBadge("boulder", "Boulder", "Pewter City", 10), Err: "badge boulder already exists"},
+		{Name: "award to an unknown trainer", Caller: brock, Run: award("Ash", "boulder"), Err: "trainer Ash does not exist"},
+		{
+			Name:   "credit Red, opening an unregistered account",
+			Caller: admin,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.MintPokeCoin(ctx, "Red", 10)
+			},
+		},
+		{Name: "award to an unregistered trainer", Caller: brock, Run: award("Red", "boulder"), Err: "trainer Red is not registered"},
+		{
+			Name:   "Ash registers",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.RegisterTrainer(ctx, "Ash", "Ash Ketchum")
+			},
+		},
+		{Name: "award requires a gym leader", Caller: misty, Run: award("Ash", "boulder"), Err: "requires role gym_leader"},
+		{Name: "award requires the badge's gym", Caller: ceruleanLeader, Run: award("Ash", "boulder"), Err: "requires gym Pewter City"},
+		{Name: "award unknown badge", Caller: brock, Run: award("Ash", "cascade"), Err: "badge cascade does not exist"},
+		{Name: "no badge yet", Caller: ash, Run: hasBadge("Ash", false)},
+		{
+			Name:   "level Pikachu up to the badge's level",
+			Caller: admin,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				p, err := s.GainExperience(ctx, "poke1", 2025)
+				if err == nil {
+					require.Equal(t, 10, p.Level)
+				}
+				return err
+			},
+		},
+		{Name: "battle without the badge", Caller: admin, Run: battle, Err: "Pokemon poke1 is level 10, trainer Ash needs the Boulder badge to battle with it"},
+		{Name: "award badge", Caller: brock, Run: award("Ash", "boulder")},
+		{Name: "award twice", Caller: brock, Run: award("Ash", "boulder"), Err: "trainer Ash already holds the Boulder badge"},
+		{Name: "badge is held", Caller: ash, Run: hasBadge("Ash", true)},
+		{
+			Name:   "awarded badges",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				badges, err := s.GetTrainerBadges(ctx, "Ash")
+				require.NoError(t, err)
+				require.Len(t, badges, 1)
+				require.Equal(t, "Brock", badges[0].AwardedBy)
+				require.Equal(t, "Pewter City", badges[0].Gym)
+
+				trainer, err := s.ReadTrainer(ctx, "Ash")
+				if err == nil {
+					require.Equal(t, []string{"boulder"}, trainer.Badges)
+				}
+				return err
+			},
+		},
+		{
+			Name:   "awarded badges by page",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				page, err := s.GetTrainerBadgesPaginated(ctx, "Ash", 1, "")
+				if err == nil {
+					require.Len(t, page.Badges, 1)
+					require.Equal(t, "Pewter City", page.Badges[0].Gym)
+				}
+				return err
+			},
+		},
+		{Name: "battle with the badge", Caller: admin, Run: battle},
+	})
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"random"
+	"repository"
+)
+
+const (
+	battleObjectType            = "battle"
+	typeEffectivenessObjectType = "typeeffectiveness"
+
+	// EventBattleCompleted is the chaincode event emitted with the BattleResult of every battle
+	EventBattleCompleted = "BattleCompleted"
+)
+
+var typeEffectivenessRepo = repository.New[TypeEffectiveness](typeEffectivenessObjectType)
+
+// defaultTypeChart is the type effectiveness table written by InitLedger. Matchups that are not
+// listed are neutral.
+var defaultTypeChart = []TypeEffectiveness{
+	{AttackType: "Fire", DefendType: "Grass", Multiplier: 2},
+	{AttackType: "Fire", DefendType: "Water", Multiplier: 0.5},
+	{AttackType: "Water", DefendType: "Fire", Multiplier: 2},
+	{AttackType: "Water", DefendType: "Grass", Multiplier: 0.5},
+	{AttackType: "Grass", DefendType: "Water", Multiplier: 2},
+	{AttackType: "Grass", DefendType: "Fire", Multiplier: 0.5},
+	{AttackType: "Electric", DefendType: "Water", Multiplier: 2},
+	{AttackType: "Electric", DefendType: "Grass", Multiplier: 0.5},
+	{AttackType: "Electric", DefendType: "Ground", Multiplier: 0},
+}
+
+// TypeEffectiveness is the damage multiplier of an attacking type against a defending type
+type TypeEffectiveness struct {
+	AttackType string  `json:"attackType"`
+	DefendType string  `json:"defendType"`
+	Multiplier float64 `json:"multiplier"`
+}
+
+// BattleResult records the outcome of a battle between two Pokemon
+type BattleResult struct {
+	ID               string `json:"id"`
+	Pokemon1         string `json:"pokemon1"`
+	Pokemon2         string `json:"pokemon2"`
+	Score1           int    `json:"score1"`
+	Score2           int    `json:"score2"`
+	Winner           string `json:"winner"`
+	WinnerTrainer    string `json:"winnerTrainer"`
+	LoserTrainer     string `json:"loserTrainer"`
+	WinnerExperience int    `json:"winnerExperience"`
+	LoserExperience  int    `json:"loserExperience"`
+	SeasonID         string `json:"seasonId"`
+	FoughtAt         string `json:"foughtAt"`
+}
+
+// SetTypeEffectiveness stores the damage multiplier of one type matchup. Admin only.
+func (s *SmartContract) SetTypeEffectiveness(ctx contractapi.TransactionContextInterface, attackType string, defendType string, multiplier float64) error {
+	err := requireAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	if attackType == "" || defendType == "" {
+		return fmt.Errorf("attack and defend types must not be empty")
+	}
+	if multiplier < 0 || multiplier > 4 {
+		return fmt.Errorf("multiplier must be between 0 and 4")
+	}
+
+	return putTypeEffectiveness(ctx, &TypeEffectiveness{AttackType: attackType, DefendType: defendType, Multiplier: multiplier})
+}
+
+// GetTypeEffectiveness returns the damage multiplier of a type matchup, 1 when none is stored
+func (s *SmartContract) GetTypeEffectiveness(ctx contractapi.TransactionContextInterface, attackType string, defendType string) (float64, error) {
+	return typeMultiplier(ctx, attackType, defendType)
+}
+
+// Battle resolves a battle between two Pokemon of different trainers. Each side scores its power
+// times its type effectiveness against the other, varied by up to 15% by a roll derived from the
+// transaction ID and the given seed, so every endorser reaches the same result. The winner gains
+// experience scaled by the loser's power, the loser a little, and both level up as their
+// experience allows. A Pokemon at or above the level a gym badge unlocks only battles if its
+// trainer holds that badge. A lent Pokemon battles for its borrower. While a season is open the
+// battle also counts towards the trainers' season record. Admin only.
+func (s *SmartContract) Battle(ctx contractapi.TransactionContextInterface, pokeID1 string, pokeID2 string, seed string) (*BattleResult, error) {
+	err := requireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p1, err := s.ReadPokemon(ctx, pokeID1)
+	if err != nil {
+		return nil, err
+	}
+	p2, err := s.ReadPokemon(ctx, pokeID2)
+	if err != nil {
+		return nil, err
+	}
+	trainers := make(map[string]string)
+	for _, p := range []*Pokemon{p1, p2} {
+		trainers[p.ID], err = activeTrainer(ctx, p)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if trainers[p1.ID] == trainers[p2.ID] {
+		return nil, fmt.Errorf("a trainer cannot battle themselves")
+	}
+	for _, p := range []*Pokemon{p1, p2} {
+		err = checkBattleBadges(ctx, p, trainers[p.ID])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	effect1, err := typeMultiplier(ctx, p1.Type, p2.Type)
+	if err != nil {
+		return nil, err
+	}
+	effect2, err := typeMultiplier(ctx, p2.Type, p1.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	txID := ctx.GetStub().GetTxID()
+	rng := random.New(txID, seed, p1.ID, p2.ID)
+	score1 := int(math.Round(float64(p1.Power) * effect1 * float64(rng.Between(85, 115)) / 100))
+	score2 := int(math.Round(float64(p2.Power) * effect2 * float64(rng.Between(85, 115)) / 100))
+
+	winner, loser := p1, p2
+	if score2 > score1 || (score2 == score1 && rng.Bool()) {
+		winner, loser = p2, p1
+	}
+	before := []*Pokemon{copyPokemon(p1), copyPokemon(p2)}
+	winnerExperience := 10 + loser.Power/10
+	loserExperience := 5
+	gainExperience(winner, winnerExperience)
+	gainExperience(loser, loserExperience)
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := BattleResult{
+		ID:               txID,
+		Pokemon1:         p1.ID,
+		Pokemon2:         p2.ID,
+		Score1:           score1,
+		Score2:           score2,
+		Winner:           winner.ID,
+		WinnerTrainer:    trainers[winner.ID],
+		LoserTrainer:     trainers[loser.ID],
+		WinnerExperience: winnerExperience,
+		LoserExperience:  loserExperience,
+		FoughtAt:         now.Format(time.RFC3339),
+	}
+
+	for _, p := range []*Pokemon{p1, p2} {
+		pokeJSON, err := json.Marshal(p)
+		if err != nil {
+			return nil, err
+		}
+		err = ctx.GetStub().PutState(pokemonNamespace.Key(p.ID), pokeJSON)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	batch := make(statsBatch)
+	err = batch.applyRollups(ctx, before, []*Pokemon{p1, p2})
+	if err != nil {
+		return nil, err
+	}
+	season, err := getActiveSeason(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if season != nil {
+		result.SeasonID = season.ID
+		winnerStats, err := batch.load(ctx, trainers[winner.ID])
+		if err != nil {
+			return nil, err
+		}
+		resetSeason(winnerStats, season.ID)
+		winnerStats.SeasonWins++
+		winnerStats.LifetimeWins++
+
+		loserStats, err := batch.load(ctx, trainers[loser.ID])
+		if err != nil {
+			return nil, err
+		}
+		resetSeason(loserStats, season.ID)
+		loserStats.SeasonLosses++
+		loserStats.LifetimeLosses++
+	}
+	err = batch.save(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resultKey, err := ctx.GetStub().CreateCompositeKey(battleObjectType, []string{result.ID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.GetStub().PutState(resultKey, resultJSON)
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.GetStub().SetEvent(EventBattleCompleted, resultJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set event: %v", err)
+	}
+
+	return &result, nil
+}
+
+// ReadBattleResult returns the result of the battle fought in the given transaction
+func (s *SmartContract) ReadBattleResult(ctx contractapi.TransactionContextInterface, id string) (*BattleResult, error) {
+	resultKey, err := ctx.GetStub().CreateCompositeKey(battleObjectType, []string{id})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	resultJSON, err := ctx.GetStub().GetState(resultKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if resultJSON == nil {
+		return nil, fmt.Errorf("battle %s does not exist", id)
+	}
+
+	var result BattleResult
+	err = json.Unmarshal(resultJSON, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func copyPokemon(p *Pokemon) *Pokemon {
+	c := *p
+	return &c
+}
+
+func typeMultiplier(ctx contractapi.TransactionContextInterface, attackType string, defendType string) (float64, error) {
+	effectKey, err := ctx.GetStub().CreateCompositeKey(typeEffectivenessObjectType, []string{attackType, defendType})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	effectJSON, err := ctx.GetStub().GetState(effectKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if effectJSON == nil {
+		return 1, nil
+	}
+
+	var effect TypeEffectiveness
+	err = json.Unmarshal(effectJSON, &effect)
+	if err != nil {
+		return 0, err
+	}
+
+	return effect.Multiplier, nil
+}
+
+func putTypeEffectiveness(ctx contractapi.TransactionContextInterface, effect *TypeEffectiveness) error {
+	return typeEffectivenessRepo.Put(ctx, effect, effect.AttackType, effect.DefendType)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBattle(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	effectiveness := func(attackType string, defendType string, want float64) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			multiplier, err := s.GetTypeEffectiveness(ctx, attackType, defendType)
+			require.Equal(t, want, multiplier)
+			return err
+		}
+	}
+	setEffectiveness := func(attackType string, defendType string, multiplier float64) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.SetTypeEffectiveness(ctx, attackType, defendType, multiplier)
+		}
+	}
+	battle := func(pokeID1 string, pokeID2 string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.Battle(ctx, pokeID1, pokeID2, "seed")
+			return err
+		}
+	}
+	create := func(id string, name string, ptype string, trainer string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.CreatePokemon(ctx, id, name, ptype, trainer, "Route 4", 40)
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "sample chart", Caller: ash, Run: effectiveness("Fire", "Water", 0.5)},
+		{Name: "unlisted matchups are neutral", Caller: ash, Run: effectiveness("Fire", "Rock", 1)},
+		{Name: "set effectiveness", Caller: admin, Run: setEffectiveness("Water", "Rock", 2)},
+		{Name: "set effectiveness is kept", Caller: ash, Run: effectiveness("Water", "Rock", 2)},
+		{Name: "set effectiveness requires admin", Caller: ash, Run: setEffectiveness("Water", "Rock", 2), Err: "requires role admin"},
+		{Name: "set effectiveness without a type", Caller: admin, Run: setEffectiveness("", "Rock", 2), Err: "attack and defend types must not be empty"},
+		{Name: "set effectiveness out of range", Caller: admin, Run: setEffectiveness("Water", "Rock", 5), Err: "multiplier must be between 0 and 4"},
+		{Name: "create Sandshrew", Caller: red, Run: create("poke4", "Sandshrew", "Ground", "Red")},
+		{Name: "create Bulbasaur", Caller: ash, Run: create("poke5", "Bulbasaur", "Grass", "Ash")},
+		{
+			Name:   "electric attacks do not hurt ground types",
+			Caller: admin,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				result, err := s.Battle(ctx, "poke1", "poke4", "seed")
+				require.NoError(t, err)
+				require.Equal(t, ctx.GetStub().GetTxID(), result.ID)
+				require.Zero(t, result.Score1)
+				require.Equal(t, "poke4", result.Winner)
+				require.Equal(t, "Red", result.WinnerTrainer)
+				require.Equal(t, "Ash", result.LoserTrainer)
+				require.Equal(t, 10+55/10, result.WinnerExperience)
+				require.Empty(t, result.SeasonID)
+
+				var event BattleResult
+				require.Equal(t, EventBattleCompleted, lastEvent(t, ctx, &event))
+				require.Equal(t, *result, event)
+
+				stored, err := s.ReadBattleResult(ctx, result.ID)
+				if err == nil {
+					require.Equal(t, result, stored)
+				}
+				return err
+			},
+		},
+		{
+			Name:   "both sides gain experience",
+			Caller: ash,
+			Run: readPokemon("poke1", func(t *testing.T, p *Pokemon) {
+				require.Equal(t, 5, p.Experience)
+			}),
+		},
+		{
+			Name:   "read missing battle",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.ReadBattleResult(ctx, "tx99")
+				return err
+			},
+			Err: "battle tx99 does not exist",
+		},
+		{Name: "battle requires admin", Caller: ash, Run: battle("poke1", "poke4"), Err: "requires role admin"},
+		{Name: "battle against oneself", Caller: admin, Run: battle("poke1", "poke5"), Err: "a trainer cannot battle themselves"},
+		{Name: "battle a missing Pokemon", Caller: admin, Run: battle("poke1", "poke9"), Err: "Pokemon poke9 does not exist"},
+	})
+}
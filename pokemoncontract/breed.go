@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"random"
+	"repository"
+)
+
+const (
+	breedingObjectType = "breeding"
+
+	// breedingCooldown is how long a Pokemon must rest after breeding before it can breed again
+	breedingCooldown = 24 * time.Hour
+)
+
+var breedingRepo = repository.New[BreedingRecord](breedingObjectType)
+
+// BreedingRecord tracks when a Pokemon last bred, for its breeding cooldown
+type BreedingRecord struct {
+	PokemonID   string `json:"pokemonId"`
+	LastBredAt  string `json:"lastBredAt"`
+	LastChildID string `json:"lastChildId"`
+	Offspring   int    `json:"offspring"`
+}
+
+// BreedPokemon breeds two Pokemon of the submitting trainer into a new Pokemon. The child takes the
+// type of one parent, chosen from the transaction ID, is the base form of that parent's species
+// and starts with 70 to 90% of the parents' average power. Each parent must wait breedingCooldown
+// before breeding again.
+func (s *SmartContract) BreedPokemon(ctx contractapi.TransactionContextInterface, parent1ID string, parent2ID string, childID string) (*Pokemon, error) {
+	if parent1ID == parent2ID {
+		return nil, fmt.Errorf("a Pokemon cannot breed with itself")
+	}
+	exists, err := s.PokemonExists(ctx, childID)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, fmt.Errorf("Pokemon %s already exists", childID)
+	}
+
+	caller, err := callerTrainer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p1, err := s.ReadPokemon(ctx, parent1ID)
+	if err != nil {
+		return nil, err
+	}
+	p2, err := s.ReadPokemon(ctx, parent2ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, parent := range []*Pokemon{p1, p2} {
+		if parent.Trainer != caller {
+			return nil, fmt.Errorf("Pokemon %s is not trained by %s", parent.ID, caller)
+		}
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var records []*BreedingRecord
+	for _, parent := range []*Pokemon{p1, p2} {
+		record, err := getBreedingRecord(ctx, parent.ID)
+		if err != nil {
+			return nil, err
+		}
+		if record == nil {
+			record = &BreedingRecord{PokemonID: parent.ID}
+		}
+		if record.LastBredAt != "" {
+			lastBredAt, err := time.Parse(time.RFC3339, record.LastBredAt)
+			if err != nil {
+				return nil, fmt.Errorf("Pokemon %s has an invalid breeding date: %v", parent.ID, err)
+			}
+			if readyAt := lastBredAt.Add(breedingCooldown); now.Before(readyAt) {
+				return nil, fmt.Errorf("Pokemon %s can breed again at %s", parent.ID, readyAt.Format(time.RFC3339))
+			}
+		}
+		records = append(records, record)
+	}
+
+	rng := random.New(ctx.GetStub().GetTxID(), p1.ID, p2.ID, childID)
+	inherited := p1
+	if rng.Bool() {
+		inherited = p2
+	}
+	species, err := baseSpecies(ctx, inherited.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	child := Pokemon{
+		ID:       childID,
+		Name:     species,
+		Type:     inherited.Type,
+		Power:    (p1.Power + p2.Power) / 2 * rng.Between(70, 90) / 100,
+		Trainer:  caller,
+		Location: p1.Location,
+
+		Level:          1,
+		EvolutionStage: 1,
+	}
+	childJSON, err := json.Marshal(child)
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.GetStub().PutState(pokemonNamespace.Key(childID), childJSON)
+	if err != nil {
+		return nil, err
+	}
+	err = updateRollups(ctx, nil, []*Pokemon{&child})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, record := range records {
+		record.LastBredAt = now.Format(time.RFC3339)
+		record.LastChildID = childID
+		record.Offspring++
+		err = putBreedingRecord(ctx, record)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &child, nil
+}
+
+// GetBreedingRecord returns when a Pokemon last bred
+func (s *SmartContract) GetBreedingRecord(ctx contractapi.TransactionContextInterface, id string) (*BreedingRecord, error) {
+	record, err := getBreedingRecord(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, fmt.Errorf("Pokemon %s has never bred", id)
+	}
+
+	return record, nil
+}
+
+// baseSpecies returns the first form of a species' evolution chain, walking the species registry
+// backwards. Species that are not registered are their own base form.
+func baseSpecies(ctx contractapi.TransactionContextInterface, name string) (string, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(speciesObjectType, []string{})
+	if err != nil {
+		return "", err
+	}
+	defer resultsIterator.Close()
+
+	evolvesFrom := make(map[string]string)
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return "", err
+		}
+
+		var species Species
+		err = json.Unmarshal(queryResponse.Value, &species)
+		if err != nil {
+			return "", err
+		}
+		if species.EvolvesTo != "" {
+			evolvesFrom[species.EvolvesTo] = species.Name
+		}
+	}
+
+	// RegisterSpecies rejects cycles, the bound only guards against records written before it did
+	for steps := 0; evolvesFrom[name] != "" && steps < 100; steps++ {
+		name = evolvesFrom[name]
+	}
+
+	return name, nil
+}
+
+func getBreedingRecord(ctx contractapi.TransactionContextInterface, id string) (*BreedingRecord, error) {
+	return breedingRepo.Get(ctx, id)
+}
+
+func putBreedingRecord(ctx contractapi.TransactionContextInterface, record *BreedingRecord) error {
+	return breedingRepo.Put(ctx, record, record.PokemonID)
+}
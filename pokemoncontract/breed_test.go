@@ -0,0 +1,87 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBreeding(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	breed := func(parent1ID string, parent2ID string, childID string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.BreedPokemon(ctx, parent1ID, parent2ID, childID)
+			return err
+		}
+	}
+	record := func(id string, offspring int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			record, err := s.GetBreedingRecord(ctx, id)
+			if err == nil {
+				require.Equal(t, offspring, record.Offspring)
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{
+			Name:   "create Raichu",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.CreatePokemon(ctx, "poke4", "Raichu", "Electric", "Ash", "Viridian City", 70)
+			},
+		},
+		{
+			Name:   "breed",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				child, err := s.BreedPokemon(ctx, "poke1", "poke4", "egg1")
+				if err == nil {
+					require.Equal(t, "Pikachu", child.Name)
+					require.Equal(t, "Electric", child.Type)
+					require.Equal(t, "Ash", child.Trainer)
+					require.Equal(t, "Pallet Town", child.Location)
+					require.GreaterOrEqual(t, child.Power, (55+70)/2*70/100)
+					require.LessOrEqual(t, child.Power, (55+70)/2*90/100)
+				}
+				return err
+			},
+		},
+		{
+			Name:   "children are stored",
+			Caller: ash,
+			Run: readPokemon("egg1", func(t *testing.T, p *Pokemon) {
+				require.Equal(t, 1, p.Level)
+			}),
+		},
+		{
+			Name:   "parents record their child",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				record, err := s.GetBreedingRecord(ctx, "poke4")
+				if err == nil {
+					require.Equal(t, "egg1", record.LastChildID)
+					require.Equal(t, 1, record.Offspring)
+				}
+				return err
+			},
+		},
+		{Name: "never bred", Caller: ash, Run: record("poke2", 0), Err: "Pokemon poke2 has never bred"},
+		{Name: "breed during the cooldown", Caller: ash, Run: breed("poke1", "poke4", "egg2"), Err: "Pokemon poke1 can breed again at 2024-01-02T09:00:03Z"},
+		{Name: "breed with itself", Caller: ash, Run: breed("poke1", "poke1", "egg2"), Err: "a Pokemon cannot breed with itself"},
+		{Name: "breed into an existing Pokemon", Caller: ash, Run: breed("poke1", "poke4", "poke2"), Err: "Pokemon poke2 already exists"},
+		{Name: "breed another trainer's Pokemon", Caller: ash, Run: breed("poke1", "poke2", "egg2"), Err: "Pokemon poke2 is not trained by Ash"},
+		{Name: "breed a missing Pokemon", Caller: ash, Run: breed("poke1", "poke9", "egg2"), Err: "Pokemon poke9 does not exist"},
+	})
+
+	ledger.Advance(breedingCooldown)
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "breed after the cooldown", Caller: ash, Run: breed("poke4", "poke1", "egg2")},
+		{Name: "offspring are counted", Caller: ash, Run: record("poke1", 2)},
+	})
+}
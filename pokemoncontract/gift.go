@@ -0,0 +1,273 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"authz"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+const (
+	giftObjectType           = "gift"
+	giftCountObjectType      = "giftcount"
+	giftProvenanceObjectType = "giftprovenance"
+
+	// maxGiftsPerDay is how many gifts a trainer may send per UTC day
+	maxGiftsPerDay = 3
+
+	// maxGiftMessageLength bounds the message attached to a gift
+	maxGiftMessageLength = 280
+)
+
+var (
+	giftRepo           = repository.New[Gift](giftObjectType)
+	giftProvenanceRepo = repository.New[GiftProvenance](giftProvenanceObjectType)
+)
+
+// Gift is a pending transfer of a Pokemon to another trainer, awaiting the recipient's acceptance
+type Gift struct {
+	PokemonID string `json:"pokemonId"`
+	From      string `json:"from"`
+	Recipient string `json:"recipient"`
+	Message   string `json:"message"`
+	GiftedAt  string `json:"giftedAt"`
+}
+
+// GiftProvenance records a completed gift in a Pokemon's ownership history
+type GiftProvenance struct {
+	PokemonID  string `json:"pokemonId"`
+	From       string `json:"from"`
+	To         string `json:"to"`
+	Message    string `json:"message"`
+	GiftedAt   string `json:"giftedAt"`
+	AcceptedAt string `json:"acceptedAt"`
+	TxID       string `json:"txId"`
+}
+
+// GiftPokemon offers a Pokemon to another trainer with a message. Unlike a trade there is no
+// payment; the Pokemon moves only once the recipient accepts. The submitting client's certificate
+// common name must match the Pokemon's trainer.
+func (s *SmartContract) GiftPokemon(ctx contractapi.TransactionContextInterface, id string, recipient string, message string) error {
+	p, err := s.ReadPokemon(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = requireTrainer(ctx, p.Trainer)
+	if err != nil {
+		return err
+	}
+	err = checkNotLent(ctx, id)
+	if err != nil {
+		return err
+	}
+	if recipient == "" || recipient == p.Trainer {
+		return fmt.Errorf("recipient must be another trainer")
+	}
+	if len(message) > maxGiftMessageLength {
+		return fmt.Errorf("gift message must be at most %d characters", maxGiftMessageLength)
+	}
+
+	pending, err := getGift(ctx, id)
+	if err != nil {
+		return err
+	}
+	if pending != nil {
+		return fmt.Errorf("Pokemon %s already has a pending gift to %s", id, pending.Recipient)
+	}
+	transfer, err := getTransfer(ctx, id)
+	if err != nil {
+		return err
+	}
+	if transfer != nil {
+		return fmt.Errorf("Pokemon %s has a pending transfer to %s", id, transfer.NewTrainer)
+	}
+	listing, err := getListing(ctx, id)
+	if err != nil {
+		return err
+	}
+	if listing != nil {
+		return fmt.Errorf("Pokemon %s is listed for sale, cancel the listing first", id)
+	}
+	trade, err := getPrivateTrade(ctx, id)
+	if err != nil {
+		return err
+	}
+	if trade != nil {
+		return fmt.Errorf("Pokemon %s has a pending private trade with %s", id, trade.Buyer)
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	countKey, err := ctx.GetStub().CreateCompositeKey(giftCountObjectType, []string{p.Trainer, now.Format("2006-01-02")})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	countJSON, err := ctx.GetStub().GetState(countKey)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	count := 0
+	if countJSON != nil {
+		err = json.Unmarshal(countJSON, &count)
+		if err != nil {
+			return err
+		}
+	}
+	if count >= maxGiftsPerDay {
+		return fmt.Errorf("trainer %s has reached the limit of %d gifts per day", p.Trainer, maxGiftsPerDay)
+	}
+	countJSON, err = json.Marshal(count + 1)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().PutState(countKey, countJSON)
+	if err != nil {
+		return err
+	}
+
+	gift := Gift{
+		PokemonID: id,
+		From:      p.Trainer,
+		Recipient: recipient,
+		Message:   message,
+		GiftedAt:  now.Format(time.RFC3339),
+	}
+	return giftRepo.Put(ctx, &gift, id)
+}
+
+// AcceptGift transfers a gifted Pokemon to the recipient and records the gift in its provenance.
+// Only the recipient may accept.
+func (s *SmartContract) AcceptGift(ctx contractapi.TransactionContextInterface, id string) error {
+	gift, err := s.ReadGift(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = requireTrainer(ctx, gift.Recipient)
+	if err != nil {
+		return err
+	}
+
+	p, err := s.ReadPokemon(ctx, id)
+	if err != nil {
+		return err
+	}
+	if p.Trainer != gift.From {
+		return fmt.Errorf("Pokemon %s is no longer trained by %s", id, gift.From)
+	}
+	before := *p
+	p.Trainer = gift.Recipient
+	pokeJSON, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().PutState(pokemonNamespace.Key(id), pokeJSON)
+	if err != nil {
+		return err
+	}
+	err = updateRollups(ctx, []*Pokemon{&before}, []*Pokemon{p})
+	if err != nil {
+		return err
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	provenance := GiftProvenance{
+		PokemonID:  id,
+		From:       gift.From,
+		To:         gift.Recipient,
+		Message:    gift.Message,
+		GiftedAt:   gift.GiftedAt,
+		AcceptedAt: now.Format(time.RFC3339),
+		TxID:       ctx.GetStub().GetTxID(),
+	}
+	err = giftProvenanceRepo.Put(ctx, &provenance, id, provenance.AcceptedAt, provenance.TxID)
+	if err != nil {
+		return err
+	}
+
+	return deleteGift(ctx, id)
+}
+
+// DeclineGift withdraws a pending gift. Either the giver or the recipient may decline it.
+func (s *SmartContract) DeclineGift(ctx contractapi.TransactionContextInterface, id string) error {
+	gift, err := s.ReadGift(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = requireTrainer(ctx, gift.From, gift.Recipient)
+	if err != nil {
+		return err
+	}
+
+	return deleteGift(ctx, id)
+}
+
+// ReadGift returns the pending gift of a Pokemon
+func (s *SmartContract) ReadGift(ctx contractapi.TransactionContextInterface, id string) (*Gift, error) {
+	gift, err := getGift(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if gift == nil {
+		return nil, fmt.Errorf("Pokemon %s has no pending gift", id)
+	}
+
+	return gift, nil
+}
+
+// GetGiftProvenance returns the accepted gifts of a Pokemon, oldest first
+func (s *SmartContract) GetGiftProvenance(ctx contractapi.TransactionContextInterface, id string) ([]*GiftProvenance, error) {
+	return giftProvenanceRepo.List(ctx, id)
+}
+
+func getGift(ctx contractapi.TransactionContextInterface, id string) (*Gift, error) {
+	return giftRepo.Get(ctx, id)
+}
+
+func deleteGift(ctx contractapi.TransactionContextInterface, id string) error {
+	giftKey, err := ctx.GetStub().CreateCompositeKey(giftObjectType, []string{id})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	return ctx.GetStub().DelState(giftKey)
+}
+
+// requireTrainer returns an error unless the submitting client is one of the given trainers
+func requireTrainer(ctx contractapi.TransactionContextInterface, trainers ...string) error {
+	caller, err := callerTrainer(ctx)
+	if err != nil {
+		return err
+	}
+	for _, trainer := range trainers {
+		if caller == trainer {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("submitting client %s is not authorized for this Pokemon", caller)
+}
+
+// callerTrainer returns the trainer name of the submitting client: its trainer attribute when the
+// CA issued one, otherwise its enrollment ID, otherwise its certificate common name
+func callerTrainer(ctx contractapi.TransactionContextInterface) (string, error) {
+	client, err := authz.ClientOf(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, attribute := range []string{"trainer", "hf.EnrollmentID"} {
+		value, _ := client.Attribute(attribute)
+		if value != "" {
+			return value, nil
+		}
+	}
+
+	return client.CommonName, nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGifts(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	gift := func(id string, recipient string, message string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.GiftPokemon(ctx, id, recipient, message)
+		}
+	}
+	accept := func(id string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.AcceptGift(ctx, id)
+		}
+	}
+	decline := func(id string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.DeclineGift(ctx, id)
+		}
+	}
+	readGift := func(id string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			gift, err := s.ReadGift(ctx, id)
+			if err == nil {
+				require.Equal(t, "Ash", gift.From)
+				require.Equal(t, "Misty", gift.Recipient)
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{
+			Name:   "create Bulbasaur",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.CreatePokemon(ctx, "poke4", "Bulbasaur", "Grass", "Ash", "Pallet Town", 45)
+			},
+		},
+		{Name: "gift another trainer's Pokemon", Caller: red, Run: gift("poke1", "Misty", ""), Err: "submitting client Red is not authorized for this Pokemon"},
+		{Name: "gift to oneself", Caller: ash, Run: gift("poke1", "Ash", ""), Err: "recipient must be another trainer"},
+		{Name: "gift with a long message", Caller: ash, Run: gift("poke1", "Misty", strings.Repeat("a", 281)), Err: "gift message must be at most 280 characters"},
+		{Name: "gift a missing Pokemon", Caller: ash, Run: gift("poke9", "Misty", ""), Err: "Pokemon poke9 does not exist"},
+		{Name: "gift", Caller: ash, Run: gift("poke1", "Misty", "Take good care of Pikachu")},
+		{Name: "read gift", Caller: misty, Run: readGift("poke1")},
+		{Name: "gift twice", Caller: ash, Run: gift("poke1", "Red", ""), Err: "Pokemon poke1 already has a pending gift to Misty"},
+		{Name: "accept someone else's gift", Caller: red, Run: accept("poke1"), Err: "submitting client Red is not authorized for this Pokemon"},
+		{Name: "decline someone else's gift", Caller: red, Run: decline("poke1"), Err: "submitting client Red is not authorized for this Pokemon"},
+		{Name: "accept gift", Caller: misty, Run: accept("poke1")},
+		{
+			Name:   "accepted gifts change the trainer",
+			Caller: misty,
+			Run: readPokemon("poke1", func(t *testing.T, p *Pokemon) {
+				require.Equal(t, "Misty", p.Trainer)
+			}),
+		},
+		{
+			Name:   "accepted gifts are kept in the provenance",
+			Caller: misty,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				provenance, err := s.GetGiftProvenance(ctx, "poke1")
+				if err == nil {
+					require.Len(t, provenance, 1)
+					require.Equal(t, "Take good care of Pikachu", provenance[0].Message)
+					require.Equal(t, "Misty", provenance[0].To)
+				}
+				return err
+			},
+		},
+		{Name: "accepted gifts are no longer pending", Caller: misty, Run: readGift("poke1"), Err: "Pokemon poke1 has no pending gift"},
+		{Name: "accept a gift that is not pending", Caller: misty, Run: accept("poke1"), Err: "Pokemon poke1 has no pending gift"},
+		{Name: "second gift of the day", Caller: ash, Run: gift("poke4", "Misty", "")},
+		{Name: "recipients decline gifts", Caller: misty, Run: decline("poke4")},
+		{Name: "third gift of the day", Caller: ash, Run: gift("poke4", "Misty", "")},
+		{Name: "givers decline gifts", Caller: ash, Run: decline("poke4")},
+		{Name: "fourth gift of the day", Caller: ash, Run: gift("poke4", "Misty", ""), Err: "trainer Ash has reached the limit of 3 gifts per day"},
+	})
+
+	ledger.Advance(24 * time.Hour)
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "gift the next day", Caller: ash, Run: gift("poke4", "Misty", "")},
+	})
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+const (
+	itemObjectType   = "item"
+	trainerItemIndex = "trainer~item"
+
+	// itemEffectHeal restores the item's potency in power
+	itemEffectHeal = "heal"
+	// itemEffectEvolve evolves a Pokemon regardless of its species' minimum power
+	itemEffectEvolve = "evolve"
+)
+
+var itemRepo = repository.New[Item](itemObjectType)
+
+// Item is a consumable held in a trainer's inventory, such as a potion or an evolution stone. It
+// is used up when a trainer uses it on one of their Pokemon.
+type Item struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Effect      string `json:"effect"`
+	Potency     int    `json:"potency"`
+	PokemonType string `json:"pokemonType"` // the only type the item works on, any type when empty
+	Holder      string `json:"holder"`
+	MintedAt    string `json:"mintedAt"`
+}
+
+// MintItem creates an item in a trainer's inventory. effect is heal, which restores potency
+// power since Pokemon carry no hit points, or evolve, which evolves a Pokemon whatever its power.
+// pokemonType restricts the item to Pokemon of one type, as for evolution stones. Admin only.
+func (s *SmartContract) MintItem(ctx contractapi.TransactionContextInterface, id string, name string, effect string, potency int, pokemonType string, trainer string) error {
+	err := requireAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	if name == "" || trainer == "" {
+		return fmt.Errorf("item name and trainer must not be empty")
+	}
+	switch effect {
+	case itemEffectHeal:
+		if potency <= 0 {
+			return fmt.Errorf("a healing item must have a positive potency")
+		}
+	case itemEffectEvolve:
+	default:
+		return fmt.Errorf("unknown item effect %q, expected %s or %s", effect, itemEffectHeal, itemEffectEvolve)
+	}
+
+	existing, err := getItem(ctx, id)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("item %s already exists", id)
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	item := Item{
+		ID:          id,
+		Name:        name,
+		Effect:      effect,
+		Potency:     potency,
+		PokemonType: pokemonType,
+		Holder:      trainer,
+		MintedAt:    now.Format(time.RFC3339),
+	}
+	err = putItem(ctx, &item)
+	if err != nil {
+		return err
+	}
+
+	return putTrainerItemIndex(ctx, &item)
+}
+
+// GiveItemToTrainer moves an item from the submitting trainer's inventory to another trainer's
+func (s *SmartContract) GiveItemToTrainer(ctx contractapi.TransactionContextInterface, itemID string, trainer string) error {
+	item, err := s.ReadItem(ctx, itemID)
+	if err != nil {
+		return err
+	}
+	err = requireTrainer(ctx, item.Holder)
+	if err != nil {
+		return err
+	}
+	if trainer == "" {
+		return fmt.Errorf("trainer must not be empty")
+	}
+	if trainer == item.Holder {
+		return fmt.Errorf("item %s is already held by %s", itemID, trainer)
+	}
+
+	err = delTrainerItemIndex(ctx, item)
+	if err != nil {
+		return err
+	}
+	item.Holder = trainer
+	err = putItem(ctx, item)
+	if err != nil {
+		return err
+	}
+
+	return putTrainerItemIndex(ctx, item)
+}
+
+// UseItem uses up an item of the submitting trainer on one of their Pokemon and returns the
+// Pokemon with the item's effect applied
+func (s *SmartContract) UseItem(ctx contractapi.TransactionContextInterface, pokemonID string, itemID string) (*Pokemon, error) {
+	item, err := s.ReadItem(ctx, itemID)
+	if err != nil {
+		return nil, err
+	}
+	err = requireTrainer(ctx, item.Holder)
+	if err != nil {
+		return nil, err
+	}
+	p, err := s.ReadPokemon(ctx, pokemonID)
+	if err != nil {
+		return nil, err
+	}
+	if p.Trainer != item.Holder {
+		return nil, fmt.Errorf("Pokemon %s is not trained by %s", pokemonID, item.Holder)
+	}
+	if item.PokemonType != "" && p.Type != item.PokemonType {
+		return nil, fmt.Errorf("%s only works on %s Pokemon, %s is %s", item.Name, item.PokemonType, pokemonID, p.Type)
+	}
+	before := *p
+
+	switch item.Effect {
+	case itemEffectHeal:
+		p.Power = addPower(p.Power, item.Potency)
+	case itemEffectEvolve:
+		err = evolve(ctx, p, true)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("item %s has unknown effect %q", itemID, item.Effect)
+	}
+
+	pokeJSON, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.GetStub().PutState(pokemonNamespace.Key(pokemonID), pokeJSON)
+	if err != nil {
+		return nil, err
+	}
+	err = updateRollups(ctx, []*Pokemon{&before}, []*Pokemon{p})
+	if err != nil {
+		return nil, err
+	}
+
+	err = delTrainerItemIndex(ctx, item)
+	if err != nil {
+		return nil, err
+	}
+	itemKey, err := ctx.GetStub().CreateCompositeKey(itemObjectType, []string{itemID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().DelState(itemKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// ReadItem returns an item
+func (s *SmartContract) ReadItem(ctx contractapi.TransactionContextInterface, id string) (*Item, error) {
+	item, err := getItem(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return nil, fmt.Errorf("item %s does not exist", id)
+	}
+
+	return item, nil
+}
+
+// GetItemsByTrainer returns a trainer's inventory, using the trainer item index
+func (s *SmartContract) GetItemsByTrainer(ctx contractapi.TransactionContextInterface, trainer string) ([]*Item, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(trainerItemIndex, []string{trainer})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var items []*Item
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, err
+		}
+		item, err := s.ReadItem(ctx, keyParts[1])
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// putTrainerItemIndex records the trainer~item index entry of an item.
+// Only the key is needed, so a single null byte is stored as the value.
+func putTrainerItemIndex(ctx contractapi.TransactionContextInterface, item *Item) error {
+	indexKey, err := ctx.GetStub().CreateCompositeKey(trainerItemIndex, []string{item.Holder, item.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	return ctx.GetStub().PutState(indexKey, []byte{0x00})
+}
+
+func delTrainerItemIndex(ctx contractapi.TransactionContextInterface, item *Item) error {
+	indexKey, err := ctx.GetStub().CreateCompositeKey(trainerItemIndex, []string{item.Holder, item.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	return ctx.GetStub().DelState(indexKey)
+}
+
+func getItem(ctx contractapi.TransactionContextInterface, id string) (*Item, error) {
+	return itemRepo.Get(ctx, id)
+}
+
+func putItem(ctx contractapi.TransactionContextInterface, item *Item) error {
+	return itemRepo.Put(ctx, item, item.ID)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestItems(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	mint := func(id string, name string, effect string, potency int, pokemonType string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.MintItem(ctx, id, name, effect, potency, pokemonType, "Ash")
+		}
+	}
+	give := func(id string, trainer string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.GiveItemToTrainer(ctx, id, trainer)
+		}
+	}
+	use := func(pokemonID string, itemID string, check func(t *testing.T, p *Pokemon)) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			p, err := s.UseItem(ctx, pokemonID, itemID)
+			if err == nil && check != nil {
+				check(t, p)
+			}
+			return err
+		}
+	}
+	inventory := func(trainer string, want ...string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			items, err := s.GetItemsByTrainer(ctx, trainer)
+			if err == nil {
+				var ids []string
+				for _, item := range items {
+					ids = append(ids, item.ID)
+				}
+				require.Equal(t, want, ids)
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "mint potion", Caller: admin, Run: mint("potion", "Potion", itemEffectHeal, 20, "")},
+		{Name: "mint thunder stone", Caller: admin, Run: mint("thunderstone", "Thunder Stone", itemEffectEvolve, 0, "Electric")},
+		{Name: "mint fire stone", Caller: admin, Run: mint("firestone", "Fire Stone", itemEffectEvolve, 0, "Fire")},
+		{Name: "mint requires admin", Caller: ash, Run: mint("potion2", "Potion", itemEffectHeal, 20, ""), Err: "requires role admin"},
+		{Name: "mint without a name", Caller: admin, Run: mint("potion2", "", itemEffectHeal, 20, ""), Err: "item name and trainer must not be empty"},
+		{Name: "mint a powerless potion", Caller: admin, Run: mint("potion2", "Potion", itemEffectHeal, 0, ""), Err: "a healing item must have a positive potency"},
+		{Name: "mint an unknown effect", Caller: admin, Run: mint("potion2", "Potion", "teleport", 20, ""), Err: `unknown item effect "teleport", expected heal or evolve`},
+		{Name: "mint duplicate", Caller: admin, Run: mint("potion", "Potion", itemEffectHeal, 20, ""), Err: "item potion already exists"},
+		{Name: "inventory", Caller: ash, Run: inventory("Ash", "firestone", "potion", "thunderstone")},
+		{
+			Name:   "heal",
+			Caller: ash,
+			Run: use("poke1", "potion", func(t *testing.T, p *Pokemon) {
+				require.Equal(t, 75, p.Power)
+			}),
+		},
+		{
+			Name:   "used items are gone",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.ReadItem(ctx, "potion")
+				return err
+			},
+			Err: "item potion does not exist",
+		},
+		{Name: "use another trainer's item", Caller: red, Run: use("poke2", "thunderstone", nil), Err: "submitting client Red is not authorized for this Pokemon"},
+		{Name: "use on another trainer's Pokemon", Caller: ash, Run: use("poke2", "thunderstone", nil), Err: "Pokemon poke2 is not trained by Ash"},
+		{Name: "use on the wrong type", Caller: ash, Run: use("poke1", "firestone", nil), Err: "Fire Stone only works on Fire Pokemon, poke1 is Electric"},
+		{
+			Name:   "evolution stones skip the minimum power",
+			Caller: ash,
+			Run: use("poke1", "thunderstone", func(t *testing.T, p *Pokemon) {
+				require.Equal(t, "Raichu", p.Name)
+				require.Equal(t, 2, p.EvolutionStage)
+			}),
+		},
+		{Name: "give to oneself", Caller: ash, Run: give("firestone", "Ash"), Err: "item firestone is already held by Ash"},
+		{Name: "give to nobody", Caller: ash, Run: give("firestone", ""), Err: "trainer must not be empty"},
+		{Name: "give someone else's item", Caller: red, Run: give("firestone", "Red"), Err: "submitting client Red is not authorized for this Pokemon"},
+		{Name: "give a missing item", Caller: ash, Run: give("potion", "Red"), Err: "item potion does not exist"},
+		{Name: "give", Caller: ash, Run: give("firestone", "Red")},
+		{Name: "given items leave the inventory", Caller: ash, Run: inventory("Ash")},
+		{Name: "given items join the recipient's inventory", Caller: red, Run: inventory("Red", "firestone")},
+		{
+			Name:   "recipients use given items",
+			Caller: red,
+			Run: use("poke2", "firestone", func(t *testing.T, p *Pokemon) {
+				require.Equal(t, "Charmeleon", p.Name)
+			}),
+		},
+	})
+}
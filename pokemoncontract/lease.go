@@ -0,0 +1,302 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+const (
+	leaseObjectType   = "lease"
+	trainerLeaseIndex = "trainer~lease"
+)
+
+var leaseRepo = repository.New[Lease](leaseObjectType)
+
+// Lease lends a Pokemon to another trainer until a point in time. While the lease runs the
+// borrower battles with the Pokemon and holds its transfer rights; the owner stays its trainer.
+type Lease struct {
+	PokemonID string `json:"pokemonId"`
+	Owner     string `json:"owner"`
+	Borrower  string `json:"borrower"`
+	Until     string `json:"until"` // RFC 3339, compared with the transaction timestamp
+	LentAt    string `json:"lentAt"`
+}
+
+// LendPokemon lends a Pokemon of the submitting trainer to a borrower until the given RFC 3339
+// time. A Pokemon with a pending gift, transfer, listing or private trade cannot be lent.
+func (s *SmartContract) LendPokemon(ctx contractapi.TransactionContextInterface, id string, borrower string, until string) (*Lease, error) {
+	p, err := s.ReadPokemon(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	err = requireTrainer(ctx, p.Trainer)
+	if err != nil {
+		return nil, err
+	}
+	if borrower == "" || borrower == p.Trainer {
+		return nil, fmt.Errorf("borrower must be another trainer")
+	}
+	untilTime, err := time.Parse(time.RFC3339, until)
+	if err != nil {
+		return nil, fmt.Errorf("invalid lease end %q, expected RFC 3339", until)
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !untilTime.After(now) {
+		return nil, fmt.Errorf("the lease end %s has already passed", until)
+	}
+
+	lease, err := getLease(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if lease != nil && leaseActive(lease, now) {
+		return nil, fmt.Errorf("Pokemon %s is lent to %s until %s", id, lease.Borrower, lease.Until)
+	}
+	err = checkNoPendingHandover(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if lease != nil {
+		// an expired lease that was never reclaimed
+		err = deleteLease(ctx, lease)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	lease = &Lease{
+		PokemonID: id,
+		Owner:     p.Trainer,
+		Borrower:  borrower,
+		Until:     untilTime.UTC().Format(time.RFC3339),
+		LentAt:    now.Format(time.RFC3339),
+	}
+	err = putLease(ctx, lease)
+	if err != nil {
+		return nil, err
+	}
+
+	return lease, nil
+}
+
+// ReclaimPokemon ends the lease of a Pokemon. The owner may reclaim it once the lease has
+// expired; the borrower may return it at any time.
+func (s *SmartContract) ReclaimPokemon(ctx contractapi.TransactionContextInterface, id string) error {
+	lease, err := s.ReadLease(ctx, id)
+	if err != nil {
+		return err
+	}
+	caller, err := callerTrainer(ctx)
+	if err != nil {
+		return err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	switch caller {
+	case lease.Borrower:
+	case lease.Owner:
+		if leaseActive(lease, now) {
+			return fmt.Errorf("Pokemon %s is lent to %s until %s", id, lease.Borrower, lease.Until)
+		}
+	default:
+		return fmt.Errorf("submitting client %s is not authorized for this Pokemon", caller)
+	}
+
+	return deleteLease(ctx, lease)
+}
+
+// ReadLease returns the lease of a Pokemon, whether it is running or expired but not reclaimed
+func (s *SmartContract) ReadLease(ctx contractapi.TransactionContextInterface, id string) (*Lease, error) {
+	lease, err := getLease(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if lease == nil {
+		return nil, fmt.Errorf("Pokemon %s is not lent", id)
+	}
+
+	return lease, nil
+}
+
+// GetLeasesByTrainer returns the leases a trainer is the owner or borrower of
+func (s *SmartContract) GetLeasesByTrainer(ctx contractapi.TransactionContextInterface, trainer string) ([]*Lease, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(trainerLeaseIndex, []string{trainer})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var leases []*Lease
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, err
+		}
+		lease, err := s.ReadLease(ctx, keyParts[1])
+		if err != nil {
+			return nil, err
+		}
+		leases = append(leases, lease)
+	}
+
+	return leases, nil
+}
+
+// activeTrainer returns the trainer currently in charge of a Pokemon: the borrower while a lease
+// runs, otherwise its own trainer
+func activeTrainer(ctx contractapi.TransactionContextInterface, p *Pokemon) (string, error) {
+	lease, err := getLease(ctx, p.ID)
+	if err != nil {
+		return "", err
+	}
+	if lease == nil {
+		return p.Trainer, nil
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return "", err
+	}
+	if !leaseActive(lease, now) {
+		return p.Trainer, nil
+	}
+
+	return lease.Borrower, nil
+}
+
+// checkNotLent returns an error while a lease of the Pokemon runs
+func checkNotLent(ctx contractapi.TransactionContextInterface, id string) error {
+	lease, err := getLease(ctx, id)
+	if err != nil {
+		return err
+	}
+	if lease == nil {
+		return nil
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	if leaseActive(lease, now) {
+		return fmt.Errorf("Pokemon %s is lent to %s until %s", id, lease.Borrower, lease.Until)
+	}
+
+	return nil
+}
+
+// checkNoPendingHandover returns an error if a gift, transfer, listing or private trade of the
+// Pokemon is pending
+func checkNoPendingHandover(ctx contractapi.TransactionContextInterface, id string) error {
+	gift, err := getGift(ctx, id)
+	if err != nil {
+		return err
+	}
+	if gift != nil {
+		return fmt.Errorf("Pokemon %s has a pending gift to %s", id, gift.Recipient)
+	}
+	transfer, err := getTransfer(ctx, id)
+	if err != nil {
+		return err
+	}
+	if transfer != nil {
+		return fmt.Errorf("Pokemon %s has a pending transfer to %s", id, transfer.NewTrainer)
+	}
+	listing, err := getListing(ctx, id)
+	if err != nil {
+		return err
+	}
+	if listing != nil {
+		return fmt.Errorf("Pokemon %s is listed for sale, cancel the listing first", id)
+	}
+	trade, err := getPrivateTrade(ctx, id)
+	if err != nil {
+		return err
+	}
+	if trade != nil {
+		return fmt.Errorf("Pokemon %s has a pending private trade with %s", id, trade.Buyer)
+	}
+
+	return nil
+}
+
+// leaseActive reports whether a lease still runs at the given time
+func leaseActive(lease *Lease, now time.Time) bool {
+	until, err := time.Parse(time.RFC3339, lease.Until)
+	if err != nil {
+		return false
+	}
+
+	return now.Before(until)
+}
+
+func getLease(ctx contractapi.TransactionContextInterface, id string) (*Lease, error) {
+	return leaseRepo.Get(ctx, id)
+}
+
+// putLease stores a lease with a trainer~lease index entry for each party.
+// Only the index keys are needed, so a single null byte is stored as their value.
+func putLease(ctx contractapi.TransactionContextInterface, lease *Lease) error {
+	leaseKey, err := ctx.GetStub().CreateCompositeKey(leaseObjectType, []string{lease.PokemonID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	leaseJSON, err := json.Marshal(lease)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().PutState(leaseKey, leaseJSON)
+	if err != nil {
+		return err
+	}
+
+	for _, trainer := range []string{lease.Owner, lease.Borrower} {
+		indexKey, err := ctx.GetStub().CreateCompositeKey(trainerLeaseIndex, []string{trainer, lease.PokemonID})
+		if err != nil {
+			return fmt.Errorf("failed to create composite key: %v", err)
+		}
+		err = ctx.GetStub().PutState(indexKey, []byte{0x00})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func deleteLease(ctx contractapi.TransactionContextInterface, lease *Lease) error {
+	leaseKey, err := ctx.GetStub().CreateCompositeKey(leaseObjectType, []string{lease.PokemonID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().DelState(leaseKey)
+	if err != nil {
+		return err
+	}
+
+	for _, trainer := range []string{lease.Owner, lease.Borrower} {
+		indexKey, err := ctx.GetStub().CreateCompositeKey(trainerLeaseIndex, []string{trainer, lease.PokemonID})
+		if err != nil {
+			return fmt.Errorf("failed to create composite key: %v", err)
+		}
+		err = ctx.GetStub().DelState(indexKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
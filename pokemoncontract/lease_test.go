@@ -0,0 +1,109 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeases(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	lend := func(id string, borrower string, until string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.LendPokemon(ctx, id, borrower, until)
+			return err
+		}
+	}
+	reclaim := func(id string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.ReclaimPokemon(ctx, id)
+		}
+	}
+	leases := func(trainer string, want ...string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			leases, err := s.GetLeasesByTrainer(ctx, trainer)
+			if err == nil {
+				var ids []string
+				for _, lease := range leases {
+					ids = append(ids, lease.PokemonID)
+				}
+				require.Equal(t, want, ids)
+			}
+			return err
+		}
+	}
+	readLease := func(id string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			lease, err := s.ReadLease(ctx, id)
+			if err == nil {
+				require.Equal(t, "Ash", lease.Owner)
+				require.Equal(t, "Misty", lease.Borrower)
+				require.Equal(t, "2024-01-02T09:00:00Z", lease.Until)
+			}
+			return err
+		}
+	}
+	const tomorrow = "2024-01-02T14:00:00+05:00"
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "lend another trainer's Pokemon", Caller: red, Run: lend("poke1", "Red", tomorrow), Err: "submitting client Red is not authorized for this Pokemon"},
+		{Name: "lend to oneself", Caller: ash, Run: lend("poke1", "Ash", tomorrow), Err: "borrower must be another trainer"},
+		{Name: "lend until a malformed time", Caller: ash, Run: lend("poke1", "Misty", "tomorrow"), Err: `invalid lease end "tomorrow", expected RFC 3339`},
+		{Name: "lend until a past time", Caller: ash, Run: lend("poke1", "Misty", "2023-12-31T00:00:00Z"), Err: "the lease end 2023-12-31T00:00:00Z has already passed"},
+		{Name: "lend", Caller: ash, Run: lend("poke1", "Misty", tomorrow)},
+		{Name: "lease ends are kept in UTC", Caller: misty, Run: readLease("poke1")},
+		{Name: "lend twice", Caller: ash, Run: lend("poke1", "Red", tomorrow), Err: "Pokemon poke1 is lent to Misty until 2024-01-02T09:00:00Z"},
+		{Name: "owners list their leases", Caller: ash, Run: leases("Ash", "poke1")},
+		{Name: "borrowers list their leases", Caller: misty, Run: leases("Misty", "poke1")},
+		{Name: "others have no leases", Caller: red, Run: leases("Red")},
+		{
+			Name:   "lent Pokemon cannot be gifted",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.GiftPokemon(ctx, "poke1", "Red", "")
+			},
+			Err: "Pokemon poke1 is lent to Misty",
+		},
+		{
+			Name:   "lent Pokemon battle for their borrower",
+			Caller: admin,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.Battle(ctx, "poke1", "poke3", "seed")
+				return err
+			},
+			Err: "a trainer cannot battle themselves",
+		},
+		{Name: "owners cannot reclaim a running lease", Caller: ash, Run: reclaim("poke1"), Err: "Pokemon poke1 is lent to Misty until 2024-01-02T09:00:00Z"},
+		{Name: "others cannot reclaim", Caller: red, Run: reclaim("poke1"), Err: "submitting client Red is not authorized for this Pokemon"},
+		{Name: "borrowers return Pokemon early", Caller: misty, Run: reclaim("poke1")},
+		{Name: "returned Pokemon are not lent", Caller: ash, Run: readLease("poke1"), Err: "Pokemon poke1 is not lent"},
+		{Name: "returned leases leave the index", Caller: misty, Run: leases("Misty")},
+		{
+			Name:   "gift Charmander",
+			Caller: red,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.GiftPokemon(ctx, "poke2", "Misty", "")
+			},
+		},
+		{Name: "lend a Pokemon with a pending gift", Caller: red, Run: lend("poke2", "Ash", tomorrow), Err: "Pokemon poke2 has a pending gift to Misty"},
+		{Name: "lend again", Caller: ash, Run: lend("poke1", "Misty", tomorrow)},
+	})
+
+	ledger.Advance(48 * time.Hour)
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "expired leases are kept until reclaimed", Caller: ash, Run: readLease("poke1")},
+		{Name: "lend over an expired lease", Caller: ash, Run: lend("poke1", "Red", "2024-01-05T09:00:00Z")},
+		{Name: "the expired lease is replaced", Caller: misty, Run: leases("Misty")},
+	})
+
+	ledger.Advance(72 * time.Hour)
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "owners reclaim expired leases", Caller: ash, Run: reclaim("poke1")},
+		{Name: "reclaimed leases leave the index", Caller: ash, Run: leases("Ash")},
+	})
+}
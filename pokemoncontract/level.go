@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"querybuilder"
+)
+
+const (
+	// maxLevel is the highest level a Pokemon can reach. Experience keeps accumulating beyond it
+	// but grants no further power.
+	maxLevel = 100
+	// experienceStep scales the experience curve: reaching level L takes experienceStep*(L-1)^2
+	// experience in total
+	experienceStep = 25
+	// maxPower caps the power a Pokemon can reach by levelling up and evolving
+	maxPower = 999
+	// evolutionLevels is how many levels of its new type's growth a Pokemon gains on evolution
+	evolutionLevels = 5
+	// maxLeaderboardSize bounds the number of Pokemon GetLeaderboard returns
+	maxLeaderboardSize = 100
+)
+
+// growthCurve is the power a type gains as it levels up. Reaching level L from level 1 adds
+// PerLevel*(L-1) + Acceleration*(L-1)^2/10 power, so types with a higher acceleration start
+// slowly and overtake the others at high levels.
+type growthCurve struct {
+	PerLevel     int
+	Acceleration int
+}
+
+// growthCurves holds the growth curve of every type that does not use defaultGrowthCurve
+var growthCurves = map[string]growthCurve{
+	"Electric": {PerLevel: 4, Acceleration: 1},
+	"Fire":     {PerLevel: 3, Acceleration: 2},
+	"Water":    {PerLevel: 2, Acceleration: 3},
+	"Grass":    {PerLevel: 2, Acceleration: 2},
+}
+
+var defaultGrowthCurve = growthCurve{PerLevel: 2, Acceleration: 2}
+
+// GainExperience adds experience to a Pokemon and levels it up, adding the power its type's
+// growth curve grants for every level reached. Admin only.
+func (s *SmartContract) GainExperience(ctx contractapi.TransactionContextInterface, id string, xp int) (*Pokemon, error) {
+	err := requireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if xp <= 0 {
+		return nil, fmt.Errorf("experience must be positive")
+	}
+
+	p, err := s.ReadPokemon(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	before := *p
+
+	gainExperience(p, xp)
+
+	pokeJSON, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.GetStub().PutState(pokemonNamespace.Key(id), pokeJSON)
+	if err != nil {
+		return nil, err
+	}
+	err = updateRollups(ctx, []*Pokemon{&before}, []*Pokemon{p})
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// GetLeaderboard returns the topN Pokemon with the highest levels, ties broken by experience.
+// It uses a rich query, so it requires CouchDB as the state database and the level index
+// shipped in META-INF. Pokemon written before levels existed are ranked once they next change, and
+// deleted Pokemon, whose tombstones the query would otherwise match, are left out.
+func (s *SmartContract) GetLeaderboard(ctx contractapi.TransactionContextInterface, topN int) ([]*Pokemon, error) {
+	if topN <= 0 || topN > maxLeaderboardSize {
+		return nil, fmt.Errorf("topN must be between 1 and %d", maxLeaderboardSize)
+	}
+
+	queryString, err := querybuilder.New().
+		Gt("level", 0).
+		Gt("evolutionStage", 0).
+		Exists("deleted", false).
+		Sort("level", querybuilder.Desc).
+		Sort("experience", querybuilder.Desc).
+		Limit(topN).
+		UseIndex("indexLevelDoc", "indexLevel").
+		Build()
+	if err != nil {
+		return nil, err
+	}
+	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var pokemons []*Pokemon
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		p, err := decodePokemon(queryResponse.Value)
+		if err != nil {
+			return nil, err
+		}
+		pokemons = append(pokemons, p)
+	}
+
+	return pokemons, nil
+}
+
+// gainExperience adds experience to a Pokemon and levels it up
+func gainExperience(p *Pokemon, experience int) {
+	p.Experience += experience
+	levelUp(p, levelForExperience(p.Experience))
+}
+
+// levelUp raises a Pokemon to the given level, adding the power its type's growth curve grants
+// between its current level and the new one. Pokemon never lose levels.
+func levelUp(p *Pokemon, level int) {
+	if level <= p.Level {
+		return
+	}
+	p.Power = addPower(p.Power, typeGrowth(p.Type, level)-typeGrowth(p.Type, p.Level))
+	p.Level = level
+}
+
+// evolutionBonus returns the power a Pokemon of the given level gains on evolving into a species
+// of the given type, evolutionLevels levels of that type's growth
+func evolutionBonus(ptype string, level int) int {
+	return typeGrowth(ptype, level+evolutionLevels) - typeGrowth(ptype, level)
+}
+
+// levelForExperience returns the level reached with the given total experience
+func levelForExperience(experience int) int {
+	if experience < 0 {
+		experience = 0
+	}
+	level := 1 + isqrt(experience/experienceStep)
+	if level > maxLevel {
+		level = maxLevel
+	}
+	return level
+}
+
+// typeGrowth returns the power a Pokemon of the given type gains from level 1 to level
+func typeGrowth(ptype string, level int) int {
+	curve, ok := growthCurves[ptype]
+	if !ok {
+		curve = defaultGrowthCurve
+	}
+	steps := level - 1
+	if steps < 0 {
+		steps = 0
+	}
+	return curve.PerLevel*steps + curve.Acceleration*steps*steps/10
+}
+
+// addPower adds a power gain, capped at maxPower. Power above the cap set by other means is kept.
+func addPower(power int, gain int) int {
+	if power >= maxPower {
+		return power
+	}
+	power += gain
+	if power > maxPower {
+		power = maxPower
+	}
+	return power
+}
+
+// isqrt returns the integer square root of n, the largest r with r*r <= n
+func isqrt(n int) int {
+	if n <= 0 {
+		return 0
+	}
+	r := n
+	for x := (r + 1) / 2; x < r; x = (x + n/x) / 2 {
+		r = x
+	}
+	return r
+}
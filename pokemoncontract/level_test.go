@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLevels(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	gain := func(id string, xp int, level int, power int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			p, err := s.GainExperience(ctx, id, xp)
+			if err == nil {
+				require.Equal(t, level, p.Level)
+				require.Equal(t, power, p.Power)
+			}
+			return err
+		}
+	}
+	leaderboard := func(topN int, want ...string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			pokemons, err := s.GetLeaderboard(ctx, topN)
+			if err == nil {
+				var ids []string
+				for _, p := range pokemons {
+					ids = append(ids, p.ID)
+				}
+				require.Equal(t, want, ids)
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "level up", Caller: admin, Run: gain("poke1", 100, 3, 55+typeGrowth("Electric", 3))},
+		{Name: "experience below the next level", Caller: admin, Run: gain("poke1", 10, 3, 55+typeGrowth("Electric", 3))},
+		{Name: "levels and power are capped", Caller: admin, Run: gain("poke1", 1000000, maxLevel, maxPower)},
+		{Name: "water types grow slowly at first", Caller: admin, Run: gain("poke3", 25, 2, 48+2)},
+		{Name: "gain requires admin", Caller: ash, Run: gain("poke1", 100, 0, 0), Err: "requires role admin"},
+		{Name: "gain no experience", Caller: admin, Run: gain("poke1", 0, 0, 0), Err: "experience must be positive"},
+		{Name: "gain on a missing Pokemon", Caller: admin, Run: gain("poke9", 100, 0, 0), Err: "Pokemon poke9 does not exist"},
+		{Name: "leaderboard", Caller: ash, Run: leaderboard(2, "poke1", "poke3")},
+		{Name: "leaderboard too small", Caller: ash, Run: leaderboard(0), Err: "topN must be between 1 and 100"},
+		{Name: "leaderboard too large", Caller: ash, Run: leaderboard(101), Err: "topN must be between 1 and 100"},
+	})
+}
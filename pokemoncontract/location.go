@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	locationPokemonIndex = "location~pokemon"
+
+	// EventPokemonMoved is the chaincode event emitted with the PokemonMove of every move
+	EventPokemonMoved = "PokemonMoved"
+)
+
+// PokemonMove records a Pokemon moving from one location to another
+type PokemonMove struct {
+	PokemonID string `json:"pokemonId"`
+	Trainer   string `json:"trainer"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	MovedAt   string `json:"movedAt"`
+}
+
+// Migration is one location of a Pokemon in its key history. From is empty for the location the
+// Pokemon was created at.
+type Migration struct {
+	TxID      string `json:"txId"`
+	Timestamp string `json:"timestamp"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+}
+
+// MovePokemon moves a Pokemon of the submitting trainer to a new location and emits a
+// PokemonMoved event
+func (s *SmartContract) MovePokemon(ctx contractapi.TransactionContextInterface, id string, newLocation string) error {
+	if newLocation == "" {
+		return fmt.Errorf("location must not be empty")
+	}
+	p, err := s.ReadPokemon(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = requireTrainer(ctx, p.Trainer)
+	if err != nil {
+		return err
+	}
+	if p.Location == newLocation {
+		return fmt.Errorf("Pokemon %s is already at %s", id, newLocation)
+	}
+	before := *p
+	p.Location = newLocation
+
+	pokeJSON, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().PutState(pokemonNamespace.Key(id), pokeJSON)
+	if err != nil {
+		return err
+	}
+	err = updateRollups(ctx, []*Pokemon{&before}, []*Pokemon{p})
+	if err != nil {
+		return err
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	move := PokemonMove{
+		PokemonID: id,
+		Trainer:   p.Trainer,
+		From:      before.Location,
+		To:        newLocation,
+		MovedAt:   now.Format(time.RFC3339),
+	}
+	moveJSON, err := json.Marshal(move)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().SetEvent(EventPokemonMoved, moveJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set event: %v", err)
+	}
+
+	return nil
+}
+
+// GetPokemonsByLocation returns the Pokemon at a location, using the location index
+func (s *SmartContract) GetPokemonsByLocation(ctx contractapi.TransactionContextInterface, location string) ([]*Pokemon, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(locationPokemonIndex, []string{location})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var pokemons []*Pokemon
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, err
+		}
+		p, err := s.ReadPokemon(ctx, keyParts[1])
+		if err != nil {
+			return nil, err
+		}
+		pokemons = append(pokemons, p)
+	}
+
+	return pokemons, nil
+}
+
+// GetMigrationHistory returns the locations a Pokemon has been at, oldest first, assembled from
+// the history of its key
+func (s *SmartContract) GetMigrationHistory(ctx contractapi.TransactionContextInterface, id string) ([]*Migration, error) {
+	records, err := s.GetHistory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []*Migration
+	location := ""
+	// the history is returned most recent first
+	for i := len(records) - 1; i >= 0; i-- {
+		record := records[i]
+		if record.IsDelete || record.Pokemon == nil {
+			location = ""
+			continue
+		}
+		if record.Pokemon.Location == location {
+			continue
+		}
+		migrations = append(migrations, &Migration{
+			TxID:      record.TxID,
+			Timestamp: record.Timestamp,
+			From:      location,
+			To:        record.Pokemon.Location,
+		})
+		location = record.Pokemon.Location
+	}
+
+	return migrations, nil
+}
+
+// updateLocationIndex moves the location index entries of Pokemon removed from and added to
+// their locations, leaving entries that are both removed and added alone
+func updateLocationIndex(ctx contractapi.TransactionContextInterface, removed []*Pokemon, added []*Pokemon) error {
+	kept := make(map[[2]string]bool)
+	for _, p := range added {
+		kept[[2]string{p.Location, p.ID}] = true
+	}
+	for _, p := range removed {
+		if kept[[2]string{p.Location, p.ID}] {
+			continue
+		}
+		indexKey, err := ctx.GetStub().CreateCompositeKey(locationPokemonIndex, []string{p.Location, p.ID})
+		if err != nil {
+			return fmt.Errorf("failed to create composite key: %v", err)
+		}
+		err = ctx.GetStub().DelState(indexKey)
+		if err != nil {
+			return err
+		}
+	}
+	for _, p := range added {
+		err := putLocationIndex(ctx, p)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// putLocationIndex records the location~pokemon index entry of a Pokemon.
+// Only the key is needed, so a single null byte is stored as the value.
+func putLocationIndex(ctx contractapi.TransactionContextInterface, p *Pokemon) error {
+	indexKey, err := ctx.GetStub().CreateCompositeKey(locationPokemonIndex, []string{p.Location, p.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	return ctx.GetStub().PutState(indexKey, []byte{0x00})
+}
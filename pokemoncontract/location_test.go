@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocations(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	move := func(id string, location string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.MovePokemon(ctx, id, location)
+		}
+	}
+	at := func(location string, want ...string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			pokemons, err := s.GetPokemonsByLocation(ctx, location)
+			if err == nil {
+				var ids []string
+				for _, p := range pokemons {
+					ids = append(ids, p.ID)
+				}
+				require.Equal(t, want, ids)
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "sample locations", Caller: ash, Run: at("Pallet Town", "poke1")},
+		{
+			Name:   "move",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				err := s.MovePokemon(ctx, "poke1", "Viridian City")
+				if err == nil {
+					var move PokemonMove
+					require.Equal(t, EventPokemonMoved, lastEvent(t, ctx, &move))
+					require.Equal(t, "Pallet Town", move.From)
+					require.Equal(t, "Viridian City", move.To)
+					require.Equal(t, "Ash", move.Trainer)
+				}
+				return err
+			},
+		},
+		{Name: "moved Pokemon leave their location", Caller: ash, Run: at("Pallet Town")},
+		{Name: "moved Pokemon arrive at their location", Caller: ash, Run: at("Viridian City", "poke1")},
+		{Name: "move nowhere", Caller: ash, Run: move("poke1", ""), Err: "location must not be empty"},
+		{Name: "move to the current location", Caller: ash, Run: move("poke1", "Viridian City"), Err: "Pokemon poke1 is already at Viridian City"},
+		{Name: "move another trainer's Pokemon", Caller: red, Run: move("poke1", "Pallet Town"), Err: "submitting client Red is not authorized for this Pokemon"},
+		{
+			Name:   "power up in place",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.UpdatePokemon(ctx, "poke1", "Ash", 60)
+			},
+		},
+		{Name: "move back", Caller: ash, Run: move("poke1", "Pallet Town")},
+		{
+			Name:   "migration history",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				migrations, err := s.GetMigrationHistory(ctx, "poke1")
+				if err == nil {
+					require.Len(t, migrations, 3)
+					require.Equal(t, &Migration{TxID: "tx1", Timestamp: "2024-01-01T09:00:01Z", To: "Pallet Town"}, migrations[0])
+					require.Equal(t, "Pallet Town", migrations[1].From)
+					require.Equal(t, "Viridian City", migrations[1].To)
+					require.Equal(t, "Viridian City", migrations[2].From)
+					require.Equal(t, "Pallet Town", migrations[2].To)
+				}
+				return err
+			},
+		},
+	})
+}
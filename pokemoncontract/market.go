@@ -0,0 +1,424 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+const (
+	listingObjectType = "listing"
+	bidObjectType     = "bid"
+)
+
+var (
+	listingRepo = repository.New[Listing](listingObjectType)
+	bidRepo     = repository.New[Bid](bidObjectType)
+)
+
+// Listing offers a Pokemon for sale. Bids at or above the asking price are held in escrow until
+// the seller accepts one or the listing is cancelled.
+type Listing struct {
+	PokemonID string `json:"pokemonId"`
+	Seller    string `json:"seller"`
+	Price     int    `json:"price"`
+	ListedAt  string `json:"listedAt"`
+}
+
+// Bid is an offer on a listed Pokemon. The amount has been moved out of the bidder's PokeCoin
+// balance and is returned when the bid is withdrawn, another bid is accepted or the listing is
+// cancelled.
+type Bid struct {
+	PokemonID string `json:"pokemonId"`
+	Bidder    string `json:"bidder"`
+	Amount    int    `json:"amount"`
+	PlacedAt  string `json:"placedAt"`
+}
+
+// MintPokeCoin credits PokeCoin to a trainer. Admin only.
+func (s *SmartContract) MintPokeCoin(ctx contractapi.TransactionContextInterface, trainer string, amount int) error {
+	err := requireAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	if trainer == "" {
+		return fmt.Errorf("trainer must not be empty")
+	}
+	if amount <= 0 {
+		return fmt.Errorf("amount must be positive")
+	}
+
+	balances := make(coinBatch)
+	err = balances.credit(ctx, trainer, amount)
+	if err != nil {
+		return err
+	}
+
+	return balances.save(ctx)
+}
+
+// GetPokeCoinBalance returns the PokeCoin a trainer has available to bid with. Coins held in
+// escrow by open bids are not included.
+func (s *SmartContract) GetPokeCoinBalance(ctx contractapi.TransactionContextInterface, trainer string) (int, error) {
+	t, err := loadTrainer(ctx, trainer)
+	if err != nil {
+		return 0, err
+	}
+
+	return t.CoinBalance, nil
+}
+
+// ListForSale puts a Pokemon up for sale at an asking price in PokeCoin. Only its trainer may list
+// it, and a Pokemon with a pending gift or transfer cannot be listed.
+func (s *SmartContract) ListForSale(ctx contractapi.TransactionContextInterface, id string, price int) error {
+	p, err := s.ReadPokemon(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = requireTrainer(ctx, p.Trainer)
+	if err != nil {
+		return err
+	}
+	err = checkNotLent(ctx, id)
+	if err != nil {
+		return err
+	}
+	if price <= 0 {
+		return fmt.Errorf("price must be positive")
+	}
+
+	listing, err := getListing(ctx, id)
+	if err != nil {
+		return err
+	}
+	if listing != nil {
+		return fmt.Errorf("Pokemon %s is already listed for %d PokeCoin", id, listing.Price)
+	}
+	gift, err := getGift(ctx, id)
+	if err != nil {
+		return err
+	}
+	if gift != nil {
+		return fmt.Errorf("Pokemon %s has a pending gift to %s", id, gift.Recipient)
+	}
+	transfer, err := getTransfer(ctx, id)
+	if err != nil {
+		return err
+	}
+	if transfer != nil {
+		return fmt.Errorf("Pokemon %s has a pending transfer to %s", id, transfer.NewTrainer)
+	}
+	trade, err := getPrivateTrade(ctx, id)
+	if err != nil {
+		return err
+	}
+	if trade != nil {
+		return fmt.Errorf("Pokemon %s has a pending private trade with %s", id, trade.Buyer)
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	listing = &Listing{
+		PokemonID: id,
+		Seller:    p.Trainer,
+		Price:     price,
+		ListedAt:  now.Format(time.RFC3339),
+	}
+
+	return putListing(ctx, listing)
+}
+
+// PlaceBid offers amount PokeCoin for a listed Pokemon, moving the coins from the caller's balance
+// into escrow. A trainer holds at most one bid per listing; bidding again replaces the earlier bid
+// and settles the difference against the balance.
+func (s *SmartContract) PlaceBid(ctx contractapi.TransactionContextInterface, id string, amount int) error {
+	listing, err := s.ReadListing(ctx, id)
+	if err != nil {
+		return err
+	}
+	bidder, err := callerTrainer(ctx)
+	if err != nil {
+		return err
+	}
+	if bidder == listing.Seller {
+		return fmt.Errorf("trainer %s cannot bid on their own listing", bidder)
+	}
+	if amount < listing.Price {
+		return fmt.Errorf("bid of %d is below the asking price of %d PokeCoin", amount, listing.Price)
+	}
+
+	balances := make(coinBatch)
+	previous, err := getBid(ctx, id, bidder)
+	if err != nil {
+		return err
+	}
+	if previous != nil {
+		err = balances.credit(ctx, bidder, previous.Amount)
+		if err != nil {
+			return err
+		}
+	}
+	err = balances.debit(ctx, bidder, amount)
+	if err != nil {
+		return err
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	bid := Bid{
+		PokemonID: id,
+		Bidder:    bidder,
+		Amount:    amount,
+		PlacedAt:  now.Format(time.RFC3339),
+	}
+	err = putBid(ctx, &bid)
+	if err != nil {
+		return err
+	}
+
+	return balances.save(ctx)
+}
+
+// WithdrawBid returns the caller's bid on a listing to their balance
+func (s *SmartContract) WithdrawBid(ctx contractapi.TransactionContextInterface, id string) error {
+	bidder, err := callerTrainer(ctx)
+	if err != nil {
+		return err
+	}
+	bid, err := getBid(ctx, id, bidder)
+	if err != nil {
+		return err
+	}
+	if bid == nil {
+		return fmt.Errorf("trainer %s has no bid on Pokemon %s", bidder, id)
+	}
+
+	balances := make(coinBatch)
+	err = balances.credit(ctx, bidder, bid.Amount)
+	if err != nil {
+		return err
+	}
+	err = deleteBid(ctx, id, bidder)
+	if err != nil {
+		return err
+	}
+
+	return balances.save(ctx)
+}
+
+// AcceptBid sells a listed Pokemon to a bidder. In one transaction the escrowed bid is paid to the
+// seller, the Pokemon moves to the bidder, every other bid is refunded and the listing is removed.
+// Only the seller may accept.
+func (s *SmartContract) AcceptBid(ctx contractapi.TransactionContextInterface, id string, bidder string) error {
+	listing, err := s.ReadListing(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = requireTrainer(ctx, listing.Seller)
+	if err != nil {
+		return err
+	}
+
+	p, err := s.ReadPokemon(ctx, id)
+	if err != nil {
+		return err
+	}
+	if p.Trainer != listing.Seller {
+		return fmt.Errorf("Pokemon %s is no longer trained by %s", id, listing.Seller)
+	}
+
+	bids, err := s.GetBids(ctx, id)
+	if err != nil {
+		return err
+	}
+	balances := make(coinBatch)
+	var accepted *Bid
+	for _, bid := range bids {
+		if bid.Bidder == bidder {
+			accepted = bid
+			err = balances.credit(ctx, listing.Seller, bid.Amount)
+		} else {
+			err = balances.credit(ctx, bid.Bidder, bid.Amount)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if accepted == nil {
+		return fmt.Errorf("trainer %s has no bid on Pokemon %s", bidder, id)
+	}
+
+	before := *p
+	p.Trainer = bidder
+	pokeJSON, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().PutState(pokemonNamespace.Key(id), pokeJSON)
+	if err != nil {
+		return err
+	}
+	err = updateRollups(ctx, []*Pokemon{&before}, []*Pokemon{p})
+	if err != nil {
+		return err
+	}
+
+	err = closeListing(ctx, id, bids)
+	if err != nil {
+		return err
+	}
+
+	return balances.save(ctx)
+}
+
+// CancelListing takes a Pokemon off the market and refunds every bid. Only the seller may cancel.
+func (s *SmartContract) CancelListing(ctx contractapi.TransactionContextInterface, id string) error {
+	listing, err := s.ReadListing(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = requireTrainer(ctx, listing.Seller)
+	if err != nil {
+		return err
+	}
+
+	bids, err := s.GetBids(ctx, id)
+	if err != nil {
+		return err
+	}
+	balances := make(coinBatch)
+	for _, bid := range bids {
+		err = balances.credit(ctx, bid.Bidder, bid.Amount)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = closeListing(ctx, id, bids)
+	if err != nil {
+		return err
+	}
+
+	return balances.save(ctx)
+}
+
+// ReadListing returns the listing of a Pokemon
+func (s *SmartContract) ReadListing(ctx contractapi.TransactionContextInterface, id string) (*Listing, error) {
+	listing, err := getListing(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if listing == nil {
+		return nil, fmt.Errorf("Pokemon %s is not listed for sale", id)
+	}
+
+	return listing, nil
+}
+
+// GetBids returns the open bids on a listed Pokemon
+func (s *SmartContract) GetBids(ctx contractapi.TransactionContextInterface, id string) ([]*Bid, error) {
+	return bidRepo.List(ctx, id)
+}
+
+// coinBatch collects the trainers whose PokeCoin balance a transaction changes, so that each
+// trainer is read and written once even when credited or debited more than once
+type coinBatch map[string]*Trainer
+
+func (b coinBatch) load(ctx contractapi.TransactionContextInterface, trainer string) (*Trainer, error) {
+	if t, ok := b[trainer]; ok {
+		return t, nil
+	}
+	t, err := loadTrainer(ctx, trainer)
+	if err != nil {
+		return nil, err
+	}
+	b[trainer] = t
+	return t, nil
+}
+
+func (b coinBatch) credit(ctx contractapi.TransactionContextInterface, trainer string, amount int) error {
+	t, err := b.load(ctx, trainer)
+	if err != nil {
+		return err
+	}
+	t.CoinBalance += amount
+	return nil
+}
+
+func (b coinBatch) debit(ctx contractapi.TransactionContextInterface, trainer string, amount int) error {
+	t, err := b.load(ctx, trainer)
+	if err != nil {
+		return err
+	}
+	if t.CoinBalance < amount {
+		return fmt.Errorf("trainer %s has %d PokeCoin, %d needed", trainer, t.CoinBalance, amount)
+	}
+	t.CoinBalance -= amount
+	return nil
+}
+
+// save writes every trainer in the batch
+func (b coinBatch) save(ctx contractapi.TransactionContextInterface) error {
+	trainers := make([]string, 0, len(b))
+	for trainer := range b {
+		trainers = append(trainers, trainer)
+	}
+	sort.Strings(trainers)
+	for _, trainer := range trainers {
+		err := putTrainer(ctx, b[trainer])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// closeListing removes a listing and its bids
+func closeListing(ctx contractapi.TransactionContextInterface, id string, bids []*Bid) error {
+	for _, bid := range bids {
+		err := deleteBid(ctx, id, bid.Bidder)
+		if err != nil {
+			return err
+		}
+	}
+	listingKey, err := ctx.GetStub().CreateCompositeKey(listingObjectType, []string{id})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	return ctx.GetStub().DelState(listingKey)
+}
+
+func getListing(ctx contractapi.TransactionContextInterface, id string) (*Listing, error) {
+	return listingRepo.Get(ctx, id)
+}
+
+func putListing(ctx contractapi.TransactionContextInterface, listing *Listing) error {
+	return listingRepo.Put(ctx, listing, listing.PokemonID)
+}
+
+func getBid(ctx contractapi.TransactionContextInterface, id string, bidder string) (*Bid, error) {
+	return bidRepo.Get(ctx, id, bidder)
+}
+
+func putBid(ctx contractapi.TransactionContextInterface, bid *Bid) error {
+	return bidRepo.Put(ctx, bid, bid.PokemonID, bid.Bidder)
+}
+
+func deleteBid(ctx contractapi.TransactionContextInterface, id string, bidder string) error {
+	bidKey, err := ctx.GetStub().CreateCompositeKey(bidObjectType, []string{id, bidder})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	return ctx.GetStub().DelState(bidKey)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarket(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	mintCoin := func(trainer string, amount int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.MintPokeCoin(ctx, trainer, amount)
+		}
+	}
+	balance := func(trainer string, want int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			balance, err := s.GetPokeCoinBalance(ctx, trainer)
+			require.Equal(t, want, balance)
+			return err
+		}
+	}
+	list := func(price int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.ListForSale(ctx, "poke1", price)
+		}
+	}
+	bid := func(amount int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.PlaceBid(ctx, "poke1", amount)
+		}
+	}
+	withdraw := func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return s.WithdrawBid(ctx, "poke1")
+	}
+	accept := func(bidder string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.AcceptBid(ctx, "poke1", bidder)
+		}
+	}
+	cancel := func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return s.CancelListing(ctx, "poke1")
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "mint coins for Red", Caller: admin, Run: mintCoin("Red", 100)},
+		{Name: "mint coins for Misty", Caller: admin, Run: mintCoin("Misty", 80)},
+		{Name: "mint requires admin", Caller: red, Run: mintCoin("Red", 100), Err: "requires role admin"},
+		{Name: "mint for nobody", Caller: admin, Run: mintCoin("", 100), Err: "trainer must not be empty"},
+		{Name: "mint nothing", Caller: admin, Run: mintCoin("Red", 0), Err: "amount must be positive"},
+		{Name: "minted balance", Caller: red, Run: balance("Red", 100)},
+		{Name: "trainers without an account have nothing", Caller: ash, Run: balance("Ash", 0)},
+		{Name: "list another trainer's Pokemon", Caller: red, Run: list(50), Err: "submitting client Red is not authorized for this Pokemon"},
+		{Name: "list for free", Caller: ash, Run: list(0), Err: "price must be positive"},
+		{Name: "list", Caller: ash, Run: list(50)},
+		{
+			Name:   "read listing",
+			Caller: red,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				listing, err := s.ReadListing(ctx, "poke1")
+				if err == nil {
+					require.Equal(t, "Ash", listing.Seller)
+					require.Equal(t, 50, listing.Price)
+				}
+				return err
+			},
+		},
+		{Name: "list twice", Caller: ash, Run: list(60), Err: "Pokemon poke1 is already listed for 50 PokeCoin"},
+		{
+			Name:   "listed Pokemon cannot be deleted",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.DeletePokemon(ctx, "poke1", "released")
+			},
+			Err: "Pokemon poke1 is listed for sale, cancel the listing first",
+		},
+		{Name: "bid on one's own listing", Caller: ash, Run: bid(50), Err: "trainer Ash cannot bid on their own listing"},
+		{Name: "bid below the asking price", Caller: red, Run: bid(40), Err: "bid of 40 is below the asking price of 50 PokeCoin"},
+		{Name: "bid beyond the balance", Caller: red, Run: bid(150), Err: "trainer Red has 100 PokeCoin, 150 needed"},
+		{Name: "bid", Caller: red, Run: bid(60)},
+		{Name: "bids are escrowed", Caller: red, Run: balance("Red", 40)},
+		{Name: "raise bid", Caller: red, Run: bid(70)},
+		{Name: "raised bids settle the difference", Caller: red, Run: balance("Red", 30)},
+		{Name: "competing bid", Caller: misty, Run: bid(55)},
+		{
+			Name:   "open bids",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				bids, err := s.GetBids(ctx, "poke1")
+				if err == nil {
+					require.Len(t, bids, 2)
+					require.Equal(t, "Misty", bids[0].Bidder)
+					require.Equal(t, 70, bids[1].Amount)
+				}
+				return err
+			},
+		},
+		{Name: "withdraw bid", Caller: misty, Run: withdraw},
+		{Name: "withdrawn bids are refunded", Caller: misty, Run: balance("Misty", 80)},
+		{Name: "withdraw without a bid", Caller: misty, Run: withdraw, Err: "trainer Misty has no bid on Pokemon poke1"},
+		{Name: "bid again", Caller: misty, Run: bid(50)},
+		{Name: "accept as another trainer", Caller: red, Run: accept("Red"), Err: "submitting client Red is not authorized for this Pokemon"},
+		{Name: "accept a trainer without a bid", Caller: ash, Run: accept("Brock"), Err: "trainer Brock has no bid on Pokemon poke1"},
+		{Name: "accept bid", Caller: ash, Run: accept("Red")},
+		{
+			Name:   "sold Pokemon move to the bidder",
+			Caller: red,
+			Run: readPokemon("poke1", func(t *testing.T, p *Pokemon) {
+				require.Equal(t, "Red", p.Trainer)
+			}),
+		},
+		{Name: "the seller is paid", Caller: ash, Run: balance("Ash", 70)},
+		{Name: "other bids are refunded", Caller: misty, Run: balance("Misty", 80)},
+		{
+			Name:   "sold Pokemon are no longer listed",
+			Caller: red,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.ReadListing(ctx, "poke1")
+				return err
+			},
+			Err: "Pokemon poke1 is not listed for sale",
+		},
+		{Name: "list again", Caller: red, Run: list(10)},
+		{Name: "bid on the new listing", Caller: misty, Run: bid(10)},
+		{Name: "cancel as another trainer", Caller: ash, Run: cancel, Err: "submitting client Ash is not authorized for this Pokemon"},
+		{Name: "cancel listing", Caller: red, Run: cancel},
+		{Name: "cancelled listings refund their bids", Caller: misty, Run: balance("Misty", 80)},
+		{Name: "cancel a listing that is gone", Caller: red, Run: cancel, Err: "Pokemon poke1 is not listed for sale"},
+	})
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+const mintCounterObjectType = "mintcounter"
+
+var mintCounterRepo = repository.New[MintCounter](mintCounterObjectType)
+
+// MintCounter counts the Pokemon of one species a trainer has minted
+type MintCounter struct {
+	Trainer string `json:"trainer"`
+	Species string `json:"species"`
+	Count   int    `json:"count"`
+}
+
+// MintPokemon creates a Pokemon for the submitting trainer under an ID derived from the
+// transaction ID and the trainer, so clients cannot claim or front-run chosen IDs. The trainer's
+// counter for the species is incremented.
+func (s *SmartContract) MintPokemon(ctx contractapi.TransactionContextInterface, name string, ptype string, location string, power int) (*Pokemon, error) {
+	if name == "" || ptype == "" {
+		return nil, fmt.Errorf("name and type must not be empty")
+	}
+	if power <= 0 || power > maxPower {
+		return nil, fmt.Errorf("power must be between 1 and %d", maxPower)
+	}
+	trainer, err := callerTrainer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := sha256.Sum256([]byte(ctx.GetStub().GetTxID() + "\x00" + trainer))
+	id := "pk" + hex.EncodeToString(digest[:10])
+	exists, err := s.PokemonExists(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		// a transaction mints at most one Pokemon per trainer
+		return nil, fmt.Errorf("Pokemon %s already exists", id)
+	}
+
+	p := Pokemon{
+		ID:       id,
+		Name:     name,
+		Type:     ptype,
+		Power:    power,
+		Trainer:  trainer,
+		Location: location,
+
+		Level:          1,
+		EvolutionStage: 1,
+	}
+	pokeJSON, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.GetStub().PutState(pokemonNamespace.Key(id), pokeJSON)
+	if err != nil {
+		return nil, err
+	}
+	err = updateRollups(ctx, nil, []*Pokemon{&p})
+	if err != nil {
+		return nil, err
+	}
+
+	counter, err := s.GetMintCounter(ctx, trainer, name)
+	if err != nil {
+		return nil, err
+	}
+	counter.Count++
+	err = putMintCounter(ctx, counter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+// GetMintCounter returns how many Pokemon of a species a trainer has minted
+func (s *SmartContract) GetMintCounter(ctx contractapi.TransactionContextInterface, trainer string, species string) (*MintCounter, error) {
+	counterKey, err := ctx.GetStub().CreateCompositeKey(mintCounterObjectType, []string{trainer, species})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	counterJSON, err := ctx.GetStub().GetState(counterKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if counterJSON == nil {
+		return &MintCounter{Trainer: trainer, Species: species}, nil
+	}
+
+	var counter MintCounter
+	err = json.Unmarshal(counterJSON, &counter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &counter, nil
+}
+
+func putMintCounter(ctx contractapi.TransactionContextInterface, counter *MintCounter) error {
+	return mintCounterRepo.Put(ctx, counter, counter.Trainer, counter.Species)
+}
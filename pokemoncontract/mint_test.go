@@ -0,0 +1,70 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMinting(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	mint := func(name string, ptype string, power int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.MintPokemon(ctx, name, ptype, "Viridian Forest", power)
+			return err
+		}
+	}
+	counter := func(trainer string, species string, want int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			counter, err := s.GetMintCounter(ctx, trainer, species)
+			if err == nil {
+				require.Equal(t, want, counter.Count)
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{
+			Name:   "mint",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				p, err := s.MintPokemon(ctx, "Caterpie", "Bug", "Viridian Forest", 30)
+				if err == nil {
+					digest := sha256.Sum256([]byte(ctx.GetStub().GetTxID() + "\x00" + "Ash"))
+					require.Equal(t, "pk"+hex.EncodeToString(digest[:10]), p.ID)
+					require.Equal(t, "Ash", p.Trainer)
+
+					stored, err := s.ReadPokemon(ctx, p.ID)
+					require.NoError(t, err)
+					require.Equal(t, p, stored)
+				}
+				return err
+			},
+		},
+		{Name: "mint another", Caller: ash, Run: mint("Caterpie", "Bug", 35)},
+		{Name: "minted species are counted", Caller: ash, Run: counter("Ash", "Caterpie", 2)},
+		{Name: "unminted species count zero", Caller: ash, Run: counter("Red", "Caterpie", 0)},
+		{
+			Name:   "mint twice in one transaction",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.MintPokemon(ctx, "Weedle", "Bug", "Viridian Forest", 30)
+				require.NoError(t, err)
+				_, err = s.MintPokemon(ctx, "Weedle", "Bug", "Viridian Forest", 30)
+				return err
+			},
+			Err: "already exists",
+		},
+		{Name: "failed mints are not counted", Caller: ash, Run: counter("Ash", "Weedle", 0)},
+		{Name: "mint without a type", Caller: ash, Run: mint("Caterpie", "", 30), Err: "name and type must not be empty"},
+		{Name: "mint without power", Caller: ash, Run: mint("Caterpie", "Bug", 0), Err: "power must be between 1 and 999"},
+		{Name: "mint beyond the maximum power", Caller: ash, Run: mint("Caterpie", "Bug", 1000), Err: "power must be between 1 and 999"},
+	})
+}
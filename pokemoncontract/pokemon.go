@@ -3,10 +3,13 @@ package main
 import (
 	"encoding/json"
 	"fmt"
-	"strconv"
+	"os"
+	"time"
 
+	"authz"
+	"ccaas"
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
-	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"repository"
 )
 
 // SmartContract provides functions for managing Pokemon
@@ -16,35 +19,80 @@ type SmartContract struct {
 
 // Pokemon defines the structure for a Pokemon asset
 type Pokemon struct {
-	ID        string `json:"id"`
-	Name      string `json:"name"`
-	Type      string `json:"type"`
-	Power     int    `json:"power"`
-	Trainer   string `json:"trainer"`
-	Evolved   bool   `json:"evolved"`
-	Location  string `json:"location"`
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	Type           string `json:"type"`
+	Power          int    `json:"power"`
+	Experience     int    `json:"experience"`
+	Level          int    `json:"level"`
+	Trainer        string `json:"trainer"`
+	EvolutionStage int    `json:"evolutionStage"` // 1 for a base form, one more per evolution
+	Location       string `json:"location"`
+
+	Deleted        bool   `json:"deleted,omitempty" metadata:",optional"`
+	DeletionReason string `json:"deletionReason,omitempty" metadata:",optional"`
+	DeletedBy      string `json:"deletedBy,omitempty" metadata:",optional"`
+	DeletedAt      string `json:"deletedAt,omitempty" metadata:",optional"`
+}
+
+// pokemonAssetType is the asset type deleted Pokemon are kept under as tombstones
+const pokemonAssetType = "pokemon"
+
+// pokemonNamespace prefixes the plain keys of Pokemon, POKE_id
+const pokemonNamespace repository.Namespace = "POKE_"
+
+// deletedPokemonRepo keeps deleted Pokemon under tombstone~pokemon~id until they are restored
+var deletedPokemonRepo = repository.New[Pokemon](repository.TombstoneObjectType)
+
+// HistoryRecord is one recorded version of a Pokemon. Pokemon is empty for a deletion.
+type HistoryRecord struct {
+	TxID      string   `json:"txId"`
+	Timestamp string   `json:"timestamp"`
+	IsDelete  bool     `json:"isDelete"`
+	Pokemon   *Pokemon `json:"pokemon,omitempty" metadata:",optional"`
+}
+
+// HistoryPage is one page of a Pokemon's history with the bookmark for the next page
+type HistoryPage struct {
+	Records             []*HistoryRecord `json:"records,omitempty" metadata:",optional"`
+	FetchedRecordsCount int32            `json:"fetchedRecordsCount"`
+	Bookmark            string           `json:"bookmark"`
 }
 
 // InitLedger adds initial Pokemons to the ledger
 func (s *SmartContract) InitLedger(ctx contractapi.TransactionContextInterface) error {
 	pokemons := []Pokemon{
-		{ID: "poke1", Name: "Pikachu", Type: "Electric", Power: 55, Trainer: "Ash", Evolved: false, Location: "Pallet Town"},
-		{ID: "poke2", Name: "Charmander", Type: "Fire", Power: 52, Trainer: "Red", Evolved: false, Location: "Cinnabar Island"},
-		{ID: "poke3", Name: "Squirtle", Type: "Water", Power: 48, Trainer: "Misty", Evolved: false, Location: "Cerulean City"},
+		{ID: "poke1", Name: "Pikachu", Type: "Electric", Power: 55, Trainer: "Ash", Level: 1, EvolutionStage: 1, Location: "Pallet Town"},
+		{ID: "poke2", Name: "Charmander", Type: "Fire", Power: 52, Trainer: "Red", Level: 1, EvolutionStage: 1, Location: "Cinnabar Island"},
+		{ID: "poke3", Name: "Squirtle", Type: "Water", Power: 48, Trainer: "Misty", Level: 1, EvolutionStage: 1, Location: "Cerulean City"},
 	}
 
-	for _, p := range pokemons {
+	var added []*Pokemon
+	for i, p := range pokemons {
 		pokeJSON, err := json.Marshal(p)
 		if err != nil {
 			return err
 		}
 
-		err = ctx.GetStub().PutState(p.ID, pokeJSON)
+		err = ctx.GetStub().PutState(pokemonNamespace.Key(p.ID), pokeJSON)
 		if err != nil {
 			return err
 		}
+		added = append(added, &pokemons[i])
 	}
-	return nil
+	for i := range defaultSpecies {
+		err := putSpecies(ctx, &defaultSpecies[i])
+		if err != nil {
+			return err
+		}
+	}
+	for i := range defaultTypeChart {
+		err := putTypeEffectiveness(ctx, &defaultTypeChart[i])
+		if err != nil {
+			return err
+		}
+	}
+	return updateRollups(ctx, nil, added)
 }
 
 // CreatePokemon adds a new Pokemon to the ledger
@@ -63,8 +111,10 @@ func (s *SmartContract) CreatePokemon(ctx contractapi.TransactionContextInterfac
 		Type:     ptype,
 		Power:    power,
 		Trainer:  trainer,
-		Evolved:  false,
 		Location: location,
+
+		Level:          1,
+		EvolutionStage: 1,
 	}
 
 	pokeJSON, err := json.Marshal(p)
@@ -72,122 +122,371 @@ func (s *SmartContract) CreatePokemon(ctx contractapi.TransactionContextInterfac
 		return err
 	}
 
-	return ctx.GetStub().PutState(id, pokeJSON)
+	err = ctx.GetStub().PutState(pokemonNamespace.Key(id), pokeJSON)
+	if err != nil {
+		return err
+	}
+	return updateRollups(ctx, nil, []*Pokemon{&p})
 }
 
 // ReadPokemon returns the Pokemon from the ledger
 func (s *SmartContract) ReadPokemon(ctx contractapi.TransactionContextInterface, id string) (*Pokemon, error) {
-	pokeJSON, err := ctx.GetStub().GetState(id)
+	pokeJSON, err := ctx.GetStub().GetState(pokemonNamespace.Key(id))
 	if err != nil {
 		return nil, fmt.Errorf("failed to read from world state: %v", err)
 	}
 	if pokeJSON == nil {
+		deleted, err := deletedPokemonRepo.Get(ctx, pokemonAssetType, id)
+		if err != nil {
+			return nil, err
+		}
+		if deleted != nil {
+			return nil, fmt.Errorf("Pokemon %s was deleted on %s", id, deleted.DeletedAt)
+		}
 		return nil, fmt.Errorf("Pokemon %s does not exist", id)
 	}
 
+	return decodePokemon(pokeJSON)
+}
+
+// decodePokemon unmarshals a stored Pokemon, upgrading records written before evolution stages
+// or levels
+func decodePokemon(pokeJSON []byte) (*Pokemon, error) {
 	var poke Pokemon
-	err = json.Unmarshal(pokeJSON, &poke)
+	err := json.Unmarshal(pokeJSON, &poke)
 	if err != nil {
 		return nil, err
 	}
+	if poke.EvolutionStage == 0 {
+		// records written before evolution stages only carry an evolved flag
+		var legacy struct {
+			Evolved bool `json:"evolved"`
+		}
+		err = json.Unmarshal(pokeJSON, &legacy)
+		if err != nil {
+			return nil, err
+		}
+		poke.EvolutionStage = 1
+		if legacy.Evolved {
+			poke.EvolutionStage = 2
+		}
+	}
+	if poke.Level == 0 {
+		// records written before levels were introduced level up from their experience, without
+		// the power growth already granted for it
+		poke.Level = levelForExperience(poke.Experience)
+	}
 	return &poke, nil
 }
 
-// UpdatePokemon modifies the power or trainer of a Pokemon
+// UpdatePokemon modifies the power of a Pokemon. The trainer must stay the same; ownership
+// changes go through TransferPokemon and AcceptTransfer. Only the current trainer may update it.
 func (s *SmartContract) UpdatePokemon(ctx contractapi.TransactionContextInterface, id, trainer string, power int) error {
 	p, err := s.ReadPokemon(ctx, id)
 	if err != nil {
 		return err
 	}
+	holder, err := activeTrainer(ctx, p)
+	if err != nil {
+		return err
+	}
+	err = requireTrainer(ctx, holder)
+	if err != nil {
+		return err
+	}
+	if trainer != p.Trainer {
+		return fmt.Errorf("Pokemon %s is trained by %s, use TransferPokemon to change its trainer", id, p.Trainer)
+	}
+	before := *p
 
 	p.Power = power
-	p.Trainer = trainer
 
 	pokeJSON, err := json.Marshal(p)
 	if err != nil {
 		return err
 	}
 
-	return ctx.GetStub().PutState(id, pokeJSON)
+	err = ctx.GetStub().PutState(pokemonNamespace.Key(id), pokeJSON)
+	if err != nil {
+		return err
+	}
+	return updateRollups(ctx, []*Pokemon{&before}, []*Pokemon{p})
 }
 
-// EvolvePokemon marks a Pokemon as evolved
+// EvolvePokemon evolves a Pokemon into the next species of its evolution chain, as registered in
+// the species registry, once it has reached the species' minimum power. The previous form is
+// kept in the Pokemon's evolution lineage. The Pokemon gains evolutionLevels levels' worth of
+// its new type's power growth.
 func (s *SmartContract) EvolvePokemon(ctx contractapi.TransactionContextInterface, id string) error {
 	p, err := s.ReadPokemon(ctx, id)
 	if err != nil {
 		return err
 	}
+	before := *p
 
-	if p.Evolved {
-		return fmt.Errorf("Pokemon %s is already evolved", id)
+	err = evolve(ctx, p, false)
+	if err != nil {
+		return err
 	}
 
-	p.Evolved = true
-	p.Power += 30 // bonus power on evolution
-
 	pokeJSON, err := json.Marshal(p)
 	if err != nil {
 		return err
 	}
 
-	return ctx.GetStub().PutState(id, pokeJSON)
+	err = ctx.GetStub().PutState(pokemonNamespace.Key(id), pokeJSON)
+	if err != nil {
+		return err
+	}
+	return updateRollups(ctx, []*Pokemon{&before}, []*Pokemon{p})
 }
 
-// DeletePokemon removes a Pokemon from ledger
-func (s *SmartContract) DeletePokemon(ctx contractapi.TransactionContextInterface, id string) error {
-	exists, err := s.PokemonExists(ctx, id)
+// evolve moves a Pokemon to the next species of its evolution chain and records the evolution.
+// A forced evolution skips the species' minimum power.
+func evolve(ctx contractapi.TransactionContextInterface, p *Pokemon, force bool) error {
+	species, err := getSpecies(ctx, p.Name)
+	if err != nil {
+		return err
+	}
+	if species == nil {
+		return fmt.Errorf("species %s is not registered", p.Name)
+	}
+	if species.EvolvesTo == "" {
+		return fmt.Errorf("%s does not evolve any further", p.Name)
+	}
+	if !force && p.Power < species.MinPowerToEvolve {
+		return fmt.Errorf("%s needs %d power to evolve, Pokemon %s has %d", p.Name, species.MinPowerToEvolve, p.ID, p.Power)
+	}
+	evolved, err := getSpecies(ctx, species.EvolvesTo)
 	if err != nil {
 		return err
 	}
-	if !exists {
-		return fmt.Errorf("Pokemon %s does not exist", id)
+	if evolved == nil {
+		return fmt.Errorf("species %s is not registered", species.EvolvesTo)
 	}
 
-	return ctx.GetStub().DelState(id)
+	err = putEvolution(ctx, p, evolved.Name)
+	if err != nil {
+		return err
+	}
+	p.Name = evolved.Name
+	p.Type = evolved.Type
+	p.EvolutionStage++
+	p.Power = addPower(p.Power, evolutionBonus(p.Type, p.Level))
+
+	return nil
 }
 
-func (s *SmartContract) GetHistory(ctx contractapi.TransactionContextInterface, id string) ([]string, error) {
-	resultsIterator, err := ctx.GetStub().GetHistoryForKey(id)
+// DeletePokemon soft deletes a Pokemon. It leaves its trainer's rollups and the indexes, but is
+// kept as a tombstone with the reason and the deleting client until RestorePokemon brings it back.
+// A Pokemon listed for sale cannot be deleted while bids are held in escrow against it.
+func (s *SmartContract) DeletePokemon(ctx contractapi.TransactionContextInterface, id string, reason string) error {
+	if reason == "" {
+		return fmt.Errorf("deletion reason must not be empty")
+	}
+	p, err := s.ReadPokemon(ctx, id)
+	if err != nil {
+		return err
+	}
+	listing, err := getListing(ctx, id)
+	if err != nil {
+		return err
+	}
+	if listing != nil {
+		return fmt.Errorf("Pokemon %s is listed for sale, cancel the listing first", id)
+	}
+
+	err = ctx.GetStub().DelState(pokemonNamespace.Key(id))
+	if err != nil {
+		return err
+	}
+	err = updateRollups(ctx, []*Pokemon{p}, nil)
+	if err != nil {
+		return err
+	}
+
+	deleter, err := callerTrainer(ctx)
+	if err != nil {
+		return err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	p.Deleted = true
+	p.DeletionReason = reason
+	p.DeletedBy = deleter
+	p.DeletedAt = now.Format(time.RFC3339)
+
+	return deletedPokemonRepo.Put(ctx, p, pokemonAssetType, id)
+}
+
+// RestorePokemon brings back a deleted Pokemon to its trainer as it was when deleted. Admin only.
+func (s *SmartContract) RestorePokemon(ctx contractapi.TransactionContextInterface, id string) error {
+	err := requireAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	p, err := deletedPokemonRepo.Get(ctx, pokemonAssetType, id)
+	if err != nil {
+		return err
+	}
+	if p == nil {
+		return fmt.Errorf("Pokemon %s is not deleted", id)
+	}
+
+	p.Deleted = false
+	p.DeletionReason = ""
+	p.DeletedBy = ""
+	p.DeletedAt = ""
+	pokeJSON, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().PutState(pokemonNamespace.Key(id), pokeJSON)
+	if err != nil {
+		return err
+	}
+	err = updateRollups(ctx, nil, []*Pokemon{p})
+	if err != nil {
+		return err
+	}
+
+	return deletedPokemonRepo.Delete(ctx, pokemonAssetType, id)
+}
+
+// GetHistory returns every recorded version of a Pokemon, most recent first
+func (s *SmartContract) GetHistory(ctx contractapi.TransactionContextInterface, id string) ([]*HistoryRecord, error) {
+	page, err := s.GetHistoryPaginated(ctx, id, 0, "")
 	if err != nil {
 		return nil, err
 	}
-	defer resultsIterator.Close()
 
-	var history []string
+	return page.Records, nil
+}
 
-	for resultsIterator.HasNext() {
-		resp, err := resultsIterator.Next()
-		if err != nil {
-			return nil, err
+// GetHistoryPaginated returns up to pageSize versions of a Pokemon, most recent first, starting
+// after the version whose transaction ID is given as bookmark. An empty bookmark starts from the
+// current version and a pageSize of 0 returns every remaining version. The returned bookmark is
+// empty once the oldest version has been returned.
+func (s *SmartContract) GetHistoryPaginated(ctx contractapi.TransactionContextInterface, id string, pageSize int, bookmark string) (*HistoryPage, error) {
+	if pageSize < 0 {
+		return nil, fmt.Errorf("pageSize must not be negative")
+	}
+
+	modifications, err := pokemonNamespace.History(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &HistoryPage{}
+	skipping := bookmark != ""
+	for _, resp := range modifications {
+		if skipping {
+			skipping = resp.TxId != bookmark
+			continue
 		}
-		var tx string
-		if resp.IsDelete {
-			tx = fmt.Sprintf("Deleted at TxID: %s", resp.TxId)
-		} else {
-			tx = fmt.Sprintf("TxID: %s, Data: %s", resp.TxId, string(resp.Value))
+		if pageSize > 0 && len(page.Records) == pageSize {
+			page.Bookmark = page.Records[len(page.Records)-1].TxID
+			break
 		}
-		history = append(history, tx)
+
+		record := &HistoryRecord{
+			TxID:      resp.TxId,
+			Timestamp: time.Unix(resp.Timestamp.Seconds, int64(resp.Timestamp.Nanos)).UTC().Format(time.RFC3339),
+			IsDelete:  resp.IsDelete,
+		}
+		if !resp.IsDelete && len(resp.Value) > 0 {
+			record.Pokemon, err = decodePokemon(resp.Value)
+			if err != nil {
+				return nil, err
+			}
+		}
+		page.Records = append(page.Records, record)
 	}
+	if skipping {
+		return nil, fmt.Errorf("bookmark %s is not in the history of Pokemon %s", bookmark, id)
+	}
+	page.FetchedRecordsCount = int32(len(page.Records))
 
-	return history, nil
+	return page, nil
 }
 
+// PokemonExists returns true when a Pokemon with the given ID exists or was deleted, as the IDs of
+// deleted Pokemon stay reserved for a restore
 func (s *SmartContract) PokemonExists(ctx contractapi.TransactionContextInterface, id string) (bool, error) {
-	pokeJSON, err := ctx.GetStub().GetState(id)
+	pokeJSON, err := ctx.GetStub().GetState(pokemonNamespace.Key(id))
 	if err != nil {
 		return false, err
 	}
-	return pokeJSON != nil, nil
+	if pokeJSON != nil {
+		return true, nil
+	}
+
+	return deletedPokemonRepo.Exists(ctx, pokemonAssetType, id)
+}
+
+// GetAuditTrail returns up to pageSize entries of the audit trail, from the transaction startTx up
+// to but excluding endTx in transaction ID order. Evaluate it rather than submitting it.
+func (s *SmartContract) GetAuditTrail(ctx contractapi.TransactionContextInterface, startTx string, endTx string, pageSize int) (*authz.AuditPage, error) {
+	return authz.AuditTrail(ctx, startTx, endTx, pageSize)
 }
 
+// SetLogLevel changes the chaincode's log level until it restarts, after which
+// CORE_CHAINCODE_LOGGING_LEVEL applies again. Submit it so that every endorsing peer's chaincode
+// runs it. Admin only.
+func (s *SmartContract) SetLogLevel(ctx contractapi.TransactionContextInterface, level string) error {
+	err := requireAdmin(ctx)
+	if err != nil {
+		return err
+	}
+
+	return authz.SetLogLevel(level)
+}
+
+// MigratePokemonKeys moves up to limit Pokemon from the plain IDs they were stored under before
+// pokemonNamespace to their POKE_ keys and returns how many it moved. Submit it until it moves
+// none; Pokemon not yet moved are invisible to the contract. Admin only.
+func (s *SmartContract) MigratePokemonKeys(ctx contractapi.TransactionContextInterface, limit int) (int, error) {
+	err := requireAdmin(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return pokemonNamespace.Migrate(ctx, limit)
+}
+
+// PruneIdempotencyKeys forgets up to limit idempotency keys whose retention has run out and returns
+// how many it forgot. Submit it periodically from a single scheduler. Admin only.
+func (s *SmartContract) PruneIdempotencyKeys(ctx contractapi.TransactionContextInterface, limit int) (int, error) {
+	err := requireAdmin(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return repository.PruneIdempotencyRecords(ctx, limit)
+}
+
+// audit is the BeforeTransaction handler of the Pokemon contract. Fabric refuses paginated queries
+// in a transaction that has written, so the functions running one are not audited.
+var audit = authz.Audit("GetPokemonsByTrainerPaginated", "GetTrainerBadgesPaginated", "GetAuditTrail")
+
 func main() {
-	cc, err := contractapi.NewChaincode(new(SmartContract))
+	pokemonContract, speciesRegistry := new(SmartContract), new(SpeciesRegistry)
+	pokemonContract.TransactionContextHandler = new(authz.TransactionContext)
+	speciesRegistry.TransactionContextHandler = new(authz.TransactionContext)
+	pokemonContract.BeforeTransaction = audit
+	speciesRegistry.BeforeTransaction = authz.Audit()
+
+	cc, err := contractapi.NewChaincode(pokemonContract, speciesRegistry)
 	if err != nil {
-		panic(fmt.Sprintf("Error creating Pokemon chaincode: %v", err))
+		authz.Logger.Error("failed to create Pokemon chaincode", "error", err)
+		os.Exit(1)
 	}
 
-	if err := cc.Start(); err != nil {
-		panic(fmt.Sprintf("Error starting chaincode: %v", err))
+	if err := ccaas.Start(cc); err != nil {
+		authz.Logger.Error("failed to start Pokemon chaincode", "error", err)
+		os.Exit(1)
 	}
 }
 
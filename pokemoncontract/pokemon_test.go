@@ -0,0 +1,428 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"chaincodetest"
+	"chaincodetest/chaincodefakes"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var (
+	ash   = chaincodetest.Identity{MSPID: "Org1MSP", CommonName: "Ash"}
+	red   = chaincodetest.Identity{MSPID: "Org1MSP", CommonName: "Red"}
+	misty = chaincodetest.Identity{MSPID: "Org2MSP", CommonName: "Misty"}
+	brock = chaincodetest.Identity{MSPID: "Org2MSP", CommonName: "Brock", Attributes: map[string]string{"role": gymLeaderRole, "gym": "Pewter City"}}
+	admin = chaincodetest.Identity{MSPID: "Org1MSP", CommonName: "admin1", Attributes: map[string]string{"role": "admin"}}
+)
+
+// newLedger returns a ledger holding the sample Pokemon, species and type chart of InitLedger
+func newLedger(t *testing.T) *chaincodetest.Ledger {
+	ledger := chaincodetest.NewLedger()
+	ctx, _ := ledger.Tx(admin)
+	require.NoError(t, new(SmartContract).InitLedger(ctx))
+
+	return ledger
+}
+
+// readPokemon returns a check reading a Pokemon and passing it to check on success
+func readPokemon(id string, check func(t *testing.T, p *Pokemon)) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+	return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		p, err := new(SmartContract).ReadPokemon(ctx, id)
+		if err == nil && check != nil {
+			check(t, p)
+		}
+		return err
+	}
+}
+
+// lastEvent returns the name of the chaincode event the transaction set last and decodes its
+// payload into v
+func lastEvent(t *testing.T, ctx contractapi.TransactionContextInterface, v interface{}) string {
+	stub := ctx.GetStub().(*chaincodefakes.ChaincodeStub)
+	require.NotZero(t, stub.SetEventCallCount())
+	name, payload := stub.SetEventArgsForCall(stub.SetEventCallCount() - 1)
+
+	require.NoError(t, json.Unmarshal(payload, v))
+	return name
+}
+
+func TestPokemon(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	update := func(id string, trainer string, power int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.UpdatePokemon(ctx, id, trainer, power)
+		}
+	}
+	evolve := func(id string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.EvolvePokemon(ctx, id)
+		}
+	}
+	exists := func(id string, want bool) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			exists, err := s.PokemonExists(ctx, id)
+			require.Equal(t, want, exists)
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{
+			Name:   "read sample Pokemon",
+			Caller: ash,
+			Run: readPokemon("poke1", func(t *testing.T, p *Pokemon) {
+				require.Equal(t, &Pokemon{ID: "poke1", Name: "Pikachu", Type: "Electric", Power: 55, Level: 1, Trainer: "Ash", EvolutionStage: 1, Location: "Pallet Town"}, p)
+			}),
+		},
+		{Name: "read missing Pokemon", Caller: ash, Run: readPokemon("poke9", nil), Err: "Pokemon poke9 does not exist"},
+		{
+			Name:   "create Pokemon",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.CreatePokemon(ctx, "poke4", "Bulbasaur", "Grass", "Ash", "Pallet Town", 45)
+			},
+		},
+		{
+			Name:   "created Pokemon start at level 1",
+			Caller: ash,
+			Run: readPokemon("poke4", func(t *testing.T, p *Pokemon) {
+				require.Equal(t, 1, p.Level)
+				require.Equal(t, 1, p.EvolutionStage)
+			}),
+		},
+		{
+			Name:   "create duplicate",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.CreatePokemon(ctx, "poke4", "Bulbasaur", "Grass", "Ash", "Pallet Town", 45)
+			},
+			Err: "Pokemon poke4 already exists",
+		},
+		{Name: "Pokemon exists", Caller: ash, Run: exists("poke4", true)},
+		{Name: "Pokemon does not exist", Caller: ash, Run: exists("poke9", false)},
+		{Name: "update power", Caller: ash, Run: update("poke4", "Ash", 50)},
+		{Name: "update cannot change the trainer", Caller: ash, Run: update("poke4", "Red", 50), Err: "Pokemon poke4 is trained by Ash, use TransferPokemon to change its trainer"},
+		{Name: "update missing Pokemon", Caller: ash, Run: update("poke9", "Ash", 50), Err: "Pokemon poke9 does not exist"},
+		{Name: "only the trainer updates", Caller: misty, Run: update("poke4", "Ash", 99), Err: "submitting client Misty is not authorized for this Pokemon"},
+		{Name: "evolve below the minimum power", Caller: ash, Run: evolve("poke1"), Err: "Pikachu needs 60 power to evolve, Pokemon poke1 has 55"},
+		{Name: "power up", Caller: ash, Run: update("poke1", "Ash", 60)},
+		{Name: "evolve", Caller: ash, Run: evolve("poke1")},
+		{
+			Name:   "evolved Pokemon take the next species and grow",
+			Caller: ash,
+			Run: readPokemon("poke1", func(t *testing.T, p *Pokemon) {
+				require.Equal(t, "Raichu", p.Name)
+				require.Equal(t, 2, p.EvolutionStage)
+				require.Equal(t, 60+evolutionBonus("Electric", 1), p.Power)
+			}),
+		},
+		{
+			Name:   "evolutions are kept in the lineage",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				lineage, err := s.GetEvolutionLineage(ctx, "poke1")
+				if err == nil {
+					require.Len(t, lineage, 1)
+					require.Equal(t, "Pikachu", lineage[0].FromSpecies)
+					require.Equal(t, "Raichu", lineage[0].ToSpecies)
+					require.Equal(t, 60, lineage[0].PowerBefore)
+					require.Equal(t, 2, lineage[0].Stage)
+				}
+				return err
+			},
+		},
+		{Name: "evolve a final form", Caller: ash, Run: evolve("poke1"), Err: "Raichu does not evolve any further"},
+		{Name: "evolve an unregistered species", Caller: ash, Run: evolve("poke4"), Err: "species Bulbasaur is not registered"},
+		{
+			Name:   "write a record from before evolution stages and levels",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return ctx.GetStub().PutState(pokemonNamespace.Key("poke5"), []byte(`{"id":"poke5","name":"Raichu","type":"Electric","power":70,"experience":100,"trainer":"Ash","evolved":true}`))
+			},
+		},
+		{
+			Name:   "legacy records are upgraded on read",
+			Caller: ash,
+			Run: readPokemon("poke5", func(t *testing.T, p *Pokemon) {
+				require.Equal(t, 2, p.EvolutionStage)
+				require.Equal(t, 3, p.Level)
+				require.Equal(t, 70, p.Power)
+			}),
+		},
+		{
+			Name:   "delete Pokemon",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.DeletePokemon(ctx, "poke4", "released")
+			},
+		},
+		{Name: "deleted Pokemon IDs stay reserved", Caller: ash, Run: exists("poke4", true)},
+		{
+			Name:   "delete a deleted Pokemon",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.DeletePokemon(ctx, "poke4", "released")
+			},
+			Err: "Pokemon poke4 was deleted on",
+		},
+		{
+			Name:   "delete missing Pokemon",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.DeletePokemon(ctx, "poke9", "released")
+			},
+			Err: "Pokemon poke9 does not exist",
+		},
+	})
+}
+
+func TestRestorePokemon(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	remove := func(id string, reason string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.DeletePokemon(ctx, id, reason)
+		}
+	}
+	restore := func(id string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.RestorePokemon(ctx, id)
+		}
+	}
+	trained := func(includeDeleted bool, want ...string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			pokemons, err := s.GetPokemonsByTrainer(ctx, "Ash", includeDeleted)
+			if err == nil {
+				var ids []string
+				for _, p := range pokemons {
+					ids = append(ids, p.ID)
+					if p.Deleted {
+						require.Equal(t, "released into the wild", p.DeletionReason)
+						require.Equal(t, "Ash", p.DeletedBy)
+					}
+				}
+				require.Equal(t, want, ids)
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "delete without a reason", Caller: ash, Run: remove("poke1", ""), Err: "deletion reason must not be empty"},
+		{Name: "delete poke1", Caller: ash, Run: remove("poke1", "released into the wild")},
+		{Name: "deleted Pokemon are not listed", Caller: ash, Run: trained(false)},
+		{Name: "list deleted Pokemon", Caller: ash, Run: trained(true, "poke1")},
+		{Name: "read a deleted Pokemon", Caller: ash, Run: readPokemon("poke1", nil), Err: "Pokemon poke1 was deleted on"},
+		{Name: "restore requires admin", Caller: ash, Run: restore("poke1"), Err: "requires role admin"},
+		{Name: "restore a live Pokemon", Caller: admin, Run: restore("poke2"), Err: "Pokemon poke2 is not deleted"},
+		{Name: "restore poke1", Caller: admin, Run: restore("poke1")},
+		{Name: "a restored Pokemon is listed again", Caller: ash, Run: trained(false, "poke1")},
+		{
+			Name:   "a restored Pokemon is as it was",
+			Caller: ash,
+			Run: readPokemon("poke1", func(t *testing.T, p *Pokemon) {
+				require.Equal(t, "Pikachu", p.Name)
+				require.False(t, p.Deleted)
+				require.Empty(t, p.DeletedAt)
+			}),
+		},
+	})
+}
+
+func TestMigratePokemonKeys(t *testing.T) {
+	s := new(SmartContract)
+	ledger := chaincodetest.NewLedger()
+
+	migrate := func(moved int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			count, err := s.MigratePokemonKeys(ctx, 10)
+			if err == nil {
+				require.Equal(t, moved, count)
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{
+			Name:   "Pokemon under its plain ID",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return ctx.GetStub().PutState("poke1", []byte(`{"id":"poke1","name":"Pikachu","type":"Electric","power":55,"trainer":"Ash","evolutionStage":1}`))
+			},
+		},
+		{Name: "only admins migrate", Caller: ash, Run: migrate(0), Err: "requires role admin"},
+		{Name: "migrate", Caller: admin, Run: migrate(1)},
+		{Name: "nothing left to migrate", Caller: admin, Run: migrate(0)},
+		{Name: "the migrated Pokemon", Caller: ash, Run: readPokemon("poke1", func(t *testing.T, p *Pokemon) {
+			require.Equal(t, "Pikachu", p.Name)
+		})},
+		{
+			Name:   "the migrated Pokemon keeps its history",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				page, err := s.GetHistoryPaginated(ctx, "poke1", 0, "")
+				if err == nil {
+					require.Len(t, page.Records, 2)
+					require.Equal(t, "tx1", page.Records[1].TxID)
+					require.False(t, page.Records[1].IsDelete)
+				}
+				return err
+			},
+		},
+	})
+}
+
+func TestGetHistory(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	history := func(pageSize int, bookmark string, want []string, next string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			page, err := s.GetHistoryPaginated(ctx, "poke1", pageSize, bookmark)
+			if err == nil {
+				var txIDs []string
+				for _, record := range page.Records {
+					txIDs = append(txIDs, record.TxID)
+				}
+				require.Equal(t, want, txIDs)
+				require.Equal(t, int32(len(want)), page.FetchedRecordsCount)
+				require.Equal(t, next, page.Bookmark)
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{
+			Name:   "power up",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.UpdatePokemon(ctx, "poke1", "Ash", 58)
+			},
+		},
+		{
+			Name:   "delete",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.DeletePokemon(ctx, "poke1", "released")
+			},
+		},
+		{
+			Name:   "every version, most recent first",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				records, err := s.GetHistory(ctx, "poke1")
+				if err == nil {
+					require.Len(t, records, 3)
+					require.True(t, records[0].IsDelete)
+					require.Nil(t, records[0].Pokemon)
+					require.Equal(t, 58, records[1].Pokemon.Power)
+					require.Equal(t, "tx1", records[2].TxID)
+					require.Equal(t, "2024-01-01T09:00:01Z", records[2].Timestamp)
+				}
+				return err
+			},
+		},
+		{Name: "first page", Caller: ash, Run: history(2, "", []string{"tx3", "tx2"}, "tx2")},
+		{Name: "last page", Caller: ash, Run: history(2, "tx2", []string{"tx1"}, "")},
+		{Name: "unknown bookmark", Caller: ash, Run: history(2, "tx9", nil, ""), Err: "bookmark tx9 is not in the history of Pokemon poke1"},
+		{Name: "negative page size", Caller: ash, Run: history(-1, "", nil, ""), Err: "pageSize must not be negative"},
+		{
+			Name:   "corrupt version",
+			Caller: ash,
+			Stub: func(stub *chaincodefakes.ChaincodeStub) {
+				stub.GetHistoryForKeyReturns(chaincodetest.HistoryIterator(&queryresult.KeyModification{TxId: "tx9", Value: []byte("{"), Timestamp: timestamppb.New(ledger.Now())}), nil)
+			},
+			Run: history(0, "", nil, ""),
+			Err: "unexpected end of JSON input",
+		},
+	})
+}
+
+func TestGetAuditTrail(t *testing.T) {
+	s := new(SmartContract)
+	ledger := chaincodetest.NewLedger()
+
+	var cases []chaincodetest.Case
+	for _, function := range []string{"CreatePokemon", "GetAuditTrail", "SpeciesRegistry:RegisterSpecies"} {
+		function := function
+		cases = append(cases, chaincodetest.Case{
+			Name:   "audit " + function,
+			Caller: ash,
+			Stub: func(stub *chaincodefakes.ChaincodeStub) {
+				stub.GetFunctionAndParametersReturns(function, nil)
+			},
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return audit(ctx)
+			},
+		})
+	}
+	cases = append(cases, chaincodetest.Case{
+		Name:   "read the trail",
+		Caller: admin,
+		Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			page, err := s.GetAuditTrail(ctx, "", "", 10)
+			if err == nil {
+				require.Len(t, page.Entries, 2)
+				require.Equal(t, "CreatePokemon", page.Entries[0].Function)
+				require.Equal(t, "tx3", page.Entries[1].TxID)
+				require.Equal(t, "SpeciesRegistry:RegisterSpecies", page.Entries[1].Function)
+			}
+			return err
+		},
+	})
+
+	ledger.Run(t, cases)
+}
+
+func TestAuditSkipsPaginatedQueries(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	// paginated runs the audit handler as Fabric does before the function, then the function's
+	// paginated query, which fails if the handler wrote
+	paginated := func(function string, query func(ctx contractapi.TransactionContextInterface) error) chaincodetest.Case {
+		return chaincodetest.Case{
+			Name:   function,
+			Caller: admin,
+			Stub: func(stub *chaincodefakes.ChaincodeStub) {
+				stub.GetFunctionAndParametersReturns(function, nil)
+			},
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				err := audit(ctx)
+				if err != nil {
+					return err
+				}
+				return query(ctx)
+			},
+		}
+	}
+	listPokemons := func(ctx contractapi.TransactionContextInterface) error {
+		_, err := s.GetPokemonsByTrainerPaginated(ctx, "Ash", 10, "")
+		return err
+	}
+
+	audited := paginated("CreatePokemon", listPokemons)
+	audited.Err = "has written and cannot run a paginated query"
+	ledger.Run(t, []chaincodetest.Case{
+		paginated("GetPokemonsByTrainerPaginated", listPokemons),
+		paginated("GetTrainerBadgesPaginated", func(ctx contractapi.TransactionContextInterface) error {
+			_, err := s.GetTrainerBadgesPaginated(ctx, "Ash", 10, "")
+			return err
+		}),
+		paginated("GetAuditTrail", func(ctx contractapi.TransactionContextInterface) error {
+			_, err := s.GetAuditTrail(ctx, "", "", 10)
+			return err
+		}),
+		audited,
+	})
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+const (
+	// tradeCollection is the private data collection shared by the two trading organizations
+	tradeCollection = "pokemonTradeCollection"
+
+	privateTradeObjectType = "privatetrade"
+	tradePriceObjectType   = "tradeprice"
+
+	// tradePriceKey is the transient key carrying the negotiated price of a private trade
+	tradePriceKey = "trade_price"
+)
+
+var privateTradeRepo = repository.New[PrivateTrade](privateTradeObjectType)
+
+// PrivateTrade is a proposed trade of a Pokemon whose price is only known to the two trading
+// organizations. The public record names the parties; the price is held in tradeCollection.
+type PrivateTrade struct {
+	PokemonID  string `json:"pokemonId"`
+	Seller     string `json:"seller"`
+	Buyer      string `json:"buyer"`
+	ProposalID string `json:"proposalId"` // transaction ID of the proposal, keying the private price
+	ProposedAt string `json:"proposedAt"`
+}
+
+// TradePrice is the negotiated price of a private trade, stored in tradeCollection
+type TradePrice struct {
+	PokemonID  string `json:"pokemonId"`
+	ProposalID string `json:"proposalId"`
+	Seller     string `json:"seller"`
+	Buyer      string `json:"buyer"`
+	Price      int    `json:"price"`
+}
+
+// ProposePrivateTrade offers a Pokemon of the submitting trainer to a buyer at the price passed in
+// the trade_price transient field. Only the proposal's parties become public; the price is
+// written to the trade collection. The Pokemon changes hands once the buyer accepts at the same
+// price with AcceptPrivateTrade.
+func (s *SmartContract) ProposePrivateTrade(ctx contractapi.TransactionContextInterface, id string, buyer string) (*PrivateTrade, error) {
+	p, err := s.ReadPokemon(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	err = requireTrainer(ctx, p.Trainer)
+	if err != nil {
+		return nil, err
+	}
+	err = checkNotLent(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if buyer == "" || buyer == p.Trainer {
+		return nil, fmt.Errorf("buyer must be another trainer")
+	}
+	price, err := transientPrice(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := getPrivateTrade(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if pending != nil {
+		return nil, fmt.Errorf("Pokemon %s already has a pending private trade with %s", id, pending.Buyer)
+	}
+	transfer, err := getTransfer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if transfer != nil {
+		return nil, fmt.Errorf("Pokemon %s has a pending transfer to %s", id, transfer.NewTrainer)
+	}
+	gift, err := getGift(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if gift != nil {
+		return nil, fmt.Errorf("Pokemon %s has a pending gift to %s", id, gift.Recipient)
+	}
+	listing, err := getListing(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if listing != nil {
+		return nil, fmt.Errorf("Pokemon %s is listed for sale, cancel the listing first", id)
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	trade := PrivateTrade{
+		PokemonID:  id,
+		Seller:     p.Trainer,
+		Buyer:      buyer,
+		ProposalID: ctx.GetStub().GetTxID(),
+		ProposedAt: now.Format(time.RFC3339),
+	}
+	priceKey, priceJSON, err := tradePrice(ctx, &trade, price)
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.GetStub().PutPrivateData(tradeCollection, priceKey, priceJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to put private trade price: %v", err)
+	}
+	err = putPrivateTrade(ctx, &trade)
+	if err != nil {
+		return nil, err
+	}
+
+	return &trade, nil
+}
+
+// AcceptPrivateTrade completes a pending private trade. Only the buyer may accept, passing the
+// agreed price in the trade_price transient field; it is checked against the hash of the
+// seller's price, so the price itself never reaches the public ledger.
+func (s *SmartContract) AcceptPrivateTrade(ctx contractapi.TransactionContextInterface, id string) error {
+	trade, err := s.ReadPrivateTrade(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = requireTrainer(ctx, trade.Buyer)
+	if err != nil {
+		return err
+	}
+	price, err := transientPrice(ctx)
+	if err != nil {
+		return err
+	}
+
+	priceKey, priceJSON, err := tradePrice(ctx, trade, price)
+	if err != nil {
+		return err
+	}
+	sellerHash, err := ctx.GetStub().GetPrivateDataHash(tradeCollection, priceKey)
+	if err != nil {
+		return fmt.Errorf("failed to read private trade price hash: %v", err)
+	}
+	if sellerHash == nil {
+		return fmt.Errorf("the price of the private trade of Pokemon %s was not found", id)
+	}
+	buyerHash := sha256.Sum256(priceJSON)
+	if !bytes.Equal(sellerHash, buyerHash[:]) {
+		return fmt.Errorf("the price does not match the price proposed by %s", trade.Seller)
+	}
+
+	p, err := s.ReadPokemon(ctx, id)
+	if err != nil {
+		return err
+	}
+	if p.Trainer != trade.Seller {
+		return fmt.Errorf("Pokemon %s is no longer trained by %s", id, trade.Seller)
+	}
+	before := *p
+	p.Trainer = trade.Buyer
+	pokeJSON, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().PutState(pokemonNamespace.Key(id), pokeJSON)
+	if err != nil {
+		return err
+	}
+	err = updateRollups(ctx, []*Pokemon{&before}, []*Pokemon{p})
+	if err != nil {
+		return err
+	}
+
+	return deletePrivateTrade(ctx, id)
+}
+
+// CancelPrivateTrade withdraws a pending private trade. Either party may cancel it. The proposed
+// price stays in the trade collection.
+func (s *SmartContract) CancelPrivateTrade(ctx contractapi.TransactionContextInterface, id string) error {
+	trade, err := s.ReadPrivateTrade(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = requireTrainer(ctx, trade.Seller, trade.Buyer)
+	if err != nil {
+		return err
+	}
+
+	return deletePrivateTrade(ctx, id)
+}
+
+// ReadPrivateTrade returns the pending private trade of a Pokemon, without its price
+func (s *SmartContract) ReadPrivateTrade(ctx contractapi.TransactionContextInterface, id string) (*PrivateTrade, error) {
+	trade, err := getPrivateTrade(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if trade == nil {
+		return nil, fmt.Errorf("Pokemon %s has no pending private trade", id)
+	}
+
+	return trade, nil
+}
+
+// ReadTradePrice returns the price of a private trade proposal from the trade collection. Only
+// peers of the trading organizations hold it.
+func (s *SmartContract) ReadTradePrice(ctx contractapi.TransactionContextInterface, id string, proposalID string) (*TradePrice, error) {
+	priceKey, err := ctx.GetStub().CreateCompositeKey(tradePriceObjectType, []string{id, proposalID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	priceJSON, err := ctx.GetStub().GetPrivateData(tradeCollection, priceKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private trade price: %v", err)
+	}
+	if priceJSON == nil {
+		return nil, fmt.Errorf("no price of proposal %s for Pokemon %s", proposalID, id)
+	}
+
+	var price TradePrice
+	err = json.Unmarshal(priceJSON, &price)
+	if err != nil {
+		return nil, err
+	}
+
+	return &price, nil
+}
+
+// tradePrice returns the private data key and value of a trade's price. Seller and buyer both
+// build the value from the public trade record, so equal prices give equal hashes.
+func tradePrice(ctx contractapi.TransactionContextInterface, trade *PrivateTrade, price int) (string, []byte, error) {
+	priceKey, err := ctx.GetStub().CreateCompositeKey(tradePriceObjectType, []string{trade.PokemonID, trade.ProposalID})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	priceJSON, err := json.Marshal(TradePrice{
+		PokemonID:  trade.PokemonID,
+		ProposalID: trade.ProposalID,
+		Seller:     trade.Seller,
+		Buyer:      trade.Buyer,
+		Price:      price,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	return priceKey, priceJSON, nil
+}
+
+// transientPrice reads the trade price from the transient map
+func transientPrice(ctx contractapi.TransactionContextInterface) (int, error) {
+	transientMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return 0, fmt.Errorf("error getting transient: %v", err)
+	}
+	value, ok := transientMap[tradePriceKey]
+	if !ok {
+		return 0, fmt.Errorf("%s must be passed in the transient map", tradePriceKey)
+	}
+	price, err := strconv.Atoi(string(value))
+	if err != nil || price <= 0 {
+		return 0, fmt.Errorf("%s must be a positive whole number", tradePriceKey)
+	}
+
+	return price, nil
+}
+
+func getPrivateTrade(ctx contractapi.TransactionContextInterface, id string) (*PrivateTrade, error) {
+	return privateTradeRepo.Get(ctx, id)
+}
+
+func putPrivateTrade(ctx contractapi.TransactionContextInterface, trade *PrivateTrade) error {
+	return privateTradeRepo.Put(ctx, trade, trade.PokemonID)
+}
+
+func deletePrivateTrade(ctx contractapi.TransactionContextInterface, id string) error {
+	tradeKey, err := ctx.GetStub().CreateCompositeKey(privateTradeObjectType, []string{id})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	return ctx.GetStub().DelState(tradeKey)
+}
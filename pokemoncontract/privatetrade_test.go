@@ -0,0 +1,101 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"chaincodetest/chaincodefakes"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrivateTrades(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	price := func(value string) func(stub *chaincodefakes.ChaincodeStub) {
+		return func(stub *chaincodefakes.ChaincodeStub) {
+			stub.GetTransientReturns(map[string][]byte{tradePriceKey: []byte(value)}, nil)
+		}
+	}
+	propose := func(buyer string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.ProposePrivateTrade(ctx, "poke1", buyer)
+			return err
+		}
+	}
+	accept := func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return s.AcceptPrivateTrade(ctx, "poke1")
+	}
+	cancel := func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return s.CancelPrivateTrade(ctx, "poke1")
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "propose without a price", Caller: ash, Run: propose("Red"), Err: "trade_price must be passed in the transient map"},
+		{Name: "propose a malformed price", Caller: ash, Stub: price("forty"), Run: propose("Red"), Err: "trade_price must be a positive whole number"},
+		{Name: "propose to oneself", Caller: ash, Stub: price("40"), Run: propose("Ash"), Err: "buyer must be another trainer"},
+		{Name: "propose another trainer's Pokemon", Caller: red, Stub: price("40"), Run: propose("Red"), Err: "submitting client Red is not authorized for this Pokemon"},
+		{
+			Name:   "propose",
+			Caller: ash,
+			Stub:   price("40"),
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				trade, err := s.ProposePrivateTrade(ctx, "poke1", "Red")
+				if err == nil {
+					require.Equal(t, ctx.GetStub().GetTxID(), trade.ProposalID)
+					require.Equal(t, "Ash", trade.Seller)
+				}
+				return err
+			},
+		},
+		{
+			Name:   "the price is kept in the trade collection",
+			Caller: red,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				trade, err := s.ReadPrivateTrade(ctx, "poke1")
+				require.NoError(t, err)
+				require.Equal(t, "Red", trade.Buyer)
+
+				price, err := s.ReadTradePrice(ctx, "poke1", trade.ProposalID)
+				if err == nil {
+					require.Equal(t, 40, price.Price)
+				}
+				return err
+			},
+		},
+		{
+			Name:   "read an unknown proposal's price",
+			Caller: red,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.ReadTradePrice(ctx, "poke1", "tx99")
+				return err
+			},
+			Err: "no price of proposal tx99 for Pokemon poke1",
+		},
+		{Name: "propose twice", Caller: ash, Stub: price("40"), Run: propose("Misty"), Err: "Pokemon poke1 already has a pending private trade with Red"},
+		{
+			Name:   "Pokemon in a private trade cannot be listed",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.ListForSale(ctx, "poke1", 50)
+			},
+			Err: "Pokemon poke1 has a pending private trade with Red",
+		},
+		{Name: "accept as another trainer", Caller: misty, Stub: price("40"), Run: accept, Err: "submitting client Misty is not authorized for this Pokemon"},
+		{Name: "accept at another price", Caller: red, Stub: price("30"), Run: accept, Err: "the price does not match the price proposed by Ash"},
+		{Name: "accept", Caller: red, Stub: price("40"), Run: accept},
+		{
+			Name:   "traded Pokemon move to the buyer",
+			Caller: red,
+			Run: readPokemon("poke1", func(t *testing.T, p *Pokemon) {
+				require.Equal(t, "Red", p.Trainer)
+			}),
+		},
+		{Name: "accept a completed trade", Caller: red, Stub: price("40"), Run: accept, Err: "Pokemon poke1 has no pending private trade"},
+		{Name: "propose onwards", Caller: red, Stub: price("60"), Run: propose("Misty")},
+		{Name: "cancel as another trainer", Caller: ash, Run: cancel, Err: "submitting client Ash is not authorized for this Pokemon"},
+		{Name: "buyers cancel", Caller: misty, Run: cancel},
+		{Name: "cancel a cancelled trade", Caller: red, Run: cancel, Err: "Pokemon poke1 has no pending private trade"},
+	})
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// updateRollups adjusts the trainer rollups and the trainer and location indexes for Pokemon
+// removed from and added to their trainers in one transaction
+func updateRollups(ctx contractapi.TransactionContextInterface, removed []*Pokemon, added []*Pokemon) error {
+	err := updateTrainerIndex(ctx, removed, added)
+	if err != nil {
+		return err
+	}
+	err = updateLocationIndex(ctx, removed, added)
+	if err != nil {
+		return err
+	}
+
+	batch := make(statsBatch)
+	err = batch.applyRollups(ctx, removed, added)
+	if err != nil {
+		return err
+	}
+
+	return batch.save(ctx)
+}
+
+// statsBatch collects the trainer stats changed by a transaction. Each trainer's stats are read
+// and written once, however many of their Pokemon the transaction changes.
+type statsBatch map[string]*TrainerStats
+
+// load returns the trainer's stats from the batch, reading them from the world state on first use
+func (b statsBatch) load(ctx contractapi.TransactionContextInterface, trainer string) (*TrainerStats, error) {
+	if stats, ok := b[trainer]; ok {
+		return stats, nil
+	}
+	stats, err := loadTrainerStats(ctx, trainer)
+	if err != nil {
+		return nil, err
+	}
+	if stats.CountByType == nil {
+		stats.CountByType = make(map[string]int)
+	}
+	b[trainer] = stats
+	return stats, nil
+}
+
+// applyRollups removes and adds the Pokemon's contributions to their trainers' rollups
+func (b statsBatch) applyRollups(ctx contractapi.TransactionContextInterface, removed []*Pokemon, added []*Pokemon) error {
+	for _, p := range removed {
+		stats, err := b.load(ctx, p.Trainer)
+		if err != nil {
+			return err
+		}
+		addToRollup(stats, p, -1)
+	}
+	for _, p := range added {
+		stats, err := b.load(ctx, p.Trainer)
+		if err != nil {
+			return err
+		}
+		addToRollup(stats, p, 1)
+	}
+
+	return nil
+}
+
+// save writes every trainer's stats in the batch
+func (b statsBatch) save(ctx contractapi.TransactionContextInterface) error {
+	trainers := make([]string, 0, len(b))
+	for trainer := range b {
+		trainers = append(trainers, trainer)
+	}
+	sort.Strings(trainers)
+	for _, trainer := range trainers {
+		err := putTrainerStats(ctx, b[trainer])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addToRollup adds (sign 1) or removes (sign -1) a Pokemon's contribution to a trainer's rollup
+func addToRollup(stats *TrainerStats, p *Pokemon, sign int) {
+	stats.PokemonCount += sign
+	stats.TotalPower += sign * p.Power
+	stats.CountByType[p.Type] += sign
+	if stats.CountByType[p.Type] <= 0 {
+		delete(stats.CountByType, p.Type)
+	}
+	if p.EvolutionStage > 1 {
+		stats.EvolvedCount += sign
+	}
+}
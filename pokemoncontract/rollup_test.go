@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrainerStats(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	rollup := func(trainer string, count int, totalPower int, countByType map[string]int, evolved int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			stats, err := s.GetTrainerStats(ctx, trainer)
+			if err == nil {
+				require.Equal(t, count, stats.PokemonCount)
+				require.Equal(t, totalPower, stats.TotalPower)
+				require.Equal(t, countByType, stats.CountByType)
+				require.Equal(t, evolved, stats.EvolvedCount)
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "sample Pokemon are rolled up", Caller: ash, Run: rollup("Ash", 1, 55, map[string]int{"Electric": 1}, 0)},
+		{Name: "trainers without Pokemon", Caller: ash, Run: rollup("Brock", 0, 0, nil, 0)},
+		{
+			Name:   "create Pokemon",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.CreatePokemon(ctx, "poke4", "Raichu", "Electric", "Ash", "Pallet Town", 70)
+			},
+		},
+		{
+			Name:   "evolve",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				err := s.UpdatePokemon(ctx, "poke1", "Ash", 60)
+				require.NoError(t, err)
+				return s.EvolvePokemon(ctx, "poke1")
+			},
+		},
+		{Name: "changes are rolled up", Caller: ash, Run: rollup("Ash", 2, 60+evolutionBonus("Electric", 1)+70, map[string]int{"Electric": 2}, 1)},
+		{
+			Name:   "transfer",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.TransferPokemon(ctx, "poke1", "Misty")
+			},
+		},
+		{
+			Name:   "accept transfer",
+			Caller: misty,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.AcceptTransfer(ctx, "poke1")
+			},
+		},
+		{Name: "transfers leave the former trainer", Caller: ash, Run: rollup("Ash", 1, 70, map[string]int{"Electric": 1}, 0)},
+		{Name: "transfers join the new trainer", Caller: misty, Run: rollup("Misty", 2, 48+60+evolutionBonus("Electric", 1), map[string]int{"Electric": 1, "Water": 1}, 1)},
+		{
+			Name:   "delete",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.DeletePokemon(ctx, "poke4", "released")
+			},
+		},
+		{Name: "deletions empty the rollup", Caller: ash, Run: rollup("Ash", 0, 0, nil, 0)},
+	})
+}
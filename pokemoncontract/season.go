@@ -0,0 +1,341 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"authz"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+const (
+	seasonObjectType       = "season"
+	activeSeasonObjectType = "activeseason"
+	trainerStatsObjectType = "trainerstats"
+)
+
+var (
+	seasonRepo       = repository.New[Season](seasonObjectType)
+	trainerStatsRepo = repository.New[TrainerStats](trainerStatsObjectType)
+)
+
+// Season is a competitive epoch. Its leaderboard is snapshotted when the season is closed.
+type Season struct {
+	ID          string             `json:"id"`
+	Open        bool               `json:"open"`
+	OpenedAt    string             `json:"openedAt"`
+	ClosedAt    string             `json:"closedAt"`
+	Leaderboard []LeaderboardEntry `json:"leaderboard,omitempty" metadata:",optional"`
+}
+
+// LeaderboardEntry is one trainer's standing in a season
+type LeaderboardEntry struct {
+	Rank    int    `json:"rank"`
+	Trainer string `json:"trainer"`
+	Wins    int    `json:"wins"`
+	Losses  int    `json:"losses"`
+}
+
+// TrainerStats holds a trainer's battle record and a rollup of the Pokemon they train. Seasonal
+// counters belong to SeasonID and are reset the first time the trainer battles in a later season;
+// lifetime counters are never reset. The rollup is maintained incrementally by every transaction
+// that creates, changes, moves or deletes a Pokemon.
+type TrainerStats struct {
+	Trainer        string `json:"trainer"`
+	SeasonID       string `json:"seasonId"`
+	SeasonWins     int    `json:"seasonWins"`
+	SeasonLosses   int    `json:"seasonLosses"`
+	LifetimeWins   int    `json:"lifetimeWins"`
+	LifetimeLosses int    `json:"lifetimeLosses"`
+
+	PokemonCount int            `json:"pokemonCount"`
+	CountByType  map[string]int `json:"countByType,omitempty" metadata:",optional"`
+	TotalPower   int            `json:"totalPower"`
+	EvolvedCount int            `json:"evolvedCount"`
+}
+
+// OpenSeason starts a new season. Only one season can be open at a time. Admin only.
+func (s *SmartContract) OpenSeason(ctx contractapi.TransactionContextInterface, id string) error {
+	err := requireAdmin(ctx)
+	if err != nil {
+		return err
+	}
+
+	active, err := getActiveSeason(ctx)
+	if err != nil {
+		return err
+	}
+	if active != nil {
+		return fmt.Errorf("season %s is still open", active.ID)
+	}
+	existing, err := getSeason(ctx, id)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("season %s already exists", id)
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	season := Season{
+		ID:       id,
+		Open:     true,
+		OpenedAt: now.Format(time.RFC3339),
+	}
+	err = putSeason(ctx, &season)
+	if err != nil {
+		return err
+	}
+
+	activeKey, err := ctx.GetStub().CreateCompositeKey(activeSeasonObjectType, []string{})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	return ctx.GetStub().PutState(activeKey, []byte(id))
+}
+
+// CloseSeason closes the open season and snapshots its leaderboard. Admin only.
+func (s *SmartContract) CloseSeason(ctx contractapi.TransactionContextInterface) (*Season, error) {
+	err := requireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	season, err := getActiveSeason(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if season == nil {
+		return nil, fmt.Errorf("no season is open")
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(trainerStatsObjectType, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var leaderboard []LeaderboardEntry
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var stats TrainerStats
+		err = json.Unmarshal(queryResponse.Value, &stats)
+		if err != nil {
+			return nil, err
+		}
+		if stats.SeasonID != season.ID {
+			continue
+		}
+		leaderboard = append(leaderboard, LeaderboardEntry{
+			Trainer: stats.Trainer,
+			Wins:    stats.SeasonWins,
+			Losses:  stats.SeasonLosses,
+		})
+	}
+
+	sort.Slice(leaderboard, func(i, j int) bool {
+		if leaderboard[i].Wins != leaderboard[j].Wins {
+			return leaderboard[i].Wins > leaderboard[j].Wins
+		}
+		if leaderboard[i].Losses != leaderboard[j].Losses {
+			return leaderboard[i].Losses < leaderboard[j].Losses
+		}
+		return leaderboard[i].Trainer < leaderboard[j].Trainer
+	})
+	for i := range leaderboard {
+		leaderboard[i].Rank = i + 1
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	season.Open = false
+	season.ClosedAt = now.Format(time.RFC3339)
+	season.Leaderboard = leaderboard
+	err = putSeason(ctx, season)
+	if err != nil {
+		return nil, err
+	}
+
+	activeKey, err := ctx.GetStub().CreateCompositeKey(activeSeasonObjectType, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().DelState(activeKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return season, nil
+}
+
+// RecordBattle records the outcome of a battle between two trainers in the open season. Admin only.
+func (s *SmartContract) RecordBattle(ctx contractapi.TransactionContextInterface, winner string, loser string) error {
+	err := requireAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	if winner == loser {
+		return fmt.Errorf("a trainer cannot battle themselves")
+	}
+
+	season, err := getActiveSeason(ctx)
+	if err != nil {
+		return err
+	}
+	if season == nil {
+		return fmt.Errorf("battles can only be recorded while a season is open")
+	}
+
+	winnerStats, err := getTrainerStats(ctx, winner, season.ID)
+	if err != nil {
+		return err
+	}
+	winnerStats.SeasonWins++
+	winnerStats.LifetimeWins++
+	err = putTrainerStats(ctx, winnerStats)
+	if err != nil {
+		return err
+	}
+
+	loserStats, err := getTrainerStats(ctx, loser, season.ID)
+	if err != nil {
+		return err
+	}
+	loserStats.SeasonLosses++
+	loserStats.LifetimeLosses++
+
+	return putTrainerStats(ctx, loserStats)
+}
+
+// ReadSeason returns the season with the given ID
+func (s *SmartContract) ReadSeason(ctx contractapi.TransactionContextInterface, id string) (*Season, error) {
+	season, err := getSeason(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if season == nil {
+		return nil, fmt.Errorf("season %s does not exist", id)
+	}
+
+	return season, nil
+}
+
+// GetTrainerStats returns a trainer's battle record and Pokemon rollup. Seasonal counters are
+// zero when the trainer has not battled in the current season.
+func (s *SmartContract) GetTrainerStats(ctx contractapi.TransactionContextInterface, trainer string) (*TrainerStats, error) {
+	seasonID := ""
+	season, err := getActiveSeason(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if season != nil {
+		seasonID = season.ID
+	}
+
+	return getTrainerStats(ctx, trainer, seasonID)
+}
+
+// getTrainerStats loads a trainer's stats with the seasonal counters reset for seasonID
+func getTrainerStats(ctx contractapi.TransactionContextInterface, trainer string, seasonID string) (*TrainerStats, error) {
+	stats, err := loadTrainerStats(ctx, trainer)
+	if err != nil {
+		return nil, err
+	}
+	resetSeason(stats, seasonID)
+
+	return stats, nil
+}
+
+// resetSeason clears the seasonal counters when they belong to a season other than seasonID
+func resetSeason(stats *TrainerStats, seasonID string) {
+	if stats.SeasonID != seasonID {
+		stats.SeasonID = seasonID
+		stats.SeasonWins = 0
+		stats.SeasonLosses = 0
+	}
+}
+
+// loadTrainerStats loads a trainer's stats as stored, zero valued if none were recorded yet
+func loadTrainerStats(ctx contractapi.TransactionContextInterface, trainer string) (*TrainerStats, error) {
+	statsKey, err := ctx.GetStub().CreateCompositeKey(trainerStatsObjectType, []string{trainer})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	statsJSON, err := ctx.GetStub().GetState(statsKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+
+	stats := TrainerStats{Trainer: trainer}
+	if statsJSON != nil {
+		err = json.Unmarshal(statsJSON, &stats)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &stats, nil
+}
+
+func putTrainerStats(ctx contractapi.TransactionContextInterface, stats *TrainerStats) error {
+	return trainerStatsRepo.Put(ctx, stats, stats.Trainer)
+}
+
+func getActiveSeason(ctx contractapi.TransactionContextInterface) (*Season, error) {
+	activeKey, err := ctx.GetStub().CreateCompositeKey(activeSeasonObjectType, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	seasonID, err := ctx.GetStub().GetState(activeKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if seasonID == nil {
+		return nil, nil
+	}
+
+	return getSeason(ctx, string(seasonID))
+}
+
+func getSeason(ctx contractapi.TransactionContextInterface, id string) (*Season, error) {
+	return seasonRepo.Get(ctx, id)
+}
+
+func putSeason(ctx contractapi.TransactionContextInterface, season *Season) error {
+	return seasonRepo.Put(ctx, season, season.ID)
+}
+
+// requireAdmin returns an error unless the submitting client carries the role=admin attribute
+func requireAdmin(ctx contractapi.TransactionContextInterface) error {
+	role, _, err := authz.Attribute(ctx, "role")
+	if err != nil {
+		return err
+	}
+	if role != "admin" {
+		return fmt.Errorf("submitting client not authorized, requires role admin")
+	}
+
+	return nil
+}
+
+// txTime returns the transaction timestamp, which is identical on every endorsing peer
+func txTime(ctx contractapi.TransactionContextInterface) (time.Time, error) {
+	ts, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	return time.Unix(ts.Seconds, int64(ts.Nanos)).UTC(), nil
+}
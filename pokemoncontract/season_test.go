@@ -0,0 +1,108 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeasons(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	open := func(id string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.OpenSeason(ctx, id)
+		}
+	}
+	closeSeason := func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		_, err := s.CloseSeason(ctx)
+		return err
+	}
+	record := func(winner string, loser string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.RecordBattle(ctx, winner, loser)
+		}
+	}
+	stats := func(trainer string, seasonID string, seasonWins int, lifetimeWins int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			stats, err := s.GetTrainerStats(ctx, trainer)
+			if err == nil {
+				require.Equal(t, seasonID, stats.SeasonID)
+				require.Equal(t, seasonWins, stats.SeasonWins)
+				require.Equal(t, lifetimeWins, stats.LifetimeWins)
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "record without an open season", Caller: admin, Run: record("Ash", "Red"), Err: "battles can only be recorded while a season is open"},
+		{Name: "close without an open season", Caller: admin, Run: closeSeason, Err: "no season is open"},
+		{Name: "open requires admin", Caller: ash, Run: open("s1"), Err: "requires role admin"},
+		{Name: "open season", Caller: admin, Run: open("s1")},
+		{Name: "open a second season", Caller: admin, Run: open("s2"), Err: "season s1 is still open"},
+		{Name: "record battle", Caller: admin, Run: record("Ash", "Red")},
+		{Name: "record another battle", Caller: admin, Run: record("Misty", "Red")},
+		{Name: "record requires admin", Caller: ash, Run: record("Ash", "Red"), Err: "requires role admin"},
+		{Name: "record a battle against oneself", Caller: admin, Run: record("Ash", "Ash"), Err: "a trainer cannot battle themselves"},
+		{Name: "season stats", Caller: ash, Run: stats("Ash", "s1", 1, 1)},
+		{
+			Name:   "close season",
+			Caller: admin,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				season, err := s.CloseSeason(ctx)
+				if err == nil {
+					require.False(t, season.Open)
+					require.Equal(t, []LeaderboardEntry{
+						{Rank: 1, Trainer: "Ash", Wins: 1},
+						{Rank: 2, Trainer: "Misty", Wins: 1},
+						{Rank: 3, Trainer: "Red", Losses: 2},
+					}, season.Leaderboard)
+				}
+				return err
+			},
+		},
+		{
+			Name:   "closed seasons keep their leaderboard",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				season, err := s.ReadSeason(ctx, "s1")
+				if err == nil {
+					require.Len(t, season.Leaderboard, 3)
+					require.NotEmpty(t, season.ClosedAt)
+				}
+				return err
+			},
+		},
+		{
+			Name:   "read missing season",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.ReadSeason(ctx, "s9")
+				return err
+			},
+			Err: "season s9 does not exist",
+		},
+		{Name: "reopen a closed season", Caller: admin, Run: open("s1"), Err: "season s1 already exists"},
+		{Name: "open the next season", Caller: admin, Run: open("s2")},
+		{Name: "seasonal counters reset", Caller: ash, Run: stats("Ash", "s2", 0, 1)},
+		{
+			Name:   "battles count towards the open season",
+			Caller: admin,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				err := s.CreatePokemon(ctx, "poke4", "Sandshrew", "Ground", "Red", "Route 4", 40)
+				require.NoError(t, err)
+
+				result, err := s.Battle(ctx, "poke1", "poke4", "seed")
+				if err == nil {
+					require.Equal(t, "s2", result.SeasonID)
+				}
+				return err
+			},
+		},
+		{Name: "battle winners are counted", Caller: red, Run: stats("Red", "s2", 1, 1)},
+	})
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"random"
+	"repository"
+)
+
+const (
+	spawnTableObjectType = "spawntable"
+	spawnCountObjectType = "spawncount"
+
+	// wildTrainer is the trainer of spawned Pokemon nobody has caught yet
+	wildTrainer = "wild"
+)
+
+var spawnCountRepo = repository.New[SpawnCount](spawnCountObjectType)
+
+// SpawnEntry is one species a location can spawn. Its chance is its weight over the total weight
+// of the table, so rare species get small weights.
+type SpawnEntry struct {
+	Species  string `json:"species"`
+	Weight   int    `json:"weight"`
+	MinPower int    `json:"minPower"`
+	MaxPower int    `json:"maxPower"`
+}
+
+// SpawnTable lists the species a location spawns and caps how many spawn per epoch
+type SpawnTable struct {
+	Location     string        `json:"location"`
+	Entries      []*SpawnEntry `json:"entries"`
+	EpochSeconds int           `json:"epochSeconds"`
+	CapPerEpoch  int           `json:"capPerEpoch"`
+	UpdatedAt    string        `json:"updatedAt"`
+}
+
+// SpawnCount counts the Pokemon spawned at a location in one epoch
+type SpawnCount struct {
+	Location string `json:"location"`
+	Epoch    int64  `json:"epoch"`
+	Count    int    `json:"count"`
+}
+
+// SetSpawnTable replaces the spawn table of a location. entriesJSON is a JSON array of
+// SpawnEntry naming registered species with positive weights. Admin only.
+func (s *SmartContract) SetSpawnTable(ctx contractapi.TransactionContextInterface, location string, entriesJSON string, epochSeconds int, capPerEpoch int) (*SpawnTable, error) {
+	err := requireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if location == "" {
+		return nil, fmt.Errorf("location must not be empty")
+	}
+	if epochSeconds <= 0 || capPerEpoch <= 0 {
+		return nil, fmt.Errorf("epoch length and cap per epoch must be positive")
+	}
+
+	var entries []*SpawnEntry
+	err = json.Unmarshal([]byte(entriesJSON), &entries)
+	if err != nil {
+		return nil, fmt.Errorf("invalid spawn entries: %v", err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("a spawn table needs at least one entry")
+	}
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		if entry == nil {
+			return nil, fmt.Errorf("spawn entries must not be null")
+		}
+		if seen[entry.Species] {
+			return nil, fmt.Errorf("species %s is listed twice", entry.Species)
+		}
+		seen[entry.Species] = true
+		if entry.Weight <= 0 {
+			return nil, fmt.Errorf("the weight of %s must be positive", entry.Species)
+		}
+		if entry.MinPower <= 0 || entry.MaxPower < entry.MinPower || entry.MaxPower > maxPower {
+			return nil, fmt.Errorf("the power range of %s must lie between 1 and %d", entry.Species, maxPower)
+		}
+		species, err := getSpecies(ctx, entry.Species)
+		if err != nil {
+			return nil, err
+		}
+		if species == nil {
+			return nil, fmt.Errorf("species %s is not registered", entry.Species)
+		}
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	table := SpawnTable{
+		Location:     location,
+		Entries:      entries,
+		EpochSeconds: epochSeconds,
+		CapPerEpoch:  capPerEpoch,
+		UpdatedAt:    now.Format(time.RFC3339),
+	}
+	tableKey, err := ctx.GetStub().CreateCompositeKey(spawnTableObjectType, []string{location})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	tableJSON, err := json.Marshal(table)
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.GetStub().PutState(tableKey, tableJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	return &table, nil
+}
+
+// GetSpawnTable returns the spawn table of a location
+func (s *SmartContract) GetSpawnTable(ctx contractapi.TransactionContextInterface, location string) (*SpawnTable, error) {
+	tableKey, err := ctx.GetStub().CreateCompositeKey(spawnTableObjectType, []string{location})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	tableJSON, err := ctx.GetStub().GetState(tableKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if tableJSON == nil {
+		return nil, fmt.Errorf("location %s has no spawn table", location)
+	}
+
+	var table SpawnTable
+	err = json.Unmarshal(tableJSON, &table)
+	if err != nil {
+		return nil, err
+	}
+
+	return &table, nil
+}
+
+// SpawnWildPokemon spawns a wild Pokemon at a location from its spawn table. The species and
+// power are drawn from a random source seeded with the transaction ID, so every endorser draws the
+// same Pokemon. The draw is not secret: an admin can resubmit proposals until one spawns the
+// Pokemon it wants, so spawns must not guard value. Spawning fails once the location's cap for the
+// current epoch is reached. Admin only.
+func (s *SmartContract) SpawnWildPokemon(ctx contractapi.TransactionContextInterface, location string) (*Pokemon, error) {
+	err := requireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	table, err := s.GetSpawnTable(ctx, location)
+	if err != nil {
+		return nil, err
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	counter, err := s.GetSpawnCount(ctx, location)
+	if err != nil {
+		return nil, err
+	}
+	epoch := now.Unix() / int64(table.EpochSeconds)
+	if counter.Epoch != epoch {
+		counter.Epoch = epoch
+		counter.Count = 0
+	}
+	if counter.Count >= table.CapPerEpoch {
+		return nil, fmt.Errorf("%s has spawned its %d Pokemon of this epoch", location, table.CapPerEpoch)
+	}
+
+	rng := random.New(ctx.GetStub().GetTxID(), location)
+	entry := drawSpawnEntry(table.Entries, rng.Uint64())
+	species, err := getSpecies(ctx, entry.Species)
+	if err != nil {
+		return nil, err
+	}
+	if species == nil {
+		return nil, fmt.Errorf("species %s is not registered", entry.Species)
+	}
+	power := rng.Between(entry.MinPower, entry.MaxPower)
+	suffix := make([]byte, 10)
+	rng.Read(suffix)
+
+	p := Pokemon{
+		ID:       "wild" + hex.EncodeToString(suffix),
+		Name:     species.Name,
+		Type:     species.Type,
+		Power:    power,
+		Trainer:  wildTrainer,
+		Location: location,
+
+		Level:          1,
+		EvolutionStage: 1,
+	}
+	exists, err := s.PokemonExists(ctx, p.ID)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, fmt.Errorf("Pokemon %s already exists", p.ID)
+	}
+	pokeJSON, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.GetStub().PutState(pokemonNamespace.Key(p.ID), pokeJSON)
+	if err != nil {
+		return nil, err
+	}
+	err = updateRollups(ctx, nil, []*Pokemon{&p})
+	if err != nil {
+		return nil, err
+	}
+
+	counter.Count++
+	err = putSpawnCount(ctx, counter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+// GetSpawnCount returns how many Pokemon a location spawned in the epoch it last spawned in
+func (s *SmartContract) GetSpawnCount(ctx contractapi.TransactionContextInterface, location string) (*SpawnCount, error) {
+	counterKey, err := ctx.GetStub().CreateCompositeKey(spawnCountObjectType, []string{location})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	counterJSON, err := ctx.GetStub().GetState(counterKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if counterJSON == nil {
+		return &SpawnCount{Location: location}, nil
+	}
+
+	var counter SpawnCount
+	err = json.Unmarshal(counterJSON, &counter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &counter, nil
+}
+
+// drawSpawnEntry picks an entry with probability proportional to its weight
+func drawSpawnEntry(entries []*SpawnEntry, roll uint64) *SpawnEntry {
+	total := 0
+	for _, entry := range entries {
+		total += entry.Weight
+	}
+	pick := int(roll % uint64(total))
+	for _, entry := range entries {
+		if pick < entry.Weight {
+			return entry
+		}
+		pick -= entry.Weight
+	}
+
+	return entries[len(entries)-1]
+}
+
+func putSpawnCount(ctx contractapi.TransactionContextInterface, counter *SpawnCount) error {
+	return spawnCountRepo.Put(ctx, counter, counter.Location)
+}
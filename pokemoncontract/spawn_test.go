@@ -0,0 +1,99 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpawning(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	setTable := func(location string, entriesJSON string, epochSeconds int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.SetSpawnTable(ctx, location, entriesJSON, epochSeconds, 2)
+			return err
+		}
+	}
+	spawn := func(location string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			p, err := s.SpawnWildPokemon(ctx, location)
+			if err == nil {
+				require.Equal(t, wildTrainer, p.Trainer)
+				require.Contains(t, []string{"Pikachu", "Squirtle"}, p.Name)
+				require.GreaterOrEqual(t, p.Power, 20)
+				require.LessOrEqual(t, p.Power, 30)
+
+				stored, err := s.ReadPokemon(ctx, p.ID)
+				require.NoError(t, err)
+				require.Equal(t, location, stored.Location)
+			}
+			return err
+		}
+	}
+	count := func(want int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			counter, err := s.GetSpawnCount(ctx, "Viridian Forest")
+			if err == nil {
+				require.Equal(t, want, counter.Count)
+			}
+			return err
+		}
+	}
+	const entries = `[{"species":"Pikachu","weight":1,"minPower":20,"maxPower":30},{"species":"Squirtle","weight":3,"minPower":20,"maxPower":30}]`
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "set table requires admin", Caller: ash, Run: setTable("Viridian Forest", entries, 3600), Err: "requires role admin"},
+		{Name: "set table without a location", Caller: admin, Run: setTable("", entries, 3600), Err: "location must not be empty"},
+		{Name: "set table without an epoch", Caller: admin, Run: setTable("Viridian Forest", entries, 0), Err: "epoch length and cap per epoch must be positive"},
+		{Name: "set malformed table", Caller: admin, Run: setTable("Viridian Forest", "{", 3600), Err: "invalid spawn entries"},
+		{Name: "set empty table", Caller: admin, Run: setTable("Viridian Forest", "[]", 3600), Err: "a spawn table needs at least one entry"},
+		{Name: "set table with a null entry", Caller: admin, Run: setTable("Viridian Forest", "[null]", 3600), Err: "spawn entries must not be null"},
+		{Name: "set table listing a species twice", Caller: admin, Run: setTable("Viridian Forest", `[{"species":"Pikachu","weight":1,"minPower":1,"maxPower":2},{"species":"Pikachu","weight":1,"minPower":1,"maxPower":2}]`, 3600), Err: "species Pikachu is listed twice"},
+		{Name: "set table with a weightless species", Caller: admin, Run: setTable("Viridian Forest", `[{"species":"Pikachu","weight":0,"minPower":1,"maxPower":2}]`, 3600), Err: "the weight of Pikachu must be positive"},
+		{Name: "set table with an inverted power range", Caller: admin, Run: setTable("Viridian Forest", `[{"species":"Pikachu","weight":1,"minPower":30,"maxPower":20}]`, 3600), Err: "the power range of Pikachu must lie between 1 and 999"},
+		{Name: "set table with an unregistered species", Caller: admin, Run: setTable("Viridian Forest", `[{"species":"Mew","weight":1,"minPower":1,"maxPower":2}]`, 3600), Err: "species Mew is not registered"},
+		{Name: "set table", Caller: admin, Run: setTable("Viridian Forest", entries, 3600)},
+		{
+			Name:   "read table",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				table, err := s.GetSpawnTable(ctx, "Viridian Forest")
+				if err == nil {
+					require.Len(t, table.Entries, 2)
+					require.Equal(t, 3600, table.EpochSeconds)
+					require.Equal(t, 2, table.CapPerEpoch)
+				}
+				return err
+			},
+		},
+		{Name: "nothing spawned yet", Caller: ash, Run: count(0)},
+		{Name: "spawn requires admin", Caller: ash, Run: spawn("Viridian Forest"), Err: "requires role admin"},
+		{Name: "spawn without a table", Caller: admin, Run: spawn("Route 1"), Err: "location Route 1 has no spawn table"},
+		{Name: "spawn", Caller: admin, Run: spawn("Viridian Forest")},
+		{Name: "spawn again", Caller: admin, Run: spawn("Viridian Forest")},
+		{Name: "spawns are counted", Caller: ash, Run: count(2)},
+		{Name: "spawn beyond the cap", Caller: admin, Run: spawn("Viridian Forest"), Err: "Viridian Forest has spawned its 2 Pokemon of this epoch"},
+		{
+			Name:   "wild Pokemon are rolled up",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				stats, err := s.GetTrainerStats(ctx, wildTrainer)
+				if err == nil {
+					require.Equal(t, 2, stats.PokemonCount)
+				}
+				return err
+			},
+		},
+	})
+
+	ledger.Advance(time.Hour)
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "spawn in the next epoch", Caller: admin, Run: spawn("Viridian Forest")},
+		{Name: "the count restarts every epoch", Caller: ash, Run: count(1)},
+	})
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+const (
+	speciesObjectType   = "species"
+	evolutionObjectType = "evolution"
+)
+
+var (
+	speciesRepo   = repository.New[Species](speciesObjectType)
+	evolutionRepo = repository.New[Evolution](evolutionObjectType)
+)
+
+// SpeciesRegistry is the contract managing Pokemon species and their evolution chains. Its
+// functions are invoked with the SpeciesRegistry: prefix, for example SpeciesRegistry:RegisterSpecies.
+type SpeciesRegistry struct {
+	contractapi.Contract
+}
+
+// Species is a Pokemon species and the species it evolves into, if any
+type Species struct {
+	Name             string `json:"name"`
+	Type             string `json:"type"`
+	EvolvesTo        string `json:"evolvesTo"`
+	MinPowerToEvolve int    `json:"minPowerToEvolve"`
+}
+
+// Evolution records one step in a Pokemon's evolution lineage
+type Evolution struct {
+	PokemonID   string `json:"pokemonId"`
+	Stage       int    `json:"stage"`
+	FromSpecies string `json:"fromSpecies"`
+	ToSpecies   string `json:"toSpecies"`
+	PowerBefore int    `json:"powerBefore"`
+	EvolvedAt   string `json:"evolvedAt"`
+	TxID        string `json:"txId"`
+}
+
+// defaultSpecies is the species registry written by InitLedger
+var defaultSpecies = []Species{
+	{Name: "Pikachu", Type: "Electric", EvolvesTo: "Raichu", MinPowerToEvolve: 60},
+	{Name: "Raichu", Type: "Electric"},
+	{Name: "Charmander", Type: "Fire", EvolvesTo: "Charmeleon", MinPowerToEvolve: 50},
+	{Name: "Charmeleon", Type: "Fire", EvolvesTo: "Charizard", MinPowerToEvolve: 100},
+	{Name: "Charizard", Type: "Fire"},
+	{Name: "Squirtle", Type: "Water", EvolvesTo: "Wartortle", MinPowerToEvolve: 50},
+	{Name: "Wartortle", Type: "Water", EvolvesTo: "Blastoise", MinPowerToEvolve: 100},
+	{Name: "Blastoise", Type: "Water"},
+}
+
+// RegisterSpecies adds or updates a species. evolvesTo may be empty for a final form. A species
+// cannot evolve into itself or into one of its own earlier forms. Admin only.
+func (r *SpeciesRegistry) RegisterSpecies(ctx contractapi.TransactionContextInterface, name string, ptype string, evolvesTo string, minPowerToEvolve int) error {
+	err := requireAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	if name == "" || ptype == "" {
+		return fmt.Errorf("species name and type must not be empty")
+	}
+	if minPowerToEvolve < 0 {
+		return fmt.Errorf("minimum power to evolve must not be negative")
+	}
+
+	// walk the chain from evolvesTo to make sure it does not lead back to this species
+	for next, steps := evolvesTo, 0; next != ""; steps++ {
+		if next == name {
+			return fmt.Errorf("species %s cannot evolve into itself", name)
+		}
+		if steps > 100 {
+			return fmt.Errorf("the evolution chain of %s is too long", evolvesTo)
+		}
+		species, err := getSpecies(ctx, next)
+		if err != nil {
+			return err
+		}
+		if species == nil {
+			break
+		}
+		next = species.EvolvesTo
+	}
+
+	return putSpecies(ctx, &Species{Name: name, Type: ptype, EvolvesTo: evolvesTo, MinPowerToEvolve: minPowerToEvolve})
+}
+
+// GetSpecies returns a registered species
+func (r *SpeciesRegistry) GetSpecies(ctx contractapi.TransactionContextInterface, name string) (*Species, error) {
+	species, err := getSpecies(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if species == nil {
+		return nil, fmt.Errorf("species %s is not registered", name)
+	}
+
+	return species, nil
+}
+
+// GetEvolutionLineage returns the evolutions a Pokemon went through, earliest first
+func (s *SmartContract) GetEvolutionLineage(ctx contractapi.TransactionContextInterface, id string) ([]*Evolution, error) {
+	return evolutionRepo.List(ctx, id)
+}
+
+// putEvolution records the Pokemon's evolution from its current species into toSpecies
+func putEvolution(ctx contractapi.TransactionContextInterface, p *Pokemon, toSpecies string) error {
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	evolution := Evolution{
+		PokemonID:   p.ID,
+		Stage:       p.EvolutionStage + 1,
+		FromSpecies: p.Name,
+		ToSpecies:   toSpecies,
+		PowerBefore: p.Power,
+		EvolvedAt:   now.Format(time.RFC3339),
+		TxID:        ctx.GetStub().GetTxID(),
+	}
+	return evolutionRepo.Put(ctx, &evolution, p.ID, fmt.Sprintf("%02d", evolution.Stage))
+}
+
+func getSpecies(ctx contractapi.TransactionContextInterface, name string) (*Species, error) {
+	return speciesRepo.Get(ctx, name)
+}
+
+func putSpecies(ctx contractapi.TransactionContextInterface, species *Species) error {
+	return speciesRepo.Put(ctx, species, species.Name)
+}
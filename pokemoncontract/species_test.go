@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpeciesRegistry(t *testing.T) {
+	r := new(SpeciesRegistry)
+	ledger := newLedger(t)
+
+	register := func(name string, ptype string, evolvesTo string, minPowerToEvolve int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return r.RegisterSpecies(ctx, name, ptype, evolvesTo, minPowerToEvolve)
+		}
+	}
+	species := func(name string, want *Species) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			species, err := r.GetSpecies(ctx, name)
+			if err == nil {
+				require.Equal(t, want, species)
+			}
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "sample species", Caller: ash, Run: species("Charmander", &Species{Name: "Charmander", Type: "Fire", EvolvesTo: "Charmeleon", MinPowerToEvolve: 50})},
+		{Name: "unregistered species", Caller: ash, Run: species("Bulbasaur", nil), Err: "species Bulbasaur is not registered"},
+		{Name: "register species", Caller: admin, Run: register("Bulbasaur", "Grass", "Ivysaur", 40)},
+		{Name: "registered species", Caller: ash, Run: species("Bulbasaur", &Species{Name: "Bulbasaur", Type: "Grass", EvolvesTo: "Ivysaur", MinPowerToEvolve: 40})},
+		{Name: "update species", Caller: admin, Run: register("Bulbasaur", "Grass", "Ivysaur", 45)},
+		{Name: "updated species", Caller: ash, Run: species("Bulbasaur", &Species{Name: "Bulbasaur", Type: "Grass", EvolvesTo: "Ivysaur", MinPowerToEvolve: 45})},
+		{Name: "register requires admin", Caller: ash, Run: register("Mew", "Psychic", "", 0), Err: "requires role admin"},
+		{Name: "register without a type", Caller: admin, Run: register("Mew", "", "", 0), Err: "species name and type must not be empty"},
+		{Name: "register with a negative minimum power", Caller: admin, Run: register("Mew", "Psychic", "", -1), Err: "minimum power to evolve must not be negative"},
+		{Name: "register evolving into itself", Caller: admin, Run: register("Mew", "Psychic", "Mew", 0), Err: "species Mew cannot evolve into itself"},
+		{Name: "register evolving into an earlier form", Caller: admin, Run: register("Charizard", "Fire", "Charmander", 0), Err: "species Charizard cannot evolve into itself"},
+	})
+}
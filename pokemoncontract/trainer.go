@@ -0,0 +1,244 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+const (
+	trainerObjectType   = "trainer"
+	trainerPokemonIndex = "trainer~pokemon"
+)
+
+var trainerRepo = repository.New[Trainer](trainerObjectType)
+
+// Trainer is a trainer's account. A trainer who has never registered still has an account once
+// they are credited PokeCoin, with an empty name and registration date.
+type Trainer struct {
+	ID           string   `json:"id"`
+	Name         string   `json:"name"`
+	Badges       []string `json:"badges,omitempty" metadata:",optional"` // IDs of the gym badges awarded
+	CoinBalance  int      `json:"coinBalance"`
+	RegisteredAt string   `json:"registeredAt"`
+}
+
+// RegisterTrainer opens the account of the submitting trainer, or names an account that was
+// created by a PokeCoin credit
+func (s *SmartContract) RegisterTrainer(ctx contractapi.TransactionContextInterface, id string, name string) error {
+	err := requireTrainer(ctx, id)
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		return fmt.Errorf("trainer name must not be empty")
+	}
+
+	t, err := loadTrainer(ctx, id)
+	if err != nil {
+		return err
+	}
+	if t.RegisteredAt != "" {
+		return fmt.Errorf("trainer %s is already registered", id)
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	t.Name = name
+	t.RegisteredAt = now.Format(time.RFC3339)
+
+	return putTrainer(ctx, t)
+}
+
+// ReadTrainer returns a trainer's account
+func (s *SmartContract) ReadTrainer(ctx contractapi.TransactionContextInterface, id string) (*Trainer, error) {
+	t, err := getTrainer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, fmt.Errorf("trainer %s does not exist", id)
+	}
+
+	return t, nil
+}
+
+// GetPokemonsByTrainer returns the Pokemon a trainer trains, using the trainer index, followed by
+// the deleted Pokemon the trainer trained when includeDeleted is set
+func (s *SmartContract) GetPokemonsByTrainer(ctx contractapi.TransactionContextInterface, trainer string, includeDeleted bool) ([]*Pokemon, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(trainerPokemonIndex, []string{trainer})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var pokemons []*Pokemon
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, err
+		}
+		p, err := s.ReadPokemon(ctx, keyParts[1])
+		if err != nil {
+			return nil, err
+		}
+		pokemons = append(pokemons, p)
+	}
+
+	if includeDeleted {
+		deleted, err := deletedPokemonRepo.List(ctx, pokemonAssetType)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range deleted {
+			if p.Trainer == trainer {
+				pokemons = append(pokemons, p)
+			}
+		}
+	}
+
+	return pokemons, nil
+}
+
+// PokemonPage is one page of Pokemon with the bookmark the next page starts at, which is empty
+// after the last page
+type PokemonPage struct {
+	Pokemons            []*Pokemon `json:"pokemons,omitempty" metadata:",optional"`
+	FetchedRecordsCount int32      `json:"fetchedRecordsCount"`
+	Bookmark            string     `json:"bookmark"`
+}
+
+// GetPokemonsByTrainerPaginated returns one page of at most repository.MaxPageSize of the Pokemon a
+// trainer trains, using the trainer index. Evaluate it rather than submitting it, since Fabric
+// refuses paginated queries in transactions that write.
+func (s *SmartContract) GetPokemonsByTrainerPaginated(ctx contractapi.TransactionContextInterface, trainer string, pageSize int, bookmark string) (*PokemonPage, error) {
+	page := &PokemonPage{}
+	var err error
+	page.FetchedRecordsCount, page.Bookmark, err = repository.KeyPage(ctx, trainerPokemonIndex, []string{trainer}, int32(pageSize), bookmark, func(keyParts []string, _ []byte) error {
+		p, err := s.ReadPokemon(ctx, keyParts[1])
+		if err != nil {
+			return err
+		}
+		page.Pokemons = append(page.Pokemons, p)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// RebuildTrainerIndex writes the trainer and location index entries of every Pokemon on the
+// ledger, for Pokemon created before the indexes existed. It scans every Pokemon and returns how many Pokemon
+// were indexed. Admin only.
+func (s *SmartContract) RebuildTrainerIndex(ctx contractapi.TransactionContextInterface) (int, error) {
+	err := requireAdmin(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByRange(pokemonNamespace.Range(""))
+	if err != nil {
+		return 0, err
+	}
+	defer resultsIterator.Close()
+
+	indexed := 0
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return 0, err
+		}
+
+		var p Pokemon
+		err = json.Unmarshal(queryResponse.Value, &p)
+		if err != nil {
+			return 0, err
+		}
+		err = putTrainerIndex(ctx, &p)
+		if err != nil {
+			return 0, err
+		}
+		err = putLocationIndex(ctx, &p)
+		if err != nil {
+			return 0, err
+		}
+		indexed++
+	}
+
+	return indexed, nil
+}
+
+// updateTrainerIndex moves the trainer index entries of Pokemon removed from and added to their
+// trainers. Entries that are both removed and added are left alone, since a key deleted and
+// written in one transaction keeps only the last write.
+func updateTrainerIndex(ctx contractapi.TransactionContextInterface, removed []*Pokemon, added []*Pokemon) error {
+	kept := make(map[[2]string]bool)
+	for _, p := range added {
+		kept[[2]string{p.Trainer, p.ID}] = true
+	}
+	for _, p := range removed {
+		if kept[[2]string{p.Trainer, p.ID}] {
+			continue
+		}
+		indexKey, err := ctx.GetStub().CreateCompositeKey(trainerPokemonIndex, []string{p.Trainer, p.ID})
+		if err != nil {
+			return fmt.Errorf("failed to create composite key: %v", err)
+		}
+		err = ctx.GetStub().DelState(indexKey)
+		if err != nil {
+			return err
+		}
+	}
+	for _, p := range added {
+		err := putTrainerIndex(ctx, p)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// putTrainerIndex records the trainer~pokemon index entry of a Pokemon.
+// Only the key is needed, so a single null byte is stored as the value.
+func putTrainerIndex(ctx contractapi.TransactionContextInterface, p *Pokemon) error {
+	indexKey, err := ctx.GetStub().CreateCompositeKey(trainerPokemonIndex, []string{p.Trainer, p.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	return ctx.GetStub().PutState(indexKey, []byte{0x00})
+}
+
+// loadTrainer returns a trainer's account, or an empty one if the trainer has none yet
+func loadTrainer(ctx contractapi.TransactionContextInterface, id string) (*Trainer, error) {
+	t, err := getTrainer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		t = &Trainer{ID: id}
+	}
+
+	return t, nil
+}
+
+func getTrainer(ctx contractapi.TransactionContextInterface, id string) (*Trainer, error) {
+	return trainerRepo.Get(ctx, id)
+}
+
+func putTrainer(ctx contractapi.TransactionContextInterface, t *Trainer) error {
+	return trainerRepo.Put(ctx, t, t.ID)
+}
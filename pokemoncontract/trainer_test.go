@@ -0,0 +1,131 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"chaincodetest/chaincodefakes"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrainers(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+	gary := chaincodetest.Identity{MSPID: "Org2MSP", CommonName: "user7", Attributes: map[string]string{"trainer": "Gary"}}
+
+	register := func(id string, name string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.RegisterTrainer(ctx, id, name)
+		}
+	}
+	pokemons := func(trainer string, want ...string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			pokemons, err := s.GetPokemonsByTrainer(ctx, trainer, false)
+			if err == nil {
+				var ids []string
+				for _, p := range pokemons {
+					ids = append(ids, p.ID)
+				}
+				require.Equal(t, want, ids)
+			}
+			return err
+		}
+	}
+	var bookmark string
+	pokemonPage := func(pageSize int, want ...string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			page, err := s.GetPokemonsByTrainerPaginated(ctx, "Ash", pageSize, bookmark)
+			if err == nil {
+				var ids []string
+				for _, p := range page.Pokemons {
+					ids = append(ids, p.ID)
+				}
+				require.Equal(t, want, ids)
+				bookmark = page.Bookmark
+			}
+			return err
+		}
+	}
+	rebuild := func(want int) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			indexed, err := s.RebuildTrainerIndex(ctx)
+			require.Equal(t, want, indexed)
+			return err
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "register", Caller: ash, Run: register("Ash", "Ash Ketchum")},
+		{
+			Name:   "read trainer",
+			Caller: red,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				trainer, err := s.ReadTrainer(ctx, "Ash")
+				if err == nil {
+					require.Equal(t, "Ash Ketchum", trainer.Name)
+					require.Equal(t, "2024-01-01T09:00:02Z", trainer.RegisteredAt)
+				}
+				return err
+			},
+		},
+		{
+			Name:   "read unknown trainer",
+			Caller: red,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.ReadTrainer(ctx, "Brock")
+				return err
+			},
+			Err: "trainer Brock does not exist",
+		},
+		{Name: "register twice", Caller: ash, Run: register("Ash", "Ash"), Err: "trainer Ash is already registered"},
+		{Name: "register another trainer", Caller: ash, Run: register("Red", "Red"), Err: "submitting client Ash is not authorized for this Pokemon"},
+		{Name: "register without a name", Caller: red, Run: register("Red", ""), Err: "trainer name must not be empty"},
+		{Name: "the trainer attribute names the caller", Caller: gary, Run: register("Gary", "Gary Oak")},
+		{Name: "Pokemon by trainer", Caller: ash, Run: pokemons("Ash", "poke1")},
+		{Name: "trainers without Pokemon", Caller: ash, Run: pokemons("Brock")},
+		{
+			Name:   "write a Pokemon from before the indexes",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return ctx.GetStub().PutState(pokemonNamespace.Key("poke4"), []byte(`{"id":"poke4","name":"Pidgey","type":"Flying","power":30,"trainer":"Ash","location":"Route 1"}`))
+			},
+		},
+		{Name: "unindexed Pokemon are missing", Caller: ash, Run: pokemons("Ash", "poke1")},
+		{Name: "rebuild requires admin", Caller: ash, Run: rebuild(0), Err: "requires role admin"},
+		{Name: "rebuild", Caller: admin, Run: rebuild(4)},
+		{Name: "rebuilt index", Caller: ash, Run: pokemons("Ash", "poke1", "poke4")},
+		{Name: "page above the maximum", Caller: ash, Run: pokemonPage(101), Err: "pageSize must be between 1 and 100"},
+		{Name: "first page", Caller: ash, Run: pokemonPage(1, "poke1")},
+		{Name: "next page", Caller: ash, Run: pokemonPage(1, "poke4")},
+		{
+			Name:   "no bookmark after the last page",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				require.Empty(t, bookmark)
+				return nil
+			},
+		},
+		{
+			Name:   "rebuilt location index",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				pokemons, err := s.GetPokemonsByLocation(ctx, "Route 1")
+				if err == nil {
+					require.Len(t, pokemons, 1)
+				}
+				return err
+			},
+		},
+		{
+			Name:   "rebuild over a corrupt record",
+			Caller: admin,
+			Stub: func(stub *chaincodefakes.ChaincodeStub) {
+				stub.GetStateByRangeReturns(chaincodetest.StateIterator(&queryresult.KV{Key: "poke9", Value: []byte("{")}), nil)
+			},
+			Run: rebuild(0),
+			Err: "unexpected end of JSON input",
+		},
+	})
+}
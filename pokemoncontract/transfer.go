@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"repository"
+)
+
+const transferObjectType = "transfer"
+
+var transferRepo = repository.New[Transfer](transferObjectType)
+
+// Transfer is a proposed change of a Pokemon's trainer, awaiting the counter-signature of the
+// receiving trainer
+type Transfer struct {
+	PokemonID   string `json:"pokemonId"`
+	FromTrainer string `json:"fromTrainer"`
+	NewTrainer  string `json:"newTrainer"`
+	ProposedAt  string `json:"proposedAt"`
+}
+
+// TransferPokemon proposes handing a Pokemon over to another trainer. Only the current trainer may
+// propose, or the borrower while the Pokemon is lent, and the Pokemon does not move until the new
+// trainer calls AcceptTransfer. A retry under the idempotency key of a transfer already proposed
+// succeeds without proposing it again.
+func (s *SmartContract) TransferPokemon(ctx contractapi.TransactionContextInterface, id string, newTrainer string) error {
+	return repository.IdempotentOnce(ctx, func() error {
+		return s.transferPokemon(ctx, id, newTrainer)
+	})
+}
+
+// transferPokemon records a proposed transfer of a Pokemon
+func (s *SmartContract) transferPokemon(ctx contractapi.TransactionContextInterface, id string, newTrainer string) error {
+	p, err := s.ReadPokemon(ctx, id)
+	if err != nil {
+		return err
+	}
+	holder, err := activeTrainer(ctx, p)
+	if err != nil {
+		return err
+	}
+	err = requireTrainer(ctx, holder)
+	if err != nil {
+		return err
+	}
+	if newTrainer == "" || newTrainer == p.Trainer {
+		return fmt.Errorf("new trainer must be another trainer")
+	}
+
+	pending, err := getTransfer(ctx, id)
+	if err != nil {
+		return err
+	}
+	if pending != nil {
+		return fmt.Errorf("Pokemon %s already has a pending transfer to %s", id, pending.NewTrainer)
+	}
+	gift, err := getGift(ctx, id)
+	if err != nil {
+		return err
+	}
+	if gift != nil {
+		return fmt.Errorf("Pokemon %s has a pending gift to %s", id, gift.Recipient)
+	}
+	listing, err := getListing(ctx, id)
+	if err != nil {
+		return err
+	}
+	if listing != nil {
+		return fmt.Errorf("Pokemon %s is listed for sale, cancel the listing first", id)
+	}
+	trade, err := getPrivateTrade(ctx, id)
+	if err != nil {
+		return err
+	}
+	if trade != nil {
+		return fmt.Errorf("Pokemon %s has a pending private trade with %s", id, trade.Buyer)
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	transfer := Transfer{
+		PokemonID:   id,
+		FromTrainer: p.Trainer,
+		NewTrainer:  newTrainer,
+		ProposedAt:  now.Format(time.RFC3339),
+	}
+	return transferRepo.Put(ctx, &transfer, id)
+}
+
+// AcceptTransfer completes a pending transfer. Only the receiving trainer may accept. Any lease of
+// the Pokemon ends with the transfer.
+func (s *SmartContract) AcceptTransfer(ctx contractapi.TransactionContextInterface, id string) error {
+	transfer, err := s.ReadTransfer(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = requireTrainer(ctx, transfer.NewTrainer)
+	if err != nil {
+		return err
+	}
+
+	p, err := s.ReadPokemon(ctx, id)
+	if err != nil {
+		return err
+	}
+	if p.Trainer != transfer.FromTrainer {
+		return fmt.Errorf("Pokemon %s is no longer trained by %s", id, transfer.FromTrainer)
+	}
+	before := *p
+	p.Trainer = transfer.NewTrainer
+	pokeJSON, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().PutState(pokemonNamespace.Key(id), pokeJSON)
+	if err != nil {
+		return err
+	}
+	err = updateRollups(ctx, []*Pokemon{&before}, []*Pokemon{p})
+	if err != nil {
+		return err
+	}
+	lease, err := getLease(ctx, id)
+	if err != nil {
+		return err
+	}
+	if lease != nil {
+		err = deleteLease(ctx, lease)
+		if err != nil {
+			return err
+		}
+	}
+
+	return deleteTransfer(ctx, id)
+}
+
+// CancelTransfer withdraws a pending transfer. Either trainer may cancel it, as may the borrower
+// while the Pokemon is lent.
+func (s *SmartContract) CancelTransfer(ctx contractapi.TransactionContextInterface, id string) error {
+	transfer, err := s.ReadTransfer(ctx, id)
+	if err != nil {
+		return err
+	}
+	p, err := s.ReadPokemon(ctx, id)
+	if err != nil {
+		return err
+	}
+	holder, err := activeTrainer(ctx, p)
+	if err != nil {
+		return err
+	}
+	err = requireTrainer(ctx, transfer.FromTrainer, transfer.NewTrainer, holder)
+	if err != nil {
+		return err
+	}
+
+	return deleteTransfer(ctx, id)
+}
+
+// ReadTransfer returns the pending transfer of a Pokemon
+func (s *SmartContract) ReadTransfer(ctx contractapi.TransactionContextInterface, id string) (*Transfer, error) {
+	transfer, err := getTransfer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if transfer == nil {
+		return nil, fmt.Errorf("Pokemon %s has no pending transfer", id)
+	}
+
+	return transfer, nil
+}
+
+func getTransfer(ctx contractapi.TransactionContextInterface, id string) (*Transfer, error) {
+	return transferRepo.Get(ctx, id)
+}
+
+func deleteTransfer(ctx contractapi.TransactionContextInterface, id string) error {
+	transferKey, err := ctx.GetStub().CreateCompositeKey(transferObjectType, []string{id})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	return ctx.GetStub().DelState(transferKey)
+}
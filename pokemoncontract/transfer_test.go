@@ -0,0 +1,122 @@
+package main
+
+import (
+	"testing"
+
+	"chaincodetest"
+	"chaincodetest/chaincodefakes"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+	"repository"
+)
+
+func TestTransfers(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	transfer := func(id string, newTrainer string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.TransferPokemon(ctx, id, newTrainer)
+		}
+	}
+	accept := func(id string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.AcceptTransfer(ctx, id)
+		}
+	}
+	cancel := func(id string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+			return s.CancelTransfer(ctx, id)
+		}
+	}
+	trainedBy := func(id string, trainer string) func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+		return readPokemon(id, func(t *testing.T, p *Pokemon) {
+			require.Equal(t, trainer, p.Trainer)
+		})
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		{Name: "transfer another trainer's Pokemon", Caller: red, Run: transfer("poke1", "Red"), Err: "submitting client Red is not authorized for this Pokemon"},
+		{Name: "transfer to oneself", Caller: ash, Run: transfer("poke1", "Ash"), Err: "new trainer must be another trainer"},
+		{Name: "transfer", Caller: ash, Run: transfer("poke1", "Red")},
+		{
+			Name:   "read transfer",
+			Caller: red,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				transfer, err := s.ReadTransfer(ctx, "poke1")
+				if err == nil {
+					require.Equal(t, "Ash", transfer.FromTrainer)
+					require.Equal(t, "Red", transfer.NewTrainer)
+				}
+				return err
+			},
+		},
+		{Name: "proposed transfers do not move the Pokemon", Caller: ash, Run: trainedBy("poke1", "Ash")},
+		{Name: "transfer twice", Caller: ash, Run: transfer("poke1", "Misty"), Err: "Pokemon poke1 already has a pending transfer to Red"},
+		{
+			Name:   "Pokemon being transferred cannot be gifted",
+			Caller: ash,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.GiftPokemon(ctx, "poke1", "Misty", "")
+			},
+			Err: "Pokemon poke1 has a pending transfer to Red",
+		},
+		{Name: "accept as another trainer", Caller: misty, Run: accept("poke1"), Err: "submitting client Misty is not authorized for this Pokemon"},
+		{Name: "accept", Caller: red, Run: accept("poke1")},
+		{Name: "accepted transfers move the Pokemon", Caller: red, Run: trainedBy("poke1", "Red")},
+		{Name: "accept a completed transfer", Caller: red, Run: accept("poke1"), Err: "Pokemon poke1 has no pending transfer"},
+		{Name: "transfer onwards", Caller: red, Run: transfer("poke1", "Misty")},
+		{Name: "former trainers cannot cancel", Caller: ash, Run: cancel("poke1"), Err: "submitting client Ash is not authorized for this Pokemon"},
+		{Name: "receiving trainers cancel", Caller: misty, Run: cancel("poke1")},
+		{Name: "cancel a cancelled transfer", Caller: red, Run: cancel("poke1"), Err: "Pokemon poke1 has no pending transfer"},
+		{
+			Name:   "lend Squirtle",
+			Caller: misty,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.LendPokemon(ctx, "poke3", "Ash", "2024-01-02T09:00:00Z")
+				return err
+			},
+		},
+		{Name: "owners cannot transfer lent Pokemon", Caller: misty, Run: transfer("poke3", "Red"), Err: "submitting client Misty is not authorized for this Pokemon"},
+		{Name: "borrowers transfer lent Pokemon", Caller: ash, Run: transfer("poke3", "Red")},
+		{Name: "accept a lent Pokemon", Caller: red, Run: accept("poke3")},
+		{Name: "transfers move the owner's Pokemon", Caller: red, Run: trainedBy("poke3", "Red")},
+		{
+			Name:   "transfers end the lease",
+			Caller: red,
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				_, err := s.ReadLease(ctx, "poke3")
+				return err
+			},
+			Err: "Pokemon poke3 is not lent",
+		},
+	})
+}
+
+func TestTransferRetry(t *testing.T) {
+	s := new(SmartContract)
+	ledger := newLedger(t)
+
+	transfer := func(key string, id string, newTrainer string, err string) chaincodetest.Case {
+		return chaincodetest.Case{
+			Name:   "transfer " + id + " to " + newTrainer + " under " + key,
+			Caller: ash,
+			Stub: func(stub *chaincodefakes.ChaincodeStub) {
+				stub.GetTransientReturns(map[string][]byte{repository.IdempotencyKeyTransient: []byte(key)}, nil)
+				stub.GetFunctionAndParametersReturns("TransferPokemon", []string{id, newTrainer})
+				stub.GetArgsReturns([][]byte{[]byte("TransferPokemon"), []byte(id), []byte(newTrainer)})
+			},
+			Run: func(t *testing.T, ctx contractapi.TransactionContextInterface) error {
+				return s.TransferPokemon(ctx, id, newTrainer)
+			},
+			Err: err,
+		}
+	}
+
+	ledger.Run(t, []chaincodetest.Case{
+		transfer("k1", "poke1", "Red", ""),
+		transfer("k1", "poke1", "Red", ""),
+		transfer("k1", "poke1", "Misty", "the idempotency key k1 was used for a different request"),
+		transfer("k2", "poke1", "Red", "Pokemon poke1 already has a pending transfer to Red"),
+	})
+}
@@ -0,0 +1,52 @@
+package loanadmin
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/hyperledger/fabric-protos-go-apiv2/gateway"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// chaincodeResponse is the prefix peers put in front of the message a chaincode failed with
+var chaincodeResponse = regexp.MustCompile(`^chaincode response \d+, `)
+
+// chaincodeCodes maps the phrasing of chaincode errors to gRPC codes. The first match wins; a
+// chaincode error matching none is FAILED_PRECONDITION, the loan not being in a state allowing
+// the call.
+var chaincodeCodes = []struct {
+	match string
+	code  codes.Code
+}{
+	{"LOCKED: ", codes.FailedPrecondition},
+	{"does not exist", codes.NotFound},
+	{"already exists", codes.AlreadyExists},
+	{"not authorized", codes.PermissionDenied},
+	{"must be", codes.InvalidArgument},
+	{"must not", codes.InvalidArgument},
+	{"invalid", codes.InvalidArgument},
+}
+
+// toStatus converts an error of the gateway into the status the service returns. Errors raised by
+// the chaincode get the code matching their cause and the chaincode's message; other gateway
+// errors, such as an unavailable peer, keep their own status.
+func toStatus(err error) error {
+	gatewayStatus := status.Convert(err)
+	for _, detail := range gatewayStatus.Details() {
+		errorDetail, ok := detail.(*gateway.ErrorDetail)
+		if !ok || !chaincodeResponse.MatchString(errorDetail.GetMessage()) {
+			continue
+		}
+
+		message := chaincodeResponse.ReplaceAllString(errorDetail.GetMessage(), "")
+		for _, mapping := range chaincodeCodes {
+			if strings.Contains(message, mapping.match) {
+				return status.Error(mapping.code, message)
+			}
+		}
+		return status.Error(codes.FailedPrecondition, message)
+	}
+
+	return gatewayStatus.Err()
+}
@@ -0,0 +1,164 @@
+// Package loanadmin implements the LoanAdmin gRPC service over the Fabric Gateway, translating
+// each call into a bankcontract transaction.
+package loanadmin
+
+//go:generate protoc -I ../proto --go_out=.. --go_opt=module=service --go-grpc_out=.. --go-grpc_opt=module=service loanadmin.proto
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"service/loanadminpb"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// loan is the JSON form of a bankcontract loan application
+type loan struct {
+	ID               string  `json:"id"`
+	Applicant        string  `json:"applicant"`
+	Amount           int64   `json:"amount"`
+	Currency         string  `json:"currency"`
+	Term             int32   `json:"term"`
+	InterestRate     float64 `json:"interestRate"`
+	Status           string  `json:"status"`
+	DisbursedAt      string  `json:"disbursedAt"`
+	TermsHash        string  `json:"termsHash"`
+	PaidInstallments int32   `json:"paidInstallments"`
+	OwnerMSP         string  `json:"ownerMsp"`
+}
+
+// Server serves LoanAdmin by calling the loan contract as a single service identity
+type Server struct {
+	loanadminpb.UnimplementedLoanAdminServer
+	contract *client.Contract
+}
+
+// NewServer returns a LoanAdmin server calling contract
+func NewServer(contract *client.Contract) *Server {
+	return &Server{contract: contract}
+}
+
+func (s *Server) CreateLoan(ctx context.Context, request *loanadminpb.CreateLoanRequest) (*loanadminpb.LoanResult, error) {
+	switch {
+	case request.GetId() == "":
+		return nil, status.Error(codes.InvalidArgument, "id must not be empty")
+	case request.GetApplicant() == "":
+		return nil, status.Error(codes.InvalidArgument, "applicant must not be empty")
+	case request.GetAmount() <= 0:
+		return nil, status.Error(codes.InvalidArgument, "amount must be positive")
+	case request.GetTerm() <= 0:
+		return nil, status.Error(codes.InvalidArgument, "term must be a positive number of months")
+	case request.GetInterestRate() < 0:
+		return nil, status.Error(codes.InvalidArgument, "interest_rate must not be negative")
+	}
+
+	return s.submit(ctx, request.GetId(), "CreateLoanApplication",
+		request.GetId(),
+		request.GetApplicant(),
+		strconv.FormatInt(request.GetAmount(), 10),
+		strconv.Itoa(int(request.GetTerm())),
+		strconv.FormatFloat(request.GetInterestRate(), 'f', -1, 64),
+	)
+}
+
+func (s *Server) ApproveLoan(ctx context.Context, request *loanadminpb.ApproveLoanRequest) (*loanadminpb.LoanResult, error) {
+	if request.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id must not be empty")
+	}
+	return s.submit(ctx, request.GetId(), "UpdateLoanStatus", request.GetId(), "Approved")
+}
+
+func (s *Server) DisburseLoan(ctx context.Context, request *loanadminpb.DisburseLoanRequest) (*loanadminpb.LoanResult, error) {
+	if request.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id must not be empty")
+	}
+	return s.submit(ctx, request.GetId(), "UpdateLoanStatus", request.GetId(), "Disbursed")
+}
+
+func (s *Server) GetLoan(ctx context.Context, request *loanadminpb.GetLoanRequest) (*loanadminpb.Loan, error) {
+	if request.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id must not be empty")
+	}
+	return s.readLoan(ctx, request.GetId())
+}
+
+func (s *Server) ListLoans(ctx context.Context, request *loanadminpb.ListLoansRequest) (*loanadminpb.ListLoansResponse, error) {
+	var result []byte
+	var err error
+	if request.GetApplicant() == "" {
+		result, err = s.contract.EvaluateWithContext(ctx, "GetAllLoanApplications", client.WithArguments("false"))
+	} else {
+		result, err = s.contract.EvaluateWithContext(ctx, "GetLoansByApplicant", client.WithArguments(request.GetApplicant()))
+	}
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	var loans []*loan
+	if len(result) > 0 {
+		if err := json.Unmarshal(result, &loans); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to parse loan applications: %v", err)
+		}
+	}
+
+	response := &loanadminpb.ListLoansResponse{}
+	for _, l := range loans {
+		response.Loans = append(response.Loans, l.toProto())
+	}
+	return response, nil
+}
+
+// submit submits a transaction changing the loan loanID, waits for it to commit and returns the
+// loan as it then stands
+func (s *Server) submit(ctx context.Context, loanID string, name string, args ...string) (*loanadminpb.LoanResult, error) {
+	_, commit, err := s.contract.SubmitAsync(name, client.WithArguments(args...))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	commitStatus, err := commit.StatusWithContext(ctx)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	if !commitStatus.Successful {
+		return nil, status.Errorf(codes.Aborted, "transaction %s failed to commit with status %s", commitStatus.TransactionID, commitStatus.Code)
+	}
+
+	l, err := s.readLoan(ctx, loanID)
+	if err != nil {
+		return nil, err
+	}
+	return &loanadminpb.LoanResult{Loan: l, TransactionId: commitStatus.TransactionID}, nil
+}
+
+func (s *Server) readLoan(ctx context.Context, id string) (*loanadminpb.Loan, error) {
+	result, err := s.contract.EvaluateWithContext(ctx, "ReadLoanApplication", client.WithArguments(id))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	var l loan
+	if err := json.Unmarshal(result, &l); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to parse loan application %s: %v", id, err)
+	}
+	return l.toProto(), nil
+}
+
+func (l *loan) toProto() *loanadminpb.Loan {
+	return &loanadminpb.Loan{
+		Id:               l.ID,
+		Applicant:        l.Applicant,
+		Amount:           l.Amount,
+		Currency:         l.Currency,
+		Term:             l.Term,
+		InterestRate:     l.InterestRate,
+		Status:           l.Status,
+		DisbursedAt:      l.DisbursedAt,
+		TermsHash:        l.TermsHash,
+		PaidInstallments: l.PaidInstallments,
+		OwnerMsp:         l.OwnerMSP,
+	}
+}
@@ -0,0 +1,293 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: loanadmin.proto
+
+package loanadminpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	LoanAdmin_CreateLoan_FullMethodName   = "/loanadmin.v1.LoanAdmin/CreateLoan"
+	LoanAdmin_ApproveLoan_FullMethodName  = "/loanadmin.v1.LoanAdmin/ApproveLoan"
+	LoanAdmin_DisburseLoan_FullMethodName = "/loanadmin.v1.LoanAdmin/DisburseLoan"
+	LoanAdmin_GetLoan_FullMethodName      = "/loanadmin.v1.LoanAdmin/GetLoan"
+	LoanAdmin_ListLoans_FullMethodName    = "/loanadmin.v1.LoanAdmin/ListLoans"
+)
+
+// LoanAdminClient is the client API for LoanAdmin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// LoanAdmin services loan applications on the bankcontract chaincode for back-office systems.
+// Every call is made as the service's own Fabric identity, so the chaincode's role checks apply to
+// that identity. Chaincode errors are returned with the gRPC code matching their cause, such as
+// NOT_FOUND for an unknown loan or FAILED_PRECONDITION for a loan under another officer's review.
+type LoanAdminClient interface {
+	// CreateLoan records a new pending loan application
+	CreateLoan(ctx context.Context, in *CreateLoanRequest, opts ...grpc.CallOption) (*LoanResult, error)
+	// ApproveLoan approves a pending loan application, snapshotting its terms
+	ApproveLoan(ctx context.Context, in *ApproveLoanRequest, opts ...grpc.CallOption) (*LoanResult, error)
+	// DisburseLoan disburses an approved loan, starting its repayment schedule
+	DisburseLoan(ctx context.Context, in *DisburseLoanRequest, opts ...grpc.CallOption) (*LoanResult, error)
+	// GetLoan returns a loan application
+	GetLoan(ctx context.Context, in *GetLoanRequest, opts ...grpc.CallOption) (*Loan, error)
+	// ListLoans returns every loan application, or those of one applicant
+	ListLoans(ctx context.Context, in *ListLoansRequest, opts ...grpc.CallOption) (*ListLoansResponse, error)
+}
+
+type loanAdminClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewLoanAdminClient(cc grpc.ClientConnInterface) LoanAdminClient {
+	return &loanAdminClient{cc}
+}
+
+func (c *loanAdminClient) CreateLoan(ctx context.Context, in *CreateLoanRequest, opts ...grpc.CallOption) (*LoanResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LoanResult)
+	err := c.cc.Invoke(ctx, LoanAdmin_CreateLoan_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *loanAdminClient) ApproveLoan(ctx context.Context, in *ApproveLoanRequest, opts ...grpc.CallOption) (*LoanResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LoanResult)
+	err := c.cc.Invoke(ctx, LoanAdmin_ApproveLoan_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *loanAdminClient) DisburseLoan(ctx context.Context, in *DisburseLoanRequest, opts ...grpc.CallOption) (*LoanResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LoanResult)
+	err := c.cc.Invoke(ctx, LoanAdmin_DisburseLoan_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *loanAdminClient) GetLoan(ctx context.Context, in *GetLoanRequest, opts ...grpc.CallOption) (*Loan, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Loan)
+	err := c.cc.Invoke(ctx, LoanAdmin_GetLoan_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *loanAdminClient) ListLoans(ctx context.Context, in *ListLoansRequest, opts ...grpc.CallOption) (*ListLoansResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListLoansResponse)
+	err := c.cc.Invoke(ctx, LoanAdmin_ListLoans_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// LoanAdminServer is the server API for LoanAdmin service.
+// All implementations must embed UnimplementedLoanAdminServer
+// for forward compatibility.
+//
+// LoanAdmin services loan applications on the bankcontract chaincode for back-office systems.
+// Every call is made as the service's own Fabric identity, so the chaincode's role checks apply to
+// that identity. Chaincode errors are returned with the gRPC code matching their cause, such as
+// NOT_FOUND for an unknown loan or FAILED_PRECONDITION for a loan under another officer's review.
+type LoanAdminServer interface {
+	// CreateLoan records a new pending loan application
+	CreateLoan(context.Context, *CreateLoanRequest) (*LoanResult, error)
+	// ApproveLoan approves a pending loan application, snapshotting its terms
+	ApproveLoan(context.Context, *ApproveLoanRequest) (*LoanResult, error)
+	// DisburseLoan disburses an approved loan, starting its repayment schedule
+	DisburseLoan(context.Context, *DisburseLoanRequest) (*LoanResult, error)
+	// GetLoan returns a loan application
+	GetLoan(context.Context, *GetLoanRequest) (*Loan, error)
+	// ListLoans returns every loan application, or those of one applicant
+	ListLoans(context.Context, *ListLoansRequest) (*ListLoansResponse, error)
+	mustEmbedUnimplementedLoanAdminServer()
+}
+
+// UnimplementedLoanAdminServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedLoanAdminServer struct{}
+
+func (UnimplementedLoanAdminServer) CreateLoan(context.Context, *CreateLoanRequest) (*LoanResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateLoan not implemented")
+}
+func (UnimplementedLoanAdminServer) ApproveLoan(context.Context, *ApproveLoanRequest) (*LoanResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ApproveLoan not implemented")
+}
+func (UnimplementedLoanAdminServer) DisburseLoan(context.Context, *DisburseLoanRequest) (*LoanResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DisburseLoan not implemented")
+}
+func (UnimplementedLoanAdminServer) GetLoan(context.Context, *GetLoanRequest) (*Loan, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLoan not implemented")
+}
+func (UnimplementedLoanAdminServer) ListLoans(context.Context, *ListLoansRequest) (*ListLoansResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListLoans not implemented")
+}
+func (UnimplementedLoanAdminServer) mustEmbedUnimplementedLoanAdminServer() {}
+func (UnimplementedLoanAdminServer) testEmbeddedByValue()                   {}
+
+// UnsafeLoanAdminServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to LoanAdminServer will
+// result in compilation errors.
+type UnsafeLoanAdminServer interface {
+	mustEmbedUnimplementedLoanAdminServer()
+}
+
+func RegisterLoanAdminServer(s grpc.ServiceRegistrar, srv LoanAdminServer) {
+	// If the following call pancis, it indicates UnimplementedLoanAdminServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&LoanAdmin_ServiceDesc, srv)
+}
+
+func _LoanAdmin_CreateLoan_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateLoanRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LoanAdminServer).CreateLoan(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LoanAdmin_CreateLoan_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LoanAdminServer).CreateLoan(ctx, req.(*CreateLoanRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LoanAdmin_ApproveLoan_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ApproveLoanRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LoanAdminServer).ApproveLoan(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LoanAdmin_ApproveLoan_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LoanAdminServer).ApproveLoan(ctx, req.(*ApproveLoanRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LoanAdmin_DisburseLoan_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DisburseLoanRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LoanAdminServer).DisburseLoan(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LoanAdmin_DisburseLoan_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LoanAdminServer).DisburseLoan(ctx, req.(*DisburseLoanRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LoanAdmin_GetLoan_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLoanRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LoanAdminServer).GetLoan(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LoanAdmin_GetLoan_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LoanAdminServer).GetLoan(ctx, req.(*GetLoanRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LoanAdmin_ListLoans_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListLoansRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LoanAdminServer).ListLoans(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LoanAdmin_ListLoans_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LoanAdminServer).ListLoans(ctx, req.(*ListLoansRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// LoanAdmin_ServiceDesc is the grpc.ServiceDesc for LoanAdmin service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var LoanAdmin_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "loanadmin.v1.LoanAdmin",
+	HandlerType: (*LoanAdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateLoan",
+			Handler:    _LoanAdmin_CreateLoan_Handler,
+		},
+		{
+			MethodName: "ApproveLoan",
+			Handler:    _LoanAdmin_ApproveLoan_Handler,
+		},
+		{
+			MethodName: "DisburseLoan",
+			Handler:    _LoanAdmin_DisburseLoan_Handler,
+		},
+		{
+			MethodName: "GetLoan",
+			Handler:    _LoanAdmin_GetLoan_Handler,
+		},
+		{
+			MethodName: "ListLoans",
+			Handler:    _LoanAdmin_ListLoans_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "loanadmin.proto",
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"crypto/x509"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path"
+	"time"
+
+	"service/loanadmin"
+	"service/loanadminpb"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-gateway/pkg/hash"
+	"github.com/hyperledger/fabric-gateway/pkg/identity"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+var (
+	channelName   = envOrDefault("CHANNEL_NAME", "mychannel")
+	chaincodeName = envOrDefault("CHAINCODE_NAME", "bankcontract")
+	mspID         = envOrDefault("MSP_ID", "Org1MSP")
+
+	// Path to crypto materials.
+	cryptoPath = envOrDefault("CRYPTO_PATH", "../test-network/organizations/peerOrganizations/org1.example.com")
+
+	// Path to the service identity's private key directory.
+	keyDirectoryPath = envOrDefault("KEY_DIRECTORY_PATH", cryptoPath+"/users/User1@org1.example.com/msp/keystore")
+
+	// Path to the service identity's certificate.
+	certPath = envOrDefault("CERT_PATH", cryptoPath+"/users/User1@org1.example.com/msp/signcerts/cert.pem")
+
+	// Path to peer tls certificate.
+	tlsCertPath = envOrDefault("TLS_CERT_PATH", cryptoPath+"/peers/peer0.org1.example.com/tls/ca.crt")
+
+	// Gateway peer endpoint.
+	peerEndpoint = envOrDefault("PEER_ENDPOINT", "dns:///localhost:7051")
+
+	// Gateway peer SSL host name override.
+	peerHostAlias = envOrDefault("PEER_HOST_ALIAS", "peer0.org1.example.com")
+
+	// Address the LoanAdmin service listens on.
+	listenAddress = envOrDefault("LISTEN_ADDRESS", ":50051")
+)
+
+func main() {
+	connection, err := newGrpcConnection()
+	if err != nil {
+		log.Fatalf("Error connecting to the gateway peer: %v", err)
+	}
+	defer connection.Close()
+
+	gateway, err := connectGateway(connection)
+	if err != nil {
+		log.Fatalf("Error connecting to the gateway: %v", err)
+	}
+	defer gateway.Close()
+
+	contract := gateway.GetNetwork(channelName).GetContract(chaincodeName)
+
+	listener, err := net.Listen("tcp", listenAddress)
+	if err != nil {
+		log.Fatalf("Error listening on %s: %v", listenAddress, err)
+	}
+	server := grpc.NewServer()
+	loanadminpb.RegisterLoanAdminServer(server, loanadmin.NewServer(contract))
+
+	log.Printf("LoanAdmin listening on %s...", listenAddress)
+	if err := server.Serve(listener); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func newGrpcConnection() (*grpc.ClientConn, error) {
+	certificatePEM, err := os.ReadFile(tlsCertPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read TLS certificate file: %w", err)
+	}
+	certificate, err := identity.CertificateFromPEM(certificatePEM)
+	if err != nil {
+		return nil, err
+	}
+
+	certPool := x509.NewCertPool()
+	certPool.AddCert(certificate)
+	transportCredentials := credentials.NewClientTLSFromCert(certPool, peerHostAlias)
+
+	connection, err := grpc.NewClient(peerEndpoint, grpc.WithTransportCredentials(transportCredentials))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gRPC connection: %w", err)
+	}
+
+	return connection, nil
+}
+
+func connectGateway(connection *grpc.ClientConn) (*client.Gateway, error) {
+	certificatePEM, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate file: %w", err)
+	}
+	certificate, err := identity.CertificateFromPEM(certificatePEM)
+	if err != nil {
+		return nil, err
+	}
+	id, err := identity.NewX509Identity(mspID, certificate)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKeyPEM, err := readFirstFile(keyDirectoryPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key file: %w", err)
+	}
+	privateKey, err := identity.PrivateKeyFromPEM(privateKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+	sign, err := identity.NewPrivateKeySign(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.Connect(
+		id,
+		client.WithSign(sign),
+		client.WithHash(hash.SHA256),
+		client.WithClientConnection(connection),
+		client.WithEvaluateTimeout(5*time.Second),
+		client.WithEndorseTimeout(15*time.Second),
+		client.WithSubmitTimeout(5*time.Second),
+		client.WithCommitStatusTimeout(1*time.Minute),
+	)
+}
+
+func readFirstFile(dirPath string) ([]byte, error) {
+	dir, err := os.Open(dirPath)
+	if err != nil {
+		return nil, err
+	}
+
+	fileNames, err := dir.Readdirnames(1)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.ReadFile(path.Join(dirPath, fileNames[0]))
+}
+
+// envOrDefault returns the value of an environment variable, or the default when it is unset
+func envOrDefault(key, defaultValue string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}